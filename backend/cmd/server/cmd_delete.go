@@ -22,11 +22,12 @@ func cmdDelete(args []string) {
 
 // DeleteFlags holds the parsed flags for the delete command
 type DeleteFlags struct {
-	From    string
-	To      string
-	Service string
-	Yes     bool
-	Scope   string
+	From         string
+	To           string
+	Service      string
+	Yes          bool
+	ConfirmCount bool
+	Scope        string
 }
 
 // parseDeleteFlags parses command line arguments into DeleteFlags
@@ -38,6 +39,7 @@ func parseDeleteFlags(args []string) (*DeleteFlags, error) {
 	fs.StringVar(&flags.To, "to", "", "End date (YYYY-MM-DD, required)")
 	fs.StringVar(&flags.Service, "service", "", "Filter by tool (claude-code, codex, gemini)")
 	fs.BoolVar(&flags.Yes, "yes", false, "Skip confirmation prompts")
+	fs.BoolVar(&flags.ConfirmCount, "confirm-count", false, "Require typing the number of records to delete instead of y/n")
 
 	fs.Usage = func() {
 		fmt.Print(`Delete telemetry data from the database
@@ -77,6 +79,10 @@ func runDelete(args []string) error {
 		return fmt.Errorf("--from and --to are required for delete operations\nUsage: ai-observer delete <scope> --from YYYY-MM-DD --to YYYY-MM-DD")
 	}
 
+	if flags.Yes && flags.ConfirmCount {
+		return fmt.Errorf("--yes and --confirm-count cannot be used together")
+	}
+
 	// Parse scope
 	scope, err := deleter.ParseScope(flags.Scope)
 	if err != nil {
@@ -122,11 +128,12 @@ func runDelete(args []string) error {
 
 	// Run the delete operation
 	opts := deleter.Options{
-		Scope:       scope,
-		From:        fromTime,
-		To:          toTime,
-		Service:     serviceName,
-		SkipConfirm: flags.Yes,
+		Scope:        scope,
+		From:         fromTime,
+		To:           toTime,
+		Service:      serviceName,
+		SkipConfirm:  flags.Yes,
+		ConfirmCount: flags.ConfirmCount,
 	}
 
 	ctx := context.Background()
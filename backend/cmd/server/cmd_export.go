@@ -29,7 +29,16 @@ type ExportFlags struct {
 	DryRun    bool
 	Verbose   bool
 	Yes       bool
+	Resume    bool
+	SingleDB  bool
+	Format    string
+	Codec     string
+	Level     int
 	Source    string
+	Filename  string
+
+	S3URI         string
+	S3DeleteLocal bool
 }
 
 // parseExportFlags parses command line arguments into ExportFlags
@@ -45,9 +54,17 @@ func parseExportFlags(args []string) (*ExportFlags, error) {
 	fs.BoolVar(&flags.DryRun, "dry-run", false, "Preview what would be exported")
 	fs.BoolVar(&flags.Verbose, "verbose", false, "Show detailed progress")
 	fs.BoolVar(&flags.Yes, "yes", false, "Skip confirmation prompts")
+	fs.BoolVar(&flags.Resume, "resume", false, "Resume a failed export, skipping already completed signals")
+	fs.BoolVar(&flags.SingleDB, "single-db", false, "Write one DuckDB file with data tables instead of Parquet files")
+	fs.StringVar(&flags.Format, "format", "parquet", "Signal file format (parquet, csv, jsonl)")
+	fs.StringVar(&flags.Codec, "codec", "zstd", "Parquet compression codec (zstd, snappy, gzip)")
+	fs.IntVar(&flags.Level, "compression-level", 0, "Compression level 1-9 for ZIP deflate and ZSTD (0 = defaults)")
+	fs.StringVar(&flags.Filename, "filename-template", "", "Name for the .duckdb/.zip output using {source}, {from}, {to}, {ts}")
+	fs.StringVar(&flags.S3URI, "s3-uri", "", "Upload the output files to s3://bucket/prefix using the standard AWS credentials")
+	fs.BoolVar(&flags.S3DeleteLocal, "s3-delete-local", false, "Delete the local output files after uploading them with --s3-uri")
 
 	fs.Usage = func() {
-		fmt.Print(`Export telemetry data to Parquet files
+		fmt.Print(`Export telemetry data to Parquet, CSV or JSONL files
 
 Usage: ai-observer export [claude-code|codex|gemini|all] --output <directory> [options]
 
@@ -90,6 +107,28 @@ func runExport(args []string) error {
 		return err
 	}
 
+	format, err := exporter.ParseExportFormat(flags.Format)
+	if err != nil {
+		return err
+	}
+
+	codec, err := exporter.ParseParquetCodec(flags.Codec)
+	if err != nil {
+		return err
+	}
+
+	if err := exporter.ValidateFilenameTemplate(flags.Filename); err != nil {
+		return err
+	}
+
+	if flags.S3URI != "" {
+		if _, _, err := exporter.ParseS3URI(flags.S3URI); err != nil {
+			return err
+		}
+	} else if flags.S3DeleteLocal {
+		return fmt.Errorf("--s3-delete-local requires --s3-uri")
+	}
+
 	// Parse optional dates
 	fromDate, err := importer.ParseDateArg(flags.From)
 	if err != nil {
@@ -101,6 +140,13 @@ func runExport(args []string) error {
 		return err
 	}
 
+	if flags.SingleDB && flags.Resume {
+		return fmt.Errorf("--single-db cannot be combined with --resume")
+	}
+	if flags.SingleDB && format != exporter.FormatParquet {
+		return fmt.Errorf("--single-db cannot be combined with --format %s", format)
+	}
+
 	// Validate date range if both specified
 	if fromDate != nil && toDate != nil && fromDate.After(*toDate) {
 		return fmt.Errorf("--from date must be before --to date")
@@ -117,6 +163,16 @@ func runExport(args []string) error {
 		DryRun:      flags.DryRun,
 		SkipConfirm: flags.Yes,
 		Verbose:     flags.Verbose,
+		Resume:      flags.Resume,
+		SingleDB:    flags.SingleDB,
+
+		Format:           format,
+		ParquetCodec:     codec,
+		CompressionLevel: flags.Level,
+		FilenameTemplate: flags.Filename,
+
+		S3URI:         flags.S3URI,
+		S3DeleteLocal: flags.S3DeleteLocal,
 	}
 
 	ctx := context.Background()
@@ -20,14 +20,15 @@ func cmdImport(args []string) {
 
 // ImportFlags holds the parsed flags for the import command
 type ImportFlags struct {
-	From    string
-	To      string
-	DryRun  bool
-	Force   bool
-	Verbose bool
-	Purge   bool
-	Yes     bool
-	Tool    string
+	From      string
+	To        string
+	DryRun    bool
+	Force     bool
+	Verbose   bool
+	Purge     bool
+	Yes       bool
+	Tool      string
+	BatchSize int
 }
 
 // parseImportFlags parses command line arguments into ImportFlags
@@ -42,6 +43,7 @@ func parseImportFlags(args []string) (*ImportFlags, error) {
 	fs.BoolVar(&flags.Verbose, "verbose", false, "Show detailed progress")
 	fs.BoolVar(&flags.Purge, "purge", false, "Delete existing data in time range before import")
 	fs.BoolVar(&flags.Yes, "yes", false, "Skip confirmation prompts")
+	fs.IntVar(&flags.BatchSize, "batch-size", importer.DefaultBatchSize, "Records to write per database transaction")
 
 	fs.Usage = func() {
 		fmt.Print(`Import local sessions from AI tool files
@@ -99,6 +101,10 @@ func runImport(args []string) error {
 		return fmt.Errorf("--from date must be before --to date")
 	}
 
+	if flags.BatchSize <= 0 {
+		return fmt.Errorf("--batch-size must be positive")
+	}
+
 	// Load config and initialize store
 	cfg := config.Load()
 	store, err := storage.NewDuckDBStore(cfg.DatabasePath)
@@ -119,6 +125,8 @@ func runImport(args []string) error {
 		ToDate:      toDate,
 		Purge:       flags.Purge,
 		SkipConfirm: flags.Yes,
+		BatchSize:   flags.BatchSize,
+		MaxAttrs:    cfg.MaxAttributes,
 	}
 
 	// Run import
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/config"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// clearScreen moves the cursor home and clears the terminal (ANSI)
+const clearScreen = "\033[H\033[2J"
+
+func cmdStats(args []string) {
+	if err := runStats(args); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// StatsFlags holds the parsed flags for the stats command
+type StatsFlags struct {
+	Follow   bool
+	Interval time.Duration
+}
+
+// parseStatsFlags parses command line arguments into StatsFlags
+func parseStatsFlags(args []string) (*StatsFlags, error) {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+
+	flags := &StatsFlags{}
+	fs.BoolVar(&flags.Follow, "follow", false, "Refresh the view continuously until Ctrl-C")
+	fs.DurationVar(&flags.Interval, "interval", 2*time.Second, "Refresh interval for --follow")
+
+	fs.Usage = func() {
+		fmt.Print(`Show ingestion counts and cost from the database (read-only)
+
+Usage: ai-observer stats [options]
+
+Options:
+`)
+		printFlags(fs)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+func runStats(args []string) error {
+	flags, err := parseStatsFlags(reorderArgs(args))
+	if err != nil {
+		return err
+	}
+
+	if flags.Interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	cfg := config.Load()
+	collect := func(ctx context.Context) (*statsSnapshot, error) {
+		return collectStats(ctx, cfg.DatabasePath)
+	}
+
+	if !flags.Follow {
+		snap, err := collect(context.Background())
+		if err != nil {
+			return err
+		}
+		renderStats(os.Stdout, snap, nil)
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return followStats(ctx, os.Stdout, flags.Interval, collect)
+}
+
+// statsSnapshot is a point-in-time view of the database used by the stats command
+type statsSnapshot struct {
+	At    time.Time
+	Stats *api.StatsResponse
+	Cost  map[string]float64 // USD per service
+}
+
+// collectStats opens the database read-only for a single snapshot, so the
+// running server's writes are picked up on the next refresh
+func collectStats(ctx context.Context, dbPath string) (*statsSnapshot, error) {
+	store, err := storage.OpenReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cost, err := store.GetCostTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsSnapshot{At: time.Now(), Stats: stats, Cost: cost}, nil
+}
+
+// followStats clears and redraws the stats view every interval until ctx is cancelled
+func followStats(ctx context.Context, w io.Writer, interval time.Duration, collect func(context.Context) (*statsSnapshot, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev *statsSnapshot
+	for {
+		snap, err := collect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		fmt.Fprint(w, clearScreen)
+		renderStats(w, snap, prev)
+		fmt.Fprintf(w, "\nRefreshing every %s, press Ctrl-C to exit\n", interval)
+		prev = snap
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderStats writes the stats view; when prev is set, counts show the change since prev
+func renderStats(w io.Writer, snap, prev *statsSnapshot) {
+	s := snap.Stats
+	fmt.Fprintf(w, "AI Observer stats (%s)\n\n", snap.At.Format("2006-01-02 15:04:05"))
+
+	row := func(label string, cur int64, before func(*api.StatsResponse) int64) {
+		fmt.Fprintf(w, "  %-10s %12d", label, cur)
+		if prev != nil {
+			fmt.Fprintf(w, "  %+d", cur-before(prev.Stats))
+		}
+		fmt.Fprintln(w)
+	}
+	row("Traces", s.TraceCount, func(p *api.StatsResponse) int64 { return p.TraceCount })
+	row("Spans", s.SpanCount, func(p *api.StatsResponse) int64 { return p.SpanCount })
+	row("Logs", s.LogCount, func(p *api.StatsResponse) int64 { return p.LogCount })
+	row("Metrics", s.MetricCount, func(p *api.StatsResponse) int64 { return p.MetricCount })
+	fmt.Fprintf(w, "  %-10s %11.2f%%\n", "Errors", s.ErrorRate)
+
+	services := make([]string, 0, len(snap.Cost))
+	var total float64
+	for service, cost := range snap.Cost {
+		services = append(services, service)
+		total += cost
+	}
+	sort.Strings(services)
+
+	fmt.Fprintf(w, "\nCost (USD)\n")
+	for _, service := range services {
+		fmt.Fprintf(w, "  %-20s %10.4f\n", service, snap.Cost[service])
+	}
+	fmt.Fprintf(w, "  %-20s %10.4f\n", "Total", total)
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/importer"
+	"github.com/tobilg/ai-observer/internal/storage"
 )
 
 // captureOutput captures stdout during function execution
@@ -366,6 +373,9 @@ func TestParseImportFlags(t *testing.T) {
 		if flags.Tool != "claude-code" {
 			t.Errorf("expected tool 'claude', got %q", flags.Tool)
 		}
+		if flags.BatchSize != importer.DefaultBatchSize {
+			t.Errorf("expected default batch-size %d, got %d", importer.DefaultBatchSize, flags.BatchSize)
+		}
 	})
 
 	t.Run("all flags", func(t *testing.T) {
@@ -377,6 +387,7 @@ func TestParseImportFlags(t *testing.T) {
 			"--verbose",
 			"--purge",
 			"--yes",
+			"--batch-size", "500",
 			"all",
 		})
 		if err != nil {
@@ -406,6 +417,9 @@ func TestParseImportFlags(t *testing.T) {
 		if !flags.Yes {
 			t.Error("expected yes to be true")
 		}
+		if flags.BatchSize != 500 {
+			t.Errorf("expected batch-size 500, got %d", flags.BatchSize)
+		}
 	})
 
 	t.Run("invalid flag", func(t *testing.T) {
@@ -479,11 +493,40 @@ func TestParseExportFlags(t *testing.T) {
 			"--dry-run",
 			"--verbose",
 			"--yes",
+			"--resume",
+			"--single-db",
+			"--format", "csv",
+			"--codec", "snappy",
+			"--compression-level", "6",
+			"--filename-template", "nightly-{source}",
+			"--s3-uri", "s3://bucket/exports",
+			"--s3-delete-local",
 			"all",
 		})
 		if err != nil {
 			t.Fatalf("parseExportFlags failed: %v", err)
 		}
+		if !flags.Resume {
+			t.Error("expected resume to be true")
+		}
+		if !flags.SingleDB {
+			t.Error("expected single-db to be true")
+		}
+		if flags.Format != "csv" {
+			t.Errorf("expected format 'csv', got %q", flags.Format)
+		}
+		if flags.S3URI != "s3://bucket/exports" || !flags.S3DeleteLocal {
+			t.Errorf("expected S3 upload flags, got %q and %v", flags.S3URI, flags.S3DeleteLocal)
+		}
+		if flags.Codec != "snappy" {
+			t.Errorf("expected codec 'snappy', got %q", flags.Codec)
+		}
+		if flags.Level != 6 {
+			t.Errorf("expected compression level 6, got %d", flags.Level)
+		}
+		if flags.Filename != "nightly-{source}" {
+			t.Errorf("expected filename template 'nightly-{source}', got %q", flags.Filename)
+		}
 		if !flags.FromFiles {
 			t.Error("expected from-files to be true")
 		}
@@ -519,6 +562,13 @@ func TestRunExportValidation(t *testing.T) {
 		}
 	})
 
+	t.Run("single-db with resume", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--single-db", "--resume", "claude-code"})
+		if err == nil {
+			t.Error("expected error combining --single-db and --resume")
+		}
+	})
+
 	t.Run("invalid from date", func(t *testing.T) {
 		err := runExport([]string{"--output", "/tmp", "--from", "invalid", "claude-code"})
 		if err == nil {
@@ -532,6 +582,48 @@ func TestRunExportValidation(t *testing.T) {
 			t.Error("expected error for from after to")
 		}
 	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--format", "xlsx", "claude-code"})
+		if err == nil {
+			t.Error("expected error for invalid format")
+		}
+	})
+
+	t.Run("single-db with csv", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--single-db", "--format", "csv", "claude-code"})
+		if err == nil {
+			t.Error("expected error combining --single-db and --format csv")
+		}
+	})
+
+	t.Run("invalid s3 uri", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--s3-uri", "https://bucket/prefix", "claude-code"})
+		if err == nil {
+			t.Error("expected error for a non-s3 URI")
+		}
+	})
+
+	t.Run("s3-delete-local without s3-uri", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--s3-delete-local", "claude-code"})
+		if err == nil {
+			t.Error("expected error for --s3-delete-local without --s3-uri")
+		}
+	})
+
+	t.Run("single-db with jsonl", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--single-db", "--format", "jsonl", "claude-code"})
+		if err == nil {
+			t.Error("expected error combining --single-db and --format jsonl")
+		}
+	})
+
+	t.Run("invalid codec", func(t *testing.T) {
+		err := runExport([]string{"--output", "/tmp", "--codec", "lz5", "claude-code"})
+		if err == nil {
+			t.Error("expected error for invalid codec")
+		}
+	})
 }
 
 // Tests for parseDeleteFlags
@@ -571,6 +663,16 @@ func TestParseDeleteFlags(t *testing.T) {
 			t.Error("expected yes to be true")
 		}
 	})
+
+	t.Run("with confirm-count", func(t *testing.T) {
+		flags, err := parseDeleteFlags([]string{"--from", "2025-01-01", "--to", "2025-01-31", "--confirm-count", "all"})
+		if err != nil {
+			t.Fatalf("parseDeleteFlags failed: %v", err)
+		}
+		if !flags.ConfirmCount {
+			t.Error("expected confirm-count to be true")
+		}
+	})
 }
 
 // Tests for runDelete validation
@@ -596,6 +698,13 @@ func TestRunDeleteValidation(t *testing.T) {
 		}
 	})
 
+	t.Run("yes with confirm-count", func(t *testing.T) {
+		err := runDelete([]string{"--from", "2025-01-01", "--to", "2025-01-31", "--yes", "--confirm-count", "logs"})
+		if err == nil {
+			t.Error("expected error for --yes combined with --confirm-count")
+		}
+	})
+
 	t.Run("invalid scope", func(t *testing.T) {
 		err := runDelete([]string{"--from", "2025-01-01", "--to", "2025-01-31", "invalid"})
 		if err == nil {
@@ -624,3 +733,61 @@ func TestRunDeleteValidation(t *testing.T) {
 		}
 	})
 }
+
+// Tests for parseStatsFlags
+func TestParseStatsFlags(t *testing.T) {
+	flags, err := parseStatsFlags([]string{"--follow", "--interval", "5s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !flags.Follow {
+		t.Error("expected Follow to be true")
+	}
+	if flags.Interval != 5*time.Second {
+		t.Errorf("Interval = %v, want 5s", flags.Interval)
+	}
+
+	if err := runStats([]string{"--interval", "0s"}); err == nil {
+		t.Error("expected error for non-positive interval")
+	}
+}
+
+func TestFollowStats_OneCycle(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "stats.duckdb")
+	store, err := storage.NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	cost := 1.25
+	temporality := int32(1)
+	if err := store.InsertMetrics(context.Background(), []api.MetricDataPoint{{
+		Timestamp: time.Now(), ServiceName: "claude-code", MetricName: "claude_code.cost.usage",
+		MetricType: "sum", Value: &cost, AggregationTemporality: &temporality,
+	}}); err != nil {
+		t.Fatalf("failed to insert metric: %v", err)
+	}
+	store.Close()
+
+	// Cancel after the first snapshot so exactly one refresh cycle is rendered
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collect := func(ctx context.Context) (*statsSnapshot, error) {
+		defer cancel()
+		return collectStats(ctx, dbPath)
+	}
+
+	var buf bytes.Buffer
+	if err := followStats(ctx, &buf, time.Hour, collect); err != nil {
+		t.Fatalf("followStats failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, clearScreen) {
+		t.Error("expected output to start by clearing the screen")
+	}
+	for _, want := range []string{"Metrics", "claude-code", "1.2500", "Ctrl-C"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -33,6 +33,8 @@ func main() {
 		cmdDelete(os.Args[2:])
 	case "setup":
 		cmdSetup(os.Args[2:])
+	case "stats":
+		cmdStats(os.Args[2:])
 	case "serve":
 		runServer()
 	case "-v", "--version", "version":
@@ -62,6 +64,7 @@ Commands:
   export    Export telemetry data to Parquet files
   delete    Delete telemetry data from database
   setup     Show setup instructions for AI tools
+  stats     Show ingestion counts and cost (--follow for a live view)
   serve     Start the OTLP server (default if no command)
 
 Options:
@@ -73,9 +76,29 @@ Use "ai-observer [command] --help" for command-specific options.
 Environment Variables:
   AI_OBSERVER_API_PORT       API server port (default: 8080)
   AI_OBSERVER_OTLP_PORT      OTLP ingestion port (default: 4318)
+  AI_OBSERVER_OTLP_GRPC_PORT OTLP/gRPC ingestion port, 0 disables (default: 4317)
   AI_OBSERVER_DATABASE_PATH  DuckDB database path (default: ./data/ai-observer.duckdb)
   AI_OBSERVER_FRONTEND_URL   Frontend URL for CORS (default: http://localhost:5173)
   AI_OBSERVER_LOG_LEVEL      Log level: DEBUG, INFO, WARN, ERROR (default: INFO)
+  AI_OBSERVER_ENV_LABEL      Environment label shown in API responses (e.g. prod, staging)
+  AI_OBSERVER_METRIC_ALLOWLIST  Comma-separated metric name globs to store (default: all)
+  AI_OBSERVER_METRIC_DENYLIST   Comma-separated metric name globs to drop at ingestion
+  AI_OBSERVER_MODEL_ALIASES     Comma-separated pattern=canonical model name aliases for breakdowns
+  AI_OBSERVER_SERVICE_GROUPS    Comma-separated pattern=group rules for grouping services in the UI
+  AI_OBSERVER_DB_PRAGMAS        Semicolon-separated DuckDB SET/PRAGMA statements applied at startup
+  AI_OBSERVER_DISABLED_ENDPOINTS  Comma-separated endpoint paths to disable ({param} and * allowed)
+  AI_OBSERVER_DISABLED_ENDPOINT_STATUS  Status returned for disabled endpoints: 404 or 403 (default: 404)
+  AI_OBSERVER_INGEST_WEIGHTS    Comma-separated service=weight rules for fair ingestion (default weight: 1)
+  AI_OBSERVER_SESSION_IDLE_TIMEOUT  Idle time after which a session is reported as completed (default: 30m)
+  AI_OBSERVER_INGEST_SIGNALS    Comma-separated OTLP signals to store: traces, metrics, logs (default: all)
+  AI_OBSERVER_ASYNC_INGEST      Set to 1 to acknowledge OTLP requests before they are stored
+  AI_OBSERVER_MAX_ATTRS         Maximum entries kept per attribute map, 0 disables (default: 128)
+  AI_OBSERVER_METRIC_TS_RESOLUTION  Duration metric timestamps are truncated to, last write wins (e.g. 1s)
+  AI_OBSERVER_CURRENCY        Display currency for costs, stored in USD (default: USD)
+  AI_OBSERVER_EXCHANGE_RATE   Units of AI_OBSERVER_CURRENCY per USD (default: 1)
+  AI_OBSERVER_RETENTION_DAYS  Days of telemetry to keep, pruned hourly; 0 disables (default: 0)
+  AI_OBSERVER_STORAGE_SAMPLE_INTERVAL  Seconds between storage usage samples, 0 disables (default: 300)
+  AI_OBSERVER_STORAGE_SAMPLES   Storage usage samples kept for growth reporting (default: 288)
   AI_OBSERVER_CLAUDE_PATH    Custom Claude Code config directory
   AI_OBSERVER_CODEX_PATH     Custom Codex CLI home directory
   AI_OBSERVER_GEMINI_PATH    Custom Gemini CLI home directory
@@ -117,6 +140,7 @@ func runServer() {
 		"database", cfg.DatabasePath,
 		"api_port", cfg.APIPort,
 		"otlp_port", cfg.OTLPPort,
+		"otlp_grpc_port", cfg.OTLPGRPCPort,
 	)
 
 	if err := srv.ListenAndServe(); err != nil {
@@ -0,0 +1,158 @@
+// Package alerts evaluates metric threshold rules in the background and records and
+// broadcasts the alerts they fire.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/storage"
+	"github.com/tobilg/ai-observer/internal/websocket"
+)
+
+// ValidComparison reports whether c is one of the api.AlertComparison constants
+func ValidComparison(c string) bool {
+	switch c {
+	case api.AlertComparisonAbove, api.AlertComparisonAboveOrEqual, api.AlertComparisonBelow, api.AlertComparisonBelowOrEqual:
+		return true
+	}
+	return false
+}
+
+// Breached reports whether value crosses threshold under comparison
+func Breached(comparison string, value, threshold float64) bool {
+	switch comparison {
+	case api.AlertComparisonAbove:
+		return value > threshold
+	case api.AlertComparisonAboveOrEqual:
+		return value >= threshold
+	case api.AlertComparisonBelow:
+		return value < threshold
+	case api.AlertComparisonBelowOrEqual:
+		return value <= threshold
+	}
+	return false
+}
+
+// Evaluator checks every alert rule against the aggregate of its metric over the rule's
+// trailing window, as QueryMetricSeries computes it for single-value widgets, summed across
+// services and token types. A rule fires when the aggregate crosses its threshold after not
+// having done so on the previous evaluation, so a breach that lasts produces one event; a
+// rule whose metric has no data in the window is skipped. Which rules are firing is kept in
+// memory only, so rules still breached after a restart fire once more.
+//
+// An Evaluator is driven by a single goroutine (Run) and is not safe for concurrent use.
+type Evaluator struct {
+	store  *storage.DuckDBStore
+	hub    *websocket.Hub
+	now    func() time.Time
+	firing map[string]bool
+}
+
+// NewEvaluator creates an evaluator that stores fired alerts in store and broadcasts them
+// through hub, which may be nil
+func NewEvaluator(store *storage.DuckDBStore, hub *websocket.Hub) *Evaluator {
+	return &Evaluator{
+		store:  store,
+		hub:    hub,
+		now:    time.Now,
+		firing: make(map[string]bool),
+	}
+}
+
+// Run evaluates the rules every interval until ctx is cancelled
+func (e *Evaluator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := e.Evaluate(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to evaluate alert rules", "error", err)
+		}
+	}
+}
+
+// Evaluate checks all rules once and returns the alerts that fired. A rule that cannot be
+// evaluated is logged and skipped so it does not hold back the others.
+func (e *Evaluator) Evaluate(ctx context.Context) ([]api.AlertEvent, error) {
+	rules, err := e.store.ListAlertRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.now()
+	firing := make(map[string]bool, len(rules))
+	fired := []api.AlertEvent{}
+	for _, rule := range rules {
+		value, ok, err := e.aggregate(ctx, rule, now)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Warn("Failed to evaluate alert rule", "rule", rule.Name, "error", err)
+			firing[rule.ID] = e.firing[rule.ID]
+			continue
+		}
+		if !ok || !Breached(rule.Comparison, value, rule.Threshold) {
+			continue
+		}
+
+		firing[rule.ID] = true
+		if e.firing[rule.ID] {
+			continue
+		}
+
+		event := api.AlertEvent{
+			RuleID:        rule.ID,
+			RuleName:      rule.Name,
+			MetricName:    rule.MetricName,
+			Service:       rule.Service,
+			Comparison:    rule.Comparison,
+			Threshold:     rule.Threshold,
+			WindowSeconds: rule.WindowSeconds,
+			Value:         value,
+			FiredAt:       now,
+		}
+		if err := e.store.InsertAlertEvent(ctx, &event); err != nil {
+			return nil, err
+		}
+		logger.Info("Alert fired", "rule", rule.Name, "metric", rule.MetricName,
+			"value", value, "comparison", rule.Comparison, "threshold", rule.Threshold)
+		fired = append(fired, event)
+	}
+	e.firing = firing
+
+	if len(fired) > 0 && e.hub != nil {
+		e.hub.Broadcast(websocket.NewAlertsMessage(fired))
+	}
+	return fired, nil
+}
+
+// aggregate returns the value of a rule's metric over the window ending at now; ok is false
+// when the metric has no data in the window
+func (e *Evaluator) aggregate(ctx context.Context, rule api.AlertRule, now time.Time) (float64, bool, error) {
+	window := time.Duration(rule.WindowSeconds) * time.Second
+	resp, err := e.store.QueryMetricSeries(ctx, rule.MetricName, rule.Service, now.Add(-window), now, rule.WindowSeconds, true, 0)
+	if err != nil {
+		return 0, false, fmt.Errorf("querying %s: %w", rule.MetricName, err)
+	}
+
+	var total float64
+	ok := false
+	for _, series := range resp.Series {
+		for _, point := range series.DataPoints {
+			total += point[1]
+			ok = true
+		}
+	}
+	return total, ok, nil
+}
@@ -0,0 +1,117 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+func setupTestStore(t *testing.T) (*storage.DuckDBStore, func()) {
+	t.Helper()
+	store, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test store: %v", err)
+	}
+	return store, func() { store.Close() }
+}
+
+func TestBreached(t *testing.T) {
+	tests := []struct {
+		comparison string
+		value      float64
+		want       bool
+	}{
+		{">", 6, true},
+		{">", 5, false},
+		{">=", 5, true},
+		{"<", 4, true},
+		{"<", 5, false},
+		{"<=", 5, true},
+		{"==", 5, false},
+	}
+	for _, tt := range tests {
+		if got := Breached(tt.comparison, tt.value, 5); got != tt.want {
+			t.Errorf("Breached(%q, %v, 5) = %v, want %v", tt.comparison, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	delta := int32(1)
+	cost := func(at time.Time, service string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: at, ServiceName: service, MetricName: "claude_code.cost.usage",
+			MetricType: "sum", Value: &v, AggregationTemporality: &delta,
+			Attributes: map[string]string{"model": "sonnet"},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		cost(now.Add(-10*time.Minute), "claude-code", 3.5),
+		cost(now.Add(-5*time.Minute), "claude-code", 2.5),
+		cost(now.Add(-2*time.Hour), "claude-code", 50), // Outside the window
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	costRule, err := store.CreateAlertRule(ctx, &api.AlertRuleRequest{
+		Name: "Cost per hour", MetricName: "claude_code.cost.usage", Comparison: ">", Threshold: 5, WindowSeconds: 3600,
+	})
+	if err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	// Not breached, and a rule on a metric without data never fires
+	for _, req := range []api.AlertRuleRequest{
+		{Name: "Cost per hour (codex)", MetricName: "claude_code.cost.usage", Service: "codex_cli_rs", Comparison: "<", Threshold: 1, WindowSeconds: 3600},
+		{Name: "Cost per minute", MetricName: "claude_code.cost.usage", Comparison: ">", Threshold: 5, WindowSeconds: 60},
+		{Name: "No tokens", MetricName: "missing.metric", Comparison: "<", Threshold: 1, WindowSeconds: 3600},
+	} {
+		if _, err := store.CreateAlertRule(ctx, &req); err != nil {
+			t.Fatalf("CreateAlertRule failed: %v", err)
+		}
+	}
+
+	e := NewEvaluator(store, nil)
+	e.now = func() time.Time { return now }
+
+	fired, err := e.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(fired) != 1 || fired[0].RuleID != costRule.ID || fired[0].Value != 6 || !fired[0].FiredAt.Equal(now) {
+		t.Fatalf("expected the cost rule to fire with 6, got %+v", fired)
+	}
+
+	// Still breached: no new event
+	if fired, err = e.Evaluate(ctx); err != nil || len(fired) != 0 {
+		t.Errorf("expected no new alert while the breach lasts, got %+v, %v", fired, err)
+	}
+
+	// Recovered once the spend leaves the window, then breached again
+	e.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if fired, err = e.Evaluate(ctx); err != nil || len(fired) != 0 {
+		t.Errorf("expected no alert after recovery, got %+v, %v", fired, err)
+	}
+	if err := store.InsertMetrics(ctx, []api.MetricDataPoint{cost(now.Add(2*time.Hour-10*time.Minute), "claude-code", 8)}); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+	if fired, err = e.Evaluate(ctx); err != nil || len(fired) != 1 || fired[0].Value != 8 {
+		t.Errorf("expected the cost rule to fire again with 8, got %+v, %v", fired, err)
+	}
+
+	events, err := store.ListAlertEvents(ctx, "", now.Add(-time.Hour), now.Add(3*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("ListAlertEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Value != 8 || events[1].Value != 6 || events[1].RuleName != "Cost per hour" {
+		t.Errorf("expected both firings to be stored, got %+v", events)
+	}
+}
@@ -269,3 +269,32 @@ func TestErrorResponse_OmitEmptyMessage(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected, string(data))
 	}
 }
+
+func TestWidgetConfigResolve(t *testing.T) {
+	config := WidgetConfig{
+		Service:            "$service",
+		MetricName:         "${prefix}.token.usage",
+		BreakdownAttribute: "type",
+		BreakdownValue:     "$service_type",
+	}
+	got := config.Resolve(map[string]string{
+		"service":      "claude-code",
+		"prefix":       "claude_code",
+		"service_type": "input",
+	})
+
+	want := WidgetConfig{
+		Service:            "claude-code",
+		MetricName:         "claude_code.token.usage",
+		BreakdownAttribute: "type",
+		BreakdownValue:     "input",
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	// Unknown references are kept
+	if got := (WidgetConfig{Service: "$other"}).Resolve(map[string]string{"service": "x"}); got.Service != "$other" {
+		t.Errorf("expected unknown reference to be kept, got %q", got.Service)
+	}
+}
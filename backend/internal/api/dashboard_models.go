@@ -1,6 +1,10 @@
 package api
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 // Dashboard represents a user-defined dashboard
 type Dashboard struct {
@@ -10,6 +14,29 @@ type Dashboard struct {
 	IsDefault   bool      `json:"isDefault"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+
+	Variables []DashboardVariable `json:"variables,omitempty"`
+}
+
+// Dashboard variable types
+const (
+	VariableTypeQuery = "query" // Options come from OptionsQuery
+	VariableTypeText  = "text"  // Free text, starting at Default
+)
+
+// Dashboard variable option queries
+const (
+	VariableOptionsServices    = "services"
+	VariableOptionsMetricNames = "metric_names"
+)
+
+// DashboardVariable is a dashboard-wide value that widget configs reference as $name or
+// ${name}, so one dashboard can be switched between services or metrics
+type DashboardVariable struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Default      string `json:"default,omitempty"`
+	OptionsQuery string `json:"optionsQuery,omitempty"` // services or metric_names; query variables only
 }
 
 // DashboardWidget represents a widget placed on a dashboard
@@ -36,23 +63,70 @@ type WidgetConfig struct {
 	ChartStacked       *bool  `json:"chartStacked,omitempty"`
 }
 
+// Resolve returns the config with $name and ${name} references replaced by values[name].
+// References to names missing from values are left as-is.
+func (c WidgetConfig) Resolve(values map[string]string) WidgetConfig {
+	if len(values) == 0 {
+		return c
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	// The replacer takes the first match at each position, so longer names go first and
+	// $service_name is not read as $service followed by "_name"
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, "${"+name+"}", values[name], "$"+name, values[name])
+	}
+	r := strings.NewReplacer(pairs...)
+
+	c.Service = r.Replace(c.Service)
+	c.MetricName = r.Replace(c.MetricName)
+	c.BreakdownAttribute = r.Replace(c.BreakdownAttribute)
+	c.BreakdownValue = r.Replace(c.BreakdownValue)
+	return c
+}
+
 // DashboardWithWidgets represents a full dashboard with its widgets
 type DashboardWithWidgets struct {
 	Dashboard
 	Widgets []DashboardWidget `json:"widgets"`
 }
 
+// DashboardExportVersion is the version of the DashboardExport format written by exports
+const DashboardExportVersion = 1
+
+// DashboardExport is a self-contained copy of a dashboard and its widgets for moving it to
+// another installation. It carries no ids or timestamps; an import creates fresh ones.
+type DashboardExport struct {
+	Version     int                   `json:"version"`
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Variables   []DashboardVariable   `json:"variables,omitempty"`
+	Widgets     []CreateWidgetRequest `json:"widgets"`
+}
+
 // Request/Response types
 
 type CreateDashboardRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	IsDefault   bool   `json:"isDefault,omitempty"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	IsDefault   bool                `json:"isDefault,omitempty"`
+	Variables   []DashboardVariable `json:"variables,omitempty"`
+}
+
+// CreateDashboardFromTemplateRequest names the built-in template a dashboard is created from
+type CreateDashboardFromTemplateRequest struct {
+	Template string `json:"template"`
 }
 
 type UpdateDashboardRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
+	Name        string              `json:"name,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Variables   []DashboardVariable `json:"variables,omitempty"` // nil keeps the current variables; [] removes them
 }
 
 type CreateWidgetRequest struct {
@@ -87,3 +161,31 @@ type UpdateWidgetPositionsRequest struct {
 type DashboardsResponse struct {
 	Dashboards []Dashboard `json:"dashboards"`
 }
+
+// WidgetDataResponse is a widget's data resolved on the server, with the widget config after
+// dashboard variables were substituted
+type WidgetDataResponse struct {
+	WidgetID string       `json:"widgetId"`
+	Config   WidgetConfig `json:"config"`
+	Series   []TimeSeries `json:"series"`
+}
+
+// VariableOptionsResponse lists the values a query variable can take
+type VariableOptionsResponse struct {
+	Options []string `json:"options"`
+}
+
+// ValidateMetricRequest is a widget metric query checked before it is saved
+type ValidateMetricRequest struct {
+	WidgetConfig
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// ValidateMetricResponse reports whether a widget metric query returns data
+type ValidateMetricResponse struct {
+	Exists      bool   `json:"exists"`
+	MetricType  string `json:"metricType,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	SampleCount int    `json:"sampleCount"`
+}
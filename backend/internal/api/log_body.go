@@ -0,0 +1,24 @@
+package api
+
+// Log body modes accepted by the logs query endpoints
+const (
+	LogBodyNormalized = "normalized" // display body, see LogRecord.NormalizedBody (default)
+	LogBodyRaw        = "raw"        // body exactly as received at ingestion
+)
+
+// NormalizedBody returns the body shown to users. Event-style records that carry their
+// name in the event.name attribute and no body (e.g. the tracing crate's
+// OpenTelemetryTracingBridge) are displayed by event name. The stored Body is never changed.
+func (l *LogRecord) NormalizedBody() string {
+	if l.Body == "" {
+		return l.LogAttributes["event.name"]
+	}
+	return l.Body
+}
+
+// NormalizeLogBodies replaces each record's Body with its normalized form for display
+func NormalizeLogBodies(logs []LogRecord) {
+	for i := range logs {
+		logs[i].Body = logs[i].NormalizedBody()
+	}
+}
@@ -26,12 +26,19 @@ type Span struct {
 	ResourceAttributes map[string]string `json:"resourceAttributes,omitempty"`
 	ScopeName          string            `json:"scopeName,omitempty"`
 	ScopeVersion       string            `json:"scopeVersion,omitempty"`
+	ScopeAttributes    map[string]string `json:"scopeAttributes,omitempty"`
 	SpanAttributes     map[string]string `json:"spanAttributes,omitempty"`
 	Duration           int64             `json:"duration"`
 	StatusCode         string            `json:"statusCode,omitempty"`
 	StatusMessage      string            `json:"statusMessage,omitempty"`
 	Events             []SpanEvent       `json:"events,omitempty"`
 	Links              []SpanLink        `json:"links,omitempty"`
+
+	// Attributes, events and links discarded by the SDK before export; attributes removed by
+	// the ingestion attribute limit are added to DroppedAttributesCount
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount,omitempty"`
+	DroppedEventsCount     uint32 `json:"droppedEventsCount,omitempty"`
+	DroppedLinksCount      uint32 `json:"droppedLinksCount,omitempty"`
 }
 
 type SpanEvent struct {
@@ -64,6 +71,8 @@ type LogRecord struct {
 	ScopeVersion       string            `json:"scopeVersion,omitempty"`
 	ScopeAttributes    map[string]string `json:"scopeAttributes,omitempty"`
 	LogAttributes      map[string]string `json:"logAttributes,omitempty"`
+
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount,omitempty"` // Attributes discarded by the SDK or the ingestion attribute limit
 }
 
 // Metric represents a metric data point
@@ -76,6 +85,7 @@ type MetricDataPoint struct {
 	ResourceAttributes     map[string]string `json:"resourceAttributes,omitempty"`
 	ScopeName              string            `json:"scopeName,omitempty"`
 	ScopeVersion           string            `json:"scopeVersion,omitempty"`
+	ScopeAttributes        map[string]string `json:"scopeAttributes,omitempty"`
 	Attributes             map[string]string `json:"attributes,omitempty"`
 	MetricType             string            `json:"metricType"`
 	Value                  *float64          `json:"value,omitempty"`
@@ -99,19 +109,37 @@ type MetricDataPoint struct {
 
 // Query response types
 type TracesResponse struct {
-	Traces  []TraceOverview `json:"traces"`
-	Total   int             `json:"total"`
-	HasMore bool            `json:"hasMore"`
+	Traces     []TraceOverview `json:"traces"`
+	Total      int             `json:"total"`
+	HasMore    bool            `json:"hasMore"`
+	NextCursor string          `json:"nextCursor,omitempty"` // Continues in the requested direction when HasMore
 }
 
 type SpansResponse struct {
 	Spans []Span `json:"spans"`
 }
 
+// TraceSessionResponse links a trace to its session and that session's logs
+type TraceSessionResponse struct {
+	TraceID   string      `json:"traceId"`
+	SessionID string      `json:"sessionId,omitempty"` // Empty when no session could be correlated
+	Method    string      `json:"method,omitempty"`    // attribute, trace_id or proximity
+	Logs      []LogRecord `json:"logs"`
+}
+
+// CorrelationReport summarizes trace/log correlation gaps in a time range
+type CorrelationReport struct {
+	TraceCount        int64 `json:"traceCount"`        // Distinct traces in range
+	TracesWithoutLogs int64 `json:"tracesWithoutLogs"` // Traces with no log referencing their TraceId
+	LogsWithTraceID   int64 `json:"logsWithTraceId"`   // Logs in range that carry a TraceId
+	OrphanedLogs      int64 `json:"orphanedLogs"`      // Logs whose TraceId matches no stored span
+}
+
 type LogsResponse struct {
-	Logs    []LogRecord `json:"logs"`
-	Total   int         `json:"total"`
-	HasMore bool        `json:"hasMore"`
+	Logs       []LogRecord `json:"logs"`
+	Total      int         `json:"total"`
+	HasMore    bool        `json:"hasMore"`
+	NextCursor string      `json:"nextCursor,omitempty"` // Continues in the requested direction when HasMore
 }
 
 type MetricsResponse struct {
@@ -127,7 +155,31 @@ type TimeSeries struct {
 }
 
 type TimeSeriesResponse struct {
-	Series []TimeSeries `json:"series"`
+	Series    []TimeSeries `json:"series"`
+	Truncated bool         `json:"truncated,omitempty"` // More series matched than the grouped query's maxSeries
+}
+
+// MetricDeltaResponse is the change of a metric between two times
+type MetricDeltaResponse struct {
+	Name  string    `json:"name"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Delta float64   `json:"delta"`
+}
+
+// HistogramBucket is one bucket of a histogram distribution, covering values above the
+// previous bucket's UpperBound
+type HistogramBucket struct {
+	UpperBound *float64 `json:"upperBound"` // nil (null) for the +Inf overflow bucket
+	Count      uint64   `json:"count"`
+}
+
+// MetricHistogramResponse is the bucket distribution of a histogram metric over a time range
+type MetricHistogramResponse struct {
+	Name    string            `json:"name"`
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Buckets []HistogramBucket `json:"buckets"`
 }
 
 // Batch metric series request/response types
@@ -146,6 +198,8 @@ type MetricQuery struct {
 	Name      string `json:"name"`
 	Service   string `json:"service,omitempty"`
 	Aggregate bool   `json:"aggregate,omitempty"`
+	// Quantile in (0, 1] returns quantiles reconstructed from exponential histogram buckets
+	Quantile float64 `json:"quantile,omitempty"`
 }
 
 // BatchMetricSeriesResponse contains results for all queried metrics
@@ -161,18 +215,339 @@ type MetricQueryResult struct {
 	Series  []TimeSeries `json:"series,omitempty"`
 }
 
+// CountResponse is returned by the count-only query endpoints
+type CountResponse struct {
+	Count int `json:"count"`
+}
+
 type StatsResponse struct {
-	TraceCount   int64    `json:"traceCount"`
-	SpanCount    int64    `json:"spanCount"`
-	LogCount     int64    `json:"logCount"`
-	MetricCount  int64    `json:"metricCount"`
-	ServiceCount int      `json:"serviceCount"`
-	Services     []string `json:"services"`
-	ErrorRate    float64  `json:"errorRate"`
+	TraceCount     int64    `json:"traceCount"`
+	SpanCount      int64    `json:"spanCount"`
+	LogCount       int64    `json:"logCount"`
+	MetricCount    int64    `json:"metricCount"`
+	ServiceCount   int      `json:"serviceCount"`
+	Services       []string `json:"services"`
+	ErrorRate      float64  `json:"errorRate"`
+	Env            string   `json:"env,omitempty"`
+	DroppedMetrics int64    `json:"droppedMetrics,omitempty"` // Metric points dropped by the ingestion allowlist/denylist
+	SampledLogs    int64    `json:"sampledLogs,omitempty"`    // Low-severity logs dropped by ingestion sampling since startup
+
+	TruncatedAttributes int64 `json:"truncatedAttributes,omitempty"` // Attributes removed by the ingestion attribute limit since startup
+	IgnoredRequests     int64 `json:"ignoredRequests,omitempty"`     // OTLP requests for disabled signals acknowledged without storing since startup
+
+	Ingest map[string]IngestCounters `json:"ingest,omitempty"` // Per-service OTLP write counters since startup
+	Dedup  *DedupStats               `json:"dedup,omitempty"`  // Set when ingestion deduplication is enabled
+
+	Extrapolated bool `json:"extrapolated,omitempty"` // Span and trace counts are scaled by their sampling probability
+}
+
+// IngestCounters tracks the OTLP writes made for one service
+type IngestCounters struct {
+	Requests    int64 `json:"requests"`
+	Records     int64 `json:"records"`
+	QueueWaitMs int64 `json:"queueWaitMs"` // Total time spent waiting for a fair write slot
+}
+
+// DedupStats reports the OTLP export requests checked by ingestion deduplication since startup
+type DedupStats struct {
+	WindowSeconds float64 `json:"windowSeconds"`
+	Hits          int64   `json:"hits"`    // Duplicate requests acknowledged without storing
+	Misses        int64   `json:"misses"`  // Requests stored
+	Tracked       int     `json:"tracked"` // Requests currently remembered within the window
+}
+
+// VersionResponse identifies the running build
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+}
+
+// DerivePreviewResponse lists the metrics ingestion would derive from the stored source
+// metrics of a time range, without storing them
+type DerivePreviewResponse struct {
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	SourceMetrics int               `json:"sourceMetrics"` // Stored points read by the derivation
+	Counts        map[string]int    `json:"counts"`        // Derived points per metric name
+	Metrics       []MetricDataPoint `json:"metrics"`
+}
+
+// IngestState reports whether OTLP ingestion is paused
+type IngestState struct {
+	Paused         bool `json:"paused"`
+	PendingBatches int  `json:"pendingBatches"` // Batches waiting for async storage
+}
+
+// ServiceSummary aggregates one service's telemetry in a time range
+type ServiceSummary struct {
+	Service       string             `json:"service"`
+	From          time.Time          `json:"from"`
+	To            time.Time          `json:"to"`
+	TraceCount    int64              `json:"traceCount"`
+	SpanCount     int64              `json:"spanCount"`
+	LogCount      int64              `json:"logCount"`
+	MetricCount   int64              `json:"metricCount"`
+	ErrorCount    int64              `json:"errorCount"` // Spans with ERROR status
+	ErrorRate     float64            `json:"errorRate"`  // Percentage of spans with ERROR status
+	Latency       LatencyPercentiles `json:"latency"`
+	TopOperations []OperationSummary `json:"topOperations"`
+	CostUSD       float64            `json:"costUsd"`                // Sum of *.cost.usage metrics in range
+	Cost          *float64           `json:"cost,omitempty"`         // CostUSD in Currency.Code, when configured
+	Currency      *CostCurrency      `json:"currency,omitempty"`     // Display currency, when configured
+	Extrapolated  bool               `json:"extrapolated,omitempty"` // Span, trace and operation counts are scaled by their sampling probability
+}
+
+// OverviewResponse composes the home dashboard's data in one payload. Stats are all-time;
+// the error count, top models and log levels cover the time since Since.
+type OverviewResponse struct {
+	Since            time.Time        `json:"since"`
+	Stats            *StatsResponse   `json:"stats"`
+	RecentErrorCount int64            `json:"recentErrorCount"`    // Spans with ERROR status since Since
+	TodayCostUSD     float64          `json:"todayCostUsd"`        // Sum of *.cost.usage metrics since local midnight
+	TopModels        []ModelCost      `json:"topModels"`           // Models with the highest cost since Since
+	LogLevels        map[string]int64 `json:"logLevels"`           // Log counts per severity since Since
+	TodayCost        *float64         `json:"todayCost,omitempty"` // TodayCostUSD in Currency.Code, when configured
+	Currency         *CostCurrency    `json:"currency,omitempty"`  // Display currency, when configured
+}
+
+// CostCurrency is the display currency costs are converted to. Costs are stored in USD;
+// converted values are costUsd * Rate.
+type CostCurrency struct {
+	Code string  `json:"code"`
+	Rate float64 `json:"rate"` // Units of Code per USD
+}
+
+// ModelCost is the cost attributed to one model
+type ModelCost struct {
+	Model   string   `json:"model"`
+	CostUSD float64  `json:"costUsd"`
+	Cost    *float64 `json:"cost,omitempty"` // CostUSD in the display currency, when configured
+}
+
+// ModelCostResponse is the cost per model between two times
+type ModelCostResponse struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Models   []ModelCost   `json:"models"`
+	Currency *CostCurrency `json:"currency,omitempty"` // Display currency, when configured
+}
+
+// ProjectCost is the cost attributed to one project (working directory)
+type ProjectCost struct {
+	Project string   `json:"project"`
+	CostUSD float64  `json:"costUsd"`
+	Cost    *float64 `json:"cost,omitempty"` // CostUSD in the display currency, when configured
+}
+
+// ProjectCostResponse is the cost per project between two times
+type ProjectCostResponse struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Projects []ProjectCost `json:"projects"`
+	Currency *CostCurrency `json:"currency,omitempty"` // Display currency, when configured
+}
+
+// ScatterPoint pairs a trace's duration with the cost correlated to it
+type ScatterPoint struct {
+	TraceID     string    `json:"traceId"`
+	ServiceName string    `json:"serviceName"`
+	RootSpan    string    `json:"rootSpan"`
+	SessionID   string    `json:"sessionId"`
+	StartTime   time.Time `json:"startTime"`
+	Duration    int64     `json:"duration"` // Nanoseconds
+	CostUSD     float64   `json:"costUsd"`
+	Cost        *float64  `json:"cost,omitempty"` // CostUSD in the display currency, when configured
+}
+
+// LatencyCostResponse is the latency/cost scatter of traces between two times
+type LatencyCostResponse struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Points   []ScatterPoint `json:"points"`
+	Currency *CostCurrency  `json:"currency,omitempty"` // Display currency, when configured
+}
+
+// PromptCost is the cost of user prompts sharing one normalized pattern
+type PromptCost struct {
+	Pattern  string    `json:"pattern"`
+	Count    int64     `json:"count"`
+	Example  string    `json:"example"` // Most recent prompt with this pattern
+	LastSeen time.Time `json:"lastSeen"`
+	CostUSD  float64   `json:"costUsd"`
+	Cost     *float64  `json:"cost,omitempty"` // CostUSD in the display currency, when configured
+}
+
+// PromptCostResponse is the cost per prompt pattern between two times
+type PromptCostResponse struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Prompts  []PromptCost  `json:"prompts"`
+	Currency *CostCurrency `json:"currency,omitempty"` // Display currency, when configured
+}
+
+// SessionUsage is the token usage and cost of a session, in total or for one model
+type SessionUsage struct {
+	Model        string   `json:"model,omitempty"`
+	InputTokens  int64    `json:"inputTokens"`
+	OutputTokens int64    `json:"outputTokens"`
+	CacheRead    int64    `json:"cacheRead"`
+	CacheWrite   int64    `json:"cacheWrite"`
+	CostUSD      float64  `json:"costUsd"`
+	Cost         *float64 `json:"cost,omitempty"` // CostUSD in the display currency, when configured
+}
+
+// SessionCostResponse is the token usage and cost of one session
+type SessionCostResponse struct {
+	SessionID string         `json:"sessionId"`
+	Source    string         `json:"source"` // "metrics" or "logs", whichever the totals were computed from
+	Total     SessionUsage   `json:"total"`
+	Models    []SessionUsage `json:"models"`
+	Currency  *CostCurrency  `json:"currency,omitempty"` // Display currency, when configured
+}
+
+// AttrCardinality is how many distinct values an attribute key has in a time range
+type AttrCardinality struct {
+	Key            string `json:"key"`
+	DistinctValues int64  `json:"distinctValues"`
+	Occurrences    int64  `json:"occurrences"` // Records carrying the key
+}
+
+// AttrCardinalityResponse lists attribute keys of one signal, highest cardinality first
+type AttrCardinalityResponse struct {
+	Signal     string            `json:"signal"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Attributes []AttrCardinality `json:"attributes"`
+}
+
+// LatencyPercentiles are span duration percentiles in nanoseconds
+type LatencyPercentiles struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+}
+
+// ServiceLatency holds a service's span duration percentiles in nanoseconds
+type ServiceLatency struct {
+	Service    string `json:"service"`
+	SpanCount  int64  `json:"spanCount"`
+	TraceCount int64  `json:"traceCount"` // Counted like /api/traces, with Codex CLI virtual traces
+	P50        int64  `json:"p50"`
+	P95        int64  `json:"p95"`
+	P99        int64  `json:"p99"`
+	Max        int64  `json:"max"`
+}
+
+// ServiceLatencyResponse lists span latency per service, slowest p95 first
+type ServiceLatencyResponse struct {
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Services []ServiceLatency `json:"services"`
+}
+
+// ServiceGraphNode is a service in the service graph
+type ServiceGraphNode struct {
+	Service    string `json:"service"`
+	Group      string `json:"group,omitempty"` // Value of the groupBy resource attribute, when requested and set
+	SpanCount  int64  `json:"spanCount"`
+	ErrorCount int64  `json:"errorCount"`
+}
+
+// ServiceGraphEdge counts the spans of Target whose parent span belongs to Source
+type ServiceGraphEdge struct {
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	CallCount  int64  `json:"callCount"`
+	ErrorCount int64  `json:"errorCount"`
+}
+
+// ServiceGraphResponse is the graph of services and the calls between them
+type ServiceGraphResponse struct {
+	From    time.Time          `json:"from"`
+	To      time.Time          `json:"to"`
+	GroupBy string             `json:"groupBy,omitempty"`
+	Nodes   []ServiceGraphNode `json:"nodes"`
+	Edges   []ServiceGraphEdge `json:"edges"`
+}
+
+// OperationSummary aggregates the spans sharing a span name
+type OperationSummary struct {
+	Name        string `json:"name"`
+	Count       int64  `json:"count"`
+	ErrorCount  int64  `json:"errorCount"`
+	AvgDuration int64  `json:"avgDuration"` // Nanoseconds
+}
+
+// DroppedDataResponse reports how much data OTLP SDKs discarded before export, per service
+type DroppedDataResponse struct {
+	Services []ServiceDroppedCounts `json:"services"`
+}
+
+// ServiceDroppedCounts sums the dropped counts reported on one service's spans and logs
+type ServiceDroppedCounts struct {
+	ServiceName           string `json:"serviceName"`
+	SpansWithDrops        int64  `json:"spansWithDrops"`
+	SpanDroppedAttributes int64  `json:"spanDroppedAttributes"`
+	SpanDroppedEvents     int64  `json:"spanDroppedEvents"`
+	SpanDroppedLinks      int64  `json:"spanDroppedLinks"`
+	LogsWithDrops         int64  `json:"logsWithDrops"`
+	LogDroppedAttributes  int64  `json:"logDroppedAttributes"`
 }
 
 type ServicesResponse struct {
 	Services []string `json:"services"`
+	// Groups maps services to their configured display group; omitted when no groups are configured
+	Groups map[string]string `json:"groups,omitempty"`
+}
+
+// StorageSample is a point-in-time measurement of database size and table row counts
+type StorageSample struct {
+	Timestamp time.Time        `json:"timestamp"`
+	SizeBytes int64            `json:"sizeBytes"`
+	Rows      map[string]int64 `json:"rows"`
+}
+
+// StorageGrowth is the change in storage between two samples
+type StorageGrowth struct {
+	From         time.Time        `json:"from"`
+	To           time.Time        `json:"to"`
+	SizeBytes    int64            `json:"sizeBytes"`
+	BytesPerHour float64          `json:"bytesPerHour"`
+	Rows         map[string]int64 `json:"rows"`
+}
+
+// StorageUsageResponse reports current storage usage and growth over recent samples
+type StorageUsageResponse struct {
+	Current StorageSample   `json:"current"`
+	Samples []StorageSample `json:"samples"`
+	Growth  *StorageGrowth  `json:"growth,omitempty"` // nil until at least one earlier sample exists
+}
+
+// DataTimeRangeResponse is the span of timestamps covered by stored telemetry
+type DataTimeRangeResponse struct {
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// IngestUploadResponse reports the records stored from a bulk upload
+type IngestUploadResponse struct {
+	Format  string `json:"format"` // jsonl, parquet or zip
+	Spans   int64  `json:"spans"`
+	Logs    int64  `json:"logs"`
+	Metrics int64  `json:"metrics"`
+}
+
+// ScopeInfo describes an instrumentation scope seen for a service
+type ScopeInfo struct {
+	ServiceName  string   `json:"serviceName"`
+	ScopeName    string   `json:"scopeName"`
+	ScopeVersion string   `json:"scopeVersion,omitempty"`
+	Signals      []string `json:"signals"` // traces, logs, metrics
+}
+
+type ScopesResponse struct {
+	Scopes []ScopeInfo `json:"scopes"`
 }
 
 type MetricNamesResponse struct {
@@ -183,6 +558,37 @@ type BreakdownValuesResponse struct {
 	Values []string `json:"values"`
 }
 
+// BreakdownRow is the aggregated value of a metric for one stored attribute value
+type BreakdownRow struct {
+	Value string  `json:"value"`
+	Total float64 `json:"total"`
+}
+
+// BreakdownBucket is the aggregated value of a metric for a single attribute value
+type BreakdownBucket struct {
+	Value     string   `json:"value"`
+	Total     float64  `json:"total"`
+	RawValues []string `json:"rawValues,omitempty"` // Stored values folded into this bucket by model aliases
+}
+
+type BreakdownResponse struct {
+	Attribute string            `json:"attribute"`
+	Buckets   []BreakdownBucket `json:"buckets"`
+}
+
+// MetricRow is the latest value of a single metric series (one distinct attribute set)
+type MetricRow struct {
+	ServiceName string            `json:"serviceName"`
+	Attributes  map[string]string `json:"attributes"`
+	Value       *float64          `json:"value"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
+
+type MetricTableResponse struct {
+	Columns []string    `json:"columns"` // Sorted union of attribute keys across rows
+	Rows    []MetricRow `json:"rows"`
+}
+
 // Session represents a conversation session summary
 type Session struct {
 	SessionID    string    `json:"sessionId"`
@@ -191,8 +597,16 @@ type Session struct {
 	LastTime     time.Time `json:"lastTime"`
 	MessageCount int       `json:"messageCount"`
 	Model        string    `json:"model,omitempty"`
+	Status       string    `json:"status"`            // SessionActive or SessionCompleted
+	Preview      string    `json:"preview,omitempty"` // Start of the first user prompt, with ?preview=true
 }
 
+// Session statuses, derived from the time since a session's last log
+const (
+	SessionActive    = "active"
+	SessionCompleted = "completed"
+)
+
 // SessionsResponse for listing sessions
 type SessionsResponse struct {
 	Sessions []Session `json:"sessions"`
@@ -210,6 +624,7 @@ type TranscriptMessage struct {
 	ToolName     string    `json:"toolName,omitempty"`
 	ToolInput    string    `json:"toolInput,omitempty"`
 	ToolOutput   string    `json:"toolOutput,omitempty"`   // Tool execution output (from imports)
+	ToolCallID   string    `json:"toolCallId,omitempty"`   // Shared by a tool_use and its tool_result
 	InputTokens  int       `json:"inputTokens,omitempty"`  // Input token count
 	OutputTokens int       `json:"outputTokens,omitempty"` // Output token count
 	CacheRead    int       `json:"cacheRead,omitempty"`    // Cache read tokens
@@ -228,3 +643,103 @@ type TranscriptResponse struct {
 	LastTime    time.Time           `json:"lastTime"`
 	Messages    []TranscriptMessage `json:"messages"`
 }
+
+// Annotation target types
+const (
+	AnnotationTargetTrace = "trace"
+	AnnotationTargetLog   = "log"
+)
+
+// Annotation is a note and tags a user attached to a trace or log, e.g. while analyzing an
+// incident. Log targets are identified by LogAnnotationTarget.
+type Annotation struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"targetType"`
+	TargetID   string    `json:"targetId"`
+	Note       string    `json:"note,omitempty"`
+	Tags       []string  `json:"tags"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// LogAnnotationTarget returns the target ID of a log annotation: the log's service name and
+// timestamp in UTC with nanoseconds, e.g. "claude-code@2026-01-02T15:04:05.123456Z"
+func LogAnnotationTarget(serviceName string, timestamp time.Time) string {
+	return serviceName + "@" + timestamp.UTC().Format(time.RFC3339Nano)
+}
+
+// CreateAnnotationRequest is the body of POST /api/traces/{traceId}/annotations
+type CreateAnnotationRequest struct {
+	Note string   `json:"note"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// CreateLogAnnotationRequest is the body of POST /api/logs/annotations, naming the log by its
+// service and timestamp
+type CreateLogAnnotationRequest struct {
+	ServiceName string    `json:"serviceName"`
+	Timestamp   time.Time `json:"timestamp"`
+	Note        string    `json:"note"`
+	Tags        []string  `json:"tags,omitempty"`
+}
+
+// AnnotationsResponse lists annotations, newest first
+type AnnotationsResponse struct {
+	Annotations []Annotation `json:"annotations"`
+}
+
+// Alert rule comparisons between a metric's aggregate and the rule threshold
+const (
+	AlertComparisonAbove        = ">"
+	AlertComparisonAboveOrEqual = ">="
+	AlertComparisonBelow        = "<"
+	AlertComparisonBelowOrEqual = "<="
+)
+
+// AlertRule fires when the aggregate of a metric over the trailing window crosses the
+// threshold, e.g. claude_code.cost.usage > 5 over 3600 seconds for "cost per hour > $5"
+type AlertRule struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	MetricName    string    `json:"metricName"`
+	Service       string    `json:"service,omitempty"` // Empty matches all services
+	Comparison    string    `json:"comparison"`
+	Threshold     float64   `json:"threshold"`
+	WindowSeconds int64     `json:"windowSeconds"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// AlertRuleRequest is the body of POST /api/alerts/rules and PUT /api/alerts/rules/{id}
+type AlertRuleRequest struct {
+	Name          string  `json:"name"`
+	MetricName    string  `json:"metricName"`
+	Service       string  `json:"service,omitempty"`
+	Comparison    string  `json:"comparison"`
+	Threshold     float64 `json:"threshold"`
+	WindowSeconds int64   `json:"windowSeconds"`
+}
+
+// AlertRulesResponse lists alert rules by name
+type AlertRulesResponse struct {
+	Rules []AlertRule `json:"rules"`
+}
+
+// AlertEvent records a rule firing. The rule is copied as it was when it fired, so events
+// stay readable after the rule is changed or deleted.
+type AlertEvent struct {
+	ID            string    `json:"id"`
+	RuleID        string    `json:"ruleId"`
+	RuleName      string    `json:"ruleName"`
+	MetricName    string    `json:"metricName"`
+	Service       string    `json:"service,omitempty"`
+	Comparison    string    `json:"comparison"`
+	Threshold     float64   `json:"threshold"`
+	WindowSeconds int64     `json:"windowSeconds"`
+	Value         float64   `json:"value"`
+	FiredAt       time.Time `json:"firedAt"`
+}
+
+// AlertEventsResponse lists fired alerts, newest first
+type AlertEventsResponse struct {
+	Events []AlertEvent `json:"events"`
+}
@@ -1,28 +1,169 @@
 package config
 
 import (
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	// Server ports
-	OTLPPort int
-	APIPort  int
+	// Server ports (OTLPGRPCPort 0 disables OTLP/gRPC)
+	OTLPPort     int
+	OTLPGRPCPort int
+	APIPort      int
 
 	// Database
 	DatabasePath string
 
 	// Frontend
 	FrontendURL string
+
+	// Environment label (e.g. "prod", "staging") surfaced in API responses
+	EnvLabel string
+
+	// Metric name glob patterns applied at ingestion (empty allowlist allows all)
+	MetricAllowlist []string
+	MetricDenylist  []string
+
+	// Model name aliases ("pattern=canonical") applied when grouping by model at query time
+	ModelAliases []string
+
+	// Service group rules ("pattern=group") reported by /api/services for grouping/coloring
+	ServiceGroups []string
+
+	// DuckDB SET/PRAGMA statements applied after the store is opened
+	DBPragmas []string
+
+	// Endpoint path patterns rejected at routing time, and the status returned for them
+	DisabledEndpoints      []string
+	DisabledEndpointStatus int
+
+	// Per-service "service=weight" rules for sharing the ingestion write path
+	IngestWeights []string
+
+	// Maximum entries kept per attribute map of ingested and imported records (0 = unlimited)
+	MaxAttributes int
+
+	// Resolution metric timestamps are truncated to at ingestion, keeping the last point per
+	// series and bucket (0 = full precision)
+	MetricTimestampResolution time.Duration
+
+	// Time without new logs after which a session is reported as completed
+	SessionIdleTimeout time.Duration
+
+	// OTLP signals stored at ingestion: traces, metrics, logs (empty = all)
+	IngestSignals []string
+
+	// Share of TRACE, DEBUG and INFO logs kept at ingestion (0-1); WARN and above are always kept
+	LogSampleInfo float64
+
+	// Acknowledge OTLP batches once decoded and store them in the background
+	AsyncIngest bool
+
+	// Buffer the rows of async OTLP batches and commit them in one transaction once this many
+	// rows are pending (0 disables batching; enabling it implies AsyncIngest) or every
+	// IngestBatchInterval
+	IngestBatchRows     int
+	IngestBatchInterval time.Duration
+
+	// Dashboard returned by /api/dashboards/default when none is flagged as default:
+	// "latest" (the most recently updated one) or "none"
+	DashboardFallback string
+
+	// Maximum number of dashboards and of widgets per dashboard (0 = unlimited); creating
+	// more is rejected with 409
+	MaxDashboards          int
+	MaxWidgetsPerDashboard int
+
+	// Display currency for costs and its exchange rate in units per USD; costs are stored in USD
+	Currency     string
+	ExchangeRate float64
+
+	// Maximum concurrent /api queries (0 = unlimited) and how long excess queries wait for
+	// a slot before being rejected with 503
+	MaxConcurrentQueries int
+	QueryQueueTimeout    time.Duration
+
+	// Tools whose local session files are imported live as they change (claude-code, codex,
+	// gemini or all; empty = off)
+	WatchImport []string
+
+	// Tools whose local session files are imported once while the server starts, before it
+	// serves requests (same values as WatchImport; empty = off)
+	ImportOnStart []string
+
+	// Days of telemetry kept before the retention worker deletes it (0 disables)
+	RetentionDays int
+
+	// How often alert rules are evaluated (0 disables the evaluator)
+	AlertInterval time.Duration
+
+	// Minimum size in bytes of API responses compressed with gzip or zstd (0 disables)
+	CompressMinBytes int
+
+	// Window within which identical OTLP export requests are stored only once (0 disables)
+	DedupWindow time.Duration
+
+	// Storage usage sampling for /api/self/storage
+	StorageSampleInterval time.Duration
+	StorageSamples        int
 }
 
 func Load() *Config {
 	return &Config{
 		OTLPPort:     getEnvInt("AI_OBSERVER_OTLP_PORT", 4318),
+		OTLPGRPCPort: getEnvInt("AI_OBSERVER_OTLP_GRPC_PORT", 4317),
 		APIPort:      getEnvInt("AI_OBSERVER_API_PORT", 8080),
 		DatabasePath: getEnv("AI_OBSERVER_DATABASE_PATH", "./data/ai-observer.duckdb"),
 		FrontendURL:  getEnv("AI_OBSERVER_FRONTEND_URL", "http://localhost:5173"),
+		EnvLabel:     getEnv("AI_OBSERVER_ENV_LABEL", ""),
+
+		MetricAllowlist: getEnvList("AI_OBSERVER_METRIC_ALLOWLIST"),
+		MetricDenylist:  getEnvList("AI_OBSERVER_METRIC_DENYLIST"),
+		ModelAliases:    getEnvList("AI_OBSERVER_MODEL_ALIASES"),
+		ServiceGroups:   getEnvList("AI_OBSERVER_SERVICE_GROUPS"),
+		DBPragmas:       splitEnv("AI_OBSERVER_DB_PRAGMAS", ";"),
+
+		DisabledEndpoints:      getEnvList("AI_OBSERVER_DISABLED_ENDPOINTS"),
+		DisabledEndpointStatus: getEnvStatus("AI_OBSERVER_DISABLED_ENDPOINT_STATUS"),
+
+		IngestWeights: getEnvList("AI_OBSERVER_INGEST_WEIGHTS"),
+		IngestSignals: getEnvList("AI_OBSERVER_INGEST_SIGNALS"),
+		AsyncIngest:   getEnvBool("AI_OBSERVER_ASYNC_INGEST"),
+		LogSampleInfo: getEnvFloat("AI_OBSERVER_LOG_SAMPLE_INFO", 1),
+		MaxAttributes: getEnvInt("AI_OBSERVER_MAX_ATTRS", 128),
+
+		IngestBatchRows:     getEnvInt("AI_OBSERVER_INGEST_BATCH_ROWS", 0),
+		IngestBatchInterval: time.Duration(getEnvInt("AI_OBSERVER_INGEST_BATCH_INTERVAL_MS", 1000)) * time.Millisecond,
+
+		MetricTimestampResolution: getEnvDuration("AI_OBSERVER_METRIC_TS_RESOLUTION", 0),
+		SessionIdleTimeout:        getEnvDuration("AI_OBSERVER_SESSION_IDLE_TIMEOUT", 30*time.Minute),
+
+		DashboardFallback:      strings.ToLower(getEnv("AI_OBSERVER_DASHBOARD_FALLBACK", "latest")),
+		MaxDashboards:          getEnvInt("AI_OBSERVER_MAX_DASHBOARDS", 0),
+		MaxWidgetsPerDashboard: getEnvInt("AI_OBSERVER_MAX_WIDGETS_PER_DASHBOARD", 0),
+
+		MaxConcurrentQueries: getEnvInt("AI_OBSERVER_MAX_CONCURRENT_QUERIES", 0),
+		QueryQueueTimeout:    getEnvDuration("AI_OBSERVER_QUERY_QUEUE_TIMEOUT", 5*time.Second),
+
+		WatchImport:   getEnvList("AI_OBSERVER_WATCH_IMPORT"),
+		ImportOnStart: getEnvList("AI_OBSERVER_IMPORT_ON_START"),
+
+		RetentionDays: getEnvInt("AI_OBSERVER_RETENTION_DAYS", 0),
+
+		AlertInterval: time.Duration(getEnvInt("AI_OBSERVER_ALERT_INTERVAL", 60)) * time.Second,
+
+		CompressMinBytes: getEnvInt("AI_OBSERVER_COMPRESS_MIN_BYTES", 1024),
+
+		DedupWindow: getEnvDuration("AI_OBSERVER_DEDUP_WINDOW", 0),
+
+		Currency:     getEnv("AI_OBSERVER_CURRENCY", "USD"),
+		ExchangeRate: getEnvFloat("AI_OBSERVER_EXCHANGE_RATE", 1),
+
+		StorageSampleInterval: time.Duration(getEnvInt("AI_OBSERVER_STORAGE_SAMPLE_INTERVAL", 300)) * time.Second,
+		StorageSamples:        getEnvInt("AI_OBSERVER_STORAGE_SAMPLES", 288),
 	}
 }
 
@@ -41,3 +182,51 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvFloat parses a float environment variable, returning defaultValue when unset or invalid
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration parses a Go duration such as "1s", returning defaultValue when unset, invalid
+// or negative
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d < 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvBool reports whether the environment variable is set to a true value such as "1" or "true"
+func getEnvBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
+}
+
+// getEnvStatus returns the status for disabled endpoints: 403 if configured, otherwise 404
+func getEnvStatus(key string) int {
+	if getEnvInt(key, http.StatusNotFound) == http.StatusForbidden {
+		return http.StatusForbidden
+	}
+	return http.StatusNotFound
+}
+
+// getEnvList parses a comma-separated environment variable, dropping empty entries
+func getEnvList(key string) []string {
+	return splitEnv(key, ",")
+}
+
+// splitEnv splits an environment variable on sep, trimming and dropping empty entries
+func splitEnv(key, sep string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
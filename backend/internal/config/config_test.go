@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -11,6 +12,9 @@ func TestLoad_Defaults(t *testing.T) {
 	os.Unsetenv("AI_OBSERVER_API_PORT")
 	os.Unsetenv("AI_OBSERVER_DATABASE_PATH")
 	os.Unsetenv("AI_OBSERVER_FRONTEND_URL")
+	os.Unsetenv("AI_OBSERVER_ENV_LABEL")
+	os.Unsetenv("AI_OBSERVER_METRIC_ALLOWLIST")
+	os.Unsetenv("AI_OBSERVER_METRIC_DENYLIST")
 
 	cfg := Load()
 
@@ -26,6 +30,15 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.FrontendURL != "http://localhost:5173" {
 		t.Errorf("FrontendURL = %s, want http://localhost:5173", cfg.FrontendURL)
 	}
+	if cfg.EnvLabel != "" {
+		t.Errorf("EnvLabel = %s, want empty", cfg.EnvLabel)
+	}
+	if cfg.MetricAllowlist != nil || cfg.MetricDenylist != nil {
+		t.Errorf("metric filter lists = %v / %v, want nil", cfg.MetricAllowlist, cfg.MetricDenylist)
+	}
+	if cfg.StorageSampleInterval != 5*time.Minute || cfg.StorageSamples != 288 {
+		t.Errorf("storage sampling = %v / %d, want 5m0s / 288", cfg.StorageSampleInterval, cfg.StorageSamples)
+	}
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -33,11 +46,13 @@ func TestLoad_CustomValues(t *testing.T) {
 	os.Setenv("AI_OBSERVER_API_PORT", "3000")
 	os.Setenv("AI_OBSERVER_DATABASE_PATH", "/custom/path.duckdb")
 	os.Setenv("AI_OBSERVER_FRONTEND_URL", "https://example.com")
+	os.Setenv("AI_OBSERVER_ENV_LABEL", "prod")
 	defer func() {
 		os.Unsetenv("AI_OBSERVER_OTLP_PORT")
 		os.Unsetenv("AI_OBSERVER_API_PORT")
 		os.Unsetenv("AI_OBSERVER_DATABASE_PATH")
 		os.Unsetenv("AI_OBSERVER_FRONTEND_URL")
+		os.Unsetenv("AI_OBSERVER_ENV_LABEL")
 	}()
 
 	cfg := Load()
@@ -54,6 +69,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	if cfg.FrontendURL != "https://example.com" {
 		t.Errorf("FrontendURL = %s, want https://example.com", cfg.FrontendURL)
 	}
+	if cfg.EnvLabel != "prod" {
+		t.Errorf("EnvLabel = %s, want prod", cfg.EnvLabel)
+	}
 }
 
 func TestLoad_InvalidIntFallsBackToDefault(t *testing.T) {
@@ -73,3 +91,330 @@ func TestLoad_InvalidIntFallsBackToDefault(t *testing.T) {
 		t.Errorf("APIPort = %d, want 8080 (default on empty)", cfg.APIPort)
 	}
 }
+
+func TestLoad_MetricFilterLists(t *testing.T) {
+	os.Setenv("AI_OBSERVER_METRIC_ALLOWLIST", "claude_code.*, gemini_cli.token.usage,,")
+	os.Setenv("AI_OBSERVER_METRIC_DENYLIST", "*.debug")
+	defer func() {
+		os.Unsetenv("AI_OBSERVER_METRIC_ALLOWLIST")
+		os.Unsetenv("AI_OBSERVER_METRIC_DENYLIST")
+	}()
+
+	cfg := Load()
+
+	if len(cfg.MetricAllowlist) != 2 || cfg.MetricAllowlist[0] != "claude_code.*" || cfg.MetricAllowlist[1] != "gemini_cli.token.usage" {
+		t.Errorf("MetricAllowlist = %v, want [claude_code.* gemini_cli.token.usage]", cfg.MetricAllowlist)
+	}
+	if len(cfg.MetricDenylist) != 1 || cfg.MetricDenylist[0] != "*.debug" {
+		t.Errorf("MetricDenylist = %v, want [*.debug]", cfg.MetricDenylist)
+	}
+}
+
+func TestLoad_ModelAliases(t *testing.T) {
+	os.Setenv("AI_OBSERVER_MODEL_ALIASES", "claude-sonnet-4-*=claude-sonnet-4, gpt-5*=gpt-5")
+	defer os.Unsetenv("AI_OBSERVER_MODEL_ALIASES")
+
+	cfg := Load()
+
+	if len(cfg.ModelAliases) != 2 || cfg.ModelAliases[0] != "claude-sonnet-4-*=claude-sonnet-4" || cfg.ModelAliases[1] != "gpt-5*=gpt-5" {
+		t.Errorf("ModelAliases = %v, want [claude-sonnet-4-*=claude-sonnet-4 gpt-5*=gpt-5]", cfg.ModelAliases)
+	}
+}
+
+func TestLoad_ServiceGroups(t *testing.T) {
+	os.Setenv("AI_OBSERVER_SERVICE_GROUPS", "claude-*=claude, codex*=openai")
+	defer os.Unsetenv("AI_OBSERVER_SERVICE_GROUPS")
+
+	cfg := Load()
+
+	if len(cfg.ServiceGroups) != 2 || cfg.ServiceGroups[0] != "claude-*=claude" || cfg.ServiceGroups[1] != "codex*=openai" {
+		t.Errorf("ServiceGroups = %v, want [claude-*=claude codex*=openai]", cfg.ServiceGroups)
+	}
+}
+
+func TestLoad_DBPragmas(t *testing.T) {
+	os.Setenv("AI_OBSERVER_DB_PRAGMAS", "SET GLOBAL threads = 4; PRAGMA enable_progress_bar;")
+	defer os.Unsetenv("AI_OBSERVER_DB_PRAGMAS")
+
+	cfg := Load()
+
+	if len(cfg.DBPragmas) != 2 || cfg.DBPragmas[0] != "SET GLOBAL threads = 4" || cfg.DBPragmas[1] != "PRAGMA enable_progress_bar" {
+		t.Errorf("DBPragmas = %q, want [SET GLOBAL threads = 4, PRAGMA enable_progress_bar]", cfg.DBPragmas)
+	}
+}
+
+func TestLoad_DisabledEndpoints(t *testing.T) {
+	os.Setenv("AI_OBSERVER_DISABLED_ENDPOINTS", "/api/traces/{traceId}/session, /api/export/*")
+	os.Setenv("AI_OBSERVER_DISABLED_ENDPOINT_STATUS", "403")
+	defer os.Unsetenv("AI_OBSERVER_DISABLED_ENDPOINTS")
+	defer os.Unsetenv("AI_OBSERVER_DISABLED_ENDPOINT_STATUS")
+
+	cfg := Load()
+
+	if len(cfg.DisabledEndpoints) != 2 || cfg.DisabledEndpoints[0] != "/api/traces/{traceId}/session" || cfg.DisabledEndpoints[1] != "/api/export/*" {
+		t.Errorf("DisabledEndpoints = %q, want [/api/traces/{traceId}/session /api/export/*]", cfg.DisabledEndpoints)
+	}
+	if cfg.DisabledEndpointStatus != 403 {
+		t.Errorf("DisabledEndpointStatus = %d, want 403", cfg.DisabledEndpointStatus)
+	}
+}
+
+func TestLoad_DisabledEndpointStatusFallsBackTo404(t *testing.T) {
+	os.Setenv("AI_OBSERVER_DISABLED_ENDPOINT_STATUS", "500")
+	defer os.Unsetenv("AI_OBSERVER_DISABLED_ENDPOINT_STATUS")
+
+	cfg := Load()
+
+	if cfg.DisabledEndpointStatus != 404 {
+		t.Errorf("DisabledEndpointStatus = %d, want 404", cfg.DisabledEndpointStatus)
+	}
+}
+
+func TestLoad_AsyncIngest(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_ASYNC_INGEST")
+	if Load().AsyncIngest {
+		t.Error("AsyncIngest should be disabled by default")
+	}
+
+	os.Setenv("AI_OBSERVER_ASYNC_INGEST", "1")
+	defer os.Unsetenv("AI_OBSERVER_ASYNC_INGEST")
+	if !Load().AsyncIngest {
+		t.Error("AsyncIngest should be enabled by AI_OBSERVER_ASYNC_INGEST=1")
+	}
+}
+
+func TestLoad_IngestBatching(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_INGEST_BATCH_ROWS")
+	os.Unsetenv("AI_OBSERVER_INGEST_BATCH_INTERVAL_MS")
+	cfg := Load()
+	if cfg.IngestBatchRows != 0 || cfg.IngestBatchInterval != time.Second {
+		t.Errorf("expected batching disabled with a 1s interval, got %d rows and %v", cfg.IngestBatchRows, cfg.IngestBatchInterval)
+	}
+
+	os.Setenv("AI_OBSERVER_INGEST_BATCH_ROWS", "5000")
+	os.Setenv("AI_OBSERVER_INGEST_BATCH_INTERVAL_MS", "250")
+	defer os.Unsetenv("AI_OBSERVER_INGEST_BATCH_ROWS")
+	defer os.Unsetenv("AI_OBSERVER_INGEST_BATCH_INTERVAL_MS")
+	cfg = Load()
+	if cfg.IngestBatchRows != 5000 || cfg.IngestBatchInterval != 250*time.Millisecond {
+		t.Errorf("expected 5000 rows every 250ms, got %d rows and %v", cfg.IngestBatchRows, cfg.IngestBatchInterval)
+	}
+}
+
+func TestLoad_LogSampleInfo(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_LOG_SAMPLE_INFO")
+	if got := Load().LogSampleInfo; got != 1 {
+		t.Errorf("LogSampleInfo = %v, want 1", got)
+	}
+
+	os.Setenv("AI_OBSERVER_LOG_SAMPLE_INFO", "0.1")
+	defer os.Unsetenv("AI_OBSERVER_LOG_SAMPLE_INFO")
+	if got := Load().LogSampleInfo; got != 0.1 {
+		t.Errorf("LogSampleInfo = %v, want 0.1", got)
+	}
+}
+
+func TestLoad_DashboardFallback(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_DASHBOARD_FALLBACK")
+	if got := Load().DashboardFallback; got != "latest" {
+		t.Errorf("DashboardFallback = %q, want latest", got)
+	}
+
+	os.Setenv("AI_OBSERVER_DASHBOARD_FALLBACK", "None")
+	defer os.Unsetenv("AI_OBSERVER_DASHBOARD_FALLBACK")
+	if got := Load().DashboardFallback; got != "none" {
+		t.Errorf("DashboardFallback = %q, want none", got)
+	}
+}
+
+func TestLoad_DashboardLimits(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_MAX_DASHBOARDS")
+	os.Unsetenv("AI_OBSERVER_MAX_WIDGETS_PER_DASHBOARD")
+	if cfg := Load(); cfg.MaxDashboards != 0 || cfg.MaxWidgetsPerDashboard != 0 {
+		t.Errorf("limits = %d/%d, want unlimited", cfg.MaxDashboards, cfg.MaxWidgetsPerDashboard)
+	}
+
+	os.Setenv("AI_OBSERVER_MAX_DASHBOARDS", "20")
+	os.Setenv("AI_OBSERVER_MAX_WIDGETS_PER_DASHBOARD", "50")
+	defer os.Unsetenv("AI_OBSERVER_MAX_DASHBOARDS")
+	defer os.Unsetenv("AI_OBSERVER_MAX_WIDGETS_PER_DASHBOARD")
+	if cfg := Load(); cfg.MaxDashboards != 20 || cfg.MaxWidgetsPerDashboard != 50 {
+		t.Errorf("limits = %d/%d, want 20/50", cfg.MaxDashboards, cfg.MaxWidgetsPerDashboard)
+	}
+}
+
+func TestLoad_MaxAttributes(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_MAX_ATTRS")
+	if got := Load().MaxAttributes; got != 128 {
+		t.Errorf("MaxAttributes = %d, want 128", got)
+	}
+
+	os.Setenv("AI_OBSERVER_MAX_ATTRS", "0")
+	defer os.Unsetenv("AI_OBSERVER_MAX_ATTRS")
+	if got := Load().MaxAttributes; got != 0 {
+		t.Errorf("MaxAttributes = %d, want 0", got)
+	}
+}
+
+func TestLoad_MetricTimestampResolution(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_METRIC_TS_RESOLUTION")
+	if got := Load().MetricTimestampResolution; got != 0 {
+		t.Errorf("MetricTimestampResolution = %v, want 0", got)
+	}
+
+	defer os.Unsetenv("AI_OBSERVER_METRIC_TS_RESOLUTION")
+	for value, want := range map[string]time.Duration{"1s": time.Second, "500ms": 500 * time.Millisecond, "bogus": 0, "-1s": 0} {
+		os.Setenv("AI_OBSERVER_METRIC_TS_RESOLUTION", value)
+		if got := Load().MetricTimestampResolution; got != want {
+			t.Errorf("MetricTimestampResolution for %q = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestLoad_IngestSignals(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_INGEST_SIGNALS")
+	if got := Load().IngestSignals; len(got) != 0 {
+		t.Errorf("IngestSignals = %v, want empty", got)
+	}
+
+	os.Setenv("AI_OBSERVER_INGEST_SIGNALS", "metrics, logs")
+	defer os.Unsetenv("AI_OBSERVER_INGEST_SIGNALS")
+	got := Load().IngestSignals
+	if len(got) != 2 || got[0] != "metrics" || got[1] != "logs" {
+		t.Errorf("IngestSignals = %v, want [metrics logs]", got)
+	}
+}
+
+func TestLoad_SessionIdleTimeout(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_SESSION_IDLE_TIMEOUT")
+	if got := Load().SessionIdleTimeout; got != 30*time.Minute {
+		t.Errorf("SessionIdleTimeout = %v, want 30m", got)
+	}
+
+	os.Setenv("AI_OBSERVER_SESSION_IDLE_TIMEOUT", "2h")
+	defer os.Unsetenv("AI_OBSERVER_SESSION_IDLE_TIMEOUT")
+	if got := Load().SessionIdleTimeout; got != 2*time.Hour {
+		t.Errorf("SessionIdleTimeout = %v, want 2h", got)
+	}
+}
+
+func TestLoad_MaxConcurrentQueries(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_MAX_CONCURRENT_QUERIES")
+	os.Unsetenv("AI_OBSERVER_QUERY_QUEUE_TIMEOUT")
+	cfg := Load()
+	if cfg.MaxConcurrentQueries != 0 || cfg.QueryQueueTimeout != 5*time.Second {
+		t.Errorf("expected unlimited queries with a 5s queue timeout, got %d and %v", cfg.MaxConcurrentQueries, cfg.QueryQueueTimeout)
+	}
+
+	os.Setenv("AI_OBSERVER_MAX_CONCURRENT_QUERIES", "4")
+	os.Setenv("AI_OBSERVER_QUERY_QUEUE_TIMEOUT", "500ms")
+	defer os.Unsetenv("AI_OBSERVER_MAX_CONCURRENT_QUERIES")
+	defer os.Unsetenv("AI_OBSERVER_QUERY_QUEUE_TIMEOUT")
+	cfg = Load()
+	if cfg.MaxConcurrentQueries != 4 || cfg.QueryQueueTimeout != 500*time.Millisecond {
+		t.Errorf("expected 4 queries with a 500ms queue timeout, got %d and %v", cfg.MaxConcurrentQueries, cfg.QueryQueueTimeout)
+	}
+}
+
+func TestLoad_WatchImport(t *testing.T) {
+	os.Setenv("AI_OBSERVER_WATCH_IMPORT", "claude-code, codex")
+	defer os.Unsetenv("AI_OBSERVER_WATCH_IMPORT")
+	got := Load().WatchImport
+	if len(got) != 2 || got[0] != "claude-code" || got[1] != "codex" {
+		t.Errorf("WatchImport = %v, want [claude-code codex]", got)
+	}
+}
+
+func TestLoad_ImportOnStart(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_IMPORT_ON_START")
+	if got := Load().ImportOnStart; len(got) != 0 {
+		t.Errorf("expected no startup import by default, got %v", got)
+	}
+
+	os.Setenv("AI_OBSERVER_IMPORT_ON_START", "all")
+	defer os.Unsetenv("AI_OBSERVER_IMPORT_ON_START")
+	if got := Load().ImportOnStart; len(got) != 1 || got[0] != "all" {
+		t.Errorf("ImportOnStart = %v, want [all]", got)
+	}
+}
+
+func TestLoad_OTLPGRPCPort(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_OTLP_GRPC_PORT")
+	if got := Load().OTLPGRPCPort; got != 4317 {
+		t.Errorf("OTLPGRPCPort = %d, want 4317", got)
+	}
+
+	os.Setenv("AI_OBSERVER_OTLP_GRPC_PORT", "0")
+	defer os.Unsetenv("AI_OBSERVER_OTLP_GRPC_PORT")
+	if got := Load().OTLPGRPCPort; got != 0 {
+		t.Errorf("OTLPGRPCPort = %d, want 0", got)
+	}
+}
+
+func TestLoad_RetentionDays(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_RETENTION_DAYS")
+	if got := Load().RetentionDays; got != 0 {
+		t.Errorf("RetentionDays = %d, want 0 (disabled)", got)
+	}
+
+	os.Setenv("AI_OBSERVER_RETENTION_DAYS", "30")
+	defer os.Unsetenv("AI_OBSERVER_RETENTION_DAYS")
+	if got := Load().RetentionDays; got != 30 {
+		t.Errorf("RetentionDays = %d, want 30", got)
+	}
+}
+
+func TestLoad_AlertInterval(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_ALERT_INTERVAL")
+	if got := Load().AlertInterval; got != time.Minute {
+		t.Errorf("AlertInterval = %v, want 1m0s", got)
+	}
+
+	os.Setenv("AI_OBSERVER_ALERT_INTERVAL", "0")
+	defer os.Unsetenv("AI_OBSERVER_ALERT_INTERVAL")
+	if got := Load().AlertInterval; got != 0 {
+		t.Errorf("AlertInterval = %v, want 0 (disabled)", got)
+	}
+}
+
+func TestLoad_CompressMinBytes(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_COMPRESS_MIN_BYTES")
+	if got := Load().CompressMinBytes; got != 1024 {
+		t.Errorf("CompressMinBytes = %d, want 1024", got)
+	}
+
+	os.Setenv("AI_OBSERVER_COMPRESS_MIN_BYTES", "0")
+	defer os.Unsetenv("AI_OBSERVER_COMPRESS_MIN_BYTES")
+	if got := Load().CompressMinBytes; got != 0 {
+		t.Errorf("CompressMinBytes = %d, want 0 (disabled)", got)
+	}
+}
+
+func TestLoad_DedupWindow(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_DEDUP_WINDOW")
+	if got := Load().DedupWindow; got != 0 {
+		t.Errorf("DedupWindow = %v, want 0 (disabled)", got)
+	}
+
+	os.Setenv("AI_OBSERVER_DEDUP_WINDOW", "5m")
+	defer os.Unsetenv("AI_OBSERVER_DEDUP_WINDOW")
+	if got := Load().DedupWindow; got != 5*time.Minute {
+		t.Errorf("DedupWindow = %v, want 5m0s", got)
+	}
+}
+
+func TestLoad_Currency(t *testing.T) {
+	os.Unsetenv("AI_OBSERVER_CURRENCY")
+	os.Unsetenv("AI_OBSERVER_EXCHANGE_RATE")
+	if cfg := Load(); cfg.Currency != "USD" || cfg.ExchangeRate != 1 {
+		t.Errorf("Currency = %q at %v, want USD at 1", cfg.Currency, cfg.ExchangeRate)
+	}
+
+	os.Setenv("AI_OBSERVER_CURRENCY", "EUR")
+	os.Setenv("AI_OBSERVER_EXCHANGE_RATE", "0.92")
+	defer os.Unsetenv("AI_OBSERVER_CURRENCY")
+	defer os.Unsetenv("AI_OBSERVER_EXCHANGE_RATE")
+	if cfg := Load(); cfg.Currency != "EUR" || cfg.ExchangeRate != 0.92 {
+		t.Errorf("Currency = %q at %v, want EUR at 0.92", cfg.Currency, cfg.ExchangeRate)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,11 +25,13 @@ const (
 
 // Options configures the delete operation
 type Options struct {
-	Scope       Scope
-	From        time.Time
-	To          time.Time
-	Service     string // Optional filter by service name
-	SkipConfirm bool   // Skip confirmation prompt (--yes flag)
+	Scope        Scope
+	From         time.Time
+	To           time.Time
+	Service      string    // Optional filter by service name
+	SkipConfirm  bool      // Skip confirmation prompt (--yes flag)
+	ConfirmCount bool      // Require typing the record count instead of y/n (--confirm-count flag)
+	Input        io.Reader // Source of confirmation input (defaults to os.Stdin)
 }
 
 // Summary contains the results of a delete operation
@@ -174,13 +178,17 @@ func PrintResult(summary *Summary, opts Options) {
 
 // ConfirmDelete prompts the user for confirmation
 func ConfirmDelete() bool {
+	return confirmYesNo(os.Stdin)
+}
+
+// confirmYesNo prompts for y/n confirmation, reading the answer from r
+func confirmYesNo(r io.Reader) bool {
 	fmt.Println()
 	fmt.Println("This action cannot be undone.")
 	fmt.Print("Continue? [y/N] ")
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
+	response, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && response == "" {
 		return false
 	}
 
@@ -188,6 +196,27 @@ func ConfirmDelete() bool {
 	return response == "y" || response == "yes"
 }
 
+// ConfirmCount prompts the user to re-enter the number of records to delete, reading
+// the answer from r. Only an exact match confirms the deletion.
+func ConfirmCount(r io.Reader, count int64) bool {
+	fmt.Println()
+	fmt.Println("This action cannot be undone.")
+	fmt.Printf("Type the number of records to delete (%d) to continue: ", count)
+
+	response, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && response == "" {
+		return false
+	}
+
+	entered, err := strconv.ParseInt(strings.TrimSpace(response), 10, 64)
+	return err == nil && entered == count
+}
+
+// Total returns the number of rows covered by the summary (spans count as trace rows)
+func (s *Summary) Total() int64 {
+	return s.LogCount + s.MetricCount + s.SpanCount
+}
+
 // IsEmpty returns true if the summary has no records to delete
 func (s *Summary) IsEmpty() bool {
 	return s.LogCount == 0 && s.MetricCount == 0 && s.TraceCount == 0 && s.SpanCount == 0
@@ -212,7 +241,18 @@ func Run(ctx context.Context, store *storage.DuckDBStore, opts Options) error {
 
 	// Ask for confirmation unless --yes flag is set
 	if !opts.SkipConfirm {
-		if !ConfirmDelete() {
+		input := opts.Input
+		if input == nil {
+			input = os.Stdin
+		}
+
+		var confirmed bool
+		if opts.ConfirmCount {
+			confirmed = ConfirmCount(input, summary.Total())
+		} else {
+			confirmed = confirmYesNo(input)
+		}
+		if !confirmed {
 			fmt.Println("Aborted.")
 			return nil
 		}
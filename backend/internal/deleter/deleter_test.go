@@ -2,6 +2,7 @@ package deleter
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -668,3 +669,79 @@ func TestRunWithUnknownScope(t *testing.T) {
 		t.Error("expected error for unknown scope in Run")
 	}
 }
+
+func TestConfirmCount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"3\n", true},
+		{" 3 \n", true},
+		{"3", true}, // EOF without newline
+		{"4\n", false},
+		{"y\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := ConfirmCount(strings.NewReader(tt.input), 3); got != tt.want {
+			t.Errorf("ConfirmCount(%q, 3) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRunWithConfirmCount(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("mismatched count aborts", func(t *testing.T) {
+		store, cleanup := setupTestStore(t)
+		defer cleanup()
+		setupTestData(t, store)
+
+		opts := Options{
+			Scope:        ScopeLogs,
+			From:         now.Add(-2 * time.Hour),
+			To:           now.Add(1 * time.Hour),
+			ConfirmCount: true,
+			Input:        strings.NewReader("2\n"),
+		}
+		if err := Run(ctx, store, opts); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		summary, err := Preview(ctx, store, opts)
+		if err != nil {
+			t.Fatalf("Preview after Run failed: %v", err)
+		}
+		if summary.LogCount != 3 {
+			t.Errorf("expected logs to be kept after mismatched count, got %d", summary.LogCount)
+		}
+	})
+
+	t.Run("matching count proceeds", func(t *testing.T) {
+		store, cleanup := setupTestStore(t)
+		defer cleanup()
+		setupTestData(t, store)
+
+		opts := Options{
+			Scope:        ScopeLogs,
+			From:         now.Add(-2 * time.Hour),
+			To:           now.Add(1 * time.Hour),
+			ConfirmCount: true,
+			Input:        strings.NewReader("3\n"),
+		}
+		if err := Run(ctx, store, opts); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		summary, err := Preview(ctx, store, opts)
+		if err != nil {
+			t.Fatalf("Preview after Run failed: %v", err)
+		}
+		if summary.LogCount != 0 {
+			t.Errorf("expected 0 logs after confirmed delete, got %d", summary.LogCount)
+		}
+	})
+}
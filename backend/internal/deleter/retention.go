@@ -0,0 +1,107 @@
+package deleter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// retentionBatch is the width of the time slices expired telemetry is deleted in. Each slice
+// takes the store's write lock separately, so OTLP inserts can interleave with a large prune.
+// Slices start at the next stored timestamp, so gaps in the data are skipped.
+const retentionBatch = time.Hour
+
+// Retention periodically deletes traces, logs and metrics older than a maximum age and
+// checkpoints the database afterwards so the file shrinks.
+type Retention struct {
+	store  *storage.DuckDBStore
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewRetention creates a retention worker that keeps the given number of days of telemetry
+func NewRetention(store *storage.DuckDBStore, days int) *Retention {
+	return &Retention{
+		store:  store,
+		maxAge: time.Duration(days) * 24 * time.Hour,
+		now:    time.Now,
+	}
+}
+
+// Run prunes immediately and then every interval until ctx is cancelled
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Prune(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to prune expired telemetry", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes all telemetry older than the retention period, oldest first in
+// retentionBatch slices starting at stored timestamps, and returns how many rows were
+// removed per table
+func (r *Retention) Prune(ctx context.Context) (*Summary, error) {
+	summary := &Summary{}
+	cutoff := r.now().Add(-r.maxAge)
+
+	dataRange, err := r.store.GetDataTimeRange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dataRange.From == nil || !dataRange.From.Before(cutoff) {
+		return summary, nil
+	}
+
+	// Range deletes include their end, so stop just short of the cutoff
+	last := cutoff.Add(-time.Microsecond)
+	for from := *dataRange.From; !from.After(last); {
+		to := from.Add(retentionBatch)
+		if to.After(last) {
+			to = last
+		}
+		batch, err := Execute(ctx, r.store, Options{Scope: ScopeAll, From: from, To: to})
+		if err != nil {
+			return nil, fmt.Errorf("pruning telemetry before %s: %w", cutoff.Format(time.RFC3339), err)
+		}
+		summary.SpanCount += batch.SpanCount
+		summary.LogCount += batch.LogCount
+		summary.MetricCount += batch.MetricCount
+
+		next, err := r.store.NextTimestampAfter(ctx, to)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			break
+		}
+		from = *next
+	}
+
+	if summary.IsEmpty() {
+		return summary, nil
+	}
+
+	logger.Info("Pruned expired telemetry",
+		"before", cutoff.Format(time.RFC3339),
+		"otel_traces", summary.SpanCount,
+		"otel_logs", summary.LogCount,
+		"otel_metrics", summary.MetricCount,
+	)
+
+	if err := r.store.Checkpoint(ctx); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,107 @@
+package deleter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestRetentionPrune(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+	older := now.Add(-40 * 24 * time.Hour)
+
+	logs := []api.LogRecord{
+		{Timestamp: older, ServiceName: "claude_code", Body: "very old"},
+		{Timestamp: old, ServiceName: "claude_code", Body: "old"},
+		{Timestamp: now, ServiceName: "claude_code", Body: "recent"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+	metrics := []api.MetricDataPoint{
+		{Timestamp: old, ServiceName: "claude_code", MetricName: "token.usage", MetricType: "gauge", Value: ptrFloat64(1)},
+		{Timestamp: now, ServiceName: "claude_code", MetricName: "token.usage", MetricType: "gauge", Value: ptrFloat64(2)},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+	spans := []api.Span{
+		{Timestamp: older, TraceID: "trace1", SpanID: "span1", SpanName: "old_span", ServiceName: "claude_code"},
+		{Timestamp: now, TraceID: "trace2", SpanID: "span2", SpanName: "new_span", ServiceName: "claude_code"},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	summary, err := NewRetention(store, 7).Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if summary.LogCount != 2 || summary.MetricCount != 1 || summary.SpanCount != 1 {
+		t.Errorf("unexpected prune summary: %+v", summary)
+	}
+
+	remaining, err := store.CountAllInRange(ctx, older.Add(-time.Hour), now.Add(time.Hour), "")
+	if err != nil {
+		t.Fatalf("CountAllInRange failed: %v", err)
+	}
+	if remaining.LogCount != 1 || remaining.MetricCount != 1 || remaining.SpanCount != 1 {
+		t.Errorf("expected only recent telemetry to remain, got %+v", remaining)
+	}
+
+	// Nothing left to prune
+	summary, err = NewRetention(store, 7).Prune(ctx)
+	if err != nil {
+		t.Fatalf("second Prune failed: %v", err)
+	}
+	if !summary.IsEmpty() {
+		t.Errorf("expected nothing to prune, got %+v", summary)
+	}
+}
+
+func TestRetentionPrune_SkipsGaps(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A point with an unset timestamp lands at the epoch; pruning must not walk every hour
+	// from there, so it finishes well within the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: time.Unix(0, 0), ServiceName: "claude_code", Body: "epoch"},
+		{Timestamp: now.Add(-10 * 24 * time.Hour), ServiceName: "claude_code", Body: "old"},
+		{Timestamp: now, ServiceName: "claude_code", Body: "recent"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	summary, err := NewRetention(store, 7).Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if summary.LogCount != 2 {
+		t.Errorf("expected 2 expired logs to be pruned, got %+v", summary)
+	}
+}
+
+func TestRetentionPrune_EmptyDatabase(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	summary, err := NewRetention(store, 1).Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !summary.IsEmpty() {
+		t.Errorf("expected empty summary, got %+v", summary)
+	}
+}
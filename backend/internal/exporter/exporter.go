@@ -12,18 +12,27 @@ import (
 	"github.com/tobilg/ai-observer/internal/storage"
 )
 
-// Exporter handles exporting telemetry data to Parquet files
+// Exporter handles exporting telemetry data to Parquet, CSV or JSONL files
 type Exporter struct {
 	store   *storage.DuckDBStore
 	verbose bool
+
+	// writeTable writes a single table to a file; replaceable in tests
+	writeTable func(ctx context.Context, table, outputPath string, from, to *time.Time, service, copyOptions string) (int64, error)
+
+	// newUploader creates the S3 client used with Options.S3URI; replaceable in tests
+	newUploader func(ctx context.Context) (objectUploader, error)
 }
 
 // NewExporter creates a new Exporter
 func NewExporter(store *storage.DuckDBStore, verbose bool) *Exporter {
-	return &Exporter{
+	e := &Exporter{
 		store:   store,
 		verbose: verbose,
 	}
+	e.writeTable = e.exportTable
+	e.newUploader = newS3Uploader
+	return e
 }
 
 // Preview returns a summary of what would be exported without actually exporting
@@ -80,75 +89,51 @@ func getDateRange(from, to *time.Time) (time.Time, time.Time) {
 	return fromTime, toTime
 }
 
-// Export performs the actual export to Parquet files
+// Export performs the actual export to Parquet, CSV or JSONL files, or to a single DuckDB file with
+// opts.SingleDB
 func (e *Exporter) Export(ctx context.Context, opts Options) (*Summary, error) {
-	summary := &Summary{}
-
-	// Ensure output directory exists
-	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
-		return nil, fmt.Errorf("creating output directory: %w", err)
-	}
-
-	// Export each table to Parquet
-	tracesPath := filepath.Join(opts.OutputDir, "traces.parquet")
-	logsPath := filepath.Join(opts.OutputDir, "logs.parquet")
-	metricsPath := filepath.Join(opts.OutputDir, "metrics.parquet")
-
-	service := opts.ServiceName()
-
-	// Export traces
-	if e.verbose {
-		fmt.Print("Exporting traces... ")
+	if err := opts.validateCompression(); err != nil {
+		return nil, err
 	}
-	tracesCount, err := e.exportToParquet(ctx, "otel_traces", tracesPath, opts.FromDate, opts.ToDate, service)
-	if err != nil {
-		return nil, fmt.Errorf("exporting traces: %w", err)
-	}
-	summary.TracesCount = tracesCount
-	summary.OutputFiles = append(summary.OutputFiles, tracesPath)
-	if e.verbose {
-		fmt.Printf("done (%d rows)\n", tracesCount)
+	if _, err := ParseExportFormat(string(opts.FileFormat())); err != nil {
+		return nil, err
 	}
 
-	// Export logs
-	if e.verbose {
-		fmt.Print("Exporting logs... ")
+	if opts.SingleDB && opts.Resume {
+		return nil, fmt.Errorf("resume is not supported for single database exports")
 	}
-	logsCount, err := e.exportToParquet(ctx, "otel_logs", logsPath, opts.FromDate, opts.ToDate, service)
-	if err != nil {
-		return nil, fmt.Errorf("exporting logs: %w", err)
-	}
-	summary.LogsCount = logsCount
-	summary.OutputFiles = append(summary.OutputFiles, logsPath)
-	if e.verbose {
-		fmt.Printf("done (%d rows)\n", logsCount)
+	if opts.SingleDB && opts.FileFormat() != FormatParquet {
+		return nil, fmt.Errorf("single database exports cannot use the %s format", opts.FileFormat())
 	}
 
-	// Export metrics
-	if e.verbose {
-		fmt.Print("Exporting metrics... ")
-	}
-	metricsCount, err := e.exportToParquet(ctx, "otel_metrics", metricsPath, opts.FromDate, opts.ToDate, service)
-	if err != nil {
-		return nil, fmt.Errorf("exporting metrics: %w", err)
+	if err := ValidateFilenameTemplate(opts.FilenameTemplate); err != nil {
+		return nil, err
 	}
-	summary.MetricsCount = metricsCount
-	summary.OutputFiles = append(summary.OutputFiles, metricsPath)
-	if e.verbose {
-		fmt.Printf("done (%d rows)\n", metricsCount)
+	if opts.S3URI != "" {
+		if _, _, err := ParseS3URI(opts.S3URI); err != nil {
+			return nil, err
+		}
+	} else if opts.S3DeleteLocal {
+		return nil, fmt.Errorf("deleting local files requires an S3 URI")
 	}
+	opts.exportedAt = time.Now()
 
-	// Create views database
-	if e.verbose {
-		fmt.Print("Creating views database... ")
+	// Ensure output directory exists
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
 	}
-	viewsDBPath := e.generateViewsDBPath(opts)
-	if err := e.createViewsDatabase(ctx, viewsDBPath); err != nil {
-		return nil, fmt.Errorf("creating views database: %w", err)
+
+	service := opts.ServiceName()
+
+	var summary *Summary
+	var err error
+	if opts.SingleDB {
+		summary, err = e.exportSingleDB(ctx, e.generateViewsDBPath(opts), opts, service)
+	} else {
+		summary, err = e.exportFiles(ctx, opts, service)
 	}
-	summary.OutputFiles = append(summary.OutputFiles, viewsDBPath)
-	if e.verbose {
-		fmt.Println("done")
+	if err != nil {
+		return nil, err
 	}
 
 	// Create ZIP archive if requested
@@ -157,7 +142,7 @@ func (e *Exporter) Export(ctx context.Context, opts Options) (*Summary, error) {
 			fmt.Print("Creating ZIP archive... ")
 		}
 		zipPath := e.generateZipPath(opts)
-		if err := CreateZipArchive(opts.OutputDir, summary.OutputFiles, zipPath); err != nil {
+		if err := CreateZipArchiveWithLevel(opts.OutputDir, summary.OutputFiles, zipPath, opts.CompressionLevel); err != nil {
 			return nil, fmt.Errorf("creating ZIP archive: %w", err)
 		}
 
@@ -173,6 +158,9 @@ func (e *Exporter) Export(ctx context.Context, opts Options) (*Summary, error) {
 		}
 	}
 
+	// All signals completed, so markers are no longer needed
+	removeCompletionMarkers(opts.OutputDir)
+
 	// Calculate total size
 	for _, file := range summary.OutputFiles {
 		if info, err := os.Stat(file); err == nil {
@@ -180,19 +168,119 @@ func (e *Exporter) Export(ctx context.Context, opts Options) (*Summary, error) {
 		}
 	}
 
+	if opts.S3URI != "" {
+		uris, err := e.uploadToS3(ctx, summary.OutputFiles, opts)
+		if err != nil {
+			return nil, fmt.Errorf("uploading to S3: %w", err)
+		}
+		if opts.S3DeleteLocal {
+			for _, file := range summary.OutputFiles {
+				os.Remove(file)
+			}
+			summary.OutputFiles = uris
+		} else {
+			summary.OutputFiles = append(summary.OutputFiles, uris...)
+		}
+	}
+
+	return summary, nil
+}
+
+// exportFiles exports each signal to a file in the export format, and creates the views
+// database over Parquet files
+func (e *Exporter) exportFiles(ctx context.Context, opts Options, service string) (*Summary, error) {
+	summary := &Summary{}
+
+	// A fresh (non-resume) export starts over, discarding markers from earlier attempts
+	if !opts.Resume {
+		removeCompletionMarkers(opts.OutputDir)
+	}
+
+	// Export each signal table to its file
+	for _, sig := range exportSignals {
+		outputPath := filepath.Join(opts.OutputDir, sig.file(opts.FileFormat()))
+
+		count, err := e.exportSignal(ctx, sig, outputPath, opts, service)
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", sig.name, err)
+		}
+
+		switch sig.name {
+		case "traces":
+			summary.TracesCount = count
+		case "logs":
+			summary.LogsCount = count
+		case "metrics":
+			summary.MetricsCount = count
+		}
+		summary.OutputFiles = append(summary.OutputFiles, outputPath)
+	}
+
+	// CSV and JSONL files are meant for other tools; the views database only reads Parquet
+	if opts.FileFormat() != FormatParquet {
+		return summary, nil
+	}
+
+	// Create views database
+	if e.verbose {
+		fmt.Print("Creating views database... ")
+	}
+	viewsDBPath := e.generateViewsDBPath(opts)
+	if err := e.createViewsDatabase(ctx, viewsDBPath); err != nil {
+		return nil, fmt.Errorf("creating views database: %w", err)
+	}
+	summary.OutputFiles = append(summary.OutputFiles, viewsDBPath)
+	if e.verbose {
+		fmt.Println("done")
+	}
+
 	return summary, nil
 }
 
+// exportSignal exports a single signal, skipping it when resuming and a completion marker
+// from a previous run with the same options is present
+func (e *Exporter) exportSignal(ctx context.Context, sig exportSignal, outputPath string, opts Options, service string) (int64, error) {
+	if opts.Resume && isSignalComplete(opts.OutputDir, sig.name, outputPath, opts.resumeFingerprint()) {
+		count, err := e.countFileRows(ctx, outputPath)
+		if err != nil {
+			return 0, err
+		}
+		if e.verbose {
+			fmt.Printf("Skipping %s (already exported, %d rows)\n", sig.name, count)
+		}
+		return count, nil
+	}
+
+	if e.verbose {
+		fmt.Printf("Exporting %s... ", sig.name)
+	}
+	var count int64
+	var err error
+	if opts.FileFormat() == FormatJSONL {
+		count, err = e.exportJSONL(ctx, sig.table, outputPath, opts.FromDate, opts.ToDate, service)
+	} else {
+		count, err = e.writeTable(ctx, sig.table, outputPath, opts.FromDate, opts.ToDate, service, opts.copyOptions())
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := markSignalComplete(opts.OutputDir, sig.name, opts.resumeFingerprint()); err != nil {
+		return 0, err
+	}
+	if e.verbose {
+		fmt.Printf("done (%d rows)\n", count)
+	}
+	return count, nil
+}
+
 // generateViewsDBPath generates the views database filename
 func (e *Exporter) generateViewsDBPath(opts Options) string {
-	filename := fmt.Sprintf("ai-observer-export-%s-%s.duckdb", opts.Source, opts.DateRangeString())
-	return filepath.Join(opts.OutputDir, filename)
+	return filepath.Join(opts.OutputDir, opts.OutputName(".duckdb"))
 }
 
 // generateZipPath generates the ZIP archive filename
 func (e *Exporter) generateZipPath(opts Options) string {
-	filename := fmt.Sprintf("ai-observer-export-%s-%s.zip", opts.Source, opts.DateRangeString())
-	return filepath.Join(opts.OutputDir, filename)
+	return filepath.Join(opts.OutputDir, opts.OutputName(".zip"))
 }
 
 // PrintPreview prints the export preview to stdout
@@ -230,13 +318,27 @@ func PrintPreview(summary *Summary, opts Options) {
 	fmt.Println()
 	fmt.Printf("Output directory: %s\n", opts.OutputDir)
 	fmt.Println("Files to create:")
-	fmt.Println("  - traces.parquet")
-	fmt.Println("  - logs.parquet")
-	fmt.Println("  - metrics.parquet")
-	fmt.Printf("  - ai-observer-export-%s-%s.duckdb\n", opts.Source, opts.DateRangeString())
+	if opts.SingleDB {
+		fmt.Printf("  - %s (otel_traces, otel_logs, otel_metrics tables)\n", opts.OutputName(".duckdb"))
+	} else {
+		for _, sig := range exportSignals {
+			fmt.Printf("  - %s\n", sig.file(opts.FileFormat()))
+		}
+		if opts.FileFormat() == FormatParquet {
+			fmt.Printf("  - %s\n", opts.OutputName(".duckdb"))
+		}
+	}
 
 	if opts.CreateZip {
-		fmt.Printf("  - ai-observer-export-%s-%s.zip (all files combined)\n", opts.Source, opts.DateRangeString())
+		fmt.Printf("  - %s (all files combined)\n", opts.OutputName(".zip"))
+	}
+
+	if opts.S3URI != "" {
+		if opts.S3DeleteLocal {
+			fmt.Printf("Upload to: %s (local files removed afterwards)\n", opts.S3URI)
+		} else {
+			fmt.Printf("Upload to: %s\n", opts.S3URI)
+		}
 	}
 }
 
@@ -244,7 +346,21 @@ func PrintPreview(summary *Summary, opts Options) {
 func PrintResult(summary *Summary, opts Options) {
 	fmt.Println()
 	fmt.Println("Export complete!")
-	fmt.Printf("Output: %s (%d files, %s)\n", opts.OutputDir, len(summary.OutputFiles), formatSize(summary.TotalSize))
+	if opts.S3URI == "" {
+		fmt.Printf("Output: %s (%d files, %s)\n", opts.OutputDir, len(summary.OutputFiles), formatSize(summary.TotalSize))
+		return
+	}
+	uploaded := 0
+	for _, file := range summary.OutputFiles {
+		if strings.HasPrefix(file, "s3://") {
+			uploaded++
+		}
+	}
+	if opts.S3DeleteLocal {
+		fmt.Printf("Output: %s (%d files, %s)\n", opts.S3URI, uploaded, formatSize(summary.TotalSize))
+	} else {
+		fmt.Printf("Output: %s and %s (%d files, %s)\n", opts.OutputDir, opts.S3URI, uploaded, formatSize(summary.TotalSize))
+	}
 }
 
 // ConfirmExport prompts the user for confirmation
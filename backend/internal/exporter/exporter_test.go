@@ -2,11 +2,19 @@ package exporter
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/storage"
 )
@@ -566,6 +574,124 @@ func TestCreateZipArchive(t *testing.T) {
 	}
 }
 
+func TestCreateZipArchiveWithLevel(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Compressible but non-trivial content so deflate levels make a difference
+	var content strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&content, "span %d service=svc-%d duration=%d status=%s\n", i, i%7, (i*7919)%100003, []string{"OK", "ERROR", "UNSET"}[i%3])
+	}
+	dataFile := filepath.Join(tmpDir, "data.txt")
+	if err := os.WriteFile(dataFile, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	zipSize := func(level int) int64 {
+		t.Helper()
+		zipPath := filepath.Join(tmpDir, fmt.Sprintf("level-%d.zip", level))
+		if err := CreateZipArchiveWithLevel(tmpDir, []string{dataFile}, zipPath, level); err != nil {
+			t.Fatalf("CreateZipArchiveWithLevel(%d) failed: %v", level, err)
+		}
+		info, err := os.Stat(zipPath)
+		if err != nil {
+			t.Fatalf("stat zip: %v", err)
+		}
+		return info.Size()
+	}
+
+	stored := zipSize(0)
+	fast := zipSize(1)
+	best := zipSize(9)
+
+	if fast >= stored {
+		t.Errorf("expected level 1 (%d bytes) to be smaller than stored (%d bytes)", fast, stored)
+	}
+	if best > fast {
+		t.Errorf("expected level 9 (%d bytes) to be no larger than level 1 (%d bytes)", best, fast)
+	}
+
+	if err := CreateZipArchiveWithLevel(tmpDir, []string{dataFile}, filepath.Join(tmpDir, "bad.zip"), 10); err == nil {
+		t.Error("expected error for compression level 10")
+	}
+}
+
+func TestParseParquetCodec(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ParquetCodec
+		wantErr bool
+	}{
+		{"", CodecZSTD, false},
+		{"zstd", CodecZSTD, false},
+		{"SNAPPY", CodecSnappy, false},
+		{"gzip", CodecGzip, false},
+		{"lz5", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseParquetCodec(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.input)
+				}
+				if !strings.Contains(err.Error(), "invalid parquet codec") || !strings.Contains(err.Error(), "zstd, snappy, gzip") {
+					t.Errorf("expected descriptive error, got %q", err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseParquetCodec(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExporterExportCodecs(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+	setupTestData(t, store)
+
+	for _, codec := range []ParquetCodec{CodecSnappy, CodecGzip, CodecZSTD} {
+		t.Run(string(codec), func(t *testing.T) {
+			exporter := NewExporter(store, false)
+			summary, err := exporter.Export(ctx, Options{
+				Source:           SourceAll,
+				OutputDir:        t.TempDir(),
+				ParquetCodec:     codec,
+				CompressionLevel: 3,
+			})
+			if err != nil {
+				t.Fatalf("Export with %s failed: %v", codec, err)
+			}
+			if summary.TracesCount == 0 {
+				t.Error("expected exported traces")
+			}
+		})
+	}
+
+	t.Run("invalid codec", func(t *testing.T) {
+		exporter := NewExporter(store, false)
+		_, err := exporter.Export(ctx, Options{Source: SourceAll, OutputDir: t.TempDir(), ParquetCodec: "brotli9000"})
+		if err == nil || !strings.Contains(err.Error(), "invalid parquet codec") {
+			t.Errorf("expected invalid codec error, got %v", err)
+		}
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		exporter := NewExporter(store, false)
+		_, err := exporter.Export(ctx, Options{Source: SourceAll, OutputDir: t.TempDir(), CompressionLevel: 42})
+		if err == nil || !strings.Contains(err.Error(), "invalid compression level") {
+			t.Errorf("expected invalid level error, got %v", err)
+		}
+	})
+}
+
 func TestExporterExportEmptyDatabase(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -832,6 +958,100 @@ func TestGenerateZipPath(t *testing.T) {
 	}
 }
 
+func TestOutputNameFilenameTemplate(t *testing.T) {
+	date1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	exportedAt := time.Date(2025, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		opts Options
+		ext  string
+		want string
+	}{
+		{
+			name: "default naming unchanged",
+			opts: Options{Source: SourceClaude, FromDate: &date1, ToDate: &date2, exportedAt: exportedAt},
+			ext:  ".zip",
+			want: "ai-observer-export-claude-code-2025-01-01-2025-01-15.zip",
+		},
+		{
+			name: "all placeholders",
+			opts: Options{Source: SourceCodex, FromDate: &date1, ToDate: &date2, FilenameTemplate: "nightly_{source}_{from}_{to}_{ts}", exportedAt: exportedAt},
+			ext:  ".duckdb",
+			want: "nightly_codex_2025-01-01_2025-01-15_20250203-040506.duckdb",
+		},
+		{
+			name: "open date range",
+			opts: Options{Source: SourceAll, FilenameTemplate: "{source}-{from}-{to}"},
+			ext:  ".zip",
+			want: "all-start-now.zip",
+		},
+		{
+			name: "timestamp before export",
+			opts: Options{Source: SourceGemini, FilenameTemplate: "gemini-{ts}"},
+			ext:  ".zip",
+			want: "gemini-{ts}.zip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.OutputName(tt.ext); got != tt.want {
+				t.Errorf("OutputName(%q) = %q, want %q", tt.ext, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	for _, template := range []string{"", "export-{source}", "{ts}_{from}_{to}"} {
+		if err := ValidateFilenameTemplate(template); err != nil {
+			t.Errorf("ValidateFilenameTemplate(%q) unexpected error: %v", template, err)
+		}
+	}
+	for _, template := range []string{"../escape", "dir/{source}", `dir\{source}`} {
+		if err := ValidateFilenameTemplate(template); err == nil {
+			t.Errorf("ValidateFilenameTemplate(%q) expected error", template)
+		}
+	}
+}
+
+func TestExporterExportFilenameTemplate(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	exporter := NewExporter(store, false)
+	tmpDir := t.TempDir()
+
+	summary, err := exporter.Export(ctx, Options{
+		Source:           SourceAll,
+		OutputDir:        tmpDir,
+		CreateZip:        true,
+		FilenameTemplate: "pipeline-{source}-{ts}",
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if len(summary.OutputFiles) != 1 {
+		t.Fatalf("expected 1 output file, got %v", summary.OutputFiles)
+	}
+	name := filepath.Base(summary.OutputFiles[0])
+	if ok, _ := filepath.Match("pipeline-all-[0-9]*-[0-9]*.zip", name); !ok {
+		t.Errorf("unexpected ZIP name %q", name)
+	}
+	if _, err := os.Stat(summary.OutputFiles[0]); err != nil {
+		t.Errorf("expected ZIP file to exist: %v", err)
+	}
+
+	if _, err := exporter.Export(ctx, Options{Source: SourceAll, OutputDir: tmpDir, FilenameTemplate: "../{source}"}); err == nil {
+		t.Error("expected error for template escaping the output directory")
+	}
+}
+
 func TestCreateZipArchiveWithNonexistentFile(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "zip-error-test-*")
 	if err != nil {
@@ -1008,3 +1228,632 @@ func TestConfirmExport(t *testing.T) {
 	// Testing this would require mocking os.Stdin
 	t.Skip("ConfirmExport requires stdin mocking")
 }
+
+func TestExporterExportResume(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	opts := Options{
+		Source:    SourceAll,
+		OutputDir: tmpDir,
+	}
+
+	// First attempt fails while exporting metrics
+	failing := NewExporter(store, false)
+	realWrite := failing.writeTable
+	failing.writeTable = func(ctx context.Context, table, outputPath string, from, to *time.Time, service, compression string) (int64, error) {
+		if table == "otel_metrics" {
+			return 0, errors.New("injected failure")
+		}
+		return realWrite(ctx, table, outputPath, from, to, service, compression)
+	}
+
+	if _, err := failing.Export(ctx, opts); err == nil {
+		t.Fatal("expected first export to fail")
+	}
+
+	tracesPath := filepath.Join(tmpDir, "traces.parquet")
+	tracesInfo, err := os.Stat(tracesPath)
+	if err != nil {
+		t.Fatalf("expected traces.parquet after failed export: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".traces.done")); err != nil {
+		t.Errorf("expected traces completion marker: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".metrics.done")); !os.IsNotExist(err) {
+		t.Error("expected no metrics completion marker after failure")
+	}
+
+	// Resume, recording which tables get written
+	resumed := NewExporter(store, false)
+	realWrite = resumed.writeTable
+	var written []string
+	resumed.writeTable = func(ctx context.Context, table, outputPath string, from, to *time.Time, service, compression string) (int64, error) {
+		written = append(written, table)
+		return realWrite(ctx, table, outputPath, from, to, service, compression)
+	}
+
+	opts.Resume = true
+	summary, err := resumed.Export(ctx, opts)
+	if err != nil {
+		t.Fatalf("resumed export failed: %v", err)
+	}
+
+	for _, table := range written {
+		if table == "otel_traces" || table == "otel_logs" {
+			t.Errorf("resume re-wrote already completed table %s", table)
+		}
+	}
+	if len(written) != 1 || written[0] != "otel_metrics" {
+		t.Errorf("expected only otel_metrics to be written, got %v", written)
+	}
+
+	// Traces file must be untouched and still counted
+	afterInfo, err := os.Stat(tracesPath)
+	if err != nil {
+		t.Fatalf("traces.parquet missing after resume: %v", err)
+	}
+	if !afterInfo.ModTime().Equal(tracesInfo.ModTime()) {
+		t.Error("traces.parquet was modified during resume")
+	}
+	if summary.TracesCount != 2 {
+		t.Errorf("expected 2 traces, got %d", summary.TracesCount)
+	}
+	if summary.LogsCount != 3 {
+		t.Errorf("expected 3 logs, got %d", summary.LogsCount)
+	}
+	if summary.MetricsCount != 3 {
+		t.Errorf("expected 3 metrics, got %d", summary.MetricsCount)
+	}
+
+	// Markers are cleaned up after a successful export
+	markers, _ := filepath.Glob(filepath.Join(tmpDir, ".*.done"))
+	if len(markers) != 0 {
+		t.Errorf("expected completion markers to be removed, found %v", markers)
+	}
+}
+
+func TestExporterExportResumeWithChangedRange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	opts := Options{Source: SourceAll, OutputDir: tmpDir}
+
+	// First attempt fails while exporting metrics, leaving traces and logs complete
+	failing := NewExporter(store, false)
+	realWrite := failing.writeTable
+	failing.writeTable = func(ctx context.Context, table, outputPath string, from, to *time.Time, service, compression string) (int64, error) {
+		if table == "otel_metrics" {
+			return 0, errors.New("injected failure")
+		}
+		return realWrite(ctx, table, outputPath, from, to, service, compression)
+	}
+	if _, err := failing.Export(ctx, opts); err == nil {
+		t.Fatal("expected first export to fail")
+	}
+
+	// Resuming with a different range must not keep files exported for the old one
+	resumed := NewExporter(store, false)
+	realWrite = resumed.writeTable
+	var written []string
+	resumed.writeTable = func(ctx context.Context, table, outputPath string, from, to *time.Time, service, compression string) (int64, error) {
+		written = append(written, table)
+		return realWrite(ctx, table, outputPath, from, to, service, compression)
+	}
+
+	from := time.Now().Add(-24 * time.Hour)
+	opts.Resume = true
+	opts.FromDate = &from
+	if _, err := resumed.Export(ctx, opts); err != nil {
+		t.Fatalf("resumed export failed: %v", err)
+	}
+
+	if want := []string{"otel_traces", "otel_logs", "otel_metrics"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("expected all signals to be re-exported, got %v", written)
+	}
+}
+
+func TestExporterExportWithoutResumeRewrites(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// Leave a stale marker from an earlier attempt
+	stale := Options{Source: SourceAll}
+	if err := markSignalComplete(tmpDir, "traces", stale.resumeFingerprint()); err != nil {
+		t.Fatalf("failed to write marker: %v", err)
+	}
+
+	exporter := NewExporter(store, false)
+	realWrite := exporter.writeTable
+	var written []string
+	exporter.writeTable = func(ctx context.Context, table, outputPath string, from, to *time.Time, service, compression string) (int64, error) {
+		written = append(written, table)
+		return realWrite(ctx, table, outputPath, from, to, service, compression)
+	}
+
+	if _, err := exporter.Export(ctx, Options{Source: SourceAll, OutputDir: tmpDir}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if len(written) != 3 {
+		t.Errorf("expected all 3 tables to be written without --resume, got %v", written)
+	}
+}
+
+func TestExporterExportSingleDB(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	exporter := NewExporter(store, false)
+	opts := Options{Source: SourceClaude, OutputDir: tmpDir, SingleDB: true}
+
+	summary, err := exporter.Export(ctx, opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	dbPath := exporter.generateViewsDBPath(opts)
+	if len(summary.OutputFiles) != 1 || summary.OutputFiles[0] != dbPath {
+		t.Fatalf("expected only %s as output, got %v", dbPath, summary.OutputFiles)
+	}
+	if parquetFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.parquet")); len(parquetFiles) != 0 {
+		t.Errorf("expected no parquet files, got %v", parquetFiles)
+	}
+
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open exported database: %v", err)
+	}
+	defer db.Close()
+
+	expected := map[string]int64{
+		"otel_traces":  2,
+		"otel_logs":    2,
+		"otel_metrics": 2,
+	}
+	for table, want := range expected {
+		var got int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&got); err != nil {
+			t.Fatalf("querying %s: %v", table, err)
+		}
+		if got != want {
+			t.Errorf("expected %d rows in %s, got %d", want, table, got)
+		}
+	}
+
+	if summary.TracesCount != 2 || summary.LogsCount != 2 || summary.MetricsCount != 2 {
+		t.Errorf("unexpected summary counts: traces=%d logs=%d metrics=%d", summary.TracesCount, summary.LogsCount, summary.MetricsCount)
+	}
+}
+
+func TestExporterExportSingleDBRejectsResume(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	exporter := NewExporter(store, false)
+	_, err := exporter.Export(context.Background(), Options{Source: SourceAll, OutputDir: t.TempDir(), SingleDB: true, Resume: true})
+	if err == nil {
+		t.Error("expected error combining single database export with resume")
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ExportFormat
+		wantErr bool
+	}{
+		{"", FormatParquet, false},
+		{"parquet", FormatParquet, false},
+		{"CSV", FormatCSV, false},
+		{"jsonl", FormatJSONL, false},
+		{"xlsx", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseExportFormat(tt.input)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "parquet, csv, jsonl") {
+					t.Fatalf("expected descriptive error for %q, got %v", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseExportFormat(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// fileLines returns the lines of an exported CSV or JSONL file
+func fileLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+func TestExporterExportCSV(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	tmpDir := t.TempDir()
+	opts := Options{Source: SourceAll, OutputDir: tmpDir, Format: FormatCSV}
+	summary, err := NewExporter(store, false).Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if summary.TracesCount != 2 || summary.LogsCount != 3 || summary.MetricsCount != 3 {
+		t.Errorf("unexpected summary counts: traces=%d logs=%d metrics=%d", summary.TracesCount, summary.LogsCount, summary.MetricsCount)
+	}
+	want := map[string]int64{"traces.csv": 2, "logs.csv": 3, "metrics.csv": 3}
+	if len(summary.OutputFiles) != len(want) {
+		t.Fatalf("expected only the CSV files, got %v", summary.OutputFiles)
+	}
+	for _, path := range summary.OutputFiles {
+		rows, ok := want[filepath.Base(path)]
+		if !ok {
+			t.Fatalf("unexpected output file %s", path)
+		}
+		lines := fileLines(t, path)
+		if !strings.HasPrefix(lines[0], "Timestamp,") {
+			t.Errorf("expected a header row in %s, got %q", path, lines[0])
+		}
+		if int64(len(lines)-1) != rows {
+			t.Errorf("expected %d rows in %s, got %d", rows, path, len(lines)-1)
+		}
+	}
+	if dbFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.duckdb")); len(dbFiles) != 0 {
+		t.Errorf("expected no views database for CSV, got %v", dbFiles)
+	}
+}
+
+func TestExporterExportCSVEmptyDatabase(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	exporter := NewExporter(store, false)
+	opts := Options{Source: SourceAll, OutputDir: t.TempDir(), Format: FormatCSV}
+	summary, err := exporter.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !summary.IsEmpty() {
+		t.Errorf("expected all counts to be 0, got %+v", summary)
+	}
+	for _, path := range summary.OutputFiles {
+		if lines := fileLines(t, path); len(lines) != 1 || !strings.HasPrefix(lines[0], "Timestamp,") {
+			t.Errorf("expected a header-only CSV at %s, got %q", path, lines)
+		}
+		// Resumed exports count the rows of existing files
+		if count, err := exporter.countFileRows(context.Background(), path); err != nil || count != 0 {
+			t.Errorf("expected 0 rows counted in %s, got %d (%v)", path, count, err)
+		}
+	}
+}
+
+func TestExporterExportCSVWithZip(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	tmpDir := t.TempDir()
+	exporter := NewExporter(store, false)
+	opts := Options{Source: SourceAll, OutputDir: tmpDir, Format: FormatCSV, CreateZip: true}
+	summary, err := exporter.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if len(summary.OutputFiles) != 1 || summary.OutputFiles[0] != exporter.generateZipPath(opts) {
+		t.Fatalf("expected only the ZIP archive, got %v", summary.OutputFiles)
+	}
+	if csvFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.csv")); len(csvFiles) != 0 {
+		t.Errorf("expected CSV files to be removed after zipping, found %v", csvFiles)
+	}
+}
+
+func TestExporterExportCSVRejectsSingleDB(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	exporter := NewExporter(store, false)
+	_, err := exporter.Export(context.Background(), Options{Source: SourceAll, OutputDir: t.TempDir(), SingleDB: true, Format: FormatCSV})
+	if err == nil {
+		t.Error("expected error combining single database export with CSV")
+	}
+}
+
+func TestExporterExportJSONL(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	ctx := context.Background()
+	span := api.Span{
+		Timestamp: time.Now(), TraceID: "trace2", SpanID: "span3", SpanName: "tool", ServiceName: "claude-code",
+		SpanAttributes: map[string]string{"tool_name": "Bash"},
+		Events:         []api.SpanEvent{{Timestamp: time.Now(), Name: "exception", Attributes: map[string]string{"exception.type": "Timeout"}}},
+	}
+	if err := store.InsertSpans(ctx, []api.Span{span}); err != nil {
+		t.Fatalf("failed to insert span: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	exporter := NewExporter(store, false)
+	opts := Options{Source: SourceClaude, OutputDir: tmpDir, Format: FormatJSONL}
+	summary, err := exporter.Export(ctx, opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if summary.TracesCount != 3 || summary.LogsCount != 2 || summary.MetricsCount != 2 {
+		t.Errorf("unexpected summary counts: traces=%d logs=%d metrics=%d", summary.TracesCount, summary.LogsCount, summary.MetricsCount)
+	}
+	if len(summary.OutputFiles) != 3 {
+		t.Fatalf("expected only the JSONL files, got %v", summary.OutputFiles)
+	}
+	if dbFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.duckdb")); len(dbFiles) != 0 {
+		t.Errorf("expected no views database for JSONL, got %v", dbFiles)
+	}
+
+	var spans []api.Span
+	for _, line := range fileLines(t, filepath.Join(tmpDir, "traces.jsonl")) {
+		var s api.Span
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		spans = append(spans, s)
+	}
+	var found bool
+	for _, s := range spans {
+		if s.SpanID != "span3" {
+			continue
+		}
+		found = true
+		if s.SpanAttributes["tool_name"] != "Bash" {
+			t.Errorf("expected span attributes, got %v", s.SpanAttributes)
+		}
+		if len(s.Events) != 1 || s.Events[0].Attributes["exception.type"] != "Timeout" {
+			t.Errorf("expected span events with attributes, got %+v", s.Events)
+		}
+	}
+	if !found {
+		t.Errorf("expected span3 in traces.jsonl, got %+v", spans)
+	}
+
+	for _, line := range fileLines(t, filepath.Join(tmpDir, "logs.jsonl")) {
+		var log api.LogRecord
+		if err := json.Unmarshal([]byte(line), &log); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if log.ServiceName != "claude-code" {
+			t.Errorf("expected only claude-code logs, got %+v", log)
+		}
+	}
+
+	// Resumed exports count the rows of existing files
+	if count, err := exporter.countFileRows(ctx, filepath.Join(tmpDir, "metrics.jsonl")); err != nil || count != 2 {
+		t.Errorf("expected 2 rows counted in metrics.jsonl, got %d (%v)", count, err)
+	}
+}
+
+func TestExporterExportJSONLDateFilter(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	from := time.Now().Add(-45 * time.Minute)
+	opts := Options{Source: SourceAll, OutputDir: t.TempDir(), Format: FormatJSONL, FromDate: &from}
+	summary, err := NewExporter(store, false).Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if summary.TracesCount != 2 || summary.LogsCount != 2 || summary.MetricsCount != 2 {
+		t.Errorf("unexpected summary counts: traces=%d logs=%d metrics=%d", summary.TracesCount, summary.LogsCount, summary.MetricsCount)
+	}
+}
+
+func TestExporterExportJSONLEmptyDatabase(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	opts := Options{Source: SourceAll, OutputDir: t.TempDir(), Format: FormatJSONL}
+	summary, err := NewExporter(store, false).Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !summary.IsEmpty() {
+		t.Errorf("expected all counts to be 0, got %+v", summary)
+	}
+	for _, path := range summary.OutputFiles {
+		if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+			t.Errorf("expected an empty file at %s, got %v (%v)", path, info, err)
+		}
+	}
+}
+
+func TestExporterExportJSONLWithZip(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	tmpDir := t.TempDir()
+	exporter := NewExporter(store, false)
+	opts := Options{Source: SourceAll, OutputDir: tmpDir, Format: FormatJSONL, CreateZip: true}
+	summary, err := exporter.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if len(summary.OutputFiles) != 1 || summary.OutputFiles[0] != exporter.generateZipPath(opts) {
+		t.Fatalf("expected only the ZIP archive, got %v", summary.OutputFiles)
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, "*.jsonl")); len(files) != 0 {
+		t.Errorf("expected JSONL files to be removed after zipping, found %v", files)
+	}
+}
+
+// fakeUploader records the objects put to S3
+type fakeUploader struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (f *fakeUploader) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*params.Bucket+"/"+*params.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestParseS3URI(t *testing.T) {
+	tests := []struct {
+		uri        string
+		wantBucket string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"s3://bucket", "bucket", "", false},
+		{"s3://bucket/", "bucket", "", false},
+		{"s3://bucket/exports/daily/", "bucket", "exports/daily", false},
+		{"s3:///prefix", "", "", true},
+		{"https://bucket/prefix", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			bucket, prefix, err := ParseS3URI(tt.uri)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tt.uri)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bucket != tt.wantBucket || prefix != tt.wantPrefix {
+				t.Errorf("ParseS3URI(%q) = %q, %q; want %q, %q", tt.uri, bucket, prefix, tt.wantBucket, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestExporterExportToS3(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	tmpDir := t.TempDir()
+	uploader := &fakeUploader{objects: make(map[string][]byte)}
+	exporter := NewExporter(store, false)
+	exporter.newUploader = func(ctx context.Context) (objectUploader, error) { return uploader, nil }
+
+	opts := Options{Source: SourceAll, OutputDir: tmpDir, Format: FormatCSV, S3URI: "s3://bucket/exports/"}
+	summary, err := exporter.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(tmpDir, "traces.csv"), filepath.Join(tmpDir, "logs.csv"), filepath.Join(tmpDir, "metrics.csv"),
+		"s3://bucket/exports/traces.csv", "s3://bucket/exports/logs.csv", "s3://bucket/exports/metrics.csv",
+	}
+	if !reflect.DeepEqual(summary.OutputFiles, want) {
+		t.Errorf("expected output files %v, got %v", want, summary.OutputFiles)
+	}
+	local, err := os.ReadFile(filepath.Join(tmpDir, "logs.csv"))
+	if err != nil {
+		t.Fatalf("expected local files to be kept: %v", err)
+	}
+	if string(uploader.objects["bucket/exports/logs.csv"]) != string(local) {
+		t.Errorf("expected the uploaded object to match the local file")
+	}
+	if summary.TotalSize == 0 {
+		t.Error("expected total size of the local files")
+	}
+}
+
+func TestExporterExportToS3DeleteLocal(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	tmpDir := t.TempDir()
+	uploader := &fakeUploader{objects: make(map[string][]byte)}
+	exporter := NewExporter(store, false)
+	exporter.newUploader = func(ctx context.Context) (objectUploader, error) { return uploader, nil }
+
+	opts := Options{Source: SourceAll, OutputDir: tmpDir, CreateZip: true, S3URI: "s3://bucket", S3DeleteLocal: true}
+	summary, err := exporter.Export(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	zipName := opts.OutputName(".zip")
+	if len(summary.OutputFiles) != 1 || summary.OutputFiles[0] != "s3://bucket/"+zipName {
+		t.Fatalf("expected only the uploaded ZIP archive, got %v", summary.OutputFiles)
+	}
+	if _, ok := uploader.objects["bucket/"+zipName]; !ok {
+		t.Errorf("expected %s to be uploaded, got %v", zipName, uploader.objects)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("expected local files to be deleted, found %v", entries)
+	}
+}
+
+func TestExporterExportToS3Errors(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	setupTestData(t, store)
+
+	exporter := NewExporter(store, false)
+	uploader := &fakeUploader{err: errors.New("access denied")}
+	exporter.newUploader = func(ctx context.Context) (objectUploader, error) { return uploader, nil }
+
+	tmpDir := t.TempDir()
+	_, err := exporter.Export(context.Background(), Options{Source: SourceAll, OutputDir: tmpDir, S3URI: "s3://bucket", S3DeleteLocal: true})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("expected upload error, got %v", err)
+	}
+	// Nothing is deleted when the upload fails
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, "*.parquet")); len(files) != 3 {
+		t.Errorf("expected local files to be kept, found %v", files)
+	}
+
+	if _, err := exporter.Export(context.Background(), Options{Source: SourceAll, OutputDir: t.TempDir(), S3URI: "bucket/prefix"}); err == nil {
+		t.Error("expected error for an invalid S3 URI")
+	}
+	if _, err := exporter.Export(context.Background(), Options{Source: SourceAll, OutputDir: t.TempDir(), S3DeleteLocal: true}); err == nil {
+		t.Error("expected error deleting local files without an S3 URI")
+	}
+}
@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// exportJSONL writes a table to a newline-delimited JSON file with one api.Span,
+// api.LogRecord or api.MetricDataPoint object per line, in timestamp order
+func (e *Exporter) exportJSONL(ctx context.Context, table, outputPath string, from, to *time.Time, service string) (int64, error) {
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return 0, fmt.Errorf("creating %s: %w", outputPath, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	var count int64
+	write := func(record interface{}) error {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("writing record: %w", err)
+		}
+		count++
+		return nil
+	}
+
+	fromTime, toTime := getDateRange(from, to)
+	switch table {
+	case "otel_traces":
+		err = e.store.StreamSpansInRange(ctx, fromTime, toTime, service, func(span api.Span) error {
+			return write(span)
+		})
+	case "otel_logs":
+		err = e.store.StreamLogsInRange(ctx, fromTime, toTime, service, func(log api.LogRecord) error {
+			return write(log)
+		})
+	case "otel_metrics":
+		err = e.store.StreamMetricsInRange(ctx, fromTime, toTime, service, func(m api.MetricDataPoint) error {
+			return write(m)
+		})
+	default:
+		err = fmt.Errorf("unknown table: %s", table)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("writing %s: %w", outputPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("closing %s: %w", outputPath, err)
+	}
+	return count, nil
+}
+
+// countLines returns the number of newline-terminated lines in a file
+func countLines(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("counting rows in %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var count int64
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		count += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("counting rows in %s: %w", path, err)
+		}
+	}
+}
@@ -18,6 +18,27 @@ const (
 	SourceAll    SourceType = "all" // Export-specific: no filter
 )
 
+// ExportFormat is the file format the signal tables are exported to
+type ExportFormat string
+
+const (
+	FormatParquet ExportFormat = "parquet"
+	FormatCSV     ExportFormat = "csv"
+	FormatJSONL   ExportFormat = "jsonl" // One JSON object per row (NDJSON)
+)
+
+// ParquetCodec is the compression codec used for Parquet output
+type ParquetCodec string
+
+const (
+	CodecZSTD   ParquetCodec = "zstd"
+	CodecSnappy ParquetCodec = "snappy"
+	CodecGzip   ParquetCodec = "gzip"
+)
+
+// MaxCompressionLevel is the highest supported CompressionLevel (deflate best compression)
+const MaxCompressionLevel = 9
+
 // Options configures the export operation
 type Options struct {
 	Source      SourceType // Tool to export (claude, codex, gemini, all)
@@ -29,6 +50,24 @@ type Options struct {
 	DryRun      bool       // Preview without exporting
 	SkipConfirm bool       // Skip confirmation prompt
 	Verbose     bool       // Show detailed progress
+	Resume      bool       // Skip signals already completed by a previous run
+	SingleDB    bool       // Write one DuckDB file with data tables instead of Parquet files and views
+
+	Format           ExportFormat // Signal file format (default parquet); CSV and JSONL exports have no views database
+	ParquetCodec     ParquetCodec // Parquet compression codec (default zstd)
+	CompressionLevel int          // 0 = defaults (ZIP entries stored); 1-9 = ZIP deflate level and ZSTD level
+
+	// S3URI (s3://bucket/prefix) uploads the output files after they are created locally,
+	// using the standard AWS credentials; S3DeleteLocal removes the local files afterwards
+	S3URI         string
+	S3DeleteLocal bool
+
+	// FilenameTemplate names the .duckdb and .zip outputs (without extension) using the
+	// placeholders {source}, {from}, {to} and {ts}; empty keeps the default naming
+	FilenameTemplate string
+
+	// exportedAt is substituted for {ts}; set once per export so all outputs share it
+	exportedAt time.Time
 }
 
 // ServiceName returns the ServiceName filter value for this source
@@ -41,6 +80,53 @@ func (o *Options) ServiceName() string {
 	return tools.Tool(o.Source).ServiceName()
 }
 
+// Codec returns the Parquet codec to use, defaulting to ZSTD
+func (o *Options) Codec() ParquetCodec {
+	if o.ParquetCodec == "" {
+		return CodecZSTD
+	}
+	return o.ParquetCodec
+}
+
+// FileFormat returns the signal file format, defaulting to Parquet
+func (o *Options) FileFormat() ExportFormat {
+	if o.Format == "" {
+		return FormatParquet
+	}
+	return o.Format
+}
+
+// validateCompression checks the codec and compression level settings
+func (o *Options) validateCompression() error {
+	if _, err := ParseParquetCodec(string(o.Codec())); err != nil {
+		return err
+	}
+	if o.CompressionLevel < 0 || o.CompressionLevel > MaxCompressionLevel {
+		return fmt.Errorf("invalid compression level: %d (valid: 0-%d)", o.CompressionLevel, MaxCompressionLevel)
+	}
+	return nil
+}
+
+// copyOptions returns the COPY options writing a signal file in the export format. JSONL
+// files are written by the exporter itself and take no COPY options.
+func (o *Options) copyOptions() string {
+	if o.FileFormat() == FormatCSV {
+		return "FORMAT CSV, HEADER"
+	}
+	return "FORMAT PARQUET, " + o.parquetCompressionClause()
+}
+
+// parquetCompressionClause returns the COPY options selecting the Parquet codec and level
+func (o *Options) parquetCompressionClause() string {
+	codec := o.Codec()
+	clause := fmt.Sprintf("COMPRESSION '%s'", strings.ToUpper(string(codec)))
+	// DuckDB only honours an explicit compression level for ZSTD
+	if codec == CodecZSTD && o.CompressionLevel > 0 {
+		clause += fmt.Sprintf(", COMPRESSION_LEVEL %d", o.CompressionLevel)
+	}
+	return clause
+}
+
 // DateRangeString returns a formatted string for the date range
 // Returns "all" if no date filter is set
 func (o *Options) DateRangeString() string {
@@ -56,13 +142,53 @@ func (o *Options) DateRangeString() string {
 	return fmt.Sprintf("start-%s", o.ToDate.Format("2006-01-02"))
 }
 
+// ValidateFilenameTemplate checks that a filename template names a file inside the output directory
+func ValidateFilenameTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if strings.ContainsAny(template, `/\`) || strings.Contains(template, "..") {
+		return fmt.Errorf("invalid filename template: %s (must not contain path separators or '..')", template)
+	}
+	return nil
+}
+
+// OutputName returns the name of an output file with the given extension (e.g. ".zip"),
+// from FilenameTemplate or the default "ai-observer-export-<source>-<range>" naming.
+// {from} and {to} are YYYY-MM-DD dates, or "start" and "now" when unset. {ts} is the
+// export time as YYYYMMDD-HHMMSS (UTC) and is left as-is before the export starts.
+func (o *Options) OutputName(ext string) string {
+	if o.FilenameTemplate == "" {
+		return fmt.Sprintf("ai-observer-export-%s-%s%s", o.Source, o.DateRangeString(), ext)
+	}
+
+	from, to := "start", "now"
+	if o.FromDate != nil {
+		from = o.FromDate.Format("2006-01-02")
+	}
+	if o.ToDate != nil {
+		to = o.ToDate.Format("2006-01-02")
+	}
+	ts := "{ts}"
+	if !o.exportedAt.IsZero() {
+		ts = o.exportedAt.UTC().Format("20060102-150405")
+	}
+
+	return strings.NewReplacer(
+		"{source}", string(o.Source),
+		"{from}", from,
+		"{to}", to,
+		"{ts}", ts,
+	).Replace(o.FilenameTemplate) + ext
+}
+
 // Summary contains export statistics
 type Summary struct {
 	TracesCount  int64    // Number of trace spans exported
 	LogsCount    int64    // Number of log records exported
 	MetricsCount int64    // Number of metric data points exported
-	OutputFiles  []string // List of output file paths
-	TotalSize    int64    // Total size of exported files in bytes
+	OutputFiles  []string // List of output file paths, and s3:// URIs of uploaded files
+	TotalSize    int64    // Total size of exported files in bytes (measured before any upload)
 }
 
 // IsEmpty returns true if there's nothing to export
@@ -86,6 +212,44 @@ func ParseSourceArg(s string) (SourceType, error) {
 	}
 }
 
+// ParseParquetCodec parses a Parquet codec name (case-insensitive); empty selects ZSTD
+func ParseParquetCodec(s string) (ParquetCodec, error) {
+	switch strings.ToLower(s) {
+	case "", "zstd":
+		return CodecZSTD, nil
+	case "snappy":
+		return CodecSnappy, nil
+	case "gzip":
+		return CodecGzip, nil
+	default:
+		return "", fmt.Errorf("invalid parquet codec: %s (valid: %s)", s, strings.Join(ValidParquetCodecs(), ", "))
+	}
+}
+
+// ParseExportFormat parses an export format name (case-insensitive); empty selects Parquet
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "", "parquet":
+		return FormatParquet, nil
+	case "csv":
+		return FormatCSV, nil
+	case "jsonl":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("invalid export format: %s (valid: %s)", s, strings.Join(ValidExportFormats(), ", "))
+	}
+}
+
+// ValidExportFormats returns a list of valid export format names for help text
+func ValidExportFormats() []string {
+	return []string{string(FormatParquet), string(FormatCSV), string(FormatJSONL)}
+}
+
+// ValidParquetCodecs returns a list of valid Parquet codec names for help text
+func ValidParquetCodecs() []string {
+	return []string{string(CodecZSTD), string(CodecSnappy), string(CodecGzip)}
+}
+
 // ValidSources returns a list of valid source names for help text
 func ValidSources() []string {
 	return []string{"claude-code", "codex", "gemini", "all"}
@@ -3,11 +3,13 @@ package exporter
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
-// exportToParquet exports a table to Parquet format using DuckDB COPY TO
-func (e *Exporter) exportToParquet(ctx context.Context, table, outputPath string, from, to *time.Time, service string) (int64, error) {
+// exportTable exports a table to a file using DuckDB COPY TO. copyOptions is the COPY option
+// clause selecting the format and, for Parquet, the codec (see Options.copyOptions).
+func (e *Exporter) exportTable(ctx context.Context, table, outputPath string, from, to *time.Time, service, copyOptions string) (int64, error) {
 	var query string
 	var args []interface{}
 
@@ -16,28 +18,13 @@ func (e *Exporter) exportToParquet(ctx context.Context, table, outputPath string
 
 	if hasFilters {
 		// Build filtered query
-		query = fmt.Sprintf("SELECT * FROM %s WHERE 1=1", table)
-
-		if from != nil {
-			query += " AND Timestamp >= ?"
-			args = append(args, *from)
-		}
-
-		if to != nil {
-			query += " AND Timestamp <= ?"
-			args = append(args, *to)
-		}
-
-		if service != "" {
-			query += " AND ServiceName = ?"
-			args = append(args, service)
-		}
+		query, args = filteredSelect(table, from, to, service)
 
 		// Wrap in COPY statement
-		query = fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET, COMPRESSION 'ZSTD')", query, outputPath)
+		query = fmt.Sprintf("COPY (%s) TO '%s' (%s)", query, outputPath, copyOptions)
 	} else {
 		// Full table export (no filters)
-		query = fmt.Sprintf("COPY %s TO '%s' (FORMAT PARQUET, COMPRESSION 'ZSTD')", table, outputPath)
+		query = fmt.Sprintf("COPY %s TO '%s' (%s)", table, outputPath, copyOptions)
 	}
 
 	// Execute the COPY command
@@ -47,12 +34,51 @@ func (e *Exporter) exportToParquet(ctx context.Context, table, outputPath string
 	}
 
 	// Count the rows in the output file to report
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM read_parquet('%s')", outputPath)
-	var count int64
-	if err := e.store.DB().QueryRowContext(ctx, countQuery).Scan(&count); err != nil {
+	count, err := e.countFileRows(ctx, outputPath)
+	if err != nil {
 		// If we can't count, just return 0 - the export still succeeded
 		return 0, nil
 	}
 
 	return count, nil
 }
+
+// countFileRows returns the number of rows in an existing Parquet, CSV or JSONL file, by extension
+func (e *Exporter) countFileRows(ctx context.Context, path string) (int64, error) {
+	if strings.HasSuffix(path, "."+string(FormatJSONL)) {
+		return countLines(path)
+	}
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM read_parquet('%s')", path)
+	if strings.HasSuffix(path, "."+string(FormatCSV)) {
+		// All columns as VARCHAR, so a header-only file needs no type detection
+		countQuery = fmt.Sprintf("SELECT COUNT(*) FROM read_csv('%s', header = true, all_varchar = true)", path)
+	}
+	var count int64
+	if err := e.store.DB().QueryRowContext(ctx, countQuery).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting rows in %s: %w", path, err)
+	}
+	return count, nil
+}
+
+// filteredSelect builds a SELECT over a table restricted to the time range and service filters
+func filteredSelect(table string, from, to *time.Time, service string) (string, []interface{}) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE 1=1", table)
+	var args []interface{}
+
+	if from != nil {
+		query += " AND Timestamp >= ?"
+		args = append(args, *from)
+	}
+
+	if to != nil {
+		query += " AND Timestamp <= ?"
+		args = append(args, *to)
+	}
+
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	return query, args
+}
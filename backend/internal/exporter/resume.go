@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// exportSignal describes a single telemetry table exported to a file
+type exportSignal struct {
+	name  string // Signal name used in messages, marker files and the output filename
+	table string // Source table in the database
+}
+
+// exportSignals lists the signals in the order they are exported
+var exportSignals = []exportSignal{
+	{"traces", "otel_traces"},
+	{"logs", "otel_logs"},
+	{"metrics", "otel_metrics"},
+}
+
+// file returns the signal's filename in the output directory, e.g. "traces.parquet"
+func (sig exportSignal) file(format ExportFormat) string {
+	return sig.name + "." + string(format)
+}
+
+// markerPath returns the completion marker path for a signal.
+// Markers are hidden files so they don't clutter the export directory.
+func markerPath(outputDir, signal string) string {
+	return filepath.Join(outputDir, fmt.Sprintf(".%s.done", signal))
+}
+
+// resumeFingerprint identifies the options that determine a signal file's contents, so a
+// resumed export only keeps files written with the same source, range and file settings
+func (o *Options) resumeFingerprint() string {
+	formatDate := func(t *time.Time) string {
+		if t == nil {
+			return "none"
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("source=%s\nfromFiles=%t\nfrom=%s\nto=%s\nformat=%s\ncopy=%s\n",
+		o.Source, o.FromFiles, formatDate(o.FromDate), formatDate(o.ToDate), o.FileFormat(), o.copyOptions())))
+	return hex.EncodeToString(sum[:])
+}
+
+// markSignalComplete writes the completion marker for a signal, recording the fingerprint of
+// the options it was exported with
+func markSignalComplete(outputDir, signal, fingerprint string) error {
+	if err := os.WriteFile(markerPath(outputDir, signal), []byte(fingerprint), 0644); err != nil {
+		return fmt.Errorf("writing completion marker for %s: %w", signal, err)
+	}
+	return nil
+}
+
+// isSignalComplete returns true if a previous run with the same options fingerprint finished
+// exporting the signal and its output file is still present
+func isSignalComplete(outputDir, signal, outputPath, fingerprint string) bool {
+	marker, err := os.ReadFile(markerPath(outputDir, signal))
+	if err != nil || !bytes.Equal(bytes.TrimSpace(marker), []byte(fingerprint)) {
+		return false
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return false
+	}
+	return true
+}
+
+// removeCompletionMarkers deletes all completion markers in the output directory
+func removeCompletionMarkers(outputDir string) {
+	for _, sig := range exportSignals {
+		os.Remove(markerPath(outputDir, sig.name))
+	}
+}
@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// objectUploader is the part of the S3 client used to upload export files
+type objectUploader interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// newS3Uploader creates an S3 client from the standard AWS configuration: environment
+// variables (AWS_ACCESS_KEY_ID, AWS_REGION, AWS_ENDPOINT_URL, ...), shared config and
+// credentials files, and instance roles
+func newS3Uploader(ctx context.Context) (objectUploader, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+// ParseS3URI splits an s3://bucket/prefix URI into its bucket and key prefix (without
+// leading or trailing slashes; empty for the bucket root)
+func ParseS3URI(uri string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid S3 URI: %s (expected s3://bucket/prefix)", uri)
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 URI: %s (missing bucket)", uri)
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// uploadToS3 uploads the export's output files under opts.S3URI and returns their s3:// URIs.
+// Each file is stored under its base name, e.g. s3://bucket/prefix/traces.parquet.
+func (e *Exporter) uploadToS3(ctx context.Context, files []string, opts Options) ([]string, error) {
+	bucket, prefix, err := ParseS3URI(opts.S3URI)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader, err := e.newUploader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uris := make([]string, 0, len(files))
+	for _, file := range files {
+		key := path.Join(prefix, filepath.Base(file))
+		if e.verbose {
+			fmt.Printf("Uploading %s to s3://%s/%s... ", filepath.Base(file), bucket, key)
+		}
+		if err := uploadFile(ctx, uploader, file, bucket, key); err != nil {
+			return nil, err
+		}
+		if e.verbose {
+			fmt.Println("done")
+		}
+		uris = append(uris, fmt.Sprintf("s3://%s/%s", bucket, key))
+	}
+	return uris, nil
+}
+
+// uploadFile uploads a single local file to bucket/key
+func uploadFile(ctx context.Context, uploader objectUploader, file, bucket, key string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", file, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	_, err = uploader.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		Body:          f,
+		ContentLength: aws.Int64(info.Size()),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading %s to s3://%s/%s: %w", filepath.Base(file), bucket, key, err)
+	}
+	return nil
+}
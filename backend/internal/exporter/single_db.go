@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// singleDBAlias is the name the output database is attached under while it is populated
+const singleDBAlias = "export_db"
+
+// exportSingleDB writes the filtered data into one self-contained DuckDB file holding
+// otel_traces, otel_logs and otel_metrics tables (rather than views over Parquet files)
+func (e *Exporter) exportSingleDB(ctx context.Context, dbPath string, opts Options, service string) (*Summary, error) {
+	// Start from a fresh file so tables from an earlier export don't linger
+	for _, path := range []string{dbPath, dbPath + ".wal"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("removing existing database: %w", err)
+		}
+	}
+
+	// ATTACH and the copies must run on the same connection
+	conn, err := e.store.DB().Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	attach := fmt.Sprintf("ATTACH '%s' AS %s", strings.ReplaceAll(dbPath, "'", "''"), singleDBAlias)
+	if _, err := conn.ExecContext(ctx, attach); err != nil {
+		return nil, fmt.Errorf("attaching output database: %w", err)
+	}
+	attached := true
+	defer func() {
+		if attached {
+			conn.ExecContext(context.Background(), "DETACH "+singleDBAlias)
+		}
+	}()
+
+	summary := &Summary{}
+	for _, sig := range exportSignals {
+		if e.verbose {
+			fmt.Printf("Exporting %s... ", sig.name)
+		}
+
+		query, args := filteredSelect(sig.table, opts.FromDate, opts.ToDate, service)
+		create := fmt.Sprintf("CREATE TABLE %s.%s AS %s", singleDBAlias, sig.table, query)
+		if _, err := conn.ExecContext(ctx, create, args...); err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", sig.name, err)
+		}
+
+		var count int64
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", singleDBAlias, sig.table)
+		if err := conn.QueryRowContext(ctx, countQuery).Scan(&count); err != nil {
+			return nil, fmt.Errorf("counting %s: %w", sig.name, err)
+		}
+
+		switch sig.name {
+		case "traces":
+			summary.TracesCount = count
+		case "logs":
+			summary.LogsCount = count
+		case "metrics":
+			summary.MetricsCount = count
+		}
+
+		if e.verbose {
+			fmt.Printf("done (%d rows)\n", count)
+		}
+	}
+
+	// Detach now so the file is fully written before it is zipped or measured
+	if _, err := conn.ExecContext(ctx, "DETACH "+singleDBAlias); err != nil {
+		return nil, fmt.Errorf("detaching output database: %w", err)
+	}
+	attached = false
+
+	summary.OutputFiles = []string{dbPath}
+	return summary, nil
+}
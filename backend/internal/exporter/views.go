@@ -44,7 +44,8 @@ func (e *Exporter) createViewsDatabase(ctx context.Context, dbPath string) error
 	}
 
 	for _, v := range views {
-		query := fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM read_parquet('%s')", v.name, v.parquetFile)
+		// CREATE OR REPLACE so a resumed export can reuse a views database left by an earlier attempt
+		query := fmt.Sprintf("CREATE OR REPLACE VIEW %s AS SELECT * FROM read_parquet('%s')", v.name, v.parquetFile)
 		if _, err := viewsDB.ExecContext(ctx, query); err != nil {
 			return fmt.Errorf("creating view %s: %w", v.name, err)
 		}
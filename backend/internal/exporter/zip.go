@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"archive/zip"
+	"compress/flate"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,16 @@ import (
 // CreateZipArchive creates a ZIP file containing all exported files
 // Uses streaming to avoid loading entire files into memory
 func CreateZipArchive(outputDir string, files []string, zipPath string) error {
+	return CreateZipArchiveWithLevel(outputDir, files, zipPath, 0)
+}
+
+// CreateZipArchiveWithLevel creates a ZIP file, deflating entries at the given level (1-9).
+// Level 0 stores entries uncompressed.
+func CreateZipArchiveWithLevel(outputDir string, files []string, zipPath string, level int) error {
+	if level < 0 || level > flate.BestCompression {
+		return fmt.Errorf("invalid compression level: %d (valid: 0-%d)", level, flate.BestCompression)
+	}
+
 	zipFile, err := os.Create(zipPath)
 	if err != nil {
 		return fmt.Errorf("creating zip file: %w", err)
@@ -20,8 +31,16 @@ func CreateZipArchive(outputDir string, files []string, zipPath string) error {
 	zipWriter := zip.NewWriter(zipFile)
 	defer zipWriter.Close()
 
+	method := zip.Store
+	if level > 0 {
+		method = zip.Deflate
+		zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		})
+	}
+
 	for _, filePath := range files {
-		if err := addFileToZip(zipWriter, filePath); err != nil {
+		if err := addFileToZip(zipWriter, filePath, method); err != nil {
 			return fmt.Errorf("adding %s to zip: %w", filepath.Base(filePath), err)
 		}
 	}
@@ -29,8 +48,8 @@ func CreateZipArchive(outputDir string, files []string, zipPath string) error {
 	return nil
 }
 
-// addFileToZip adds a single file to the ZIP archive
-func addFileToZip(zipWriter *zip.Writer, filePath string) error {
+// addFileToZip adds a single file to the ZIP archive using the given method
+func addFileToZip(zipWriter *zip.Writer, filePath string, method uint16) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
@@ -50,9 +69,9 @@ func addFileToZip(zipWriter *zip.Writer, filePath string) error {
 	// Use only the base filename in the archive (flat structure)
 	header.Name = filepath.Base(filePath)
 
-	// Use Store method since Parquet files are already ZSTD compressed
-	// This avoids double-compression overhead
-	header.Method = zip.Store
+	// Store by default since Parquet files are already compressed,
+	// avoiding double-compression overhead unless a level was requested
+	header.Method = method
 
 	writer, err := zipWriter.CreateHeader(header)
 	if err != nil {
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tobilg/ai-observer/internal/alerts"
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// maxAlertWindowSeconds bounds the window of an alert rule to 30 days
+const maxAlertWindowSeconds = 30 * 24 * 60 * 60
+
+// ListAlertRules handles GET /api/alerts/rules
+func (h *Handlers) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.store.ListAlertRules(r.Context())
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, api.AlertRulesResponse{Rules: rules})
+}
+
+// GetAlertRule handles GET /api/alerts/rules/{id}
+func (h *Handlers) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		api.WriteError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	rule, err := h.store.GetAlertRule(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rule == nil {
+		api.WriteError(w, http.StatusNotFound, "alert rule not found")
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, rule)
+}
+
+// CreateAlertRule handles POST /api/alerts/rules
+func (h *Handlers) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req api.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateAlertRule(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.store.CreateAlertRule(r.Context(), &req)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateAlertRule handles PUT /api/alerts/rules/{id}, replacing all fields of the rule
+func (h *Handlers) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		api.WriteError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	var req api.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateAlertRule(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.store.UpdateAlertRule(r.Context(), id, &req)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rule == nil {
+		api.WriteError(w, http.StatusNotFound, "alert rule not found")
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, rule)
+}
+
+// DeleteAlertRule handles DELETE /api/alerts/rules/{id}. Alerts the rule fired are kept.
+func (h *Handlers) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		api.WriteError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	deleted, err := h.store.DeleteAlertRule(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		api.WriteError(w, http.StatusNotFound, "alert rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAlertEvents handles GET /api/alerts/events: the alerts fired in from/to (default: last
+// 24h), newest first, optionally of one rule (ruleId), up to limit (default 50)
+func (h *Handlers) ListAlertEvents(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+	limit, _ := parsePagination(r)
+
+	events, err := h.store.ListAlertEvents(r.Context(), r.URL.Query().Get("ruleId"), from, to, limit)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, api.AlertEventsResponse{Events: events})
+}
+
+// validateAlertRule checks the required fields and limits of a rule, trimming its names
+func validateAlertRule(req *api.AlertRuleRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.MetricName = strings.TrimSpace(req.MetricName)
+	req.Service = strings.TrimSpace(req.Service)
+
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(req.Name) > 255 {
+		return errors.New("name must be at most 255 characters")
+	}
+	if req.MetricName == "" {
+		return errors.New("metricName is required")
+	}
+	if !alerts.ValidComparison(req.Comparison) {
+		return fmt.Errorf("invalid comparison %q (valid: >, >=, <, <=)", req.Comparison)
+	}
+	if req.WindowSeconds <= 0 || req.WindowSeconds > maxAlertWindowSeconds {
+		return fmt.Errorf("windowSeconds must be between 1 and %d", maxAlertWindowSeconds)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// Limits for annotation notes and tags
+const (
+	maxAnnotationNoteLength = 10000
+	maxAnnotationTags       = 32
+)
+
+// ListAnnotations handles GET /api/annotations. The optional type (trace or log), targetId
+// and tag parameters narrow the list, e.g. tag=starred for the UI's starred items.
+func (h *Handlers) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := storage.AnnotationFilter{
+		TargetType: query.Get("type"),
+		TargetID:   query.Get("targetId"),
+		Tag:        query.Get("tag"),
+	}
+	switch filter.TargetType {
+	case "", api.AnnotationTargetTrace, api.AnnotationTargetLog:
+	default:
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid type %q (valid: trace, log)", filter.TargetType))
+		return
+	}
+	h.writeAnnotations(w, r, filter)
+}
+
+// GetTraceAnnotations handles GET /api/traces/{traceId}/annotations
+func (h *Handlers) GetTraceAnnotations(w http.ResponseWriter, r *http.Request) {
+	traceID := chi.URLParam(r, "traceId")
+	if traceID == "" {
+		api.WriteError(w, http.StatusBadRequest, "traceId is required")
+		return
+	}
+	h.writeAnnotations(w, r, storage.AnnotationFilter{TargetType: api.AnnotationTargetTrace, TargetID: traceID})
+}
+
+func (h *Handlers) writeAnnotations(w http.ResponseWriter, r *http.Request, filter storage.AnnotationFilter) {
+	annotations, err := h.store.ListAnnotations(r.Context(), filter)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, api.AnnotationsResponse{Annotations: annotations})
+}
+
+// AnnotateTrace handles POST /api/traces/{traceId}/annotations. The trace does not need to
+// be stored, and the annotation is kept when the trace is deleted later.
+func (h *Handlers) AnnotateTrace(w http.ResponseWriter, r *http.Request) {
+	traceID := chi.URLParam(r, "traceId")
+	if traceID == "" {
+		api.WriteError(w, http.StatusBadRequest, "traceId is required")
+		return
+	}
+
+	var req api.CreateAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateAnnotation(req.Note, req.Tags); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	annotation, err := h.store.AnnotateTrace(r.Context(), traceID, req.Note, req.Tags)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusCreated, annotation)
+}
+
+// AnnotateLog handles POST /api/logs/annotations. Logs have no ID, so the log is named by its
+// service and timestamp (see api.LogAnnotationTarget).
+func (h *Handlers) AnnotateLog(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateLogAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ServiceName == "" || req.Timestamp.IsZero() {
+		api.WriteError(w, http.StatusBadRequest, "serviceName and timestamp are required")
+		return
+	}
+	if err := validateAnnotation(req.Note, req.Tags); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	annotation, err := h.store.AnnotateLog(r.Context(), req.ServiceName, req.Timestamp, req.Note, req.Tags)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	api.WriteJSON(w, http.StatusCreated, annotation)
+}
+
+// DeleteAnnotation handles DELETE /api/annotations/{id}
+func (h *Handlers) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		api.WriteError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	deleted, err := h.store.DeleteAnnotation(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !deleted {
+		api.WriteError(w, http.StatusNotFound, "annotation not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateAnnotation checks the note and tag limits
+func validateAnnotation(note string, tags []string) error {
+	if len(note) > maxAnnotationNoteLength {
+		return fmt.Errorf("note must be at most %d characters", maxAnnotationNoteLength)
+	}
+	if len(tags) > maxAnnotationTags {
+		return fmt.Errorf("at most %d tags are allowed", maxAnnotationTags)
+	}
+	return nil
+}
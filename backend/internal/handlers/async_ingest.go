@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// asyncIngestQueueSize is the number of decoded batches that may wait for the async writer
+// before OTLP requests block
+const asyncIngestQueueSize = 1024
+
+// errAsyncIngestClosed is returned when a batch arrives after the async writer was drained
+var errAsyncIngestClosed = errors.New("async ingestion stopped")
+
+// asyncJob is a decoded OTLP batch waiting to be stored
+type asyncJob struct {
+	signal  string
+	service string
+	records int
+	persist func(ctx context.Context) error
+
+	// rows, when set, are the plain inserts persist performs, so the batch can be merged
+	// with others when insert batching is enabled
+	rows *ingestRows
+
+	// stored, when set, runs once the batch is committed and the write slot is released, so
+	// WebSocket broadcasts to slow clients do not hold up ingestion
+	stored func()
+}
+
+// AsyncIngest stores decoded OTLP batches in the background so handlers can acknowledge
+// a request as soon as it is decoded. Batches are written one at a time in arrival order
+// through the ingest queue. With insert batching, the rows of consecutive batches are
+// buffered and committed together. Batches still queued or buffered when the process dies
+// are lost.
+type AsyncIngest struct {
+	queue *IngestQueue
+	jobs  chan asyncJob
+	done  chan struct{}
+
+	batch    *ingestBatcher
+	buffered atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncIngest creates an async writer that acquires write slots from queue and starts
+// its worker goroutine
+func NewAsyncIngest(queue *IngestQueue) *AsyncIngest {
+	return newAsyncIngest(queue, nil)
+}
+
+// NewBatchedAsyncIngest creates an async writer that buffers the rows of batches and stores
+// them with insert in one transaction once maxRows rows are buffered or every interval.
+// Batches that cannot be merged, such as metrics that need earlier values for delta
+// derivation, flush the buffer and are then stored on their own.
+func NewBatchedAsyncIngest(queue *IngestQueue, maxRows int, interval time.Duration, insert func(ctx context.Context, rows ingestRows) error) *AsyncIngest {
+	return newAsyncIngest(queue, &ingestBatcher{maxRows: maxRows, interval: interval, insert: insert})
+}
+
+func newAsyncIngest(queue *IngestQueue, batch *ingestBatcher) *AsyncIngest {
+	a := &AsyncIngest{
+		queue: queue,
+		jobs:  make(chan asyncJob, asyncIngestQueueSize),
+		done:  make(chan struct{}),
+		batch: batch,
+	}
+	go a.run()
+	return a
+}
+
+// Enqueue queues a batch for storage. It blocks while the queue is full and returns ctx's
+// error if ctx ends first, or errAsyncIngestClosed once Drain has been called.
+func (a *AsyncIngest) Enqueue(ctx context.Context, job asyncJob) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return errAsyncIngestClosed
+	}
+	select {
+	case a.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pending returns the number of batches waiting to be stored, including buffered ones
+func (a *AsyncIngest) Pending() int {
+	return len(a.jobs) + int(a.buffered.Load())
+}
+
+// Flush waits until every batch queued before the call has been stored, or returns ctx's
+// error if ctx ends first. Unlike Drain, batches are still accepted afterwards.
+func (a *AsyncIngest) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	marker := asyncJob{signal: "flush", persist: func(context.Context) error {
+		close(done)
+		return nil
+	}}
+	if err := a.Enqueue(ctx, marker); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain stops accepting batches and waits until every queued or buffered batch has been
+// stored, or returns ctx's error if ctx ends first
+func (a *AsyncIngest) Drain(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.jobs)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *AsyncIngest) run() {
+	defer close(a.done)
+
+	ctx := context.Background()
+	var tick <-chan time.Time
+	if a.batch != nil && a.batch.interval > 0 {
+		ticker := time.NewTicker(a.batch.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case job, ok := <-a.jobs:
+			if !ok {
+				runStored(a.flushBatch(ctx))
+				return
+			}
+			a.store(ctx, job)
+		case <-tick:
+			runStored(a.flushBatch(ctx))
+		}
+	}
+}
+
+// store writes a job through the ingest queue, or buffers its rows when batching. The
+// stored callbacks of the written jobs run after the write slot is released.
+func (a *AsyncIngest) store(ctx context.Context, job asyncJob) {
+	release, err := a.queue.Acquire(ctx, job.service, job.records)
+	if err != nil {
+		return
+	}
+	stored := a.write(ctx, job)
+	release()
+	runStored(stored)
+}
+
+// write stores or buffers job and returns the stored callbacks of the jobs committed
+func (a *AsyncIngest) write(ctx context.Context, job asyncJob) []func() {
+	if a.batch != nil && job.rows != nil {
+		a.buffered.Add(1)
+		if a.batch.add(job) {
+			return a.flushBatch(ctx)
+		}
+		return nil
+	}
+
+	// Rows buffered before this job are stored first, so writes keep their arrival order
+	stored := a.flushBatch(ctx)
+	if err := job.persist(ctx); err != nil {
+		logger.Error("Failed to store "+job.signal+" asynchronously", "error", err)
+		return stored
+	}
+	if job.stored != nil {
+		stored = append(stored, job.stored)
+	}
+	return stored
+}
+
+// flushBatch commits the buffered rows, if any, and returns the stored callbacks of the jobs
+// that were stored
+func (a *AsyncIngest) flushBatch(ctx context.Context) []func() {
+	if a.batch == nil {
+		return nil
+	}
+	jobs := len(a.batch.pending)
+	rows, stored, err := a.batch.flush(ctx)
+	a.buffered.Add(-int64(jobs))
+	if err != nil {
+		logger.Error("Failed to store buffered batches", "batches", jobs, "rows", rows, "error", err)
+		return stored
+	}
+	if jobs > 0 {
+		logger.Debug("Stored buffered batches", "batches", jobs, "rows", rows)
+	}
+	return stored
+}
+
+// runStored runs stored callbacks in order
+func runStored(stored []func()) {
+	for _, fn := range stored {
+		fn()
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"sync/atomic"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/otlp"
+)
+
+// AttributeLimit caps the number of entries in each attribute map of ingested records so
+// malformed or adversarial records cannot store unbounded attribute maps
+type AttributeLimit struct {
+	max       int
+	truncated atomic.Int64
+}
+
+// NewAttributeLimit creates a limit of max entries per attribute map; max <= 0 disables it
+func NewAttributeLimit(max int) *AttributeLimit {
+	return &AttributeLimit{max: max}
+}
+
+// ApplySpans truncates the attribute maps of spans in place
+func (l *AttributeLimit) ApplySpans(spans []api.Span) {
+	l.truncated.Add(int64(otlp.LimitSpanAttributes(spans, l.max)))
+}
+
+// ApplyLogs truncates the attribute maps of log records in place
+func (l *AttributeLimit) ApplyLogs(logs []api.LogRecord) {
+	l.truncated.Add(int64(otlp.LimitLogAttributes(logs, l.max)))
+}
+
+// ApplyMetrics truncates the attribute maps of metric data points in place
+func (l *AttributeLimit) ApplyMetrics(metrics []api.MetricDataPoint) {
+	l.truncated.Add(int64(otlp.LimitMetricAttributes(metrics, l.max)))
+}
+
+// Truncated returns the number of attributes removed since startup
+func (l *AttributeLimit) Truncated() int64 {
+	return l.truncated.Load()
+}
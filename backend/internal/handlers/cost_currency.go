@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// CostCurrency converts USD costs into a display currency using a static exchange rate.
+// Stored costs stay in USD; converted values are only added to responses.
+type CostCurrency struct {
+	currency *api.CostCurrency // nil when costs are shown in USD only
+}
+
+// NewCostCurrency creates a converter for the given ISO currency code and rate (units per USD).
+// USD, an empty code or a non-positive rate disable conversion.
+func NewCostCurrency(code string, rate float64) *CostCurrency {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || code == "USD" {
+		return &CostCurrency{}
+	}
+	if rate <= 0 {
+		logger.Warn("Ignoring cost currency without a positive exchange rate", "currency", code, "rate", rate)
+		return &CostCurrency{}
+	}
+	return &CostCurrency{currency: &api.CostCurrency{Code: code, Rate: rate}}
+}
+
+// convert returns usd in the display currency, or nil when conversion is disabled
+func (c *CostCurrency) convert(usd float64) *float64 {
+	if c.currency == nil {
+		return nil
+	}
+	value := usd * c.currency.Rate
+	return &value
+}
+
+func (c *CostCurrency) applyProjects(resp *api.ProjectCostResponse) {
+	resp.Currency = c.currency
+	for i := range resp.Projects {
+		resp.Projects[i].Cost = c.convert(resp.Projects[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applyModels(resp *api.ModelCostResponse) {
+	resp.Currency = c.currency
+	for i := range resp.Models {
+		resp.Models[i].Cost = c.convert(resp.Models[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applyLatencyCost(resp *api.LatencyCostResponse) {
+	resp.Currency = c.currency
+	for i := range resp.Points {
+		resp.Points[i].Cost = c.convert(resp.Points[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applyPromptCost(resp *api.PromptCostResponse) {
+	resp.Currency = c.currency
+	for i := range resp.Prompts {
+		resp.Prompts[i].Cost = c.convert(resp.Prompts[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applySessionCost(resp *api.SessionCostResponse) {
+	resp.Currency = c.currency
+	resp.Total.Cost = c.convert(resp.Total.CostUSD)
+	for i := range resp.Models {
+		resp.Models[i].Cost = c.convert(resp.Models[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applyOverview(overview *api.OverviewResponse) {
+	overview.Currency = c.currency
+	overview.TodayCost = c.convert(overview.TodayCostUSD)
+	for i := range overview.TopModels {
+		overview.TopModels[i].Cost = c.convert(overview.TopModels[i].CostUSD)
+	}
+}
+
+func (c *CostCurrency) applyServiceSummary(summary *api.ServiceSummary) {
+	summary.Currency = c.currency
+	summary.Cost = c.convert(summary.CostUSD)
+}
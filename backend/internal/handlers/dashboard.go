@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
 )
 
 // ListDashboards handles GET /api/dashboards
@@ -42,6 +48,17 @@ func (h *Handlers) CreateDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateVariables(req.Variables); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.dashLimits.mu.Lock()
+	defer h.dashLimits.mu.Unlock()
+	if !h.allowNewDashboard(w, r, 0) {
+		return
+	}
+
 	dashboard, err := h.store.CreateDashboard(r.Context(), &req)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -51,7 +68,15 @@ func (h *Handlers) CreateDashboard(w http.ResponseWriter, r *http.Request) {
 	api.WriteJSON(w, http.StatusCreated, dashboard)
 }
 
-// GetDefaultDashboard handles GET /api/dashboards/default
+// Dashboard fallbacks for GET /api/dashboards/default when no dashboard is flagged as default
+const (
+	DashboardFallbackLatest = "latest" // The most recently updated dashboard
+	DashboardFallbackNone   = "none"   // 404
+)
+
+// GetDefaultDashboard handles GET /api/dashboards/default. Without a flagged default, the
+// most recently updated dashboard is returned as an implicit default unless the fallback
+// is DashboardFallbackNone.
 func (h *Handlers) GetDefaultDashboard(w http.ResponseWriter, r *http.Request) {
 	dashboard, err := h.store.GetDefaultDashboard(r.Context())
 	if err != nil {
@@ -59,6 +84,14 @@ func (h *Handlers) GetDefaultDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if dashboard == nil && h.dashFallback == DashboardFallbackLatest {
+		dashboard, err = h.store.GetLatestDashboard(r.Context())
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
 	if dashboard == nil {
 		api.WriteError(w, http.StatusNotFound, "no default dashboard found")
 		return
@@ -109,6 +142,11 @@ func (h *Handlers) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateVariables(req.Variables); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	dashboard, err := h.store.UpdateDashboard(r.Context(), id, &req)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -150,50 +188,133 @@ func (h *Handlers) SetDefaultDashboard(w http.ResponseWriter, r *http.Request) {
 	api.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-// CreateWidget handles POST /api/dashboards/{id}/widgets
-func (h *Handlers) CreateWidget(w http.ResponseWriter, r *http.Request) {
-	dashboardID := chi.URLParam(r, "id")
-	if dashboardID == "" {
+// ExportDashboard handles GET /api/dashboards/{id}/export
+func (h *Handlers) ExportDashboard(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
 		api.WriteError(w, http.StatusBadRequest, "dashboard id is required")
 		return
 	}
 
-	var req api.CreateWidgetRequest
+	export, err := h.store.ExportDashboard(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if export == nil {
+		api.WriteError(w, http.StatusNotFound, "dashboard not found")
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, export)
+}
+
+// ImportDashboard handles POST /api/dashboards/import. The body is a dashboard export as
+// returned by ExportDashboard; it is validated like a new dashboard and its widgets and
+// counts against the dashboard and widget limits.
+func (h *Handlers) ImportDashboard(w http.ResponseWriter, r *http.Request) {
+	var export api.DashboardExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if export.Version > api.DashboardExportVersion {
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unsupported export version %d (max %d)", export.Version, api.DashboardExportVersion))
+		return
+	}
+	if export.Name == "" {
+		api.WriteError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(export.Name) > 255 {
+		api.WriteError(w, http.StatusBadRequest, "name must be at most 255 characters")
+		return
+	}
+	if err := validateVariables(export.Variables); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range export.Widgets {
+		if err := validateWidget(&export.Widgets[i]); err != nil {
+			api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("widget %d: %v", i, err))
+			return
+		}
+	}
+
+	h.dashLimits.mu.Lock()
+	defer h.dashLimits.mu.Unlock()
+	if !h.allowNewDashboard(w, r, len(export.Widgets)) {
+		return
+	}
+
+	dashboard, err := h.store.ImportDashboard(r.Context(), &export)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusCreated, dashboard)
+}
+
+// CreateDashboardFromTemplate handles POST /api/dashboards/from-template with a body such as
+// {"template": "claude-overview"}; see storage.DashboardTemplateNames for the templates
+func (h *Handlers) CreateDashboardFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateDashboardFromTemplateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		api.WriteError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	if req.WidgetType == "" {
-		api.WriteError(w, http.StatusBadRequest, "widgetType is required")
+	template, ok := storage.DashboardTemplate(req.Template)
+	if !ok {
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown template %q (valid: %s)", req.Template, strings.Join(storage.DashboardTemplateNames(), ", ")))
 		return
 	}
-	if req.Title == "" {
-		api.WriteError(w, http.StatusBadRequest, "title is required")
+
+	h.dashLimits.mu.Lock()
+	defer h.dashLimits.mu.Unlock()
+	if !h.allowNewDashboard(w, r, len(template.Widgets)) {
 		return
 	}
 
-	// Validate title length (max 255 characters)
-	if len(req.Title) > 255 {
-		api.WriteError(w, http.StatusBadRequest, "title must be at most 255 characters")
+	dashboard, err := h.store.CreateDashboardFromTemplate(r.Context(), req.Template)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Validate grid position
-	if req.GridColumn < 0 {
-		api.WriteError(w, http.StatusBadRequest, "gridColumn must be non-negative")
+	api.WriteJSON(w, http.StatusCreated, dashboard)
+}
+
+// CreateWidget handles POST /api/dashboards/{id}/widgets
+func (h *Handlers) CreateWidget(w http.ResponseWriter, r *http.Request) {
+	dashboardID := chi.URLParam(r, "id")
+	if dashboardID == "" {
+		api.WriteError(w, http.StatusBadRequest, "dashboard id is required")
 		return
 	}
-	if req.GridRow < 0 {
-		api.WriteError(w, http.StatusBadRequest, "gridRow must be non-negative")
+
+	var req api.CreateWidgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	if req.ColSpan < 0 {
-		api.WriteError(w, http.StatusBadRequest, "colSpan must be non-negative")
+
+	if err := validateWidget(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if req.RowSpan < 0 {
-		api.WriteError(w, http.StatusBadRequest, "rowSpan must be non-negative")
+
+	h.dashLimits.mu.Lock()
+	defer h.dashLimits.mu.Unlock()
+	allowed, err := h.dashLimits.allowWidget(r.Context(), h.store, dashboardID)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !allowed {
+		api.WriteError(w, http.StatusConflict, fmt.Sprintf("widget limit reached (max %d per dashboard); delete a widget first", h.dashLimits.maxWidgets))
 		return
 	}
 
@@ -304,3 +425,197 @@ func (h *Handlers) DeleteWidget(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetWidgetData handles GET /api/dashboards/{id}/widgets/{widgetId}/data
+// Resolves a metric widget's config with the dashboard variables, taking each variable's value
+// from a var-<name> query parameter or its default, and returns the widget's series over
+// from/to (interval seconds, default 60).
+func (h *Handlers) GetWidgetData(w http.ResponseWriter, r *http.Request) {
+	dashboardID := chi.URLParam(r, "id")
+	widgetID := chi.URLParam(r, "widgetId")
+	if dashboardID == "" || widgetID == "" {
+		api.WriteError(w, http.StatusBadRequest, "dashboard id and widget id are required")
+		return
+	}
+
+	dashboard, err := h.store.GetDashboardWithWidgets(r.Context(), dashboardID)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if dashboard == nil {
+		api.WriteError(w, http.StatusNotFound, "dashboard not found")
+		return
+	}
+
+	var widget *api.DashboardWidget
+	for i := range dashboard.Widgets {
+		if dashboard.Widgets[i].ID == widgetID {
+			widget = &dashboard.Widgets[i]
+			break
+		}
+	}
+	if widget == nil {
+		api.WriteError(w, http.StatusNotFound, "widget not found")
+		return
+	}
+
+	// Only metric widgets are configured per widget; the others show fixed overview data
+	if widget.WidgetType != widgetTypeMetricValue && widget.WidgetType != widgetTypeMetricChart {
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("widget type %s has no widget data", widget.WidgetType))
+		return
+	}
+
+	values := make(map[string]string, len(dashboard.Variables))
+	for _, v := range dashboard.Variables {
+		values[v.Name] = v.Default
+		if value, ok := r.URL.Query()["var-"+v.Name]; ok {
+			values[v.Name] = value[0]
+		}
+	}
+	config := widget.Config.Resolve(values)
+	if config.MetricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "widget has no metric name")
+		return
+	}
+
+	from, to := parseTimeRange(r)
+	intervalSeconds := int64(60)
+	if s := r.URL.Query().Get("interval"); s != "" {
+		if parsed, err := strconv.ParseInt(s, 10, 64); err == nil && parsed > 0 {
+			intervalSeconds = parsed
+		}
+	}
+
+	// Value widgets show one aggregated number, as in the dashboard's batched fetch
+	query := api.MetricQuery{
+		ID:        widget.ID,
+		Name:      config.MetricName,
+		Service:   config.Service,
+		Aggregate: widget.WidgetType == widgetTypeMetricValue,
+	}
+	result := h.store.QueryBatchMetricSeries(r.Context(), []api.MetricQuery{query}, from, to, intervalSeconds).Results[0]
+	if !result.Success {
+		api.WriteError(w, http.StatusInternalServerError, result.Error)
+		return
+	}
+
+	series := result.Series
+	if config.BreakdownAttribute != "" && config.BreakdownValue != "" {
+		series = series[:0]
+		for _, s := range result.Series {
+			if s.Labels[config.BreakdownAttribute] == config.BreakdownValue {
+				series = append(series, s)
+			}
+		}
+	}
+	if series == nil {
+		series = []api.TimeSeries{}
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.WidgetDataResponse{WidgetID: widget.ID, Config: config, Series: series})
+}
+
+// GetVariableOptions handles GET /api/dashboards/{id}/variables/{name}/options
+// Lists the values of a query variable from its options query.
+func (h *Handlers) GetVariableOptions(w http.ResponseWriter, r *http.Request) {
+	dashboard, err := h.store.GetDashboard(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if dashboard == nil {
+		api.WriteError(w, http.StatusNotFound, "dashboard not found")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	var variable *api.DashboardVariable
+	for i := range dashboard.Variables {
+		if dashboard.Variables[i].Name == name {
+			variable = &dashboard.Variables[i]
+			break
+		}
+	}
+	if variable == nil {
+		api.WriteError(w, http.StatusNotFound, "variable not found")
+		return
+	}
+
+	var options []string
+	switch variable.OptionsQuery {
+	case api.VariableOptionsServices:
+		options, err = h.store.GetServices(r.Context())
+	case api.VariableOptionsMetricNames:
+		options, err = h.store.GetMetricNames(r.Context(), "")
+	}
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if options == nil {
+		options = []string{}
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.VariableOptionsResponse{Options: options})
+}
+
+// Widget types whose data is resolved from their config
+const (
+	widgetTypeMetricValue = "metric_value"
+	widgetTypeMetricChart = "metric_chart"
+)
+
+// variableNamePattern matches names usable as $name in widget configs
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateWidget checks that a new widget has a type and a title of at most 255 characters,
+// and that its grid position and size are non-negative
+func validateWidget(req *api.CreateWidgetRequest) error {
+	switch {
+	case req.WidgetType == "":
+		return errors.New("widgetType is required")
+	case req.Title == "":
+		return errors.New("title is required")
+	case len(req.Title) > 255:
+		return errors.New("title must be at most 255 characters")
+	case req.GridColumn < 0:
+		return errors.New("gridColumn must be non-negative")
+	case req.GridRow < 0:
+		return errors.New("gridRow must be non-negative")
+	case req.ColSpan < 0:
+		return errors.New("colSpan must be non-negative")
+	case req.RowSpan < 0:
+		return errors.New("rowSpan must be non-negative")
+	}
+	return nil
+}
+
+// validateVariables checks dashboard variables for valid, unique names, a known type and, for
+// query variables, a known options query
+func validateVariables(variables []api.DashboardVariable) error {
+	seen := make(map[string]bool, len(variables))
+	for _, v := range variables {
+		if !variableNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q (letters, digits and underscores, not starting with a digit)", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variable name %q", v.Name)
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case api.VariableTypeQuery:
+			if v.OptionsQuery != api.VariableOptionsServices && v.OptionsQuery != api.VariableOptionsMetricNames {
+				return fmt.Errorf("variable %q: invalid optionsQuery %q (valid: %s, %s)", v.Name, v.OptionsQuery, api.VariableOptionsServices, api.VariableOptionsMetricNames)
+			}
+		case api.VariableTypeText:
+			if v.OptionsQuery != "" {
+				return fmt.Errorf("variable %q: optionsQuery is only valid for query variables", v.Name)
+			}
+		default:
+			return fmt.Errorf("variable %q: invalid type %q (valid: %s, %s)", v.Name, v.Type, api.VariableTypeQuery, api.VariableTypeText)
+		}
+	}
+	return nil
+}
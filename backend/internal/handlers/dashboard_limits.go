@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// DashboardLimits caps how many dashboards an instance holds and how many widgets a dashboard
+// holds, so a runaway script or a user of a shared instance cannot create them without bound.
+// Handlers hold mu from the check until the item is created, so concurrent requests cannot
+// both take the last free slot.
+type DashboardLimits struct {
+	mu            sync.Mutex
+	maxDashboards int
+	maxWidgets    int
+}
+
+// NewDashboardLimits creates limits of maxDashboards dashboards and maxWidgets widgets per
+// dashboard; a limit <= 0 disables it
+func NewDashboardLimits(maxDashboards, maxWidgets int) *DashboardLimits {
+	return &DashboardLimits{maxDashboards: maxDashboards, maxWidgets: maxWidgets}
+}
+
+// allowDashboard reports whether another dashboard may be created; callers hold mu
+func (l *DashboardLimits) allowDashboard(ctx context.Context, store *storage.DuckDBStore) (bool, error) {
+	if l.maxDashboards <= 0 {
+		return true, nil
+	}
+	n, err := store.CountDashboards(ctx)
+	return n < l.maxDashboards, err
+}
+
+// allowWidget reports whether another widget may be added to a dashboard; callers hold mu
+func (l *DashboardLimits) allowWidget(ctx context.Context, store *storage.DuckDBStore, dashboardID string) (bool, error) {
+	if l.maxWidgets <= 0 {
+		return true, nil
+	}
+	n, err := store.CountWidgets(ctx, dashboardID)
+	return n < l.maxWidgets, err
+}
+
+// allowNewDashboard reports whether a dashboard with the given number of widgets may be
+// created, answering the request with 409 (or 500 when counting fails) when it may not.
+// Callers hold h.dashLimits.mu.
+func (h *Handlers) allowNewDashboard(w http.ResponseWriter, r *http.Request, widgets int) bool {
+	allowed, err := h.dashLimits.allowDashboard(r.Context(), h.store)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !allowed {
+		api.WriteError(w, http.StatusConflict, fmt.Sprintf("dashboard limit reached (max %d); delete a dashboard first", h.dashLimits.maxDashboards))
+		return false
+	}
+	if limit := h.dashLimits.maxWidgets; limit > 0 && widgets > limit {
+		api.WriteError(w, http.StatusConflict, fmt.Sprintf("dashboard has %d widgets, more than the limit of %d per dashboard", widgets, limit))
+		return false
+	}
+	return true
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/tobilg/ai-observer/internal/api"
@@ -165,6 +166,60 @@ func TestGetDefaultDashboard(t *testing.T) {
 	}
 }
 
+func TestGetDefaultDashboard_ImplicitLatest(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	getDefault := func() (int, *api.DashboardWithWidgets) {
+		req := httptest.NewRequest(http.MethodGet, "/api/dashboards/default", nil)
+		rec := httptest.NewRecorder()
+		h.GetDefaultDashboard(rec, req)
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+		var dashboard api.DashboardWithWidgets
+		if err := json.NewDecoder(rec.Body).Decode(&dashboard); err != nil {
+			t.Fatalf("failed to decode dashboard: %v", err)
+		}
+		return rec.Code, &dashboard
+	}
+
+	first := createTestDashboard(t, h, "First")
+	second := createTestDashboard(t, h, "Second")
+	createTestWidget(t, h, first.ID, "Tokens")
+
+	// Updating the first dashboard makes it the most recently updated one
+	if _, err := h.store.UpdateDashboard(context.Background(), first.ID, &api.UpdateDashboardRequest{Name: "First (edited)"}); err != nil {
+		t.Fatalf("UpdateDashboard failed: %v", err)
+	}
+
+	code, dashboard := getDefault()
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200 with an implicit default, got %d", code)
+	}
+	if dashboard.Dashboard.ID != first.ID || dashboard.Dashboard.IsDefault || len(dashboard.Widgets) != 1 {
+		t.Errorf("expected the latest dashboard %s with its widget as implicit default, got %+v", first.ID, dashboard)
+	}
+
+	// An explicit default overrides the implicit one
+	setDashboardAsDefault(t, h, second.ID)
+	if _, err := h.store.UpdateDashboard(context.Background(), first.ID, &api.UpdateDashboardRequest{Name: "First (edited again)"}); err != nil {
+		t.Fatalf("UpdateDashboard failed: %v", err)
+	}
+	if _, dashboard := getDefault(); dashboard == nil || dashboard.Dashboard.ID != second.ID {
+		t.Errorf("expected the explicit default %s, got %+v", second.ID, dashboard)
+	}
+
+	// Without the fallback, only a flagged default is returned
+	if err := h.store.DeleteDashboard(context.Background(), second.ID); err != nil {
+		t.Fatalf("DeleteDashboard failed: %v", err)
+	}
+	h.SetDashboardFallback(DashboardFallbackNone)
+	if code, _ := getDefault(); code != http.StatusNotFound {
+		t.Errorf("expected status 404 with the fallback disabled, got %d", code)
+	}
+}
+
 func TestUpdateDashboard(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -346,6 +401,160 @@ func TestDeleteWidget(t *testing.T) {
 	}
 }
 
+func TestDashboardLimits(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetDashboardLimits(2, 1)
+
+	withParams := func(req *http.Request, params ...string) *http.Request {
+		rctx := chi.NewRouteContext()
+		for i := 0; i < len(params); i += 2 {
+			rctx.URLParams.Add(params[i], params[i+1])
+		}
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	createDashboard := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/dashboards", bytes.NewReader([]byte(`{"name":"Extra"}`)))
+		rec := httptest.NewRecorder()
+		h.CreateDashboard(rec, req)
+		return rec.Code
+	}
+	createWidget := func(dashboardID string) int {
+		body := []byte(`{"widgetType":"stats","title":"Extra"}`)
+		req := withParams(httptest.NewRequest(http.MethodPost, "/api/dashboards/"+dashboardID+"/widgets", bytes.NewReader(body)), "id", dashboardID)
+		rec := httptest.NewRecorder()
+		h.CreateWidget(rec, req)
+		return rec.Code
+	}
+
+	first := createTestDashboard(t, h, "First")
+	second := createTestDashboard(t, h, "Second")
+	if code := createDashboard(); code != http.StatusConflict {
+		t.Fatalf("expected status 409 above the dashboard limit, got %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	h.DeleteDashboard(rec, withParams(httptest.NewRequest(http.MethodDelete, "/api/dashboards/"+second.ID, nil), "id", second.ID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("failed to delete dashboard: %d", rec.Code)
+	}
+	if code := createDashboard(); code != http.StatusCreated {
+		t.Errorf("expected status 201 after a delete, got %d", code)
+	}
+
+	widget := createTestWidget(t, h, first.ID, "Only")
+	if code := createWidget(first.ID); code != http.StatusConflict {
+		t.Fatalf("expected status 409 above the widget limit, got %d", code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.DeleteWidget(rec, withParams(httptest.NewRequest(http.MethodDelete, "/api/dashboards/"+first.ID+"/widgets/"+widget.ID, nil), "id", first.ID, "widgetId", widget.ID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("failed to delete widget: %d", rec.Code)
+	}
+	if code := createWidget(first.ID); code != http.StatusCreated {
+		t.Errorf("expected status 201 after a delete, got %d", code)
+	}
+}
+
+func TestExportImportDashboard(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	dashboard := createTestDashboard(t, h, "Shared")
+	createTestWidget(t, h, dashboard.ID, "Requests")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/dashboards/"+dashboard.ID+"/export", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", dashboard.ID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	h.ExportDashboard(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	blob := rec.Body.Bytes()
+	if bytes.Contains(blob, []byte(dashboard.ID)) {
+		t.Errorf("export contains the dashboard id: %s", blob)
+	}
+
+	importBlob := func(body []byte) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ImportDashboard(rec, httptest.NewRequest(http.MethodPost, "/api/dashboards/import", bytes.NewReader(body)))
+		return rec
+	}
+
+	rec = importBlob(blob)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var imported api.DashboardWithWidgets
+	if err := json.NewDecoder(rec.Body).Decode(&imported); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if imported.ID == dashboard.ID || imported.Name != "Shared (2)" || len(imported.Widgets) != 1 || imported.Widgets[0].Title != "Requests" {
+		t.Errorf("unexpected imported dashboard: %+v", imported)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid json", `{`, http.StatusBadRequest},
+		{"missing name", `{"version":1,"widgets":[]}`, http.StatusBadRequest},
+		{"newer version", `{"version":99,"name":"Future","widgets":[]}`, http.StatusBadRequest},
+		{"invalid widget", `{"version":1,"name":"Bad","widgets":[{"widgetType":"stats"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := importBlob([]byte(tt.body)); rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	h.SetDashboardLimits(3, 0)
+	if rec := importBlob(blob); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 below the limit, got %d", rec.Code)
+	}
+	if rec := importBlob(blob); rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409 above the dashboard limit, got %d", rec.Code)
+	}
+}
+
+func TestCreateDashboardFromTemplate(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.CreateDashboardFromTemplate(rec, httptest.NewRequest(http.MethodPost, "/api/dashboards/from-template", bytes.NewReader([]byte(body))))
+		return rec
+	}
+
+	rec := create(`{"template":"claude-overview"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var dashboard api.DashboardWithWidgets
+	if err := json.NewDecoder(rec.Body).Decode(&dashboard); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if dashboard.Name != "Claude Code Overview" || len(dashboard.Widgets) == 0 {
+		t.Errorf("unexpected dashboard: %+v", dashboard)
+	}
+
+	if rec := create(`{"template":"unknown"}`); rec.Code != http.StatusBadRequest || !bytes.Contains(rec.Body.Bytes(), []byte("cost-tracking")) {
+		t.Errorf("expected 400 listing the templates, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	h.SetDashboardLimits(0, 2)
+	if rec := create(`{"template":"codex-overview"}`); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a template above the widget limit, got %d", rec.Code)
+	}
+}
+
 func TestUpdateWidgetPositions(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -408,6 +617,172 @@ func TestUpdateWidgetPositions(t *testing.T) {
 
 // Helper functions for dashboard tests
 
+func TestCreateDashboard_Variables(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		variables  []api.DashboardVariable
+		wantStatus int
+	}{
+		{"query variable", []api.DashboardVariable{{Name: "service", Type: "query", OptionsQuery: "services"}}, http.StatusCreated},
+		{"text variable", []api.DashboardVariable{{Name: "metric", Type: "text", Default: "cost.usage"}}, http.StatusCreated},
+		{"invalid name", []api.DashboardVariable{{Name: "1st", Type: "text"}}, http.StatusBadRequest},
+		{"duplicate name", []api.DashboardVariable{{Name: "a", Type: "text"}, {Name: "a", Type: "text"}}, http.StatusBadRequest},
+		{"invalid type", []api.DashboardVariable{{Name: "a", Type: "list"}}, http.StatusBadRequest},
+		{"invalid options query", []api.DashboardVariable{{Name: "a", Type: "query", OptionsQuery: "users"}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(api.CreateDashboardRequest{Name: "Templated", Variables: tt.variables})
+			req := httptest.NewRequest(http.MethodPost, "/api/dashboards", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			h.CreateDashboard(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetWidgetData_Variables(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	value := func(v float64) *float64 { return &v }
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", MetricName: "session.count", MetricType: "gauge", Value: value(3)},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "codex_cli_rs", MetricName: "session.count", MetricType: "gauge", Value: value(7)},
+	}
+	if err := h.store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	dashboard, err := h.store.CreateDashboard(ctx, &api.CreateDashboardRequest{
+		Name: "Per service",
+		Variables: []api.DashboardVariable{
+			{Name: "service", Type: api.VariableTypeQuery, Default: "claude-code", OptionsQuery: api.VariableOptionsServices},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	widget, err := h.store.CreateWidget(ctx, dashboard.ID, &api.CreateWidgetRequest{
+		WidgetType: "metric_chart",
+		Title:      "Sessions",
+		Config:     api.WidgetConfig{Service: "$service", MetricName: "session.count"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create widget: %v", err)
+	}
+	stats, err := h.store.CreateWidget(ctx, dashboard.ID, &api.CreateWidgetRequest{WidgetType: "stats_traces", Title: "Traces"})
+	if err != nil {
+		t.Fatalf("failed to create widget: %v", err)
+	}
+
+	get := func(widgetID, query string) (*httptest.ResponseRecorder, api.WidgetDataResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/dashboards/"+dashboard.ID+"/widgets/"+widgetID+"/data"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", dashboard.ID)
+		rctx.URLParams.Add("widgetId", widgetID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.GetWidgetData(rec, req)
+		var resp api.WidgetDataResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+	total := func(resp api.WidgetDataResponse) float64 {
+		var sum float64
+		for _, s := range resp.Series {
+			for _, dp := range s.DataPoints {
+				sum += dp[1]
+			}
+		}
+		return sum
+	}
+
+	// The variable's default selects claude-code
+	rec, resp := get(widget.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp.Config.Service != "claude-code" || total(resp) != 3 {
+		t.Errorf("expected claude-code data, got config %+v and total %v", resp.Config, total(resp))
+	}
+
+	// var-service overrides the default
+	_, resp = get(widget.ID, "?var-service=codex_cli_rs")
+	if resp.Config.Service != "codex_cli_rs" || total(resp) != 7 {
+		t.Errorf("expected codex data, got config %+v and total %v", resp.Config, total(resp))
+	}
+
+	// An empty value selects all services
+	_, resp = get(widget.ID, "?var-service=")
+	if resp.Config.Service != "" || total(resp) != 10 {
+		t.Errorf("expected data for all services, got config %+v and total %v", resp.Config, total(resp))
+	}
+
+	if rec, _ := get(stats.ID, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a stats widget, got %d", rec.Code)
+	}
+	if rec, _ := get("missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown widget, got %d", rec.Code)
+	}
+}
+
+func TestGetVariableOptions(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := h.store.InsertLogs(ctx, []api.LogRecord{{Timestamp: time.Now(), ServiceName: "claude-code", Body: "x"}}); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+	dashboard, err := h.store.CreateDashboard(ctx, &api.CreateDashboardRequest{
+		Name:      "Per service",
+		Variables: []api.DashboardVariable{{Name: "service", Type: api.VariableTypeQuery, OptionsQuery: api.VariableOptionsServices}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+
+	get := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/dashboards/"+dashboard.ID+"/variables/"+name+"/options", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", dashboard.ID)
+		rctx.URLParams.Add("name", name)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.GetVariableOptions(rec, req)
+		return rec
+	}
+
+	rec := get("service")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp api.VariableOptionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Options) != 1 || resp.Options[0] != "claude-code" {
+		t.Errorf("expected [claude-code], got %v", resp.Options)
+	}
+
+	if rec := get("missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown variable, got %d", rec.Code)
+	}
+}
+
 func createTestDashboard(t *testing.T, h *Handlers, name string) *api.Dashboard {
 	t.Helper()
 	body, _ := json.Marshal(map[string]interface{}{"name": name})
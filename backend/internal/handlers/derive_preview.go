@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/otlp"
+)
+
+// maxDerivePreviewSource bounds the stored points a derivation preview reads into memory
+const maxDerivePreviewSource = 100000
+
+var errDerivePreviewTooLarge = errors.New("too many source metrics")
+
+// PreviewDerivedMetrics handles GET /api/admin/derive/preview: it runs the ingestion-time
+// metric derivation (otlp.DeriveMetrics: Claude Code user-facing and Gemini CLI cost metrics)
+// over the source metrics stored in from/to, optionally of one service, and returns what would
+// be derived without writing anything. Codex CLI metrics are derived from SSE log events that
+// are not stored, so they cannot be previewed.
+func (h *Handlers) PreviewDerivedMetrics(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+	service := r.URL.Query().Get("service")
+
+	var source []api.MetricDataPoint
+	err := h.store.StreamMetricsInRange(r.Context(), from, to, service, func(m api.MetricDataPoint) error {
+		if !otlp.IsDerivationSource(m.MetricName) {
+			return nil
+		}
+		if len(source) == maxDerivePreviewSource {
+			return errDerivePreviewTooLarge
+		}
+		source = append(source, m)
+		return nil
+	})
+	if errors.Is(err, errDerivePreviewTooLarge) {
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("more than %d source metrics in range; narrow from/to or set service", maxDerivePreviewSource))
+		return
+	}
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	derived := otlp.DeriveMetrics(source)
+	sortDerivedMetrics(derived)
+
+	resp := api.DerivePreviewResponse{
+		From:          from,
+		To:            to,
+		SourceMetrics: len(source),
+		Counts:        make(map[string]int),
+		Metrics:       derived,
+	}
+	if resp.Metrics == nil {
+		resp.Metrics = []api.MetricDataPoint{}
+	}
+	for _, m := range derived {
+		resp.Counts[m.MetricName]++
+	}
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// sortDerivedMetrics orders derived points by time, name, service, model and token type, as
+// the Claude Code derivation returns them in map order
+func sortDerivedMetrics(metrics []api.MetricDataPoint) {
+	sort.SliceStable(metrics, func(i, j int) bool {
+		a, b := metrics[i], metrics[j]
+		switch {
+		case !a.Timestamp.Equal(b.Timestamp):
+			return a.Timestamp.Before(b.Timestamp)
+		case a.MetricName != b.MetricName:
+			return a.MetricName < b.MetricName
+		case a.ServiceName != b.ServiceName:
+			return a.ServiceName < b.ServiceName
+		case a.Attributes["model"] != b.Attributes["model"]:
+			return a.Attributes["model"] < b.Attributes["model"]
+		}
+		return a.Attributes["type"] < b.Attributes["type"]
+	})
+}
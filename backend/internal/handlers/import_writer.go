@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+	"github.com/tobilg/ai-observer/internal/websocket"
+)
+
+// ImportWriter stores records imported by the session file watcher and broadcasts them to
+// WebSocket clients, so watched sessions show up live like OTLP data
+type ImportWriter struct {
+	store *storage.DuckDBStore
+	hub   *websocket.Hub
+}
+
+// ImportWriter returns a writer for imported records that shares the OTLP broadcast path
+func (h *Handlers) ImportWriter() *ImportWriter {
+	return &ImportWriter{store: h.store, hub: h.hub}
+}
+
+// InsertBatch stores spans, logs and metrics in a single transaction and broadcasts them
+func (w *ImportWriter) InsertBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint) error {
+	return w.InsertImportBatch(ctx, spans, logs, metrics, nil)
+}
+
+// InsertImportBatch stores records with the import state of their session files in a single
+// transaction and broadcasts them, logs normalized like the default query view
+func (w *ImportWriter) InsertImportBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint, states []*storage.ImportState) error {
+	if err := w.store.InsertImportBatch(ctx, spans, logs, metrics, states); err != nil {
+		return err
+	}
+	if w.hub == nil {
+		return nil
+	}
+	if len(spans) > 0 {
+		w.hub.Broadcast(websocket.NewTracesMessage(spans))
+	}
+	if len(logs) > 0 {
+		api.NormalizeLogBodies(logs)
+		w.hub.Broadcast(websocket.NewLogsMessage(logs))
+	}
+	if len(metrics) > 0 {
+		w.hub.Broadcast(websocket.NewMetricsMessage(metrics))
+	}
+	return nil
+}
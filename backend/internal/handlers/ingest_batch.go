@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// ingestRows are converted rows that can be stored together with the rows of other batches
+type ingestRows struct {
+	spans   []api.Span
+	logs    []api.LogRecord
+	metrics []api.MetricDataPoint
+}
+
+func (r *ingestRows) len() int {
+	return len(r.spans) + len(r.logs) + len(r.metrics)
+}
+
+// ingestBatcher buffers the rows of async jobs so they are committed in one transaction once
+// maxRows rows are pending or the flush interval elapses. It is only used by the async
+// writer's goroutine.
+type ingestBatcher struct {
+	maxRows  int
+	interval time.Duration
+	insert   func(ctx context.Context, rows ingestRows) error
+
+	pending []asyncJob
+	rows    int
+}
+
+// add buffers the rows of job and reports whether the batch is full
+func (b *ingestBatcher) add(job asyncJob) bool {
+	b.pending = append(b.pending, job)
+	b.rows += job.rows.len()
+	return b.rows >= b.maxRows
+}
+
+// flush stores the buffered rows and returns the jobs' stored callbacks in arrival order, for
+// the caller to run once the write slot is released. When the combined insert fails, each
+// job is stored on its own with its persist function, so one bad batch does not discard the
+// others and failures its persist tolerates (such as derived metrics) stay non-fatal. The
+// buffer is reset either way, as the jobs were already acknowledged.
+func (b *ingestBatcher) flush(ctx context.Context) (rows int, stored []func(), err error) {
+	if len(b.pending) == 0 {
+		return 0, nil, nil
+	}
+	jobs, rows := b.pending, b.rows
+	b.pending, b.rows = nil, 0
+
+	var merged ingestRows
+	for _, job := range jobs {
+		merged.spans = append(merged.spans, job.rows.spans...)
+		merged.logs = append(merged.logs, job.rows.logs...)
+		merged.metrics = append(merged.metrics, job.rows.metrics...)
+	}
+
+	if err := b.insert(ctx, merged); err != nil {
+		logger.Warn("Failed to store buffered batches together, storing them one by one", "batches", len(jobs), "error", err)
+		var errs []error
+		for _, job := range jobs {
+			if err := job.persist(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("storing %s: %w", job.signal, err))
+				continue
+			}
+			if job.stored != nil {
+				stored = append(stored, job.stored)
+			}
+		}
+		return rows, stored, errors.Join(errs...)
+	}
+
+	for _, job := range jobs {
+		if job.stored != nil {
+			stored = append(stored, job.stored)
+		}
+	}
+	return rows, stored, nil
+}
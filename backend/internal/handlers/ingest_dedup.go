@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"google.golang.org/protobuf/proto"
+)
+
+// dedupKey identifies an export request by its signal and content
+type dedupKey [sha256.Size]byte
+
+// IngestDedup makes OTLP ingestion idempotent within a time window: an export request with
+// the same signal and content as one accepted less than window ago is acknowledged without
+// being stored again, e.g. when an exporter retries a batch whose response it never got.
+// Requests are compared after decoding, so a retry over OTLP/HTTP and OTLP/gRPC or as JSON
+// and protobuf is still recognized. Hits and misses are counted for /api/stats.
+type IngestDedup struct {
+	window time.Duration // 0 disables deduplication
+	now    func() time.Time
+
+	mu    sync.Mutex
+	seen  map[dedupKey]time.Time
+	order []dedupEntry // Accepted requests, oldest first, for expiry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type dedupEntry struct {
+	key dedupKey
+	at  time.Time
+}
+
+// NewIngestDedup creates a deduplicator with the given window; window <= 0 disables it
+func NewIngestDedup(window time.Duration) *IngestDedup {
+	return &IngestDedup{
+		window: max(window, 0),
+		now:    time.Now,
+		seen:   make(map[dedupKey]time.Time),
+	}
+}
+
+// Stats returns the window and the hit and miss counts since startup
+func (d *IngestDedup) Stats() api.DedupStats {
+	d.mu.Lock()
+	tracked := len(d.seen)
+	d.mu.Unlock()
+	return api.DedupStats{
+		WindowSeconds: d.window.Seconds(),
+		Hits:          d.hits.Load(),
+		Misses:        d.misses.Load(),
+		Tracked:       tracked,
+	}
+}
+
+// claim reports whether req duplicates a request for signal accepted within the window. If
+// not, req is remembered under the returned key until the window passes or settle forgets it.
+func (d *IngestDedup) claim(signal string, req proto.Message) (dedupKey, bool) {
+	if d.window == 0 {
+		return dedupKey{}, false
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		logger.Logger().Warn("Failed to hash OTLP request for deduplication", "signal", signal, "error", err)
+		return dedupKey{}, false
+	}
+	key := dedupKey(sha256.Sum256(append([]byte(signal+"\x00"), data...)))
+
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire(now)
+	if _, ok := d.seen[key]; ok {
+		d.hits.Add(1)
+		logger.Logger().Debug("Dropping duplicate OTLP request", "signal", signal)
+		return key, true
+	}
+	d.misses.Add(1)
+	d.seen[key] = now
+	d.order = append(d.order, dedupEntry{key: key, at: now})
+	return key, false
+}
+
+// settle forgets a claimed request when *err reports that storing it failed, so the
+// exporter's retry is stored rather than dropped as a duplicate
+func (d *IngestDedup) settle(key dedupKey, err *error) {
+	if d.window == 0 || *err == nil {
+		return
+	}
+	d.mu.Lock()
+	delete(d.seen, key)
+	d.mu.Unlock()
+}
+
+// expire forgets the requests accepted at least window before now; callers hold d.mu
+func (d *IngestDedup) expire(now time.Time) {
+	cutoff := now.Add(-d.window)
+	n := 0
+	for n < len(d.order) && !d.order[n].at.After(cutoff) {
+		entry := d.order[n]
+		// A key forgotten by settle and claimed again has a newer entry further on
+		if at, ok := d.seen[entry.key]; ok && at.Equal(entry.at) {
+			delete(d.seen, entry.key)
+		}
+		n++
+	}
+	d.order = d.order[n:]
+}
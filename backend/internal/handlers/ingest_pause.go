@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// ingestPausedRetryAfter is the Retry-After, in seconds, sent to OTLP clients while ingestion is paused
+const ingestPausedRetryAfter = 30
+
+// IngestPause lets operators stop accepting OTLP data at runtime, e.g. during compaction or
+// a backup. While paused, OTLP requests are rejected with 503 and Retry-After so exporters
+// buffer and retry instead of dropping data.
+type IngestPause struct {
+	// inflight is held for reading by every admitted OTLP request; Pause takes it for
+	// writing to wait until requests admitted before the pause have finished
+	inflight sync.RWMutex
+
+	mu     sync.Mutex
+	paused bool
+}
+
+// admit reports whether an OTLP request may proceed. On true, the caller must call done
+// once the request has finished.
+func (p *IngestPause) admit() bool {
+	p.inflight.RLock()
+	p.mu.Lock()
+	paused := p.paused
+	p.mu.Unlock()
+	if paused {
+		p.inflight.RUnlock()
+		return false
+	}
+	return true
+}
+
+func (p *IngestPause) done() {
+	p.inflight.RUnlock()
+}
+
+// Pause stops admitting OTLP requests and waits until those already admitted have finished
+func (p *IngestPause) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+
+	// Acquiring the write lock waits for admitted requests to release their read locks
+	p.inflight.Lock()
+	p.inflight.Unlock()
+}
+
+// Resume admits OTLP requests again
+func (p *IngestPause) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.mu.Unlock()
+}
+
+// Paused reports whether ingestion is paused
+func (p *IngestPause) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// IngestPauseMiddleware rejects OTLP requests with 503 and Retry-After while ingestion is paused
+func (h *Handlers) IngestPauseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.pause.admit() {
+			w.Header().Set("Retry-After", strconv.Itoa(ingestPausedRetryAfter))
+			api.WriteError(w, http.StatusServiceUnavailable, "ingestion paused")
+			return
+		}
+		defer h.pause.done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PauseIngest handles POST /api/admin/ingest/pause. It returns once requests admitted before
+// the pause have finished and batches queued for async storage have been written.
+func (h *Handlers) PauseIngest(w http.ResponseWriter, r *http.Request) {
+	h.pause.Pause()
+	if h.async != nil {
+		if err := h.async.Flush(r.Context()); err != nil {
+			api.WriteError(w, http.StatusServiceUnavailable, "flushing queued batches: "+err.Error())
+			return
+		}
+	}
+	logger.Info("OTLP ingestion paused")
+	h.writeIngestState(w)
+}
+
+// ResumeIngest handles POST /api/admin/ingest/resume
+func (h *Handlers) ResumeIngest(w http.ResponseWriter, r *http.Request) {
+	h.pause.Resume()
+	logger.Info("OTLP ingestion resumed")
+	h.writeIngestState(w)
+}
+
+// GetIngestState handles GET /api/admin/ingest
+func (h *Handlers) GetIngestState(w http.ResponseWriter, r *http.Request) {
+	h.writeIngestState(w)
+}
+
+func (h *Handlers) writeIngestState(w http.ResponseWriter) {
+	state := api.IngestState{Paused: h.pause.Paused()}
+	if h.async != nil {
+		state.PendingBatches = h.async.Pending()
+	}
+	api.WriteJSON(w, http.StatusOK, state)
+}
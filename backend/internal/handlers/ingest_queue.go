@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"container/heap"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// IngestQueue serializes store writes and shares them between services using weighted fair
+// queuing. Each write is tagged with a virtual finish time of start + records/weight, and the
+// waiting write with the smallest tag goes next, so a service that floods the OTLP endpoints
+// mostly delays its own telemetry rather than everyone else's.
+type IngestQueue struct {
+	mu       sync.Mutex
+	weights  map[string]float64
+	busy     bool
+	vtime    float64            // start tag of the write currently holding the slot
+	finish   map[string]float64 // finish tag of each service's latest write
+	waiting  ticketHeap
+	seq      uint64
+	counters map[string]*api.IngestCounters
+}
+
+type ingestTicket struct {
+	service  string
+	records  int
+	start    float64
+	finish   float64
+	seq      uint64
+	queuedAt time.Time
+	ready    chan struct{}
+	index    int // position in the heap, -1 once granted
+}
+
+// NewIngestQueue creates a queue from "service=weight" rules. Services without a rule get
+// weight 1; malformed or non-positive weights are ignored.
+func NewIngestQueue(rules []string) *IngestQueue {
+	q := &IngestQueue{
+		weights:  make(map[string]float64),
+		finish:   make(map[string]float64),
+		counters: make(map[string]*api.IngestCounters),
+	}
+	for _, rule := range rules {
+		service, weight, ok := strings.Cut(rule, "=")
+		service = strings.TrimSpace(service)
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if !ok || service == "" || err != nil || w <= 0 {
+			continue
+		}
+		q.weights[service] = w
+	}
+	return q
+}
+
+// Acquire waits until the service may write the given number of records and returns a
+// function that releases the write slot. It returns ctx's error if ctx ends while waiting.
+func (q *IngestQueue) Acquire(ctx context.Context, service string, records int) (func(), error) {
+	q.mu.Lock()
+	start := max(q.vtime, q.finish[service])
+	t := &ingestTicket{
+		service:  service,
+		records:  records,
+		start:    start,
+		finish:   start + float64(max(records, 1))/q.weight(service),
+		seq:      q.seq,
+		queuedAt: time.Now(),
+		ready:    make(chan struct{}),
+	}
+	q.seq++
+	q.finish[service] = t.finish
+
+	if !q.busy {
+		q.busy = true
+		q.grant(t)
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	heap.Push(&q.waiting, t)
+	q.mu.Unlock()
+
+	select {
+	case <-t.ready:
+		return q.releaseFunc(), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		if t.index >= 0 {
+			heap.Remove(&q.waiting, t.index)
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		q.mu.Unlock()
+		// Granted while giving up; hand the slot to the next writer
+		q.release()
+		return nil, ctx.Err()
+	}
+}
+
+// Counters returns a snapshot of the per-service ingestion counters
+func (q *IngestQueue) Counters() map[string]api.IngestCounters {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.counters) == 0 {
+		return nil
+	}
+	out := make(map[string]api.IngestCounters, len(q.counters))
+	for service, c := range q.counters {
+		out[service] = *c
+	}
+	return out
+}
+
+func (q *IngestQueue) weight(service string) float64 {
+	if w, ok := q.weights[service]; ok {
+		return w
+	}
+	return 1
+}
+
+// grant gives the write slot to t. Caller must hold q.mu.
+func (q *IngestQueue) grant(t *ingestTicket) {
+	q.vtime = t.start
+	t.index = -1
+
+	c, ok := q.counters[t.service]
+	if !ok {
+		c = &api.IngestCounters{}
+		q.counters[t.service] = c
+	}
+	c.Requests++
+	c.Records += int64(t.records)
+	c.QueueWaitMs += time.Since(t.queuedAt).Milliseconds()
+
+	close(t.ready)
+}
+
+// release passes the write slot to the waiting write with the smallest finish tag
+func (q *IngestQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting.Len() == 0 {
+		q.busy = false
+		return
+	}
+	q.grant(heap.Pop(&q.waiting).(*ingestTicket))
+}
+
+func (q *IngestQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() { once.Do(q.release) }
+}
+
+// ticketHeap orders waiting writes by finish tag, then arrival
+type ticketHeap []*ingestTicket
+
+func (h ticketHeap) Len() int { return len(h) }
+
+func (h ticketHeap) Less(i, j int) bool {
+	if h[i].finish != h[j].finish {
+		return h[i].finish < h[j].finish
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h ticketHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ticketHeap) Push(x any) {
+	t := x.(*ingestTicket)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *ticketHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	t.index = -1
+	return t
+}
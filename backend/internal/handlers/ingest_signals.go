@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// IngestSignals selects which OTLP signals (traces, metrics, logs) are stored. Requests for a
+// disabled signal are acknowledged with an empty OTLP success so exporters do not retry them.
+type IngestSignals struct {
+	enabled map[string]bool // nil enables every signal
+	ignored atomic.Int64
+}
+
+// NewIngestSignals enables the named signals; an empty list enables all of them
+func NewIngestSignals(signals []string) *IngestSignals {
+	s := &IngestSignals{}
+	if len(signals) == 0 {
+		return s
+	}
+	s.enabled = make(map[string]bool, len(signals))
+	for _, signal := range signals {
+		s.enabled[strings.ToLower(strings.TrimSpace(signal))] = true
+	}
+	return s
+}
+
+// Enabled reports whether the signal is stored
+func (s *IngestSignals) Enabled(signal string) bool {
+	return s.enabled == nil || s.enabled[signal]
+}
+
+// Ignored returns the number of requests for disabled signals acknowledged since startup
+func (s *IngestSignals) Ignored() int64 {
+	return s.ignored.Load()
+}
+
+// skip reports whether a request for signal should be acknowledged without being stored,
+// counting it as ignored
+func (s *IngestSignals) skip(signal string) bool {
+	if s.Enabled(signal) {
+		return false
+	}
+	s.ignored.Add(1)
+	logger.Debug("Ignoring disabled OTLP signal", "signal", signal)
+	return true
+}
+
+// ignore acknowledges an HTTP request for a disabled signal without decoding or storing it.
+// It reports false when the signal is enabled and the request should be handled.
+func (s *IngestSignals) ignore(w http.ResponseWriter, r *http.Request, signal string) bool {
+	if !s.skip(signal) {
+		return false
+	}
+	io.Copy(io.Discard, r.Body)
+	writeOTLPSuccess(w)
+	return true
+}
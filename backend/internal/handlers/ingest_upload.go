@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// maxUploadBytes limits the request body of a bulk upload (100 MB)
+const maxUploadBytes int64 = 100 * 1024 * 1024
+
+// maxUploadUncompressedBytes limits the total uncompressed size of the files in an uploaded
+// ZIP archive
+const maxUploadUncompressedBytes = 4 * maxUploadBytes
+
+// Bulk upload formats
+const (
+	uploadFormatJSONL   = "jsonl"
+	uploadFormatParquet = "parquet"
+	uploadFormatZip     = "zip"
+)
+
+// uploadTables maps the signal of an uploaded file to the table its Parquet rows go into
+var uploadTables = map[string]string{
+	"traces":  "otel_traces",
+	"logs":    "otel_logs",
+	"metrics": "otel_metrics",
+}
+
+// parquetUpload is an uploaded Parquet file waiting to be imported
+type parquetUpload struct {
+	signal string
+	data   []byte
+}
+
+// uploadBatch collects the records of an upload, so nothing is stored until every file
+// has been decoded and validated
+type uploadBatch struct {
+	spans   []api.Span
+	logs    []api.LogRecord
+	metrics []api.MetricDataPoint
+	parquet []parquetUpload
+}
+
+// UploadIngest handles POST /api/ingest/upload. The body is a JSONL or Parquet file of one
+// signal, selected with ?signal=traces|logs|metrics, or a ZIP archive of such files named
+// after their signal (traces.jsonl, logs.parquet, ...) as written by the export command.
+// JSONL lines use the api.Span, api.LogRecord and api.MetricDataPoint shapes; Parquet files
+// use the table columns. The format is detected from the content unless ?format= is given.
+func (h *Handlers) UploadIngest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	signal := query.Get("signal")
+	if _, ok := uploadTables[signal]; signal != "" && !ok {
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid signal %q (valid: traces, logs, metrics)", signal))
+		return
+	}
+	format := query.Get("format")
+	switch format {
+	case "", uploadFormatJSONL, uploadFormatParquet, uploadFormatZip:
+	default:
+		api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid format %q (valid: jsonl, parquet, zip)", format))
+		return
+	}
+
+	if r.ContentLength > maxUploadBytes {
+		api.WriteErrorFromError(w, api.NewPayloadTooLargeError(maxUploadBytes, r.ContentLength))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			api.WriteErrorFromError(w, api.NewPayloadTooLargeError(maxUploadBytes, r.ContentLength))
+			return
+		}
+		api.WriteError(w, http.StatusBadRequest, "failed to read body")
+		return
+	}
+
+	if format == "" {
+		format = detectUploadFormat(body)
+	}
+
+	batch := &uploadBatch{}
+	if format == uploadFormatZip {
+		err = batch.addZip(body)
+	} else if signal == "" {
+		err = errors.New("signal is required for jsonl and parquet uploads")
+	} else {
+		err = batch.addFile(format, signal, body)
+	}
+	if err == nil {
+		err = h.decodeParquet(r.Context(), batch)
+	}
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.storeUpload(r.Context(), batch)
+	if err != nil {
+		logger.Logger().Error("Failed to store upload", "error", err)
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Format = format
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// detectUploadFormat recognizes ZIP and Parquet files by their magic bytes; anything else
+// is treated as JSONL
+func detectUploadFormat(body []byte) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("PK\x03\x04")), bytes.HasPrefix(body, []byte("PK\x05\x06")):
+		return uploadFormatZip
+	case bytes.HasPrefix(body, []byte("PAR1")):
+		return uploadFormatParquet
+	default:
+		return uploadFormatJSONL
+	}
+}
+
+// addZip adds the JSONL and Parquet files of a ZIP archive. Files are matched by name, e.g.
+// traces.jsonl or logs.parquet; other entries such as the views database are skipped.
+func (b *uploadBatch) addZip(body []byte) error {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return fmt.Errorf("reading zip archive: %w", err)
+	}
+
+	remaining := maxUploadUncompressedBytes
+	found := false
+	for _, file := range archive.File {
+		signal, ext, _ := strings.Cut(path.Base(file.Name), ".")
+		if _, ok := uploadTables[signal]; !ok || file.FileInfo().IsDir() {
+			continue
+		}
+		if ext != uploadFormatJSONL && ext != uploadFormatParquet {
+			continue
+		}
+
+		data, err := readZipFile(file, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= int64(len(data))
+
+		if err := b.addFile(ext, signal, data); err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		found = true
+	}
+	if !found {
+		return errors.New("zip archive contains no traces, logs or metrics .jsonl or .parquet files")
+	}
+	return nil
+}
+
+// readZipFile reads an archive entry, failing once more than limit bytes are decompressed
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("zip archive exceeds the uncompressed size limit of %d bytes", maxUploadUncompressedBytes)
+	}
+	return data, nil
+}
+
+// addFile adds the records of a single JSONL or Parquet file of signal
+func (b *uploadBatch) addFile(format, signal string, data []byte) error {
+	if format == uploadFormatParquet {
+		b.parquet = append(b.parquet, parquetUpload{signal: signal, data: data})
+		return nil
+	}
+
+	switch signal {
+	case "traces":
+		return decodeJSONL(data, b.addSpan)
+	case "logs":
+		return decodeJSONL(data, b.addLog)
+	default:
+		return decodeJSONL(data, b.addMetric)
+	}
+}
+
+// addSpan, addLog and addMetric validate and add a decoded record
+func (b *uploadBatch) addSpan(span api.Span) error {
+	if err := validateUploadTimestamp(span); err != nil {
+		return err
+	}
+	if span.TraceID == "" || span.SpanID == "" {
+		return errors.New("traceId and spanId are required")
+	}
+	b.spans = append(b.spans, span)
+	return nil
+}
+
+func (b *uploadBatch) addLog(log api.LogRecord) error {
+	if err := validateUploadTimestamp(log); err != nil {
+		return err
+	}
+	b.logs = append(b.logs, log)
+	return nil
+}
+
+func (b *uploadBatch) addMetric(metric api.MetricDataPoint) error {
+	if err := validateUploadTimestamp(metric); err != nil {
+		return err
+	}
+	if metric.MetricName == "" {
+		return errors.New("metricName is required")
+	}
+	b.metrics = append(b.metrics, metric)
+	return nil
+}
+
+// decodeParquet reads the rows of the batch's Parquet files into its records, validated like
+// JSONL lines, so they are stored and broadcast together with them
+func (h *Handlers) decodeParquet(ctx context.Context, b *uploadBatch) error {
+	for _, file := range b.parquet {
+		var err error
+		switch file.signal {
+		case "traces":
+			err = h.store.ReadParquetSpans(ctx, bytes.NewReader(file.data), numberRows(b.addSpan))
+		case "logs":
+			err = h.store.ReadParquetLogs(ctx, bytes.NewReader(file.data), numberRows(b.addLog))
+		default:
+			err = h.store.ReadParquetMetrics(ctx, bytes.NewReader(file.data), numberRows(b.addMetric))
+		}
+		if err != nil {
+			return fmt.Errorf("%s parquet: %w", file.signal, err)
+		}
+	}
+	b.parquet = nil
+	return nil
+}
+
+// numberRows prefixes the errors of add with the 1-based number of the failing row
+func numberRows[T any](add func(T) error) func(T) error {
+	row := 0
+	return func(record T) error {
+		row++
+		if err := add(record); err != nil {
+			return fmt.Errorf("row %d: %w", row, err)
+		}
+		return nil
+	}
+}
+
+// decodeJSONL decodes one T per line and passes it to add. Blank lines are skipped.
+func decodeJSONL[T any](data []byte, add func(T) error) error {
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var record T
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if err := add(record); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateUploadTimestamp rejects records without a timestamp
+func validateUploadTimestamp(record any) error {
+	var missing bool
+	switch r := record.(type) {
+	case api.Span:
+		missing = r.Timestamp.IsZero()
+	case api.LogRecord:
+		missing = r.Timestamp.IsZero()
+	case api.MetricDataPoint:
+		missing = r.Timestamp.IsZero()
+	}
+	if missing {
+		return errors.New("timestamp is required")
+	}
+	return nil
+}
+
+// storeUpload inserts the decoded records in one transaction through the import writer, so
+// they are broadcast like imported session data
+func (h *Handlers) storeUpload(ctx context.Context, batch *uploadBatch) (*api.IngestUploadResponse, error) {
+	if err := h.ImportWriter().InsertBatch(ctx, batch.spans, batch.logs, batch.metrics); err != nil {
+		return nil, fmt.Errorf("storing upload: %w", err)
+	}
+
+	resp := &api.IngestUploadResponse{
+		Spans:   int64(len(batch.spans)),
+		Logs:    int64(len(batch.logs)),
+		Metrics: int64(len(batch.metrics)),
+	}
+	logger.Logger().Info("Stored bulk upload", "spans", resp.Spans, "logs", resp.Logs, "metrics", resp.Metrics)
+	return resp, nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// logSampleMaxSeverity is the highest SeverityNumber that is sampled (INFO4); WARN and
+// above are always kept
+const logSampleMaxSeverity = 12
+
+// LogSampler drops a random share of TRACE, DEBUG and INFO logs at ingestion so chatty
+// low-severity logs do not crowd out warnings and errors. Logs without a known severity are
+// always kept, as are WARN, ERROR and FATAL logs.
+type LogSampler struct {
+	rate    float64
+	dropped atomic.Int64
+	random  func() float64
+}
+
+// NewLogSampler creates a sampler that keeps low-severity logs with probability rate; rates
+// of 1 or more keep everything and rates of 0 or less drop every low-severity log
+func NewLogSampler(rate float64) *LogSampler {
+	return &LogSampler{rate: min(max(rate, 0), 1), random: rand.Float64}
+}
+
+// Apply returns the logs that are kept and counts the rest as dropped
+func (s *LogSampler) Apply(logs []api.LogRecord) []api.LogRecord {
+	if s.rate >= 1 {
+		return logs
+	}
+
+	kept := logs[:0]
+	for _, log := range logs {
+		if !sampledSeverity(log) || s.random() < s.rate {
+			kept = append(kept, log)
+		}
+	}
+	s.dropped.Add(int64(len(logs) - len(kept)))
+	return kept
+}
+
+// Dropped returns the number of logs dropped by sampling since startup
+func (s *LogSampler) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// sampledSeverity reports whether a log is TRACE, DEBUG or INFO, by its SeverityNumber or,
+// when that is unset, its SeverityText
+func sampledSeverity(log api.LogRecord) bool {
+	severity := log.SeverityNumber
+	if severity == 0 {
+		band, ok := severityNumbers[strings.ToUpper(strings.TrimSpace(log.SeverityText))]
+		if !ok {
+			return false
+		}
+		severity = band[0]
+	}
+	return severity <= logSampleMaxSeverity
+}
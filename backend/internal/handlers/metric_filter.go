@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"path"
+	"sync/atomic"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// MetricFilter drops metrics at ingestion based on glob patterns matched against the metric name.
+// An empty allowlist allows every metric; the denylist is applied after the allowlist.
+type MetricFilter struct {
+	allow   []string
+	deny    []string
+	dropped atomic.Int64
+}
+
+// NewMetricFilter creates a filter from allowlist and denylist glob patterns (path.Match syntax)
+func NewMetricFilter(allow, deny []string) *MetricFilter {
+	return &MetricFilter{allow: allow, deny: deny}
+}
+
+// Allowed reports whether a metric with the given name should be stored
+func (f *MetricFilter) Allowed(name string) bool {
+	if len(f.allow) > 0 && !matchAny(f.allow, name) {
+		return false
+	}
+	return !matchAny(f.deny, name)
+}
+
+// Apply returns the metrics that pass the filter and counts the rest as dropped
+func (f *MetricFilter) Apply(metrics []api.MetricDataPoint) []api.MetricDataPoint {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return metrics
+	}
+
+	kept := metrics[:0]
+	for _, m := range metrics {
+		if f.Allowed(m.MetricName) {
+			kept = append(kept, m)
+		}
+	}
+	f.dropped.Add(int64(len(metrics) - len(kept)))
+	return kept
+}
+
+// Dropped returns the number of metric data points dropped since startup
+func (f *MetricFilter) Dropped() int64 {
+	return f.dropped.Load()
+}
+
+// matchAny reports whether name matches any of the patterns; malformed patterns never match
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
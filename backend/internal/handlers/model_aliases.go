@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"path"
+	"strings"
+)
+
+// ModelAliases canonicalizes model attribute values at query time so that drifting model
+// names (e.g. dated snapshots of the same model) are grouped together. Stored data is never modified.
+type ModelAliases struct {
+	rules []modelAlias
+}
+
+type modelAlias struct {
+	pattern   string
+	canonical string
+}
+
+// NewModelAliases creates aliases from "pattern=canonical" rules, where pattern uses path.Match syntax.
+// Rules are evaluated in order and the first match wins; malformed rules are ignored.
+func NewModelAliases(rules []string) *ModelAliases {
+	a := &ModelAliases{}
+	for _, rule := range rules {
+		pattern, canonical, ok := strings.Cut(rule, "=")
+		pattern, canonical = strings.TrimSpace(pattern), strings.TrimSpace(canonical)
+		if !ok || pattern == "" || canonical == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			continue
+		}
+		a.rules = append(a.rules, modelAlias{pattern: pattern, canonical: canonical})
+	}
+	return a
+}
+
+// Canonical returns the canonical name for a model, or the model itself if no rule matches
+func (a *ModelAliases) Canonical(model string) string {
+	for _, rule := range a.rules {
+		if ok, _ := path.Match(rule.pattern, model); ok {
+			return rule.canonical
+		}
+	}
+	return model
+}
+
+// isModelAttribute reports whether an attribute key holds a model name (model, gen_ai.request.model, ...)
+func isModelAttribute(attribute string) bool {
+	return attribute == "model" || strings.HasSuffix(attribute, ".model")
+}
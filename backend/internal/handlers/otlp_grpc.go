@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RegisterGRPC registers the OTLP/gRPC trace, metrics and logs services on s. They share
+// the conversion and storage path of the OTLP/HTTP handlers.
+func (h *Handlers) RegisterGRPC(s *grpc.Server) {
+	coltracepb.RegisterTraceServiceServer(s, &grpcTraceService{h: h})
+	colmetricspb.RegisterMetricsServiceServer(s, &grpcMetricsService{h: h})
+	collogspb.RegisterLogsServiceServer(s, &grpcLogsService{h: h})
+}
+
+// GRPCPauseInterceptor rejects OTLP/gRPC calls with Unavailable and a retry delay while
+// ingestion is paused, the gRPC counterpart of IngestPauseMiddleware
+func (h *Handlers) GRPCPauseInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !h.pause.admit() {
+		st := grpcstatus.New(codes.Unavailable, "ingestion paused")
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(ingestPausedRetryAfter * time.Second),
+		}); err == nil {
+			st = detailed
+		}
+		return nil, st.Err()
+	}
+	defer h.pause.done()
+	return handler(ctx, req)
+}
+
+// grpcIngestError maps an error returned by an ingest function to a gRPC status. OTLP
+// exporters retry Unavailable, so batches that could not be queued are not lost.
+func grpcIngestError(signal string, err error) error {
+	if errors.Is(err, errIngestUnavailable) || errors.Is(err, errIngestCancelled) {
+		return grpcstatus.Error(codes.Unavailable, err.Error())
+	}
+	logger.Error("Failed to store "+signal, "error", err)
+	return grpcstatus.Error(codes.Internal, "failed to store "+signal)
+}
+
+type grpcTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	h *Handlers
+}
+
+// Export handles opentelemetry.proto.collector.trace.v1.TraceService/Export
+func (s *grpcTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if !s.h.signals.skip("traces") {
+		if err := s.h.ingestTraces(ctx, req); err != nil {
+			return nil, grpcIngestError("traces", err)
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type grpcMetricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	h *Handlers
+}
+
+// Export handles opentelemetry.proto.collector.metrics.v1.MetricsService/Export
+func (s *grpcMetricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	if !s.h.signals.skip("metrics") {
+		if err := s.h.ingestMetrics(ctx, req); err != nil {
+			return nil, grpcIngestError("metrics", err)
+		}
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+type grpcLogsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	h *Handlers
+}
+
+// Export handles opentelemetry.proto.collector.logs.v1.LogsService/Export
+func (s *grpcLogsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if !s.h.signals.skip("logs") {
+		if err := s.h.ingestLogs(ctx, req); err != nil {
+			return nil, grpcIngestError("logs", err)
+		}
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
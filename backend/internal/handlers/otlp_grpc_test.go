@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// startTestGRPC serves h's OTLP/gRPC services over an in-memory listener and returns a
+// client connection to them
+func startTestGRPC(t *testing.T, h *Handlers) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(h.GRPCPauseInterceptor))
+	h.RegisterGRPC(srv)
+	go srv.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return conn
+}
+
+func grpcResource() *resourcepb.Resource {
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "grpc-service"}}},
+		},
+	}
+}
+
+func grpcTraceRequest(traceID []byte, start time.Time) *coltracepb.ExportTraceServiceRequest {
+	return &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: grpcResource(),
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: "test-scope", Version: "1.0"},
+				Spans: []*tracepb.Span{{
+					TraceId:           traceID,
+					SpanId:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+					Name:              "llm_request",
+					Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+					StartTimeUnixNano: uint64(start.UnixNano()),
+					EndTimeUnixNano:   uint64(start.Add(250 * time.Millisecond).UnixNano()),
+					Attributes: []*commonpb.KeyValue{
+						{Key: "model", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "sonnet"}}},
+					},
+					Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestGRPC_TracesMatchHTTP(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	conn := startTestGRPC(t, h)
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	grpcTraceID := bytes.Repeat([]byte{0xaa}, 16)
+	httpTraceID := bytes.Repeat([]byte{0xbb}, 16)
+
+	if _, err := coltracepb.NewTraceServiceClient(conn).Export(ctx, grpcTraceRequest(grpcTraceID, start)); err != nil {
+		t.Fatalf("gRPC export failed: %v", err)
+	}
+
+	body, err := proto.Marshal(grpcTraceRequest(httpTraceID, start))
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	h.HandleTraces(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("HTTP export failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	grpcSpans, err := h.store.GetTraceSpans(ctx, hex.EncodeToString(grpcTraceID))
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+	httpSpans, err := h.store.GetTraceSpans(ctx, hex.EncodeToString(httpTraceID))
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+	if len(grpcSpans) != 1 || len(httpSpans) != 1 {
+		t.Fatalf("expected one span per transport, got %d via gRPC and %d via HTTP", len(grpcSpans), len(httpSpans))
+	}
+
+	grpcSpan, httpSpan := grpcSpans[0], httpSpans[0]
+	grpcSpan.TraceID, httpSpan.TraceID = "", ""
+	if !reflect.DeepEqual(grpcSpan, httpSpan) {
+		t.Errorf("spans differ between transports:\ngRPC: %+v\nHTTP: %+v", grpcSpan, httpSpan)
+	}
+}
+
+func TestGRPC_MetricsAndLogs(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	conn := startTestGRPC(t, h)
+
+	ctx := context.Background()
+	now := uint64(time.Now().Add(-time.Minute).UnixNano())
+
+	metrics := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: grpcResource(),
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "queue.depth",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{{
+							TimeUnixNano: now,
+							Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: 7},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}
+	if _, err := colmetricspb.NewMetricsServiceClient(conn).Export(ctx, metrics); err != nil {
+		t.Fatalf("gRPC metrics export failed: %v", err)
+	}
+
+	logs := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: grpcResource(),
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{{
+					TimeUnixNano:   now,
+					SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+					SeverityText:   "INFO",
+					Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello over grpc"}},
+				}},
+			}},
+		}},
+	}
+	if _, err := collogspb.NewLogsServiceClient(conn).Export(ctx, logs); err != nil {
+		t.Fatalf("gRPC logs export failed: %v", err)
+	}
+
+	stats, err := h.store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.MetricCount != 1 || stats.LogCount != 1 {
+		t.Errorf("expected 1 metric and 1 log, got %d metrics and %d logs", stats.MetricCount, stats.LogCount)
+	}
+}
+
+func TestGRPC_PausedAndDisabledSignals(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	conn := startTestGRPC(t, h)
+	client := coltracepb.NewTraceServiceClient(conn)
+	ctx := context.Background()
+
+	h.pause.Pause()
+	_, err := client.Export(ctx, grpcTraceRequest(bytes.Repeat([]byte{1}, 16), time.Now()))
+	if grpcstatus.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable while paused, got %v", err)
+	}
+	h.pause.Resume()
+
+	h.SetIngestSignals([]string{"metrics"})
+	if _, err := client.Export(ctx, grpcTraceRequest(bytes.Repeat([]byte{2}, 16), time.Now())); err != nil {
+		t.Fatalf("expected disabled signal to be acknowledged, got %v", err)
+	}
+
+	stats, err := h.store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.SpanCount != 0 {
+		t.Errorf("expected no stored spans, got %d", stats.SpanCount)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 
@@ -9,10 +10,14 @@ import (
 	"github.com/tobilg/ai-observer/internal/logger"
 	"github.com/tobilg/ai-observer/internal/otlp"
 	"github.com/tobilg/ai-observer/internal/websocket"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 )
 
 // HandleLogs handles POST /v1/logs
 func (h *Handlers) HandleLogs(w http.ResponseWriter, r *http.Request) {
+	if h.signals.ignore(w, r, "logs") {
+		return
+	}
 	log := logger.Logger()
 	contentType := r.Header.Get("Content-Type")
 
@@ -28,7 +33,7 @@ func (h *Handlers) HandleLogs(w http.ResponseWriter, r *http.Request) {
 	decoder, body, _, err := otlp.GetDecoderWithDetection(bytes.NewReader(rawBody), contentType)
 	if err != nil {
 		log.Error("Failed to detect logs format", "error", err)
-		api.WriteError(w, http.StatusBadRequest, err.Error())
+		writeDecoderError(w, err)
 		return
 	}
 
@@ -39,39 +44,79 @@ func (h *Handlers) HandleLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := otlp.ConvertLogs(req)
-
-	// Store logs
-	if err := h.store.InsertLogs(r.Context(), result.Logs); err != nil {
-		log.Error("Failed to store logs", "error", err)
-		api.WriteError(w, http.StatusInternalServerError, "failed to store logs")
+	if err := h.ingestLogs(r.Context(), req); err != nil {
+		writeIngestError(w, "logs", err)
 		return
 	}
 
-	// Store derived metrics (e.g., from Codex SSE events)
-	if len(result.DerivedMetrics) > 0 {
-		if err := h.store.InsertMetrics(r.Context(), result.DerivedMetrics); err != nil {
-			// Log but don't fail the request - metrics are supplementary
-			log.Warn("Failed to store derived metrics", "error", err)
-		} else {
-			log.Debug("Stored derived metrics from logs", "count", len(result.DerivedMetrics))
-		}
+	writeOTLPSuccess(w)
+}
+
+// ingestLogs converts and stores a decoded log export request, including the metrics
+// derived from it. It is shared by the OTLP/HTTP and OTLP/gRPC transports.
+func (h *Handlers) ingestLogs(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (err error) {
+	key, duplicate := h.dedup.claim("logs", req)
+	if duplicate {
+		return nil
+	}
+	defer h.dedup.settle(key, &err)
 
+	log := logger.Logger()
+	result := otlp.ConvertLogs(req)
+
+	// Metrics derived from the logs are kept even when their log is sampled out
+	received := len(result.Logs)
+	result.Logs = h.logSampler.Apply(result.Logs)
+	h.attrLimit.ApplyLogs(result.Logs)
+	h.attrLimit.ApplyMetrics(result.DerivedMetrics)
+
+	service := ""
+	if len(result.Logs) > 0 {
+		service = result.Logs[0].ServiceName
+	}
+	broadcast := func() {
 		// Broadcast derived metrics to WebSocket clients
-		if h.hub != nil {
+		if h.hub != nil && len(result.DerivedMetrics) > 0 {
 			h.hub.Broadcast(websocket.NewMetricsMessage(result.DerivedMetrics))
 		}
+
+		// Broadcast logs to WebSocket clients, normalized like the default query view
+		if h.hub != nil && len(result.Logs) > 0 {
+			api.NormalizeLogBodies(result.Logs)
+			h.hub.Broadcast(websocket.NewLogsMessage(result.Logs))
+		}
 	}
+	persist := func(ctx context.Context) error {
+		// Store logs
+		if err := h.store.InsertLogs(ctx, result.Logs); err != nil {
+			return err
+		}
 
-	// Broadcast logs to WebSocket clients
-	if h.hub != nil && len(result.Logs) > 0 {
-		h.hub.Broadcast(websocket.NewLogsMessage(result.Logs))
+		// Store derived metrics (e.g., from Codex SSE events)
+		if len(result.DerivedMetrics) > 0 {
+			if err := h.store.InsertMetrics(ctx, result.DerivedMetrics); err != nil {
+				// Log but don't fail the request - metrics are supplementary
+				log.Warn("Failed to store derived metrics", "error", err)
+			} else {
+				log.Debug("Stored derived metrics from logs", "count", len(result.DerivedMetrics))
+			}
+		}
+		return nil
 	}
 
-	log.Debug("Received log records", "count", len(result.Logs))
+	records := len(result.Logs) + len(result.DerivedMetrics)
+	job := asyncJob{
+		signal:  "logs",
+		service: service,
+		records: records,
+		persist: persist,
+		rows:    &ingestRows{logs: result.Logs, metrics: result.DerivedMetrics},
+		stored:  broadcast,
+	}
+	if err := h.persistBatch(ctx, job); err != nil {
+		return err
+	}
 
-	// OTLP success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("{}"))
+	log.Debug("Received log records", "count", received, "stored", len(result.Logs))
+	return nil
 }
@@ -8,17 +8,21 @@ import (
 	"github.com/tobilg/ai-observer/internal/logger"
 	"github.com/tobilg/ai-observer/internal/otlp"
 	"github.com/tobilg/ai-observer/internal/websocket"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 )
 
 // HandleMetrics handles POST /v1/metrics
 func (h *Handlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.signals.ignore(w, r, "metrics") {
+		return
+	}
 	log := logger.Logger()
 	contentType := r.Header.Get("Content-Type")
 
 	// Use format detection to handle Content-Type mismatches
 	decoder, body, _, err := otlp.GetDecoderWithDetection(r.Body, contentType)
 	if err != nil {
-		api.WriteError(w, http.StatusBadRequest, err.Error())
+		writeDecoderError(w, err)
 		return
 	}
 
@@ -29,38 +33,153 @@ func (h *Handlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := otlp.ConvertMetrics(req)
+	if err := h.ingestMetrics(r.Context(), req); err != nil {
+		writeIngestError(w, "metrics", err)
+		return
+	}
 
-	// Derive delta metrics from cumulative metrics using DB lookup for previous values
-	lookup := func(ctx context.Context, metricName, serviceName string, attributes map[string]string) (float64, bool) {
-		return h.store.GetLatestMetricValue(ctx, metricName, serviceName, attributes)
+	writeOTLPSuccess(w)
+}
+
+// ingestMetrics converts, filters and stores a decoded metric export request. It is shared
+// by the OTLP/HTTP and OTLP/gRPC transports so both produce identical rows.
+func (h *Handlers) ingestMetrics(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (err error) {
+	key, duplicate := h.dedup.claim("metrics", req)
+	if duplicate {
+		return nil
 	}
-	deltaResult := otlp.ConvertCumulativeToDelta(r.Context(), result.Metrics, lookup)
+	defer h.dedup.settle(key, &err)
 
-	// Combine: original metrics + delta metrics + other derived metrics (like cost)
-	allMetrics := append(deltaResult.Original, deltaResult.Deltas...)
-	allMetrics = append(allMetrics, result.DerivedMetrics...)
+	result := otlp.ConvertMetrics(req)
 
-	if err := h.store.InsertMetrics(r.Context(), allMetrics); err != nil {
-		log.Error("Failed to store metrics", "error", err)
-		api.WriteError(w, http.StatusInternalServerError, "failed to store metrics")
-		return
+	// Drop metrics excluded by the configured allowlist/denylist before deriving deltas
+	received := len(result.Metrics) + len(result.DerivedMetrics)
+	result.Metrics = h.metricFilter.Apply(result.Metrics)
+	result.DerivedMetrics = h.metricFilter.Apply(result.DerivedMetrics)
+	dropped := received - len(result.Metrics) - len(result.DerivedMetrics)
+	h.attrLimit.ApplyMetrics(result.Metrics)
+	h.attrLimit.ApplyMetrics(result.DerivedMetrics)
+
+	// Cumulative-to-delta derivation looks up the previously stored value, so in async mode
+	// it runs in the writer after earlier batches have been stored. The other metrics are
+	// plain inserts that can be merged with other batches, unless timestamps are rounded
+	// and stored with ReplaceMetrics.
+	if h.async != nil {
+		cumulative := result
+		var plain []api.MetricDataPoint
+		if h.tsRes == 0 {
+			cumulative.Metrics = nil
+			for _, m := range result.Metrics {
+				if otlp.ShouldConvertToDelta(m.MetricName) {
+					cumulative.Metrics = append(cumulative.Metrics, m)
+				} else {
+					plain = append(plain, m)
+				}
+			}
+			plain = append(plain, result.DerivedMetrics...)
+			cumulative.DerivedMetrics = nil
+		}
+
+		if len(plain) > 0 {
+			persist := func(ctx context.Context) error {
+				_, err := h.storeMetrics(ctx, plain)
+				return err
+			}
+			job := asyncJob{
+				signal:  "metrics",
+				service: plain[0].ServiceName,
+				records: len(plain),
+				persist: persist,
+				rows:    &ingestRows{metrics: plain},
+				stored:  func() { h.broadcastMetrics(plain) },
+			}
+			if err := h.persistBatch(ctx, job); err != nil {
+				return err
+			}
+		}
+
+		pending := append(append([]api.MetricDataPoint{}, cumulative.Metrics...), cumulative.DerivedMetrics...)
+		if len(pending) == 0 {
+			return nil
+		}
+		var stored []api.MetricDataPoint
+		persist := func(ctx context.Context) (err error) {
+			allMetrics, _ := h.deriveMetrics(ctx, cumulative)
+			stored, err = h.storeMetrics(ctx, allMetrics)
+			return err
+		}
+		job := asyncJob{
+			signal:  "metrics",
+			service: pending[0].ServiceName,
+			records: len(pending),
+			persist: persist,
+			stored:  func() { h.broadcastMetrics(stored) },
+		}
+		return h.persistBatch(ctx, job)
 	}
 
-	// Broadcast to WebSocket clients
-	if h.hub != nil && len(allMetrics) > 0 {
-		h.hub.Broadcast(websocket.NewMetricsMessage(allMetrics))
+	allMetrics, deltaResult := h.deriveMetrics(ctx, result)
+
+	service := ""
+	if len(allMetrics) > 0 {
+		service = allMetrics[0].ServiceName
+	}
+	var stored []api.MetricDataPoint
+	persist := func(ctx context.Context) (err error) {
+		stored, err = h.storeMetrics(ctx, allMetrics)
+		return err
+	}
+	job := asyncJob{
+		signal:  "metrics",
+		service: service,
+		records: len(allMetrics),
+		persist: persist,
+		stored:  func() { h.broadcastMetrics(stored) },
+	}
+	if err := h.persistBatch(ctx, job); err != nil {
+		return err
 	}
 
-	log.Debug("Received metrics",
+	logger.Debug("Received metrics",
 		"received", len(result.Metrics),
 		"stored", len(allMetrics),
+		"dropped", dropped,
 		"original", len(deltaResult.Original),
 		"deltas", len(deltaResult.Deltas),
 		"derived", len(result.DerivedMetrics))
+	return nil
+}
+
+// deriveMetrics derives delta metrics from cumulative metrics using DB lookup for previous
+// values and returns original metrics + delta metrics + other derived metrics (like cost)
+func (h *Handlers) deriveMetrics(ctx context.Context, result otlp.MetricConversionResult) ([]api.MetricDataPoint, otlp.CumulativeToDeltaResult) {
+	lookup := func(ctx context.Context, metricName, serviceName string, attributes map[string]string) (float64, bool) {
+		return h.store.GetLatestMetricValue(ctx, metricName, serviceName, attributes)
+	}
+	deltaResult := otlp.ConvertCumulativeToDelta(ctx, result.Metrics, lookup)
 
-	// OTLP success response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("{}"))
+	allMetrics := append(deltaResult.Original, deltaResult.Deltas...)
+	allMetrics = append(allMetrics, result.DerivedMetrics...)
+	return allMetrics, deltaResult
+}
+
+// storeMetrics stores metrics and returns the points stored, with timestamps rounded when a
+// timestamp resolution is configured
+func (h *Handlers) storeMetrics(ctx context.Context, metrics []api.MetricDataPoint) ([]api.MetricDataPoint, error) {
+	if h.tsRes > 0 {
+		metrics = otlp.RoundMetricTimestamps(metrics, h.tsRes)
+		if err := h.store.ReplaceMetrics(ctx, metrics); err != nil {
+			return nil, err
+		}
+	} else if err := h.store.InsertMetrics(ctx, metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// broadcastMetrics sends stored metrics to WebSocket clients
+func (h *Handlers) broadcastMetrics(metrics []api.MetricDataPoint) {
+	if h.hub != nil && len(metrics) > 0 {
+		h.hub.Broadcast(websocket.NewMetricsMessage(metrics))
+	}
 }
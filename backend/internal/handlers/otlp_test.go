@@ -1,12 +1,32 @@
 package handlers
 
 import (
+	"archive/zip"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+	"github.com/tobilg/ai-observer/pkg/compression"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
 )
 
 // OTLP JSON payload structures for testing
@@ -29,8 +49,9 @@ type scopeSpan struct {
 }
 
 type scope struct {
-	Name    string `json:"name,omitempty"`
-	Version string `json:"version,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Version    string     `json:"version,omitempty"`
+	Attributes []keyValue `json:"attributes,omitempty"`
 }
 
 type span struct {
@@ -43,6 +64,10 @@ type span struct {
 	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
 	Attributes        []keyValue `json:"attributes,omitempty"`
 	Status            *status    `json:"status,omitempty"`
+
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount,omitempty"`
+	DroppedEventsCount     uint32 `json:"droppedEventsCount,omitempty"`
+	DroppedLinksCount      uint32 `json:"droppedLinksCount,omitempty"`
 }
 
 type status struct {
@@ -71,6 +96,7 @@ type resourceLog struct {
 }
 
 type scopeLog struct {
+	Scope      *scope      `json:"scope,omitempty"`
 	LogRecords []logRecord `json:"logRecords"`
 }
 
@@ -82,6 +108,8 @@ type logRecord struct {
 	Attributes     []keyValue `json:"attributes,omitempty"`
 	TraceID        string     `json:"traceId,omitempty"`
 	SpanID         string     `json:"spanId,omitempty"`
+
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount,omitempty"`
 }
 
 type otlpMetricsRequest struct {
@@ -94,6 +122,7 @@ type resourceMetric struct {
 }
 
 type scopeMetric struct {
+	Scope   *scope   `json:"scope,omitempty"`
 	Metrics []metric `json:"metrics"`
 }
 
@@ -224,6 +253,57 @@ func gzipCompress(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Helper to zstd-compress data
+func zstdCompress(data []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil), nil
+}
+
+func TestHandleTraces_CompressedBody(t *testing.T) {
+	body, err := json.Marshal(createTracesPayload())
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	for _, tc := range []struct {
+		encoding string
+		compress func([]byte) ([]byte, error)
+	}{
+		{"zstd", zstdCompress},
+		{"gzip", gzipCompress},
+	} {
+		t.Run(tc.encoding, func(t *testing.T) {
+			h, cleanup := setupTestHandlers(t)
+			defer cleanup()
+
+			compressed, err := tc.compress(body)
+			if err != nil {
+				t.Fatalf("failed to compress payload: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(compressed))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", tc.encoding)
+			rec := httptest.NewRecorder()
+			compression.DecompressMiddleware(10<<20)(http.HandlerFunc(h.HandleTraces)).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			stats, err := h.store.GetStats(context.Background())
+			if err != nil {
+				t.Fatalf("GetStats failed: %v", err)
+			}
+			if stats.SpanCount != 1 {
+				t.Errorf("expected 1 stored span, got %d", stats.SpanCount)
+			}
+		})
+	}
+}
+
 func TestHandleTraces_ValidJSON(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -462,6 +542,203 @@ func TestHandleMetrics_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestHandleMetrics_AllowDenyList(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetMetricFilter([]string{"claude_code.*", "keep_me"}, []string{"claude_code.debug.*"})
+
+	gauge := func(name string) metric {
+		return metric{
+			Name: name,
+			Gauge: &gaugeMetric{
+				DataPoints: []dataPoint{{TimeUnixNano: "1609459200000000000", AsDouble: 1.0}},
+			},
+		}
+	}
+
+	payload := otlpMetricsRequest{
+		ResourceMetrics: []resourceMetric{
+			{
+				Resource: resource{
+					Attributes: []keyValue{
+						{Key: "service.name", Value: anyValue{StringValue: "test-service"}},
+					},
+				},
+				ScopeMetrics: []scopeMetric{
+					{
+						Metrics: []metric{
+							gauge("claude_code.session.count"),
+							gauge("claude_code.debug.heap"),
+							gauge("keep_me"),
+							gauge("noisy_metric"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.HandleMetrics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	names, err := h.store.GetMetricNames(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetMetricNames failed: %v", err)
+	}
+
+	stored := make(map[string]bool)
+	for _, name := range names {
+		stored[name] = true
+	}
+	if len(stored) != 2 || !stored["claude_code.session.count"] || !stored["keep_me"] {
+		t.Errorf("expected only allowed metrics to be stored, got %v", names)
+	}
+
+	if dropped := h.metricFilter.Dropped(); dropped != 2 {
+		t.Errorf("expected 2 dropped metrics, got %d", dropped)
+	}
+}
+
+func TestHandleLogs_SeveritySampling(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetLogSampleRate(0.25)
+
+	const perSeverity = 2000
+	var records []logRecord
+	for i := 0; i < perSeverity; i++ {
+		ts := fmt.Sprintf("%d", time.Now().UnixNano()+int64(i))
+		records = append(records,
+			logRecord{TimeUnixNano: ts, SeverityNumber: 9, SeverityText: "INFO", Body: anyValue{StringValue: "info"}},
+			logRecord{TimeUnixNano: ts, SeverityNumber: 17, SeverityText: "ERROR", Body: anyValue{StringValue: "error"}},
+		)
+		if i < 100 {
+			records = append(records,
+				logRecord{TimeUnixNano: ts, SeverityNumber: 13, SeverityText: "WARN", Body: anyValue{StringValue: "warn"}},
+				logRecord{TimeUnixNano: ts, Body: anyValue{StringValue: "no severity"}},
+			)
+		}
+	}
+	payload := createLogsPayload()
+	payload.ResourceLogs[0].ScopeLogs[0].LogRecords = records
+	body, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.HandleLogs(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	counts := map[string]int64{}
+	rows, err := h.store.DB().Query("SELECT Body, COUNT(*) FROM otel_logs GROUP BY Body")
+	if err != nil {
+		t.Fatalf("counting logs: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var body string
+		var n int64
+		if err := rows.Scan(&body, &n); err != nil {
+			t.Fatalf("scanning count: %v", err)
+		}
+		counts[body] = n
+	}
+
+	if counts["error"] != perSeverity || counts["warn"] != 100 || counts["no severity"] != 100 {
+		t.Errorf("expected every error, warning and unknown-severity log to be kept, got %v", counts)
+	}
+	if info := counts["info"]; info < perSeverity/5 || info > perSeverity*3/10 {
+		t.Errorf("expected roughly 25%% of %d info logs, got %d", perSeverity, info)
+	}
+
+	stats := &api.StatsResponse{}
+	h.addRuntimeStats(stats)
+	if stats.SampledLogs != perSeverity-counts["info"] {
+		t.Errorf("SampledLogs = %d, want %d", stats.SampledLogs, perSeverity-counts["info"])
+	}
+}
+
+func TestLogSampler_Apply(t *testing.T) {
+	logs := []api.LogRecord{
+		{SeverityNumber: 1},
+		{SeverityNumber: 5},
+		{SeverityNumber: 12},
+		{SeverityNumber: 13},
+		{SeverityNumber: 21},
+		{SeverityText: "debug"},
+		{SeverityText: "Warn"},
+		{SeverityText: "custom"},
+		{},
+	}
+
+	for _, tt := range []struct {
+		rate float64
+		kept int
+	}{
+		{1, 9},
+		{2, 9},
+		{0, 5},
+		{-1, 5},
+	} {
+		s := NewLogSampler(tt.rate)
+		kept := s.Apply(append([]api.LogRecord(nil), logs...))
+		if len(kept) != tt.kept {
+			t.Errorf("rate %v: kept %d logs, want %d", tt.rate, len(kept), tt.kept)
+		}
+		if s.Dropped() != int64(len(logs)-tt.kept) {
+			t.Errorf("rate %v: Dropped() = %d, want %d", tt.rate, s.Dropped(), len(logs)-tt.kept)
+		}
+	}
+
+	// A fixed draw just below or above the rate decides the low-severity logs
+	s := NewLogSampler(0.5)
+	s.random = func() float64 { return 0.49 }
+	if kept := s.Apply(append([]api.LogRecord(nil), logs...)); len(kept) != len(logs) {
+		t.Errorf("draw below the rate: kept %d logs, want %d", len(kept), len(logs))
+	}
+	s.random = func() float64 { return 0.5 }
+	if kept := s.Apply(append([]api.LogRecord(nil), logs...)); len(kept) != 5 {
+		t.Errorf("draw at the rate: kept %d logs, want 5", len(kept))
+	}
+}
+
+func TestMetricFilter_Allowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		input string
+		want  bool
+	}{
+		{"no lists allows all", nil, nil, "anything", true},
+		{"allowlist glob match", []string{"claude_code.*"}, nil, "claude_code.cost", true},
+		{"allowlist miss", []string{"claude_code.*"}, nil, "gemini_cli.cost", false},
+		{"denylist match", nil, []string{"*.debug"}, "heap.debug", false},
+		{"denylist miss", nil, []string{"*.debug"}, "heap.usage", true},
+		{"denylist overrides allowlist", []string{"claude_code.*"}, []string{"claude_code.cost"}, "claude_code.cost", false},
+		{"malformed pattern never matches", []string{"["}, nil, "anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewMetricFilter(tt.allow, tt.deny)
+			if got := f.Allowed(tt.input); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestHandleRoot_RoutesToTraces(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -566,6 +843,86 @@ func TestHandleTraces_AutoDetectJSON(t *testing.T) {
 	}
 }
 
+func TestHandleOTLP_Protobuf(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := uint64(time.Now().Add(-time.Minute).UnixNano())
+	metrics, _ := proto.Marshal(&colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: grpcResource(),
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "queue.depth",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{{
+							TimeUnixNano: now,
+							Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: 3},
+						}},
+					}},
+				}},
+			}},
+		}},
+	})
+	logs, _ := proto.Marshal(&collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: grpcResource(),
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{{
+					TimeUnixNano: now,
+					SeverityText: "INFO",
+					Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "protobuf log"}},
+				}},
+			}},
+		}},
+	})
+
+	for _, tc := range []struct {
+		path    string
+		body    []byte
+		handler http.HandlerFunc
+	}{
+		{"/v1/metrics", metrics, h.HandleMetrics},
+		{"/v1/logs", logs, h.HandleLogs},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		rec := httptest.NewRecorder()
+		tc.handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tc.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	stats, err := h.store.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.MetricCount != 1 || stats.LogCount != 1 {
+		t.Errorf("expected 1 metric and 1 log, got %d metrics and %d logs", stats.MetricCount, stats.LogCount)
+	}
+}
+
+func TestHandleOTLP_UnsupportedContentType(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	body, _ := json.Marshal(createTracesPayload())
+	for path, handler := range map[string]http.HandlerFunc{
+		"/v1/traces":  h.HandleTraces,
+		"/v1/metrics": h.HandleMetrics,
+		"/v1/logs":    h.HandleLogs,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/xml")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected status 415, got %d", path, rec.Code)
+		}
+	}
+}
+
 func TestHandleTraces_WithAttributes(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -822,3 +1179,1206 @@ func TestHandleTraces_ErrorStatus(t *testing.T) {
 		t.Errorf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
 	}
 }
+
+func TestHandleOTLP_ScopeAttributes(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sc := scope{
+		Name:       "com.anthropic.claude_code",
+		Version:    "2.0.0",
+		Attributes: []keyValue{{Key: "scope.kind", Value: anyValue{StringValue: "cli"}}},
+	}
+
+	post := func(path string, handler http.HandlerFunc, payload interface{}) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("failed to marshal payload: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	traces := createTracesPayload()
+	traces.ResourceSpans[0].ScopeSpans[0].Scope = sc
+	post("/v1/traces", h.HandleTraces, traces)
+
+	logs := createLogsPayload()
+	logs.ResourceLogs[0].ScopeLogs[0].Scope = &sc
+	post("/v1/logs", h.HandleLogs, logs)
+
+	metrics := createMetricsPayload()
+	metrics.ResourceMetrics[0].ScopeMetrics[0].Scope = &sc
+	post("/v1/metrics", h.HandleMetrics, metrics)
+
+	from, to := time.Time{}, time.Now().Add(time.Hour)
+	tracesResp, err := h.store.QueryTraces(ctx, "", "", "", from, to, 10, 0)
+	if err != nil || len(tracesResp.Traces) != 1 {
+		t.Fatalf("QueryTraces: got %+v, err %v", tracesResp, err)
+	}
+	spans, err := h.store.GetTraceSpans(ctx, tracesResp.Traces[0].TraceID)
+	if err != nil || len(spans) != 1 {
+		t.Fatalf("GetTraceSpans: got %d spans, err %v", len(spans), err)
+	}
+	if got := spans[0].ScopeAttributes["scope.kind"]; got != "cli" {
+		t.Errorf("span scope attribute = %q, want cli", got)
+	}
+
+	logsResp, err := h.store.QueryLogs(ctx, "", "", storage.SeverityRange{}, "", "", from, to, 10, 0)
+	if err != nil || len(logsResp.Logs) != 1 {
+		t.Fatalf("QueryLogs: got %+v, err %v", logsResp, err)
+	}
+	if got := logsResp.Logs[0].ScopeAttributes["scope.kind"]; got != "cli" {
+		t.Errorf("log scope attribute = %q, want cli", got)
+	}
+
+	metricsResp, err := h.store.QueryMetrics(ctx, "", "test_gauge", "", from, to, 10, 0)
+	if err != nil || len(metricsResp.Metrics) != 1 {
+		t.Fatalf("QueryMetrics: got %+v, err %v", metricsResp, err)
+	}
+	if got := metricsResp.Metrics[0].ScopeAttributes["scope.kind"]; got != "cli" {
+		t.Errorf("metric scope attribute = %q, want cli", got)
+	}
+}
+
+func TestHandleOTLP_DroppedCounts(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	traces := createTracesPayload()
+	s := &traces.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	s.DroppedAttributesCount, s.DroppedEventsCount, s.DroppedLinksCount = 5, 2, 1
+	body, _ := json.Marshal(traces)
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.HandleTraces(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("traces: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	logs := createLogsPayload()
+	logs.ResourceLogs[0].ScopeLogs[0].LogRecords[0].DroppedAttributesCount = 3
+	body, _ = json.Marshal(logs)
+	req = httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	h.HandleLogs(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logs: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	from, to := time.Time{}, time.Now().Add(time.Hour)
+	tracesResp, err := h.store.QueryTraces(ctx, "", "", "", from, to, 10, 0)
+	if err != nil || len(tracesResp.Traces) != 1 {
+		t.Fatalf("QueryTraces: got %+v, err %v", tracesResp, err)
+	}
+	spans, err := h.store.GetTraceSpans(ctx, tracesResp.Traces[0].TraceID)
+	if err != nil || len(spans) != 1 {
+		t.Fatalf("GetTraceSpans: got %d spans, err %v", len(spans), err)
+	}
+	if spans[0].DroppedAttributesCount != 5 || spans[0].DroppedEventsCount != 2 || spans[0].DroppedLinksCount != 1 {
+		t.Errorf("unexpected span dropped counts: %+v", spans[0])
+	}
+
+	logsResp, err := h.store.QueryLogs(ctx, "", "", storage.SeverityRange{}, "", "", from, to, 10, 0)
+	if err != nil || len(logsResp.Logs) != 1 {
+		t.Fatalf("QueryLogs: got %+v, err %v", logsResp, err)
+	}
+	if logsResp.Logs[0].DroppedAttributesCount != 3 {
+		t.Errorf("log DroppedAttributesCount = %d, want 3", logsResp.Logs[0].DroppedAttributesCount)
+	}
+}
+
+func TestHandleLogs_TruncatesExcessiveAttributes(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetMaxAttributes(10)
+
+	payload := createLogsPayload()
+	record := &payload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	record.DroppedAttributesCount = 1
+	record.Attributes = nil
+	for i := 0; i < 5000; i++ {
+		record.Attributes = append(record.Attributes, keyValue{Key: fmt.Sprintf("attr.%05d", i), Value: anyValue{StringValue: "x"}})
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.HandleLogs(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	logsResp, err := h.store.QueryLogs(context.Background(), "", "", storage.SeverityRange{}, "", "", time.Time{}, time.Now().Add(time.Hour), 10, 0)
+	if err != nil || len(logsResp.Logs) != 1 {
+		t.Fatalf("QueryLogs: got %+v, err %v", logsResp, err)
+	}
+	stored := logsResp.Logs[0]
+	if len(stored.LogAttributes) != 10 {
+		t.Errorf("expected 10 stored attributes, got %d", len(stored.LogAttributes))
+	}
+	if stored.DroppedAttributesCount != 4991 {
+		t.Errorf("DroppedAttributesCount = %d, want 4991 (1 from the SDK, 4990 truncated)", stored.DroppedAttributesCount)
+	}
+	if got := h.attrLimit.Truncated(); got != 4990 {
+		t.Errorf("Truncated() = %d, want 4990", got)
+	}
+}
+
+// tracesPayloadFor creates a traces payload with n spans from the given service
+func tracesPayloadFor(t *testing.T, service string, n int) []byte {
+	t.Helper()
+	payload := createTracesPayload()
+	rs := &payload.ResourceSpans[0]
+	rs.Resource.Attributes[0].Value.StringValue = service
+
+	template := rs.ScopeSpans[0].Spans[0]
+	rs.ScopeSpans[0].Spans = nil
+	for i := 0; i < n; i++ {
+		s := template
+		s.SpanID = fmt.Sprintf("%016x", i+1)
+		rs.ScopeSpans[0].Spans = append(rs.ScopeSpans[0].Spans, s)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+	return body
+}
+
+// waitQueued blocks until n writes are waiting in the queue
+func waitQueued(t *testing.T, q *IngestQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.mu.Lock()
+		queued := q.waiting.Len()
+		q.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d queued writes, have %d", n, queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIngestQueue_QuietServiceNotStarved(t *testing.T) {
+	q := NewIngestQueue(nil)
+	ctx := context.Background()
+
+	// Hold the write slot so every following write has to queue
+	hold, err := q.Acquire(ctx, "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(service string, records int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := q.Acquire(ctx, service, records)
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, service)
+			mu.Unlock()
+			release()
+		}()
+	}
+
+	const chattyWrites = 20
+	for i := 0; i < chattyWrites; i++ {
+		enqueue("chatty", 100)
+	}
+	waitQueued(t, q, chattyWrites)
+	enqueue("quiet", 1)
+	waitQueued(t, q, chattyWrites+1)
+
+	hold()
+	wg.Wait()
+
+	if len(order) != chattyWrites+1 {
+		t.Fatalf("expected %d writes, got %d", chattyWrites+1, len(order))
+	}
+	if order[0] != "quiet" {
+		t.Errorf("expected quiet service to be served first despite arriving last, got order %v", order)
+	}
+
+	counters := q.Counters()
+	if counters["chatty"].Requests != chattyWrites || counters["chatty"].Records != chattyWrites*100 {
+		t.Errorf("unexpected chatty counters: %+v", counters["chatty"])
+	}
+	if counters["quiet"].Requests != 1 || counters["quiet"].Records != 1 {
+		t.Errorf("unexpected quiet counters: %+v", counters["quiet"])
+	}
+}
+
+func TestIngestQueue_Weights(t *testing.T) {
+	q := NewIngestQueue([]string{"heavy=4", "invalid", "zero=0"})
+
+	if got := q.weight("heavy"); got != 4 {
+		t.Errorf("weight(heavy) = %v, want 4", got)
+	}
+	if got := q.weight("zero"); got != 1 {
+		t.Errorf("weight(zero) = %v, want default 1", got)
+	}
+	if got := q.weight("other"); got != 1 {
+		t.Errorf("weight(other) = %v, want default 1", got)
+	}
+}
+
+func TestIngestQueue_CancelWhileWaiting(t *testing.T) {
+	q := NewIngestQueue(nil)
+
+	hold, err := q.Acquire(context.Background(), "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := q.Acquire(ctx, "waiter", 1)
+		done <- err
+	}()
+	waitQueued(t, q, 1)
+	cancel()
+
+	if err := <-done; err == nil {
+		t.Error("expected error for cancelled Acquire")
+	}
+	hold()
+
+	// The slot must be free again
+	release, err := q.Acquire(context.Background(), "next", 1)
+	if err != nil {
+		t.Fatalf("Acquire after cancel failed: %v", err)
+	}
+	release()
+}
+
+func TestHandleTraces_UnbalancedServicesBothProgress(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	chattyBody := tracesPayloadFor(t, "chatty", 50)
+	quietBody := tracesPayloadFor(t, "quiet", 1)
+
+	post := func(body []byte) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleTraces(rec, req)
+		return rec.Code
+	}
+
+	const chattyRequests, quietRequests = 12, 3
+	var chattyDone atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < chattyRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code := post(chattyBody); code != http.StatusOK {
+				t.Errorf("chatty request: expected status 200, got %d", code)
+			}
+			chattyDone.Add(1)
+		}()
+	}
+	waitQueued(t, h.ingest, 4)
+
+	var quietWG sync.WaitGroup
+	for i := 0; i < quietRequests; i++ {
+		quietWG.Add(1)
+		go func() {
+			defer quietWG.Done()
+			if code := post(quietBody); code != http.StatusOK {
+				t.Errorf("quiet request: expected status 200, got %d", code)
+			}
+		}()
+	}
+	quietWG.Wait()
+
+	if done := chattyDone.Load(); done == chattyRequests {
+		t.Errorf("quiet service only finished after all %d chatty requests", chattyRequests)
+	}
+	wg.Wait()
+
+	counters := h.ingest.Counters()
+	if counters["chatty"].Requests != chattyRequests || counters["chatty"].Records != chattyRequests*50 {
+		t.Errorf("unexpected chatty counters: %+v", counters["chatty"])
+	}
+	if counters["quiet"].Requests != quietRequests || counters["quiet"].Records != quietRequests {
+		t.Errorf("unexpected quiet counters: %+v", counters["quiet"])
+	}
+}
+
+// countSpans returns the number of stored spans
+func countSpans(t *testing.T, h *Handlers) int {
+	t.Helper()
+	var count int
+	if err := h.store.DB().QueryRow("SELECT COUNT(*) FROM otel_traces").Scan(&count); err != nil {
+		t.Fatalf("counting spans: %v", err)
+	}
+	return count
+}
+
+func TestHandleTraces_AsyncIngestAcksBeforeStoring(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableAsyncIngest()
+
+	// Hold the write slot so the batch cannot be stored while the request is handled
+	hold, err := h.ingest.Acquire(context.Background(), "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "async", 5)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	h.HandleTraces(rec, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("fast-ack took %v", elapsed)
+	}
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "{}" {
+		t.Fatalf("expected 200 {}, got %d %q", rec.Code, rec.Body.String())
+	}
+	if n := countSpans(t, h); n != 0 {
+		t.Errorf("expected no spans before the writer runs, got %d", n)
+	}
+
+	hold()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for countSpans(t, h) != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for async spans, have %d", countSpans(t, h))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+}
+
+func TestAsyncIngest_DrainStoresQueuedBatches(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableAsyncIngest()
+
+	hold, err := h.ingest.Acquire(context.Background(), "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "async", 3)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleTraces(rec, req)
+		return rec.Code
+	}
+
+	const batches = 10
+	for i := 0; i < batches; i++ {
+		if code := post(); code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", code)
+		}
+	}
+
+	drained := make(chan error)
+	go func() { drained <- async.Drain(context.Background()) }()
+
+	// Batches arriving after shutdown began are refused rather than silently dropped
+	accepted := batches
+	deadline := time.Now().Add(5 * time.Second)
+	for post() != http.StatusServiceUnavailable {
+		accepted++
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the writer to stop accepting batches")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	hold()
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if n := countSpans(t, h); n != accepted*3 {
+		t.Errorf("expected %d spans after drain, got %d", accepted*3, n)
+	}
+}
+
+func TestAsyncIngest_DrainTimeout(t *testing.T) {
+	q := NewIngestQueue(nil)
+	async := NewAsyncIngest(q)
+
+	hold, err := q.Acquire(context.Background(), "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer hold()
+
+	stored := make(chan struct{})
+	err = async.Enqueue(context.Background(), asyncJob{signal: "traces", records: 1, persist: func(context.Context) error {
+		close(stored)
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := async.Drain(ctx); err == nil {
+		t.Error("expected Drain to time out while the write slot is held")
+	}
+
+	hold()
+	<-stored
+}
+
+func TestAsyncIngest_StoredRunsAfterRelease(t *testing.T) {
+	q := NewIngestQueue(nil)
+	async := NewAsyncIngest(q)
+
+	// The stored callback stands in for a broadcast to a slow WebSocket client
+	unblock := make(chan struct{})
+	job := asyncJob{signal: "traces", records: 1,
+		persist: func(context.Context) error { return nil },
+		stored:  func() { <-unblock },
+	}
+	if err := async.Enqueue(context.Background(), job); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	release, err := q.Acquire(ctx, "other", 1)
+	if err != nil {
+		t.Fatalf("expected the write slot to be free while stored runs: %v", err)
+	}
+	release()
+
+	close(unblock)
+	if err := async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+}
+
+func TestBatchedIngest_FlushesWhenFull(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableBatchedIngest(10, time.Hour)
+	defer async.Drain(context.Background())
+
+	post := func() {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "batched", 4)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleTraces(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+
+	// Two batches stay below the threshold and are only buffered
+	post()
+	post()
+	deadline := time.Now().Add(5 * time.Second)
+	for async.Pending() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for batches to be buffered, pending %d", async.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := countSpans(t, h); n != 0 {
+		t.Errorf("expected no spans before the batch is full, got %d", n)
+	}
+
+	// The third batch fills the buffer and all of them are committed together
+	post()
+	for countSpans(t, h) != 12 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the batch to be stored, have %d spans", countSpans(t, h))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// The buffered count drops once the commit has returned
+	for async.Pending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected nothing pending after the flush, got %d", async.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchedIngest_FlushesOnInterval(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableBatchedIngest(1000, 20*time.Millisecond)
+	defer async.Drain(context.Background())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "batched", 3)))
+	req.Header.Set("Content-Type", "application/json")
+	h.HandleTraces(httptest.NewRecorder(), req)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for countSpans(t, h) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the interval flush, have %d spans", countSpans(t, h))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchedIngest_DrainStoresBufferedRows(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableBatchedIngest(1000, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "batched", 2)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleTraces(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+
+	if err := async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if n := countSpans(t, h); n != 10 {
+		t.Errorf("expected buffered spans to be stored on drain, got %d", n)
+	}
+}
+
+func TestBatchedIngest_UnbatchedJobFlushesFirst(t *testing.T) {
+	q := NewIngestQueue(nil)
+	var order []string
+	async := NewBatchedAsyncIngest(q, 1000, time.Hour, func(_ context.Context, rows ingestRows) error {
+		order = append(order, fmt.Sprintf("batch:%d", rows.len()))
+		return nil
+	})
+
+	spans := &ingestRows{spans: []api.Span{{SpanID: "a"}, {SpanID: "b"}}}
+	if err := async.Enqueue(context.Background(), asyncJob{signal: "traces", records: 2, rows: spans, stored: func() {
+		order = append(order, "stored")
+	}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := async.Enqueue(context.Background(), asyncJob{signal: "metrics", records: 1, persist: func(context.Context) error {
+		order = append(order, "metrics")
+		return nil
+	}}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	// The buffered rows are written first; their stored callback runs once the slot is released
+	if want := []string{"batch:2", "metrics", "stored"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("write order = %v, want %v", order, want)
+	}
+}
+
+func TestBatchedIngest_FailedBatchStoresJobsSeparately(t *testing.T) {
+	q := NewIngestQueue(nil)
+	async := NewBatchedAsyncIngest(q, 1000, time.Hour, func(context.Context, ingestRows) error {
+		return errors.New("injected batch failure")
+	})
+
+	var persisted, stored []string
+	enqueue := func(name string, persistErr error) {
+		t.Helper()
+		job := asyncJob{signal: "logs", records: 1,
+			rows: &ingestRows{logs: []api.LogRecord{{Body: name}}},
+			persist: func(context.Context) error {
+				persisted = append(persisted, name)
+				return persistErr
+			},
+			stored: func() { stored = append(stored, name) },
+		}
+		if err := async.Enqueue(context.Background(), job); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	enqueue("a", nil)
+	enqueue("b", errors.New("bad rows"))
+	enqueue("c", nil)
+
+	if err := async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(persisted, want) {
+		t.Errorf("expected every job to be retried on its own, got %v", persisted)
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(stored, want) {
+		t.Errorf("expected the jobs that were stored to be reported, got %v", stored)
+	}
+}
+
+func TestBatchedIngest_MergesMetrics(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	var inserts []int
+	h.async = NewBatchedAsyncIngest(h.ingest, 1000, time.Hour, func(ctx context.Context, rows ingestRows) error {
+		inserts = append(inserts, len(rows.metrics))
+		return h.store.InsertBatch(ctx, rows.spans, rows.logs, rows.metrics)
+	})
+
+	for i := 0; i < 3; i++ {
+		payload := createMetricsPayload()
+		payload.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Gauge.DataPoints[0].AsDouble = float64(i)
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleMetrics(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+
+	if err := h.async.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if want := []int{3}; !reflect.DeepEqual(inserts, want) {
+		t.Errorf("expected metrics from all requests in one InsertBatch, got inserts %v", inserts)
+	}
+}
+
+func TestIngestPause_RejectsWhilePaused(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	handler := h.IngestPauseMiddleware(http.HandlerFunc(h.HandleTraces))
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "svc", 2)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	admin := func(handler http.HandlerFunc, path string) api.IngestState {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, rec.Code)
+		}
+		var state api.IngestState
+		if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+			t.Fatalf("failed to decode state: %v", err)
+		}
+		return state
+	}
+
+	if state := admin(h.PauseIngest, "/api/admin/ingest/pause"); !state.Paused {
+		t.Fatalf("expected paused state, got %+v", state)
+	}
+	rec := post()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503 while paused, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if n := countSpans(t, h); n != 0 {
+		t.Errorf("expected no spans stored while paused, got %d", n)
+	}
+
+	if state := admin(h.ResumeIngest, "/api/admin/ingest/resume"); state.Paused {
+		t.Fatalf("expected resumed state, got %+v", state)
+	}
+	if rec := post(); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after resume, got %d", rec.Code)
+	}
+	if n := countSpans(t, h); n != 2 {
+		t.Errorf("expected 2 spans after resume, got %d", n)
+	}
+}
+
+func TestIngestPause_FlushesAsyncQueue(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	async := h.EnableAsyncIngest()
+	defer async.Drain(context.Background())
+
+	hold, err := h.ingest.Acquire(context.Background(), "holder", 1)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	handler := h.IngestPauseMiddleware(http.HandlerFunc(h.HandleTraces))
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(tracesPayloadFor(t, "async", 4)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	paused := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.PauseIngest(rec, httptest.NewRequest(http.MethodPost, "/api/admin/ingest/pause", nil))
+		paused <- rec
+	}()
+
+	// Pause must not return while the queued batch is still waiting to be stored
+	select {
+	case <-paused:
+		t.Fatal("pause returned before the async queue was flushed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	hold()
+	if rec := <-paused; rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 from pause, got %d", rec.Code)
+	}
+	if n := countSpans(t, h); n != 4 {
+		t.Errorf("expected queued spans to be stored once paused, got %d", n)
+	}
+}
+
+func TestHandleMetrics_TimestampResolution(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetMetricTimestampResolution(time.Second)
+
+	post := func(points ...dataPoint) {
+		t.Helper()
+		payload := otlpMetricsRequest{
+			ResourceMetrics: []resourceMetric{
+				{
+					Resource: resource{
+						Attributes: []keyValue{
+							{Key: "service.name", Value: anyValue{StringValue: "test-service"}},
+						},
+					},
+					ScopeMetrics: []scopeMetric{
+						{
+							Metrics: []metric{
+								{Name: "queue.depth", Gauge: &gaugeMetric{DataPoints: points}},
+							},
+						},
+					},
+				},
+			},
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleMetrics(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	// Two points 200ms apart in one batch collapse to the later one
+	post(
+		dataPoint{TimeUnixNano: "1609459200100000000", AsDouble: 1},
+		dataPoint{TimeUnixNano: "1609459200300000000", AsDouble: 2},
+	)
+
+	ctx := context.Background()
+	from := time.Unix(1609459199, 0)
+	to := time.Unix(1609459202, 0)
+	resp, err := h.store.QueryMetrics(ctx, "", "queue.depth", "", from, to, 100, 0)
+	if err != nil {
+		t.Fatalf("QueryMetrics failed: %v", err)
+	}
+	if len(resp.Metrics) != 1 {
+		t.Fatalf("expected 1 point, got %d", len(resp.Metrics))
+	}
+	if got := resp.Metrics[0]; *got.Value != 2 || !got.Timestamp.Equal(time.Unix(1609459200, 0)) {
+		t.Errorf("got value %v at %v, want 2 at the rounded second", *got.Value, got.Timestamp)
+	}
+
+	// A later batch in the same bucket replaces the stored point
+	post(dataPoint{TimeUnixNano: "1609459200900000000", AsDouble: 3})
+
+	resp, err = h.store.QueryMetrics(ctx, "", "queue.depth", "", from, to, 100, 0)
+	if err != nil {
+		t.Fatalf("QueryMetrics failed: %v", err)
+	}
+	if len(resp.Metrics) != 1 || *resp.Metrics[0].Value != 3 {
+		t.Fatalf("expected a single point with value 3, got %+v", resp.Metrics)
+	}
+}
+
+func TestHandleMetrics_FullTimestampPrecision(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	payload := otlpMetricsRequest{
+		ResourceMetrics: []resourceMetric{
+			{
+				ScopeMetrics: []scopeMetric{
+					{
+						Metrics: []metric{
+							{Name: "queue.depth", Gauge: &gaugeMetric{DataPoints: []dataPoint{
+								{TimeUnixNano: "1609459200100000000", AsDouble: 1},
+								{TimeUnixNano: "1609459200300000000", AsDouble: 2},
+							}}},
+						},
+					},
+				},
+			},
+		},
+	}
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.HandleMetrics(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	resp, err := h.store.QueryMetrics(context.Background(), "", "queue.depth", "", time.Unix(1609459199, 0), time.Unix(1609459202, 0), 100, 0)
+	if err != nil {
+		t.Fatalf("QueryMetrics failed: %v", err)
+	}
+	if len(resp.Metrics) != 2 {
+		t.Errorf("expected 2 points without rounding, got %d", len(resp.Metrics))
+	}
+}
+
+func TestHandleOTLP_IngestSignals(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetIngestSignals([]string{"metrics"})
+
+	post := func(handler http.HandlerFunc, path string, payload interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST %s: expected status 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	post(h.HandleTraces, "/v1/traces", createTracesPayload())
+	post(h.HandleRoot, "/", createTracesPayload())
+	post(h.HandleLogs, "/v1/logs", createLogsPayload())
+	post(h.HandleMetrics, "/v1/metrics", createMetricsPayload())
+
+	stats, err := h.store.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.SpanCount != 0 || stats.LogCount != 0 {
+		t.Errorf("expected disabled traces and logs to be ignored, got %d spans and %d logs", stats.SpanCount, stats.LogCount)
+	}
+	if stats.MetricCount == 0 {
+		t.Error("expected metrics to be stored")
+	}
+	if got := h.signals.Ignored(); got != 3 {
+		t.Errorf("expected 3 ignored requests, got %d", got)
+	}
+}
+
+func TestUploadIngest_JSONLLogs(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	body := fmt.Sprintf(`{"timestamp":%q,"serviceName":"ci","severityText":"ERROR","body":"build failed","logAttributes":{"job":"test"}}
+
+{"timestamp":%q,"serviceName":"ci","severityText":"INFO","body":"build started"}
+`, now.Format(time.RFC3339Nano), now.Add(-time.Second).Format(time.RFC3339Nano))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/upload?signal=logs", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.UploadIngest(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp api.IngestUploadResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Format != "jsonl" || resp.Logs != 2 || resp.Spans != 0 || resp.Metrics != 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	logs, err := h.store.QueryLogs(context.Background(), "ci", "", storage.SeverityRange{}, "", "", now.Add(-time.Hour), now.Add(time.Hour), 10, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if len(logs.Logs) != 2 || logs.Logs[0].Body != "build failed" || logs.Logs[0].LogAttributes["job"] != "test" {
+		t.Errorf("unexpected stored logs: %+v", logs.Logs)
+	}
+}
+
+func TestUploadIngest_Zip(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Export logs to Parquet from a separate store
+	source, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create source store: %v", err)
+	}
+	defer source.Close()
+	if err := source.InsertLogs(ctx, []api.LogRecord{{Timestamp: now, ServiceName: "ci", Body: "from parquet"}}); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	var logsParquet bytes.Buffer
+	if err := source.ExportLogsParquet(ctx, &logsParquet, "", "", storage.SeverityRange{}, "", "", now.Add(-time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatalf("ExportLogsParquet failed: %v", err)
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	files := map[string][]byte{
+		"traces.jsonl":  []byte(fmt.Sprintf(`{"timestamp":%q,"traceId":"t1","spanId":"s1","serviceName":"ci","spanName":"build"}`+"\n", now.Format(time.RFC3339))),
+		"logs.parquet":  logsParquet.Bytes(),
+		"views.duckdb":  []byte("skipped"),
+		".traces.done":  nil,
+		"metrics.jsonl": []byte(fmt.Sprintf(`{"timestamp":%q,"serviceName":"ci","metricName":"build.duration","metricType":"gauge","value":12.5}`+"\n", now.Format(time.RFC3339))),
+	}
+	for name, data := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("creating %s: %v", name, err)
+		}
+		f.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zip: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/upload", &archive)
+	w := httptest.NewRecorder()
+	h.UploadIngest(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp api.IngestUploadResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Format != "zip" || resp.Spans != 1 || resp.Logs != 1 || resp.Metrics != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	spans, err := h.store.GetTraceSpans(ctx, "t1")
+	if err != nil || len(spans) != 1 || spans[0].SpanName != "build" {
+		t.Errorf("expected the uploaded span, got %+v (err %v)", spans, err)
+	}
+	logs, err := h.store.QueryLogs(ctx, "ci", "", storage.SeverityRange{}, "", "", now.Add(-time.Hour), now.Add(time.Hour), 10, 0)
+	if err != nil || len(logs.Logs) != 1 || logs.Logs[0].Body != "from parquet" {
+		t.Errorf("expected the Parquet log, got %+v (err %v)", logs, err)
+	}
+}
+
+func TestUploadIngest_Errors(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ts := time.Now().UTC().Format(time.RFC3339)
+	var emptyZip bytes.Buffer
+	zw := zip.NewWriter(&emptyZip)
+	zw.Create("README.txt")
+	zw.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		body       string
+		wantStatus int
+		wantMsg    string
+	}{
+		{"missing signal", "", `{"body":"x"}`, http.StatusBadRequest, "signal is required"},
+		{"invalid signal", "?signal=events", `{}`, http.StatusBadRequest, "invalid signal"},
+		{"invalid format", "?signal=logs&format=csv", `{}`, http.StatusBadRequest, "invalid format"},
+		{"invalid json", "?signal=logs", fmt.Sprintf(`{"timestamp":%q}`+"\n{not json", ts), http.StatusBadRequest, "line 2"},
+		{"missing timestamp", "?signal=logs", `{"body":"x"}`, http.StatusBadRequest, "timestamp is required"},
+		{"missing span id", "?signal=traces", fmt.Sprintf(`{"timestamp":%q,"traceId":"t1"}`, ts), http.StatusBadRequest, "spanId are required"},
+		{"zip without signal files", "", emptyZip.String(), http.StatusBadRequest, "no traces, logs or metrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/ingest/upload"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.UploadIngest(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantMsg) {
+				t.Errorf("expected error containing %q, got %s", tt.wantMsg, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("too large", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/ingest/upload?signal=logs", strings.NewReader("{}"))
+		req.ContentLength = maxUploadBytes + 1
+		w := httptest.NewRecorder()
+		h.UploadIngest(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected 413, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	// Nothing is stored when a later line fails to decode
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/upload?signal=logs", strings.NewReader(fmt.Sprintf(`{"timestamp":%q,"body":"kept?"}`+"\n{bad", ts)))
+	h.UploadIngest(httptest.NewRecorder(), req)
+	if count, _ := h.store.CountLogs(context.Background(), "", "", storage.SeverityRange{}, "", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour)); count != 0 {
+		t.Errorf("expected no logs stored from a rejected upload, got %d", count)
+	}
+
+	// ... or when a Parquet file in an archive is invalid, even if its JSONL files decode
+	var archive bytes.Buffer
+	zw = zip.NewWriter(&archive)
+	for name, data := range map[string]string{
+		"logs.jsonl":      fmt.Sprintf(`{"timestamp":%q,"body":"kept?"}`, ts),
+		"metrics.parquet": "not parquet",
+	} {
+		f, _ := zw.Create(name)
+		f.Write([]byte(data))
+	}
+	zw.Close()
+	w := httptest.NewRecorder()
+	h.UploadIngest(w, httptest.NewRequest(http.MethodPost, "/api/ingest/upload", &archive))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "metrics parquet") {
+		t.Errorf("expected 400 for the invalid Parquet file, got %d: %s", w.Code, w.Body.String())
+	}
+	if count, _ := h.store.CountLogs(context.Background(), "", "", storage.SeverityRange{}, "", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour)); count != 0 {
+		t.Errorf("expected no logs stored from a rejected archive, got %d", count)
+	}
+}
+
+func TestHandleOTLP_Dedup(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetDedupWindow(time.Minute)
+	now := time.Now()
+	h.dedup.now = func() time.Time { return now }
+
+	body, _ := json.Marshal(createLogsPayload())
+	post := func() {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.HandleLogs(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	logCount := func() int64 {
+		t.Helper()
+		stats, err := h.store.GetStats(context.Background())
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		return stats.LogCount
+	}
+
+	post()
+	stored := logCount()
+	if stored == 0 {
+		t.Fatal("expected the first request to be stored")
+	}
+
+	// A retry within the window is acknowledged but not stored again
+	now = now.Add(30 * time.Second)
+	post()
+	if got := logCount(); got != stored {
+		t.Errorf("expected duplicate to be dropped, log count went from %d to %d", stored, got)
+	}
+
+	// Once the window has passed the same request is stored again
+	now = now.Add(time.Minute)
+	post()
+	if got := logCount(); got != 2*stored {
+		t.Errorf("expected request outside the window to be stored, got %d logs, want %d", got, 2*stored)
+	}
+
+	var stats api.StatsResponse
+	h.addRuntimeStats(&stats)
+	if stats.Dedup == nil {
+		t.Fatal("expected dedup stats when deduplication is enabled")
+	}
+	if stats.Dedup.Hits != 1 || stats.Dedup.Misses != 2 || stats.Dedup.WindowSeconds != 60 {
+		t.Errorf("unexpected dedup stats: %+v", *stats.Dedup)
+	}
+}
+
+func TestIngestDedup_ForgetsFailedRequests(t *testing.T) {
+	d := NewIngestDedup(time.Minute)
+	req := &collogspb.ExportLogsServiceRequest{}
+
+	key, duplicate := d.claim("logs", req)
+	if duplicate {
+		t.Fatal("expected first request not to be a duplicate")
+	}
+	err := errors.New("store failed")
+	d.settle(key, &err)
+
+	// The retry of a request that failed to store is not dropped
+	if _, duplicate := d.claim("logs", req); duplicate {
+		t.Error("expected retry of a failed request not to be a duplicate")
+	}
+	if _, duplicate := d.claim("logs", req); !duplicate {
+		t.Error("expected second retry to be a duplicate")
+	}
+	// Requests are keyed by signal as well as content
+	if _, duplicate := d.claim("traces", req); duplicate {
+		t.Error("expected request for another signal not to be a duplicate")
+	}
+
+	disabled := NewIngestDedup(0)
+	disabled.claim("logs", req)
+	if _, duplicate := disabled.claim("logs", req); duplicate {
+		t.Error("expected disabled deduplication to store every request")
+	}
+}
@@ -2,28 +2,179 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/logger"
 	"github.com/tobilg/ai-observer/internal/otlp"
 	"github.com/tobilg/ai-observer/internal/storage"
 	"github.com/tobilg/ai-observer/internal/websocket"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 )
 
 type Handlers struct {
-	store *storage.DuckDBStore
-	hub   *websocket.Hub
+	store         *storage.DuckDBStore
+	hub           *websocket.Hub
+	envLabel      string
+	metricFilter  *MetricFilter
+	logSampler    *LogSampler
+	modelAliases  *ModelAliases
+	serviceGroups *ServiceGroups
+	ingest        *IngestQueue
+	async         *AsyncIngest
+	usage         *storage.UsageSampler
+	pause         *IngestPause
+	attrLimit     *AttributeLimit
+	tsRes         time.Duration
+	signals       *IngestSignals
+	sessionIdle   time.Duration
+	currency      *CostCurrency
+	queryLimit    *QueryLimit
+	dashFallback  string
+	dashLimits    *DashboardLimits
+	dedup         *IngestDedup
+	started       time.Time // Reported as uptime by Health
 }
 
 func New(store *storage.DuckDBStore, hub *websocket.Hub) *Handlers {
 	return &Handlers{
-		store: store,
-		hub:   hub,
+		store:         store,
+		hub:           hub,
+		metricFilter:  NewMetricFilter(nil, nil),
+		logSampler:    NewLogSampler(1),
+		modelAliases:  NewModelAliases(nil),
+		serviceGroups: NewServiceGroups(nil),
+		ingest:        NewIngestQueue(nil),
+		pause:         &IngestPause{},
+		attrLimit:     NewAttributeLimit(otlp.DefaultMaxAttributes),
+		signals:       NewIngestSignals(nil),
+		sessionIdle:   DefaultSessionIdleTimeout,
+		currency:      NewCostCurrency("", 0),
+		queryLimit:    NewQueryLimit(0, 0),
+		dashFallback:  DashboardFallbackLatest,
+		dashLimits:    NewDashboardLimits(0, 0),
+		dedup:         NewIngestDedup(0),
+		started:       time.Now(),
 	}
 }
 
+// SetEnvLabel configures the environment label reported by /health and /api/stats
+func (h *Handlers) SetEnvLabel(label string) {
+	h.envLabel = label
+}
+
+// SetMetricFilter configures the metric name allowlist and denylist applied at ingestion
+func (h *Handlers) SetMetricFilter(allow, deny []string) {
+	h.metricFilter = NewMetricFilter(allow, deny)
+}
+
+// SetLogSampleRate configures the share of TRACE, DEBUG and INFO logs kept at ingestion
+func (h *Handlers) SetLogSampleRate(rate float64) {
+	h.logSampler = NewLogSampler(rate)
+}
+
+// SetModelAliases configures the model name canonicalization rules applied in breakdown queries
+func (h *Handlers) SetModelAliases(rules []string) {
+	h.modelAliases = NewModelAliases(rules)
+}
+
+// SetServiceGroups configures the "pattern=group" rules used to group services in /api/services
+func (h *Handlers) SetServiceGroups(rules []string) {
+	h.serviceGroups = NewServiceGroups(rules)
+}
+
+// SetCostCurrency configures the display currency and exchange rate (units per USD) that
+// cost endpoints report alongside USD
+func (h *Handlers) SetCostCurrency(code string, rate float64) {
+	h.currency = NewCostCurrency(code, rate)
+}
+
+// SetMaxConcurrentQueries configures how many API queries may run at once and how long an
+// excess query waits for a slot before it is rejected; max <= 0 disables the limit
+func (h *Handlers) SetMaxConcurrentQueries(max int, queueTimeout time.Duration) {
+	h.queryLimit = NewQueryLimit(max, queueTimeout)
+}
+
+// SetMaxAttributes configures the maximum number of entries kept in each attribute map of
+// ingested records; max <= 0 disables the limit
+func (h *Handlers) SetMaxAttributes(max int) {
+	h.attrLimit = NewAttributeLimit(max)
+}
+
+// SetMetricTimestampResolution configures the resolution OTLP metric timestamps are truncated
+// to at ingestion; points of the same series in one bucket collapse to the last write.
+// A resolution <= 0 keeps full precision.
+func (h *Handlers) SetMetricTimestampResolution(resolution time.Duration) {
+	h.tsRes = resolution
+}
+
+// SetSessionIdleTimeout configures how long a session may go without new logs before it is
+// reported as completed; timeout <= 0 uses DefaultSessionIdleTimeout
+func (h *Handlers) SetSessionIdleTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultSessionIdleTimeout
+	}
+	h.sessionIdle = timeout
+}
+
+// SetDashboardFallback configures which dashboard /api/dashboards/default returns when none
+// is flagged as default: DashboardFallbackLatest or DashboardFallbackNone. Unknown modes use
+// DashboardFallbackLatest.
+func (h *Handlers) SetDashboardFallback(mode string) {
+	if mode != DashboardFallbackNone {
+		mode = DashboardFallbackLatest
+	}
+	h.dashFallback = mode
+}
+
+// SetDashboardLimits configures the maximum number of dashboards and of widgets per
+// dashboard; a limit <= 0 disables it
+func (h *Handlers) SetDashboardLimits(maxDashboards, maxWidgets int) {
+	h.dashLimits = NewDashboardLimits(maxDashboards, maxWidgets)
+}
+
+// SetIngestSignals configures which OTLP signals are stored; an empty list stores all of them
+func (h *Handlers) SetIngestSignals(signals []string) {
+	h.signals = NewIngestSignals(signals)
+}
+
+// SetDedupWindow configures the window within which identical OTLP export requests are
+// stored only once; window <= 0 disables deduplication
+func (h *Handlers) SetDedupWindow(window time.Duration) {
+	h.dedup = NewIngestDedup(window)
+}
+
+// SetIngestWeights configures the per-service "service=weight" rules for fair ingestion
+func (h *Handlers) SetIngestWeights(rules []string) {
+	h.ingest = NewIngestQueue(rules)
+}
+
+// EnableAsyncIngest makes the OTLP handlers acknowledge batches as soon as they are decoded
+// and store them in the background. The returned writer must be drained on shutdown.
+func (h *Handlers) EnableAsyncIngest() *AsyncIngest {
+	h.async = NewAsyncIngest(h.ingest)
+	return h.async
+}
+
+// EnableBatchedIngest enables async ingestion with insert batching: the rows of decoded
+// batches are buffered and committed in one transaction once maxRows rows are pending or
+// every interval. The returned writer must be drained on shutdown to store buffered rows.
+func (h *Handlers) EnableBatchedIngest(maxRows int, interval time.Duration) *AsyncIngest {
+	h.async = NewBatchedAsyncIngest(h.ingest, maxRows, interval, func(ctx context.Context, rows ingestRows) error {
+		return h.store.InsertBatch(ctx, rows.spans, rows.logs, rows.metrics)
+	})
+	return h.async
+}
+
+// SetUsageSampler configures the sampler whose history backs /api/self/storage
+func (h *Handlers) SetUsageSampler(sampler *storage.UsageSampler) {
+	h.usage = sampler
+}
+
 // HandleRoot handles POST / by detecting signal type from body (workaround for Gemini CLI bug)
 func (h *Handlers) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	log := logger.Logger()
@@ -55,13 +206,16 @@ func (h *Handlers) HandleRoot(w http.ResponseWriter, r *http.Request) {
 
 // HandleTraces handles POST /v1/traces
 func (h *Handlers) HandleTraces(w http.ResponseWriter, r *http.Request) {
+	if h.signals.ignore(w, r, "traces") {
+		return
+	}
 	log := logger.Logger()
 	contentType := r.Header.Get("Content-Type")
 
 	// Use format detection to handle Content-Type mismatches
 	decoder, body, _, err := otlp.GetDecoderWithDetection(r.Body, contentType)
 	if err != nil {
-		api.WriteError(w, http.StatusBadRequest, err.Error())
+		writeDecoderError(w, err)
 		return
 	}
 
@@ -72,25 +226,109 @@ func (h *Handlers) HandleTraces(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.ingestTraces(r.Context(), req); err != nil {
+		writeIngestError(w, "traces", err)
+		return
+	}
+
+	writeOTLPSuccess(w)
+}
+
+// ingestTraces converts and stores a decoded trace export request. It is shared by the
+// OTLP/HTTP and OTLP/gRPC transports so both produce identical rows.
+func (h *Handlers) ingestTraces(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (err error) {
+	key, duplicate := h.dedup.claim("traces", req)
+	if duplicate {
+		return nil
+	}
+	defer h.dedup.settle(key, &err)
+
 	spans := otlp.ConvertTraces(req)
+	h.attrLimit.ApplySpans(spans)
 
-	// Store spans as-is - Codex CLI spans are handled at query time
-	if err := h.store.InsertSpans(r.Context(), spans); err != nil {
-		log.Error("Failed to store traces", "error", err)
-		api.WriteError(w, http.StatusInternalServerError, "failed to store traces")
-		return
+	service := ""
+	if len(spans) > 0 {
+		service = spans[0].ServiceName
+	}
+	// Broadcast to WebSocket clients once stored
+	broadcast := func() {
+		if h.hub != nil && len(spans) > 0 {
+			h.hub.Broadcast(websocket.NewTracesMessage(spans))
+		}
+	}
+	persist := func(ctx context.Context) error {
+		// Store spans as-is - Codex CLI spans are handled at query time
+		return h.store.InsertSpans(ctx, spans)
 	}
 
-	// Broadcast to WebSocket clients
-	if h.hub != nil && len(spans) > 0 {
-		h.hub.Broadcast(websocket.NewTracesMessage(spans))
+	job := asyncJob{
+		signal:  "traces",
+		service: service,
+		records: len(spans),
+		persist: persist,
+		rows:    &ingestRows{spans: spans},
+		stored:  broadcast,
+	}
+	if err := h.persistBatch(ctx, job); err != nil {
+		return err
 	}
 
-	log.Debug("Received spans", "count", len(spans))
+	logger.Debug("Received spans", "count", len(spans))
+	return nil
+}
 
-	// OTLP success response
+// Errors returned by the ingest functions when a batch could not be handed to storage;
+// any other error means storing the batch failed
+var (
+	errIngestUnavailable = errors.New("ingestion unavailable")
+	errIngestCancelled   = errors.New("ingestion cancelled")
+)
+
+// persistBatch stores a converted batch through the ingest queue, or hands it to the async
+// writer without waiting for it to be stored when async ingestion is enabled
+func (h *Handlers) persistBatch(ctx context.Context, job asyncJob) error {
+	if h.async != nil {
+		if err := h.async.Enqueue(ctx, job); err != nil {
+			return errIngestUnavailable
+		}
+		logger.Debug("Queued batch for async storage", "signal", job.signal, "records", job.records)
+		return nil
+	}
+
+	release, err := h.ingest.Acquire(ctx, job.service, job.records)
+	if err != nil {
+		return errIngestCancelled
+	}
+	err = job.persist(ctx)
+	release()
+	if err == nil && job.stored != nil {
+		job.stored()
+	}
+	return err
+}
+
+// writeIngestError writes the HTTP response for an error returned by an ingest function
+func writeIngestError(w http.ResponseWriter, signal string, err error) {
+	if errors.Is(err, errIngestUnavailable) || errors.Is(err, errIngestCancelled) {
+		api.WriteError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	logger.Error("Failed to store "+signal, "error", err)
+	api.WriteError(w, http.StatusInternalServerError, "failed to store "+signal)
+}
+
+// writeDecoderError maps a decoder selection failure to 415 for unknown encodings and 400 otherwise
+func writeDecoderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, otlp.ErrUnsupportedContentType) {
+		api.WriteError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	api.WriteError(w, http.StatusBadRequest, err.Error())
+}
+
+// writeOTLPSuccess writes the OTLP success response
+func writeOTLPSuccess(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("{}"))
 }
-
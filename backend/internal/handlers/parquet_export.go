@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// formatParquet is the format query parameter value selecting a Parquet download
+const formatParquet = "parquet"
+
+// parseResponseFormat reads the format query parameter: json (default) or parquet
+func parseResponseFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return "json", nil
+	case formatParquet:
+		return formatParquet, nil
+	default:
+		return "", fmt.Errorf("invalid format %q (valid: json, parquet)", format)
+	}
+}
+
+// parquetResponse sets the download headers on the first write, so a failed export can still
+// answer with a JSON error. Every write is flushed to the client, so the file goes out in
+// chunks as it is read instead of being collected first.
+type parquetResponse struct {
+	w        http.ResponseWriter
+	filename string
+	gzip     bool
+	started  bool
+}
+
+func (p *parquetResponse) Write(b []byte) (int, error) {
+	if !p.started {
+		p.started = true
+		p.w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+		p.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", p.filename))
+		p.w.Header().Add("Vary", "Accept-Encoding")
+		if p.gzip {
+			p.w.Header().Set("Content-Encoding", "gzip")
+		}
+		p.w.WriteHeader(http.StatusOK)
+	}
+	n, err := p.w.Write(b)
+	if f, ok := p.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows a gzip response
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// writeParquet streams the Parquet file produced by export as a download named after signal,
+// gzip-compressed when the client accepts it. Export stops when the request is canceled.
+func writeParquet(w http.ResponseWriter, r *http.Request, signal string, export func(io.Writer) error) {
+	resp := &parquetResponse{
+		w:        w,
+		filename: fmt.Sprintf("%s-%s.parquet", signal, time.Now().UTC().Format("20060102-150405")),
+		gzip:     acceptsGzip(r),
+	}
+
+	var out io.Writer = resp
+	var gz *gzip.Writer
+	if resp.gzip {
+		// The gzip header is only written on the first write, so an export that fails before
+		// producing any bytes still leaves resp unstarted
+		gz = gzip.NewWriter(resp)
+		out = gz
+	}
+
+	err := export(out)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		if !resp.started {
+			api.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logger.Logger().Error("Failed to stream parquet export", "signal", signal, "error", err)
+	}
+}
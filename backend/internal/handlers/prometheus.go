@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/otlp"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// prometheusContentType is the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusMetrics handles GET /metrics on the OTLP port. It renders the current value of
+// every stored gauge and sum series in the Prometheus text format, so Prometheus or Grafana
+// can scrape ai-observer. Monotonic sums become counters and other sums gauges; names are
+// sanitized (claude_code.token.usage -> claude_code_token_usage, counters get _total) and
+// attributes become labels next to service_name. The optional service parameter limits the
+// output to one service. The .delta series derived at ingestion are left out, as the
+// counter of their cumulative source carries the same information.
+func (h *Handlers) PrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.store.GetMetricSnapshots(r.Context(), r.URL.Query().Get("service"))
+	if err != nil {
+		logger.Logger().Error("Failed to read metrics for Prometheus", "error", err)
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.WriteHeader(http.StatusOK)
+	bw := bufio.NewWriter(w)
+	writePrometheusMetrics(bw, snapshots)
+	bw.Flush()
+}
+
+// writePrometheusMetrics writes the snapshots as metric families. Snapshots are grouped by
+// their sanitized name; the first snapshot of a family decides its type and help text.
+func writePrometheusMetrics(w *bufio.Writer, snapshots []storage.MetricSnapshot) {
+	type family struct {
+		name, help, kind string
+		samples          []storage.MetricSnapshot
+	}
+	families := make(map[string]*family)
+	var names []string
+	for _, s := range snapshots {
+		if base, ok := strings.CutSuffix(s.MetricName, ".delta"); ok && otlp.ShouldConvertToDelta(base) {
+			continue
+		}
+
+		kind := "gauge"
+		name := prometheusName(s.MetricName)
+		if s.MetricType == "sum" && s.Monotonic {
+			kind = "counter"
+			if !strings.HasSuffix(name, "_total") {
+				name += "_total"
+			}
+		}
+
+		f, ok := families[name]
+		if !ok {
+			help := s.Description
+			if s.Unit != "" {
+				help = strings.TrimSpace(help + " (unit: " + s.Unit + ")")
+			}
+			f = &family{name: name, help: help, kind: kind}
+			families[name] = f
+			names = append(names, name)
+		}
+		f.samples = append(f.samples, s)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := families[name]
+		if f.help != "" {
+			w.WriteString("# HELP " + name + " " + escapePrometheusHelp(f.help) + "\n")
+		}
+		w.WriteString("# TYPE " + name + " " + f.kind + "\n")
+		for _, s := range f.samples {
+			w.WriteString(name)
+			writePrometheusLabels(w, s)
+			w.WriteString(" " + formatPrometheusValue(s.Value) + "\n")
+		}
+	}
+}
+
+// writePrometheusLabels writes service_name and the sanitized attribute keys in sorted
+// order. An attribute whose sanitized key repeats an earlier label gets a numeric suffix
+// (e.g. model_2), so distinct series never share a label set.
+func writePrometheusLabels(w *bufio.Writer, s storage.MetricSnapshot) {
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	seen := map[string]bool{"service_name": true}
+	w.WriteString(`{service_name="` + escapePrometheusLabel(s.ServiceName) + `"`)
+	for _, k := range keys {
+		label := prometheusName(k)
+		if strings.HasPrefix(label, "__") {
+			continue
+		}
+		for i := 2; seen[label]; i++ {
+			label = prometheusName(k) + "_" + strconv.Itoa(i)
+		}
+		seen[label] = true
+		w.WriteString("," + label + `="` + escapePrometheusLabel(s.Attributes[k]) + `"`)
+	}
+	w.WriteString("}")
+}
+
+// prometheusName replaces characters that are invalid in Prometheus metric and label names
+// with underscores, prefixing names that start with a digit
+func prometheusName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func escapePrometheusHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapePrometheusLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatPrometheusValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -2,13 +2,20 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/storage"
+	"github.com/tobilg/ai-observer/internal/version"
 	"github.com/tobilg/ai-observer/internal/websocket"
 )
 
@@ -16,10 +23,34 @@ import (
 func (h *Handlers) QueryTraces(w http.ResponseWriter, r *http.Request) {
 	service := r.URL.Query().Get("service")
 	search := r.URL.Query().Get("search")
+	event := r.URL.Query().Get("event")
 	from, to := parseTimeRange(r)
 	limit, offset := parsePagination(r)
 
-	resp, err := h.store.QueryTraces(r.Context(), service, search, from, to, limit, offset)
+	page, err := parsePageCursor(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format, err := parseResponseFormat(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if format == formatParquet {
+		writeParquet(w, r, "traces", func(out io.Writer) error {
+			return h.store.ExportSpansParquet(r.Context(), out, service, search, event, from, to)
+		})
+		return
+	}
+
+	var resp *api.TracesResponse
+	if page != nil {
+		resp, err = h.store.QueryTracesPage(r.Context(), service, search, event, from, to, limit, *page)
+	} else {
+		resp, err = h.store.QueryTraces(r.Context(), service, search, event, from, to, limit, offset)
+	}
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -28,6 +59,36 @@ func (h *Handlers) QueryTraces(w http.ResponseWriter, r *http.Request) {
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
+// CountTraces handles GET /api/traces/count
+func (h *Handlers) CountTraces(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	search := r.URL.Query().Get("search")
+	event := r.URL.Query().Get("event")
+	from, to := parseTimeRange(r)
+
+	count, err := h.store.CountTraces(r.Context(), service, search, event, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.CountResponse{Count: count})
+}
+
+// GetSpanKinds handles GET /api/traces/kinds
+func (h *Handlers) GetSpanKinds(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	kinds, err := h.store.GetSpanKindDistribution(r.Context(), service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, kinds)
+}
+
 // GetTrace handles GET /api/traces/{traceId}
 func (h *Handlers) GetTrace(w http.ResponseWriter, r *http.Request) {
 	traceID := chi.URLParam(r, "traceId")
@@ -35,8 +96,13 @@ func (h *Handlers) GetTrace(w http.ResponseWriter, r *http.Request) {
 		api.WriteError(w, http.StatusBadRequest, "traceId is required")
 		return
 	}
+	role, err := storage.ParseSpanRole(r.URL.Query().Get("spanRole"))
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	spans, err := h.store.GetTraceSpans(r.Context(), traceID)
+	spans, err := h.store.GetTraceSpansByRole(r.Context(), traceID, role)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -51,8 +117,112 @@ func (h *Handlers) GetTrace(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetTraceSpans handles GET /api/traces/{traceId}/spans
+// Clients sending "Accept: application/x-ndjson" (or ?stream=true) receive spans as
+// newline-delimited JSON, flushed as they are read from the database.
 func (h *Handlers) GetTraceSpans(w http.ResponseWriter, r *http.Request) {
-	h.GetTrace(w, r) // Same implementation
+	if !wantsNDJSON(r) {
+		h.GetTrace(w, r) // Same implementation
+		return
+	}
+
+	traceID := chi.URLParam(r, "traceId")
+	if traceID == "" {
+		api.WriteError(w, http.StatusBadRequest, "traceId is required")
+		return
+	}
+	role, err := storage.ParseSpanRole(r.URL.Query().Get("spanRole"))
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	streamed := 0
+
+	err = h.store.StreamTraceSpans(r.Context(), traceID, role, func(span api.Span) error {
+		// Defer headers until the first span so an empty trace can still return 404
+		if streamed == 0 {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+		}
+		streamed++
+
+		if err := enc.Encode(span); err != nil {
+			return err
+		}
+		// Flushing is best effort; not every ResponseWriter supports it
+		_ = rc.Flush()
+		return nil
+	})
+
+	if err != nil {
+		if streamed == 0 {
+			api.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// Headers are already sent; the client sees a truncated stream
+		logger.Logger().Warn("Trace span stream aborted", "traceId", traceID, "streamed", streamed, "error", err)
+		return
+	}
+
+	if streamed == 0 {
+		api.WriteError(w, http.StatusNotFound, "trace not found")
+	}
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for a newline-delimited JSON stream
+func wantsNDJSON(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true" || strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// GetTraceSession handles GET /api/traces/{traceId}/session
+// Resolves the session a trace belongs to and returns the session's logs.
+func (h *Handlers) GetTraceSession(w http.ResponseWriter, r *http.Request) {
+	traceID := chi.URLParam(r, "traceId")
+	if traceID == "" {
+		api.WriteError(w, http.StatusBadRequest, "traceId is required")
+		return
+	}
+
+	limit, _ := parsePagination(r)
+
+	bodyMode, err := parseLogBodyMode(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.store.GetTraceSession(r.Context(), traceID, limit)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if resp == nil {
+		api.WriteError(w, http.StatusNotFound, "trace not found")
+		return
+	}
+	if bodyMode == api.LogBodyNormalized {
+		api.NormalizeLogBodies(resp.Logs)
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetCorrelationGaps handles GET /api/correlation/gaps
+func (h *Handlers) GetCorrelationGaps(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+
+	report, err := h.store.GetCorrelationGaps(r.Context(), from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, report)
 }
 
 // QueryRecentTraces handles GET /api/traces/recent
@@ -79,6 +249,18 @@ func (h *Handlers) QueryMetrics(w http.ResponseWriter, r *http.Request) {
 	from, to := parseTimeRange(r)
 	limit, offset := parsePagination(r)
 
+	format, err := parseResponseFormat(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if format == formatParquet {
+		writeParquet(w, r, "metrics", func(out io.Writer) error {
+			return h.store.ExportMetricsParquet(r.Context(), out, service, metricName, metricType, from, to)
+		})
+		return
+	}
+
 	resp, err := h.store.QueryMetrics(r.Context(), service, metricName, metricType, from, to, limit, offset)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -88,64 +270,469 @@ func (h *Handlers) QueryMetrics(w http.ResponseWriter, r *http.Request) {
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
-// ListMetricNames handles GET /api/metrics/names
-func (h *Handlers) ListMetricNames(w http.ResponseWriter, r *http.Request) {
+// CountMetrics handles GET /api/metrics/count
+func (h *Handlers) CountMetrics(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	metricName := r.URL.Query().Get("name")
+	metricType := r.URL.Query().Get("type")
+	from, to := parseTimeRange(r)
+
+	count, err := h.store.CountMetrics(r.Context(), service, metricName, metricType, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.CountResponse{Count: count})
+}
+
+// ListMetricNames handles GET /api/metrics/names
+func (h *Handlers) ListMetricNames(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+
+	names, err := h.store.GetMetricNames(r.Context(), service)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.MetricNamesResponse{Names: names})
+}
+
+// GetBreakdownValues handles GET /api/metrics/breakdown-values
+func (h *Handlers) GetBreakdownValues(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+
+	attribute := r.URL.Query().Get("attribute")
+	if attribute == "" {
+		api.WriteError(w, http.StatusBadRequest, "attribute parameter is required")
+		return
+	}
+
+	service := r.URL.Query().Get("service")
+
+	values, err := h.store.GetBreakdownValues(r.Context(), metricName, attribute, service)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if isModelAttribute(attribute) {
+		values = h.canonicalModelValues(values)
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.BreakdownValuesResponse{Values: values})
+}
+
+// canonicalModelValues maps model names through the configured aliases, dropping duplicates
+func (h *Handlers) canonicalModelValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		canonical := h.modelAliases.Canonical(v)
+		if !seen[canonical] {
+			seen[canonical] = true
+			result = append(result, canonical)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// GetMetricBreakdown handles GET /api/metrics/breakdown
+// Aggregates a metric over the time range per attribute value. Model attributes are
+// canonicalized through the configured model aliases before grouping.
+func (h *Handlers) GetMetricBreakdown(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+
+	attribute := r.URL.Query().Get("attribute")
+	if attribute == "" {
+		api.WriteError(w, http.StatusBadRequest, "attribute parameter is required")
+		return
+	}
+
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	rows, err := h.store.GetMetricBreakdown(r.Context(), metricName, attribute, service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	buckets := make(map[string]*api.BreakdownBucket)
+	for _, row := range rows {
+		value := row.Value
+		if isModelAttribute(attribute) {
+			value = h.modelAliases.Canonical(row.Value)
+		}
+		b, ok := buckets[value]
+		if !ok {
+			b = &api.BreakdownBucket{Value: value}
+			buckets[value] = b
+		}
+		b.Total += row.Total
+		b.RawValues = append(b.RawValues, row.Value)
+	}
+
+	resp := api.BreakdownResponse{Attribute: attribute, Buckets: make([]api.BreakdownBucket, 0, len(buckets))}
+	for _, b := range buckets {
+		sort.Strings(b.RawValues)
+		resp.Buckets = append(resp.Buckets, *b)
+	}
+	sort.Slice(resp.Buckets, func(i, j int) bool {
+		if resp.Buckets[i].Total != resp.Buckets[j].Total {
+			return resp.Buckets[i].Total > resp.Buckets[j].Total
+		}
+		return resp.Buckets[i].Value < resp.Buckets[j].Value
+	})
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetMetricTable handles GET /api/metrics/table
+func (h *Handlers) GetMetricTable(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+
+	service := r.URL.Query().Get("service")
+
+	rows, err := h.store.GetMetricTable(r.Context(), metricName, service)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Collect the attribute keys present across rows as table columns
+	seen := make(map[string]bool)
+	columns := []string{}
+	for _, row := range rows {
+		for key := range row.Attributes {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	if rows == nil {
+		rows = []api.MetricRow{}
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.MetricTableResponse{Columns: columns, Rows: rows})
+}
+
+// QueryMetricSeries handles GET /api/metrics/series
+func (h *Handlers) QueryMetricSeries(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+
+	service := r.URL.Query().Get("service")
+	intervalStr := r.URL.Query().Get("interval")
+	var intervalSeconds int64 = 60 // default 1 minute
+	if intervalStr != "" {
+		if parsed, err := strconv.ParseInt(intervalStr, 10, 64); err == nil && parsed > 0 {
+			intervalSeconds = parsed
+		}
+	}
+	aggregate := r.URL.Query().Get("aggregate") == "true"
+	from, to := parseTimeRange(r)
+
+	var quantile float64
+	if q := r.URL.Query().Get("quantile"); q != "" {
+		parsed, err := strconv.ParseFloat(q, 64)
+		if err != nil || !validQuantile(parsed) {
+			api.WriteError(w, http.StatusBadRequest, "quantile must be a number in (0, 1]")
+			return
+		}
+		quantile = parsed
+	}
+
+	groupBy, err := parseGroupBy(r.URL.Query().Get("groupBy"))
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if groupBy != nil {
+		if quantile > 0 {
+			api.WriteError(w, http.StatusBadRequest, "quantile cannot be combined with groupBy")
+			return
+		}
+		maxSeries := defaultGroupedSeries
+		if v := r.URL.Query().Get("maxSeries"); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 || parsed > maxGroupedSeries {
+				api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("maxSeries must be between 1 and %d", maxGroupedSeries))
+				return
+			}
+			maxSeries = parsed
+		}
+
+		resp, err := h.store.QueryMetricSeriesGroupBy(r.Context(), metricName, service, from, to, intervalSeconds, aggregate, groupBy, maxSeries)
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	resp, err := h.store.QueryMetricSeries(r.Context(), metricName, service, from, to, intervalSeconds, aggregate, quantile)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// Limits of grouped metric series queries
+const (
+	maxGroupByKeys       = 4
+	defaultGroupedSeries = 100
+	maxGroupedSeries     = 1000
+)
+
+// parseGroupBy parses the comma-separated groupBy keys of a metric series query, dropping
+// duplicates; it returns nil when none are given
+func parseGroupBy(raw string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(raw, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		if strings.Contains(key, `"`) {
+			return nil, fmt.Errorf("invalid groupBy key %q", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	if len(keys) > maxGroupByKeys {
+		return nil, fmt.Errorf("groupBy accepts at most %d keys", maxGroupByKeys)
+	}
+	return keys, nil
+}
+
+// QueryErrorRateSeries handles GET /api/traces/error-rate-series
+func (h *Handlers) QueryErrorRateSeries(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	var intervalSeconds int64 = 60 // default 1 minute
+	if parsed, err := strconv.ParseInt(r.URL.Query().Get("interval"), 10, 64); err == nil && parsed > 0 {
+		intervalSeconds = parsed
+	}
+	from, to := parseTimeRange(r)
+
+	resp, err := h.store.QueryErrorRateSeries(r.Context(), service, from, to, intervalSeconds)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// validQuantile reports whether q is a usable histogram quantile
+func validQuantile(q float64) bool {
+	return q > 0 && q <= 1
+}
+
+// GetMetricDelta handles GET /api/metrics/delta
+func (h *Handlers) GetMetricDelta(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	delta, err := h.store.GetMetricDelta(r.Context(), metricName, service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.MetricDeltaResponse{Name: metricName, From: from, To: to, Delta: delta})
+}
+
+// GetMetricHistogram handles GET /api/metrics/histogram
+func (h *Handlers) GetMetricHistogram(w http.ResponseWriter, r *http.Request) {
+	metricName := r.URL.Query().Get("name")
+	if metricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
+		return
+	}
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	buckets, err := h.store.QueryMetricHistogram(r.Context(), metricName, service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.MetricHistogramResponse{Name: metricName, From: from, To: to, Buckets: buckets})
+}
+
+// GetCostByProject handles GET /api/cost/by-project
+func (h *Handlers) GetCostByProject(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+
+	projects, err := h.store.GetCostByProject(r.Context(), from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := api.ProjectCostResponse{From: from, To: to, Projects: projects}
+	h.currency.applyProjects(&resp)
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetLatencyCost handles GET /api/analytics/latency-cost
+func (h *Handlers) GetLatencyCost(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	points, err := h.store.GetLatencyCostScatter(r.Context(), service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := api.LatencyCostResponse{From: from, To: to, Points: points}
+	h.currency.applyLatencyCost(&resp)
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetEfficiency handles GET /api/analytics/efficiency, the tokens used per dollar per
+// service and interval bucket (default 1 hour)
+func (h *Handlers) GetEfficiency(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	model := r.URL.Query().Get("model")
+	var intervalSeconds int64 = 3600
+	if parsed, err := strconv.ParseInt(r.URL.Query().Get("interval"), 10, 64); err == nil && parsed > 0 {
+		intervalSeconds = parsed
+	}
+	from, to := parseTimeRange(r)
+
+	resp, err := h.store.GetEfficiencySeries(r.Context(), service, model, from, to, intervalSeconds)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetPromptCost handles GET /api/prompts/cost, the cost per normalized prompt pattern.
+// The optional limit parameter keeps only the most expensive patterns (default 50).
+func (h *Handlers) GetPromptCost(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+	limit, _ := parsePagination(r)
+
+	prompts, err := h.store.GetPromptCostBreakdown(r.Context(), from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(prompts) > limit {
+		prompts = prompts[:limit]
+	}
+
+	resp := api.PromptCostResponse{From: from, To: to, Prompts: prompts}
+	h.currency.applyPromptCost(&resp)
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetCostByModel handles GET /api/cost/by-model
+// Models are canonicalized through the configured model aliases, merging their costs.
+func (h *Handlers) GetCostByModel(w http.ResponseWriter, r *http.Request) {
 	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
 
-	names, err := h.store.GetMetricNames(r.Context(), service)
+	costs, err := h.store.GetCostByModel(r.Context(), service, from, to)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	api.WriteJSON(w, http.StatusOK, api.MetricNamesResponse{Names: names})
+	index := make(map[string]int, len(costs))
+	models := make([]api.ModelCost, 0, len(costs))
+	for _, c := range costs {
+		model := h.modelAliases.Canonical(c.Model)
+		if i, ok := index[model]; ok {
+			models[i].CostUSD += c.CostUSD
+			continue
+		}
+		index[model] = len(models)
+		models = append(models, api.ModelCost{Model: model, CostUSD: c.CostUSD})
+	}
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].CostUSD != models[j].CostUSD {
+			return models[i].CostUSD > models[j].CostUSD
+		}
+		return models[i].Model < models[j].Model
+	})
+
+	resp := api.ModelCostResponse{From: from, To: to, Models: models}
+	h.currency.applyModels(&resp)
+
+	api.WriteJSON(w, http.StatusOK, resp)
 }
 
-// GetBreakdownValues handles GET /api/metrics/breakdown-values
-func (h *Handlers) GetBreakdownValues(w http.ResponseWriter, r *http.Request) {
-	metricName := r.URL.Query().Get("name")
-	if metricName == "" {
-		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
-		return
+// GetAttributeCardinality handles GET /api/attributes/cardinality
+func (h *Handlers) GetAttributeCardinality(w http.ResponseWriter, r *http.Request) {
+	signal := r.URL.Query().Get("signal")
+	if signal == "" {
+		signal = "traces"
 	}
-
-	attribute := r.URL.Query().Get("attribute")
-	if attribute == "" {
-		api.WriteError(w, http.StatusBadRequest, "attribute parameter is required")
+	if signal != "traces" && signal != "logs" && signal != "metrics" {
+		api.WriteError(w, http.StatusBadRequest, "signal must be one of traces, logs, metrics")
 		return
 	}
+	from, to := parseTimeRange(r)
 
-	service := r.URL.Query().Get("service")
-
-	values, err := h.store.GetBreakdownValues(r.Context(), metricName, attribute, service)
+	attrs, err := h.store.GetAttributeCardinality(r.Context(), signal, from, to)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	api.WriteJSON(w, http.StatusOK, api.BreakdownValuesResponse{Values: values})
+	api.WriteJSON(w, http.StatusOK, api.AttrCardinalityResponse{Signal: signal, From: from, To: to, Attributes: attrs})
 }
 
-// QueryMetricSeries handles GET /api/metrics/series
-func (h *Handlers) QueryMetricSeries(w http.ResponseWriter, r *http.Request) {
-	metricName := r.URL.Query().Get("name")
-	if metricName == "" {
-		api.WriteError(w, http.StatusBadRequest, "name parameter is required")
-		return
-	}
-
+// GetCacheHitRatio handles GET /api/metrics/cache-hit-ratio
+func (h *Handlers) GetCacheHitRatio(w http.ResponseWriter, r *http.Request) {
 	service := r.URL.Query().Get("service")
-	intervalStr := r.URL.Query().Get("interval")
+	model := r.URL.Query().Get("model")
 	var intervalSeconds int64 = 60 // default 1 minute
-	if intervalStr != "" {
-		if parsed, err := strconv.ParseInt(intervalStr, 10, 64); err == nil && parsed > 0 {
-			intervalSeconds = parsed
-		}
+	if parsed, err := strconv.ParseInt(r.URL.Query().Get("interval"), 10, 64); err == nil && parsed > 0 {
+		intervalSeconds = parsed
 	}
-	aggregate := r.URL.Query().Get("aggregate") == "true"
 	from, to := parseTimeRange(r)
 
-	resp, err := h.store.QueryMetricSeries(r.Context(), metricName, service, from, to, intervalSeconds, aggregate)
+	resp, err := h.store.GetCacheHitRatio(r.Context(), service, model, from, to, intervalSeconds)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -183,6 +770,10 @@ func (h *Handlers) QueryBatchMetricSeries(w http.ResponseWriter, r *http.Request
 			api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("query %d: name is required", i))
 			return
 		}
+		if q.Quantile != 0 && !validQuantile(q.Quantile) {
+			api.WriteError(w, http.StatusBadRequest, fmt.Sprintf("query %d: quantile must be in (0, 1]", i))
+			return
+		}
 	}
 
 	// Parse time range from request body
@@ -198,6 +789,29 @@ func (h *Handlers) QueryBatchMetricSeries(w http.ResponseWriter, r *http.Request
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
+// ValidateMetric handles POST /api/metrics/validate
+func (h *Handlers) ValidateMetric(w http.ResponseWriter, r *http.Request) {
+	var req api.ValidateMetricRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.WriteError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if req.MetricName == "" {
+		api.WriteError(w, http.StatusBadRequest, "metricName is required")
+		return
+	}
+
+	from, to := parseTimeRangeFromStrings(req.From, req.To)
+
+	resp, err := h.store.ValidateMetricQuery(r.Context(), req.WidgetConfig, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
 // parseTimeRangeFromStrings parses time range from string parameters
 func parseTimeRangeFromStrings(fromStr, toStr string) (from, to time.Time) {
 	// Default to last 24 hours
@@ -225,20 +839,154 @@ func (h *Handlers) QueryLogs(w http.ResponseWriter, r *http.Request) {
 	severity := r.URL.Query().Get("severity")
 	traceID := r.URL.Query().Get("traceId")
 	search := r.URL.Query().Get("search")
+	severities, err := parseSeverityRange(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	from, to := parseTimeRange(r)
 	limit, offset := parsePagination(r)
 
-	resp, err := h.store.QueryLogs(r.Context(), service, severity, traceID, search, from, to, limit, offset)
+	bodyMode, err := parseLogBodyMode(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := parsePageCursor(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format, err := parseResponseFormat(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if format == formatParquet {
+		// Parquet exports keep the stored bodies, as the body mode only applies to JSON
+		writeParquet(w, r, "logs", func(out io.Writer) error {
+			return h.store.ExportLogsParquet(r.Context(), out, service, severity, severities, traceID, search, from, to)
+		})
+		return
+	}
+
+	var resp *api.LogsResponse
+	if page != nil {
+		resp, err = h.store.QueryLogsPage(r.Context(), service, severity, severities, traceID, search, from, to, limit, *page)
+	} else {
+		resp, err = h.store.QueryLogs(r.Context(), service, severity, severities, traceID, search, from, to, limit, offset)
+	}
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if bodyMode == api.LogBodyNormalized {
+		api.NormalizeLogBodies(resp.Logs)
+	}
 
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
+// parseLogBodyMode reads the body query parameter: normalized (default) or raw
+func parseLogBodyMode(r *http.Request) (string, error) {
+	switch mode := r.URL.Query().Get("body"); mode {
+	case "", api.LogBodyNormalized:
+		return api.LogBodyNormalized, nil
+	case api.LogBodyRaw:
+		return api.LogBodyRaw, nil
+	default:
+		return "", fmt.Errorf("invalid body mode %q (valid: %s, %s)", mode, api.LogBodyNormalized, api.LogBodyRaw)
+	}
+}
+
+// severityNumbers maps OTLP severity names to the SeverityNumber range of their band
+// (e.g. WARN covers WARN through WARN4)
+var severityNumbers = map[string][2]int32{
+	"TRACE": {1, 4},
+	"DEBUG": {5, 8},
+	"INFO":  {9, 12},
+	"WARN":  {13, 16},
+	"ERROR": {17, 20},
+	"FATAL": {21, 24},
+}
+
+// parseSeverityRange reads the minSeverity and maxSeverity query parameters, each a
+// SeverityNumber (1-24) or a severity name. A name as minSeverity starts at the bottom of its
+// band and as maxSeverity ends at the top, so maxSeverity=WARN includes WARN4.
+func parseSeverityRange(r *http.Request) (storage.SeverityRange, error) {
+	var sr storage.SeverityRange
+	var err error
+	if sr.Min, err = parseSeverityBound(r.URL.Query().Get("minSeverity"), 0); err != nil {
+		return sr, fmt.Errorf("invalid minSeverity: %w", err)
+	}
+	if sr.Max, err = parseSeverityBound(r.URL.Query().Get("maxSeverity"), 1); err != nil {
+		return sr, fmt.Errorf("invalid maxSeverity: %w", err)
+	}
+	if sr.Min > 0 && sr.Max > 0 && sr.Min > sr.Max {
+		return sr, fmt.Errorf("minSeverity must not be above maxSeverity")
+	}
+	return sr, nil
+}
+
+// parseSeverityBound parses one severity bound; edge selects the bottom (0) or top (1) of a
+// named band. Empty means unbounded.
+func parseSeverityBound(value string, edge int) (int32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if band, ok := severityNumbers[strings.ToUpper(value)]; ok {
+		return band[edge], nil
+	}
+	n, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || n < 1 || n > 24 {
+		return 0, fmt.Errorf("%q is not a severity number (1-24) or one of TRACE, DEBUG, INFO, WARN, ERROR, FATAL", value)
+	}
+	return int32(n), nil
+}
+
+// CountLogs handles GET /api/logs/count
+func (h *Handlers) CountLogs(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	severity := r.URL.Query().Get("severity")
+	traceID := r.URL.Query().Get("traceId")
+	search := r.URL.Query().Get("search")
+	severities, err := parseSeverityRange(r)
+	if err != nil {
+		api.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	from, to := parseTimeRange(r)
+
+	count, err := h.store.CountLogs(r.Context(), service, severity, severities, traceID, search, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.CountResponse{Count: count})
+}
+
 // GetLogLevels handles GET /api/logs/levels
+// With ?byService=true, returns severity counts per service within from/to
 func (h *Handlers) GetLogLevels(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("byService") == "true" {
+		from, to := parseTimeRange(r)
+		matrix, err := h.store.GetLogLevelMatrix(r.Context(), from, to)
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		api.WriteJSON(w, http.StatusOK, matrix)
+		return
+	}
+
 	levels, err := h.store.GetLogLevels(r.Context())
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -253,8 +1001,27 @@ func (h *Handlers) QuerySessions(w http.ResponseWriter, r *http.Request) {
 	service := r.URL.Query().Get("service")
 	from, to := parseTimeRange(r)
 	limit, offset := parsePagination(r)
+	preview := r.URL.Query().Get("preview") == "true"
+
+	resp, err := h.store.QuerySessions(r.Context(), service, from, to, limit, offset, preview)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	markSessionStatus(resp.Sessions, time.Now(), h.sessionIdle)
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetSessionActivity handles GET /api/sessions/activity
+func (h *Handlers) GetSessionActivity(w http.ResponseWriter, r *http.Request) {
+	var intervalSeconds int64 = 86400 // default 1 day
+	if parsed, err := strconv.ParseInt(r.URL.Query().Get("interval"), 10, 64); err == nil && parsed > 0 {
+		intervalSeconds = parsed
+	}
+	from, to := parseTimeRange(r)
 
-	resp, err := h.store.QuerySessions(r.Context(), service, from, to, limit, offset)
+	resp, err := h.store.GetSessionActivity(r.Context(), from, to, intervalSeconds)
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -263,6 +1030,23 @@ func (h *Handlers) QuerySessions(w http.ResponseWriter, r *http.Request) {
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
+// DefaultSessionIdleTimeout is how long a session may go without new logs before it is
+// reported as completed, unless configured otherwise
+const DefaultSessionIdleTimeout = 30 * time.Minute
+
+// markSessionStatus sets each session's status: completed once its last log is more than
+// idle before now, active otherwise. Sessions have no explicit end event, so a session that
+// resumes after the idle window is reported as active again.
+func markSessionStatus(sessions []api.Session, now time.Time, idle time.Duration) {
+	for i := range sessions {
+		if now.Sub(sessions[i].LastTime) > idle {
+			sessions[i].Status = api.SessionCompleted
+		} else {
+			sessions[i].Status = api.SessionActive
+		}
+	}
+}
+
 // GetSessionTranscript handles GET /api/sessions/{sessionId}/transcript
 func (h *Handlers) GetSessionTranscript(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "sessionId")
@@ -280,36 +1064,259 @@ func (h *Handlers) GetSessionTranscript(w http.ResponseWriter, r *http.Request)
 	api.WriteJSON(w, http.StatusOK, resp)
 }
 
+// GetSessionCost handles GET /api/sessions/{sessionId}/cost
+func (h *Handlers) GetSessionCost(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionId")
+	if sessionID == "" {
+		api.WriteError(w, http.StatusBadRequest, "sessionId is required")
+		return
+	}
+
+	resp, err := h.store.GetSessionCost(r.Context(), sessionID)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if resp == nil {
+		api.WriteError(w, http.StatusNotFound, "session not found: "+sessionID)
+		return
+	}
+
+	h.currency.applySessionCost(resp)
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
 // ListServices handles GET /api/services
 func (h *Handlers) ListServices(w http.ResponseWriter, r *http.Request) {
+	if h.notModified(w, r) {
+		return
+	}
+
 	services, err := h.store.GetServices(r.Context())
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	api.WriteJSON(w, http.StatusOK, api.ServicesResponse{Services: services})
+	api.WriteJSON(w, http.StatusOK, api.ServicesResponse{
+		Services: services,
+		Groups:   h.serviceGroups.Assign(services),
+	})
+}
+
+// GetServiceSummary handles GET /api/services/{name}/summary
+func (h *Handlers) GetServiceSummary(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "name")
+	if service == "" {
+		api.WriteError(w, http.StatusBadRequest, "service name is required")
+		return
+	}
+	from, to := parseTimeRange(r)
+	extrapolate := r.URL.Query().Get("extrapolate") == "true"
+
+	summary, err := h.store.GetServiceSummary(r.Context(), service, from, to, extrapolate)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.currency.applyServiceSummary(summary)
+
+	api.WriteJSON(w, http.StatusOK, summary)
+}
+
+// GetServiceLatency handles GET /api/services/latency
+func (h *Handlers) GetServiceLatency(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+	from, to := parseTimeRange(r)
+
+	latency, err := h.store.GetServiceLatencyStats(r.Context(), service, from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, latency)
+}
+
+// GetServiceGraph handles GET /api/services/graph. The optional groupBy parameter names a
+// resource attribute whose value annotates each node as its group.
+func (h *Handlers) GetServiceGraph(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+
+	graph, err := h.store.GetServiceGraph(r.Context(), from, to, r.URL.Query().Get("groupBy"))
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, graph)
+}
+
+// GetDataTimeRange handles GET /api/time-range
+func (h *Handlers) GetDataTimeRange(w http.ResponseWriter, r *http.Request) {
+	if h.notModified(w, r) {
+		return
+	}
+
+	timeRange, err := h.store.GetDataTimeRange(r.Context())
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, timeRange)
+}
+
+// notModified sets Last-Modified from the store's data version and answers 304 when the
+// request's If-Modified-Since is current. Writes within the current second can't be told
+// apart at HTTP date resolution, so Last-Modified is only sent once that second has passed.
+func (h *Handlers) notModified(w http.ResponseWriter, r *http.Request) bool {
+	_, modified := h.store.DataVersion()
+	modified = modified.Truncate(time.Second)
+	if !modified.Before(time.Now().Truncate(time.Second)) {
+		return false
+	}
+
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ListScopes handles GET /api/scopes
+func (h *Handlers) ListScopes(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+
+	scopes, err := h.store.GetScopes(r.Context(), service)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if scopes == nil {
+		scopes = []api.ScopeInfo{}
+	}
+
+	api.WriteJSON(w, http.StatusOK, api.ScopesResponse{Scopes: scopes})
 }
 
 // GetStats handles GET /api/stats
+// With extrapolate=true, span and trace counts are scaled up by the spans' sampling probability.
 func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.store.GetStats(r.Context())
+	getStats := h.store.GetStats
+	if r.URL.Query().Get("extrapolate") == "true" {
+		getStats = h.store.GetExtrapolatedStats
+	}
+	stats, err := getStats(r.Context())
 	if err != nil {
 		api.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.addRuntimeStats(stats)
 
 	api.WriteJSON(w, http.StatusOK, stats)
 }
 
+// addRuntimeStats fills in the stats tracked in memory rather than in the database
+func (h *Handlers) addRuntimeStats(stats *api.StatsResponse) {
+	stats.Env = h.envLabel
+	stats.DroppedMetrics = h.metricFilter.Dropped()
+	stats.SampledLogs = h.logSampler.Dropped()
+	stats.TruncatedAttributes = h.attrLimit.Truncated()
+	stats.IgnoredRequests = h.signals.Ignored()
+	stats.Ingest = h.ingest.Counters()
+	if h.dedup.window > 0 {
+		dedup := h.dedup.Stats()
+		stats.Dedup = &dedup
+	}
+}
+
+// GetOverview handles GET /api/overview
+func (h *Handlers) GetOverview(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	if s := r.URL.Query().Get("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			api.WriteError(w, http.StatusBadRequest, "invalid since: must be an RFC3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	overview, err := h.store.GetOverview(r.Context(), since, today)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.addRuntimeStats(overview.Stats)
+	h.currency.applyOverview(overview)
+
+	api.WriteJSON(w, http.StatusOK, overview)
+}
+
+// GetDroppedCounts handles GET /api/dropped
+func (h *Handlers) GetDroppedCounts(w http.ResponseWriter, r *http.Request) {
+	from, to := parseTimeRange(r)
+
+	resp, err := h.store.GetDroppedCounts(r.Context(), from, to)
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetStorageUsage handles GET /api/self/storage
+func (h *Handlers) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	current, err := h.store.GetStorageUsage(r.Context())
+	if err != nil {
+		api.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := api.StorageUsageResponse{Current: *current, Samples: []api.StorageSample{}}
+	if h.usage != nil {
+		n, _ := strconv.Atoi(r.URL.Query().Get("samples"))
+		if samples := h.usage.Samples(n); len(samples) > 0 {
+			resp.Samples = samples
+			resp.Growth = storage.StorageGrowth(samples[0], *current)
+		}
+	}
+
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
 // HandleWebSocket handles GET /ws
 func (h *Handlers) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	websocket.ServeWs(h.hub, w, r)
 }
 
-// Health handles GET /health
+// Health handles GET /health, reporting the running version and how long ago it started
 func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
-	api.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	resp := map[string]string{
+		"status":  "ok",
+		"version": version.Version,
+		"uptime":  time.Since(h.started).Round(time.Second).String(),
+	}
+	if h.envLabel != "" {
+		resp["env"] = h.envLabel
+	}
+	api.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetVersion handles GET /api/version
+func (h *Handlers) GetVersion(w http.ResponseWriter, r *http.Request) {
+	api.WriteJSON(w, http.StatusOK, api.VersionResponse{
+		Version:   version.Version,
+		GitCommit: version.GitCommit,
+		BuildDate: version.BuildDate,
+	})
 }
 
 // Helper functions
@@ -357,3 +1364,25 @@ func parsePagination(r *http.Request) (limit, offset int) {
 
 	return limit, offset
 }
+
+// parsePageCursor reads the before/after cursor parameters. It returns nil when neither is set,
+// in which case the offset from parsePagination applies.
+func parsePageCursor(r *http.Request) (*storage.PageCursor, error) {
+	before := r.URL.Query().Get("before")
+	after := r.URL.Query().Get("after")
+	if before != "" && after != "" {
+		return nil, fmt.Errorf("before and after cannot be combined")
+	}
+	token := before
+	if after != "" {
+		token = after
+	}
+	if token == "" {
+		return nil, nil
+	}
+	cursor, err := storage.DecodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.PageCursor{Cursor: cursor, After: after != ""}, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// DefaultQueryQueueTimeout is how long a query waits for a free slot before it is rejected
+const DefaultQueryQueueTimeout = 5 * time.Second
+
+// queryLimitRetryAfter is the Retry-After, in seconds, sent with queries rejected by the limit
+const queryLimitRetryAfter = 1
+
+// QueryLimit bounds how many API queries run at once so that many dashboards refreshing
+// together cannot pile work onto the single DuckDB connection. Excess queries wait for a
+// free slot and are rejected with 503 and Retry-After if none frees up in time.
+type QueryLimit struct {
+	slots   chan struct{} // nil when unlimited
+	timeout time.Duration
+}
+
+// NewQueryLimit creates a limit of max concurrent queries that wait at most timeout for a
+// slot. max <= 0 disables the limit; timeout <= 0 uses DefaultQueryQueueTimeout.
+func NewQueryLimit(max int, timeout time.Duration) *QueryLimit {
+	if timeout <= 0 {
+		timeout = DefaultQueryQueueTimeout
+	}
+	l := &QueryLimit{timeout: timeout}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire waits for a free slot and reports whether one was taken. On true, the caller must
+// call release once the query has finished.
+func (l *QueryLimit) acquire(ctx context.Context) bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *QueryLimit) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// QueryLimitMiddleware runs API requests within the concurrent query limit, rejecting those
+// that found no free slot with 503 and Retry-After
+func (h *Handlers) QueryLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.queryLimit.acquire(r.Context()) {
+			w.Header().Set("Retry-After", strconv.Itoa(queryLimitRetryAfter))
+			api.WriteError(w, http.StatusServiceUnavailable, "too many concurrent queries")
+			return
+		}
+		defer h.queryLimit.release()
+		next.ServeHTTP(w, r)
+	})
+}
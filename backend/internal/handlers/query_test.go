@@ -1,17 +1,29 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/otlp"
 	"github.com/tobilg/ai-observer/internal/storage"
+	"github.com/tobilg/ai-observer/internal/version"
 	"github.com/tobilg/ai-observer/internal/websocket"
 )
 
@@ -86,6 +98,31 @@ func TestGetStats(t *testing.T) {
 	}
 }
 
+func TestGetStats_Extrapolate(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	spans := []api.Span{
+		{Timestamp: time.Now(), TraceID: "t1", SpanID: "a", SpanName: "op", ServiceName: "svc"},
+		{Timestamp: time.Now(), TraceID: "t2", SpanID: "b", TraceState: "ot=th:c", SpanName: "op", ServiceName: "svc"},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	for query, want := range map[string]int64{"": 2, "?extrapolate=true": 5} {
+		rec := httptest.NewRecorder()
+		h.GetStats(rec, httptest.NewRequest(http.MethodGet, "/api/stats"+query, nil))
+		var stats api.StatsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if stats.SpanCount != want || stats.TraceCount != want || stats.Extrapolated != (query != "") {
+			t.Errorf("%q: expected %d spans and traces, got %+v", query, want, stats)
+		}
+	}
+}
+
 func TestListServices(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -110,6 +147,215 @@ func TestListServices(t *testing.T) {
 	}
 }
 
+func TestGetDataTimeRange(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	insertTestTrace(t, h.store, "trace1", "span1", "svc-a", "op")
+	insertTestLog(t, h.store, "svc-b", "INFO", "hello")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/time-range", nil)
+	rec := httptest.NewRecorder()
+	h.GetDataTimeRange(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp api.DataTimeRangeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.From == nil || resp.To == nil {
+		t.Fatalf("expected from and to to be set, got %+v", resp)
+	}
+	if resp.To.Before(*resp.From) {
+		t.Errorf("expected to >= from, got from=%v to=%v", resp.From, resp.To)
+	}
+}
+
+func TestIfModifiedSince(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	insertTestTrace(t, h.store, "trace1", "span1", "svc-a", "op")
+
+	// Last-Modified is only sent once the second of the latest write has passed
+	now := time.Now()
+	time.Sleep(now.Truncate(time.Second).Add(time.Second).Sub(now))
+
+	endpoints := []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/api/services", h.ListServices},
+		{"/api/time-range", h.GetDataTimeRange},
+	}
+
+	get := func(handler http.HandlerFunc, path, since string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if since != "" {
+			req.Header.Set("If-Modified-Since", since)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	lastModified := make(map[string]string)
+	for _, ep := range endpoints {
+		rec := get(ep.handler, ep.path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", ep.path, rec.Code)
+		}
+		lastModified[ep.path] = rec.Header().Get("Last-Modified")
+		if lastModified[ep.path] == "" {
+			t.Fatalf("%s: expected Last-Modified header", ep.path)
+		}
+
+		rec = get(ep.handler, ep.path, lastModified[ep.path])
+		if rec.Code != http.StatusNotModified {
+			t.Errorf("%s: expected status 304 for current If-Modified-Since, got %d", ep.path, rec.Code)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("%s: expected empty body for 304, got %q", ep.path, rec.Body.String())
+		}
+	}
+
+	// New data invalidates the cached responses
+	insertTestTrace(t, h.store, "trace2", "span2", "svc-b", "op")
+
+	for _, ep := range endpoints {
+		rec := get(ep.handler, ep.path, lastModified[ep.path])
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200 after new data, got %d", ep.path, rec.Code)
+		}
+	}
+
+	var resp api.ServicesResponse
+	rec := get(h.ListServices, "/api/services", lastModified["/api/services"])
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Services) != 2 {
+		t.Errorf("expected 2 services after new data, got %v", resp.Services)
+	}
+}
+
+func TestGetStorageUsage(t *testing.T) {
+	store, err := storage.NewDuckDBStore(filepath.Join(t.TempDir(), "test.duckdb"))
+	if err != nil {
+		t.Fatalf("failed to create test store: %v", err)
+	}
+	defer store.Close()
+
+	h := New(store, websocket.NewHub())
+	sampler := storage.NewUsageSampler(store, 10)
+	h.SetUsageSampler(sampler)
+
+	ctx := context.Background()
+	if _, err := sampler.Sample(ctx); err != nil {
+		t.Fatalf("Sample failed: %v", err)
+	}
+
+	insertTestTrace(t, store, "trace1", "span1", "svc-a", "op")
+	insertTestLog(t, store, "svc-a", "INFO", "first")
+	insertTestLog(t, store, "svc-a", "INFO", "second")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/self/storage", nil)
+	rec := httptest.NewRecorder()
+	h.GetStorageUsage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp api.StorageUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Current.SizeBytes <= 0 {
+		t.Errorf("expected positive current size, got %d", resp.Current.SizeBytes)
+	}
+	want := map[string]int64{"otel_traces": 1, "otel_logs": 2, "otel_metrics": 0}
+	for table, count := range want {
+		if resp.Current.Rows[table] != count {
+			t.Errorf("Current.Rows[%s] = %d, want %d", table, resp.Current.Rows[table], count)
+		}
+	}
+
+	if len(resp.Samples) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(resp.Samples))
+	}
+	if resp.Growth == nil {
+		t.Fatal("expected growth since the first sample")
+	}
+	if resp.Growth.Rows["otel_logs"] != 2 || resp.Growth.Rows["otel_traces"] != 1 {
+		t.Errorf("unexpected row growth: %v", resp.Growth.Rows)
+	}
+}
+
+func TestGetStorageUsage_WithoutSampler(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/self/storage", nil)
+	rec := httptest.NewRecorder()
+	h.GetStorageUsage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp api.StorageUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Growth != nil || len(resp.Samples) != 0 {
+		t.Errorf("expected no samples or growth without a sampler, got %+v", resp)
+	}
+}
+
+func TestListScopes(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	spans := []api.Span{{
+		TraceID:      "trace1",
+		SpanID:       "span1",
+		ServiceName:  "test-service",
+		SpanName:     "op",
+		Timestamp:    time.Now(),
+		ScopeName:    "test-scope",
+		ScopeVersion: "0.1.0",
+	}}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scopes?service=test-service", nil)
+	rec := httptest.NewRecorder()
+
+	h.ListScopes(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp api.ScopesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Scopes) != 1 {
+		t.Fatalf("expected 1 scope, got %d", len(resp.Scopes))
+	}
+	if resp.Scopes[0].ScopeName != "test-scope" || resp.Scopes[0].ScopeVersion != "0.1.0" {
+		t.Errorf("unexpected scope: %+v", resp.Scopes[0])
+	}
+}
+
 func TestQueryTraces(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -124,6 +370,7 @@ func TestQueryTraces(t *testing.T) {
 		{"with offset", "/api/traces?offset=5", http.StatusOK},
 		{"with service filter", "/api/traces?service=test-service", http.StatusOK},
 		{"with search filter", "/api/traces?search=test", http.StatusOK},
+		{"with event filter", "/api/traces?event=exception", http.StatusOK},
 		{"with time range", "/api/traces?from=2024-01-01T00:00:00Z&to=2024-12-31T23:59:59Z", http.StatusOK},
 		{"limit capped at 1000", "/api/traces?limit=5000", http.StatusOK},
 		{"invalid limit uses default", "/api/traces?limit=invalid", http.StatusOK},
@@ -143,6 +390,93 @@ func TestQueryTraces(t *testing.T) {
 	}
 }
 
+func TestCountEndpoints_MatchQueryTotal(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	insertTestTrace(t, h.store, "trace-1", "span-1", "svc-a", "alpha")
+	insertTestTrace(t, h.store, "trace-2", "span-2", "svc-a", "beta")
+	insertTestTrace(t, h.store, "trace-3", "span-3", "svc-b", "alpha")
+	insertTestLog(t, h.store, "svc-a", "INFO", "hello")
+	insertTestLog(t, h.store, "svc-a", "ERROR", "boom")
+	insertTestLog(t, h.store, "svc-b", "INFO", "hello again")
+
+	value := 1.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: time.Now(), ServiceName: "svc-a", MetricName: "m1", MetricType: "gauge", Value: &value},
+		{Timestamp: time.Now(), ServiceName: "svc-a", MetricName: "m2", MetricType: "sum", Value: &value},
+		{Timestamp: time.Now(), ServiceName: "svc-b", MetricName: "m1", MetricType: "gauge", Value: &value},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	count := func(handler http.HandlerFunc, url string) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", url, rec.Code, rec.Body.String())
+		}
+		var resp api.CountResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode count: %v", url, err)
+		}
+		return resp.Count
+	}
+
+	total := func(handler http.HandlerFunc, url string, resp interface{ total() int }) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", url, err)
+		}
+		return resp.total()
+	}
+
+	tests := []struct {
+		name     string
+		countURL string
+		queryURL string
+		count    http.HandlerFunc
+		query    http.HandlerFunc
+		resp     interface{ total() int }
+	}{
+		{"traces", "/api/traces/count", "/api/traces?limit=1", h.CountTraces, h.QueryTraces, &tracesTotal{}},
+		{"traces filtered", "/api/traces/count?service=svc-a&search=alpha", "/api/traces?service=svc-a&search=alpha&limit=1", h.CountTraces, h.QueryTraces, &tracesTotal{}},
+		{"logs", "/api/logs/count", "/api/logs?limit=1", h.CountLogs, h.QueryLogs, &logsTotal{}},
+		{"logs filtered", "/api/logs/count?severity=INFO&search=hello", "/api/logs?severity=INFO&search=hello&limit=1", h.CountLogs, h.QueryLogs, &logsTotal{}},
+		{"metrics", "/api/metrics/count", "/api/metrics?limit=1", h.CountMetrics, h.QueryMetrics, &metricsTotal{}},
+		{"metrics filtered", "/api/metrics/count?name=m1&type=gauge", "/api/metrics?name=m1&type=gauge&limit=1", h.CountMetrics, h.QueryMetrics, &metricsTotal{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := count(tt.count, tt.countURL)
+			want := total(tt.query, tt.queryURL, tt.resp)
+			if got != want {
+				t.Errorf("count = %d, query total = %d", got, want)
+			}
+			if got == 0 {
+				t.Error("expected a non-zero count for seeded data")
+			}
+		})
+	}
+}
+
+type tracesTotal struct{ api.TracesResponse }
+
+func (r *tracesTotal) total() int { return r.Total }
+
+type logsTotal struct{ api.LogsResponse }
+
+func (r *logsTotal) total() int { return r.Total }
+
+type metricsTotal struct{ api.MetricsResponse }
+
+func (r *metricsTotal) total() int { return r.Total }
+
 func TestGetTrace(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -177,132 +511,528 @@ func TestGetTrace(t *testing.T) {
 	}
 }
 
-func TestGetTrace_MissingTraceID(t *testing.T) {
+func TestGetTrace_CodexVirtualTrace(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
-	req := httptest.NewRequest(http.MethodGet, "/api/traces/", nil)
-	// Empty route context (no traceId)
-	rctx := chi.NewRouteContext()
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "c1", SpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now.Add(-time.Minute)},
+		{TraceID: "c1", SpanID: "tool", ParentSpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now.Add(-time.Minute)},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
 
 	rec := httptest.NewRecorder()
-	h.GetTrace(rec, req)
+	h.QueryTraces(rec, httptest.NewRequest(http.MethodGet, "/api/traces", nil))
+	var list api.TracesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode traces: %v", err)
+	}
+	if len(list.Traces) != 1 || list.Traces[0].TraceID != "codex:turn" {
+		t.Fatalf("expected the prefixed virtual trace, got %+v", list.Traces)
+	}
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("expected status 400, got %d", rec.Code)
+	traceID := list.Traces[0].TraceID
+	req := httptest.NewRequest(http.MethodGet, "/api/traces/"+traceID, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("traceId", traceID)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec = httptest.NewRecorder()
+	h.GetTrace(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var detail api.SpansResponse
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatalf("failed to decode spans: %v", err)
+	}
+	if len(detail.Spans) != 2 {
+		t.Errorf("expected the virtual trace's 2 spans, got %+v", detail.Spans)
 	}
 }
 
-func TestQueryLogs(t *testing.T) {
+func TestGetTraceSpans_SpanRole(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
-	// Insert test logs
-	insertTestLog(t, h.store, "test-service", "INFO", "Test log message")
-	insertTestLog(t, h.store, "test-service", "ERROR", "Error log message")
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "trace-role", SpanID: "root", ServiceName: "svc", SpanName: "root", Timestamp: now},
+		{TraceID: "trace-role", SpanID: "mid", ParentSpanID: "root", ServiceName: "svc", SpanName: "mid", Timestamp: now.Add(time.Millisecond)},
+		{TraceID: "trace-role", SpanID: "leaf", ParentSpanID: "mid", ServiceName: "svc", SpanName: "leaf", Timestamp: now.Add(2 * time.Millisecond)},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
 
-	tests := []struct {
-		name       string
-		query      string
-		wantStatus int
-	}{
-		{"default params", "/api/logs", http.StatusOK},
-		{"with severity filter", "/api/logs?severity=ERROR", http.StatusOK},
-		{"with service filter", "/api/logs?service=test-service", http.StatusOK},
-		{"with search", "/api/logs?search=error", http.StatusOK},
-		{"with pagination", "/api/logs?limit=10&offset=0", http.StatusOK},
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/traces/trace-role/spans?"+query, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("traceId", "trace-role")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.GetTraceSpans(rec, req)
+		return rec
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
-			rec := httptest.NewRecorder()
+	for role, want := range map[string]string{"root": "root", "leaf": "leaf"} {
+		rec := get("spanRole=" + role)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", role, rec.Code, rec.Body.String())
+		}
+		var resp api.SpansResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Spans) != 1 || resp.Spans[0].SpanID != want {
+			t.Errorf("%s: expected only span %q, got %+v", role, want, resp.Spans)
+		}
+	}
 
-			h.QueryLogs(rec, req)
+	rec := get("spanRole=leaf&stream=true")
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), "\n") != 1 || !strings.Contains(rec.Body.String(), `"spanId":"leaf"`) {
+		t.Errorf("expected a single streamed leaf span, got %d: %s", rec.Code, rec.Body.String())
+	}
 
-			if rec.Code != tt.wantStatus {
-				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
-			}
-		})
+	if rec := get("spanRole=branch"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown role, got %d", rec.Code)
 	}
 }
 
-func TestGetLogLevels(t *testing.T) {
+func TestGetTraceSpans_Stream(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
-	req := httptest.NewRequest(http.MethodGet, "/api/logs/levels", nil)
-	rec := httptest.NewRecorder()
+	now := time.Now()
+	var spans []api.Span
+	for i := 0; i < 5; i++ {
+		spans = append(spans, api.Span{
+			TraceID:     "trace-stream",
+			SpanID:      fmt.Sprintf("span-%d", i),
+			ServiceName: "test-service",
+			SpanName:    "op",
+			Timestamp:   now.Add(time.Duration(i) * time.Millisecond),
+		})
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
 
-	h.GetLogLevels(rec, req)
+	newRequest := func(traceID string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/traces/"+traceID+"/spans", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("traceId", traceID)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetTraceSpans(rec, newRequest("trace-stream"))
 
 	if rec.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", rec.Code)
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected NDJSON content type, got %q", ct)
+	}
+
+	dec := json.NewDecoder(rec.Body)
+	var got []string
+	for dec.More() {
+		var span api.Span
+		if err := dec.Decode(&span); err != nil {
+			t.Fatalf("failed to decode streamed span: %v", err)
+		}
+		got = append(got, span.SpanID)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 streamed spans, got %d", len(got))
+	}
+	for i, id := range got {
+		if id != fmt.Sprintf("span-%d", i) {
+			t.Errorf("span %d: expected span-%d, got %s", i, i, id)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetTraceSpans(rec, newRequest("nonexistent"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing trace, got %d", rec.Code)
 	}
 }
 
-func TestQueryMetrics(t *testing.T) {
+func TestGetTrace_MissingTraceID(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
-	tests := []struct {
-		name       string
-		query      string
-		wantStatus int
-	}{
-		{"default params", "/api/metrics", http.StatusOK},
-		{"with name filter", "/api/metrics?name=cpu_usage", http.StatusOK},
-		{"with type filter", "/api/metrics?type=gauge", http.StatusOK},
-		{"with service filter", "/api/metrics?service=test-service", http.StatusOK},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
-			rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/traces/", nil)
+	// Empty route context (no traceId)
+	rctx := chi.NewRouteContext()
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
-			h.QueryMetrics(rec, req)
+	rec := httptest.NewRecorder()
+	h.GetTrace(rec, req)
 
-			if rec.Code != tt.wantStatus {
-				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
-			}
-		})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
 	}
 }
 
-func TestListMetricNames(t *testing.T) {
+func TestQueryLogs_BodyMode(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
-	req := httptest.NewRequest(http.MethodGet, "/api/metrics/names", nil)
-	rec := httptest.NewRecorder()
+	logs := []api.LogRecord{{
+		Timestamp:     time.Now(),
+		ServiceName:   "svc-a",
+		SeverityText:  "INFO",
+		LogAttributes: map[string]string{"event.name": "user_prompt"},
+	}}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
 
-	h.ListMetricNames(rec, req)
+	tests := []struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"", http.StatusOK, "user_prompt"},
+		{"?body=normalized", http.StatusOK, "user_prompt"},
+		{"?body=raw", http.StatusOK, ""},
+		{"?body=invalid", http.StatusBadRequest, ""},
+	}
 
-	if rec.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", rec.Code)
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		h.QueryLogs(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: expected status %d, got %d", tt.query, tt.wantCode, rec.Code)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+
+		var resp api.LogsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Logs) != 1 {
+			t.Fatalf("%q: expected 1 log, got %d", tt.query, len(resp.Logs))
+		}
+		if resp.Logs[0].Body != tt.wantBody {
+			t.Errorf("%q: expected body %q, got %q", tt.query, tt.wantBody, resp.Logs[0].Body)
+		}
 	}
 }
 
-func TestQueryMetricSeries(t *testing.T) {
+func TestQueryLogs_SeverityRange(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
 
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", SeverityText: "DEBUG", SeverityNumber: 5, Body: "debug"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "INFO", SeverityNumber: 9, Body: "info"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "WARN", SeverityNumber: 13, Body: "warn"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "WARN", SeverityNumber: 15, Body: "warn3"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "ERROR", SeverityNumber: 17, Body: "error"},
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
 	tests := []struct {
-		name       string
-		query      string
-		wantStatus int
+		query string
+		want  []string
 	}{
-		{"missing name parameter", "/api/metrics/series", http.StatusBadRequest},
-		{"with name parameter", "/api/metrics/series?name=cpu_usage", http.StatusOK},
-		{"with all params", "/api/metrics/series?name=cpu_usage&service=test&interval=60&aggregate=true", http.StatusOK},
-		{"with time range", "/api/metrics/series?name=cpu_usage&from=2024-01-01T00:00:00Z&to=2024-12-31T23:59:59Z", http.StatusOK},
+		{"?minSeverity=WARN", []string{"error", "warn", "warn3"}},
+		{"?minSeverity=warn", []string{"error", "warn", "warn3"}},
+		{"?minSeverity=13", []string{"error", "warn", "warn3"}},
+		{"?maxSeverity=WARN", []string{"debug", "info", "warn", "warn3"}},
+		{"?minSeverity=INFO&maxSeverity=13", []string{"info", "warn"}},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
+		req := httptest.NewRequest(http.MethodGet, "/api/logs"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		h.QueryLogs(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", tt.query, rec.Code)
+		}
+		var resp api.LogsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		var bodies []string
+		for _, log := range resp.Logs {
+			bodies = append(bodies, log.Body)
+		}
+		sort.Strings(bodies)
+		if !reflect.DeepEqual(bodies, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, bodies)
+		}
+	}
+
+	for _, query := range []string{"?minSeverity=LOUD", "?maxSeverity=25", "?minSeverity=0", "?minSeverity=ERROR&maxSeverity=WARN"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil)
+		rec := httptest.NewRecorder()
+		h.QueryLogs(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs/count?minSeverity=WARN", nil)
+	rec := httptest.NewRecorder()
+	h.CountLogs(rec, req)
+	var count api.CountResponse
+	if err := json.NewDecoder(rec.Body).Decode(&count); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if count.Count != 3 {
+		t.Errorf("expected 3 logs at or above WARN, got %d", count.Count)
+	}
+}
+
+func TestQueryLogs_Cursor(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	var logs []api.LogRecord
+	for i := 0; i < 3; i++ {
+		logs = append(logs, api.LogRecord{Timestamp: now.Add(-time.Duration(i) * time.Minute), ServiceName: "svc", Body: fmt.Sprintf("log-%d", i)})
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	get := func(query string) (*httptest.ResponseRecorder, api.LogsResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/logs"+query, nil)
+		rec := httptest.NewRecorder()
+		h.QueryLogs(rec, req)
+		var resp api.LogsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	rec, first := get("?limit=2")
+	if rec.Code != http.StatusOK || first.NextCursor == "" {
+		t.Fatalf("expected a next cursor, got status %d and %+v", rec.Code, first)
+	}
+	rec, second := get("?limit=2&before=" + first.NextCursor)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if len(second.Logs) != 1 || second.Logs[0].Body != "log-2" || second.HasMore || second.NextCursor != "" {
+		t.Errorf("unexpected second page: %+v", second)
+	}
+	if second.Total != 3 {
+		t.Errorf("expected total 3, got %d", second.Total)
+	}
+
+	for _, query := range []string{
+		"?before=not-a-cursor",
+		"?before=" + first.NextCursor + "&after=" + first.NextCursor,
+		"?after=" + storage.Cursor{Timestamp: now, ID: "trace"}.Encode(),
+	} {
+		if rec, _ := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestQueryTraces_Cursor(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: now},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "svc", SpanName: "op", Timestamp: now.Add(-time.Minute)},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	next := storage.Cursor{Timestamp: now, ID: "t1"}.Encode()
+	req := httptest.NewRequest(http.MethodGet, "/api/traces?before="+next, nil)
+	rec := httptest.NewRecorder()
+	h.QueryTraces(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp api.TracesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Traces) != 1 || resp.Traces[0].TraceID != "t2" {
+		t.Errorf("expected only t2 before t1, got %+v", resp.Traces)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/traces?after=bad!", nil)
+	rec = httptest.NewRecorder()
+	h.QueryTraces(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid cursor, got %d", rec.Code)
+	}
+}
+
+func TestQueryLogs(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	// Insert test logs
+	insertTestLog(t, h.store, "test-service", "INFO", "Test log message")
+	insertTestLog(t, h.store, "test-service", "ERROR", "Error log message")
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"default params", "/api/logs", http.StatusOK},
+		{"with severity filter", "/api/logs?severity=ERROR", http.StatusOK},
+		{"with service filter", "/api/logs?service=test-service", http.StatusOK},
+		{"with search", "/api/logs?search=error", http.StatusOK},
+		{"with pagination", "/api/logs?limit=10&offset=0", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			h.QueryLogs(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetLogLevels(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs/levels", nil)
+	rec := httptest.NewRecorder()
+
+	h.GetLogLevels(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestGetLogLevels_ByService(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	insertTestLog(t, h.store, "svc-a", "INFO", "one")
+	insertTestLog(t, h.store, "svc-a", "ERROR", "two")
+	insertTestLog(t, h.store, "svc-b", "INFO", "three")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs/levels?byService=true", nil)
+	rec := httptest.NewRecorder()
+
+	h.GetLogLevels(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var matrix map[string]map[string]int64
+	if err := json.NewDecoder(rec.Body).Decode(&matrix); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if matrix["svc-a"]["INFO"] != 1 || matrix["svc-a"]["ERROR"] != 1 || matrix["svc-b"]["INFO"] != 1 {
+		t.Errorf("unexpected matrix: %v", matrix)
+	}
+	if _, ok := matrix["svc-b"]["ERROR"]; ok {
+		t.Errorf("expected no ERROR entry for svc-b, got %v", matrix["svc-b"])
+	}
+}
+
+func TestQueryMetrics(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"default params", "/api/metrics", http.StatusOK},
+		{"with name filter", "/api/metrics?name=cpu_usage", http.StatusOK},
+		{"with type filter", "/api/metrics?type=gauge", http.StatusOK},
+		{"with service filter", "/api/metrics?service=test-service", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			h.QueryMetrics(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestListMetricNames(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/names", nil)
+	rec := httptest.NewRecorder()
+
+	h.ListMetricNames(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestQueryMetricSeries(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"missing name parameter", "/api/metrics/series", http.StatusBadRequest},
+		{"with name parameter", "/api/metrics/series?name=cpu_usage", http.StatusOK},
+		{"with all params", "/api/metrics/series?name=cpu_usage&service=test&interval=60&aggregate=true", http.StatusOK},
+		{"with time range", "/api/metrics/series?name=cpu_usage&from=2024-01-01T00:00:00Z&to=2024-12-31T23:59:59Z", http.StatusOK},
+		{"with quantile", "/api/metrics/series?name=cpu_usage&quantile=0.99", http.StatusOK},
+		{"quantile out of range", "/api/metrics/series?name=cpu_usage&quantile=1.5", http.StatusBadRequest},
+		{"invalid quantile", "/api/metrics/series?name=cpu_usage&quantile=p99", http.StatusBadRequest},
+		{"with groupBy", "/api/metrics/series?name=cpu_usage&groupBy=service,model&maxSeries=10", http.StatusOK},
+		{"too many groupBy keys", "/api/metrics/series?name=cpu_usage&groupBy=a,b,c,d,e", http.StatusBadRequest},
+		{"groupBy with quantile", "/api/metrics/series?name=cpu_usage&groupBy=model&quantile=0.5", http.StatusBadRequest},
+		{"maxSeries out of range", "/api/metrics/series?name=cpu_usage&groupBy=model&maxSeries=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
 			rec := httptest.NewRecorder()
 
 			h.QueryMetricSeries(rec, req)
@@ -314,6 +1044,54 @@ func TestQueryMetricSeries(t *testing.T) {
 	}
 }
 
+func TestQueryMetricSeries_GroupBy(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	point := func(service, model string, value float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: now, ServiceName: service, MetricName: "claude_code.token.usage", MetricType: "sum", Value: &value,
+			Attributes: map[string]string{"model": model, "type": "input"},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		point("claude-code", "sonnet", 100),
+		point("claude-code", "opus", 50),
+		point("claude-code-ci", "sonnet", 25),
+		point("claude-code-ci", "sonnet", 5),
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	from := now.Add(-time.Hour).Format(time.RFC3339)
+	to := now.Add(time.Hour).Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/series?name=claude_code.token.usage&aggregate=true&groupBy=service,model&from="+from+"&to="+to, nil)
+	rec := httptest.NewRecorder()
+	h.QueryMetricSeries(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.TimeSeriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, s := range resp.Series {
+		if len(s.Labels) != 2 {
+			t.Errorf("expected service and model labels, got %v", s.Labels)
+		}
+		got[s.Labels["service"]+"/"+s.Labels["model"]] = s.DataPoints[0][1]
+	}
+	want := map[string]float64{"claude-code/sonnet": 100, "claude-code/opus": 50, "claude-code-ci/sonnet": 30}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("series = %v, want %v", got, want)
+	}
+}
+
 func TestQueryBatchMetricSeries(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -340,6 +1118,15 @@ func TestQueryBatchMetricSeries(t *testing.T) {
 			},
 			wantStatus: http.StatusOK,
 		},
+		{
+			name: "query quantile out of range",
+			body: map[string]interface{}{
+				"queries": []map[string]interface{}{
+					{"id": "q1", "name": "cpu_usage", "quantile": 2},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "query missing id",
 			body: map[string]interface{}{
@@ -434,6 +1221,53 @@ func TestHealth(t *testing.T) {
 	if resp["status"] != "ok" {
 		t.Errorf("expected status 'ok', got '%s'", resp["status"])
 	}
+	if resp["version"] != version.Version {
+		t.Errorf("expected version %q, got %q", version.Version, resp["version"])
+	}
+	if _, err := time.ParseDuration(resp["uptime"]); err != nil {
+		t.Errorf("expected a duration as uptime, got %q", resp["uptime"])
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/version", nil)
+	rec := httptest.NewRecorder()
+	h.GetVersion(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp api.VersionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := api.VersionResponse{Version: version.Version, GitCommit: version.GitCommit, BuildDate: version.BuildDate}
+	if resp != want {
+		t.Errorf("expected %+v, got %+v", want, resp)
+	}
+}
+
+func TestHealth_EnvLabel(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetEnvLabel("prod")
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+
+	h.Health(rec, req)
+
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp["env"] != "prod" {
+		t.Errorf("expected env 'prod', got '%s'", resp["env"])
+	}
 }
 
 func TestQueryRecentTraces(t *testing.T) {
@@ -530,6 +1364,47 @@ func TestParseTimeRange(t *testing.T) {
 	}
 }
 
+func TestGetMetricTable(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	value := 5.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: time.Now(), ServiceName: "svc", MetricName: "sessions", MetricType: "gauge", Value: &value, Attributes: map[string]string{"user": "u1"}},
+		{Timestamp: time.Now(), ServiceName: "svc", MetricName: "sessions", MetricType: "gauge", Value: &value, Attributes: map[string]string{"model": "m1"}},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/table", nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricTable(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without name, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/metrics/table?name=sessions", nil)
+	rec = httptest.NewRecorder()
+	h.GetMetricTable(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.MetricTableResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Rows) != 2 {
+		t.Errorf("expected 2 rows, got %d", len(resp.Rows))
+	}
+	if len(resp.Columns) != 2 || resp.Columns[0] != "model" || resp.Columns[1] != "user" {
+		t.Errorf("expected columns [model user], got %v", resp.Columns)
+	}
+}
+
 func TestGetBreakdownValues(t *testing.T) {
 	h, cleanup := setupTestHandlers(t)
 	defer cleanup()
@@ -558,3 +1433,1727 @@ func TestGetBreakdownValues(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSpanKinds(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	// Ingest via OTLP so numeric span kinds are mapped to their names
+	body, _ := json.Marshal(createTracesPayload())
+	req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.HandleTraces(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("failed to ingest traces: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/traces/kinds?service=test-service&from=2020-12-31T00:00:00Z&to=2021-01-02T00:00:00Z", nil)
+	rec = httptest.NewRecorder()
+	h.GetSpanKinds(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var kinds map[string]int64
+	if err := json.NewDecoder(rec.Body).Decode(&kinds); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(kinds) != 1 || kinds["SERVER"] != 1 {
+		t.Errorf("expected one SERVER span, got %v", kinds)
+	}
+}
+
+func TestListServices_Groups(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	var logs []api.LogRecord
+	for _, service := range []string{"claude-code", "claude-desktop", "codex_cli_rs", "gemini-cli"} {
+		logs = append(logs, api.LogRecord{Timestamp: now, ServiceName: service, Body: "hello"})
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	list := func() api.ServicesResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/services", nil)
+		rec := httptest.NewRecorder()
+		h.ListServices(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp api.ServicesResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := list(); resp.Groups != nil {
+		t.Errorf("expected no groups without configuration, got %v", resp.Groups)
+	}
+
+	h.SetServiceGroups([]string{"claude-*=claude", "codex*=openai"})
+	resp := list()
+	want := map[string]string{"claude-code": "claude", "claude-desktop": "claude", "codex_cli_rs": "openai"}
+	if len(resp.Services) != 4 {
+		t.Errorf("expected 4 services, got %v", resp.Services)
+	}
+	if !reflect.DeepEqual(resp.Groups, want) {
+		t.Errorf("Groups = %v, want %v", resp.Groups, want)
+	}
+}
+
+func TestServiceGroups_Group(t *testing.T) {
+	g := NewServiceGroups([]string{"claude-*=claude", "claude-code=ignored", "malformed", "[=broken"})
+
+	tests := []struct {
+		service string
+		want    string
+	}{
+		{"claude-code", "claude"},
+		{"claude-desktop", "claude"},
+		{"gemini-cli", ""},
+	}
+	for _, tt := range tests {
+		if got := g.Group(tt.service); got != tt.want {
+			t.Errorf("Group(%q) = %q, want %q", tt.service, got, tt.want)
+		}
+	}
+}
+
+func TestGetBreakdownValues_ModelAliases(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetModelAliases([]string{"claude-sonnet-4-*=claude-sonnet-4"})
+
+	temporality := int32(1) // DELTA
+	cost := func(model string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: time.Now(), ServiceName: "claude-code", MetricName: "claude_code.cost.usage",
+			MetricType: "sum", Value: &v, AggregationTemporality: &temporality,
+			Attributes: map[string]string{"model": model},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		cost("claude-sonnet-4-20250514", 1.5),
+		cost("claude-sonnet-4-5", 2.0),
+		cost("claude-opus-4-1", 4.0),
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	// Both sonnet snapshots collapse into one canonical value, but stored data is left untouched
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/breakdown-values?name=claude_code.cost.usage&attribute=model", nil)
+	rec := httptest.NewRecorder()
+	h.GetBreakdownValues(rec, req)
+
+	var values api.BreakdownValuesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&values); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(values.Values) != 2 || values.Values[0] != "claude-opus-4-1" || values.Values[1] != "claude-sonnet-4" {
+		t.Errorf("expected canonical values [claude-opus-4-1 claude-sonnet-4], got %v", values.Values)
+	}
+
+	raw, err := h.store.GetBreakdownValues(context.Background(), "claude_code.cost.usage", "model", "")
+	if err != nil {
+		t.Fatalf("GetBreakdownValues failed: %v", err)
+	}
+	if len(raw) != 3 {
+		t.Errorf("expected 3 stored model values, got %v", raw)
+	}
+}
+
+func TestGetMetricBreakdown(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetModelAliases([]string{"claude-sonnet-4-*=claude-sonnet-4"})
+
+	temporality := int32(1) // DELTA
+	cost := func(model string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: time.Now(), ServiceName: "claude-code", MetricName: "claude_code.cost.usage",
+			MetricType: "sum", Value: &v, AggregationTemporality: &temporality,
+			Attributes: map[string]string{"model": model},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		cost("claude-sonnet-4-20250514", 1.5),
+		cost("claude-sonnet-4-5", 2.0),
+		cost("claude-opus-4-1", 4.0),
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	for _, path := range []string{"/api/metrics/breakdown?attribute=model", "/api/metrics/breakdown?name=claude_code.cost.usage"} {
+		rec := httptest.NewRecorder()
+		h.GetMetricBreakdown(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d", path, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/breakdown?name=claude_code.cost.usage&attribute=model", nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricBreakdown(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.BreakdownResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// Totals per model, largest first, with the sonnet snapshots in one aliased bucket
+	if len(resp.Buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %+v", resp.Buckets)
+	}
+	if resp.Buckets[0].Value != "claude-opus-4-1" || resp.Buckets[0].Total != 4.0 {
+		t.Errorf("unexpected first bucket: %+v", resp.Buckets[0])
+	}
+	sonnet := resp.Buckets[1]
+	if sonnet.Value != "claude-sonnet-4" || sonnet.Total != 3.5 {
+		t.Errorf("expected claude-sonnet-4 bucket with total 3.5, got %+v", sonnet)
+	}
+	if len(sonnet.RawValues) != 2 || sonnet.RawValues[0] != "claude-sonnet-4-20250514" || sonnet.RawValues[1] != "claude-sonnet-4-5" {
+		t.Errorf("expected both raw model names in bucket, got %v", sonnet.RawValues)
+	}
+}
+
+func TestModelAliases_Canonical(t *testing.T) {
+	a := NewModelAliases([]string{"claude-sonnet-4-*=claude-sonnet-4", "gpt-5=gpt-5-latest", "malformed", "[=broken"})
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-sonnet-4-20250514", "claude-sonnet-4"},
+		{"claude-sonnet-4-5", "claude-sonnet-4"},
+		{"gpt-5", "gpt-5-latest"},
+		{"claude-opus-4-1", "claude-opus-4-1"},
+	}
+	for _, tt := range tests {
+		if got := a.Canonical(tt.model); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestGetTraceSession(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	spans := []api.Span{{
+		TraceID: "trace-123", SpanID: "span-1", ServiceName: "claude-code", SpanName: "claude.request",
+		Timestamp: time.Now(), Duration: 100000000, SpanAttributes: map[string]string{"session.id": "sess-1"},
+	}}
+	if err := h.store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+	logs := []api.LogRecord{
+		{Timestamp: time.Now(), ServiceName: "claude-code", Body: "prompt", LogAttributes: map[string]string{"session.id": "sess-1"}},
+		{Timestamp: time.Now(), ServiceName: "claude-code", Body: "other", LogAttributes: map[string]string{"session.id": "sess-2"}},
+	}
+	if err := h.store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	request := func(traceID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/traces/"+traceID+"/session", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("traceId", traceID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.GetTraceSession(rec, req)
+		return rec
+	}
+
+	rec := request("trace-123")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.TraceSessionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.SessionID != "sess-1" || resp.Method != "attribute" {
+		t.Errorf("expected sess-1 via attribute, got %q via %q", resp.SessionID, resp.Method)
+	}
+	if len(resp.Logs) != 1 || resp.Logs[0].Body != "prompt" {
+		t.Errorf("expected the session's single log, got %+v", resp.Logs)
+	}
+
+	if rec := request("nonexistent"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown trace, got %d", rec.Code)
+	}
+}
+
+func TestGetServiceSummary(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: time.Now(), Duration: int64(time.Millisecond), StatusCode: "ERROR"},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "svc", SpanName: "op", Timestamp: time.Now(), Duration: int64(time.Millisecond), StatusCode: "OK"},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/services/svc/summary", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("name", "svc")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	h.GetServiceSummary(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var summary api.ServiceSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if summary.Service != "svc" || summary.SpanCount != 2 || summary.TraceCount != 1 || summary.ErrorRate != 50 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if len(summary.TopOperations) != 1 || summary.TopOperations[0].Name != "op" {
+		t.Errorf("unexpected top operations: %+v", summary.TopOperations)
+	}
+}
+
+func TestGetServiceLatency(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	// Empty database
+	req := httptest.NewRequest(http.MethodGet, "/api/services/latency", nil)
+	rec := httptest.NewRecorder()
+	h.GetServiceLatency(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"services":[]`) {
+		t.Fatalf("expected 200 with an empty service list, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "fast", SpanName: "op", Timestamp: time.Now(), Duration: int64(time.Millisecond)},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "slow", SpanName: "op", Timestamp: time.Now(), Duration: int64(time.Second)},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetServiceLatency(rec, httptest.NewRequest(http.MethodGet, "/api/services/latency", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.ServiceLatencyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Services) != 2 || resp.Services[0].Service != "slow" || resp.Services[0].Max != int64(time.Second) {
+		t.Errorf("expected the slow service first, got %+v", resp.Services)
+	}
+}
+
+func TestGetServiceGraph(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "frontend", SpanName: "op", Timestamp: now, ResourceAttributes: map[string]string{"deployment.environment": "prod"}},
+		{TraceID: "t1", SpanID: "s2", ParentSpanID: "s1", ServiceName: "backend", SpanName: "op", Timestamp: now, ResourceAttributes: map[string]string{"deployment.environment": "dev"}},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetServiceGraph(rec, httptest.NewRequest(http.MethodGet, "/api/services/graph?groupBy=deployment.environment", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.ServiceGraphResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	groups := map[string]string{}
+	for _, n := range resp.Nodes {
+		groups[n.Service] = n.Group
+	}
+	if groups["frontend"] != "prod" || groups["backend"] != "dev" {
+		t.Errorf("expected nodes grouped by environment, got %+v", resp.Nodes)
+	}
+	if len(resp.Edges) != 1 || resp.Edges[0].Source != "frontend" || resp.Edges[0].Target != "backend" {
+		t.Errorf("expected a frontend -> backend edge, got %+v", resp.Edges)
+	}
+}
+
+func TestQueryErrorRateSeries(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: now, StatusCode: "ERROR"},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "svc", SpanName: "op", Timestamp: now, StatusCode: "OK"},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	from := now.Add(-time.Hour).UTC().Format(time.RFC3339)
+	to := now.Add(time.Minute).UTC().Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/traces/error-rate-series?interval=3600&service=svc&from="+from+"&to="+to, nil)
+	rec := httptest.NewRecorder()
+	h.QueryErrorRateSeries(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.TimeSeriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Series) != 1 || resp.Series[0].Labels["service"] != "svc" {
+		t.Fatalf("expected one svc series, got %+v", resp.Series)
+	}
+	var max float64
+	for _, point := range resp.Series[0].DataPoints {
+		if point[1] > max {
+			max = point[1]
+		}
+	}
+	if max != 0.5 {
+		t.Errorf("expected an error rate of 0.5 in the bucket with spans, got %v", resp.Series[0].DataPoints)
+	}
+}
+
+func TestGetLatencyCost(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetCostCurrency("EUR", 0.5)
+
+	ctx := context.Background()
+	now := time.Now()
+	span := api.Span{
+		TraceID: "t1", SpanID: "s1", ServiceName: "claude-code", SpanName: "turn", Timestamp: now.Add(-time.Minute),
+		Duration: int64(3 * time.Second), SpanAttributes: map[string]string{"session.id": "sess-1"},
+	}
+	if err := h.store.InsertSpans(ctx, []api.Span{span}); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+	cost := 2.0
+	metric := api.MetricDataPoint{
+		Timestamp: now.Add(-30 * time.Second), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum",
+		Attributes: map[string]string{"session.id": "sess-1"}, Value: &cost,
+	}
+	if err := h.store.InsertMetrics(ctx, []api.MetricDataPoint{metric}); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetLatencyCost(rec, httptest.NewRequest(http.MethodGet, "/api/analytics/latency-cost", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.LatencyCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Points) != 1 {
+		t.Fatalf("expected 1 point, got %+v", resp.Points)
+	}
+	p := resp.Points[0]
+	if p.TraceID != "t1" || p.Duration != int64(3*time.Second) || p.CostUSD != 2 || p.Cost == nil || *p.Cost != 1 {
+		t.Errorf("unexpected point: %+v", p)
+	}
+	if resp.Currency == nil || resp.Currency.Code != "EUR" {
+		t.Errorf("expected the display currency, got %+v", resp.Currency)
+	}
+}
+
+func TestGetPromptCost(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetCostCurrency("EUR", 0.5)
+
+	ctx := context.Background()
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-3 * time.Minute), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"event.name": "user_prompt", "session.id": "sess-1", "prompt": "Run test 1"}},
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"event.name": "user_prompt", "session.id": "sess-1", "prompt": "Run test 2"}},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"event.name": "user_prompt", "session.id": "sess-1", "prompt": "Summarize"}},
+	}
+	if err := h.store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+	var metrics []api.MetricDataPoint
+	for i, offset := range []time.Duration{170, 110, 50} {
+		cost := float64(i + 1)
+		metrics = append(metrics, api.MetricDataPoint{
+			Timestamp: now.Add(-offset * time.Second), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum",
+			Attributes: map[string]string{"session.id": "sess-1"}, Value: &cost,
+		})
+	}
+	if err := h.store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetPromptCost(rec, httptest.NewRequest(http.MethodGet, "/api/prompts/cost?limit=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.PromptCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Prompts) != 1 {
+		t.Fatalf("expected the most expensive pattern only, got %+v", resp.Prompts)
+	}
+	p := resp.Prompts[0]
+	if p.Pattern != "run test <n>" || p.Count != 2 || p.CostUSD != 3 || p.Cost == nil || *p.Cost != 1.5 {
+		t.Errorf("unexpected prompt cost: %+v", p)
+	}
+	if resp.Currency == nil || resp.Currency.Code != "EUR" {
+		t.Errorf("expected the display currency, got %+v", resp.Currency)
+	}
+}
+
+func TestGetSessionCost(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetCostCurrency("EUR", 0.5)
+
+	now := time.Now()
+	tokens, cost := 1000.0, 2.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.token.usage", MetricType: "sum", Value: &tokens, Attributes: map[string]string{"session.id": "sess-1", "model": "sonnet", "type": "input"}},
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Value: &cost, Attributes: map[string]string{"session.id": "sess-1", "model": "sonnet"}},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	get := func(sessionID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions/"+sessionID+"/cost", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("sessionId", sessionID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		h.GetSessionCost(rec, req)
+		return rec
+	}
+
+	rec := get("sess-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.SessionCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total.InputTokens != 1000 || resp.Total.CostUSD != 2 || resp.Total.Cost == nil || *resp.Total.Cost != 1 {
+		t.Errorf("unexpected total: %+v", resp.Total)
+	}
+	if len(resp.Models) != 1 || resp.Models[0].Model != "sonnet" || resp.Models[0].Cost == nil {
+		t.Errorf("unexpected models: %+v", resp.Models)
+	}
+
+	if rec := get("unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown session, got %d", rec.Code)
+	}
+}
+
+func TestGetCacheHitRatio(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	input, cacheRead := 25.0, 75.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.token.usage", MetricType: "sum", Value: &input, Attributes: map[string]string{"type": "input", "model": "sonnet"}},
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.token.usage", MetricType: "sum", Value: &cacheRead, Attributes: map[string]string{"type": "cacheRead", "model": "sonnet"}},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/cache-hit-ratio?service=claude-code&model=sonnet&interval=86400", nil)
+	rec := httptest.NewRecorder()
+	h.GetCacheHitRatio(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.TimeSeriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Series) != 1 || len(resp.Series[0].DataPoints) != 1 || resp.Series[0].DataPoints[0][1] != 0.75 {
+		t.Errorf("expected a single 0.75 ratio, got %+v", resp.Series)
+	}
+}
+
+func TestGetEfficiency(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	tokens, cost := 5000.0, 2.5
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.token.usage", MetricType: "sum", Value: &tokens, Attributes: map[string]string{"type": "input", "model": "sonnet"}},
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Value: &cost, Attributes: map[string]string{"model": "sonnet"}},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/analytics/efficiency?service=claude-code&interval=86400", nil)
+	rec := httptest.NewRecorder()
+	h.GetEfficiency(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.TimeSeriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Series) != 1 || len(resp.Series[0].DataPoints) != 1 || resp.Series[0].DataPoints[0][1] != 2000 {
+		t.Errorf("expected 2000 tokens per dollar, got %+v", resp.Series)
+	}
+}
+
+func TestGetMetricDelta(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	cumulative := int32(2)
+	before, after := 1.25, 3.75
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-2 * time.Hour), ServiceName: "svc", MetricName: "cost", MetricType: "sum", AggregationTemporality: &cumulative, Value: &before},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc", MetricName: "cost", MetricType: "sum", AggregationTemporality: &cumulative, Value: &after},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	from := now.Add(-time.Hour).Format(time.RFC3339)
+	to := now.Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/delta?name=cost&service=svc&from="+from+"&to="+to, nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricDelta(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.MetricDeltaResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Name != "cost" || resp.Delta != 2.5 {
+		t.Errorf("unexpected delta response: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetMetricDelta(rec, httptest.NewRequest(http.MethodGet, "/api/metrics/delta", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without name, got %d", rec.Code)
+	}
+}
+
+func TestGetMetricHistogram(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	delta := int32(1)
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc", MetricName: "latency", MetricType: "histogram",
+			AggregationTemporality: &delta, ExplicitBounds: []float64{0.5}, BucketCounts: []uint64{3, 4}},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	from := now.Add(-time.Hour).Format(time.RFC3339)
+	to := now.Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/metrics/histogram?name=latency&from="+from+"&to="+to, nil)
+	rec := httptest.NewRecorder()
+	h.GetMetricHistogram(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `{"upperBound":null,"count":4}`) {
+		t.Errorf("expected a null bound for the overflow bucket, got %s", rec.Body.String())
+	}
+	var resp api.MetricHistogramResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Name != "latency" || len(resp.Buckets) != 2 || *resp.Buckets[0].UpperBound != 0.5 || resp.Buckets[0].Count != 3 {
+		t.Errorf("unexpected histogram response: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetMetricHistogram(rec, httptest.NewRequest(http.MethodGet, "/api/metrics/histogram", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without name, got %d", rec.Code)
+	}
+}
+
+func TestGetOverview(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetEnvLabel("test")
+
+	ctx := context.Background()
+	now := time.Now()
+	spans := []api.Span{{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: now, StatusCode: "ERROR"}}
+	if err := h.store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+	if err := h.store.InsertLogs(ctx, []api.LogRecord{{Timestamp: now, ServiceName: "svc", SeverityText: "WARN", Body: "w"}}); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+	delta, cost := int32(1), 0.25
+	metrics := []api.MetricDataPoint{{
+		Timestamp: now, ServiceName: "svc", MetricName: "claude_code.cost.usage", MetricType: "sum",
+		AggregationTemporality: &delta, Value: &cost, Attributes: map[string]string{"model": "sonnet"},
+	}}
+	if err := h.store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	since := now.Add(-time.Hour).UTC().Format(time.RFC3339)
+	rec := httptest.NewRecorder()
+	h.GetOverview(rec, httptest.NewRequest(http.MethodGet, "/api/overview?since="+since, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var overview api.OverviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&overview); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if overview.Stats == nil || overview.Stats.SpanCount != 1 || overview.Stats.Env != "test" {
+		t.Errorf("unexpected stats section: %+v", overview.Stats)
+	}
+	if overview.RecentErrorCount != 1 {
+		t.Errorf("RecentErrorCount = %d, want 1", overview.RecentErrorCount)
+	}
+	if len(overview.TopModels) != 1 || overview.TopModels[0].Model != "sonnet" || overview.TopModels[0].CostUSD != 0.25 {
+		t.Errorf("unexpected top models: %+v", overview.TopModels)
+	}
+	if overview.LogLevels["WARN"] != 1 {
+		t.Errorf("unexpected log levels: %v", overview.LogLevels)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetOverview(rec, httptest.NewRequest(http.MethodGet, "/api/overview?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid since, got %d", rec.Code)
+	}
+}
+
+func TestGetDroppedCounts(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: time.Now(), DroppedAttributesCount: 4, DroppedEventsCount: 1},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "svc", SpanName: "op", Timestamp: time.Now()},
+	}
+	if err := h.store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("failed to insert spans: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/dropped", nil)
+	rec := httptest.NewRecorder()
+	h.GetDroppedCounts(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.DroppedDataResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Services) != 1 {
+		t.Fatalf("expected 1 service, got %+v", resp.Services)
+	}
+	got := resp.Services[0]
+	if got.ServiceName != "svc" || got.SpansWithDrops != 1 || got.SpanDroppedAttributes != 4 || got.SpanDroppedEvents != 1 {
+		t.Errorf("unexpected dropped counts: %+v", got)
+	}
+}
+
+func TestGetCorrelationGaps(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	logs := []api.LogRecord{{Timestamp: time.Now(), ServiceName: "svc", TraceID: "missing-trace", Body: "orphan"}}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/correlation/gaps", nil)
+	rec := httptest.NewRecorder()
+	h.GetCorrelationGaps(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var report api.CorrelationReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if report.OrphanedLogs != 1 {
+		t.Errorf("expected 1 orphaned log, got %d", report.OrphanedLogs)
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	value := 42.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc", MetricName: "cpu_usage", MetricType: "gauge", MetricUnit: "%", Value: &value},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	validate := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/metrics/validate", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		h.ValidateMetric(rec, req)
+		return rec
+	}
+
+	rec := validate(map[string]interface{}{"metricName": "cpu_usage", "service": "svc"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.ValidateMetricResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Exists || resp.MetricType != "gauge" || resp.Unit != "%" || resp.SampleCount != 1 {
+		t.Errorf("unexpected validation for seeded metric: %+v", resp)
+	}
+
+	rec = validate(map[string]interface{}{"metricName": "missing"})
+	resp = api.ValidateMetricResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Exists || resp.SampleCount != 0 {
+		t.Errorf("expected unknown metric to not exist, got %+v", resp)
+	}
+
+	if rec := validate(map[string]interface{}{"service": "svc"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without metricName, got %d", rec.Code)
+	}
+}
+
+func TestGetCostByProject(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	api1, web := 1.25, 0.5
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Attributes: map[string]string{"project": "/src/api"}, Value: &api1},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "codex_cli_rs", MetricName: "codex_cli_rs.cost.usage", MetricType: "sum", Attributes: map[string]string{"project": "/src/web"}, Value: &web},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetCostByProject(rec, httptest.NewRequest(http.MethodGet, "/api/cost/by-project", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.ProjectCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Projects) != 2 || resp.Projects[0].Project != "/src/api" || resp.Projects[1].CostUSD != 0.5 {
+		t.Errorf("unexpected projects: %+v", resp.Projects)
+	}
+	if resp.Currency != nil || resp.Projects[0].Cost != nil {
+		t.Errorf("expected USD only without a configured currency, got %+v", resp)
+	}
+}
+
+func TestGetCostByModel(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetModelAliases([]string{"claude-sonnet-4-*=claude-sonnet-4"})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sonnet, sonnetDated, gpt := 0.5, 1.0, 1.25
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Attributes: map[string]string{"model": "claude-sonnet-4"}, Value: &sonnet},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Attributes: map[string]string{"model": "claude-sonnet-4-20250514"}, Value: &sonnetDated},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "codex_cli_rs", MetricName: "codex_cli_rs.cost.usage", MetricType: "sum", Attributes: map[string]string{"model": "gpt-4o"}, Value: &gpt},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetCostByModel(rec, httptest.NewRequest(http.MethodGet, "/api/cost/by-model", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.ModelCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Models) != 2 || resp.Models[0].Model != "claude-sonnet-4" || resp.Models[0].CostUSD != 1.5 || resp.Models[1].Model != "gpt-4o" {
+		t.Errorf("expected aliased sonnet costs merged ahead of gpt-4o, got %+v", resp.Models)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetCostByModel(rec, httptest.NewRequest(http.MethodGet, "/api/cost/by-model?service=codex_cli_rs", nil))
+	resp = api.ModelCostResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Models) != 1 || resp.Models[0].Model != "gpt-4o" {
+		t.Errorf("expected only gpt-4o for codex_cli_rs, got %+v", resp.Models)
+	}
+}
+
+func TestGetAttributeCardinality(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", Body: "a", LogAttributes: map[string]string{"session.id": "s1", "event.name": "api_request"}},
+		{Timestamp: now, ServiceName: "svc", Body: "b", LogAttributes: map[string]string{"session.id": "s2", "event.name": "api_request"}},
+		{Timestamp: now, ServiceName: "svc", Body: "c", LogAttributes: map[string]string{"session.id": "s3", "event.name": "tool_result"}},
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetAttributeCardinality(rec, httptest.NewRequest(http.MethodGet, "/api/attributes/cardinality?signal=logs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.AttrCardinalityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Signal != "logs" || len(resp.Attributes) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if top := resp.Attributes[0]; top.Key != "session.id" || top.DistinctValues != 3 {
+		t.Errorf("expected session.id with 3 distinct values first, got %+v", top)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetAttributeCardinality(rec, httptest.NewRequest(http.MethodGet, "/api/attributes/cardinality?signal=profiles", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for unknown signal, got %d", rec.Code)
+	}
+}
+
+func TestCostCurrency(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetCostCurrency("eur", 0.9)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	cost := 2.5
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Attributes: map[string]string{"project": "/src/api", "model": "claude-sonnet-4"}, Value: &cost},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetCostByProject(rec, httptest.NewRequest(http.MethodGet, "/api/cost/by-project", nil))
+	var projects api.ProjectCostResponse
+	if err := json.NewDecoder(rec.Body).Decode(&projects); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if projects.Currency == nil || projects.Currency.Code != "EUR" || projects.Currency.Rate != 0.9 {
+		t.Errorf("expected EUR at rate 0.9, got %+v", projects.Currency)
+	}
+	if len(projects.Projects) != 1 || projects.Projects[0].CostUSD != 2.5 || projects.Projects[0].Cost == nil || *projects.Projects[0].Cost != 2.25 {
+		t.Errorf("expected 2.5 USD converted to 2.25 EUR, got %+v", projects.Projects)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetOverview(rec, httptest.NewRequest(http.MethodGet, "/api/overview", nil))
+	var overview api.OverviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&overview); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if overview.TodayCost == nil || *overview.TodayCost != overview.TodayCostUSD*0.9 {
+		t.Errorf("expected today's cost converted at 0.9, got %v for %v USD", overview.TodayCost, overview.TodayCostUSD)
+	}
+	if len(overview.TopModels) != 1 || overview.TopModels[0].Cost == nil || *overview.TopModels[0].Cost != 2.25 {
+		t.Errorf("expected top model cost of 2.25 EUR, got %+v", overview.TopModels)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/services/claude-code/summary", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("name", "claude-code")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	h.GetServiceSummary(rec, req)
+	var summary api.ServiceSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if summary.Cost == nil || *summary.Cost != 2.25 || summary.Currency == nil {
+		t.Errorf("expected service cost of 2.25 EUR, got %+v (%v)", summary.Cost, summary.Currency)
+	}
+}
+
+func TestNewCostCurrency(t *testing.T) {
+	for _, tt := range []struct {
+		code    string
+		rate    float64
+		enabled bool
+	}{
+		{"", 1, false},
+		{"usd", 1, false},
+		{"EUR", 0, false},
+		{"JPY", 150, true},
+	} {
+		c := NewCostCurrency(tt.code, tt.rate)
+		if (c.currency != nil) != tt.enabled {
+			t.Errorf("NewCostCurrency(%q, %v) enabled = %v, want %v", tt.code, tt.rate, c.currency != nil, tt.enabled)
+		}
+	}
+}
+
+func TestQueryLogs_ParquetFormat(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	logs := []api.LogRecord{
+		{Timestamp: time.Now().Add(-time.Minute), ServiceName: "svc", SeverityText: "INFO", Body: "hello"},
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.QueryLogs(rec, httptest.NewRequest(http.MethodGet, "/api/logs?format=parquet&service=svc", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/vnd.apache.parquet" {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "logs-") || !strings.Contains(cd, ".parquet") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if body := rec.Body.Bytes(); !bytes.HasPrefix(body, []byte("PAR1")) || !bytes.HasSuffix(body, []byte("PAR1")) {
+		t.Errorf("response is not a Parquet file (%d bytes)", len(body))
+	}
+
+	rec = httptest.NewRecorder()
+	h.QueryMetrics(rec, httptest.NewRequest(http.MethodGet, "/api/metrics?format=csv", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
+func TestQueryLogs_ParquetGzip(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "svc", SeverityText: "INFO", Body: "first"},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc", SeverityText: "INFO", Body: "second"},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "other", SeverityText: "INFO", Body: "skipped"},
+	}
+	if err := h.store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/logs?format=parquet&service=svc", nil)
+	req.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	rec := httptest.NewRecorder()
+	h.QueryLogs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("expected gzip Content-Encoding, got %q", ce)
+	}
+	if !rec.Flushed {
+		t.Error("expected the export to be flushed while streaming")
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("response is not gzip: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress response: %v", err)
+	}
+
+	// Load the decompressed file into a fresh store to check its rows
+	target, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer target.Close()
+	n, err := target.ImportParquet(ctx, "otel_logs", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decompressed response is not a valid Parquet file: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+	imported, err := target.QueryLogs(ctx, "", "", storage.SeverityRange{}, "", "", now.Add(-time.Hour), now, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to query imported logs: %v", err)
+	}
+	var bodies []string
+	for _, l := range imported.Logs {
+		bodies = append(bodies, l.Body)
+	}
+	if !reflect.DeepEqual(bodies, []string{"second", "first"}) {
+		t.Errorf("unexpected rows %v", bodies)
+	}
+
+	// gzip;q=0 refuses gzip, so the file is sent as is
+	req = httptest.NewRequest(http.MethodGet, "/api/logs?format=parquet&service=svc", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0")
+	rec = httptest.NewRecorder()
+	h.QueryLogs(rec, req)
+	if ce := rec.Header().Get("Content-Encoding"); ce != "" || !bytes.HasPrefix(rec.Body.Bytes(), []byte("PAR1")) {
+		t.Errorf("expected an uncompressed Parquet file, got Content-Encoding %q", ce)
+	}
+}
+
+func TestQuerySessions_Status(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetSessionIdleTimeout(30 * time.Minute)
+
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-3 * time.Hour), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"session.id": "old"}},
+		{Timestamp: now.Add(-2 * time.Hour), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"session.id": "old"}},
+		{Timestamp: now.Add(-time.Hour), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"session.id": "recent"}},
+		{Timestamp: now.Add(-5 * time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"session.id": "recent"}},
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.QuerySessions(rec, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.SessionsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, s := range resp.Sessions {
+		statuses[s.SessionID] = s.Status
+	}
+	if statuses["recent"] != api.SessionActive || statuses["old"] != api.SessionCompleted {
+		t.Errorf("unexpected session statuses: %v", statuses)
+	}
+}
+
+func TestQuerySessions_Preview(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	logs := []api.LogRecord{{
+		Timestamp:     time.Now(),
+		ServiceName:   "claude-code",
+		Body:          "user_prompt",
+		LogAttributes: map[string]string{"session.id": "s1", "event.name": "user_prompt", "prompt": "Refactor the parser"},
+	}}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	for query, want := range map[string]string{"": "", "?preview=true": "Refactor the parser"} {
+		rec := httptest.NewRecorder()
+		h.QuerySessions(rec, httptest.NewRequest(http.MethodGet, "/api/sessions"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected status 200, got %d", query, rec.Code)
+		}
+		var resp api.SessionsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Sessions) != 1 || resp.Sessions[0].Preview != want {
+			t.Errorf("%q: expected preview %q, got %+v", query, want, resp.Sessions)
+		}
+	}
+}
+
+func TestGetSessionActivity(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"session.id": "s1"}},
+		{Timestamp: now, ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"session.id": "s1"}},
+		{Timestamp: now, ServiceName: "codex", Body: "user_message", LogAttributes: map[string]string{"conversation.id": "c1"}},
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.GetSessionActivity(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/activity", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp api.TimeSeriesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	got := map[string]float64{}
+	for _, series := range resp.Series {
+		if len(series.DataPoints) != 1 {
+			t.Fatalf("expected one daily bucket in %s, got %v", series.Name, series.DataPoints)
+		}
+		got[series.Name] = series.DataPoints[0][1]
+	}
+	if got["active_sessions"] != 2 || got["messages"] != 3 {
+		t.Errorf("expected 2 sessions with 3 messages, got %v", got)
+	}
+}
+
+// concurrencyTracker wraps a handler and records the most requests it ran at once
+type concurrencyTracker struct {
+	active, max atomic.Int32
+}
+
+func (c *concurrencyTracker) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := c.active.Add(1)
+		for {
+			m := c.max.Load()
+			if n <= m || c.max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		defer c.active.Add(-1)
+		next(w, r)
+	}
+}
+
+func TestQueryLimitMiddleware_RejectsExcess(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetMaxConcurrentQueries(2, 50*time.Millisecond)
+
+	release := make(chan struct{})
+	entered := make(chan struct{}, 6)
+	var tracker concurrencyTracker
+	handler := h.QueryLimitMiddleware(tracker.wrap(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const requests = 6
+	recs := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		}(recs[i])
+	}
+	// Hold the two admitted queries until the others gave up waiting
+	<-entered
+	<-entered
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	var ok, rejected int
+	for _, rec := range recs {
+		switch rec.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusServiceUnavailable:
+			rejected++
+			if got := rec.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want 1", got)
+			}
+		default:
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+	}
+	if ok != 2 || rejected != requests-2 {
+		t.Errorf("expected 2 queries served and %d rejected, got %d and %d", requests-2, ok, rejected)
+	}
+	if max := tracker.max.Load(); max != 2 {
+		t.Errorf("expected at most 2 concurrent queries, got %d", max)
+	}
+}
+
+func TestQueryLimitMiddleware_QueuesQueries(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+	h.SetMaxConcurrentQueries(2, time.Minute)
+
+	now := time.Now()
+	logs := make([]api.LogRecord, 5)
+	for i := range logs {
+		logs[i] = api.LogRecord{Timestamp: now.Add(-time.Duration(i) * time.Second), ServiceName: "svc", Body: fmt.Sprintf("log %d", i)}
+	}
+	if err := h.store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("failed to insert logs: %v", err)
+	}
+
+	var tracker concurrencyTracker
+	handler := h.QueryLimitMiddleware(tracker.wrap(h.QueryLogs))
+
+	const requests = 20
+	recs := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/logs?service=svc", nil))
+		}(recs[i])
+	}
+	wg.Wait()
+
+	for i, rec := range recs {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected queued query to succeed, got %d", i, rec.Code)
+		}
+		var resp api.LogsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("request %d: failed to decode response: %v", i, err)
+		}
+		if len(resp.Logs) != len(logs) {
+			t.Errorf("request %d: expected %d logs, got %d", i, len(logs), len(resp.Logs))
+		}
+	}
+	if max := tracker.max.Load(); max > 2 {
+		t.Errorf("expected at most 2 concurrent queries, got %d", max)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	withParam := func(req *http.Request, key, value string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add(key, value)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	list := func(handler http.HandlerFunc, req *http.Request) []api.Annotation {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp api.AnnotationsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.Annotations
+	}
+
+	// Annotate a trace
+	body := `{"note":"tool call hung for 2 minutes","tags":["incident","starred"]}`
+	req := withParam(httptest.NewRequest(http.MethodPost, "/api/traces/trace-1/annotations", strings.NewReader(body)), "traceId", "trace-1")
+	rec := httptest.NewRecorder()
+	h.AnnotateTrace(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created api.Annotation
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode annotation: %v", err)
+	}
+	if created.TargetType != "trace" || created.TargetID != "trace-1" || created.Note != "tool call hung for 2 minutes" {
+		t.Errorf("unexpected annotation: %+v", created)
+	}
+
+	// Annotate a log
+	body = `{"serviceName":"claude-code","timestamp":"2026-01-02T15:04:05.5Z","note":"rate limited"}`
+	rec = httptest.NewRecorder()
+	h.AnnotateLog(rec, httptest.NewRequest(http.MethodPost, "/api/logs/annotations", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201 for the log annotation, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// List them for the trace, by tag and by type
+	traceAnnotations := list(h.GetTraceAnnotations, withParam(httptest.NewRequest(http.MethodGet, "/api/traces/trace-1/annotations", nil), "traceId", "trace-1"))
+	if len(traceAnnotations) != 1 || traceAnnotations[0].ID != created.ID {
+		t.Fatalf("expected the trace annotation, got %+v", traceAnnotations)
+	}
+	if got := traceAnnotations[0].Tags; !reflect.DeepEqual(got, []string{"incident", "starred"}) {
+		t.Errorf("tags = %v, want [incident starred]", got)
+	}
+	if starred := list(h.ListAnnotations, httptest.NewRequest(http.MethodGet, "/api/annotations?tag=starred", nil)); len(starred) != 1 {
+		t.Errorf("expected 1 starred annotation, got %d", len(starred))
+	}
+	logAnnotations := list(h.ListAnnotations, httptest.NewRequest(http.MethodGet, "/api/annotations?type=log", nil))
+	if len(logAnnotations) != 1 || logAnnotations[0].TargetID != "claude-code@2026-01-02T15:04:05.5Z" {
+		t.Errorf("unexpected log annotations: %+v", logAnnotations)
+	}
+
+	// Delete the trace annotation
+	del := func() int {
+		rec := httptest.NewRecorder()
+		h.DeleteAnnotation(rec, withParam(httptest.NewRequest(http.MethodDelete, "/api/annotations/"+created.ID, nil), "id", created.ID))
+		return rec.Code
+	}
+	if code := del(); code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", code)
+	}
+	if code := del(); code != http.StatusNotFound {
+		t.Errorf("expected status 404 for a deleted annotation, got %d", code)
+	}
+	if all := list(h.ListAnnotations, httptest.NewRequest(http.MethodGet, "/api/annotations", nil)); len(all) != 1 {
+		t.Errorf("expected only the log annotation to remain, got %d", len(all))
+	}
+}
+
+func TestAnnotations_Validation(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	rec := httptest.NewRecorder()
+	h.ListAnnotations(rec, httptest.NewRequest(http.MethodGet, "/api/annotations?type=metric", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid type: expected status 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.AnnotateLog(rec, httptest.NewRequest(http.MethodPost, "/api/logs/annotations", strings.NewReader(`{"note":"x"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("log without service and timestamp: expected status 400, got %d", rec.Code)
+	}
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("traceId", "trace-1")
+	body := fmt.Sprintf(`{"note":%q}`, strings.Repeat("x", maxAnnotationNoteLength+1))
+	req := httptest.NewRequest(http.MethodPost, "/api/traces/trace-1/annotations", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec = httptest.NewRecorder()
+	h.AnnotateTrace(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("long note: expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestAlertRules(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	withID := func(req *http.Request, id string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	// Create
+	body := `{"name":" Cost per hour ","metricName":"claude_code.cost.usage","comparison":">","threshold":5,"windowSeconds":3600}`
+	rec := httptest.NewRecorder()
+	h.CreateAlertRule(rec, httptest.NewRequest(http.MethodPost, "/api/alerts/rules", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rule api.AlertRule
+	if err := json.NewDecoder(rec.Body).Decode(&rule); err != nil {
+		t.Fatalf("failed to decode rule: %v", err)
+	}
+	if rule.ID == "" || rule.Name != "Cost per hour" || rule.Threshold != 5 {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	// Update
+	body = `{"name":"Cost per hour","metricName":"claude_code.cost.usage","comparison":">=","threshold":10,"windowSeconds":3600}`
+	rec = httptest.NewRecorder()
+	h.UpdateAlertRule(rec, withID(httptest.NewRequest(http.MethodPut, "/api/alerts/rules/"+rule.ID, strings.NewReader(body)), rule.ID))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"threshold":10`) {
+		t.Fatalf("expected the updated rule, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	h.UpdateAlertRule(rec, withID(httptest.NewRequest(http.MethodPut, "/api/alerts/rules/missing", strings.NewReader(body)), "missing"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("update of an unknown rule: expected status 404, got %d", rec.Code)
+	}
+
+	// List and get
+	rec = httptest.NewRecorder()
+	h.ListAlertRules(rec, httptest.NewRequest(http.MethodGet, "/api/alerts/rules", nil))
+	var rules api.AlertRulesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&rules); err != nil {
+		t.Fatalf("failed to decode rules: %v", err)
+	}
+	if len(rules.Rules) != 1 || rules.Rules[0].Comparison != ">=" {
+		t.Errorf("expected the updated rule, got %+v", rules.Rules)
+	}
+	rec = httptest.NewRecorder()
+	h.GetAlertRule(rec, withID(httptest.NewRequest(http.MethodGet, "/api/alerts/rules/missing", nil), "missing"))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown rule: expected status 404, got %d", rec.Code)
+	}
+
+	// Events outlive their rule
+	event := api.AlertEvent{RuleID: rule.ID, RuleName: rule.Name, MetricName: rule.MetricName, Comparison: ">=", Threshold: 10, WindowSeconds: 3600, Value: 12, FiredAt: time.Now()}
+	if err := h.store.InsertAlertEvent(context.Background(), &event); err != nil {
+		t.Fatalf("failed to insert alert event: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	h.DeleteAlertRule(rec, withID(httptest.NewRequest(http.MethodDelete, "/api/alerts/rules/"+rule.ID, nil), rule.ID))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ListAlertEvents(rec, httptest.NewRequest(http.MethodGet, "/api/alerts/events?ruleId="+rule.ID, nil))
+	var events api.AlertEventsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatalf("failed to decode events: %v", err)
+	}
+	if len(events.Events) != 1 || events.Events[0].Value != 12 {
+		t.Errorf("expected the stored event, got %+v", events.Events)
+	}
+}
+
+func TestAlertRules_Validation(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid body", `{`},
+		{"missing name", `{"metricName":"cost","comparison":">","threshold":5,"windowSeconds":60}`},
+		{"missing metric", `{"name":"x","comparison":">","threshold":5,"windowSeconds":60}`},
+		{"invalid comparison", `{"name":"x","metricName":"cost","comparison":"==","threshold":5,"windowSeconds":60}`},
+		{"missing window", `{"name":"x","metricName":"cost","comparison":">","threshold":5}`},
+		{"window too long", fmt.Sprintf(`{"name":"x","metricName":"cost","comparison":">","threshold":5,"windowSeconds":%d}`, maxAlertWindowSeconds+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.CreateAlertRule(rec, httptest.NewRequest(http.MethodPost, "/api/alerts/rules", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	cumulative := int32(2)
+	monotonic := true
+	value := func(v float64) *float64 { return &v }
+	metrics := []api.MetricDataPoint{
+		{
+			Timestamp: time.Now().Add(-time.Minute), ServiceName: "claude-code", MetricName: "claude_code.token.usage",
+			MetricDescription: "Number of tokens used", MetricUnit: "tokens", MetricType: "sum",
+			AggregationTemporality: &cumulative, IsMonotonic: &monotonic,
+			Attributes: map[string]string{"type": "input", "model": `claude "sonnet"`}, Value: value(1200),
+		},
+		{
+			Timestamp: time.Now(), ServiceName: "claude-code", MetricName: "claude_code.token.usage",
+			MetricDescription: "Number of tokens used", MetricUnit: "tokens", MetricType: "sum",
+			AggregationTemporality: &cumulative, IsMonotonic: &monotonic,
+			Attributes: map[string]string{"type": "input", "model": `claude "sonnet"`}, Value: value(1500),
+		},
+		{
+			Timestamp: time.Now(), ServiceName: "gemini-cli", MetricName: "gemini_cli.session.count",
+			MetricType: "sum", AggregationTemporality: &cumulative, IsMonotonic: &monotonic, Value: value(3),
+		},
+		{
+			Timestamp: time.Now(), ServiceName: "gemini-cli", MetricName: "gemini_cli.session.count.delta",
+			MetricType: "sum", IsMonotonic: &monotonic, Value: value(1),
+		},
+		{
+			Timestamp: time.Now(), ServiceName: "codex", MetricName: "1up.active", MetricType: "gauge", Value: value(0.5),
+		},
+	}
+	if err := h.store.InsertMetrics(context.Background(), metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.PrometheusMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	want := `# TYPE _1up_active gauge
+_1up_active{service_name="codex"} 0.5
+# HELP claude_code_token_usage_total Number of tokens used (unit: tokens)
+# TYPE claude_code_token_usage_total counter
+claude_code_token_usage_total{service_name="claude-code",model="claude \"sonnet\"",type="input"} 1500
+# TYPE gemini_cli_session_count_total counter
+gemini_cli_session_count_total{service_name="gemini-cli"} 3
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	h.PrometheusMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics?service=codex", nil))
+	if got := rec.Body.String(); got != "# TYPE _1up_active gauge\n_1up_active{service_name=\"codex\"} 0.5\n" {
+		t.Errorf("unexpected exposition for codex:\n%s", got)
+	}
+}
+
+func TestWritePrometheusLabels_Collisions(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writePrometheusLabels(w, storage.MetricSnapshot{
+		ServiceName: "svc",
+		Attributes:  map[string]string{"a.b": "1", "a_b": "2", "service.name": "other"},
+	})
+	w.Flush()
+
+	want := `{service_name="svc",a_b="1",a_b_2="2",service_name_2="other"}`
+	if got := buf.String(); got != want {
+		t.Errorf("labels = %s, want %s", got, want)
+	}
+}
+
+func TestPreviewDerivedMetrics(t *testing.T) {
+	h, cleanup := setupTestHandlers(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	point := func(ts time.Time, service, name, model, tokenType string, value float64) api.MetricDataPoint {
+		attrs := map[string]string{"model": model}
+		if tokenType != "" {
+			attrs["type"] = tokenType
+		}
+		return api.MetricDataPoint{Timestamp: ts, ServiceName: service, MetricName: name, MetricType: "sum", Value: &value, Attributes: attrs}
+	}
+	inRange := []api.MetricDataPoint{
+		// A user-facing call (with cache tokens) and a tool-routing call without
+		point(now.Add(-2*time.Minute), "claude-code", otlp.ClaudeTokenUsageMetric, "sonnet", "input", 100),
+		point(now.Add(-2*time.Minute), "claude-code", otlp.ClaudeTokenUsageMetric, "sonnet", "cacheRead", 400),
+		point(now.Add(-2*time.Minute), "claude-code", otlp.ClaudeCostMetric, "sonnet", "", 0.02),
+		point(now.Add(-time.Minute), "claude-code", otlp.ClaudeTokenUsageMetric, "haiku", "input", 50),
+		point(now.Add(-time.Minute), "gemini-cli", otlp.GeminiTokenUsageMetric, "gemini-2.5-flash", "input", 1000),
+		point(now.Add(-time.Minute), "claude-code", "claude_code.session.count", "sonnet", "", 1),
+	}
+	outOfRange := point(now.Add(-3*time.Hour), "claude-code", otlp.ClaudeTokenUsageMetric, "sonnet", "cacheRead", 400)
+	if err := h.store.InsertMetrics(context.Background(), append(inRange, outOfRange)); err != nil {
+		t.Fatalf("failed to insert metrics: %v", err)
+	}
+
+	from := now.Add(-time.Hour).Format(time.RFC3339)
+	to := now.Add(time.Hour).Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/derive/preview?from="+from+"&to="+to, nil)
+	rec := httptest.NewRecorder()
+	h.PreviewDerivedMetrics(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp api.DerivePreviewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	expected := otlp.DeriveMetrics(inRange)
+	sortDerivedMetrics(expected)
+	key := func(m api.MetricDataPoint) string {
+		return fmt.Sprintf("%s|%s|%s|%s|%s|%v", m.Timestamp.UTC().Format(time.RFC3339Nano), m.MetricName, m.ServiceName, m.Attributes["model"], m.Attributes["type"], *m.Value)
+	}
+	if len(resp.Metrics) != len(expected) {
+		t.Fatalf("expected %d derived metrics, got %d: %+v", len(expected), len(resp.Metrics), resp.Metrics)
+	}
+	for i := range expected {
+		if got, want := key(resp.Metrics[i]), key(expected[i]); got != want {
+			t.Errorf("derived metric %d = %s, want %s", i, got, want)
+		}
+	}
+	if resp.SourceMetrics != 5 {
+		t.Errorf("expected 5 source metrics, got %d", resp.SourceMetrics)
+	}
+	want := map[string]int{otlp.ClaudeUserFacingTokenUsageMetric: 2, otlp.ClaudeUserFacingCostMetric: 1, otlp.GeminiCostUsageMetric: 1}
+	if !reflect.DeepEqual(resp.Counts, want) {
+		t.Errorf("counts = %v, want %v", resp.Counts, want)
+	}
+
+	// The preview does not write the derived metrics
+	names, err := h.store.GetMetricNames(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetMetricNames failed: %v", err)
+	}
+	for _, name := range names {
+		if name == otlp.ClaudeUserFacingTokenUsageMetric || name == otlp.GeminiCostUsageMetric {
+			t.Errorf("preview stored derived metric %s", name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"path"
+	"strings"
+)
+
+// ServiceGroups assigns services to display groups so related services (e.g. all Claude
+// variants) can be rendered together with a shared color.
+type ServiceGroups struct {
+	rules []serviceGroup
+}
+
+type serviceGroup struct {
+	pattern string
+	group   string
+}
+
+// NewServiceGroups creates groups from "pattern=group" rules, where pattern uses path.Match syntax.
+// Rules are evaluated in order and the first match wins; malformed rules are ignored.
+func NewServiceGroups(rules []string) *ServiceGroups {
+	g := &ServiceGroups{}
+	for _, rule := range rules {
+		pattern, group, ok := strings.Cut(rule, "=")
+		pattern, group = strings.TrimSpace(pattern), strings.TrimSpace(group)
+		if !ok || pattern == "" || group == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			continue
+		}
+		g.rules = append(g.rules, serviceGroup{pattern: pattern, group: group})
+	}
+	return g
+}
+
+// Group returns the group label for a service, or "" if no rule matches
+func (g *ServiceGroups) Group(service string) string {
+	for _, rule := range g.rules {
+		if ok, _ := path.Match(rule.pattern, service); ok {
+			return rule.group
+		}
+	}
+	return ""
+}
+
+// Assign returns the group label of every service matched by a rule, or nil when no
+// groups are configured so the field is omitted from responses.
+func (g *ServiceGroups) Assign(services []string) map[string]string {
+	if len(g.rules) == 0 {
+		return nil
+	}
+	groups := make(map[string]string)
+	for _, service := range services {
+		if group := g.Group(service); group != "" {
+			groups[service] = group
+		}
+	}
+	return groups
+}
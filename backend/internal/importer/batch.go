@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// DefaultBatchSize is the number of records accumulated before the importer writes them
+const DefaultBatchSize = 10000
+
+// batchSize returns the configured batch size, or DefaultBatchSize when unset
+func (o Options) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+// RecordWriter is the subset of the store the importer writes telemetry through. Records are
+// written in one transaction with the import state of the files they were read from.
+type RecordWriter interface {
+	InsertImportBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint, states []*storage.ImportState) error
+}
+
+// batchedFile is a parsed file whose records are waiting in an importBatch
+type batchedFile struct {
+	path        string
+	sessionID   string
+	recordCount int
+	counts      storage.ImportCounts // Records the file's parse produced, before date filtering
+	checkpoint  *storage.ImportCheckpoint
+}
+
+// importBatch accumulates the records of several files so they are written in one large
+// transaction instead of a few small ones per file. Large files are split across batches as
+// they are streamed; the batch then records how far they were read, so an import that stops
+// midway resumes after the records it wrote.
+type importBatch struct {
+	logs    []api.LogRecord
+	metrics []api.MetricDataPoint
+	spans   []api.Span
+	files   []batchedFile
+	partial []batchedFile // Files still being read, as of their last queued chunk
+}
+
+// addRecords queues filtered records, possibly one chunk of a file that is still being read
+func (b *importBatch) addRecords(logs []api.LogRecord, metrics []api.MetricDataPoint, spans []api.Span) {
+	b.logs = append(b.logs, logs...)
+	b.metrics = append(b.metrics, metrics...)
+	b.spans = append(b.spans, spans...)
+}
+
+// addFile marks a completely read file as imported once the batch is written. Its earlier
+// chunks may already have been written by previous batches.
+func (b *importBatch) addFile(filePath string, result *ImportResult, counts storage.ImportCounts) {
+	b.dropPartial(filePath)
+	b.files = append(b.files, batchedFile{
+		path:        filePath,
+		sessionID:   result.SessionID,
+		recordCount: result.RecordCount,
+		counts:      counts,
+		checkpoint:  result.Checkpoint,
+	})
+}
+
+// addProgress records how far a file that is still being read has been queued: its records
+// up to checkpoint, with recordCount and counts covering them
+func (b *importBatch) addProgress(filePath string, recordCount int, counts storage.ImportCounts, checkpoint *storage.ImportCheckpoint) {
+	b.dropPartial(filePath)
+	b.partial = append(b.partial, batchedFile{
+		path:        filePath,
+		recordCount: recordCount,
+		counts:      counts,
+		checkpoint:  checkpoint,
+	})
+}
+
+// dropPartial forgets the progress recorded for a file
+func (b *importBatch) dropPartial(filePath string) {
+	b.partial = slices.DeleteFunc(b.partial, func(f batchedFile) bool { return f.path == filePath })
+}
+
+// records returns the number of queued records
+func (b *importBatch) records() int {
+	return len(b.logs) + len(b.metrics) + len(b.spans)
+}
+
+// write inserts the queued records in one transaction with the import state of the batch's
+// files. Completed files are recorded with their current content hash; partially read files
+// get no hash, so they are never considered current and the next import continues them from
+// their checkpoint.
+func (b *importBatch) write(ctx context.Context, w RecordWriter, source SourceType) error {
+	states := make([]*storage.ImportState, 0, len(b.files)+len(b.partial))
+	for _, f := range b.files {
+		hash, err := computeFileHash(f.path)
+		if err != nil {
+			return fmt.Errorf("computing file hash of %s: %w", f.path, err)
+		}
+		states = append(states, f.state(source, hash))
+	}
+	for _, f := range b.partial {
+		states = append(states, f.state(source, ""))
+	}
+	return w.InsertImportBatch(ctx, b.spans, b.logs, b.metrics, states)
+}
+
+// state returns the import state recording the file with the given content hash
+func (f batchedFile) state(source SourceType, hash string) *storage.ImportState {
+	counts := f.counts
+	return &storage.ImportState{
+		Source:      string(source),
+		FilePath:    f.path,
+		FileHash:    hash,
+		ImportedAt:  time.Now(),
+		RecordCount: f.recordCount,
+		Counts:      &counts,
+		Checkpoint:  f.checkpoint,
+	}
+}
+
+// reset empties the batch for reuse
+func (b *importBatch) reset() {
+	*b = importBatch{}
+}
@@ -1,18 +1,21 @@
 package importer
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/pricing"
+	"github.com/tobilg/ai-observer/internal/storage"
 )
 
 // ClaudeParser implements SessionParser for Claude Code JSONL files
@@ -86,7 +89,7 @@ func (p *ClaudeParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			if !info.IsDir() && strings.HasSuffix(path, ".jsonl") {
+			if !info.IsDir() && p.IsSessionFile(path) {
 				files = append(files, path)
 			}
 			return nil
@@ -100,6 +103,16 @@ func (p *ClaudeParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 	return files, nil
 }
 
+// SessionRoots returns the project directories holding session files
+func (p *ClaudeParser) SessionRoots() []string {
+	return p.configPaths
+}
+
+// IsSessionFile reports whether a path is a session JSONL file
+func (p *ClaudeParser) IsSessionFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonl")
+}
+
 // claudeJSONLEntry represents a single line in Claude Code JSONL files
 type claudeJSONLEntry struct {
 	Type      string         `json:"type,omitempty"` // Root type: "assistant", "user", "queue-operation", etc.
@@ -123,6 +136,7 @@ type claudeMessage struct {
 
 type claudeContent struct {
 	Type      string `json:"type,omitempty"`        // "text", "tool_use", "tool_result"
+	ID        string `json:"id,omitempty"`          // tool call id (for tool_use)
 	Text      string `json:"text,omitempty"`        // message text
 	Name      string `json:"name,omitempty"`        // tool name (for tool_use)
 	Input     any    `json:"input,omitempty"`       // tool input (for tool_use)
@@ -137,11 +151,38 @@ type claudeUsage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
+// claudeParseState is what a Claude Code parse carries from line to line, kept in checkpoints
+type claudeParseState struct {
+	MessageIndex int      `json:"messageIndex"`           // Order of the next transcript message
+	SeenRequests []string `json:"seenRequests,omitempty"` // messageId:requestId keys with metrics
+}
+
 // ParseFile parses a Claude Code JSONL file
 func (p *ClaudeParser) ParseFile(ctx context.Context, path string) (*ImportResult, error) {
-	file, err := os.Open(path)
+	return collectFile(ctx, p, path)
+}
+
+// ParseFileFrom parses the lines a Claude Code JSONL file gained after checkpoint from
+func (p *ClaudeParser) ParseFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint) (*ImportResult, error) {
+	return collectFileFrom(ctx, p, path, from)
+}
+
+// StreamFile parses a Claude Code JSONL file line by line, emitting records in chunks
+func (p *ClaudeParser) StreamFile(ctx context.Context, path string, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	return p.StreamFileFrom(ctx, path, storage.ImportCheckpoint{}, chunkSize, emit)
+}
+
+// StreamFileFrom parses the lines of a Claude Code JSONL file after checkpoint from, emitting
+// records in chunks
+func (p *ClaudeParser) StreamFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	var state claudeParseState
+	if err := loadParseState(from, &state); err != nil {
+		return nil, err
+	}
+
+	file, lines, err := openLines(path, from)
 	if err != nil {
-		return nil, fmt.Errorf("opening file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
@@ -153,29 +194,40 @@ func (p *ClaudeParser) ParseFile(ctx context.Context, path string) (*ImportResul
 	filename := filepath.Base(path)
 	result.SessionID = strings.TrimSuffix(filename, ".jsonl")
 
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
-
-	lineNum := 0
-	messageIndex := 0                     // Track message order for transcripts
+	messageIndex := state.MessageIndex    // Track message order for transcripts
 	seenRequests := make(map[string]bool) // For deduplication of metrics
+	for _, key := range state.SeenRequests {
+		seenRequests[key] = true
+	}
+	parseState := func() claudeParseState {
+		return claudeParseState{MessageIndex: messageIndex, SeenRequests: slices.Sorted(maps.Keys(seenRequests))}
+	}
 
-	for scanner.Scan() {
+	// Chunks are flushed before the next line is handled
+	chunks := &chunkEmitter{result: result, size: chunkSize, emit: emit, checkpoint: func() (*storage.ImportCheckpoint, error) {
+		return lines.checkpointBefore(parseState())
+	}}
+
+	for lines.Scan() {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
+		if err := chunks.flush(false); err != nil {
+			return nil, err
+		}
 
-		lineNum++
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+		line := lines.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
 		var entry claudeJSONLEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			// Skip malformed lines
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if lines.partial() {
+				lines.unread() // Possibly still being written, left to the next parse
+				break
+			}
+			warnMalformedLine(path, lines.line, err)
 			continue
 		}
 
@@ -258,16 +310,16 @@ func (p *ClaudeParser) ParseFile(ctx context.Context, path string) (*ImportResul
 
 			// Token usage metrics (creates both regular and user-facing variants)
 			if usage.InputTokens > 0 {
-				result.Metrics = append(result.Metrics, createTokenMetrics(ts, model, "input", float64(usage.InputTokens))...)
+				result.Metrics = append(result.Metrics, createTokenMetrics(ts, sessionID, model, "input", float64(usage.InputTokens))...)
 			}
 			if usage.OutputTokens > 0 {
-				result.Metrics = append(result.Metrics, createTokenMetrics(ts, model, "output", float64(usage.OutputTokens))...)
+				result.Metrics = append(result.Metrics, createTokenMetrics(ts, sessionID, model, "output", float64(usage.OutputTokens))...)
 			}
 			if usage.CacheCreationInputTokens > 0 {
-				result.Metrics = append(result.Metrics, createTokenMetrics(ts, model, "cacheCreation", float64(usage.CacheCreationInputTokens))...)
+				result.Metrics = append(result.Metrics, createTokenMetrics(ts, sessionID, model, "cacheCreation", float64(usage.CacheCreationInputTokens))...)
 			}
 			if usage.CacheReadInputTokens > 0 {
-				result.Metrics = append(result.Metrics, createTokenMetrics(ts, model, "cacheRead", float64(usage.CacheReadInputTokens))...)
+				result.Metrics = append(result.Metrics, createTokenMetrics(ts, sessionID, model, "cacheRead", float64(usage.CacheReadInputTokens))...)
 			}
 
 			// Cost metrics using pricing mode (creates both regular and user-facing variants)
@@ -279,16 +331,23 @@ func (p *ClaudeParser) ParseFile(ctx context.Context, path string) (*ImportResul
 			}
 			cost := pricing.GetClaudeCostWithMode(p.pricingMode, model, tokenUsage, entry.CostUSD)
 			if cost > 0 {
-				result.Metrics = append(result.Metrics, createCostMetrics(ts, model, cost)...)
+				result.Metrics = append(result.Metrics, createCostMetrics(ts, sessionID, model, entry.Cwd, cost)...)
 			}
 		}
 
 		result.RecordCount++
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := lines.Err(); err != nil {
 		return nil, fmt.Errorf("reading file: %w", err)
 	}
+	if err := chunks.flush(true); err != nil {
+		return nil, err
+	}
+
+	if result.Checkpoint, err = lines.checkpoint(parseState()); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -330,6 +389,9 @@ func (p *ClaudeParser) createTranscriptLogs(entry claudeJSONLEntry, ts time.Time
 			role = "tool_use"
 			attrs["message.role"] = role
 			attrs["tool.name"] = content.Name
+			if content.ID != "" {
+				attrs["tool.use_id"] = content.ID
+			}
 			if content.Input != nil {
 				if inputBytes, err := json.Marshal(content.Input); err == nil {
 					attrs["tool.input"] = string(inputBytes)
@@ -381,7 +443,7 @@ const (
 )
 
 // createTokenMetric creates a token usage metric with the specified name
-func createTokenMetric(ts time.Time, metricName, model, tokenType string, value float64) api.MetricDataPoint {
+func createTokenMetric(ts time.Time, metricName, sessionID, model, tokenType string, value float64) api.MetricDataPoint {
 	return api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceClaude.ServiceName(),
@@ -391,6 +453,7 @@ func createTokenMetric(ts time.Time, metricName, model, tokenType string, value
 		Attributes: map[string]string{
 			"type":          tokenType,
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
@@ -399,16 +462,17 @@ func createTokenMetric(ts time.Time, metricName, model, tokenType string, value
 // createTokenMetrics creates both regular and user-facing token usage metrics.
 // JSONL data is already user-facing (only assistant messages with cache tokens),
 // so both metrics have identical values for consistency with OTLP-derived metrics.
-func createTokenMetrics(ts time.Time, model, tokenType string, value float64) []api.MetricDataPoint {
+func createTokenMetrics(ts time.Time, sessionID, model, tokenType string, value float64) []api.MetricDataPoint {
 	return []api.MetricDataPoint{
-		createTokenMetric(ts, claudeTokenUsageMetric, model, tokenType, value),
-		createTokenMetric(ts, claudeUserFacingTokenUsageMetric, model, tokenType, value),
+		createTokenMetric(ts, claudeTokenUsageMetric, sessionID, model, tokenType, value),
+		createTokenMetric(ts, claudeUserFacingTokenUsageMetric, sessionID, model, tokenType, value),
 	}
 }
 
-// createCostMetric creates a cost metric with the specified name
-func createCostMetric(ts time.Time, metricName, model string, value float64) api.MetricDataPoint {
-	return api.MetricDataPoint{
+// createCostMetric creates a cost metric with the specified name, attributed to the project
+// (working directory) when it is known
+func createCostMetric(ts time.Time, metricName, sessionID, model, project string, value float64) api.MetricDataPoint {
+	m := api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceClaude.ServiceName(),
 		MetricName:  metricName,
@@ -416,17 +480,22 @@ func createCostMetric(ts time.Time, metricName, model string, value float64) api
 		Value:       &value,
 		Attributes: map[string]string{
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
+	if project != "" {
+		m.Attributes["project"] = project
+	}
+	return m
 }
 
 // createCostMetrics creates both regular and user-facing cost metrics.
 // JSONL data is already user-facing (only assistant messages with cache tokens),
 // so both metrics have identical values for consistency with OTLP-derived metrics.
-func createCostMetrics(ts time.Time, model string, value float64) []api.MetricDataPoint {
+func createCostMetrics(ts time.Time, sessionID, model, project string, value float64) []api.MetricDataPoint {
 	return []api.MetricDataPoint{
-		createCostMetric(ts, claudeCostMetric, model, value),
-		createCostMetric(ts, claudeUserFacingCostMetric, model, value),
+		createCostMetric(ts, claudeCostMetric, sessionID, model, project, value),
+		createCostMetric(ts, claudeUserFacingCostMetric, sessionID, model, project, value),
 	}
 }
@@ -1,7 +1,7 @@
 package importer
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,6 +12,7 @@ import (
 
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/pricing"
+	"github.com/tobilg/ai-observer/internal/storage"
 )
 
 // CodexParser implements SessionParser for Codex CLI JSONL files
@@ -66,7 +67,7 @@ func (p *CodexParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".jsonl") {
+		if !info.IsDir() && p.IsSessionFile(path) {
 			files = append(files, path)
 		}
 		return nil
@@ -79,6 +80,19 @@ func (p *CodexParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 	return files, nil
 }
 
+// SessionRoots returns the sessions directory, whose files are nested in per-day folders
+func (p *CodexParser) SessionRoots() []string {
+	if p.sessionsPath == "" {
+		return nil
+	}
+	return []string{p.sessionsPath}
+}
+
+// IsSessionFile reports whether a path is a session JSONL file
+func (p *CodexParser) IsSessionFile(path string) bool {
+	return strings.HasSuffix(path, ".jsonl")
+}
+
 // codexJSONLEntry represents a single line in Codex CLI JSONL files
 // Format: { "timestamp": "...", "type": "session_meta|response_item|event_msg|...", "payload": {...} }
 type codexJSONLEntry struct {
@@ -150,11 +164,69 @@ type codexTokenCount struct {
 	ToolTokens               int `json:"tool_tokens"`
 }
 
+// cachedTokens returns the cache-read tokens, which Codex reports under either field name
+func (c *codexTokenCount) cachedTokens() int {
+	if c.CacheReadInputTokens != 0 {
+		return c.CacheReadInputTokens
+	}
+	return c.CachedInputTokens
+}
+
+// codexTokenDelta returns the usage a token_count event added to the session's running
+// total prev. The first total of a session (prev nil) counts in full. A count lower than
+// before means Codex started a new conversation context; it contributes zero instead of a
+// negative delta, and the lower total becomes the base for the next event. The cache-read
+// delta is returned in CacheReadInputTokens.
+func codexTokenDelta(prev, cur *codexTokenCount) codexTokenCount {
+	if prev == nil {
+		prev = &codexTokenCount{}
+	}
+	diff := func(cur, prev int) int { return max(cur-prev, 0) }
+	return codexTokenCount{
+		InputTokens:              diff(cur.InputTokens, prev.InputTokens),
+		OutputTokens:             diff(cur.OutputTokens, prev.OutputTokens),
+		CacheCreationInputTokens: diff(cur.CacheCreationInputTokens, prev.CacheCreationInputTokens),
+		CacheReadInputTokens:     diff(cur.cachedTokens(), prev.cachedTokens()),
+		ReasoningTokens:          diff(cur.ReasoningTokens, prev.ReasoningTokens),
+		ToolTokens:               diff(cur.ToolTokens, prev.ToolTokens),
+	}
+}
+
+// codexParseState is what a Codex CLI parse carries from line to line, kept in checkpoints
+type codexParseState struct {
+	SessionID    string            `json:"sessionId,omitempty"`
+	Meta         *codexSessionMeta `json:"meta,omitempty"`
+	Model        string            `json:"model,omitempty"`
+	LastTokens   *codexTokenCount  `json:"lastTokens,omitempty"` // Cumulative usage, for deltas
+	MessageIndex int               `json:"messageIndex"`
+}
+
 // ParseFile parses a Codex CLI JSONL file
 func (p *CodexParser) ParseFile(ctx context.Context, path string) (*ImportResult, error) {
-	file, err := os.Open(path)
+	return collectFile(ctx, p, path)
+}
+
+// ParseFileFrom parses the lines a Codex CLI JSONL file gained after checkpoint from
+func (p *CodexParser) ParseFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint) (*ImportResult, error) {
+	return collectFileFrom(ctx, p, path, from)
+}
+
+// StreamFile parses a Codex CLI JSONL file line by line, emitting records in chunks
+func (p *CodexParser) StreamFile(ctx context.Context, path string, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	return p.StreamFileFrom(ctx, path, storage.ImportCheckpoint{}, chunkSize, emit)
+}
+
+// StreamFileFrom parses the lines of a Codex CLI JSONL file after checkpoint from, emitting
+// records in chunks
+func (p *CodexParser) StreamFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	var state codexParseState
+	if err := loadParseState(from, &state); err != nil {
+		return nil, err
+	}
+
+	file, lines, err := openLines(path, from)
 	if err != nil {
-		return nil, fmt.Errorf("opening file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
@@ -165,28 +237,49 @@ func (p *CodexParser) ParseFile(ctx context.Context, path string) (*ImportResult
 	// Extract session ID from filename (format: rollout-YYYY-MM-DDThh-mm-ss-{id}.jsonl)
 	filename := filepath.Base(path)
 	result.SessionID = strings.TrimSuffix(filename, ".jsonl")
+	if state.SessionID != "" {
+		result.SessionID = state.SessionID
+	}
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+	sessionMeta := state.Meta
+	currentModel := state.Model
+	lastTokenCount := state.LastTokens
+	messageIndex := state.MessageIndex // Track message order for transcripts
+	parseState := func() codexParseState {
+		return codexParseState{
+			SessionID:    result.SessionID,
+			Meta:         sessionMeta,
+			Model:        currentModel,
+			LastTokens:   lastTokenCount,
+			MessageIndex: messageIndex,
+		}
+	}
 
-	var sessionMeta *codexSessionMeta
-	var currentModel string
-	var lastTokenCount *codexTokenCount
-	messageIndex := 0 // Track message order for transcripts
+	// Chunks are flushed before the next line is handled
+	chunks := &chunkEmitter{result: result, size: chunkSize, emit: emit, checkpoint: func() (*storage.ImportCheckpoint, error) {
+		return lines.checkpointBefore(parseState())
+	}}
 
-	for scanner.Scan() {
+	for lines.Scan() {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
+		if err := chunks.flush(false); err != nil {
+			return nil, err
+		}
 
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+		line := lines.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
 		var entry codexJSONLEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if lines.partial() {
+				lines.unread() // Possibly still being written, left to the next parse
+				break
+			}
+			warnMalformedLine(path, lines.line, err)
 			continue
 		}
 
@@ -254,60 +347,33 @@ func (p *CodexParser) ParseFile(ctx context.Context, path string) (*ImportResult
 				if eventMsg.Info != nil && eventMsg.Info.TotalTokenUsage != nil {
 					tokenCount := eventMsg.Info.TotalTokenUsage
 
-					// Handle alternative field name for cached tokens
-					cachedTokens := tokenCount.CacheReadInputTokens
-					if cachedTokens == 0 {
-						cachedTokens = tokenCount.CachedInputTokens
-					}
-
-					// Calculate delta from last token count (Codex reports cumulative)
-					var deltaInput, deltaOutput, deltaCacheCreation, deltaCacheRead, deltaReasoning, deltaTool int
-
-					if lastTokenCount == nil {
-						deltaInput = tokenCount.InputTokens
-						deltaOutput = tokenCount.OutputTokens
-						deltaCacheCreation = tokenCount.CacheCreationInputTokens
-						deltaCacheRead = cachedTokens
-						deltaReasoning = tokenCount.ReasoningTokens
-						deltaTool = tokenCount.ToolTokens
-					} else {
-						lastCached := lastTokenCount.CacheReadInputTokens
-						if lastCached == 0 {
-							lastCached = lastTokenCount.CachedInputTokens
+					// Codex reports the running total; emit what this event added
+					delta := codexTokenDelta(lastTokenCount, tokenCount)
+					for _, usage := range []struct {
+						tokenType string
+						tokens    int
+					}{
+						{"input", delta.InputTokens},
+						{"output", delta.OutputTokens},
+						{"cache_creation", delta.CacheCreationInputTokens},
+						{"cache_read", delta.CacheReadInputTokens},
+						{"reasoning", delta.ReasoningTokens},
+						{"tool", delta.ToolTokens},
+					} {
+						if usage.tokens > 0 {
+							result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, result.SessionID, currentModel, usage.tokenType, float64(usage.tokens)))
 						}
-						deltaInput = tokenCount.InputTokens - lastTokenCount.InputTokens
-						deltaOutput = tokenCount.OutputTokens - lastTokenCount.OutputTokens
-						deltaCacheCreation = tokenCount.CacheCreationInputTokens - lastTokenCount.CacheCreationInputTokens
-						deltaCacheRead = cachedTokens - lastCached
-						deltaReasoning = tokenCount.ReasoningTokens - lastTokenCount.ReasoningTokens
-						deltaTool = tokenCount.ToolTokens - lastTokenCount.ToolTokens
-					}
-
-					// Create metrics for non-zero deltas
-					if deltaInput > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "input", float64(deltaInput)))
-					}
-					if deltaOutput > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "output", float64(deltaOutput)))
-					}
-					if deltaCacheCreation > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "cache_creation", float64(deltaCacheCreation)))
-					}
-					if deltaCacheRead > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "cache_read", float64(deltaCacheRead)))
-					}
-					if deltaReasoning > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "reasoning", float64(deltaReasoning)))
-					}
-					if deltaTool > 0 {
-						result.Metrics = append(result.Metrics, createCodexTokenMetric(ts, currentModel, "tool", float64(deltaTool)))
 					}
 
 					// Calculate and add cost metric
 					// Note: cache_read is used for cost calculation (cache_creation tokens are billed at input rate)
-					cost := pricing.CalculateCodexCost(currentModel, int64(deltaInput), int64(deltaCacheRead), int64(deltaOutput))
+					cost := pricing.CalculateCodexCost(currentModel, int64(delta.InputTokens), int64(delta.CacheReadInputTokens), int64(delta.OutputTokens))
 					if cost != nil && *cost > 0 {
-						result.Metrics = append(result.Metrics, createCodexCostMetric(ts, currentModel, *cost))
+						project := ""
+						if sessionMeta != nil {
+							project = sessionMeta.Cwd
+						}
+						result.Metrics = append(result.Metrics, createCodexCostMetric(ts, result.SessionID, currentModel, project, *cost))
 					}
 
 					lastTokenCount = tokenCount
@@ -508,15 +574,22 @@ func (p *CodexParser) ParseFile(ctx context.Context, path string) (*ImportResult
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := lines.Err(); err != nil {
 		return nil, fmt.Errorf("reading file: %w", err)
 	}
+	if err := chunks.flush(true); err != nil {
+		return nil, err
+	}
+
+	if result.Checkpoint, err = lines.checkpoint(parseState()); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
 // createCodexTokenMetric creates a token usage metric for Codex
-func createCodexTokenMetric(ts time.Time, model, tokenType string, value float64) api.MetricDataPoint {
+func createCodexTokenMetric(ts time.Time, sessionID, model, tokenType string, value float64) api.MetricDataPoint {
 	return api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceCodex.ServiceName(),
@@ -526,14 +599,16 @@ func createCodexTokenMetric(ts time.Time, model, tokenType string, value float64
 		Attributes: map[string]string{
 			"type":          tokenType,
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
 }
 
-// createCodexCostMetric creates a cost usage metric for Codex
-func createCodexCostMetric(ts time.Time, model string, cost float64) api.MetricDataPoint {
-	return api.MetricDataPoint{
+// createCodexCostMetric creates a cost usage metric for Codex, attributed to the session's
+// project (working directory) when it is known
+func createCodexCostMetric(ts time.Time, sessionID, model, project string, cost float64) api.MetricDataPoint {
+	m := api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceCodex.ServiceName(),
 		MetricName:  "codex_cli_rs.cost.usage",
@@ -541,7 +616,12 @@ func createCodexCostMetric(ts time.Time, model string, cost float64) api.MetricD
 		Value:       &cost,
 		Attributes: map[string]string{
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
+	if project != "" {
+		m.Attributes["project"] = project
+	}
+	return m
 }
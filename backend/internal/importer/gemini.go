@@ -67,7 +67,7 @@ func (p *GeminiParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 			return ctx.Err()
 		}
 
-		if !info.IsDir() && strings.HasPrefix(filepath.Base(path), "session-") && strings.HasSuffix(path, ".json") {
+		if !info.IsDir() && p.IsSessionFile(path) {
 			files = append(files, path)
 		}
 		return nil
@@ -80,6 +80,19 @@ func (p *GeminiParser) FindSessionFiles(ctx context.Context) ([]string, error) {
 	return files, nil
 }
 
+// SessionRoots returns the Gemini tmp directory holding the per-project chats folders
+func (p *GeminiParser) SessionRoots() []string {
+	if p.geminiPath == "" {
+		return nil
+	}
+	return []string{p.geminiPath}
+}
+
+// IsSessionFile reports whether a path is a session-*.json chat file
+func (p *GeminiParser) IsSessionFile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), "session-") && strings.HasSuffix(path, ".json")
+}
+
 // geminiSession represents a Gemini CLI session file
 type geminiSession struct {
 	SessionID   string          `json:"sessionId"`
@@ -306,37 +319,37 @@ func (p *GeminiParser) ParseFile(ctx context.Context, path string) (*ImportResul
 			var totalCost float64
 
 			if tokens.Input > 0 {
-				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, model, "input", float64(tokens.Input)))
+				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, session.SessionID, model, "input", float64(tokens.Input)))
 				if cost := pricing.CalculateGeminiCostForTokenType(model, "input", int64(tokens.Input)); cost != nil {
 					totalCost += *cost
 				}
 			}
 			if tokens.Output > 0 {
-				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, model, "output", float64(tokens.Output)))
+				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, session.SessionID, model, "output", float64(tokens.Output)))
 				if cost := pricing.CalculateGeminiCostForTokenType(model, "output", int64(tokens.Output)); cost != nil {
 					totalCost += *cost
 				}
 			}
 			if tokens.Cached > 0 {
-				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, model, "cached", float64(tokens.Cached)))
+				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, session.SessionID, model, "cached", float64(tokens.Cached)))
 				if cost := pricing.CalculateGeminiCostForTokenType(model, "cache", int64(tokens.Cached)); cost != nil {
 					totalCost += *cost
 				}
 			}
 			if tokens.Thoughts > 0 {
-				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, model, "thoughts", float64(tokens.Thoughts)))
+				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, session.SessionID, model, "thoughts", float64(tokens.Thoughts)))
 				if cost := pricing.CalculateGeminiCostForTokenType(model, "thought", int64(tokens.Thoughts)); cost != nil {
 					totalCost += *cost
 				}
 			}
 			if tokens.Tool > 0 {
-				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, model, "tool", float64(tokens.Tool)))
+				result.Metrics = append(result.Metrics, createGeminiTokenMetric(ts, session.SessionID, model, "tool", float64(tokens.Tool)))
 				// Tool tokens don't have direct cost
 			}
 
 			// Add cost metric if we calculated any cost
 			if totalCost > 0 {
-				result.Metrics = append(result.Metrics, createGeminiCostMetric(ts, model, totalCost))
+				result.Metrics = append(result.Metrics, createGeminiCostMetric(ts, session.SessionID, model, totalCost))
 			}
 		}
 	}
@@ -421,7 +434,7 @@ func mapGeminiTypeToRole(msgType string) string {
 }
 
 // createGeminiTokenMetric creates a token usage metric for Gemini
-func createGeminiTokenMetric(ts time.Time, model, tokenType string, value float64) api.MetricDataPoint {
+func createGeminiTokenMetric(ts time.Time, sessionID, model, tokenType string, value float64) api.MetricDataPoint {
 	return api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceGemini.ServiceName(),
@@ -431,13 +444,14 @@ func createGeminiTokenMetric(ts time.Time, model, tokenType string, value float6
 		Attributes: map[string]string{
 			"type":          tokenType,
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
 }
 
 // createGeminiCostMetric creates a cost usage metric for Gemini
-func createGeminiCostMetric(ts time.Time, model string, cost float64) api.MetricDataPoint {
+func createGeminiCostMetric(ts time.Time, sessionID, model string, cost float64) api.MetricDataPoint {
 	return api.MetricDataPoint{
 		Timestamp:   ts,
 		ServiceName: SourceGemini.ServiceName(),
@@ -446,6 +460,7 @@ func createGeminiCostMetric(ts time.Time, model string, cost float64) api.Metric
 		Value:       &cost,
 		Attributes: map[string]string{
 			"model":         model,
+			"session.id":    sessionID,
 			"import_source": "local_jsonl",
 		},
 	}
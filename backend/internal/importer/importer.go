@@ -10,12 +10,15 @@ import (
 
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/deleter"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/otlp"
 	"github.com/tobilg/ai-observer/internal/storage"
 )
 
 // Importer orchestrates the import process
 type Importer struct {
 	store    *storage.DuckDBStore
+	writer   RecordWriter
 	state    *StateManager
 	parsers  map[SourceType]SessionParser
 	verbose  bool
@@ -25,6 +28,7 @@ type Importer struct {
 func NewImporter(store *storage.DuckDBStore, verbose bool) *Importer {
 	return &Importer{
 		store:   store,
+		writer:  store,
 		state:   NewStateManager(store),
 		parsers: make(map[SourceType]SessionParser),
 		verbose: verbose,
@@ -54,6 +58,12 @@ func (i *Importer) RegisterAllParsersWithOptions(opts Options) {
 	i.RegisterParser(NewGeminiParser())
 }
 
+// SetWriter replaces the store as the destination of imported records, e.g. with a writer
+// that also notifies connected clients
+func (i *Importer) SetWriter(w RecordWriter) {
+	i.writer = w
+}
+
 // Import performs the import for the specified sources
 func (i *Importer) Import(ctx context.Context, sources []SourceType, opts Options) error {
 	// Collect summaries for all sources
@@ -179,6 +189,41 @@ func (i *Importer) Import(ctx context.Context, sources []SourceType, opts Option
 	return nil
 }
 
+// Sync imports the records the session files of sources gained since their last import,
+// without printing or prompting. Files that fail to import are logged, counted and skipped;
+// only cancellation of ctx ends the sync early.
+func (i *Importer) Sync(ctx context.Context, sources []SourceType, opts Options) (SyncSummary, error) {
+	var summary SyncSummary
+	for _, source := range sources {
+		parser, ok := i.parsers[source]
+		if !ok {
+			logger.Warn("No parser registered for import source, skipping", "source", source)
+			continue
+		}
+		files, err := parser.FindSessionFiles(ctx)
+		if err != nil {
+			logger.Warn("Failed to find session files", "source", source, "error", err)
+			continue
+		}
+		for _, path := range files {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			written, err := i.syncFile(ctx, parser, path, opts)
+			summary.Records += written
+			if err != nil {
+				logger.Warn("Failed to import session file", "source", source, "file", path, "error", err)
+				summary.Failed++
+				continue
+			}
+			if written > 0 {
+				summary.Files++
+			}
+		}
+	}
+	return summary, ctx.Err()
+}
+
 // scanSource scans files for a single source
 func (i *Importer) scanSource(ctx context.Context, parser SessionParser, opts Options) (*ImportSummary, error) {
 	source := parser.Source()
@@ -214,8 +259,14 @@ func (i *Importer) scanSource(ctx context.Context, parser SessionParser, opts Op
 			continue
 		}
 
-		// Parse the file to get counts
-		result, err := parser.ParseFile(ctx, filePath)
+		// Parse the file to get counts, discarding records as they are read
+		var logs, metrics, spans int
+		result, err := streamFile(ctx, parser, filePath, opts.batchSize(), func(chunk *ImportResult) error {
+			logs += len(chunk.Logs)
+			metrics += len(chunk.Metrics)
+			spans += len(chunk.Spans)
+			return nil
+		})
 		if err != nil {
 			summary.AddError(filePath, err)
 			continue
@@ -238,7 +289,7 @@ func (i *Importer) scanSource(ctx context.Context, parser SessionParser, opts Op
 		if status == StatusCurrent && opts.Force {
 			statusStr = "modified"
 		}
-		summary.Add(result, statusStr)
+		summary.AddCounts(result, logs, metrics, spans, statusStr)
 	}
 
 	return summary, nil
@@ -254,14 +305,43 @@ func (i *Importer) importSource(ctx context.Context, parser SessionParser, opts
 		return fmt.Errorf("finding session files: %w", err)
 	}
 
-	imported := 0
+	batchSize := opts.batchSize()
+
+	// Records are written in batches spanning several files, and large files are streamed
+	// into several batches. Each batch is written in one transaction with the import state of
+	// its files: complete files are recorded as imported, and files split across batches
+	// record how far they were read. Files of a failed batch are retried by the next import,
+	// which continues split files after their last written batch.
+	var batch importBatch
+	imported, failed := 0, 0
+	flush := func() error {
+		if len(batch.files) == 0 && batch.records() == 0 {
+			return nil
+		}
+		defer batch.reset()
+
+		if err := batch.write(ctx, i.writer, source); err != nil {
+			failed += len(batch.files)
+			fmt.Printf("  Error inserting batch of %d files: %v\n", len(batch.files), err)
+			return err
+		}
+
+		for _, f := range batch.files {
+			imported++
+			if i.verbose {
+				fmt.Printf("  [%s] %s: %d logs, %d metrics\n", source, f.sessionID, f.counts.Logs, f.counts.Metrics)
+			}
+		}
+		return nil
+	}
+
 	for _, filePath := range files {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
 		// Check file status
-		status, err := i.state.CheckFileStatus(ctx, source, filePath)
+		status, _, state, err := i.state.checkFile(ctx, source, filePath)
 		if err != nil {
 			if i.verbose {
 				fmt.Printf("  Error checking %s: %v\n", filePath, err)
@@ -274,76 +354,82 @@ func (i *Importer) importSource(ctx context.Context, parser SessionParser, opts
 			continue
 		}
 
-		// Parse the file
-		result, err := parser.ParseFile(ctx, filePath)
-		if err != nil {
-			if i.verbose {
-				fmt.Printf("  Error parsing %s: %v\n", filePath, err)
-			}
-			continue
-		}
-
-		// Filter by date range if specified - skip entire file if completely outside range
-		if opts.FromDate != nil && result.LastTime.Before(*opts.FromDate) {
-			continue
+		// A grown or partially imported file is parsed from where its last import stopped,
+		// unless forced. The import state keeps the counts of the whole file.
+		var checkpoint *storage.ImportCheckpoint
+		var counts storage.ImportCounts
+		recordCount := 0
+		if !opts.Force {
+			checkpoint = resumeFrom(parser, filePath, state)
 		}
-		if opts.ToDate != nil && result.FirstTime.After(*opts.ToDate) {
-			continue
+		if checkpoint != nil {
+			counts, recordCount = *state.Counts, state.RecordCount
 		}
 
-		// Filter individual records by date range (handles files spanning date boundaries)
-		logs := filterLogsByDateRange(result.Logs, opts.FromDate, opts.ToDate)
-		metrics := filterMetricsByDateRange(result.Metrics, opts.FromDate, opts.ToDate)
-		spans := filterSpansByDateRange(result.Spans, opts.FromDate, opts.ToDate)
+		// Parse the file, queueing its records chunk by chunk so large files are written
+		// while they are read
+		queued := 0
+		result, err := streamFileFrom(ctx, parser, filePath, checkpoint, batchSize, func(chunk *ImportResult) error {
+			counts.Logs += len(chunk.Logs)
+			counts.Metrics += len(chunk.Metrics)
+			counts.Spans += len(chunk.Spans)
 
-		// Skip if no records remain after filtering
-		if len(logs) == 0 && len(metrics) == 0 && len(spans) == 0 {
-			continue
-		}
-
-		// Store logs
-		if len(logs) > 0 {
-			if err := i.store.InsertLogs(ctx, logs); err != nil {
-				if i.verbose {
-					fmt.Printf("  Error inserting logs from %s: %v\n", filePath, err)
-				}
-				continue
+			// Chunks without a checkpoint hold a whole file, which is only recorded once
+			// complete and so must not be written before
+			if chunk.Checkpoint != nil {
+				batch.addProgress(filePath, recordCount+chunk.RecordCount, counts, chunk.Checkpoint)
 			}
-		}
 
-		// Store metrics
-		if len(metrics) > 0 {
-			if err := i.store.InsertMetrics(ctx, metrics); err != nil {
-				if i.verbose {
-					fmt.Printf("  Error inserting metrics from %s: %v\n", filePath, err)
-				}
-				continue
+			// Filter individual records by date range (handles files spanning date boundaries)
+			logs := filterLogsByDateRange(chunk.Logs, opts.FromDate, opts.ToDate)
+			metrics := filterMetricsByDateRange(chunk.Metrics, opts.FromDate, opts.ToDate)
+			spans := filterSpansByDateRange(chunk.Spans, opts.FromDate, opts.ToDate)
+			if len(logs) == 0 && len(metrics) == 0 && len(spans) == 0 {
+				return nil
 			}
-		}
 
-		// Store spans
-		if len(spans) > 0 {
-			if err := i.store.InsertSpans(ctx, spans); err != nil {
-				if i.verbose {
-					fmt.Printf("  Error inserting spans from %s: %v\n", filePath, err)
-				}
-				continue
-			}
-		}
+			otlp.LimitLogAttributes(logs, opts.MaxAttrs)
+			otlp.LimitMetricAttributes(metrics, opts.MaxAttrs)
+			otlp.LimitSpanAttributes(spans, opts.MaxAttrs)
 
-		// Record import state
-		if err := i.state.RecordImport(ctx, source, filePath, result.RecordCount); err != nil {
+			batch.addRecords(logs, metrics, spans)
+			queued += len(logs) + len(metrics) + len(spans)
+			if batch.records() < batchSize || chunk.Checkpoint == nil {
+				return nil
+			}
+			if err := flush(); err != nil {
+				// The file is left unrecorded, like the completed files of the batch
+				failed++
+				return err
+			}
+			return nil
+		})
+		if err != nil {
 			if i.verbose {
-				fmt.Printf("  Error recording import state for %s: %v\n", filePath, err)
+				fmt.Printf("  Error importing %s: %v\n", filePath, err)
 			}
+			continue
+		}
+
+		// Skip files without records in the date range
+		if queued == 0 {
+			batch.dropPartial(filePath)
+			continue
 		}
 
-		imported++
-		if i.verbose {
-			fmt.Printf("  [%s] %s: %d logs, %d metrics\n", source, result.SessionID, len(result.Logs), len(result.Metrics))
+		result.RecordCount += recordCount
+		batch.addFile(filePath, result, counts)
+		if batch.records() >= batchSize {
+			flush()
 		}
 	}
 
+	flush()
+
+	if failed > 0 {
+		fmt.Printf("[%s] Imported %d files (%d failed)\n", source, imported, failed)
+		return nil
+	}
 	fmt.Printf("[%s] Imported %d files\n", source, imported)
 	return nil
 }
@@ -1,11 +1,14 @@
 package importer
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
@@ -31,6 +34,7 @@ func TestClaudeParser(t *testing.T) {
 			SessionID: "test-session-123",
 			RequestID: "req-001",
 			CostUSD:   floatPtr(0.05),
+			Cwd:       "/home/user/project",
 			Message: &claudeMessage{
 				ID:    "msg-001",
 				Model: "claude-sonnet-4-20250514",
@@ -115,6 +119,17 @@ func TestClaudeParser(t *testing.T) {
 		t.Errorf("expected 2 records, got %d", result.RecordCount)
 	}
 
+	// Cost is attributed to the entry's working directory when it has one
+	projects := map[string]int{}
+	for _, m := range result.Metrics {
+		if m.MetricName == claudeCostMetric {
+			projects[m.Attributes["project"]]++
+		}
+	}
+	if projects["/home/user/project"] != 1 || projects[""] != 1 {
+		t.Errorf("expected one cost metric with and one without a project, got %v", projects)
+	}
+
 	// Verify time range
 	expectedFirst, _ := time.Parse(time.RFC3339, "2025-01-02T10:00:00.000Z")
 	expectedLast, _ := time.Parse(time.RFC3339, "2025-01-02T10:01:00.000Z")
@@ -192,6 +207,11 @@ func TestCodexParser(t *testing.T) {
 	if len(result.Metrics) != 10 {
 		t.Errorf("expected 10 metrics, got %d", len(result.Metrics))
 	}
+	for _, m := range result.Metrics {
+		if m.MetricName == "codex_cli_rs.cost.usage" && m.Attributes["project"] != "/home/user/project" {
+			t.Errorf("expected cost attributed to the session cwd, got %q", m.Attributes["project"])
+		}
+	}
 
 	// Verify time range
 	expectedFirst, _ := time.Parse(time.RFC3339, "2025-01-02T10:00:00.000Z")
@@ -204,6 +224,52 @@ func TestCodexParser(t *testing.T) {
 	}
 }
 
+// TestCodexParserTokenDeltas verifies cumulative token_count totals become non-negative deltas
+func TestCodexParserTokenDeltas(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "rollout-2025-01-02T10-00-00-reset.jsonl")
+
+	entries := []string{
+		`{"timestamp":"2025-01-02T10:00:00.000Z","type":"session_meta","payload":{"id":"session-reset","model":"gpt-4o"}}`,
+		// First total counts in full
+		`{"timestamp":"2025-01-02T10:01:00.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":1000,"output_tokens":400,"cached_input_tokens":200}}}}`,
+		// Input and cache reads drop (new context): they contribute nothing, output grows by 100
+		`{"timestamp":"2025-01-02T10:02:00.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":300,"output_tokens":500,"cached_input_tokens":50}}}}`,
+		// Deltas continue from the lower total
+		`{"timestamp":"2025-01-02T10:03:00.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":450,"output_tokens":520,"cached_input_tokens":80}}}}`,
+	}
+	if err := os.WriteFile(testFile, []byte(joinLines(entries)), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	result, err := NewCodexParser().ParseFile(context.Background(), testFile)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	type key struct {
+		minute    int
+		tokenType string
+	}
+	got := make(map[key]float64)
+	for _, m := range result.Metrics {
+		if m.Value == nil || *m.Value < 0 {
+			t.Fatalf("negative or missing value in %+v", m)
+		}
+		if m.MetricName == "codex_cli_rs.token.usage" {
+			got[key{m.Timestamp.Minute(), m.Attributes["type"]}] = *m.Value
+		}
+	}
+	want := map[key]float64{
+		{1, "input"}: 1000, {1, "output"}: 400, {1, "cache_read"}: 200,
+		{2, "output"}: 100,
+		{3, "input"}: 150, {3, "output"}: 20, {3, "cache_read"}: 30,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("token deltas = %v, want %v", got, want)
+	}
+}
+
 // TestGeminiParser tests the Gemini CLI parser
 func TestGeminiParser(t *testing.T) {
 	// Create temp directory structure
@@ -491,7 +557,7 @@ func TestStateManager(t *testing.T) {
 	})
 
 	t.Run("record import", func(t *testing.T) {
-		err := manager.RecordImport(ctx, SourceClaude, tmpFile.Name(), 10)
+		err := manager.RecordImport(ctx, SourceClaude, tmpFile.Name(), 10, storage.ImportCounts{Logs: 3}, nil)
 		if err != nil {
 			t.Fatalf("RecordImport failed: %v", err)
 		}
@@ -717,7 +783,7 @@ func TestImportDryRun(t *testing.T) {
 	}
 
 	// Verify no data was actually imported (dry run)
-	logs, _ := store.QueryLogs(ctx, "", "", "", "", time.Time{}, time.Now(), 100, 0)
+	logs, _ := store.QueryLogs(ctx, "", "", storage.SeverityRange{}, "", "", time.Time{}, time.Now(), 100, 0)
 	if logs == nil || len(logs.Logs) != 0 {
 		t.Errorf("expected 0 logs after dry run, got %d", len(logs.Logs))
 	}
@@ -785,7 +851,7 @@ func TestImportWithSkipConfirm(t *testing.T) {
 	}
 
 	// Verify data was imported
-	logs, _ := store.QueryLogs(ctx, "", "", "", "", time.Time{}, time.Now(), 100, 0)
+	logs, _ := store.QueryLogs(ctx, "", "", storage.SeverityRange{}, "", "", time.Time{}, time.Now(), 100, 0)
 	if logs == nil || len(logs.Logs) == 0 {
 		t.Error("expected logs to be imported")
 	}
@@ -858,3 +924,580 @@ func joinLines(lines []string) string {
 	}
 	return result
 }
+
+// countingWriter wraps a RecordWriter and counts the transactions that write records
+type countingWriter struct {
+	RecordWriter
+	transactions int
+	maxRecords   int   // Most records of one signal in a transaction
+	failMetrics  error // Fails every transaction with metrics
+	failAt       int   // Fails the transaction with this number, counting from 1
+	discard      bool  // Only import states are stored
+	peakHeap     uint64
+}
+
+func (w *countingWriter) InsertImportBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint, states []*storage.ImportState) error {
+	if len(spans) > 0 || len(logs) > 0 || len(metrics) > 0 {
+		w.transactions++
+	}
+	w.maxRecords = max(w.maxRecords, len(spans), len(logs), len(metrics))
+	if w.failMetrics != nil && len(metrics) > 0 {
+		return w.failMetrics
+	}
+	if w.transactions == w.failAt {
+		return fmt.Errorf("transaction %d failed", w.failAt)
+	}
+	if w.discard {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		w.peakHeap = max(w.peakHeap, m.HeapAlloc)
+		return w.RecordWriter.InsertImportBatch(ctx, nil, nil, nil, states)
+	}
+	return w.RecordWriter.InsertImportBatch(ctx, spans, logs, metrics, states)
+}
+
+// claudeRequestLine returns a JSONL line for one Claude API request
+func claudeRequestLine(t *testing.T, sessionID string, i int) []byte {
+	t.Helper()
+	entry := claudeJSONLEntry{
+		Type:      "assistant",
+		Timestamp: fmt.Sprintf("2025-01-02T10:%02d:%02d.000Z", (i/60)%60, i%60),
+		SessionID: sessionID,
+		CostUSD:   floatPtr(0.01),
+		Message: &claudeMessage{
+			ID:    fmt.Sprintf("msg-%03d", i),
+			Model: "claude-sonnet-4-20250514",
+			Role:  "assistant",
+			Type:  "message",
+			Usage: &claudeUsage{InputTokens: 100, OutputTokens: 50},
+		},
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("marshal entry: %v", err)
+	}
+	return append(data, '\n')
+}
+
+// writeClaudeSessions writes n single-request Claude session files into dir
+func writeClaudeSessions(t *testing.T, dir string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("session-%03d.jsonl", i))
+		if err := os.WriteFile(path, claudeRequestLine(t, fmt.Sprintf("session-%03d", i), i), 0644); err != nil {
+			t.Fatalf("write session file: %v", err)
+		}
+	}
+}
+
+// writeLargeClaudeSession writes one Claude session file with the given number of requests
+// and a malformed line in the middle
+func writeLargeClaudeSession(t *testing.T, dir string, requests int) string {
+	t.Helper()
+	path := filepath.Join(dir, "large-session.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create session file: %v", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for i := 0; i < requests; i++ {
+		if i == requests/2 {
+			w.WriteString("{\"type\": \"assistant\", truncated\n")
+		}
+		w.Write(claudeRequestLine(t, "large-session", i))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	return path
+}
+
+// importWithWriter imports the Claude sessions in dir into a fresh store through a countingWriter
+func importWithWriter(t *testing.T, dir string, opts Options, failMetrics error) (*storage.DuckDBStore, *countingWriter) {
+	t.Helper()
+	store, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	writer := &countingWriter{RecordWriter: store, failMetrics: failMetrics}
+	importInto(t, store, writer, dir, opts)
+	return store, writer
+}
+
+// importInto imports the Claude sessions in dir into store through writer
+func importInto(t *testing.T, store *storage.DuckDBStore, writer RecordWriter, dir string, opts Options) {
+	t.Helper()
+	t.Setenv("AI_OBSERVER_CLAUDE_PATH", dir)
+
+	imp := NewImporter(store, false)
+	imp.RegisterAllParsers()
+	imp.writer = writer
+
+	opts.SkipConfirm = true
+	if err := imp.Import(context.Background(), []SourceType{SourceClaude}, opts); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+}
+
+func countRows(t *testing.T, store *storage.DuckDBStore, table string) int {
+	t.Helper()
+	var count int
+	if err := store.DB().QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatalf("counting %s: %v", table, err)
+	}
+	return count
+}
+
+// TestImportBatching tests that many small files are written in few transactions
+func TestImportBatching(t *testing.T) {
+	const files = 30
+	dir := t.TempDir()
+	writeClaudeSessions(t, dir, files)
+
+	perFile, perFileWriter := importWithWriter(t, dir, Options{BatchSize: 1}, nil)
+	batched, batchedWriter := importWithWriter(t, dir, Options{}, nil)
+
+	for _, table := range []string{"otel_logs", "otel_metrics", "otel_traces"} {
+		want := countRows(t, perFile, table)
+		if got := countRows(t, batched, table); got != want {
+			t.Errorf("%s: expected %d rows with batching, got %d", table, want, got)
+		}
+	}
+	if countRows(t, batched, "otel_logs") < files {
+		t.Errorf("expected at least one log per file, got %d", countRows(t, batched, "otel_logs"))
+	}
+
+	imported, err := NewStateManager(batched).GetImportedFiles(context.Background(), SourceClaude)
+	if err != nil {
+		t.Fatalf("GetImportedFiles failed: %v", err)
+	}
+	if len(imported) != files {
+		t.Errorf("expected %d files recorded as imported, got %d", files, len(imported))
+	}
+
+	if batchedWriter.transactions > 1 {
+		t.Errorf("expected a single transaction, got %d", batchedWriter.transactions)
+	}
+	if perFileWriter.transactions < files {
+		t.Errorf("expected at least %d transactions without batching, got %d", files, perFileWriter.transactions)
+	}
+}
+
+// TestImportBatchFlushError tests that files of a failed batch are not recorded as imported
+func TestImportBatchFlushError(t *testing.T) {
+	dir := t.TempDir()
+	writeClaudeSessions(t, dir, 5)
+
+	store, _ := importWithWriter(t, dir, Options{}, fmt.Errorf("disk full"))
+
+	imported, err := NewStateManager(store).GetImportedFiles(context.Background(), SourceClaude)
+	if err != nil {
+		t.Fatalf("GetImportedFiles failed: %v", err)
+	}
+	if len(imported) != 0 {
+		t.Errorf("expected no files recorded after a failed flush, got %d", len(imported))
+	}
+	if n := countRows(t, store, "otel_metrics"); n != 0 {
+		t.Errorf("expected no metrics after a failed flush, got %d", n)
+	}
+}
+
+// TestImportMaxAttrs tests that imported attribute maps are truncated and the removed entries counted
+func TestImportMaxAttrs(t *testing.T) {
+	dir := t.TempDir()
+	writeClaudeSessions(t, dir, 2)
+
+	store, _ := importWithWriter(t, dir, Options{MaxAttrs: 2}, nil)
+
+	var logs, oversized, undercounted int
+	err := store.DB().QueryRow(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE len(json_keys(LogAttributes)) > 2),
+			COUNT(*) FILTER (WHERE COALESCE(DroppedAttributesCount, 0) = 0)
+		FROM otel_logs
+	`).Scan(&logs, &oversized, &undercounted)
+	if err != nil {
+		t.Fatalf("querying logs: %v", err)
+	}
+	if logs == 0 {
+		t.Fatal("expected imported logs")
+	}
+	if oversized != 0 {
+		t.Errorf("expected every log to keep at most 2 attributes, %d kept more", oversized)
+	}
+	if undercounted != 0 {
+		t.Errorf("expected every truncated log to record a dropped count, %d did not", undercounted)
+	}
+}
+
+// TestClaudeStreamFile tests that a large session is emitted in bounded chunks and that a
+// malformed line is skipped
+func TestClaudeStreamFile(t *testing.T) {
+	const requests = 2000
+	path := writeLargeClaudeSession(t, t.TempDir(), requests)
+
+	parser := NewClaudeParser()
+	chunks, largest, logs := 0, 0, 0
+	result, err := parser.StreamFile(context.Background(), path, 500, func(chunk *ImportResult) error {
+		chunks++
+		largest = max(largest, len(chunk.Logs)+len(chunk.Metrics)+len(chunk.Spans))
+		logs += len(chunk.Logs)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFile failed: %v", err)
+	}
+	if result.RecordCount != requests {
+		t.Errorf("expected %d records with the malformed line skipped, got %d", requests, result.RecordCount)
+	}
+	if len(result.Logs) != 0 || len(result.Metrics) != 0 {
+		t.Error("expected the streamed result to hold no records")
+	}
+	if chunks < 2 {
+		t.Errorf("expected several chunks, got %d", chunks)
+	}
+	// A chunk may overshoot by the records of one line
+	if largest > 520 {
+		t.Errorf("expected chunks of about 500 records, largest had %d", largest)
+	}
+
+	collected, err := parser.ParseFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	if len(collected.Logs) != logs {
+		t.Errorf("expected ParseFile to return all %d streamed logs, got %d", logs, len(collected.Logs))
+	}
+}
+
+// TestClaudeParseFileFrom tests that a grown Claude session is parsed from the checkpoint of
+// its previous parse, leaving a line still being written to the next parse
+func TestClaudeParseFileFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tail-session.jsonl")
+	if err := os.WriteFile(path, append(claudeRequestLine(t, "tail-session", 0), claudeRequestLine(t, "tail-session", 1)...), 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	parser := NewClaudeParser()
+	ctx := context.Background()
+	full, err := parser.ParseFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	info, _ := os.Stat(path)
+	if cp := full.Checkpoint; cp == nil || cp.Offset != info.Size() || cp.Lines != 2 {
+		t.Fatalf("expected a checkpoint at the end of both lines, got %+v", full.Checkpoint)
+	}
+
+	// Append a request and half of another, as if Claude Code were still writing it
+	next := claudeRequestLine(t, "tail-session", 3)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open session file: %v", err)
+	}
+	defer f.Close()
+	f.Write(claudeRequestLine(t, "tail-session", 2))
+	f.Write(next[:len(next)/2])
+
+	tail, err := parser.ParseFileFrom(ctx, path, *full.Checkpoint)
+	if err != nil {
+		t.Fatalf("ParseFileFrom failed: %v", err)
+	}
+	if tail.RecordCount != 1 || len(tail.Metrics) != len(full.Metrics)/2 {
+		t.Errorf("expected only the appended request, got %d records and %d metrics", tail.RecordCount, len(tail.Metrics))
+	}
+	info, _ = os.Stat(path)
+	if cp := tail.Checkpoint; cp.Lines != 3 || cp.Offset != info.Size()-int64(len(next)/2) {
+		t.Errorf("expected the partial line left for the next parse, got %+v", cp)
+	}
+
+	f.Write(next[len(next)/2:])
+	rest, err := parser.ParseFileFrom(ctx, path, *tail.Checkpoint)
+	if err != nil {
+		t.Fatalf("ParseFileFrom failed: %v", err)
+	}
+	info, _ = os.Stat(path)
+	if rest.RecordCount != 1 || rest.Checkpoint.Offset != info.Size() || rest.Checkpoint.Lines != 4 {
+		t.Errorf("expected the completed line, got %d records at %+v", rest.RecordCount, rest.Checkpoint)
+	}
+}
+
+// TestCodexParseFileFrom tests that a resumed Codex parse keeps the session and computes token
+// deltas against the usage before the checkpoint
+func TestCodexParseFileFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout-2025-01-02T10-00-00-abc123.jsonl")
+	entries := []string{
+		`{"timestamp":"2025-01-02T10:00:00.000Z","type":"session_meta","payload":{"id":"session-abc123","cwd":"/home/user/project","model":"gpt-4o"}}`,
+		`{"timestamp":"2025-01-02T10:02:00.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":500,"output_tokens":200}}}}`,
+	}
+	if err := os.WriteFile(path, []byte(joinLines(entries)), 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	parser := NewCodexParser()
+	ctx := context.Background()
+	full, err := parser.ParseFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	appended := []string{
+		`{"timestamp":"2025-01-02T10:03:00.000Z","type":"event_msg","payload":{"type":"agent_message"}}`,
+		`{"timestamp":"2025-01-02T10:04:00.000Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":800,"output_tokens":350}}}}`,
+	}
+	if err := os.WriteFile(path, []byte(joinLines(append(entries, appended...))), 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	tail, err := parser.ParseFileFrom(ctx, path, *full.Checkpoint)
+	if err != nil {
+		t.Fatalf("ParseFileFrom failed: %v", err)
+	}
+	if tail.SessionID != "session-abc123" {
+		t.Errorf("expected session ID 'session-abc123', got %q", tail.SessionID)
+	}
+	if len(tail.Logs) != 1 || tail.Logs[0].LogAttributes["session.id"] != "session-abc123" {
+		t.Errorf("expected the agent message logged for the session, got %+v", tail.Logs)
+	}
+	for _, m := range tail.Metrics {
+		if m.MetricName != "codex_cli_rs.token.usage" {
+			continue
+		}
+		want := map[string]float64{"input": 300, "output": 150}[m.Attributes["type"]]
+		if *m.Value != want || m.Attributes["model"] != "gpt-4o" {
+			t.Errorf("expected %s delta %v for gpt-4o, got %v for %q", m.Attributes["type"], want, *m.Value, m.Attributes["model"])
+		}
+	}
+}
+
+// TestImportAppendedFile tests that re-importing a grown session writes only its new records
+func TestImportAppendedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "grown-session.jsonl")
+	if err := os.WriteFile(path, append(claudeRequestLine(t, "grown-session", 0), claudeRequestLine(t, "grown-session", 1)...), 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	store, _ := importWithWriter(t, dir, Options{}, nil)
+	metricsPerRequest := countRows(t, store, "otel_metrics") / 2
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open session file: %v", err)
+	}
+	f.Write(claudeRequestLine(t, "grown-session", 2))
+	f.Close()
+
+	imp := NewImporter(store, false)
+	imp.RegisterAllParsers()
+	if err := imp.Import(context.Background(), []SourceType{SourceClaude}, Options{SkipConfirm: true}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if n := countRows(t, store, "otel_logs"); n != 3 {
+		t.Errorf("expected 3 logs without duplicates, got %d", n)
+	}
+	if n := countRows(t, store, "otel_metrics"); n != 3*metricsPerRequest {
+		t.Errorf("expected %d metrics without duplicates, got %d", 3*metricsPerRequest, n)
+	}
+	state, err := store.GetImportState(context.Background(), string(SourceClaude), path)
+	if err != nil {
+		t.Fatalf("GetImportState failed: %v", err)
+	}
+	if state == nil || state.RecordCount != 3 || state.Counts.Logs != 3 || state.Checkpoint == nil || state.Checkpoint.Lines != 3 {
+		t.Errorf("expected the whole file recorded with its checkpoint, got %+v", state)
+	}
+}
+
+// TestImportLargeFile tests that a large session is written in several batches while it is
+// read, and recorded as imported once complete
+func TestImportLargeFile(t *testing.T) {
+	const requests = 600
+	dir := t.TempDir()
+	writeLargeClaudeSession(t, dir, requests)
+
+	store, writer := importWithWriter(t, dir, Options{BatchSize: 500}, nil)
+
+	if n := countRows(t, store, "otel_logs"); n != requests {
+		t.Errorf("expected %d logs, got %d", requests, n)
+	}
+	records := countRows(t, store, "otel_logs") + countRows(t, store, "otel_metrics") + countRows(t, store, "otel_traces")
+	if writer.transactions < records/(2*500) {
+		t.Errorf("expected the file to be written in several batches, got %d transactions", writer.transactions)
+	}
+	// Batches hold the configured size plus at most one parser chunk
+	if writer.maxRecords > 2*500 {
+		t.Errorf("expected bounded batches, largest insert had %d records", writer.maxRecords)
+	}
+
+	imported, err := NewStateManager(store).GetImportedFiles(context.Background(), SourceClaude)
+	if err != nil {
+		t.Fatalf("GetImportedFiles failed: %v", err)
+	}
+	if len(imported) != 1 || imported[0].RecordCount != requests {
+		t.Errorf("expected the file recorded with %d records, got %+v", requests, imported)
+	}
+}
+
+// TestImportLargeFileRetry tests that an import failing midway through a large file resumes
+// after the batches it wrote, without duplicating their records
+func TestImportLargeFileRetry(t *testing.T) {
+	const requests = 600
+	dir := t.TempDir()
+	path := writeLargeClaudeSession(t, dir, requests)
+
+	complete, _ := importWithWriter(t, dir, Options{BatchSize: 500}, nil)
+
+	store, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test store: %v", err)
+	}
+	defer store.Close()
+	importInto(t, store, &countingWriter{RecordWriter: store, failAt: 3}, dir, Options{BatchSize: 500})
+
+	state, err := store.GetImportState(context.Background(), string(SourceClaude), path)
+	if err != nil {
+		t.Fatalf("GetImportState failed: %v", err)
+	}
+	if state == nil || state.FileHash != "" || state.Checkpoint == nil {
+		t.Fatalf("expected the progress of the failed import recorded without a hash, got %+v", state)
+	}
+	if status, _ := NewStateManager(store).CheckFileStatus(context.Background(), SourceClaude, path); status != StatusModified {
+		t.Errorf("expected a partially imported file to need importing, got %s", status)
+	}
+	if n := countRows(t, store, "otel_logs"); n == 0 || n >= requests {
+		t.Errorf("expected part of the logs after the failed import, got %d", n)
+	}
+
+	importInto(t, store, store, dir, Options{BatchSize: 500})
+
+	for _, table := range []string{"otel_logs", "otel_metrics", "otel_traces"} {
+		if got, want := countRows(t, store, table), countRows(t, complete, table); got != want {
+			t.Errorf("%s: expected %d rows after resuming, got %d", table, want, got)
+		}
+	}
+	state, err = store.GetImportState(context.Background(), string(SourceClaude), path)
+	if err != nil {
+		t.Fatalf("GetImportState failed: %v", err)
+	}
+	if state == nil || state.FileHash == "" || state.RecordCount != requests {
+		t.Errorf("expected the file recorded as imported with %d records, got %+v", requests, state)
+	}
+}
+
+// TestImportLargeFileMemory tests that the memory an import uses does not grow with the
+// size of the file
+func TestImportLargeFileMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large import in short mode")
+	}
+	const requests = 20000
+	dir := t.TempDir()
+	writeLargeClaudeSession(t, dir, requests)
+
+	store, err := storage.NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create test store: %v", err)
+	}
+	defer store.Close()
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	writer := &countingWriter{RecordWriter: store, discard: true}
+	importInto(t, store, writer, dir, Options{BatchSize: 500})
+
+	if writer.transactions < requests/500 {
+		t.Fatalf("expected the file to be written in many batches, got %d transactions", writer.transactions)
+	}
+	// Holding every record at once takes about 200 MB
+	if grown := int64(writer.peakHeap) - int64(before.HeapAlloc); grown > 64<<20 {
+		t.Errorf("expected bounded memory while importing, heap grew by %d MB", grown>>20)
+	}
+}
+
+// waitForRows waits until table has want rows, failing the test after a few seconds
+func waitForRows(t *testing.T, store *storage.DuckDBStore, table string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := countRows(t, store, table)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: expected %d rows, got %d", table, want, got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestWatch tests that watched session files are imported incrementally as they grow
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "live-session.jsonl")
+	initial := append(claudeRequestLine(t, "live-session", 0), claudeRequestLine(t, "live-session", 1)...)
+	if err := os.WriteFile(path, initial, 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+
+	// The existing file is imported before the watch and must not be imported again
+	store, _ := importWithWriter(t, dir, Options{}, nil)
+	metricsPerRequest := countRows(t, store, "otel_metrics") / 2
+
+	imp := NewImporter(store, false)
+	imp.RegisterAllParsers()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- imp.Watch(ctx, []SourceType{SourceClaude}, Options{Debounce: 50 * time.Millisecond}) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch failed: %v", err)
+		}
+	}()
+
+	// Append requests line by line, as Claude Code does while it works
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("open session file: %v", err)
+	}
+	defer f.Close()
+	time.Sleep(100 * time.Millisecond) // Let the watch start
+	for i := 2; i < 5; i++ {
+		if _, err := f.Write(claudeRequestLine(t, "live-session", i)); err != nil {
+			t.Fatalf("append to session file: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitForRows(t, store, "otel_logs", 5)
+	waitForRows(t, store, "otel_metrics", 5*metricsPerRequest)
+
+	// A session file in a new project directory is picked up too
+	project := filepath.Join(dir, "new-project")
+	if err := os.Mkdir(project, 0755); err != nil {
+		t.Fatalf("create project dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "other.jsonl"), claudeRequestLine(t, "other", 5), 0644); err != nil {
+		t.Fatalf("write session file: %v", err)
+	}
+	waitForRows(t, store, "otel_logs", 6)
+
+	// Settled files are not imported again
+	time.Sleep(200 * time.Millisecond)
+	if n := countRows(t, store, "otel_logs"); n != 6 {
+		t.Errorf("expected no duplicate logs, got %d", n)
+	}
+	state, err := store.GetImportState(context.Background(), string(SourceClaude), path)
+	if err != nil {
+		t.Fatalf("GetImportState failed: %v", err)
+	}
+	if state == nil || state.Counts == nil || state.Counts.Logs != 5 || state.RecordCount != 5 {
+		t.Errorf("expected the file recorded with 5 logs and records, got %+v", state)
+	}
+}
@@ -33,45 +33,60 @@ const (
 
 // CheckFileStatus determines if a file needs to be imported
 func (m *StateManager) CheckFileStatus(ctx context.Context, source SourceType, filePath string) (FileStatus, error) {
+	status, _, _, err := m.checkFile(ctx, source, filePath)
+	return status, err
+}
+
+// checkFile is CheckFileStatus, also returning the file's current hash and its stored import
+// state (nil for a new file)
+func (m *StateManager) checkFile(ctx context.Context, source SourceType, filePath string) (FileStatus, string, *storage.ImportState, error) {
 	// Get current file hash
 	currentHash, err := computeFileHash(filePath)
 	if err != nil {
-		return "", fmt.Errorf("computing file hash: %w", err)
+		return "", "", nil, fmt.Errorf("computing file hash: %w", err)
 	}
 
 	// Get stored state
 	state, err := m.store.GetImportState(ctx, string(source), filePath)
 	if err != nil {
-		return "", fmt.Errorf("getting import state: %w", err)
+		return "", "", nil, fmt.Errorf("getting import state: %w", err)
 	}
 
 	// File has never been imported
 	if state == nil {
-		return StatusNew, nil
+		return StatusNew, currentHash, nil, nil
 	}
 
 	// File has been modified
 	if state.FileHash != currentHash {
-		return StatusModified, nil
+		return StatusModified, currentHash, state, nil
 	}
 
 	// File is current (already imported, no changes)
-	return StatusCurrent, nil
+	return StatusCurrent, currentHash, state, nil
 }
 
-// RecordImport records that a file has been imported
-func (m *StateManager) RecordImport(ctx context.Context, source SourceType, filePath string, recordCount int) error {
+// RecordImport records that a file has been imported, along with the record counts its parse
+// produced and, for parsers that can continue a parse, the checkpoint the next one starts from
+func (m *StateManager) RecordImport(ctx context.Context, source SourceType, filePath string, recordCount int, counts storage.ImportCounts, checkpoint *storage.ImportCheckpoint) error {
 	hash, err := computeFileHash(filePath)
 	if err != nil {
 		return fmt.Errorf("computing file hash: %w", err)
 	}
 
+	return m.recordState(ctx, source, filePath, hash, recordCount, counts, checkpoint)
+}
+
+// recordState stores a file's import state with the given content hash
+func (m *StateManager) recordState(ctx context.Context, source SourceType, filePath, hash string, recordCount int, counts storage.ImportCounts, checkpoint *storage.ImportCheckpoint) error {
 	state := &storage.ImportState{
 		Source:      string(source),
 		FilePath:    filePath,
 		FileHash:    hash,
 		ImportedAt:  time.Now(),
 		RecordCount: recordCount,
+		Counts:      &counts,
+		Checkpoint:  checkpoint,
 	}
 
 	if err := m.store.SetImportState(ctx, state); err != nil {
@@ -81,6 +96,25 @@ func (m *StateManager) RecordImport(ctx context.Context, source SourceType, file
 	return nil
 }
 
+// resumeFrom returns the checkpoint a file's parse can continue from: the one recorded by its
+// last import, if the parser can continue there and the file has not shrunk below it.
+// Session files only grow, so a shorter file has been rewritten and is parsed in full.
+func resumeFrom(parser SessionParser, filePath string, state *storage.ImportState) *storage.ImportCheckpoint {
+	if _, ok := parser.(TailParser); !ok || state == nil || state.Checkpoint == nil || state.Counts == nil {
+		return nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() < state.Checkpoint.Offset {
+		return nil
+	}
+	return state.Checkpoint
+}
+
+// addCounts returns the sum of two files' record counts
+func addCounts(a, b storage.ImportCounts) storage.ImportCounts {
+	return storage.ImportCounts{Logs: a.Logs + b.Logs, Metrics: a.Metrics + b.Metrics, Spans: a.Spans + b.Spans}
+}
+
 // ClearSource removes all import state for a source
 func (m *StateManager) ClearSource(ctx context.Context, source SourceType) error {
 	return m.store.ClearImportState(ctx, string(source))
@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// maxLineSize is the longest JSONL line the parsers accept. Lines holding large tool outputs
+// or pasted files can run to several megabytes.
+const maxLineSize = 64 * 1024 * 1024
+
+// StreamingParser is implemented by parsers that hand records to the importer while reading a
+// file, so a large session never has to be held in memory as a whole
+type StreamingParser interface {
+	SessionParser
+
+	// StreamFile parses a session file like ParseFile, passing its records to emit in chunks
+	// of about chunkSize records. The returned result has the file's metadata and RecordCount
+	// but no records.
+	StreamFile(ctx context.Context, path string, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error)
+}
+
+// TailParser is implemented by streaming parsers of append-only JSONL files that can continue
+// a parse where an earlier one stopped, reading only the lines written since
+type TailParser interface {
+	StreamingParser
+
+	// ParseFileFrom parses the lines of a session file after checkpoint from, the Checkpoint
+	// of an earlier parse's result. The zero checkpoint parses the whole file.
+	ParseFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint) (*ImportResult, error)
+
+	// StreamFileFrom is StreamFile for the lines after checkpoint from
+	StreamFileFrom(ctx context.Context, path string, from storage.ImportCheckpoint, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error)
+}
+
+// lineReader reads the lines of a JSONL file from a checkpoint, tracking the byte offset and
+// number of the lines read
+type lineReader struct {
+	scanner    *bufio.Scanner
+	start      int64 // Start of the current line
+	startLine  int   // Number of the line before the current one
+	offset     int64 // End of the current line
+	line       int   // Number of the current line
+	size       int64 // Bytes of the current line, including its newline
+	terminated bool  // Whether the current line ends with a newline
+}
+
+// openLines opens a JSONL file positioned at checkpoint from. The caller closes the file.
+func openLines(path string, from storage.ImportCheckpoint) (*os.File, *lineReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening file: %w", err)
+	}
+	if _, err := file.Seek(from.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("seeking to offset %d: %w", from.Offset, err)
+	}
+
+	r := &lineReader{start: from.Offset, startLine: from.Lines, offset: from.Offset, line: from.Lines}
+	r.scanner = bufio.NewScanner(file)
+	// Increase buffer size for long lines
+	r.scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	r.scanner.Split(r.split)
+	return file, r, nil
+}
+
+// split is bufio.ScanLines, also recording the size of each line and whether it is terminated
+func (r *lineReader) split(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		r.size, r.terminated = int64(i+1), true
+		return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+	}
+	if atEOF && len(data) > 0 {
+		r.size, r.terminated = int64(len(data)), false
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Scan advances to the next line
+func (r *lineReader) Scan() bool {
+	r.start, r.startLine = r.offset, r.line
+	if !r.scanner.Scan() {
+		return false
+	}
+	r.offset += r.size
+	r.line++
+	return true
+}
+
+// Bytes returns the current line without its line ending
+func (r *lineReader) Bytes() []byte {
+	return r.scanner.Bytes()
+}
+
+// Err returns the first read error
+func (r *lineReader) Err() error {
+	return r.scanner.Err()
+}
+
+// partial reports whether a current line that does not decode may still be being written:
+// the file's last line, not yet terminated by a newline
+func (r *lineReader) partial() bool {
+	return !r.terminated
+}
+
+// unread leaves the current line to the next parse
+func (r *lineReader) unread() {
+	r.offset -= r.size
+	r.line--
+}
+
+// checkpoint returns the position after the current line along with the parser state,
+// which is stored as JSON
+func (r *lineReader) checkpoint(state any) (*storage.ImportCheckpoint, error) {
+	return newCheckpoint(r.offset, r.line, state)
+}
+
+// checkpointBefore returns the position before the current line, for a parser state that
+// does not include it yet. Once the lines are exhausted it equals checkpoint.
+func (r *lineReader) checkpointBefore(state any) (*storage.ImportCheckpoint, error) {
+	return newCheckpoint(r.start, r.startLine, state)
+}
+
+// newCheckpoint encodes the parser state of a checkpoint as JSON
+func newCheckpoint(offset int64, lines int, state any) (*storage.ImportCheckpoint, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encoding parser state: %w", err)
+	}
+	return &storage.ImportCheckpoint{Offset: offset, Lines: lines, State: string(data)}, nil
+}
+
+// loadParseState decodes the parser state of checkpoint from into state, leaving it unchanged
+// for the zero checkpoint
+func loadParseState(from storage.ImportCheckpoint, state any) error {
+	if from.State == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(from.State), state); err != nil {
+		return fmt.Errorf("decoding parser state: %w", err)
+	}
+	return nil
+}
+
+// chunkEmitter moves the records a parser accumulated in result to emit once there are enough.
+// Each chunk carries the RecordCount of the parse so far and, from checkpoint, where a parse
+// continues after the chunk's records, so its progress can be recorded along with them.
+type chunkEmitter struct {
+	result     *ImportResult
+	size       int
+	emit       func(*ImportResult) error
+	checkpoint func() (*storage.ImportCheckpoint, error)
+}
+
+// flush emits the pending records if they reach the chunk size, or whenever any are pending
+// when force is set
+func (c *chunkEmitter) flush(force bool) error {
+	pending := len(c.result.Logs) + len(c.result.Metrics) + len(c.result.Spans)
+	if pending == 0 || (!force && pending < c.size) {
+		return nil
+	}
+	chunk := &ImportResult{
+		FilePath:    c.result.FilePath,
+		SessionID:   c.result.SessionID,
+		Logs:        c.result.Logs,
+		Metrics:     c.result.Metrics,
+		Spans:       c.result.Spans,
+		RecordCount: c.result.RecordCount,
+	}
+	if c.checkpoint != nil {
+		var err error
+		if chunk.Checkpoint, err = c.checkpoint(); err != nil {
+			return err
+		}
+	}
+	c.result.Logs, c.result.Metrics, c.result.Spans = nil, nil, nil
+	return c.emit(chunk)
+}
+
+// collectFile runs a streaming parse and gathers every chunk into the returned result
+func collectFile(ctx context.Context, parser StreamingParser, path string) (*ImportResult, error) {
+	return collect(func(emit func(*ImportResult) error) (*ImportResult, error) {
+		return parser.StreamFile(ctx, path, math.MaxInt, emit)
+	})
+}
+
+// collectFileFrom is collectFile for the lines after checkpoint from
+func collectFileFrom(ctx context.Context, parser TailParser, path string, from storage.ImportCheckpoint) (*ImportResult, error) {
+	return collect(func(emit func(*ImportResult) error) (*ImportResult, error) {
+		return parser.StreamFileFrom(ctx, path, from, math.MaxInt, emit)
+	})
+}
+
+// collect gathers every chunk a streaming parse emits into the returned result
+func collect(stream func(emit func(*ImportResult) error) (*ImportResult, error)) (*ImportResult, error) {
+	var all ImportResult
+	result, err := stream(func(chunk *ImportResult) error {
+		all.Logs = append(all.Logs, chunk.Logs...)
+		all.Metrics = append(all.Metrics, chunk.Metrics...)
+		all.Spans = append(all.Spans, chunk.Spans...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Logs, result.Metrics, result.Spans = all.Logs, all.Metrics, all.Spans
+	return result, nil
+}
+
+// streamFile parses a file in chunks of about chunkSize records, falling back to emitting
+// all of ParseFile's records at once for parsers that cannot stream
+func streamFile(ctx context.Context, parser SessionParser, path string, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	if sp, ok := parser.(StreamingParser); ok {
+		return sp.StreamFile(ctx, path, chunkSize, emit)
+	}
+
+	result, err := parser.ParseFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	chunk := *result
+	if err := emit(&chunk); err != nil {
+		return nil, err
+	}
+	result.Logs, result.Metrics, result.Spans = nil, nil, nil
+	return result, nil
+}
+
+// warnMalformedLine logs a JSONL line that could not be decoded and is skipped
+func warnMalformedLine(path string, line int, err error) {
+	logger.Warn("Skipping malformed JSONL line", "file", path, "line", line, "error", err)
+}
+
+// streamFileFrom is streamFile for the lines after checkpoint from, parsing the whole file
+// when from is nil or the parser cannot continue a parse
+func streamFileFrom(ctx context.Context, parser SessionParser, path string, from *storage.ImportCheckpoint, chunkSize int, emit func(*ImportResult) error) (*ImportResult, error) {
+	if tp, ok := parser.(TailParser); ok && from != nil {
+		return tp.StreamFileFrom(ctx, path, *from, chunkSize, emit)
+	}
+	return streamFile(ctx, parser, path, chunkSize, emit)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/tobilg/ai-observer/internal/api"
 	"github.com/tobilg/ai-observer/internal/pricing"
+	"github.com/tobilg/ai-observer/internal/storage"
 	"github.com/tobilg/ai-observer/internal/tools"
 )
 
@@ -52,6 +53,10 @@ type ImportResult struct {
 	RecordCount int
 	FirstTime   time.Time
 	LastTime    time.Time
+
+	// Where a later parse of the grown file can continue; nil for parsers that cannot. For a
+	// streamed chunk, where a parse continues after the chunk's records.
+	Checkpoint *storage.ImportCheckpoint
 }
 
 // SessionParser defines the interface for tool-specific parsers
@@ -76,6 +81,9 @@ type Options struct {
 	SkipConfirm bool                // Skip confirmation prompts
 	Verbose     bool                // Show detailed progress
 	PricingMode pricing.PricingMode // Cost calculation mode for Claude (auto, calculate, display)
+	BatchSize   int                 // Records to accumulate before writing (0 = DefaultBatchSize)
+	MaxAttrs    int                 // Maximum entries kept per attribute map (0 = unlimited)
+	Debounce    time.Duration       // Quiet period before Watch imports a changed file (0 = DefaultWatchDebounce)
 }
 
 // FileState tracks import state for a single file
@@ -113,6 +121,13 @@ type ImportSummary struct {
 	Errors        []ImportError
 }
 
+// SyncSummary counts the session files and records written by Sync
+type SyncSummary struct {
+	Files   int // Files with new records
+	Records int // Records written
+	Failed  int // Files that could not be imported
+}
+
 // ImportError represents an error during import
 type ImportError struct {
 	FilePath string
@@ -121,20 +136,26 @@ type ImportError struct {
 
 // Add adds the counts from an ImportResult to this summary
 func (s *ImportSummary) Add(result *ImportResult, status string) {
+	s.AddCounts(result, len(result.Logs), len(result.Metrics), len(result.Spans), status)
+}
+
+// AddCounts adds a file to this summary with record counts taken separately, for results
+// whose records were streamed rather than kept
+func (s *ImportSummary) AddCounts(result *ImportResult, logs, metrics, spans int, status string) {
 	s.Files = append(s.Files, FileSummary{
 		Path:      result.FilePath,
 		SessionID: result.SessionID,
-		Logs:      len(result.Logs),
-		Metrics:   len(result.Metrics),
-		Spans:     len(result.Spans),
+		Logs:      logs,
+		Metrics:   metrics,
+		Spans:     spans,
 		FirstTime: result.FirstTime,
 		LastTime:  result.LastTime,
 		Status:    status,
 	})
 
-	s.TotalLogs += len(result.Logs)
-	s.TotalMetrics += len(result.Metrics)
-	s.TotalSpans += len(result.Spans)
+	s.TotalLogs += logs
+	s.TotalMetrics += metrics
+	s.TotalSpans += spans
 
 	switch status {
 	case "new":
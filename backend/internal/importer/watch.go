@@ -0,0 +1,273 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tobilg/ai-observer/internal/logger"
+	"github.com/tobilg/ai-observer/internal/otlp"
+	"github.com/tobilg/ai-observer/internal/storage"
+)
+
+// DefaultWatchDebounce is how long a session file must go without writes before Watch imports
+// it. Tools append JSONL files line by line, so one response causes a burst of writes.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// WatchableParser is implemented by parsers whose session files can be watched for changes
+type WatchableParser interface {
+	SessionParser
+
+	// SessionRoots returns the directories holding the tool's session files
+	SessionRoots() []string
+
+	// IsSessionFile reports whether a path below a session root is a session file
+	IsSessionFile(path string) bool
+}
+
+// watchRoot is a watched session directory and the parser of the files below it
+type watchRoot struct {
+	dir    string
+	parser WatchableParser
+}
+
+// sessionWatcher imports session files as fsnotify reports changes to them
+type sessionWatcher struct {
+	importer *Importer
+	watcher  *fsnotify.Watcher
+	opts     Options
+	roots    []watchRoot
+	pending  map[string]*time.Timer // Debounce timers of changed files, by path
+	ready    chan string            // Files whose writes have settled
+}
+
+// Watch imports the session files of sources as they change, until ctx is done. Existing
+// files are brought up to date first. A changed file is re-parsed once its writes have
+// settled for opts.Debounce, and only the records added since its last import are written.
+// Directories created below a session root, such as Codex's per-day folders, are watched
+// as they appear.
+func (i *Importer) Watch(ctx context.Context, sources []SourceType, opts Options) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	w := &sessionWatcher{
+		importer: i,
+		watcher:  watcher,
+		opts:     opts,
+		pending:  make(map[string]*time.Timer),
+		ready:    make(chan string),
+	}
+	for _, source := range sources {
+		parser, ok := i.parsers[source].(WatchableParser)
+		if !ok {
+			logger.Warn("Import source cannot be watched, skipping", "source", source)
+			continue
+		}
+		for _, dir := range parser.SessionRoots() {
+			w.roots = append(w.roots, watchRoot{dir: dir, parser: parser})
+			w.addTree(ctx, dir, parser)
+		}
+	}
+	if len(w.roots) == 0 {
+		return fmt.Errorf("no session directories to watch")
+	}
+	logger.Info("Watching session files for changes", "directories", len(w.roots))
+
+	for {
+		select {
+		case <-ctx.Done():
+			for _, t := range w.pending {
+				t.Stop()
+			}
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(ctx, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn("Session file watcher error", "error", err)
+		case path := <-w.ready:
+			delete(w.pending, path)
+			if parser := w.parserFor(path); parser != nil {
+				w.sync(ctx, parser, path)
+			}
+		}
+	}
+}
+
+// handle reacts to a file system event below a session root
+func (w *sessionWatcher) handle(ctx context.Context, event fsnotify.Event) {
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+	parser := w.parserFor(event.Name)
+	if parser == nil {
+		return
+	}
+
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			w.addTree(ctx, event.Name, parser)
+			return
+		}
+	}
+	if !parser.IsSessionFile(event.Name) {
+		return
+	}
+
+	debounce := w.opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+	if t, ok := w.pending[event.Name]; ok {
+		t.Reset(debounce)
+		return
+	}
+	path := event.Name
+	w.pending[path] = time.AfterFunc(debounce, func() {
+		select {
+		case w.ready <- path:
+		case <-ctx.Done():
+		}
+	})
+}
+
+// addTree watches dir and its subdirectories and imports the session files already in them,
+// which may have been written before the watch was in place
+func (w *sessionWatcher) addTree(ctx context.Context, dir string, parser WatchableParser) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries, continue walking
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if err := w.watcher.Add(path); err != nil {
+				logger.Warn("Failed to watch session directory", "dir", path, "error", err)
+			}
+			return nil
+		}
+		if parser.IsSessionFile(path) {
+			w.sync(ctx, parser, path)
+		}
+		return nil
+	})
+}
+
+// parserFor returns the parser of the session root holding path, or nil
+func (w *sessionWatcher) parserFor(path string) WatchableParser {
+	for _, root := range w.roots {
+		rel, err := filepath.Rel(root.dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root.parser
+		}
+	}
+	return nil
+}
+
+// sync imports a changed session file, logging failures so the watch goes on
+func (w *sessionWatcher) sync(ctx context.Context, parser SessionParser, path string) {
+	written, err := w.importer.syncFile(ctx, parser, path, w.opts)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("Failed to import session file", "source", parser.Source(), "file", path, "error", err)
+		}
+		return
+	}
+	if written > 0 {
+		logger.Info("Imported session file changes", "source", parser.Source(), "file", path, "records", written)
+	}
+}
+
+// syncFile writes the records a session file gained since its last import and returns how
+// many were written. Parsers that can continue a parse read only the lines after the recorded
+// checkpoint, and each chunk is written together with the checkpoint after it, so a failed
+// sync continues after the last written chunk. Otherwise the file is parsed in full and the
+// records its previous parse produced, per the import state, are skipped; for files imported
+// before those counts were recorded, records up to the last import time are skipped instead.
+// Such files are written at once, with their import state.
+func (i *Importer) syncFile(ctx context.Context, parser SessionParser, path string, opts Options) (int, error) {
+	source := parser.Source()
+
+	// The hash is taken before parsing: lines appended while the file is parsed are imported
+	// now, and the stale hash makes the next change re-check the file rather than skip it
+	status, hash, state, err := i.state.checkFile(ctx, source, path)
+	if err != nil {
+		return 0, err
+	}
+	if status == StatusCurrent {
+		return 0, nil
+	}
+
+	var skip, counts storage.ImportCounts
+	recordCount := 0
+	from := opts.FromDate
+	checkpoint := resumeFrom(parser, path, state)
+	if checkpoint != nil {
+		counts, recordCount = *state.Counts, state.RecordCount
+	} else if state != nil {
+		if state.Counts != nil {
+			skip = *state.Counts
+		} else if from == nil || state.ImportedAt.After(*from) {
+			from = &state.ImportedAt
+		}
+	}
+
+	var batch importBatch
+	written := 0
+	result, err := streamFileFrom(ctx, parser, path, checkpoint, opts.batchSize(), func(chunk *ImportResult) error {
+		counts.Logs += len(chunk.Logs)
+		counts.Metrics += len(chunk.Metrics)
+		counts.Spans += len(chunk.Spans)
+
+		logs := filterLogsByDateRange(chunk.Logs[takeSkip(&skip.Logs, len(chunk.Logs)):], from, opts.ToDate)
+		metrics := filterMetricsByDateRange(chunk.Metrics[takeSkip(&skip.Metrics, len(chunk.Metrics)):], from, opts.ToDate)
+		spans := filterSpansByDateRange(chunk.Spans[takeSkip(&skip.Spans, len(chunk.Spans)):], from, opts.ToDate)
+
+		otlp.LimitLogAttributes(logs, opts.MaxAttrs)
+		otlp.LimitMetricAttributes(metrics, opts.MaxAttrs)
+		otlp.LimitSpanAttributes(spans, opts.MaxAttrs)
+
+		batch.addRecords(logs, metrics, spans)
+		if chunk.Checkpoint == nil || batch.records() == 0 {
+			return nil
+		}
+		defer batch.reset()
+		batch.addProgress(path, recordCount+chunk.RecordCount, counts, chunk.Checkpoint)
+		if err := batch.write(ctx, i.writer, source); err != nil {
+			return err
+		}
+		written += batch.records()
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	recordCount += result.RecordCount
+	file := batchedFile{path: path, recordCount: recordCount, counts: counts, checkpoint: result.Checkpoint}
+	if err := i.writer.InsertImportBatch(ctx, batch.spans, batch.logs, batch.metrics, []*storage.ImportState{file.state(source, hash)}); err != nil {
+		return written, err
+	}
+	return written + batch.records(), nil
+}
+
+// takeSkip returns how many of n records to skip, taking them from the remaining skip count
+func takeSkip(remaining *int, n int) int {
+	k := min(*remaining, n)
+	*remaining -= k
+	return k
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// routeParamPattern matches chi-style route parameters such as {traceId}
+var routeParamPattern = regexp.MustCompile(`\{[^/}]+\}`)
+
+// DisabledEndpointsMiddleware rejects requests whose path matches one of the patterns,
+// responding with the given status before any handler runs. Patterns use path.Match
+// syntax, and chi-style parameters ({traceId}) match any single path segment.
+// If patterns is empty, requests pass through unchanged.
+func DisabledEndpointsMiddleware(patterns []string, status int) func(http.Handler) http.Handler {
+	normalized := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		normalized = append(normalized, routeParamPattern.ReplaceAllString(strings.TrimSuffix(p, "/"), "*"))
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(normalized) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestPath := strings.TrimSuffix(r.URL.Path, "/")
+			for _, pattern := range normalized {
+				if ok, err := path.Match(pattern, requestPath); err == nil && ok {
+					api.WriteError(w, status, "endpoint disabled")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
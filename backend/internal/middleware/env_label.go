@@ -0,0 +1,19 @@
+package middleware
+
+import "net/http"
+
+// EnvLabelHeader is the response header carrying the configured environment label
+const EnvLabelHeader = "X-AI-Observer-Env"
+
+// EnvLabelMiddleware sets the X-AI-Observer-Env header on every response.
+// If label is empty, the header is not set.
+func EnvLabelMiddleware(label string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if label != "" {
+				w.Header().Set(EnvLabelHeader, label)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package otlp
+
+import (
+	"sort"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// DefaultMaxAttributes is the default cap on the entries of each attribute map, matching the
+// OpenTelemetry SDK default attribute count limit
+const DefaultMaxAttributes = 128
+
+// TruncateAttributes returns attrs limited to max entries and the number of entries removed.
+// The lexically smallest keys are kept so the result does not depend on map iteration order.
+// A max <= 0 disables the limit.
+func TruncateAttributes(attrs map[string]string, max int) (map[string]string, int) {
+	if max <= 0 || len(attrs) <= max {
+		return attrs, 0
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kept := make(map[string]string, max)
+	for _, k := range keys[:max] {
+		kept[k] = attrs[k]
+	}
+	return kept, len(attrs) - max
+}
+
+// LimitSpanAttributes caps the resource, scope and span attributes of each span at max
+// entries. Removed entries are added to the span's DroppedAttributesCount. Returns the
+// total number of entries removed.
+func LimitSpanAttributes(spans []api.Span, max int) int {
+	total := 0
+	for i := range spans {
+		s := &spans[i]
+		n := truncateAll(max, &s.ResourceAttributes, &s.ScopeAttributes, &s.SpanAttributes)
+		s.DroppedAttributesCount += uint32(n)
+		total += n
+	}
+	return total
+}
+
+// LimitLogAttributes caps the resource, scope and log attributes of each log record at max
+// entries. Removed entries are added to the record's DroppedAttributesCount. Returns the
+// total number of entries removed.
+func LimitLogAttributes(logs []api.LogRecord, max int) int {
+	total := 0
+	for i := range logs {
+		l := &logs[i]
+		n := truncateAll(max, &l.ResourceAttributes, &l.ScopeAttributes, &l.LogAttributes)
+		l.DroppedAttributesCount += uint32(n)
+		total += n
+	}
+	return total
+}
+
+// LimitMetricAttributes caps the resource, scope and data point attributes of each metric
+// at max entries. Metrics have no dropped count column, so only the total number of entries
+// removed is returned.
+func LimitMetricAttributes(metrics []api.MetricDataPoint, max int) int {
+	total := 0
+	for i := range metrics {
+		m := &metrics[i]
+		total += truncateAll(max, &m.ResourceAttributes, &m.ScopeAttributes, &m.Attributes)
+	}
+	return total
+}
+
+func truncateAll(max int, maps ...*map[string]string) int {
+	total := 0
+	for _, attrs := range maps {
+		var n int
+		*attrs, n = TruncateAttributes(*attrs, max)
+		total += n
+	}
+	return total
+}
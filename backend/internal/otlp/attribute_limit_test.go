@@ -0,0 +1,57 @@
+package otlp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func manyAttributes(n int) map[string]string {
+	attrs := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		attrs[fmt.Sprintf("attr.%04d", i)] = "v"
+	}
+	return attrs
+}
+
+func TestTruncateAttributes(t *testing.T) {
+	attrs := manyAttributes(10)
+
+	kept, dropped := TruncateAttributes(attrs, 3)
+	if dropped != 7 || len(kept) != 3 {
+		t.Fatalf("expected 3 kept and 7 dropped, got %d kept and %d dropped", len(kept), dropped)
+	}
+	for _, key := range []string{"attr.0000", "attr.0001", "attr.0002"} {
+		if _, ok := kept[key]; !ok {
+			t.Errorf("expected %s to be kept, got %v", key, kept)
+		}
+	}
+
+	if kept, dropped := TruncateAttributes(attrs, 0); dropped != 0 || len(kept) != 10 {
+		t.Errorf("expected a limit of 0 to keep everything, got %d kept and %d dropped", len(kept), dropped)
+	}
+	if kept, dropped := TruncateAttributes(attrs, 10); dropped != 0 || len(kept) != 10 {
+		t.Errorf("expected maps at the limit to be unchanged, got %d kept and %d dropped", len(kept), dropped)
+	}
+}
+
+func TestLimitSpanAttributes(t *testing.T) {
+	spans := []api.Span{
+		{SpanAttributes: manyAttributes(5), ResourceAttributes: manyAttributes(4), DroppedAttributesCount: 1},
+		{SpanAttributes: manyAttributes(2)},
+	}
+
+	if total := LimitSpanAttributes(spans, 2); total != 5 {
+		t.Errorf("expected 5 attributes removed, got %d", total)
+	}
+	if len(spans[0].SpanAttributes) != 2 || len(spans[0].ResourceAttributes) != 2 {
+		t.Errorf("expected truncated maps, got %+v", spans[0])
+	}
+	if spans[0].DroppedAttributesCount != 6 {
+		t.Errorf("expected SDK and truncated drops to add up to 6, got %d", spans[0].DroppedAttributesCount)
+	}
+	if spans[1].DroppedAttributesCount != 0 {
+		t.Errorf("expected no drops on a span within the limit, got %d", spans[1].DroppedAttributesCount)
+	}
+}
@@ -1,6 +1,7 @@
 package otlp
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -12,6 +13,9 @@ import (
 	"github.com/tobilg/ai-observer/internal/logger"
 )
 
+// ErrUnsupportedContentType is returned for request encodings other than OTLP protobuf or JSON
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
 // Decoder interface for OTLP message decoding
 type Decoder interface {
 	DecodeTraces(r io.Reader) (*coltracepb.ExportTraceServiceRequest, error)
@@ -32,15 +36,20 @@ func GetDecoder(contentType string) (Decoder, error) {
 		// Default to protobuf per OTLP spec
 		return &ProtoDecoder{}, nil
 	default:
-		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
 	}
 }
 
 // GetDecoderWithDetection detects the actual format from the request body
 // and returns the appropriate decoder along with a new reader for the body.
 // This is useful when the Content-Type header doesn't match the actual content.
-// Also returns the detected format for logging purposes.
+// Also returns the detected format for logging purposes. Content types other than
+// protobuf, JSON or unset are rejected with ErrUnsupportedContentType.
 func GetDecoderWithDetection(r io.Reader, contentType string) (Decoder, io.Reader, Format, error) {
+	if contentType != "" && getExpectedFormat(contentType) == FormatUnknown {
+		return nil, nil, FormatUnknown, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+
 	format, newReader, err := DetectFormat(r)
 	if err != nil {
 		return nil, nil, FormatUnknown, fmt.Errorf("detecting format: %w", err)
@@ -2,6 +2,7 @@ package otlp
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"testing"
 )
@@ -143,6 +144,13 @@ func TestGetDecoderWithDetection(t *testing.T) {
 	}
 }
 
+func TestGetDecoderWithDetection_UnsupportedContentType(t *testing.T) {
+	_, _, _, err := GetDecoderWithDetection(bytes.NewReader([]byte(`{"resourceLogs":[]}`)), "text/plain")
+	if !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("expected ErrUnsupportedContentType, got %v", err)
+	}
+}
+
 func TestFormatString(t *testing.T) {
 	tests := []struct {
 		format Format
@@ -71,6 +71,8 @@ func ConvertLogs(req *collogspb.ExportLogsServiceRequest) LogConversionResult {
 					ScopeVersion:       scopeVersion,
 					ScopeAttributes:    scopeAttrs,
 					LogAttributes:      logAttrs,
+
+					DroppedAttributesCount: lr.GetDroppedAttributesCount(),
 				}
 
 				// If severity text is empty, derive from severity number
@@ -78,8 +80,6 @@ func ConvertLogs(req *collogspb.ExportLogsServiceRequest) LogConversionResult {
 					log.SeverityText = severityNumberToText(lr.GetSeverityNumber())
 				}
 
-				// Handle tracing crate's OpenTelemetryTracingBridge format:
-				// - event.name in attributes → Body
 				eventName, hasEventName := logAttrs["event.name"]
 
 				// Handle codex.sse_event events from Codex CLI:
@@ -93,10 +93,6 @@ func ConvertLogs(req *collogspb.ExportLogsServiceRequest) LogConversionResult {
 					continue // Skip storing raw log
 				}
 
-				if log.Body == "" && hasEventName {
-					log.Body = eventName
-				}
-
 				logs = append(logs, log)
 			}
 		}
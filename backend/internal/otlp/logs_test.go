@@ -236,6 +236,72 @@ func TestDecodeLogs_IntegerBody(t *testing.T) {
 	t.Logf("Integer body: %s, Double body: %s", logs[0].Body, logs[1].Body)
 }
 
+func TestConvertLogs_PreservesRawBody(t *testing.T) {
+	payload := `{
+		"resourceLogs": [{
+			"resource": {
+				"attributes": [
+					{"key": "service.name", "value": {"stringValue": "test-service"}}
+				]
+			},
+			"scopeLogs": [{
+				"scope": {"name": "test"},
+				"logRecords": [
+					{
+						"timeUnixNano": "1703500000000000000",
+						"severityNumber": 9,
+						"attributes": [
+							{"key": "event.name", "value": {"stringValue": "tool_call"}}
+						]
+					},
+					{
+						"timeUnixNano": "1703500001000000000",
+						"severityNumber": 9,
+						"body": {"stringValue": "  raw body\twith whitespace  "},
+						"attributes": [
+							{"key": "event.name", "value": {"stringValue": "tool_result"}},
+							{"key": "log.record.original", "value": {"stringValue": "<original line>"}}
+						]
+					}
+				]
+			}]
+		}]
+	}`
+
+	decoder, err := GetDecoder("application/json")
+	if err != nil {
+		t.Fatalf("Failed to get decoder: %v", err)
+	}
+
+	req, err := decoder.DecodeLogs(strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Failed to decode logs: %v", err)
+	}
+
+	logs := ConvertLogs(req).Logs
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(logs))
+	}
+
+	// The empty body is stored as received; the event name is only a display fallback
+	if logs[0].Body != "" {
+		t.Errorf("Expected raw empty body, got %q", logs[0].Body)
+	}
+	if got := logs[0].NormalizedBody(); got != "tool_call" {
+		t.Errorf("Expected normalized body 'tool_call', got %q", got)
+	}
+
+	if logs[1].Body != "  raw body\twith whitespace  " {
+		t.Errorf("Expected raw body to be preserved, got %q", logs[1].Body)
+	}
+	if got := logs[1].NormalizedBody(); got != logs[1].Body {
+		t.Errorf("Expected normalized body to equal non-empty raw body, got %q", got)
+	}
+	if logs[1].LogAttributes["log.record.original"] != "<original line>" {
+		t.Errorf("Expected log.record.original attribute to be preserved, got %q", logs[1].LogAttributes["log.record.original"])
+	}
+}
+
 func TestCodexEventTypes(t *testing.T) {
 	// Verify all documented Codex event types
 	eventTypes := map[string]string{
@@ -15,7 +15,6 @@ type MetricConversionResult struct {
 // ConvertMetrics converts OTLP metrics to internal metric format
 func ConvertMetrics(req *colmetricspb.ExportMetricsServiceRequest) MetricConversionResult {
 	var metrics []api.MetricDataPoint
-	var derivedMetrics []api.MetricDataPoint
 
 	for _, rm := range req.GetResourceMetrics() {
 		serviceName := extractServiceName(rm.GetResource().GetAttributes())
@@ -24,6 +23,7 @@ func ConvertMetrics(req *colmetricspb.ExportMetricsServiceRequest) MetricConvers
 		for _, sm := range rm.GetScopeMetrics() {
 			scopeName := sm.GetScope().GetName()
 			scopeVersion := sm.GetScope().GetVersion()
+			scopeAttrs := convertAttributes(sm.GetScope().GetAttributes())
 
 			for _, m := range sm.GetMetrics() {
 				baseMetric := api.MetricDataPoint{
@@ -34,6 +34,7 @@ func ConvertMetrics(req *colmetricspb.ExportMetricsServiceRequest) MetricConvers
 					ResourceAttributes: resourceAttrs,
 					ScopeName:          scopeName,
 					ScopeVersion:       scopeVersion,
+					ScopeAttributes:    scopeAttrs,
 				}
 
 				switch data := m.Data.(type) {
@@ -52,6 +53,15 @@ func ConvertMetrics(req *colmetricspb.ExportMetricsServiceRequest) MetricConvers
 		}
 	}
 
+	return MetricConversionResult{Metrics: metrics, DerivedMetrics: DeriveMetrics(metrics)}
+}
+
+// DeriveMetrics returns the metrics derived from converted metrics at ingestion: Gemini CLI
+// cost metrics and Claude Code user-facing metrics. Only points for which
+// IsDerivationSource is true contribute.
+func DeriveMetrics(metrics []api.MetricDataPoint) []api.MetricDataPoint {
+	var derivedMetrics []api.MetricDataPoint
+
 	// Derive Gemini cost metrics from token usage metrics
 	for _, m := range metrics {
 		if derived := DeriveGeminiCostMetric(m); derived != nil {
@@ -64,7 +74,16 @@ func ConvertMetrics(req *colmetricspb.ExportMetricsServiceRequest) MetricConvers
 	userFacingMetrics := DeriveClaudeUserFacingMetrics(metrics)
 	derivedMetrics = append(derivedMetrics, userFacingMetrics...)
 
-	return MetricConversionResult{Metrics: metrics, DerivedMetrics: derivedMetrics}
+	return derivedMetrics
+}
+
+// IsDerivationSource reports whether DeriveMetrics reads metrics named metricName
+func IsDerivationSource(metricName string) bool {
+	switch metricName {
+	case GeminiTokenUsageMetric, ClaudeTokenUsageMetric, ClaudeCostMetric:
+		return true
+	}
+	return false
 }
 
 func convertGauge(base api.MetricDataPoint, gauge *metricspb.Gauge) []api.MetricDataPoint {
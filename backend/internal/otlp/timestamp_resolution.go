@@ -0,0 +1,46 @@
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// RoundMetricTimestamps truncates metric timestamps to multiples of resolution and keeps only
+// the last point of each series (service, metric name and attributes) per rounded timestamp.
+// Input order is preserved for the surviving points. A resolution <= 0 returns metrics unchanged.
+func RoundMetricTimestamps(metrics []api.MetricDataPoint, resolution time.Duration) []api.MetricDataPoint {
+	if resolution <= 0 || len(metrics) == 0 {
+		return metrics
+	}
+
+	last := make(map[string]int, len(metrics))
+	keys := make([]string, len(metrics))
+	for i := range metrics {
+		metrics[i].Timestamp = metrics[i].Timestamp.Truncate(resolution)
+		keys[i] = seriesBucketKey(metrics[i])
+		last[keys[i]] = i
+	}
+
+	if len(last) == len(metrics) {
+		return metrics
+	}
+
+	result := make([]api.MetricDataPoint, 0, len(last))
+	for i, m := range metrics {
+		if last[keys[i]] == i {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// seriesBucketKey identifies a metric series (service, name, type and attributes) at a
+// single timestamp
+func seriesBucketKey(m api.MetricDataPoint) string {
+	// json.Marshal sorts map keys, so equal attribute maps produce equal keys
+	attrs, _ := json.Marshal(m.Attributes)
+	return m.ServiceName + "\x00" + m.MetricName + "\x00" + m.MetricType + "\x00" + string(attrs) + "\x00" + strconv.FormatInt(m.Timestamp.UnixNano(), 10)
+}
@@ -0,0 +1,76 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestRoundMetricTimestamps(t *testing.T) {
+	base := time.Unix(1609459200, 0)
+	value := func(v float64) *float64 { return &v }
+	point := func(name, model string, offset time.Duration, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			ServiceName: "svc",
+			MetricName:  name,
+			Timestamp:   base.Add(offset),
+			Attributes:  map[string]string{"model": model},
+			Value:       value(v),
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		point("tokens", "a", 100*time.Millisecond, 1),
+		point("tokens", "b", 200*time.Millisecond, 10),
+		point("tokens", "a", 300*time.Millisecond, 2),
+		point("tokens", "a", 1100*time.Millisecond, 3),
+		point("cost", "a", 400*time.Millisecond, 5),
+	}
+
+	got := RoundMetricTimestamps(metrics, time.Second)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 points, got %d", len(got))
+	}
+
+	want := []struct {
+		name  string
+		model string
+		ts    time.Time
+		value float64
+	}{
+		{"tokens", "b", base, 10},
+		{"tokens", "a", base, 2},
+		{"tokens", "a", base.Add(time.Second), 3},
+		{"cost", "a", base, 5},
+	}
+	for i, w := range want {
+		m := got[i]
+		if m.MetricName != w.name || m.Attributes["model"] != w.model || !m.Timestamp.Equal(w.ts) || *m.Value != w.value {
+			t.Errorf("point %d = %s/%s %v %v, want %s/%s %v %v", i,
+				m.MetricName, m.Attributes["model"], m.Timestamp, *m.Value, w.name, w.model, w.ts, w.value)
+		}
+	}
+}
+
+func TestRoundMetricTimestamps_Disabled(t *testing.T) {
+	ts := time.Unix(1609459200, 123)
+	metrics := []api.MetricDataPoint{{MetricName: "m", Timestamp: ts}, {MetricName: "m", Timestamp: ts}}
+
+	got := RoundMetricTimestamps(metrics, 0)
+	if len(got) != 2 || !got[0].Timestamp.Equal(ts) {
+		t.Errorf("expected metrics unchanged, got %+v", got)
+	}
+}
+
+func TestRoundMetricTimestamps_KeepsMetricTypes(t *testing.T) {
+	ts := time.Unix(1609459200, 0)
+	metrics := []api.MetricDataPoint{
+		{MetricName: "m", MetricType: "gauge", Timestamp: ts},
+		{MetricName: "m", MetricType: "sum", Timestamp: ts},
+	}
+
+	if got := RoundMetricTimestamps(metrics, time.Second); len(got) != 2 {
+		t.Errorf("expected a gauge and a sum of the same name to both be kept, got %+v", got)
+	}
+}
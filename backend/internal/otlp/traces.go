@@ -22,6 +22,7 @@ func ConvertTraces(req *coltracepb.ExportTraceServiceRequest) []api.Span {
 		for _, ss := range rs.GetScopeSpans() {
 			scopeName := ss.GetScope().GetName()
 			scopeVersion := ss.GetScope().GetVersion()
+			scopeAttrs := convertAttributes(ss.GetScope().GetAttributes())
 
 			for _, s := range ss.GetSpans() {
 				span := api.Span{
@@ -36,12 +37,17 @@ func ConvertTraces(req *coltracepb.ExportTraceServiceRequest) []api.Span {
 					ResourceAttributes: resourceAttrs,
 					ScopeName:          scopeName,
 					ScopeVersion:       scopeVersion,
+					ScopeAttributes:    scopeAttrs,
 					SpanAttributes:     convertAttributes(s.GetAttributes()),
 					Duration:           int64(s.GetEndTimeUnixNano() - s.GetStartTimeUnixNano()),
 					StatusCode:         statusCodeToString(s.GetStatus().GetCode()),
 					StatusMessage:      s.GetStatus().GetMessage(),
 					Events:             convertEvents(s.GetEvents()),
 					Links:              convertLinks(s.GetLinks()),
+
+					DroppedAttributesCount: s.GetDroppedAttributesCount(),
+					DroppedEventsCount:     s.GetDroppedEventsCount(),
+					DroppedLinksCount:      s.GetDroppedLinksCount(),
 				}
 				spans = append(spans, span)
 			}
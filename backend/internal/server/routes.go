@@ -9,57 +9,138 @@ import (
 
 func (s *Server) setupRoutes(h *handlers.Handlers) error {
 	// OTLP ingestion endpoints (port 4318)
-	s.otlpRouter.Route("/v1", func(r chi.Router) {
-		r.Post("/traces", h.HandleTraces)
-		r.Post("/metrics", h.HandleMetrics)
-		r.Post("/logs", h.HandleLogs)
+	s.otlpRouter.Group(func(r chi.Router) {
+		r.Use(h.IngestPauseMiddleware)
+		r.Post("/v1/traces", h.HandleTraces)
+		r.Post("/v1/metrics", h.HandleMetrics)
+		r.Post("/v1/logs", h.HandleLogs)
+
+		// Handle POST / for clients that don't append signal paths (e.g., Gemini CLI)
+		r.Post("/", h.HandleRoot)
 	})
 	s.otlpRouter.Get("/health", h.Health)
 
-	// Handle POST / for clients that don't append signal paths (e.g., Gemini CLI)
-	s.otlpRouter.Post("/", h.HandleRoot)
+	// Prometheus scrape endpoint for stored metrics (port 4318; /metrics on port 8080 is a
+	// frontend page)
+	s.otlpRouter.Get("/metrics", h.PrometheusMetrics)
 
 	// Query API for frontend (port 8080)
 	s.apiRouter.Route("/api", func(r chi.Router) {
-		// Traces
-		r.Get("/traces", h.QueryTraces)
-		r.Get("/traces/recent", h.QueryRecentTraces)
-		r.Get("/traces/{traceId}", h.GetTrace)
-		r.Get("/traces/{traceId}/spans", h.GetTraceSpans)
-
-		// Metrics
-		r.Get("/metrics", h.QueryMetrics)
-		r.Get("/metrics/names", h.ListMetricNames)
-		r.Get("/metrics/breakdown-values", h.GetBreakdownValues)
-		r.Get("/metrics/series", h.QueryMetricSeries)
-		r.Post("/metrics/batch-series", h.QueryBatchMetricSeries)
-
-		// Logs
-		r.Get("/logs", h.QueryLogs)
-		r.Get("/logs/levels", h.GetLogLevels)
-
-		// Sessions
-		r.Get("/sessions", h.QuerySessions)
-		r.Get("/sessions/{sessionId}/transcript", h.GetSessionTranscript)
-
-		// Services
-		r.Get("/services", h.ListServices)
-
-		// Stats
-		r.Get("/stats", h.GetStats)
-
-		// Dashboards
-		r.Get("/dashboards", h.ListDashboards)
-		r.Post("/dashboards", h.CreateDashboard)
-		r.Get("/dashboards/default", h.GetDefaultDashboard)
-		r.Get("/dashboards/{id}", h.GetDashboard)
-		r.Put("/dashboards/{id}", h.UpdateDashboard)
-		r.Delete("/dashboards/{id}", h.DeleteDashboard)
-		r.Put("/dashboards/{id}/default", h.SetDefaultDashboard)
-		r.Post("/dashboards/{id}/widgets", h.CreateWidget)
-		r.Put("/dashboards/{id}/widgets/positions", h.UpdateWidgetPositions)
-		r.Put("/dashboards/{id}/widgets/{widgetId}", h.UpdateWidget)
-		r.Delete("/dashboards/{id}/widgets/{widgetId}", h.DeleteWidget)
+		// Ingest control and bulk ingestion stay outside the query limit, so an operator can
+		// resume ingestion and uploads do not hold query slots while dashboards are busy
+		r.Get("/admin/ingest", h.GetIngestState)
+		r.Post("/admin/ingest/pause", h.PauseIngest)
+		r.Post("/admin/ingest/resume", h.ResumeIngest)
+		r.With(h.IngestPauseMiddleware).Post("/ingest/upload", h.UploadIngest)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.QueryLimitMiddleware)
+
+			// Traces
+			r.Get("/traces", h.QueryTraces)
+			r.Get("/traces/recent", h.QueryRecentTraces)
+			r.Get("/traces/count", h.CountTraces)
+			r.Get("/traces/kinds", h.GetSpanKinds)
+			r.Get("/traces/error-rate-series", h.QueryErrorRateSeries)
+			r.Get("/traces/{traceId}", h.GetTrace)
+			r.Get("/traces/{traceId}/spans", h.GetTraceSpans)
+			r.Get("/traces/{traceId}/session", h.GetTraceSession)
+			r.Get("/traces/{traceId}/annotations", h.GetTraceAnnotations)
+			r.Post("/traces/{traceId}/annotations", h.AnnotateTrace)
+
+			// Metrics
+			r.Get("/metrics", h.QueryMetrics)
+			r.Get("/metrics/count", h.CountMetrics)
+			r.Get("/metrics/names", h.ListMetricNames)
+			r.Get("/metrics/breakdown-values", h.GetBreakdownValues)
+			r.Get("/metrics/breakdown", h.GetMetricBreakdown)
+			r.Get("/metrics/table", h.GetMetricTable)
+			r.Get("/metrics/series", h.QueryMetricSeries)
+			r.Post("/metrics/batch-series", h.QueryBatchMetricSeries)
+			r.Post("/metrics/validate", h.ValidateMetric)
+			r.Get("/metrics/cache-hit-ratio", h.GetCacheHitRatio)
+			r.Get("/metrics/delta", h.GetMetricDelta)
+			r.Get("/metrics/histogram", h.GetMetricHistogram)
+
+			// Cost
+			r.Get("/cost/by-project", h.GetCostByProject)
+			r.Get("/cost/by-model", h.GetCostByModel)
+
+			// Analytics
+			r.Get("/analytics/latency-cost", h.GetLatencyCost)
+			r.Get("/analytics/efficiency", h.GetEfficiency)
+
+			// Prompts
+			r.Get("/prompts/cost", h.GetPromptCost)
+
+			// Logs
+			r.Get("/logs", h.QueryLogs)
+			r.Get("/logs/count", h.CountLogs)
+			r.Get("/logs/levels", h.GetLogLevels)
+			r.Post("/logs/annotations", h.AnnotateLog)
+
+			// Annotations
+			r.Get("/annotations", h.ListAnnotations)
+			r.Delete("/annotations/{id}", h.DeleteAnnotation)
+
+			// Alerts
+			r.Get("/alerts/rules", h.ListAlertRules)
+			r.Post("/alerts/rules", h.CreateAlertRule)
+			r.Get("/alerts/rules/{id}", h.GetAlertRule)
+			r.Put("/alerts/rules/{id}", h.UpdateAlertRule)
+			r.Delete("/alerts/rules/{id}", h.DeleteAlertRule)
+			r.Get("/alerts/events", h.ListAlertEvents)
+
+			// Attributes
+			r.Get("/attributes/cardinality", h.GetAttributeCardinality)
+
+			// Correlation
+			r.Get("/correlation/gaps", h.GetCorrelationGaps)
+
+			// Sessions
+			r.Get("/sessions", h.QuerySessions)
+			r.Get("/sessions/activity", h.GetSessionActivity)
+			r.Get("/sessions/{sessionId}/transcript", h.GetSessionTranscript)
+			r.Get("/sessions/{sessionId}/cost", h.GetSessionCost)
+
+			// Services
+			r.Get("/services", h.ListServices)
+			r.Get("/services/latency", h.GetServiceLatency)
+			r.Get("/services/graph", h.GetServiceGraph)
+			r.Get("/services/{name}/summary", h.GetServiceSummary)
+			r.Get("/time-range", h.GetDataTimeRange)
+
+			// Instrumentation scopes
+			r.Get("/scopes", h.ListScopes)
+
+			// Stats
+			r.Get("/stats", h.GetStats)
+			r.Get("/overview", h.GetOverview)
+			r.Get("/self/storage", h.GetStorageUsage)
+			r.Get("/dropped", h.GetDroppedCounts)
+			r.Get("/version", h.GetVersion)
+
+			// Admin
+			r.Get("/admin/derive/preview", h.PreviewDerivedMetrics)
+
+			// Dashboards
+			r.Get("/dashboards", h.ListDashboards)
+			r.Post("/dashboards", h.CreateDashboard)
+			r.Get("/dashboards/default", h.GetDefaultDashboard)
+			r.Post("/dashboards/import", h.ImportDashboard)
+			r.Post("/dashboards/from-template", h.CreateDashboardFromTemplate)
+			r.Get("/dashboards/{id}", h.GetDashboard)
+			r.Get("/dashboards/{id}/export", h.ExportDashboard)
+			r.Put("/dashboards/{id}", h.UpdateDashboard)
+			r.Delete("/dashboards/{id}", h.DeleteDashboard)
+			r.Put("/dashboards/{id}/default", h.SetDefaultDashboard)
+			r.Post("/dashboards/{id}/widgets", h.CreateWidget)
+			r.Put("/dashboards/{id}/widgets/positions", h.UpdateWidgetPositions)
+			r.Put("/dashboards/{id}/widgets/{widgetId}", h.UpdateWidget)
+			r.Delete("/dashboards/{id}/widgets/{widgetId}", h.DeleteWidget)
+			r.Get("/dashboards/{id}/widgets/{widgetId}/data", h.GetWidgetData)
+			r.Get("/dashboards/{id}/variables/{name}/options", h.GetVariableOptions)
+		})
 	})
 
 	// WebSocket for real-time updates (port 8080)
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -10,8 +11,11 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/tobilg/ai-observer/internal/alerts"
 	"github.com/tobilg/ai-observer/internal/config"
+	"github.com/tobilg/ai-observer/internal/deleter"
 	"github.com/tobilg/ai-observer/internal/handlers"
+	"github.com/tobilg/ai-observer/internal/importer"
 	"github.com/tobilg/ai-observer/internal/logger"
 	appMiddleware "github.com/tobilg/ai-observer/internal/middleware"
 	"github.com/tobilg/ai-observer/internal/storage"
@@ -19,6 +23,8 @@ import (
 	"github.com/tobilg/ai-observer/pkg/compression"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip" // OTLP exporters may send gzip-compressed gRPC messages
 )
 
 type Server struct {
@@ -28,18 +34,41 @@ type Server struct {
 	wsHub      *websocket.Hub
 	config     *config.Config
 
+	// Cancels background work such as storage usage sampling, retention and alert evaluation
+	stopBackground context.CancelFunc
+
+	// Background OTLP writer when async ingestion or insert batching is enabled, drained on
+	// shutdown
+	asyncIngest *handlers.AsyncIngest
+
+	// OTLP/gRPC server (port 4317), nil when disabled
+	grpcServer *grpc.Server
+
 	// HTTP servers for graceful shutdown
 	otlpServer *http.Server
 	apiServer  *http.Server
 	mu         sync.Mutex
 }
 
+// retentionInterval is how often telemetry older than AI_OBSERVER_RETENTION_DAYS is pruned
+const retentionInterval = time.Hour
+
+// logSearchIndexInterval is how often the full-text log search index checks whether enough logs
+// were added to rebuild it
+const logSearchIndexInterval = 5 * time.Minute
+
 func New(cfg *config.Config) (*Server, error) {
 	store, err := storage.NewDuckDBStore(cfg.DatabasePath)
 	if err != nil {
 		return nil, fmt.Errorf("initializing storage: %w", err)
 	}
 
+	if err := store.ApplySettings(context.Background(), cfg.DBPragmas); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("applying database settings: %w", err)
+	}
+	store.EnableLogSearch(context.Background())
+
 	hub := websocket.NewHub()
 	go hub.Run()
 
@@ -57,13 +86,106 @@ func New(cfg *config.Config) (*Server, error) {
 	s.setupMiddleware()
 
 	h := handlers.New(store, hub)
+	h.SetEnvLabel(cfg.EnvLabel)
+	h.SetMetricFilter(cfg.MetricAllowlist, cfg.MetricDenylist)
+	h.SetLogSampleRate(cfg.LogSampleInfo)
+	h.SetModelAliases(cfg.ModelAliases)
+	h.SetServiceGroups(cfg.ServiceGroups)
+	h.SetIngestWeights(cfg.IngestWeights)
+	h.SetMaxAttributes(cfg.MaxAttributes)
+	h.SetMetricTimestampResolution(cfg.MetricTimestampResolution)
+	h.SetIngestSignals(cfg.IngestSignals)
+	h.SetSessionIdleTimeout(cfg.SessionIdleTimeout)
+	h.SetDashboardFallback(cfg.DashboardFallback)
+	h.SetDashboardLimits(cfg.MaxDashboards, cfg.MaxWidgetsPerDashboard)
+	h.SetDedupWindow(cfg.DedupWindow)
+	h.SetCostCurrency(cfg.Currency, cfg.ExchangeRate)
+	h.SetMaxConcurrentQueries(cfg.MaxConcurrentQueries, cfg.QueryQueueTimeout)
+	if cfg.IngestBatchRows > 0 {
+		s.asyncIngest = h.EnableBatchedIngest(cfg.IngestBatchRows, cfg.IngestBatchInterval)
+	} else if cfg.AsyncIngest {
+		s.asyncIngest = h.EnableAsyncIngest()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+
+	if cfg.StorageSampleInterval > 0 {
+		sampler := storage.NewUsageSampler(store, cfg.StorageSamples)
+		h.SetUsageSampler(sampler)
+		go sampler.Run(ctx, cfg.StorageSampleInterval)
+	}
+
+	if cfg.RetentionDays > 0 {
+		go deleter.NewRetention(store, cfg.RetentionDays).Run(ctx, retentionInterval)
+	}
+
+	if cfg.AlertInterval > 0 {
+		go alerts.NewEvaluator(store, hub).Run(ctx, cfg.AlertInterval)
+	}
+
+	go store.RunLogSearchIndexer(ctx, logSearchIndexInterval)
+
+	startSources := importSources(cfg.ImportOnStart)
+	watchSources := importSources(cfg.WatchImport)
+	if len(startSources) > 0 || len(watchSources) > 0 {
+		imp := importer.NewImporter(store, false)
+		imp.RegisterAllParsers()
+		imp.SetWriter(h.ImportWriter())
+		opts := importer.Options{MaxAttrs: cfg.MaxAttributes}
+
+		// The startup import finishes before New returns, so the UI has data from the start.
+		// Failures are logged and never keep the server from starting.
+		if len(startSources) > 0 {
+			start := time.Now()
+			summary, err := imp.Sync(ctx, startSources, opts)
+			if err != nil {
+				logger.Warn("Startup import stopped", "error", err)
+			}
+			logger.Info("Startup import complete",
+				"files", summary.Files, "records", summary.Records, "failed", summary.Failed,
+				"duration", time.Since(start))
+		}
+
+		if len(watchSources) > 0 {
+			go func() {
+				if err := imp.Watch(ctx, watchSources, opts); err != nil {
+					logger.Warn("Session file watcher stopped", "error", err)
+				}
+			}()
+		}
+	}
+
 	if err := s.setupRoutes(h); err != nil {
 		return nil, fmt.Errorf("setting up routes: %w", err)
 	}
 
+	if cfg.OTLPGRPCPort > 0 {
+		s.grpcServer = grpc.NewServer(
+			grpc.MaxRecvMsgSize(int(appMiddleware.MaxPayloadBytes)),
+			grpc.UnaryInterceptor(h.GRPCPauseInterceptor),
+		)
+		h.RegisterGRPC(s.grpcServer)
+	}
+
 	return s, nil
 }
 
+// importSources parses the tools of AI_OBSERVER_WATCH_IMPORT or AI_OBSERVER_IMPORT_ON_START,
+// where "all" selects every tool; unknown tools are ignored
+func importSources(tools []string) []importer.SourceType {
+	var sources []importer.SourceType
+	for _, tool := range tools {
+		parsed, err := importer.ParseToolArg(tool)
+		if err != nil {
+			logger.Warn("Ignoring unknown import tool", "tool", tool)
+			continue
+		}
+		sources = append(sources, parsed...)
+	}
+	return sources
+}
+
 func (s *Server) setupMiddleware() {
 	// Common middleware for both routers
 	for _, router := range []chi.Router{s.otlpRouter, s.apiRouter} {
@@ -71,10 +193,12 @@ func (s *Server) setupMiddleware() {
 		router.Use(middleware.RealIP)
 		router.Use(RequestLogger)
 		router.Use(middleware.Recoverer)
+		router.Use(appMiddleware.EnvLabelMiddleware(s.config.EnvLabel))
+		router.Use(appMiddleware.DisabledEndpointsMiddleware(s.config.DisabledEndpoints, s.config.DisabledEndpointStatus))
 	}
 
-	// OTLP router needs gzip decompression for clients that compress payloads
-	s.otlpRouter.Use(compression.GzipDecompressMiddleware)
+	// OTLP router decompresses gzip and zstd payloads, bounded like uncompressed ones
+	s.otlpRouter.Use(compression.DecompressMiddleware(appMiddleware.MaxPayloadBytes))
 
 	// OTLP router has 10MB payload size limit
 	s.otlpRouter.Use(appMiddleware.DefaultPayloadLimitMiddleware)
@@ -84,11 +208,16 @@ func (s *Server) setupMiddleware() {
 		AllowedOrigins:   []string{s.config.FrontendURL, "http://localhost:5173", "http://localhost:8080"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Encoding", "X-Requested-With"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", appMiddleware.EnvLabelHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Compress large API responses; OTLP responses are small and exporters rarely accept it
+	if s.config.CompressMinBytes > 0 {
+		s.apiRouter.Use(compression.ResponseCompressionMiddleware(s.config.CompressMinBytes))
+	}
+
 	// Add context timeout for API requests (skips WebSocket upgrade requests)
 	// Handlers should check context.Done() to respect timeout
 	s.apiRouter.Use(appMiddleware.DefaultContextTimeoutMiddleware)
@@ -125,6 +254,27 @@ func (s *Server) ListenAndServe() error {
 		}
 	}()
 
+	// Start OTLP/gRPC server
+	if s.grpcServer != nil {
+		grpcAddr := fmt.Sprintf(":%d", s.config.OTLPGRPCPort)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return fmt.Errorf("listening for OTLP/gRPC: %w", err)
+		}
+
+		go func() {
+			log.Info("OTLP gRPC server starting",
+				"addr", grpcAddr,
+				"protocol", "gRPC",
+				"services", "TraceService, MetricsService, LogsService",
+			)
+
+			if err := s.grpcServer.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+				log.Error("OTLP gRPC server error", "error", err)
+			}
+		}()
+	}
+
 	// Create API server
 	apiAddr := fmt.Sprintf(":%d", s.config.APIPort)
 	h2sAPI := &http2.Server{}
@@ -177,6 +327,28 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}()
 	}
 
+	// Stop the OTLP/gRPC server, waiting for in-flight exports until ctx ends
+	if s.grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Shutting down OTLP gRPC server")
+			stopped := make(chan struct{})
+			go func() {
+				s.grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				s.grpcServer.Stop()
+				errMu.Lock()
+				errs = append(errs, fmt.Errorf("shutting down OTLP gRPC server: %w", ctx.Err()))
+				errMu.Unlock()
+			}
+		}()
+	}
+
 	// Shutdown API server
 	if apiServer != nil {
 		wg.Add(1)
@@ -191,9 +363,31 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}()
 	}
 
+	// Close WebSocket connections, which HTTP server shutdown does not track
+	if s.wsHub != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Closing WebSocket connections", "clients", s.wsHub.ClientCount())
+			s.wsHub.Shutdown(ctx)
+		}()
+	}
+
 	// Wait for servers to shutdown
 	wg.Wait()
 
+	// Store batches that were acknowledged but not yet written, including buffered rows
+	if s.asyncIngest != nil {
+		logger.Info("Draining async ingestion queue", "pending", s.asyncIngest.Pending())
+		if err := s.asyncIngest.Drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("draining async ingestion: %w", err))
+		}
+	}
+
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+
 	// Close storage
 	if err := s.storage.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("closing storage: %w", err))
@@ -2,13 +2,17 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/tobilg/ai-observer/internal/config"
+	"github.com/tobilg/ai-observer/internal/importer"
 )
 
 // getTestConfig returns a config with test-appropriate ports
@@ -263,3 +267,177 @@ func TestServerConcurrentRequests(t *testing.T) {
 	defer cancel()
 	server.Shutdown(ctx)
 }
+
+func TestServerEnvLabel(t *testing.T) {
+	cfg := getTestConfig(t)
+	cfg.EnvLabel = "staging"
+
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.storage.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	server.apiRouter.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-AI-Observer-Env"); got != "staging" {
+		t.Errorf("X-AI-Observer-Env header = %q, want %q", got, "staging")
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp["env"] != "staging" {
+		t.Errorf("health env = %q, want %q", resp["env"], "staging")
+	}
+}
+
+func TestServerDisabledEndpoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"not found", http.StatusNotFound},
+		{"forbidden", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := getTestConfig(t)
+			cfg.DisabledEndpoints = []string{"/api/traces/{traceId}/session", "/api/metrics/breakdown"}
+			cfg.DisabledEndpointStatus = tt.status
+
+			server, err := New(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create server: %v", err)
+			}
+			defer server.storage.Close()
+
+			for _, path := range []string{"/api/traces/abc123/session", "/api/metrics/breakdown/"} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				rec := httptest.NewRecorder()
+				server.apiRouter.ServeHTTP(rec, req)
+
+				if rec.Code != tt.status {
+					t.Errorf("GET %s: expected status %d, got %d", path, tt.status, rec.Code)
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/traces", nil)
+			rec := httptest.NewRecorder()
+			server.apiRouter.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("GET /api/traces: expected status 200, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestServerResponseCompression(t *testing.T) {
+	cfg := getTestConfig(t)
+	cfg.CompressMinBytes = 1
+
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.storage.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/traces", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	server.apiRouter.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("API Content-Encoding = %q, want gzip", got)
+	}
+
+	// OTLP ingestion responses are never compressed
+	req = httptest.NewRequest(http.MethodPost, "/v1/traces", strings.NewReader(`{"resourceSpans":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	server.otlpRouter.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 from OTLP, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("OTLP Content-Encoding = %q, want none", got)
+	}
+}
+
+func TestServerEnvLabelUnset(t *testing.T) {
+	cfg := getTestConfig(t)
+
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.storage.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	server.apiRouter.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-AI-Observer-Env"); got != "" {
+		t.Errorf("X-AI-Observer-Env header = %q, want empty", got)
+	}
+}
+
+func TestServerImportOnStart(t *testing.T) {
+	sessions := t.TempDir()
+	t.Setenv("AI_OBSERVER_CLAUDE_PATH", sessions)
+	line := `{"type":"assistant","timestamp":"2025-01-02T10:00:00.000Z","sessionId":"boot-session",` +
+		`"costUSD":0.01,"message":{"id":"msg-001","model":"claude-sonnet-4-20250514","role":"assistant",` +
+		`"type":"message","usage":{"input_tokens":100,"output_tokens":50}}}` + "\n"
+	if err := os.WriteFile(filepath.Join(sessions, "boot-session.jsonl"), []byte(line), 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+
+	cfg := getTestConfig(t)
+	cfg.ImportOnStart = []string{"claude-code"}
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.storage.Close()
+
+	// The session is stored by the time New returns, before any request is served
+	var logs int
+	if err := server.storage.DB().QueryRow("SELECT COUNT(*) FROM otel_logs").Scan(&logs); err != nil {
+		t.Fatalf("Failed to count logs: %v", err)
+	}
+	if logs != 1 {
+		t.Errorf("Expected 1 imported log, got %d", logs)
+	}
+	state, err := server.storage.GetImportState(context.Background(), string(importer.SourceClaude), filepath.Join(sessions, "boot-session.jsonl"))
+	if err != nil {
+		t.Fatalf("GetImportState failed: %v", err)
+	}
+	if state == nil {
+		t.Error("Expected the session file to be recorded as imported")
+	}
+}
+
+func TestServerImportOnStartFailure(t *testing.T) {
+	t.Setenv("AI_OBSERVER_CLAUDE_PATH", filepath.Join(t.TempDir(), "missing"))
+
+	cfg := getTestConfig(t)
+	cfg.ImportOnStart = []string{"claude-code", "unknown-tool"}
+	server, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Startup import failures must not stop the server: %v", err)
+	}
+	defer server.storage.Close()
+}
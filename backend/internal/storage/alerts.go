@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+const alertRuleColumns = "id, name, metric_name, service, comparison, threshold, window_seconds, created_at, updated_at"
+
+func scanAlertRule(row interface{ Scan(...interface{}) error }) (*api.AlertRule, error) {
+	var r api.AlertRule
+	var service sql.NullString
+	if err := row.Scan(&r.ID, &r.Name, &r.MetricName, &service, &r.Comparison, &r.Threshold, &r.WindowSeconds, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.Service = service.String
+	return &r, nil
+}
+
+// CreateAlertRule stores a new alert rule
+func (s *DuckDBStore) CreateAlertRule(ctx context.Context, req *api.AlertRuleRequest) (*api.AlertRule, error) {
+	now := time.Now()
+	r := &api.AlertRule{
+		ID:            uuid.New().String(),
+		Name:          req.Name,
+		MetricName:    req.MetricName,
+		Service:       req.Service,
+		Comparison:    req.Comparison,
+		Threshold:     req.Threshold,
+		WindowSeconds: req.WindowSeconds,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_rules (`+alertRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.Name, r.MetricName, nullString(r.Service), r.Comparison, r.Threshold, r.WindowSeconds, r.CreatedAt, r.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("inserting alert rule: %w", err)
+	}
+	return r, nil
+}
+
+// ListAlertRules returns all alert rules ordered by name
+func (s *DuckDBStore) ListAlertRules(ctx context.Context) ([]api.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		ORDER BY name, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []api.AlertRule{}
+	for rows.Next() {
+		r, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning alert rule: %w", err)
+		}
+		rules = append(rules, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetAlertRule returns an alert rule, or nil when it does not exist
+func (s *DuckDBStore) GetAlertRule(ctx context.Context, id string) (*api.AlertRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, err := scanAlertRule(s.db.QueryRowContext(ctx, "SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying alert rule: %w", err)
+	}
+	return r, nil
+}
+
+// UpdateAlertRule replaces the fields of an alert rule and returns it, or nil when it does
+// not exist
+func (s *DuckDBStore) UpdateAlertRule(ctx context.Context, id string, req *api.AlertRuleRequest) (*api.AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE alert_rules
+		SET name = ?, metric_name = ?, service = ?, comparison = ?, threshold = ?, window_seconds = ?, updated_at = ?
+		WHERE id = ?
+	`, req.Name, req.MetricName, nullString(req.Service), req.Comparison, req.Threshold, req.WindowSeconds, time.Now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("updating alert rule: %w", err)
+	}
+
+	r, err := scanAlertRule(s.db.QueryRowContext(ctx, "SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching updated alert rule: %w", err)
+	}
+	return r, nil
+}
+
+// DeleteAlertRule deletes an alert rule and reports whether it existed. Its events are kept.
+func (s *DuckDBStore) DeleteAlertRule(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("deleting alert rule: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("deleting alert rule: %w", err)
+	}
+	return n > 0, nil
+}
+
+// InsertAlertEvent records a fired alert, assigning its ID when unset
+func (s *DuckDBStore) InsertAlertEvent(ctx context.Context, e *api.AlertEvent) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO alert_events (id, rule_id, rule_name, metric_name, service, comparison, threshold, window_seconds, value, fired_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.ID, e.RuleID, e.RuleName, e.MetricName, nullString(e.Service), e.Comparison, e.Threshold, e.WindowSeconds, e.Value, formatTimeForDB(e.FiredAt))
+	if err != nil {
+		return fmt.Errorf("inserting alert event: %w", err)
+	}
+	return nil
+}
+
+// ListAlertEvents returns the alerts fired within [from, to], newest first, optionally only
+// those of one rule. limit caps the number of events when positive.
+func (s *DuckDBStore) ListAlertEvents(ctx context.Context, ruleID string, from, to time.Time, limit int) ([]api.AlertEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT id, rule_id, rule_name, metric_name, service, comparison, threshold, window_seconds, value, fired_at
+		FROM alert_events
+		WHERE fired_at >= ?::TIMESTAMP AND fired_at <= ?::TIMESTAMP
+	`
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	if ruleID != "" {
+		query += " AND rule_id = ?"
+		args = append(args, ruleID)
+	}
+	query += " ORDER BY fired_at DESC, id"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying alert events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []api.AlertEvent{}
+	for rows.Next() {
+		var e api.AlertEvent
+		var service sql.NullString
+		if err := rows.Scan(&e.ID, &e.RuleID, &e.RuleName, &e.MetricName, &service, &e.Comparison, &e.Threshold, &e.WindowSeconds, &e.Value, &e.FiredAt); err != nil {
+			return nil, fmt.Errorf("scanning alert event: %w", err)
+		}
+		e.Service = service.String
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating alert events: %w", err)
+	}
+	return events, nil
+}
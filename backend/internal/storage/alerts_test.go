@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestAlertRules(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	req := &api.AlertRuleRequest{Name: "Cost per hour", MetricName: "claude_code.cost.usage", Comparison: ">", Threshold: 5, WindowSeconds: 3600}
+	rule, err := store.CreateAlertRule(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	if rule.ID == "" || rule.Name != "Cost per hour" || rule.Threshold != 5 || rule.WindowSeconds != 3600 {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+
+	if _, err := store.CreateAlertRule(ctx, &api.AlertRuleRequest{Name: "A tokens", MetricName: "tokens", Service: "codex_cli_rs", Comparison: "<", Threshold: 1, WindowSeconds: 60}); err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+
+	rules, err := store.ListAlertRules(ctx)
+	if err != nil {
+		t.Fatalf("ListAlertRules failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "A tokens" || rules[0].Service != "codex_cli_rs" || rules[1].Service != "" {
+		t.Fatalf("expected both rules by name, got %+v", rules)
+	}
+
+	req.Threshold = 10
+	req.Service = "claude-code"
+	updated, err := store.UpdateAlertRule(ctx, rule.ID, req)
+	if err != nil {
+		t.Fatalf("UpdateAlertRule failed: %v", err)
+	}
+	if updated == nil || updated.Threshold != 10 || updated.Service != "claude-code" {
+		t.Errorf("expected the updated rule, got %+v", updated)
+	}
+	if got, err := store.GetAlertRule(ctx, rule.ID); err != nil || got == nil || got.Threshold != 10 {
+		t.Errorf("GetAlertRule = %+v, %v", got, err)
+	}
+
+	if missing, err := store.UpdateAlertRule(ctx, "missing", req); err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown rule, got %+v, %v", missing, err)
+	}
+	if missing, err := store.GetAlertRule(ctx, "missing"); err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown rule, got %+v, %v", missing, err)
+	}
+
+	if deleted, err := store.DeleteAlertRule(ctx, rule.ID); err != nil || !deleted {
+		t.Errorf("DeleteAlertRule = %v, %v, want true", deleted, err)
+	}
+	if deleted, err := store.DeleteAlertRule(ctx, rule.ID); err != nil || deleted {
+		t.Errorf("DeleteAlertRule of a deleted rule = %v, %v, want false", deleted, err)
+	}
+}
+
+func TestAlertEvents(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	events := []api.AlertEvent{
+		{RuleID: "r1", RuleName: "Cost", MetricName: "cost", Comparison: ">", Threshold: 5, WindowSeconds: 3600, Value: 6, FiredAt: now.Add(-2 * time.Hour)},
+		{RuleID: "r1", RuleName: "Cost", MetricName: "cost", Comparison: ">", Threshold: 5, WindowSeconds: 3600, Value: 7.5, FiredAt: now},
+		{RuleID: "r2", RuleName: "Tokens", MetricName: "tokens", Service: "svc", Comparison: "<", Threshold: 1, WindowSeconds: 60, Value: 0, FiredAt: now.Add(-time.Hour)},
+		{RuleID: "r1", RuleName: "Cost", MetricName: "cost", Comparison: ">", Threshold: 5, WindowSeconds: 3600, Value: 9, FiredAt: now.Add(-48 * time.Hour)},
+	}
+	for i := range events {
+		if err := store.InsertAlertEvent(ctx, &events[i]); err != nil {
+			t.Fatalf("InsertAlertEvent failed: %v", err)
+		}
+		if events[i].ID == "" {
+			t.Fatalf("expected InsertAlertEvent to assign an ID")
+		}
+	}
+
+	from, to := now.Add(-24*time.Hour), now.Add(time.Minute)
+	got, err := store.ListAlertEvents(ctx, "", from, to, 0)
+	if err != nil {
+		t.Fatalf("ListAlertEvents failed: %v", err)
+	}
+	if len(got) != 3 || got[0].Value != 7.5 || got[1].RuleID != "r2" || got[1].Service != "svc" || got[2].Value != 6 {
+		t.Fatalf("expected the 3 events in range, newest first, got %+v", got)
+	}
+	if !got[0].FiredAt.Equal(now) {
+		t.Errorf("FiredAt = %v, want %v", got[0].FiredAt, now)
+	}
+
+	got, err = store.ListAlertEvents(ctx, "r1", from, to, 1)
+	if err != nil {
+		t.Fatalf("ListAlertEvents failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Value != 7.5 {
+		t.Errorf("expected the latest r1 event, got %+v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// Annotations reference their trace or log by ID only and are not deleted with it
+
+// AnnotationFilter selects annotations; empty fields match everything
+type AnnotationFilter struct {
+	TargetType string
+	TargetID   string
+	Tag        string
+}
+
+// AnnotateTrace attaches a note and tags to a trace
+func (s *DuckDBStore) AnnotateTrace(ctx context.Context, traceID, note string, tags []string) (*api.Annotation, error) {
+	return s.createAnnotation(ctx, api.AnnotationTargetTrace, traceID, note, tags)
+}
+
+// AnnotateLog attaches a note and tags to the log of serviceName written at timestamp
+func (s *DuckDBStore) AnnotateLog(ctx context.Context, serviceName string, timestamp time.Time, note string, tags []string) (*api.Annotation, error) {
+	return s.createAnnotation(ctx, api.AnnotationTargetLog, api.LogAnnotationTarget(serviceName, timestamp), note, tags)
+}
+
+func (s *DuckDBStore) createAnnotation(ctx context.Context, targetType, targetID, note string, tags []string) (*api.Annotation, error) {
+	a := &api.Annotation{
+		ID:         uuid.New().String(),
+		TargetType: targetType,
+		TargetID:   targetID,
+		Note:       note,
+		Tags:       normalizeTags(tags),
+		CreatedAt:  time.Now(),
+	}
+	tagsJSON, err := json.Marshal(a.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling tags: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO annotations (id, target_type, target_id, note, tags, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, a.ID, a.TargetType, a.TargetID, nullString(a.Note), string(tagsJSON), a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("inserting annotation: %w", err)
+	}
+	return a, nil
+}
+
+// ListAnnotations returns the annotations matching filter, newest first
+func (s *DuckDBStore) ListAnnotations(ctx context.Context, filter AnnotationFilter) ([]api.Annotation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var conditions []string
+	var args []interface{}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, `list_contains(from_json(tags, '["VARCHAR"]'), ?)`)
+		args = append(args, filter.Tag)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, target_type, target_id, note, CAST(tags AS VARCHAR), created_at
+		FROM annotations
+		`+where+`
+		ORDER BY created_at DESC, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying annotations: %w", err)
+	}
+	defer rows.Close()
+
+	annotations := []api.Annotation{}
+	for rows.Next() {
+		var a api.Annotation
+		var note, tags sql.NullString
+		if err := rows.Scan(&a.ID, &a.TargetType, &a.TargetID, &note, &tags, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning annotation: %w", err)
+		}
+		a.Note = note.String
+		a.Tags = []string{}
+		if tags.Valid && tags.String != "" && tags.String != "null" {
+			if err := json.Unmarshal([]byte(tags.String), &a.Tags); err != nil {
+				return nil, fmt.Errorf("parsing tags of annotation %s: %w", a.ID, err)
+			}
+		}
+		annotations = append(annotations, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// DeleteAnnotation deletes an annotation and reports whether it existed
+func (s *DuckDBStore) DeleteAnnotation(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM annotations WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("deleting annotation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("deleting annotation: %w", err)
+	}
+	return n > 0, nil
+}
+
+// normalizeTags trims tags and drops empty and duplicate ones, keeping their order
+func normalizeTags(tags []string) []string {
+	out := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestAnnotateTrace(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	first, err := store.AnnotateTrace(ctx, "trace-1", "slow tool call", []string{" incident ", "slow", "incident", ""})
+	if err != nil {
+		t.Fatalf("AnnotateTrace failed: %v", err)
+	}
+	if first.ID == "" || first.TargetType != api.AnnotationTargetTrace || first.TargetID != "trace-1" {
+		t.Errorf("unexpected annotation: %+v", first)
+	}
+	if want := []string{"incident", "slow"}; !reflect.DeepEqual(first.Tags, want) {
+		t.Errorf("tags = %v, want %v", first.Tags, want)
+	}
+
+	time.Sleep(time.Millisecond)
+	second, err := store.AnnotateTrace(ctx, "trace-1", "", []string{"starred"})
+	if err != nil {
+		t.Fatalf("AnnotateTrace failed: %v", err)
+	}
+	if _, err := store.AnnotateTrace(ctx, "trace-2", "other trace", nil); err != nil {
+		t.Fatalf("AnnotateTrace failed: %v", err)
+	}
+
+	annotations, err := store.ListAnnotations(ctx, AnnotationFilter{TargetType: api.AnnotationTargetTrace, TargetID: "trace-1"})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(annotations) != 2 {
+		t.Fatalf("expected 2 annotations for trace-1, got %d", len(annotations))
+	}
+	if annotations[0].ID != second.ID || annotations[1].ID != first.ID {
+		t.Errorf("expected newest first, got %s then %s", annotations[0].ID, annotations[1].ID)
+	}
+	if annotations[1].Note != "slow tool call" || !reflect.DeepEqual(annotations[1].Tags, first.Tags) {
+		t.Errorf("stored annotation = %+v, want note and tags of %+v", annotations[1], first)
+	}
+	if annotations[0].Note != "" || !reflect.DeepEqual(annotations[0].Tags, []string{"starred"}) {
+		t.Errorf("unexpected bookmark annotation: %+v", annotations[0])
+	}
+
+	tagged, err := store.ListAnnotations(ctx, AnnotationFilter{Tag: "incident"})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != first.ID {
+		t.Errorf("expected only the incident annotation, got %+v", tagged)
+	}
+
+	all, err := store.ListAnnotations(ctx, AnnotationFilter{})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 annotations, got %d", len(all))
+	}
+}
+
+func TestAnnotateLog(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	a, err := store.AnnotateLog(ctx, "claude-code", ts, "rate limited", nil)
+	if err != nil {
+		t.Fatalf("AnnotateLog failed: %v", err)
+	}
+	if a.TargetType != api.AnnotationTargetLog || a.TargetID != "claude-code@2026-01-02T15:04:05.123456Z" {
+		t.Errorf("unexpected log annotation target: %+v", a)
+	}
+
+	annotations, err := store.ListAnnotations(ctx, AnnotationFilter{TargetType: api.AnnotationTargetLog})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(annotations) != 1 || annotations[0].TargetID != a.TargetID || len(annotations[0].Tags) != 0 {
+		t.Errorf("unexpected log annotations: %+v", annotations)
+	}
+}
+
+func TestDeleteAnnotation(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	a, err := store.AnnotateTrace(ctx, "trace-1", "note", nil)
+	if err != nil {
+		t.Fatalf("AnnotateTrace failed: %v", err)
+	}
+
+	deleted, err := store.DeleteAnnotation(ctx, a.ID)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteAnnotation = %v, %v; want true, nil", deleted, err)
+	}
+	deleted, err = store.DeleteAnnotation(ctx, a.ID)
+	if err != nil || deleted {
+		t.Errorf("second DeleteAnnotation = %v, %v; want false, nil", deleted, err)
+	}
+
+	annotations, err := store.ListAnnotations(ctx, AnnotationFilter{})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(annotations) != 0 {
+		t.Errorf("expected no annotations after delete, got %d", len(annotations))
+	}
+}
+
+func TestAnnotations_SurviveTelemetryDeletion(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now()
+	if err := store.InsertSpans(ctx, []api.Span{{TraceID: "trace-1", SpanID: "span-1", ServiceName: "svc", SpanName: "op", Timestamp: now}}); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	if _, err := store.AnnotateTrace(ctx, "trace-1", "keep me", nil); err != nil {
+		t.Fatalf("AnnotateTrace failed: %v", err)
+	}
+	if _, err := store.DeleteTracesInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), ""); err != nil {
+		t.Fatalf("DeleteTracesInRange failed: %v", err)
+	}
+
+	annotations, err := store.ListAnnotations(ctx, AnnotationFilter{TargetID: "trace-1"})
+	if err != nil {
+		t.Fatalf("ListAnnotations failed: %v", err)
+	}
+	if len(annotations) != 1 {
+		t.Errorf("expected the annotation to outlive its trace, got %d annotations", len(annotations))
+	}
+}
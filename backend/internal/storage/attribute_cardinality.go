@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// attributeColumns maps each signal to the table and column holding its record attributes
+var attributeColumns = map[string]struct{ table, column string }{
+	"traces":  {"otel_traces", "SpanAttributes"},
+	"logs":    {"otel_logs", "LogAttributes"},
+	"metrics": {"otel_metrics", "Attributes"},
+}
+
+// GetAttributeCardinality returns the number of distinct values and occurrences of every
+// attribute key of a signal ("traces", "logs" or "metrics") within the time range, highest
+// cardinality first. Keys with many distinct values (request IDs, ...) bloat the JSON columns.
+func (s *DuckDBStore) GetAttributeCardinality(ctx context.Context, signal string, from, to time.Time) ([]api.AttrCardinality, error) {
+	source, ok := attributeColumns[signal]
+	if !ok {
+		return nil, fmt.Errorf("unknown signal: %s", signal)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := fmt.Sprintf(`
+		WITH attrs AS (
+			SELECT UNNEST(json_keys(%[2]s)) as key, %[2]s as attrs
+			FROM %[1]s
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND %[2]s IS NOT NULL
+		)
+		SELECT key, COUNT(DISTINCT attrs->>key) as distinct_values, COUNT(*) as occurrences
+		FROM attrs
+		GROUP BY key
+		ORDER BY distinct_values DESC, key
+	`, source.table, source.column)
+
+	rows, err := s.db.QueryContext(ctx, query, formatTimeForDB(from), formatTimeForDB(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying attribute cardinality: %w", err)
+	}
+	defer rows.Close()
+
+	result := []api.AttrCardinality{}
+	for rows.Next() {
+		var c api.AttrCardinality
+		if err := rows.Scan(&c.Key, &c.DistinctValues, &c.Occurrences); err != nil {
+			return nil, fmt.Errorf("scanning attribute cardinality: %w", err)
+		}
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating attribute cardinality: %w", err)
+	}
+
+	return result, nil
+}
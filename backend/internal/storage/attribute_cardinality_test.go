@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetAttributeCardinality(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	var spans []api.Span
+	for i := 0; i < 50; i++ {
+		attrs := map[string]string{
+			"request.id":  fmt.Sprintf("req-%d", i),
+			"http.method": []string{"GET", "POST"}[i%2],
+		}
+		if i < 10 {
+			attrs["retry"] = "true"
+		}
+		spans = append(spans, api.Span{
+			TraceID: fmt.Sprintf("trace-%d", i), SpanID: fmt.Sprintf("span-%d", i), ServiceName: "api",
+			SpanName: "op", Timestamp: now, SpanAttributes: attrs,
+		})
+	}
+	// Outside the time range
+	spans = append(spans, api.Span{TraceID: "old", SpanID: "old", ServiceName: "api", SpanName: "op",
+		Timestamp: now.Add(-48 * time.Hour), SpanAttributes: map[string]string{"request.id": "old"}})
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	attrs, err := store.GetAttributeCardinality(ctx, "traces", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetAttributeCardinality failed: %v", err)
+	}
+	want := []api.AttrCardinality{
+		{Key: "request.id", DistinctValues: 50, Occurrences: 50},
+		{Key: "http.method", DistinctValues: 2, Occurrences: 50},
+		{Key: "retry", DistinctValues: 1, Occurrences: 10},
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("expected %d keys, got %+v", len(want), attrs)
+	}
+	for i := range want {
+		if attrs[i] != want[i] {
+			t.Errorf("attrs[%d] = %+v, want %+v", i, attrs[i], want[i])
+		}
+	}
+
+	logs, err := store.GetAttributeCardinality(ctx, "logs", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil || len(logs) != 0 {
+		t.Errorf("expected no log attributes, got %+v (err %v)", logs, err)
+	}
+
+	if _, err := store.GetAttributeCardinality(ctx, "profiles", now.Add(-time.Hour), now); err == nil {
+		t.Error("expected error for unknown signal")
+	}
+}
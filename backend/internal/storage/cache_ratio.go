@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// cacheHitRatioSeriesName is the name of the derived series returned by GetCacheHitRatio
+const cacheHitRatioSeriesName = "cache_hit_ratio"
+
+// cacheTokenMetrics are the token usage metrics that report input and cache-read tokens.
+// Claude Code and Codex CLI label cache reads "cacheRead"; Gemini CLI labels them "cache".
+var cacheTokenMetrics = []string{
+	"claude_code.token.usage",
+	"codex_cli_rs.token.usage",
+	"gemini_cli.token.usage",
+}
+
+// GetCacheHitRatio returns, per service, the prompt cache hit ratio
+// cacheRead / (input + cacheRead) of each interval bucket within [from, to], derived from the
+// token usage metrics. Buckets without input or cache-read tokens have no data point.
+// Token usage points are deltas (Gemini's cumulative counters are converted at ingestion),
+// so each bucket sums them. An empty model matches all models.
+func (s *DuckDBStore) GetCacheHitRatio(ctx context.Context, service, model string, from, to time.Time, intervalSeconds int64) (*api.TimeSeriesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	intervalStr := fmt.Sprintf("%d seconds", intervalSeconds)
+
+	query := fmt.Sprintf(`
+		SELECT
+			time_bucket(INTERVAL '%s', Timestamp) as bucket,
+			ServiceName,
+			SUM(CASE WHEN Attributes->>'type' IN ('cacheRead', 'cache') THEN COALESCE(Value, Sum) ELSE 0 END) as cache_read,
+			SUM(CASE WHEN Attributes->>'type' = 'input' THEN COALESCE(Value, Sum) ELSE 0 END) as input
+		FROM otel_metrics
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+			AND MetricName IN (?, ?, ?)
+	`, intervalStr)
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	for _, name := range cacheTokenMetrics {
+		args = append(args, name)
+	}
+
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	if model != "" {
+		query += " AND json_extract_string(Attributes, '$.model') = ?"
+		args = append(args, model)
+	}
+	query += `
+		GROUP BY bucket, ServiceName
+		HAVING cache_read + input > 0
+		ORDER BY ServiceName, bucket
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying cache hit ratio: %w", err)
+	}
+	defer rows.Close()
+
+	series := []api.TimeSeries{}
+	for rows.Next() {
+		var bucket time.Time
+		var serviceName string
+		var cacheRead, input float64
+		if err := rows.Scan(&bucket, &serviceName, &cacheRead, &input); err != nil {
+			return nil, fmt.Errorf("scanning cache hit ratio: %w", err)
+		}
+
+		if len(series) == 0 || series[len(series)-1].Labels["service"] != serviceName {
+			labels := map[string]string{"service": serviceName}
+			if model != "" {
+				labels["model"] = model
+			}
+			series = append(series, api.TimeSeries{
+				Name:       cacheHitRatioSeriesName,
+				Labels:     labels,
+				DataPoints: make([][2]float64, 0),
+			})
+		}
+		last := &series[len(series)-1]
+		last.DataPoints = append(last.DataPoints, [2]float64{
+			float64(bucket.UnixMilli()),
+			cacheRead / (input + cacheRead),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating cache hit ratio: %w", err)
+	}
+
+	return &api.TimeSeriesResponse{Series: series}, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetCacheHitRatio(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	bucket := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+
+	token := func(ts time.Time, service, metric, model, tokenType string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: metric, MetricType: "sum", Value: &v,
+			Attributes: map[string]string{"model": model, "type": tokenType},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		// Claude, first hour: 300 cache reads, 100 input -> 0.75 (output tokens are ignored)
+		token(bucket.Add(time.Minute), "claude-code", "claude_code.token.usage", "sonnet", "input", 60),
+		token(bucket.Add(2*time.Minute), "claude-code", "claude_code.token.usage", "sonnet", "input", 40),
+		token(bucket.Add(3*time.Minute), "claude-code", "claude_code.token.usage", "sonnet", "cacheRead", 300),
+		token(bucket.Add(3*time.Minute), "claude-code", "claude_code.token.usage", "sonnet", "output", 999),
+		// Claude, second hour: only opus -> 100 / (100 + 100) = 0.5
+		token(bucket.Add(61*time.Minute), "claude-code", "claude_code.token.usage", "opus", "input", 100),
+		token(bucket.Add(62*time.Minute), "claude-code", "claude_code.token.usage", "opus", "cacheRead", 100),
+		// Gemini labels cache reads "cache": 50 / (150 + 50) = 0.25
+		token(bucket.Add(time.Minute), "gemini-cli", "gemini_cli.token.usage", "gemini-2.5-pro", "input", 150),
+		token(bucket.Add(time.Minute), "gemini-cli", "gemini_cli.token.usage", "gemini-2.5-pro", "cache", 50),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := bucket.Add(-time.Hour), bucket.Add(3*time.Hour)
+	ratioAt := func(series api.TimeSeries, ts time.Time) float64 {
+		t.Helper()
+		for _, dp := range series.DataPoints {
+			if dp[0] == float64(ts.UnixMilli()) {
+				return dp[1]
+			}
+		}
+		t.Fatalf("no data point at %v in %+v", ts, series)
+		return 0
+	}
+
+	resp, err := store.GetCacheHitRatio(ctx, "claude-code", "", from, to, 3600)
+	if err != nil {
+		t.Fatalf("GetCacheHitRatio failed: %v", err)
+	}
+	if len(resp.Series) != 1 || len(resp.Series[0].DataPoints) != 2 {
+		t.Fatalf("expected one series with 2 buckets, got %+v", resp.Series)
+	}
+	if got := ratioAt(resp.Series[0], bucket); math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("first bucket ratio = %v, want 0.75", got)
+	}
+	if got := ratioAt(resp.Series[0], bucket.Add(time.Hour)); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("second bucket ratio = %v, want 0.5", got)
+	}
+
+	resp, err = store.GetCacheHitRatio(ctx, "", "sonnet", from, to, 3600)
+	if err != nil {
+		t.Fatalf("GetCacheHitRatio with model failed: %v", err)
+	}
+	if len(resp.Series) != 1 || len(resp.Series[0].DataPoints) != 1 || resp.Series[0].Labels["model"] != "sonnet" {
+		t.Fatalf("expected one sonnet bucket, got %+v", resp.Series)
+	}
+
+	resp, err = store.GetCacheHitRatio(ctx, "gemini-cli", "", from, to, 3600)
+	if err != nil {
+		t.Fatalf("GetCacheHitRatio for gemini failed: %v", err)
+	}
+	if len(resp.Series) != 1 {
+		t.Fatalf("expected one gemini series, got %+v", resp.Series)
+	}
+	if got := ratioAt(resp.Series[0], bucket); math.Abs(got-0.25) > 1e-9 {
+		t.Errorf("gemini ratio = %v, want 0.25", got)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cloneTables lists the tables copied by Clone, in insertion order
+var cloneTables = []string{
+	"otel_traces",
+	"otel_logs",
+	"otel_metrics",
+	"dashboards",
+	"dashboard_widgets",
+	"import_state",
+	"annotations",
+	"alert_rules",
+	"alert_events",
+}
+
+// Clone returns an isolated in-memory copy of the store's current data.
+// The snapshot is taken under a read lock by writing each table to a temporary
+// Parquet file, so the live database is never attached to or modified.
+// Writes to either store after cloning are not visible in the other.
+// The caller is responsible for closing the returned store.
+func (s *DuckDBStore) Clone(ctx context.Context) (*DuckDBStore, error) {
+	tmpDir, err := os.MkdirTemp("", "ai-observer-clone-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := s.snapshotTables(ctx, tmpDir); err != nil {
+		return nil, err
+	}
+
+	clone, err := NewDuckDBStore(":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("creating clone store: %w", err)
+	}
+
+	for _, table := range cloneTables {
+		query := fmt.Sprintf("INSERT INTO %s SELECT * FROM read_parquet('%s')", table, snapshotPath(tmpDir, table))
+		if _, err := clone.db.ExecContext(ctx, query); err != nil {
+			clone.Close()
+			return nil, fmt.Errorf("loading %s into clone: %w", table, err)
+		}
+	}
+
+	return clone, nil
+}
+
+// snapshotTables writes every table in cloneTables to a Parquet file in dir
+func (s *DuckDBStore) snapshotTables(ctx context.Context, dir string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, table := range cloneTables {
+		query := fmt.Sprintf("COPY %s TO '%s' (FORMAT PARQUET)", table, snapshotPath(dir, table))
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("snapshotting %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// snapshotPath returns the Parquet path for a table, escaped for use in a SQL string literal
+func snapshotPath(dir, table string) string {
+	return strings.ReplaceAll(filepath.Join(dir, table+".parquet"), "'", "''")
+}
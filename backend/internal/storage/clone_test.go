@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// seedCloneStore inserts one record of each signal type into the store
+func seedCloneStore(t *testing.T, store *DuckDBStore) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{{
+		TraceID:        "trace-1",
+		SpanID:         "span-1",
+		ServiceName:    "svc",
+		SpanName:       "op",
+		Timestamp:      now,
+		Duration:       1000,
+		SpanAttributes: map[string]string{"key": "value"},
+	}}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	logs := []api.LogRecord{{Timestamp: now, ServiceName: "svc", SeverityText: "INFO", Body: "hello"}}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	value := 42.0
+	metrics := []api.MetricDataPoint{{Timestamp: now, ServiceName: "svc", MetricName: "m", MetricType: "gauge", Value: &value}}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	if _, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{Name: "Main"}); err != nil {
+		t.Fatalf("CreateDashboard failed: %v", err)
+	}
+
+	rule, err := store.CreateAlertRule(ctx, &api.AlertRuleRequest{Name: "Cost", MetricName: "m", Comparison: ">", Threshold: 5, WindowSeconds: 60})
+	if err != nil {
+		t.Fatalf("CreateAlertRule failed: %v", err)
+	}
+	event := &api.AlertEvent{RuleID: rule.ID, RuleName: rule.Name, MetricName: "m", Comparison: ">", Threshold: 5, WindowSeconds: 60, Value: 6, FiredAt: now}
+	if err := store.InsertAlertEvent(ctx, event); err != nil {
+		t.Fatalf("InsertAlertEvent failed: %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	seedCloneStore(t, store)
+
+	ctx := context.Background()
+	clone, err := store.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+
+	original, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats on original failed: %v", err)
+	}
+	cloned, err := clone.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats on clone failed: %v", err)
+	}
+
+	if cloned.SpanCount != original.SpanCount || cloned.LogCount != original.LogCount || cloned.MetricCount != original.MetricCount {
+		t.Errorf("clone stats %+v do not match original %+v", cloned, original)
+	}
+
+	spans, err := clone.GetTraceSpans(ctx, "trace-1")
+	if err != nil {
+		t.Fatalf("GetTraceSpans on clone failed: %v", err)
+	}
+	if len(spans) != 1 || spans[0].SpanAttributes["key"] != "value" {
+		t.Errorf("expected cloned span with attributes, got %+v", spans)
+	}
+
+	dashboards, err := clone.GetDashboards(ctx)
+	if err != nil {
+		t.Fatalf("GetDashboards on clone failed: %v", err)
+	}
+	if len(dashboards) != 1 || dashboards[0].Name != "Main" {
+		t.Errorf("expected cloned dashboard, got %+v", dashboards)
+	}
+
+	rules, err := clone.ListAlertRules(ctx)
+	if err != nil {
+		t.Fatalf("ListAlertRules on clone failed: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Name != "Cost" {
+		t.Fatalf("expected cloned alert rule, got %+v", rules)
+	}
+	events, err := clone.ListAlertEvents(ctx, rules[0].ID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListAlertEvents on clone failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Value != 6 {
+		t.Errorf("expected cloned alert event, got %+v", events)
+	}
+}
+
+func TestClone_Isolation(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	seedCloneStore(t, store)
+
+	ctx := context.Background()
+	clone, err := store.Clone(ctx)
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	defer clone.Close()
+
+	// Write to the original after cloning
+	logs := []api.LogRecord{{Timestamp: time.Now(), ServiceName: "svc", Body: "after clone"}}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	original, _ := store.GetStats(ctx)
+	cloned, _ := clone.GetStats(ctx)
+
+	if original.LogCount != 2 {
+		t.Errorf("expected 2 logs in original, got %d", original.LogCount)
+	}
+	if cloned.LogCount != 1 {
+		t.Errorf("expected clone to be unaffected with 1 log, got %d", cloned.LogCount)
+	}
+}
+
+func TestClone_ParallelReuse(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	seedCloneStore(t, store)
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			t.Run(fmt.Sprintf("clone-%d", i), func(t *testing.T) {
+				t.Parallel()
+				ctx := context.Background()
+
+				clone, err := store.Clone(ctx)
+				if err != nil {
+					t.Fatalf("Clone failed: %v", err)
+				}
+				defer clone.Close()
+
+				// Each clone can be written to independently
+				spans := []api.Span{{TraceID: fmt.Sprintf("extra-%d", i), SpanID: "s", ServiceName: "svc", SpanName: "op", Timestamp: time.Now()}}
+				if err := clone.InsertSpans(ctx, spans); err != nil {
+					t.Fatalf("InsertSpans failed: %v", err)
+				}
+
+				stats, err := clone.GetStats(ctx)
+				if err != nil {
+					t.Fatalf("GetStats failed: %v", err)
+				}
+				if stats.SpanCount != 2 {
+					t.Errorf("expected 2 spans in clone, got %d", stats.SpanCount)
+				}
+			})
+		}
+	})
+
+	stats, err := store.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.SpanCount != 1 {
+		t.Errorf("expected original to keep 1 span, got %d", stats.SpanCount)
+	}
+}
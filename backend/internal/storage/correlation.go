@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// Session correlation methods, tried in this order by GetTraceSession
+const (
+	CorrelationAttribute = "attribute" // a span or resource attribute carries the session id
+	CorrelationTraceID   = "trace_id"  // a log emitted with the trace's TraceId carries the session id
+	CorrelationProximity = "proximity" // nearest session log of the same service around the trace's time range
+)
+
+// sessionProximityWindow bounds how far outside a trace's time range a log may be
+// and still be considered part of the same session
+const sessionProximityWindow = 5 * time.Minute
+
+// sessionAttributeKeys are the attributes tools use to identify a session
+var sessionAttributeKeys = []string{"session.id", "conversation.id"}
+
+// sessionIDExpr extracts the session identifier from a log row
+const sessionIDExpr = `COALESCE(
+	json_extract_string(LogAttributes, '$."session.id"'),
+	json_extract_string(LogAttributes, '$."conversation.id"')
+)`
+
+// GetTraceSession resolves the session a trace belongs to and returns up to limit of the
+// session's logs in chronological order. It returns nil if the trace does not exist, and
+// a response without SessionID if no session could be correlated.
+func (s *DuckDBStore) GetTraceSession(ctx context.Context, traceID string, limit int) (*api.TraceSessionResponse, error) {
+	spans, err := s.GetTraceSpans(ctx, traceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return nil, nil
+	}
+
+	resp := &api.TraceSessionResponse{TraceID: traceID, Logs: []api.LogRecord{}}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessionID, method, err := s.resolveTraceSession(ctx, traceID, spans)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID == "" {
+		return resp, nil
+	}
+	resp.SessionID = sessionID
+	resp.Method = method
+
+	query := `
+		SELECT
+			Timestamp, TraceId, SpanId, TraceFlags, SeverityText,
+			SeverityNumber, ServiceName, Body, ResourceSchemaUrl,
+			ResourceAttributes, ScopeSchemaUrl, ScopeName, ScopeVersion,
+			ScopeAttributes, LogAttributes, DroppedAttributesCount
+		FROM otel_logs
+		WHERE ` + sessionIDExpr + ` = ?
+		ORDER BY Timestamp ASC
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, sessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying session logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs, err := scanLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if logs != nil {
+		resp.Logs = logs
+	}
+
+	return resp, nil
+}
+
+// resolveTraceSession applies the correlation heuristics in order of confidence.
+// Caller must hold s.mu.
+func (s *DuckDBStore) resolveTraceSession(ctx context.Context, traceID string, spans []api.Span) (string, string, error) {
+	// 1. Shared attribute on any span of the trace
+	for _, span := range spans {
+		for _, attrs := range []map[string]string{span.SpanAttributes, span.ResourceAttributes} {
+			for _, key := range sessionAttributeKeys {
+				if id := attrs[key]; id != "" {
+					return id, CorrelationAttribute, nil
+				}
+			}
+		}
+	}
+
+	// 2. Logs emitted inside the trace
+	var sessionID sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT `+sessionIDExpr+` as session_id
+		FROM otel_logs
+		WHERE TraceId = ? AND `+sessionIDExpr+` IS NOT NULL
+		ORDER BY Timestamp
+		LIMIT 1
+	`, traceID).Scan(&sessionID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", fmt.Errorf("correlating session by trace id: %w", err)
+	}
+	if sessionID.Valid {
+		return sessionID.String, CorrelationTraceID, nil
+	}
+
+	// 3. Nearest session log from the same service, measured as the distance to the trace's time range
+	start, end := spans[0].Timestamp, spans[0].Timestamp
+	for _, span := range spans {
+		if span.Timestamp.Before(start) {
+			start = span.Timestamp
+		}
+		if spanEnd := span.Timestamp.Add(time.Duration(span.Duration)); spanEnd.After(end) {
+			end = spanEnd
+		}
+	}
+	startStr, endStr := formatTimeForDB(start), formatTimeForDB(end)
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT `+sessionIDExpr+` as session_id
+		FROM otel_logs
+		WHERE ServiceName = ?
+			AND `+sessionIDExpr+` IS NOT NULL
+			AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		ORDER BY GREATEST(
+			date_diff('millisecond', Timestamp, ?::TIMESTAMP),
+			date_diff('millisecond', ?::TIMESTAMP, Timestamp),
+			0
+		), Timestamp
+		LIMIT 1
+	`,
+		spans[0].ServiceName,
+		formatTimeForDB(start.Add(-sessionProximityWindow)), formatTimeForDB(end.Add(sessionProximityWindow)),
+		startStr, endStr,
+	).Scan(&sessionID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", fmt.Errorf("correlating session by time: %w", err)
+	}
+	if sessionID.Valid {
+		return sessionID.String, CorrelationProximity, nil
+	}
+
+	return "", "", nil
+}
+
+// GetCorrelationGaps reports logs whose TraceId matches no stored trace and traces that no log
+// references. Traces and logs are selected by their own timestamps within [from, to], while the
+// lookup on the other side is not time-bounded.
+func (s *DuckDBStore) GetCorrelationGaps(ctx context.Context, from, to time.Time) (*api.CorrelationReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	query := `
+		WITH range_traces AS (
+			SELECT DISTINCT TraceId
+			FROM otel_traces
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		),
+		range_logs AS (
+			SELECT TraceId
+			FROM otel_logs
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND TraceId IS NOT NULL AND TraceId != ''
+		)
+		SELECT
+			(SELECT COUNT(*) FROM range_traces) as trace_count,
+			(SELECT COUNT(*) FROM range_traces t
+				WHERE NOT EXISTS (SELECT 1 FROM otel_logs l WHERE l.TraceId = t.TraceId)) as traces_without_logs,
+			(SELECT COUNT(*) FROM range_logs) as logs_with_trace_id,
+			(SELECT COUNT(*) FROM range_logs l
+				WHERE NOT EXISTS (SELECT 1 FROM otel_traces t WHERE t.TraceId = l.TraceId)) as orphaned_logs
+	`
+
+	report := &api.CorrelationReport{}
+	if err := s.db.QueryRowContext(ctx, query, fromStr, toStr, fromStr, toStr).Scan(
+		&report.TraceCount,
+		&report.TracesWithoutLogs,
+		&report.LogsWithTraceID,
+		&report.OrphanedLogs,
+	); err != nil {
+		return nil, fmt.Errorf("querying correlation gaps: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetTraceSession(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	spans := []api.Span{
+		// Session id on a span attribute
+		{TraceID: "trace-attr", SpanID: "s1", ServiceName: "claude-code", SpanName: "claude.request",
+			Timestamp: now, Duration: int64(time.Second), SpanAttributes: map[string]string{"session.id": "sess-1"}},
+		// No session attribute, but a log carries the trace id
+		{TraceID: "trace-log", SpanID: "s2", ServiceName: "gemini-cli", SpanName: "llm_call",
+			Timestamp: now, Duration: int64(time.Second)},
+		// Only time and service proximity to sess-3 logs
+		{TraceID: "trace-near", SpanID: "s3", ServiceName: "other-tool", SpanName: "work",
+			Timestamp: now, Duration: int64(time.Second)},
+		// Nothing to correlate with
+		{TraceID: "trace-alone", SpanID: "s4", ServiceName: "lonely", SpanName: "work",
+			Timestamp: now, Duration: int64(time.Second)},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(2 * time.Second), ServiceName: "claude-code", Body: "second", LogAttributes: map[string]string{"session.id": "sess-1"}},
+		{Timestamp: now.Add(-time.Second), ServiceName: "claude-code", Body: "first", LogAttributes: map[string]string{"session.id": "sess-1"}},
+		{Timestamp: now, ServiceName: "claude-code", Body: "unrelated", LogAttributes: map[string]string{"session.id": "sess-x"}},
+		{Timestamp: now, ServiceName: "gemini-cli", TraceID: "trace-log", Body: "in trace", LogAttributes: map[string]string{"session.id": "sess-2"}},
+		{Timestamp: now.Add(time.Minute), ServiceName: "other-tool", Body: "near", LogAttributes: map[string]string{"conversation.id": "sess-3"}},
+		{Timestamp: now.Add(4 * time.Minute), ServiceName: "other-tool", Body: "far", LogAttributes: map[string]string{"conversation.id": "sess-4"}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	tests := []struct {
+		traceID    string
+		wantID     string
+		wantMethod string
+		wantLogs   int
+	}{
+		{"trace-attr", "sess-1", CorrelationAttribute, 2},
+		{"trace-log", "sess-2", CorrelationTraceID, 1},
+		{"trace-near", "sess-3", CorrelationProximity, 1},
+		{"trace-alone", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.traceID, func(t *testing.T) {
+			resp, err := store.GetTraceSession(ctx, tt.traceID, 100)
+			if err != nil {
+				t.Fatalf("GetTraceSession failed: %v", err)
+			}
+			if resp.SessionID != tt.wantID || resp.Method != tt.wantMethod {
+				t.Errorf("got session %q via %q, want %q via %q", resp.SessionID, resp.Method, tt.wantID, tt.wantMethod)
+			}
+			if len(resp.Logs) != tt.wantLogs {
+				t.Fatalf("expected %d logs, got %d", tt.wantLogs, len(resp.Logs))
+			}
+		})
+	}
+
+	resp, err := store.GetTraceSession(ctx, "trace-attr", 100)
+	if err != nil {
+		t.Fatalf("GetTraceSession failed: %v", err)
+	}
+	if resp.Logs[0].Body != "first" || resp.Logs[1].Body != "second" {
+		t.Errorf("expected session logs in chronological order, got %q, %q", resp.Logs[0].Body, resp.Logs[1].Body)
+	}
+
+	missing, err := store.GetTraceSession(ctx, "nonexistent", 100)
+	if err != nil {
+		t.Fatalf("GetTraceSession failed: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("expected nil for unknown trace, got %+v", missing)
+	}
+}
+
+func TestGetCorrelationGaps(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{
+		{TraceID: "trace-with-logs", SpanID: "s1", ServiceName: "svc", SpanName: "op", Timestamp: now},
+		{TraceID: "trace-with-logs", SpanID: "s2", ParentSpanID: "s1", ServiceName: "svc", SpanName: "child", Timestamp: now},
+		{TraceID: "trace-without-logs", SpanID: "s3", ServiceName: "svc", SpanName: "op", Timestamp: now},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", TraceID: "trace-with-logs", Body: "correlated"},
+		{Timestamp: now, ServiceName: "svc", TraceID: "missing-trace", Body: "orphan"},
+		{Timestamp: now, ServiceName: "svc", Body: "no trace"},
+		// Outside the queried range
+		{Timestamp: now.Add(-48 * time.Hour), ServiceName: "svc", TraceID: "old-missing-trace", Body: "old orphan"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	report, err := store.GetCorrelationGaps(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetCorrelationGaps failed: %v", err)
+	}
+
+	want := api.CorrelationReport{TraceCount: 2, TracesWithoutLogs: 1, LogsWithTraceID: 2, OrphanedLogs: 1}
+	if *report != want {
+		t.Errorf("report = %+v, want %+v", *report, want)
+	}
+}
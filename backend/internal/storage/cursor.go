@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for pagination cursors that were not produced by Cursor.Encode
+// for the list being paged
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a row for keyset pagination. Lists are ordered newest first by Timestamp,
+// tie-broken by ID (a log's row key or a trace's ID) descending, so paging stays stable while
+// new rows arrive.
+type Cursor struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// PageCursor selects the rows strictly before (older than) or, with After, strictly after
+// (newer than) a cursor
+type PageCursor struct {
+	Cursor
+	After bool
+}
+
+// Encode returns the cursor as an opaque URL-safe token
+func (c Cursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.Timestamp.UnixMicro(), 10) + "|" + c.ID))
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode
+func DecodeCursor(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	ts, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Timestamp: time.UnixMicro(ts).UTC(), ID: id}, nil
+}
+
+// keysetCondition returns the SQL condition and arguments selecting rows past the cursor for
+// the given timestamp and ID expressions, plus the ORDER BY direction to fetch them in
+func (p *PageCursor) keysetCondition(tsExpr, idExpr string, id interface{}) (string, []interface{}, string) {
+	op, dir := "<", "DESC"
+	if p.After {
+		op, dir = ">", "ASC"
+	}
+	ts := formatTimeForDB(p.Timestamp)
+	cond := fmt.Sprintf("(%[1]s %[3]s ?::TIMESTAMP OR (%[1]s = ?::TIMESTAMP AND %[2]s %[3]s ?))", tsExpr, idExpr, op)
+	return cond, []interface{}{ts, ts, id}, dir
+}
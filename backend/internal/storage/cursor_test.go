@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	c := Cursor{Timestamp: time.Date(2025, 3, 1, 12, 0, 0, 123456000, time.UTC), ID: "abc|def"}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	if !got.Timestamp.Equal(c.Timestamp) || got.ID != c.ID {
+		t.Errorf("round trip = %+v, want %+v", got, c)
+	}
+
+	for _, token := range []string{"!!!", "bm9waXBl", "eHxpZA"} { // invalid base64, "nopipe", "x|id"
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", token, err)
+		}
+	}
+}
+
+func TestQueryLogsPage(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	// Three logs share a timestamp, so the pages must tie-break on their row keys
+	var logs []api.LogRecord
+	for i, offset := range []time.Duration{0, -time.Minute, -time.Minute, -time.Minute, -2 * time.Minute} {
+		logs = append(logs, api.LogRecord{Timestamp: now.Add(offset), ServiceName: "svc", Body: "log-" + strconv.Itoa(i)})
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	first, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", "", from, to, 2, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if !first.HasMore || first.NextCursor == "" {
+		t.Fatalf("expected a next cursor, got %+v", first)
+	}
+
+	// Walking back with before visits every log exactly once
+	bodies := []string{first.Logs[0].Body, first.Logs[1].Body}
+	tokens := []string{first.NextCursor}
+	var last *api.LogsResponse
+	for token := first.NextCursor; token != ""; token = last.NextCursor {
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		last, err = store.QueryLogsPage(ctx, "", "", SeverityRange{}, "", "", from, to, 2, PageCursor{Cursor: cursor})
+		if err != nil {
+			t.Fatalf("QueryLogsPage failed: %v", err)
+		}
+		if last.Total != len(logs) {
+			t.Errorf("Total = %d, want %d", last.Total, len(logs))
+		}
+		for _, l := range last.Logs {
+			bodies = append(bodies, l.Body)
+		}
+		tokens = append(tokens, last.NextCursor)
+	}
+	ties := slices.Clone(bodies[1:4])
+	slices.Sort(ties)
+	if len(bodies) != 5 || bodies[0] != "log-0" || bodies[4] != "log-4" || !slices.Equal(ties, []string{"log-1", "log-2", "log-3"}) {
+		t.Errorf("paged bodies = %v, want log-0, then log-1 to log-3 in any order, then log-4", bodies)
+	}
+	if last.HasMore {
+		t.Error("expected the last page to have no more logs")
+	}
+
+	// Paging forward from the fourth log returns the two newer logs closest to it, newest first
+	cursor, err := DecodeCursor(tokens[1])
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	newer, err := store.QueryLogsPage(ctx, "", "", SeverityRange{}, "", "", from, to, 2, PageCursor{Cursor: cursor, After: true})
+	if err != nil {
+		t.Fatalf("QueryLogsPage after failed: %v", err)
+	}
+	got := []string{}
+	for _, l := range newer.Logs {
+		got = append(got, l.Body)
+	}
+	if want := []string{bodies[1], bodies[2]}; !slices.Equal(got, want) {
+		t.Errorf("after page = %v, want %v", got, want)
+	}
+	if !newer.HasMore || newer.NextCursor == "" {
+		t.Errorf("expected more newer logs, got %+v", newer)
+	}
+
+	_, err = store.QueryLogsPage(ctx, "", "", SeverityRange{}, "", "", from, to, 2, PageCursor{Cursor: Cursor{Timestamp: now, ID: "trace-1"}})
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("non-numeric log cursor error = %v, want ErrInvalidCursor", err)
+	}
+
+	// Row keys do not move when other logs are deleted and the database is checkpointed
+	if _, err := store.db.ExecContext(ctx, "DELETE FROM otel_logs WHERE Body = 'log-0'"); err != nil {
+		t.Fatalf("deleting log: %v", err)
+	}
+	if _, err := store.db.ExecContext(ctx, "CHECKPOINT"); err != nil {
+		t.Fatalf("checkpointing: %v", err)
+	}
+	older, err := store.QueryLogsPage(ctx, "", "", SeverityRange{}, "", "", from, to, 2, PageCursor{Cursor: cursor})
+	if err != nil {
+		t.Fatalf("QueryLogsPage after delete failed: %v", err)
+	}
+	got = got[:0]
+	for _, l := range older.Logs {
+		got = append(got, l.Body)
+	}
+	if want := bodies[4:]; !slices.Equal(got, want) {
+		t.Errorf("page after delete = %v, want %v", got, want)
+	}
+}
+
+func TestQueryTracesPage(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "api", SpanName: "op", Timestamp: now},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "api", SpanName: "op", Timestamp: now.Add(-time.Minute)},
+		{TraceID: "t3", SpanID: "s3", ServiceName: "api", SpanName: "op", Timestamp: now.Add(-time.Minute)},
+		// Codex first-level spans are listed as traces keyed by their prefixed span ID
+		{TraceID: "c", SpanID: "c1", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now.Add(-time.Minute)},
+		{TraceID: "c", SpanID: "c2", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now.Add(-2 * time.Minute)},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	page, err := store.QueryTraces(ctx, "", "", "", from, to, 2, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+	var ids []string
+	for {
+		for _, tr := range page.Traces {
+			ids = append(ids, tr.TraceID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor, err := DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		if page, err = store.QueryTracesPage(ctx, "", "", "", from, to, 2, PageCursor{Cursor: cursor}); err != nil {
+			t.Fatalf("QueryTracesPage failed: %v", err)
+		}
+	}
+	if want := []string{"t1", "t3", "t2", "codex:c1", "codex:c2"}; !slices.Equal(ids, want) {
+		t.Errorf("paged traces = %v, want %v", ids, want)
+	}
+
+	// Newer than the oldest trace: the two closest ones, newest first
+	newer, err := store.QueryTracesPage(ctx, "", "", "", from, to, 2,
+		PageCursor{Cursor: Cursor{Timestamp: now.Add(-2 * time.Minute), ID: "codex:c2"}, After: true})
+	if err != nil {
+		t.Fatalf("QueryTracesPage after failed: %v", err)
+	}
+	if len(newer.Traces) != 2 || newer.Traces[0].TraceID != "t2" || newer.Traces[1].TraceID != "codex:c1" {
+		t.Errorf("after page = %+v", newer.Traces)
+	}
+	if !newer.HasMore {
+		t.Error("expected more newer traces")
+	}
+}
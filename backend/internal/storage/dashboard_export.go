@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// ExportDashboard returns a dashboard and its widgets as an api.DashboardExport, or nil when
+// the dashboard does not exist. Widgets keep their grid positions and config; whether the
+// dashboard is the default is specific to this installation and left out.
+func (s *DuckDBStore) ExportDashboard(ctx context.Context, id string) (*api.DashboardExport, error) {
+	d, err := s.GetDashboardWithWidgets(ctx, id)
+	if err != nil || d == nil {
+		return nil, err
+	}
+
+	export := &api.DashboardExport{
+		Version:     api.DashboardExportVersion,
+		Name:        d.Name,
+		Description: d.Description,
+		Variables:   d.Variables,
+		Widgets:     []api.CreateWidgetRequest{},
+	}
+	for _, w := range d.Widgets {
+		export.Widgets = append(export.Widgets, api.CreateWidgetRequest{
+			WidgetType: w.WidgetType,
+			Title:      w.Title,
+			GridColumn: w.GridColumn,
+			GridRow:    w.GridRow,
+			ColSpan:    w.ColSpan,
+			RowSpan:    w.RowSpan,
+			Config:     w.Config,
+		})
+	}
+	return export, nil
+}
+
+// ImportDashboard creates a dashboard and its widgets from an export, with fresh ids, in one
+// transaction. The dashboard is not made the default. When its name is already taken, a
+// numbered suffix is added: "Usage" becomes "Usage (2)", then "Usage (3)" and so on.
+func (s *DuckDBStore) ImportDashboard(ctx context.Context, export *api.DashboardExport) (*api.DashboardWithWidgets, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	name, err := uniqueDashboardName(ctx, tx, export.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	variablesJSON, err := json.Marshal(export.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling variables: %w", err)
+	}
+
+	now := time.Now()
+	result := &api.DashboardWithWidgets{
+		Dashboard: api.Dashboard{
+			ID:          uuid.New().String(),
+			Name:        name,
+			Description: export.Description,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			Variables:   export.Variables,
+		},
+		Widgets: []api.DashboardWidget{},
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO dashboards (id, name, description, is_default, created_at, updated_at, variables)
+		VALUES (?, ?, ?, FALSE, ?, ?, ?)
+	`, result.ID, name, export.Description, now, now, string(variablesJSON)); err != nil {
+		return nil, fmt.Errorf("inserting dashboard: %w", err)
+	}
+
+	for _, req := range export.Widgets {
+		configJSON, err := json.Marshal(req.Config)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling config: %w", err)
+		}
+		w := api.DashboardWidget{
+			ID:          uuid.New().String(),
+			DashboardID: result.ID,
+			WidgetType:  req.WidgetType,
+			Title:       req.Title,
+			GridColumn:  req.GridColumn,
+			GridRow:     req.GridRow,
+			ColSpan:     max(req.ColSpan, 1),
+			RowSpan:     max(req.RowSpan, 1),
+			Config:      req.Config,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO dashboard_widgets (id, dashboard_id, widget_type, title, grid_column, grid_row, col_span, row_span, config, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, w.ID, w.DashboardID, w.WidgetType, w.Title, w.GridColumn, w.GridRow, w.ColSpan, w.RowSpan, string(configJSON), now, now); err != nil {
+			return nil, fmt.Errorf("inserting widget: %w", err)
+		}
+		result.Widgets = append(result.Widgets, w)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing dashboard import: %w", err)
+	}
+	return result, nil
+}
+
+// uniqueDashboardName returns name, or name with the lowest numbered suffix from 2 up that no
+// dashboard uses
+func uniqueDashboardName(ctx context.Context, tx *sql.Tx, name string) (string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM dashboards")
+	if err != nil {
+		return "", fmt.Errorf("querying dashboard names: %w", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return "", fmt.Errorf("scanning dashboard name: %w", err)
+		}
+		taken[n] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("iterating dashboard names: %w", err)
+	}
+
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+	return candidate, nil
+}
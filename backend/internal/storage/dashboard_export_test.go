@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestExportImportDashboard(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	stacked := true
+	source, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{
+		Name:        "Usage",
+		Description: "Token usage",
+		IsDefault:   true,
+		Variables:   []api.DashboardVariable{{Name: "service", Type: api.VariableTypeQuery, OptionsQuery: api.VariableOptionsServices}},
+	})
+	if err != nil {
+		t.Fatalf("CreateDashboard failed: %v", err)
+	}
+	widgets := []api.CreateWidgetRequest{
+		{WidgetType: "stats", Title: "Totals", GridColumn: 0, GridRow: 0, ColSpan: 4, RowSpan: 1},
+		{WidgetType: "metric_chart", Title: "Tokens", GridColumn: 1, GridRow: 2, ColSpan: 2, RowSpan: 3, Config: api.WidgetConfig{
+			Service: "$service", MetricName: "claude_code.token.usage", BreakdownAttribute: "type", BreakdownValue: "input", ChartStacked: &stacked,
+		}},
+	}
+	for i := range widgets {
+		if _, err := store.CreateWidget(ctx, source.ID, &widgets[i]); err != nil {
+			t.Fatalf("CreateWidget failed: %v", err)
+		}
+	}
+
+	export, err := store.ExportDashboard(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("ExportDashboard failed: %v", err)
+	}
+	if export.Version != api.DashboardExportVersion || export.Name != "Usage" || export.Description != "Token usage" {
+		t.Errorf("unexpected export header: %+v", export)
+	}
+	if !reflect.DeepEqual(export.Widgets, widgets) {
+		t.Errorf("exported widgets = %+v, want %+v", export.Widgets, widgets)
+	}
+
+	// The export is plain JSON without ids
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("marshaling export: %v", err)
+	}
+	var blob api.DashboardExport
+	if err := json.Unmarshal(data, &blob); err != nil {
+		t.Fatalf("unmarshaling export: %v", err)
+	}
+
+	imported, err := store.ImportDashboard(ctx, &blob)
+	if err != nil {
+		t.Fatalf("ImportDashboard failed: %v", err)
+	}
+	if imported.ID == source.ID || imported.Name != "Usage (2)" || imported.IsDefault {
+		t.Errorf("expected a new, non-default dashboard with a unique name, got %+v", imported.Dashboard)
+	}
+
+	stored, err := store.GetDashboardWithWidgets(ctx, imported.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("GetDashboardWithWidgets failed: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Variables, export.Variables) {
+		t.Errorf("variables = %+v, want %+v", stored.Variables, export.Variables)
+	}
+	if len(stored.Widgets) != len(widgets) {
+		t.Fatalf("expected %d widgets, got %d", len(widgets), len(stored.Widgets))
+	}
+	for i, w := range stored.Widgets {
+		got := api.CreateWidgetRequest{
+			WidgetType: w.WidgetType, Title: w.Title, GridColumn: w.GridColumn, GridRow: w.GridRow,
+			ColSpan: w.ColSpan, RowSpan: w.RowSpan, Config: w.Config,
+		}
+		if !reflect.DeepEqual(got, widgets[i]) {
+			t.Errorf("widget %d = %+v, want %+v", i, got, widgets[i])
+		}
+		if w.DashboardID != imported.ID {
+			t.Errorf("widget %d belongs to %s, want %s", i, w.DashboardID, imported.ID)
+		}
+	}
+
+	again, err := store.ImportDashboard(ctx, &blob)
+	if err != nil {
+		t.Fatalf("ImportDashboard failed: %v", err)
+	}
+	if again.Name != "Usage (3)" {
+		t.Errorf("expected the next free name, got %q", again.Name)
+	}
+
+	missing, err := store.ExportDashboard(ctx, "no-such-dashboard")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown dashboard, got %+v, %v", missing, err)
+	}
+}
@@ -13,6 +13,25 @@ import (
 
 // Dashboard CRUD operations
 
+// dashboardColumns is the column list read by scanDashboard
+const dashboardColumns = "id, name, description, is_default, created_at, updated_at, CAST(variables AS VARCHAR)"
+
+// scanDashboard reads a dashboard selected with dashboardColumns
+func scanDashboard(row interface{ Scan(...interface{}) error }) (*api.Dashboard, error) {
+	var d api.Dashboard
+	var desc, variables sql.NullString
+	if err := row.Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt, &variables); err != nil {
+		return nil, err
+	}
+	d.Description = desc.String
+	if variables.Valid && variables.String != "" && variables.String != "null" {
+		if err := json.Unmarshal([]byte(variables.String), &d.Variables); err != nil {
+			return nil, fmt.Errorf("parsing variables of dashboard %s: %w", d.ID, err)
+		}
+	}
+	return &d, nil
+}
+
 func (s *DuckDBStore) CreateDashboard(ctx context.Context, req *api.CreateDashboardRequest) (*api.Dashboard, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -27,10 +46,15 @@ func (s *DuckDBStore) CreateDashboard(ctx context.Context, req *api.CreateDashbo
 		}
 	}
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO dashboards (id, name, description, is_default, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, id, req.Name, req.Description, req.IsDefault, now, now)
+	variablesJSON, err := json.Marshal(req.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling variables: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO dashboards (id, name, description, is_default, created_at, updated_at, variables)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, req.Name, req.Description, req.IsDefault, now, now, string(variablesJSON))
 	if err != nil {
 		return nil, fmt.Errorf("inserting dashboard: %w", err)
 	}
@@ -42,15 +66,28 @@ func (s *DuckDBStore) CreateDashboard(ctx context.Context, req *api.CreateDashbo
 		IsDefault:   req.IsDefault,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		Variables:   req.Variables,
 	}, nil
 }
 
+// CountDashboards returns the number of dashboards
+func (s *DuckDBStore) CountDashboards(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dashboards").Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting dashboards: %w", err)
+	}
+	return n, nil
+}
+
 func (s *DuckDBStore) GetDashboards(ctx context.Context) ([]api.Dashboard, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, description, is_default, created_at, updated_at
+		SELECT `+dashboardColumns+`
 		FROM dashboards
 		ORDER BY created_at DESC
 	`)
@@ -61,13 +98,11 @@ func (s *DuckDBStore) GetDashboards(ctx context.Context) ([]api.Dashboard, error
 
 	var dashboards []api.Dashboard
 	for rows.Next() {
-		var d api.Dashboard
-		var desc sql.NullString
-		if err := rows.Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		d, err := scanDashboard(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scanning dashboard: %w", err)
 		}
-		d.Description = desc.String
-		dashboards = append(dashboards, d)
+		dashboards = append(dashboards, *d)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterating dashboards: %w", err)
@@ -80,39 +115,33 @@ func (s *DuckDBStore) GetDashboard(ctx context.Context, id string) (*api.Dashboa
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var d api.Dashboard
-	var desc sql.NullString
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, description, is_default, created_at, updated_at
+	d, err := scanDashboard(s.db.QueryRowContext(ctx, `
+		SELECT `+dashboardColumns+`
 		FROM dashboards WHERE id = ?
-	`, id).Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt)
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("querying dashboard: %w", err)
 	}
-	d.Description = desc.String
-	return &d, nil
+	return d, nil
 }
 
 func (s *DuckDBStore) GetDefaultDashboard(ctx context.Context) (*api.DashboardWithWidgets, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var d api.Dashboard
-	var desc sql.NullString
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, description, is_default, created_at, updated_at
+	d, err := scanDashboard(s.db.QueryRowContext(ctx, `
+		SELECT `+dashboardColumns+`
 		FROM dashboards WHERE is_default = TRUE
-	`).Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt)
+	`))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("querying default dashboard: %w", err)
 	}
-	d.Description = desc.String
 
 	widgets, err := s.getWidgetsForDashboardLocked(ctx, d.ID)
 	if err != nil {
@@ -120,7 +149,37 @@ func (s *DuckDBStore) GetDefaultDashboard(ctx context.Context) (*api.DashboardWi
 	}
 
 	return &api.DashboardWithWidgets{
-		Dashboard: d,
+		Dashboard: *d,
+		Widgets:   widgets,
+	}, nil
+}
+
+// GetLatestDashboard returns the most recently updated dashboard with its widgets, or nil
+// when there are no dashboards
+func (s *DuckDBStore) GetLatestDashboard(ctx context.Context) (*api.DashboardWithWidgets, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, err := scanDashboard(s.db.QueryRowContext(ctx, `
+		SELECT `+dashboardColumns+`
+		FROM dashboards
+		ORDER BY updated_at DESC, created_at DESC, id
+		LIMIT 1
+	`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying latest dashboard: %w", err)
+	}
+
+	widgets, err := s.getWidgetsForDashboardLocked(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.DashboardWithWidgets{
+		Dashboard: *d,
 		Widgets:   widgets,
 	}, nil
 }
@@ -129,19 +188,16 @@ func (s *DuckDBStore) GetDashboardWithWidgets(ctx context.Context, id string) (*
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var d api.Dashboard
-	var desc sql.NullString
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, description, is_default, created_at, updated_at
+	d, err := scanDashboard(s.db.QueryRowContext(ctx, `
+		SELECT `+dashboardColumns+`
 		FROM dashboards WHERE id = ?
-	`, id).Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt)
+	`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("querying dashboard: %w", err)
 	}
-	d.Description = desc.String
 
 	widgets, err := s.getWidgetsForDashboardLocked(ctx, id)
 	if err != nil {
@@ -149,7 +205,7 @@ func (s *DuckDBStore) GetDashboardWithWidgets(ctx context.Context, id string) (*
 	}
 
 	return &api.DashboardWithWidgets{
-		Dashboard: d,
+		Dashboard: *d,
 		Widgets:   widgets,
 	}, nil
 }
@@ -170,17 +226,24 @@ func (s *DuckDBStore) UpdateDashboard(ctx context.Context, id string, req *api.U
 		return nil, fmt.Errorf("updating dashboard: %w", err)
 	}
 
-	var d api.Dashboard
-	var desc sql.NullString
-	err = s.db.QueryRowContext(ctx, `
-		SELECT id, name, description, is_default, created_at, updated_at
+	if req.Variables != nil {
+		variablesJSON, err := json.Marshal(req.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling variables: %w", err)
+		}
+		if _, err := s.db.ExecContext(ctx, "UPDATE dashboards SET variables = ? WHERE id = ?", string(variablesJSON), id); err != nil {
+			return nil, fmt.Errorf("updating dashboard variables: %w", err)
+		}
+	}
+
+	d, err := scanDashboard(s.db.QueryRowContext(ctx, `
+		SELECT `+dashboardColumns+`
 		FROM dashboards WHERE id = ?
-	`, id).Scan(&d.ID, &d.Name, &desc, &d.IsDefault, &d.CreatedAt, &d.UpdatedAt)
+	`, id))
 	if err != nil {
 		return nil, fmt.Errorf("fetching updated dashboard: %w", err)
 	}
-	d.Description = desc.String
-	return &d, nil
+	return d, nil
 }
 
 func (s *DuckDBStore) DeleteDashboard(ctx context.Context, id string) error {
@@ -219,6 +282,18 @@ func (s *DuckDBStore) SetDefaultDashboard(ctx context.Context, id string) error
 
 // Widget CRUD operations
 
+// CountWidgets returns the number of widgets on a dashboard
+func (s *DuckDBStore) CountWidgets(ctx context.Context, dashboardID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM dashboard_widgets WHERE dashboard_id = ?", dashboardID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting widgets: %w", err)
+	}
+	return n, nil
+}
+
 func (s *DuckDBStore) CreateWidget(ctx context.Context, dashboardID string, req *api.CreateWidgetRequest) (*api.DashboardWidget, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
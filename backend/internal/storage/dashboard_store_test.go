@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/tobilg/ai-observer/internal/api"
@@ -238,6 +239,49 @@ func TestGetDefaultDashboard(t *testing.T) {
 	}
 }
 
+func TestGetLatestDashboard(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	dashboard, err := store.GetLatestDashboard(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dashboard != nil {
+		t.Error("expected nil when no dashboards exist")
+	}
+
+	first, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{Name: "First"})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	second, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{Name: "Second"})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+
+	dashboard, err = store.GetLatestDashboard(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dashboard == nil || dashboard.Dashboard.ID != second.ID {
+		t.Fatalf("expected the last created dashboard %q, got %+v", second.ID, dashboard)
+	}
+
+	if _, err := store.UpdateDashboard(ctx, first.ID, &api.UpdateDashboardRequest{Name: "First (edited)"}); err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	dashboard, err = store.GetLatestDashboard(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dashboard == nil || dashboard.Dashboard.ID != first.ID {
+		t.Errorf("expected the updated dashboard %q, got %+v", first.ID, dashboard)
+	}
+}
+
 func TestSetDefaultDashboard(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -319,6 +363,54 @@ func TestUpdateDashboard(t *testing.T) {
 	}
 }
 
+func TestDashboardVariables(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	variables := []api.DashboardVariable{
+		{Name: "service", Type: api.VariableTypeQuery, Default: "claude-code", OptionsQuery: api.VariableOptionsServices},
+	}
+	created, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{Name: "Templated", Variables: variables})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+
+	got, err := store.GetDashboardWithWidgets(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("failed to get dashboard: %v", err)
+	}
+	if !reflect.DeepEqual(got.Variables, variables) {
+		t.Errorf("expected variables %+v, got %+v", variables, got.Variables)
+	}
+
+	// Updating other fields keeps the variables
+	updated, err := store.UpdateDashboard(ctx, created.ID, &api.UpdateDashboardRequest{Name: "Renamed"})
+	if err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	if !reflect.DeepEqual(updated.Variables, variables) {
+		t.Errorf("expected variables to be kept, got %+v", updated.Variables)
+	}
+
+	updated, err = store.UpdateDashboard(ctx, created.ID, &api.UpdateDashboardRequest{Variables: []api.DashboardVariable{}})
+	if err != nil {
+		t.Fatalf("failed to update dashboard: %v", err)
+	}
+	if len(updated.Variables) != 0 {
+		t.Errorf("expected variables to be removed, got %+v", updated.Variables)
+	}
+
+	// Dashboards created without variables list none
+	plain, err := store.CreateDashboard(ctx, &api.CreateDashboardRequest{Name: "Plain"})
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	if got, err := store.GetDashboard(ctx, plain.ID); err != nil || got.Variables != nil {
+		t.Errorf("expected no variables, got %+v (%v)", got, err)
+	}
+}
+
 func TestDeleteDashboard(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/tools"
+)
+
+// ErrUnknownTemplate is returned for dashboard template names not in DashboardTemplateNames
+var ErrUnknownTemplate = errors.New("unknown dashboard template")
+
+// Widget types used by the templates, as rendered by the frontend
+const (
+	templateWidgetErrorRate      = "stats_error_rate"
+	templateWidgetTraces         = "stats_traces"
+	templateWidgetRecentSessions = "recent_sessions"
+	templateWidgetMetricValue    = "metric_value"
+	templateWidgetMetricChart    = "metric_chart"
+)
+
+// toolOverviewTemplate lays out the overview of one tool: headline cost, tokens and error
+// rate, token usage by type and cost by model over time, and the tool's latest sessions. The
+// metric names are the ones both the tool's OTLP telemetry and the session importers emit.
+// Grid positions are 1-based, like the frontend's four-column grid.
+func toolOverviewTemplate(tool tools.Tool, title, metricPrefix string) api.DashboardExport {
+	service := tool.ServiceName()
+	tokens := metricPrefix + ".token.usage"
+	cost := metricPrefix + ".cost.usage"
+	return api.DashboardExport{
+		Version:     api.DashboardExportVersion,
+		Name:        title + " Overview",
+		Description: "Token usage, cost and errors of " + title,
+		Widgets: []api.CreateWidgetRequest{
+			{WidgetType: templateWidgetMetricValue, Title: "Cost (USD)", GridColumn: 1, GridRow: 1, ColSpan: 1, RowSpan: 1, Config: api.WidgetConfig{Service: service, MetricName: cost}},
+			{WidgetType: templateWidgetMetricValue, Title: "Tokens", GridColumn: 2, GridRow: 1, ColSpan: 1, RowSpan: 1, Config: api.WidgetConfig{Service: service, MetricName: tokens}},
+			{WidgetType: templateWidgetErrorRate, Title: "Error Rate", GridColumn: 3, GridRow: 1, ColSpan: 1, RowSpan: 1},
+			{WidgetType: templateWidgetTraces, Title: "Traces", GridColumn: 4, GridRow: 1, ColSpan: 1, RowSpan: 1},
+			{WidgetType: templateWidgetMetricChart, Title: "Token Usage", GridColumn: 1, GridRow: 2, ColSpan: 2, RowSpan: 1, Config: api.WidgetConfig{Service: service, MetricName: tokens, BreakdownAttribute: "type"}},
+			{WidgetType: templateWidgetMetricChart, Title: "Cost by Model", GridColumn: 3, GridRow: 2, ColSpan: 2, RowSpan: 1, Config: api.WidgetConfig{Service: service, MetricName: cost, BreakdownAttribute: "model"}},
+			{WidgetType: templateWidgetRecentSessions, Title: "Recent Sessions", GridColumn: 1, GridRow: 3, ColSpan: 4, RowSpan: 1, Config: api.WidgetConfig{Service: service}},
+		},
+	}
+}
+
+// costTrackingTemplate compares the cost of all tools: one total and one cost-by-model chart
+// per tool
+func costTrackingTemplate() api.DashboardExport {
+	sources := []struct {
+		tool         tools.Tool
+		title        string
+		metricPrefix string
+	}{
+		{tools.Claude, "Claude Code", "claude_code"},
+		{tools.Codex, "Codex CLI", "codex_cli_rs"},
+		{tools.Gemini, "Gemini CLI", "gemini_cli"},
+	}
+
+	t := api.DashboardExport{
+		Version:     api.DashboardExportVersion,
+		Name:        "Cost Tracking",
+		Description: "Cost per tool and model",
+	}
+	for i, src := range sources {
+		config := api.WidgetConfig{Service: src.tool.ServiceName(), MetricName: src.metricPrefix + ".cost.usage"}
+		t.Widgets = append(t.Widgets, api.CreateWidgetRequest{
+			WidgetType: templateWidgetMetricValue, Title: src.title + " Cost (USD)", GridColumn: i + 1, GridRow: 1, ColSpan: 1, RowSpan: 1, Config: config,
+		})
+		config.BreakdownAttribute = "model"
+		t.Widgets = append(t.Widgets, api.CreateWidgetRequest{
+			WidgetType: templateWidgetMetricChart, Title: src.title + " Cost by Model", GridColumn: 1, GridRow: i + 2, ColSpan: 4, RowSpan: 1, Config: config,
+		})
+	}
+	return t
+}
+
+// dashboardTemplates are the curated dashboards offered by CreateDashboardFromTemplate
+var dashboardTemplates = map[string]api.DashboardExport{
+	"claude-overview": toolOverviewTemplate(tools.Claude, "Claude Code", "claude_code"),
+	"codex-overview":  toolOverviewTemplate(tools.Codex, "Codex CLI", "codex_cli_rs"),
+	"gemini-overview": toolOverviewTemplate(tools.Gemini, "Gemini CLI", "gemini_cli"),
+	"cost-tracking":   costTrackingTemplate(),
+}
+
+// DashboardTemplateNames returns the names of the dashboard templates in sorted order
+func DashboardTemplateNames() []string {
+	names := make([]string, 0, len(dashboardTemplates))
+	for name := range dashboardTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DashboardTemplate returns a copy of the named dashboard template
+func DashboardTemplate(name string) (api.DashboardExport, bool) {
+	t, ok := dashboardTemplates[name]
+	if ok {
+		t.Widgets = append([]api.CreateWidgetRequest(nil), t.Widgets...)
+	}
+	return t, ok
+}
+
+// CreateDashboardFromTemplate creates a dashboard from a template, like ImportDashboard does
+// from an export, so its name gets a numbered suffix when taken. Widgets only reference
+// metric names; one whose metric has no data yet renders empty until the data arrives.
+func (s *DuckDBStore) CreateDashboardFromTemplate(ctx context.Context, name string) (*api.DashboardWithWidgets, error) {
+	t, ok := DashboardTemplate(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+	}
+	return s.ImportDashboard(ctx, &t)
+}
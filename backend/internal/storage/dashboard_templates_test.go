@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateDashboardFromTemplate(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	want := []string{"claude-overview", "codex-overview", "cost-tracking", "gemini-overview"}
+	if names := DashboardTemplateNames(); !reflect.DeepEqual(names, want) {
+		t.Fatalf("DashboardTemplateNames() = %v, want %v", names, want)
+	}
+
+	for _, name := range want {
+		t.Run(name, func(t *testing.T) {
+			dashboard, err := store.CreateDashboardFromTemplate(ctx, name)
+			if err != nil {
+				t.Fatalf("CreateDashboardFromTemplate failed: %v", err)
+			}
+			if dashboard.Name == "" || len(dashboard.Widgets) == 0 {
+				t.Fatalf("expected a named dashboard with widgets, got %+v", dashboard)
+			}
+
+			// Widgets fit the 1-based four-column grid without overlapping
+			cells := make(map[[2]int]string)
+			for _, w := range dashboard.Widgets {
+				if w.GridColumn < 1 || w.GridRow < 1 || w.GridColumn+w.ColSpan-1 > 4 {
+					t.Errorf("widget %q exceeds the grid", w.Title)
+				}
+				for c := w.GridColumn; c < w.GridColumn+w.ColSpan; c++ {
+					for r := w.GridRow; r < w.GridRow+w.RowSpan; r++ {
+						if other, ok := cells[[2]int{c, r}]; ok {
+							t.Errorf("widgets %q and %q overlap", other, w.Title)
+						}
+						cells[[2]int{c, r}] = w.Title
+					}
+				}
+				if m := w.Config.MetricName; m != "" && !strings.HasSuffix(m, ".token.usage") && !strings.HasSuffix(m, ".cost.usage") {
+					t.Errorf("widget %q uses unexpected metric %q", w.Title, m)
+				}
+			}
+		})
+	}
+
+	// A second dashboard from the same template gets a unique name
+	again, err := store.CreateDashboardFromTemplate(ctx, "cost-tracking")
+	if err != nil {
+		t.Fatalf("CreateDashboardFromTemplate failed: %v", err)
+	}
+	if again.Name != "Cost Tracking (2)" {
+		t.Errorf("expected a unique name, got %q", again.Name)
+	}
+
+	if _, err := store.CreateDashboardFromTemplate(ctx, "nope"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("expected ErrUnknownTemplate, got %v", err)
+	}
+}
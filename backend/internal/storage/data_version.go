@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// markModified bumps the data version after telemetry was inserted or deleted
+func (s *DuckDBStore) markModified() {
+	s.version.Add(1)
+	s.modifiedAt.Store(time.Now().UnixNano())
+}
+
+// DataVersion returns a counter incremented on every telemetry write and the time of the
+// latest write. Data present when the store was opened counts as modified at open time.
+func (s *DuckDBStore) DataVersion() (uint64, time.Time) {
+	return s.version.Load(), time.Unix(0, s.modifiedAt.Load())
+}
+
+// GetDataTimeRange returns the earliest and latest timestamps across traces, logs and metrics.
+// Both are nil when no telemetry is stored.
+func (s *DuckDBStore) GetDataTimeRange(ctx context.Context) (*api.DataTimeRangeResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT MIN(min_ts), MAX(max_ts) FROM (
+			SELECT MIN(Timestamp) as min_ts, MAX(Timestamp) as max_ts FROM otel_traces
+			UNION ALL
+			SELECT MIN(Timestamp), MAX(Timestamp) FROM otel_logs
+			UNION ALL
+			SELECT MIN(Timestamp), MAX(Timestamp) FROM otel_metrics
+		)
+	`
+
+	var from, to sql.NullTime
+	if err := s.db.QueryRowContext(ctx, query).Scan(&from, &to); err != nil {
+		return nil, fmt.Errorf("querying data time range: %w", err)
+	}
+
+	resp := &api.DataTimeRangeResponse{}
+	if from.Valid {
+		resp.From = &from.Time
+	}
+	if to.Valid {
+		resp.To = &to.Time
+	}
+	return resp, nil
+}
+
+// NextTimestampAfter returns the earliest timestamp later than after across traces, logs and
+// metrics, or nil when no telemetry is stored after it
+func (s *DuckDBStore) NextTimestampAfter(ctx context.Context, after time.Time) (*time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT MIN(ts) FROM (
+			SELECT MIN(Timestamp) AS ts FROM otel_traces WHERE Timestamp > ?::TIMESTAMP
+			UNION ALL
+			SELECT MIN(Timestamp) FROM otel_logs WHERE Timestamp > ?::TIMESTAMP
+			UNION ALL
+			SELECT MIN(Timestamp) FROM otel_metrics WHERE Timestamp > ?::TIMESTAMP
+		)
+	`
+
+	afterStr := formatTimeForDB(after)
+	var next sql.NullTime
+	if err := s.db.QueryRowContext(ctx, query, afterStr, afterStr, afterStr).Scan(&next); err != nil {
+		return nil, fmt.Errorf("querying next timestamp: %w", err)
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
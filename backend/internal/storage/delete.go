@@ -124,6 +124,10 @@ func (s *DuckDBStore) DeleteLogsInRange(ctx context.Context, from, to time.Time,
 	if err != nil {
 		return 0, fmt.Errorf("getting rows affected: %w", err)
 	}
+	if count > 0 {
+		s.markLogsModified()
+		s.invalidateLogSearchIndex()
+	}
 
 	return count, nil
 }
@@ -153,6 +157,9 @@ func (s *DuckDBStore) DeleteMetricsInRange(ctx context.Context, from, to time.Ti
 	if err != nil {
 		return 0, fmt.Errorf("getting rows affected: %w", err)
 	}
+	if count > 0 {
+		s.markModified()
+	}
 
 	return count, nil
 }
@@ -182,6 +189,9 @@ func (s *DuckDBStore) DeleteTracesInRange(ctx context.Context, from, to time.Tim
 	if err != nil {
 		return 0, fmt.Errorf("getting rows affected: %w", err)
 	}
+	if count > 0 {
+		s.markModified()
+	}
 
 	return count, nil
 }
@@ -234,3 +244,14 @@ func (s *DuckDBStore) DeleteAllInRange(ctx context.Context, from, to time.Time,
 		SpanCount:   spanCount,
 	}, nil
 }
+
+// Checkpoint writes the WAL into the database file and reclaims space freed by deletes
+func (s *DuckDBStore) Checkpoint(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, "CHECKPOINT"); err != nil {
+		return fmt.Errorf("checkpointing database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// GetDroppedCounts sums the dropped attribute, event and link counts reported by OTLP SDKs on
+// spans and logs within [from, to], per service. Services that dropped nothing are omitted.
+func (s *DuckDBStore) GetDroppedCounts(ctx context.Context, from, to time.Time) (*api.DroppedDataResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	query := `
+		WITH span_drops AS (
+			SELECT
+				ServiceName,
+				COUNT(*) as spans_with_drops,
+				SUM(COALESCE(DroppedAttributesCount, 0)) as attrs,
+				SUM(COALESCE(DroppedEventsCount, 0)) as events,
+				SUM(COALESCE(DroppedLinksCount, 0)) as links
+			FROM otel_traces
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND (DroppedAttributesCount > 0 OR DroppedEventsCount > 0 OR DroppedLinksCount > 0)
+			GROUP BY ServiceName
+		),
+		log_drops AS (
+			SELECT
+				ServiceName,
+				COUNT(*) as logs_with_drops,
+				SUM(DroppedAttributesCount) as attrs
+			FROM otel_logs
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND DroppedAttributesCount > 0
+			GROUP BY ServiceName
+		)
+		SELECT
+			COALESCE(s.ServiceName, l.ServiceName) as service,
+			COALESCE(s.spans_with_drops, 0),
+			COALESCE(s.attrs, 0),
+			COALESCE(s.events, 0),
+			COALESCE(s.links, 0),
+			COALESCE(l.logs_with_drops, 0),
+			COALESCE(l.attrs, 0)
+		FROM span_drops s
+		FULL OUTER JOIN log_drops l ON s.ServiceName = l.ServiceName
+		ORDER BY service
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, fromStr, toStr, fromStr, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("querying dropped counts: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &api.DroppedDataResponse{Services: []api.ServiceDroppedCounts{}}
+	for rows.Next() {
+		var c api.ServiceDroppedCounts
+		if err := rows.Scan(
+			&c.ServiceName, &c.SpansWithDrops, &c.SpanDroppedAttributes, &c.SpanDroppedEvents,
+			&c.SpanDroppedLinks, &c.LogsWithDrops, &c.LogDroppedAttributes,
+		); err != nil {
+			return nil, fmt.Errorf("scanning dropped counts: %w", err)
+		}
+		resp.Services = append(resp.Services, c)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetDroppedCounts(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "claude-code", SpanName: "a", Timestamp: now, DroppedAttributesCount: 3, DroppedEventsCount: 1},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "claude-code", SpanName: "b", Timestamp: now, DroppedLinksCount: 2},
+		{TraceID: "t1", SpanID: "s3", ServiceName: "claude-code", SpanName: "c", Timestamp: now},
+		{TraceID: "t2", SpanID: "s4", ServiceName: "codex", SpanName: "d", Timestamp: now},
+		{TraceID: "t3", SpanID: "s5", ServiceName: "claude-code", SpanName: "old", Timestamp: now.Add(-48 * time.Hour), DroppedAttributesCount: 100},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "gemini-cli", Body: "a", DroppedAttributesCount: 4},
+		{Timestamp: now, ServiceName: "claude-code", Body: "b", DroppedAttributesCount: 5},
+		{Timestamp: now, ServiceName: "codex", Body: "c"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	resp, err := store.GetDroppedCounts(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetDroppedCounts failed: %v", err)
+	}
+
+	want := []api.ServiceDroppedCounts{
+		{ServiceName: "claude-code", SpansWithDrops: 2, SpanDroppedAttributes: 3, SpanDroppedEvents: 1, SpanDroppedLinks: 2, LogsWithDrops: 1, LogDroppedAttributes: 5},
+		{ServiceName: "gemini-cli", LogsWithDrops: 1, LogDroppedAttributes: 4},
+	}
+	if len(resp.Services) != len(want) {
+		t.Fatalf("expected %d services, got %+v", len(want), resp.Services)
+	}
+	for i := range want {
+		if resp.Services[i] != want[i] {
+			t.Errorf("service %d: expected %+v, got %+v", i, want[i], resp.Services[i])
+		}
+	}
+}
+
+func TestDroppedCountsRoundTrip(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	span := api.Span{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "a", Timestamp: now, DroppedAttributesCount: 7, DroppedEventsCount: 8, DroppedLinksCount: 9}
+	if err := store.InsertSpans(ctx, []api.Span{span}); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	if err := store.InsertLogs(ctx, []api.LogRecord{{Timestamp: now, ServiceName: "svc", Body: "x", DroppedAttributesCount: 6}}); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	spans, err := store.GetTraceSpans(ctx, "t1")
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+	if len(spans) != 1 || spans[0].DroppedAttributesCount != 7 || spans[0].DroppedEventsCount != 8 || spans[0].DroppedLinksCount != 9 {
+		t.Errorf("unexpected span dropped counts: %+v", spans)
+	}
+
+	logs, err := store.QueryLogs(ctx, "svc", "", SeverityRange{}, "", "", now.Add(-time.Hour), now.Add(time.Hour), 10, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if len(logs.Logs) != 1 || logs.Logs[0].DroppedAttributesCount != 6 {
+		t.Errorf("unexpected log dropped counts: %+v", logs.Logs)
+	}
+}
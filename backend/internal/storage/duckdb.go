@@ -7,14 +7,25 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/duckdb/duckdb-go/v2"
 )
 
 type DuckDBStore struct {
-	db *sql.DB
-	mu sync.RWMutex
+	db   *sql.DB
+	mu   sync.RWMutex
+	path string
+
+	// Telemetry write tracking, see DataVersion
+	version     atomic.Uint64
+	modifiedAt  atomic.Int64
+	logsVersion atomic.Uint64
+
+	// Full-text index over log bodies, see RefreshLogSearchIndex
+	logSearch logSearchIndex
+	refreshMu sync.Mutex // Serializes index rebuilds
 }
 
 func NewDuckDBStore(dbPath string) (*DuckDBStore, error) {
@@ -40,7 +51,8 @@ func NewDuckDBStore(dbPath string) (*DuckDBStore, error) {
 		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
 
-	store := &DuckDBStore{db: db}
+	store := &DuckDBStore{db: db, path: dbPath}
+	store.modifiedAt.Store(time.Now().UnixNano())
 
 	// Initialize schema
 	if err := store.initSchema(context.Background()); err != nil {
@@ -51,6 +63,28 @@ func NewDuckDBStore(dbPath string) (*DuckDBStore, error) {
 	return store, nil
 }
 
+// OpenReadOnly opens an existing database without write access or schema initialization.
+// Used by CLI commands that only inspect data (e.g. stats).
+func OpenReadOnly(dbPath string) (*DuckDBStore, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	db, err := sql.Open("duckdb", dbPath+"?access_mode=read_only")
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+
+	store := &DuckDBStore{db: db, path: dbPath}
+	store.modifiedAt.Store(time.Now().UnixNano())
+	return store, nil
+}
+
 func (s *DuckDBStore) Close() error {
 	return s.db.Close()
 }
@@ -73,11 +107,22 @@ func (s *DuckDBStore) initSchema(ctx context.Context) error {
 		schemaDashboards,
 		schemaDashboardWidgets,
 		schemaImportState,
+		schemaAnnotations,
+		schemaAlertRules,
+		schemaAlertEvents,
+		migrateScopeAttributes,
+		migrateDroppedCounts,
+		migrateImportStateCounts,
+		migrateImportStateCheckpoint,
+		migrateDashboardVariables,
+		migrateLogEventBodies,
 		indexTraces,
 		indexLogs,
 		indexMetrics,
 		indexDashboards,
 		indexImportState,
+		indexAnnotations,
+		indexAlertEvents,
 	}
 
 	for _, schema := range schemas {
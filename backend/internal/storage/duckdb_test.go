@@ -2,8 +2,14 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -66,6 +72,149 @@ func TestNewDuckDBStore_InitializesSchema(t *testing.T) {
 	}
 }
 
+func TestNewDuckDBStore_MigratesScopeAttributes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.duckdb")
+
+	// Simulate a database created before scope attributes were stored
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	for _, schema := range []string{schemaTraces, schemaMetrics} {
+		legacy := strings.Replace(schema, ",\n    ScopeAttributes         JSON", "", 1)
+		legacy = strings.Replace(legacy, droppedSpanColumns, "", 1)
+		if _, err := db.Exec(legacy); err != nil {
+			t.Fatalf("creating legacy table: %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewDuckDBStore() error = %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	span := api.Span{
+		Timestamp: time.Now(), TraceID: "trace-1", SpanID: "span-1", SpanName: "op", ServiceName: "svc",
+		ScopeAttributes: map[string]string{"scope.kind": "cli"},
+	}
+	if err := store.InsertSpans(ctx, []api.Span{span}); err != nil {
+		t.Fatalf("InsertSpans after migration: %v", err)
+	}
+
+	spans, err := store.GetTraceSpans(ctx, "trace-1")
+	if err != nil || len(spans) != 1 {
+		t.Fatalf("GetTraceSpans: got %d spans, err %v", len(spans), err)
+	}
+	if spans[0].ScopeAttributes["scope.kind"] != "cli" {
+		t.Errorf("ScopeAttributes = %v, want scope.kind=cli", spans[0].ScopeAttributes)
+	}
+}
+
+// droppedSpanColumns are the otel_traces columns added by migrateDroppedCounts
+const droppedSpanColumns = `,
+    DroppedAttributesCount  UINTEGER,
+    DroppedEventsCount      UINTEGER,
+    DroppedLinksCount       UINTEGER`
+
+func TestNewDuckDBStore_MigratesDroppedCounts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.duckdb")
+
+	// Simulate a database created before dropped counts were stored
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	legacyTraces := strings.Replace(schemaTraces, droppedSpanColumns, "", 1)
+	legacyLogs := strings.Replace(schemaLogs, ",\n    DroppedAttributesCount  UINTEGER", "", 1)
+	for _, schema := range []string{legacyTraces, legacyLogs} {
+		if _, err := db.Exec(schema); err != nil {
+			t.Fatalf("creating legacy table: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO otel_logs (Timestamp, ServiceName, Body, TraceFlags, SeverityNumber) VALUES (now(), 'svc', 'old', 0, 0)`); err != nil {
+		t.Fatalf("inserting legacy log: %v", err)
+	}
+	db.Close()
+
+	store, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewDuckDBStore() error = %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	span := api.Span{
+		Timestamp: time.Now(), TraceID: "trace-1", SpanID: "span-1", SpanName: "op", ServiceName: "svc",
+		DroppedAttributesCount: 2, DroppedEventsCount: 3, DroppedLinksCount: 4,
+	}
+	if err := store.InsertSpans(ctx, []api.Span{span}); err != nil {
+		t.Fatalf("InsertSpans after migration: %v", err)
+	}
+
+	spans, err := store.GetTraceSpans(ctx, "trace-1")
+	if err != nil || len(spans) != 1 {
+		t.Fatalf("GetTraceSpans: got %d spans, err %v", len(spans), err)
+	}
+	if spans[0].DroppedAttributesCount != 2 || spans[0].DroppedEventsCount != 3 || spans[0].DroppedLinksCount != 4 {
+		t.Errorf("unexpected dropped counts: %+v", spans[0])
+	}
+
+	// Rows written before the migration read back as zero
+	logs, err := store.QueryLogs(ctx, "svc", "", SeverityRange{}, "", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10, 0)
+	if err != nil || len(logs.Logs) != 1 {
+		t.Fatalf("QueryLogs: got %+v, err %v", logs, err)
+	}
+	if logs.Logs[0].DroppedAttributesCount != 0 {
+		t.Errorf("DroppedAttributesCount = %d, want 0", logs.Logs[0].DroppedAttributesCount)
+	}
+}
+
+func TestNewDuckDBStore_MigratesLogEventBodies(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.duckdb")
+
+	// Simulate logs stored while ingestion still copied event.name into empty bodies
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if _, err := db.Exec(schemaLogs); err != nil {
+		t.Fatalf("creating logs table: %v", err)
+	}
+	for _, row := range []struct{ body, attrs string }{
+		{"codex.tool_decision", `{"event.name": "codex.tool_decision"}`},
+		{"api_request", `{"event.name": "api_request", "import_source": "local_jsonl"}`},
+		{"Tool call: shell", `{"event.name": "codex.tool_decision"}`},
+	} {
+		if _, err := db.Exec(`INSERT INTO otel_logs (Timestamp, ServiceName, Body, LogAttributes, TraceFlags, SeverityNumber) VALUES (now(), 'svc', ?, ?, 0, 0)`, row.body, row.attrs); err != nil {
+			t.Fatalf("inserting legacy log: %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := NewDuckDBStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewDuckDBStore() error = %v", err)
+	}
+	defer store.Close()
+
+	logs, err := store.QueryLogs(context.Background(), "svc", "", SeverityRange{}, "", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 10, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	var bodies []string
+	for _, l := range logs.Logs {
+		bodies = append(bodies, l.Body)
+	}
+	slices.Sort(bodies)
+	// Only the OTLP record whose body was its event name gets its empty body back
+	if want := []string{"", "Tool call: shell", "api_request"}; !slices.Equal(bodies, want) {
+		t.Errorf("bodies = %q, want %q", bodies, want)
+	}
+}
+
 func TestDuckDBStore_Close(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.duckdb")
@@ -191,7 +340,7 @@ func TestQueryTraces(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now.Add(1 * time.Hour)
 
-	resp, err := store.QueryTraces(ctx, "", "", from, to, 10, 0)
+	resp, err := store.QueryTraces(ctx, "", "", "", from, to, 10, 0)
 	if err != nil {
 		t.Fatalf("QueryTraces failed: %v", err)
 	}
@@ -217,7 +366,7 @@ func TestQueryTraces_WithServiceFilter(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now.Add(1 * time.Hour)
 
-	resp, err := store.QueryTraces(ctx, "service-a", "", from, to, 10, 0)
+	resp, err := store.QueryTraces(ctx, "service-a", "", "", from, to, 10, 0)
 	if err != nil {
 		t.Fatalf("QueryTraces failed: %v", err)
 	}
@@ -234,94 +383,698 @@ func TestQueryTraces_EmptyResult(t *testing.T) {
 	from := time.Now().Add(-1 * time.Hour)
 	to := time.Now()
 
-	resp, err := store.QueryTraces(context.Background(), "", "", from, to, 10, 0)
+	resp, err := store.QueryTraces(context.Background(), "", "", "", from, to, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+
+	if resp.Total != 0 {
+		t.Errorf("expected 0 traces, got %d", resp.Total)
+	}
+	if len(resp.Traces) != 0 {
+		t.Errorf("expected empty traces slice, got %d", len(resp.Traces))
+	}
+}
+
+func TestQueryTraces_WithEventFilter(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{
+		{TraceID: "trace-001", SpanID: "span-001", ServiceName: "service-a", SpanName: "failing", Timestamp: now, StatusCode: "ERROR",
+			Events: []api.SpanEvent{{Timestamp: now, Name: "exception", Attributes: map[string]string{"exception.type": "ValueError"}}}},
+		{TraceID: "trace-002", SpanID: "span-002", ServiceName: "service-a", SpanName: "logging", Timestamp: now.Add(10 * time.Millisecond), StatusCode: "OK",
+			Events: []api.SpanEvent{{Timestamp: now, Name: "log"}}},
+		{TraceID: "trace-003", SpanID: "span-003", ServiceName: "service-b", SpanName: "plain", Timestamp: now.Add(20 * time.Millisecond), StatusCode: "OK"},
+		{TraceID: "codex-trace", SpanID: "codex-span", ServiceName: "codex_cli_rs", SpanName: "codex-failing", Timestamp: now.Add(30 * time.Millisecond), StatusCode: "ERROR",
+			Events: []api.SpanEvent{{Timestamp: now, Name: "log"}, {Timestamp: now, Name: "exception"}}},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	from := now.Add(-1 * time.Hour)
+	to := now.Add(1 * time.Hour)
+
+	resp, err := store.QueryTraces(ctx, "", "", "exception", from, to, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Traces) != 2 {
+		t.Fatalf("expected 2 traces with an exception event, got total=%d len=%d", resp.Total, len(resp.Traces))
+	}
+	got := map[string]bool{}
+	for _, tr := range resp.Traces {
+		got[tr.RootSpan] = true
+	}
+	if !got["failing"] || !got["codex-failing"] {
+		t.Errorf("expected failing and codex-failing, got %v", got)
+	}
+
+	count, err := store.CountTraces(ctx, "", "", "exception", from, to)
+	if err != nil {
+		t.Fatalf("CountTraces failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected count 2, got %d", count)
+	}
+
+	resp, err = store.QueryTraces(ctx, "service-b", "", "exception", from, to, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+	if resp.Total != 0 {
+		t.Errorf("expected no service-b traces with an exception event, got %d", resp.Total)
+	}
+}
+
+func TestQueryTraces_EventOnChildSpan(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	exception := []api.SpanEvent{{Timestamp: now, Name: "exception"}}
+
+	// Only a child span of each trace carries the event
+	spans := []api.Span{
+		{TraceID: "trace-001", SpanID: "root", ServiceName: "service-a", SpanName: "request", Timestamp: now, Duration: 50_000_000, StatusCode: "OK"},
+		{TraceID: "trace-001", SpanID: "child", ParentSpanID: "root", ServiceName: "service-a", SpanName: "query", Timestamp: now.Add(10 * time.Millisecond), Duration: 10_000_000, StatusCode: "OK", Events: exception},
+		{TraceID: "codex-trace", SpanID: "codex-root", ServiceName: "codex_cli_rs", SpanName: "session", Timestamp: now.Add(time.Second), StatusCode: "OK"},
+		{TraceID: "codex-trace", SpanID: "codex-child", ParentSpanID: "codex-root", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now.Add(time.Second + time.Millisecond), StatusCode: "OK"},
+		{TraceID: "codex-trace", SpanID: "codex-grandchild", ParentSpanID: "codex-child", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now.Add(time.Second + 2*time.Millisecond), StatusCode: "OK", Events: exception},
+		{TraceID: "codex-trace", SpanID: "codex-other", ServiceName: "codex_cli_rs", SpanName: "other-session", Timestamp: now.Add(2 * time.Second), StatusCode: "OK"},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	resp, err := store.QueryTraces(ctx, "", "", "exception", from, to, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Traces) != 2 {
+		t.Fatalf("expected 2 traces, got total=%d len=%d", resp.Total, len(resp.Traces))
+	}
+	got := map[string]api.TraceOverview{}
+	for _, tr := range resp.Traces {
+		got[tr.RootSpan] = tr
+	}
+	if tr := got["request"]; tr.SpanCount != 2 || tr.Duration != 50_000_000 {
+		t.Errorf("expected the whole trace-001 (2 spans, root duration), got %+v", tr)
+	}
+	if tr := got["session"]; tr.SpanCount != 3 {
+		t.Errorf("expected the whole codex session subtree (3 spans), got %+v", tr)
+	}
+
+	count, err := store.CountTraces(ctx, "", "", "exception", from, to)
+	if err != nil || count != 2 {
+		t.Errorf("expected count 2, got %d, %v", count, err)
+	}
+}
+
+func TestQueryTraces_DurationUsesRootSpan(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+	ms := int64(time.Millisecond)
+
+	spans := []api.Span{
+		// Nested trace whose child is clock-skewed: it appears to start before and end
+		// after its 100ms root
+		{TraceID: "nested", SpanID: "root", ServiceName: "svc", SpanName: "root", Timestamp: now, Duration: 100 * ms},
+		{TraceID: "nested", SpanID: "child", ParentSpanID: "root", ServiceName: "svc", SpanName: "child", Timestamp: now.Add(-20 * time.Millisecond), Duration: 150 * ms},
+		// Two parallel roots: 0-50ms and 30-120ms
+		{TraceID: "parallel", SpanID: "a", ServiceName: "svc", SpanName: "a", Timestamp: now.Add(time.Second), Duration: 50 * ms},
+		{TraceID: "parallel", SpanID: "b", ServiceName: "svc", SpanName: "b", Timestamp: now.Add(time.Second + 30*time.Millisecond), Duration: 90 * ms},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	want := map[string]int64{"nested": 100 * ms, "parallel": 120 * ms}
+	check := func(name string, traces []api.TraceOverview) {
+		t.Helper()
+		if len(traces) != len(want) {
+			t.Fatalf("%s: expected %d traces, got %d", name, len(want), len(traces))
+		}
+		for _, tr := range traces {
+			if tr.Duration != want[tr.TraceID] {
+				t.Errorf("%s: trace %s duration = %d, want %d", name, tr.TraceID, tr.Duration, want[tr.TraceID])
+			}
+		}
+	}
+
+	resp, err := store.QueryTraces(ctx, "", "", "", now.Add(-time.Hour), now.Add(time.Hour), 10, 0)
 	if err != nil {
 		t.Fatalf("QueryTraces failed: %v", err)
 	}
+	check("QueryTraces", resp.Traces)
+
+	recent, err := store.GetRecentTraces(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRecentTraces failed: %v", err)
+	}
+	check("GetRecentTraces", recent.Traces)
+}
+
+func TestGetTraceSpans(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{
+		{TraceID: "trace-001", SpanID: "span-001", ServiceName: "test-service", SpanName: "root", Timestamp: now, StatusCode: "OK"},
+		{TraceID: "trace-001", SpanID: "span-002", ParentSpanID: "span-001", ServiceName: "test-service", SpanName: "child1", Timestamp: now.Add(10 * time.Millisecond), StatusCode: "OK"},
+		{TraceID: "trace-001", SpanID: "span-003", ParentSpanID: "span-001", ServiceName: "test-service", SpanName: "child2", Timestamp: now.Add(20 * time.Millisecond), StatusCode: "OK"},
+		{TraceID: "trace-002", SpanID: "span-004", ServiceName: "other-service", SpanName: "other", Timestamp: now, StatusCode: "OK"},
+	}
+	store.InsertSpans(ctx, spans)
+
+	traceSpans, err := store.GetTraceSpans(ctx, "trace-001")
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+
+	if len(traceSpans) != 3 {
+		t.Errorf("expected 3 spans for trace-001, got %d", len(traceSpans))
+	}
+}
+
+func TestGetTraceSpans_NotFound(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	spans, err := store.GetTraceSpans(context.Background(), "nonexistent-trace")
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+
+	if len(spans) != 0 {
+		t.Errorf("expected 0 spans for nonexistent trace, got %d", len(spans))
+	}
+}
+
+func TestCodexVirtualTraceIDRoundTrip(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	spans := []api.Span{
+		{TraceID: "c1", SpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now.Add(-time.Minute)},
+		{TraceID: "c1", SpanID: "tool", ParentSpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now.Add(-time.Minute + time.Millisecond)},
+		{TraceID: "t1", SpanID: "root", ServiceName: "claude-code", SpanName: "claude.turn", Timestamp: now.Add(-2 * time.Minute)},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	listed, err := store.QueryTraces(ctx, "", "", "", now.Add(-time.Hour), now, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryTraces failed: %v", err)
+	}
+	recent, err := store.GetRecentTraces(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRecentTraces failed: %v", err)
+	}
+	for name, resp := range map[string]*api.TracesResponse{"QueryTraces": listed, "GetRecentTraces": recent} {
+		if len(resp.Traces) != 2 || resp.Traces[0].TraceID != "codex:turn" || resp.Traces[1].TraceID != "t1" {
+			t.Fatalf("%s: expected the prefixed codex trace and t1, got %+v", name, resp.Traces)
+		}
+	}
+	if !IsCodexTraceID(listed.Traces[0].TraceID) || IsCodexTraceID(listed.Traces[1].TraceID) {
+		t.Error("IsCodexTraceID should only recognize the virtual trace")
+	}
+
+	// The listed ID resolves to the virtual trace's subtree; the bare span ID still works
+	for _, traceID := range []string{listed.Traces[0].TraceID, "turn"} {
+		got, err := store.GetTraceSpans(ctx, traceID)
+		if err != nil {
+			t.Fatalf("GetTraceSpans(%s) failed: %v", traceID, err)
+		}
+		if len(got) != 2 || got[0].SpanID != "turn" || got[1].SpanID != "tool" {
+			t.Errorf("GetTraceSpans(%s) = %+v, want the turn subtree", traceID, got)
+		}
+	}
+
+	if got, err := store.GetTraceSpans(ctx, CodexTraceID("missing")); err != nil || len(got) != 0 {
+		t.Errorf("expected no spans for an unknown virtual trace, got %+v (%v)", got, err)
+	}
+}
+
+func TestGetTraceSpans_EventsAndLinks(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	events := []api.SpanEvent{
+		{Timestamp: now.Add(time.Millisecond), Name: "exception", Attributes: map[string]string{"exception.type": "Timeout"}},
+		{Timestamp: now.Add(2 * time.Millisecond), Name: "retry"},
+	}
+	links := []api.SpanLink{{TraceID: "trace-000", SpanID: "span-000", TraceState: "vendor=1", Attributes: map[string]string{"link.kind": "follows"}}}
+
+	spans := []api.Span{
+		{TraceID: "trace-001", SpanID: "span-001", ServiceName: "test-service", SpanName: "root", Timestamp: now, Events: events, Links: links},
+		{TraceID: "trace-001", SpanID: "span-002", ParentSpanID: "span-001", ServiceName: "test-service", SpanName: "child", Timestamp: now.Add(time.Millisecond)},
+		// Codex virtual trace: the event-carrying span is reached through the recursive subtree query
+		{TraceID: "c1", SpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now},
+		{TraceID: "c1", SpanID: "tool", ParentSpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now.Add(time.Millisecond), Events: events, Links: links},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	for _, traceID := range []string{"trace-001", "turn"} {
+		got, err := store.GetTraceSpans(ctx, traceID)
+		if err != nil {
+			t.Fatalf("GetTraceSpans(%s) failed: %v", traceID, err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected 2 spans for %s, got %d", traceID, len(got))
+		}
+
+		withEvents, without := got[0], got[1]
+		if traceID == "turn" {
+			withEvents, without = got[1], got[0]
+		}
+		if len(withEvents.Events) != 2 {
+			t.Fatalf("%s: expected 2 events, got %+v", traceID, withEvents.Events)
+		}
+		for i, want := range events {
+			event := withEvents.Events[i]
+			if event.Name != want.Name || !event.Timestamp.Equal(want.Timestamp) || len(event.Attributes) != len(want.Attributes) ||
+				event.Attributes["exception.type"] != want.Attributes["exception.type"] {
+				t.Errorf("%s: event %d = %+v, want %+v", traceID, i, event, want)
+			}
+		}
+		if !reflect.DeepEqual(withEvents.Links, links) {
+			t.Errorf("%s: expected links %+v, got %+v", traceID, links, withEvents.Links)
+		}
+		if without.Events != nil || without.Links != nil {
+			t.Errorf("%s: expected no events or links on %s, got %+v %+v", traceID, without.SpanID, without.Events, without.Links)
+		}
+	}
+}
+
+func TestGetTraceSpansByRole(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	span := func(traceID, id, parent, service string, offset time.Duration) api.Span {
+		return api.Span{TraceID: traceID, SpanID: id, ParentSpanID: parent, ServiceName: service, SpanName: id, Timestamp: now.Add(offset)}
+	}
+
+	// root -> (a -> c, b); "orphan" references a parent that was never received
+	spans := []api.Span{
+		span("t1", "root", "", "svc", 0),
+		span("t1", "a", "root", "svc", time.Millisecond),
+		span("t1", "b", "root", "svc", 2*time.Millisecond),
+		span("t1", "c", "a", "svc", 3*time.Millisecond),
+		span("t1", "orphan", "missing", "svc", 4*time.Millisecond),
+		// Codex virtual trace rooted at "turn", whose parent session span is outside the subtree
+		span("t2", "session", "", "codex_cli_rs", 0),
+		span("t2", "turn", "session", "codex_cli_rs", time.Millisecond),
+		span("t2", "tool", "turn", "codex_cli_rs", 2*time.Millisecond),
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	tests := []struct {
+		traceID string
+		role    SpanRole
+		want    []string
+	}{
+		{"t1", SpanRoleAll, []string{"root", "a", "b", "c", "orphan"}},
+		{"t1", SpanRoleRoot, []string{"root", "orphan"}},
+		{"t1", SpanRoleLeaf, []string{"b", "c", "orphan"}},
+		{"turn", SpanRoleRoot, []string{"turn"}},
+		{"turn", SpanRoleLeaf, []string{"tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.traceID+"/"+string(tt.role), func(t *testing.T) {
+			got, err := store.GetTraceSpansByRole(ctx, tt.traceID, tt.role)
+			if err != nil {
+				t.Fatalf("GetTraceSpansByRole failed: %v", err)
+			}
+			var ids []string
+			for _, s := range got {
+				ids = append(ids, s.SpanID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("spans = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseSpanRole("branch"); err == nil {
+		t.Error("expected an error for an unknown span role")
+	}
+}
+
+func TestStreamTraceSpans_Cancelled(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "svc", SpanName: "a", Timestamp: now},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "svc", SpanName: "b", Timestamp: now.Add(time.Millisecond)},
+	}
+	if err := store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	count := 0
+	err := store.StreamTraceSpans(ctx, "t1", SpanRoleAll, func(span api.Span) error {
+		count++
+		cancel()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected streaming to stop after 1 span, got %d", count)
+	}
+}
+
+func TestStreamTraceSpans_Pages(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// Spans sharing a timestamp straddle page boundaries and are ordered by SpanId
+	total := 2*traceSpanPageSize + 1
+	spans := make([]api.Span, total)
+	for i := range spans {
+		spans[i] = api.Span{TraceID: "t1", SpanID: fmt.Sprintf("s%04d", i), ServiceName: "svc", SpanName: "op", Timestamp: now.Add(time.Duration(i/3) * time.Millisecond)}
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	var streamed []string
+	err := store.StreamTraceSpans(ctx, "t1", SpanRoleAll, func(span api.Span) error {
+		// Writers are not blocked while a page is being consumed
+		if len(streamed) == 0 {
+			done := make(chan error, 1)
+			go func() {
+				done <- store.InsertSpans(ctx, []api.Span{{TraceID: "t2", SpanID: "x", ServiceName: "svc", SpanName: "op", Timestamp: now}})
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("InsertSpans failed: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("InsertSpans blocked while streaming")
+			}
+		}
+		streamed = append(streamed, span.SpanID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamTraceSpans failed: %v", err)
+	}
+
+	if len(streamed) != total {
+		t.Fatalf("expected %d spans, got %d", total, len(streamed))
+	}
+	for i, id := range streamed {
+		if want := fmt.Sprintf("s%04d", i); id != want {
+			t.Fatalf("expected span %d to be %s, got %s", i, want, id)
+		}
+	}
+}
+
+func TestStreamTraceSpans_CodexPages(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// A Codex turn whose subtree spans several pages, next to an unrelated turn in the same trace
+	spans := []api.Span{
+		{TraceID: "c1", SpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now},
+		{TraceID: "c1", SpanID: "other", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now},
+	}
+	for i := 0; i < traceSpanPageSize+10; i++ {
+		spans = append(spans, api.Span{TraceID: "c1", SpanID: fmt.Sprintf("s%04d", i), ParentSpanID: "turn", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now.Add(time.Duration(i+1) * time.Millisecond)})
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	var streamed []string
+	err := store.StreamTraceSpans(ctx, "codex:turn", SpanRoleAll, func(span api.Span) error {
+		streamed = append(streamed, span.SpanID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamTraceSpans failed: %v", err)
+	}
+
+	if len(streamed) != traceSpanPageSize+11 || streamed[0] != "turn" {
+		t.Fatalf("expected the turn and its %d children, got %d spans starting with %v", traceSpanPageSize+10, len(streamed), streamed[:1])
+	}
+	if slices.Contains(streamed, "other") {
+		t.Error("spans outside the subtree should not be streamed")
+	}
+}
+
+func TestCountTraces_IncludesCodexVirtualTraces(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "claude-code", SpanName: "a", Timestamp: now},
+		{TraceID: "t1", SpanID: "s2", ParentSpanID: "s1", ServiceName: "claude-code", SpanName: "b", Timestamp: now},
+		// Codex: two first-level spans (virtual traces) sharing one TraceId, one with a child
+		{TraceID: "c1", SpanID: "c-root-1", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now},
+		{TraceID: "c1", SpanID: "c-child", ParentSpanID: "c-root-1", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now},
+		{TraceID: "c1", SpanID: "c-root-2", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	for _, service := range []string{"", "claude-code", "codex_cli_rs"} {
+		count, err := store.CountTraces(ctx, service, "", "", from, to)
+		if err != nil {
+			t.Fatalf("CountTraces(%q) failed: %v", service, err)
+		}
+		resp, err := store.QueryTraces(ctx, service, "", "", from, to, 1, 0)
+		if err != nil {
+			t.Fatalf("QueryTraces(%q) failed: %v", service, err)
+		}
+		if count != resp.Total {
+			t.Errorf("service %q: CountTraces = %d, QueryTraces total = %d", service, count, resp.Total)
+		}
+	}
+
+	count, _ := store.CountTraces(ctx, "", "", "", from, to)
+	if count != 3 {
+		t.Errorf("expected 3 traces (1 regular + 2 codex virtual), got %d", count)
+	}
+}
+
+func TestGetServices(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// Insert spans from different services
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "service-a", SpanName: "span", Timestamp: now},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "service-b", SpanName: "span", Timestamp: now},
+		{TraceID: "t3", SpanID: "s3", ServiceName: "service-a", SpanName: "span", Timestamp: now}, // Duplicate
+	}
+	store.InsertSpans(ctx, spans)
+
+	services, err := store.GetServices(ctx)
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+
+	if len(services) != 2 {
+		t.Errorf("expected 2 unique services, got %d", len(services))
+	}
+}
+
+func TestDataVersion(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	version, opened := store.DataVersion()
+
+	if err := store.InsertLogs(ctx, []api.LogRecord{{Timestamp: time.Now(), ServiceName: "svc-a", Body: "x"}}); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	afterInsert, modified := store.DataVersion()
+	if afterInsert <= version {
+		t.Errorf("expected version to increase after insert, got %d -> %d", version, afterInsert)
+	}
+	if modified.Before(opened) {
+		t.Errorf("expected modified time %v not before open time %v", modified, opened)
+	}
+
+	// Deleting nothing leaves the version alone
+	past := time.Now().Add(-48 * time.Hour)
+	if _, err := store.DeleteLogsInRange(ctx, past.Add(-time.Hour), past, ""); err != nil {
+		t.Fatalf("DeleteLogsInRange failed: %v", err)
+	}
+	if v, _ := store.DataVersion(); v != afterInsert {
+		t.Errorf("expected version %d after empty delete, got %d", afterInsert, v)
+	}
 
-	if resp.Total != 0 {
-		t.Errorf("expected 0 traces, got %d", resp.Total)
+	if _, err := store.DeleteLogsInRange(ctx, past, time.Now().Add(time.Hour), ""); err != nil {
+		t.Fatalf("DeleteLogsInRange failed: %v", err)
 	}
-	if len(resp.Traces) != 0 {
-		t.Errorf("expected empty traces slice, got %d", len(resp.Traces))
+	if v, _ := store.DataVersion(); v <= afterInsert {
+		t.Errorf("expected version to increase after delete, got %d -> %d", afterInsert, v)
 	}
 }
 
-func TestGetTraceSpans(t *testing.T) {
+func TestGetDataTimeRange(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	now := time.Now()
 
-	spans := []api.Span{
-		{TraceID: "trace-001", SpanID: "span-001", ServiceName: "test-service", SpanName: "root", Timestamp: now, StatusCode: "OK"},
-		{TraceID: "trace-001", SpanID: "span-002", ParentSpanID: "span-001", ServiceName: "test-service", SpanName: "child1", Timestamp: now.Add(10 * time.Millisecond), StatusCode: "OK"},
-		{TraceID: "trace-001", SpanID: "span-003", ParentSpanID: "span-001", ServiceName: "test-service", SpanName: "child2", Timestamp: now.Add(20 * time.Millisecond), StatusCode: "OK"},
-		{TraceID: "trace-002", SpanID: "span-004", ServiceName: "other-service", SpanName: "other", Timestamp: now, StatusCode: "OK"},
+	empty, err := store.GetDataTimeRange(ctx)
+	if err != nil {
+		t.Fatalf("GetDataTimeRange failed: %v", err)
+	}
+	if empty.From != nil || empty.To != nil {
+		t.Errorf("expected empty range for empty database, got %+v", empty)
 	}
-	store.InsertSpans(ctx, spans)
 
-	traceSpans, err := store.GetTraceSpans(ctx, "trace-001")
+	earliest := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	latest := time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)
+	store.InsertSpans(ctx, []api.Span{{Timestamp: earliest, TraceID: "t1", SpanID: "s1", ServiceName: "svc-a"}})
+	store.InsertLogs(ctx, []api.LogRecord{{Timestamp: earliest.Add(time.Hour), ServiceName: "svc-a"}})
+	store.InsertMetrics(ctx, []api.MetricDataPoint{{Timestamp: latest, ServiceName: "svc-a", MetricName: "m", MetricType: "gauge", Value: ptrFloat64(1)}})
+
+	resp, err := store.GetDataTimeRange(ctx)
 	if err != nil {
-		t.Fatalf("GetTraceSpans failed: %v", err)
+		t.Fatalf("GetDataTimeRange failed: %v", err)
 	}
-
-	if len(traceSpans) != 3 {
-		t.Errorf("expected 3 spans for trace-001, got %d", len(traceSpans))
+	if resp.From == nil || !resp.From.Equal(earliest) {
+		t.Errorf("From = %v, want %v", resp.From, earliest)
+	}
+	if resp.To == nil || !resp.To.Equal(latest) {
+		t.Errorf("To = %v, want %v", resp.To, latest)
 	}
 }
 
-func TestGetTraceSpans_NotFound(t *testing.T) {
+func TestGetServices_Empty(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
 
-	spans, err := store.GetTraceSpans(context.Background(), "nonexistent-trace")
+	services, err := store.GetServices(context.Background())
 	if err != nil {
-		t.Fatalf("GetTraceSpans failed: %v", err)
+		t.Fatalf("GetServices failed: %v", err)
 	}
 
-	if len(spans) != 0 {
-		t.Errorf("expected 0 spans for nonexistent trace, got %d", len(spans))
+	if len(services) != 0 {
+		t.Errorf("expected 0 services, got %d", len(services))
 	}
 }
 
-func TestGetServices(t *testing.T) {
+func TestGetScopes(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
 
 	ctx := context.Background()
 	now := time.Now()
 
-	// Insert spans from different services
 	spans := []api.Span{
-		{TraceID: "t1", SpanID: "s1", ServiceName: "service-a", SpanName: "span", Timestamp: now},
-		{TraceID: "t2", SpanID: "s2", ServiceName: "service-b", SpanName: "span", Timestamp: now},
-		{TraceID: "t3", SpanID: "s3", ServiceName: "service-a", SpanName: "span", Timestamp: now}, // Duplicate
+		{TraceID: "t1", SpanID: "s1", ServiceName: "service-a", SpanName: "span", Timestamp: now, ScopeName: "tracer", ScopeVersion: "1.0.0"},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "service-a", SpanName: "span", Timestamp: now, ScopeName: "tracer", ScopeVersion: "1.0.0"}, // Duplicate
+		// No scope
+		{TraceID: "t3", SpanID: "s3", ServiceName: "service-b", SpanName: "span", Timestamp: now},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
 	}
-	store.InsertSpans(ctx, spans)
 
-	services, err := store.GetServices(ctx)
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "service-a", Body: "log", ScopeName: "tracer", ScopeVersion: "1.0.0"},
+		{Timestamp: now, ServiceName: "service-b", Body: "log", ScopeName: "logger"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now, ServiceName: "service-a", MetricName: "m", MetricType: "gauge", ScopeName: "meter", ScopeVersion: "2.1.0"},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	scopes, err := store.GetScopes(ctx, "")
 	if err != nil {
-		t.Fatalf("GetServices failed: %v", err)
+		t.Fatalf("GetScopes failed: %v", err)
 	}
 
-	if len(services) != 2 {
-		t.Errorf("expected 2 unique services, got %d", len(services))
+	if len(scopes) != 3 {
+		t.Fatalf("expected 3 distinct scopes, got %d: %+v", len(scopes), scopes)
 	}
-}
 
-func TestGetServices_Empty(t *testing.T) {
-	store, cleanup := setupTestStore(t)
-	defer cleanup()
+	// Results are ordered by service, scope name, version
+	if scopes[0].ServiceName != "service-a" || scopes[0].ScopeName != "meter" || scopes[0].ScopeVersion != "2.1.0" {
+		t.Errorf("unexpected first scope: %+v", scopes[0])
+	}
+	if len(scopes[0].Signals) != 1 || scopes[0].Signals[0] != "metrics" {
+		t.Errorf("expected meter scope in metrics only, got %v", scopes[0].Signals)
+	}
 
-	services, err := store.GetServices(context.Background())
-	if err != nil {
-		t.Fatalf("GetServices failed: %v", err)
+	if scopes[1].ScopeName != "tracer" || scopes[1].ScopeVersion != "1.0.0" {
+		t.Errorf("unexpected second scope: %+v", scopes[1])
+	}
+	if len(scopes[1].Signals) != 2 || scopes[1].Signals[0] != "logs" || scopes[1].Signals[1] != "traces" {
+		t.Errorf("expected tracer scope in logs and traces, got %v", scopes[1].Signals)
 	}
 
-	if len(services) != 0 {
-		t.Errorf("expected 0 services, got %d", len(services))
+	if scopes[2].ServiceName != "service-b" || scopes[2].ScopeName != "logger" || scopes[2].ScopeVersion != "" {
+		t.Errorf("unexpected third scope: %+v", scopes[2])
+	}
+
+	// Filter by service
+	scopes, err = store.GetScopes(ctx, "service-b")
+	if err != nil {
+		t.Fatalf("GetScopes with service failed: %v", err)
+	}
+	if len(scopes) != 1 || scopes[0].ScopeName != "logger" {
+		t.Errorf("expected only logger scope for service-b, got %+v", scopes)
 	}
 }
 
@@ -431,7 +1184,7 @@ func TestQueryLogs(t *testing.T) {
 	to := now.Add(1 * time.Hour)
 
 	// Query all logs
-	resp, err := store.QueryLogs(ctx, "", "", "", "", from, to, 10, 0)
+	resp, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", "", from, to, 10, 0)
 	if err != nil {
 		t.Fatalf("QueryLogs failed: %v", err)
 	}
@@ -458,7 +1211,7 @@ func TestQueryLogs_WithSeverityFilter(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now.Add(1 * time.Hour)
 
-	resp, err := store.QueryLogs(ctx, "", "ERROR", "", "", from, to, 10, 0)
+	resp, err := store.QueryLogs(ctx, "", "ERROR", SeverityRange{}, "", "", from, to, 10, 0)
 	if err != nil {
 		t.Fatalf("QueryLogs failed: %v", err)
 	}
@@ -468,6 +1221,46 @@ func TestQueryLogs_WithSeverityFilter(t *testing.T) {
 	}
 }
 
+func TestQueryLogs_WithSeverityRange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", SeverityText: "DEBUG", SeverityNumber: 5, Body: "debug"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "INFO", SeverityNumber: 9, Body: "info"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "WARN", SeverityNumber: 13, Body: "warn"},
+		{Timestamp: now, ServiceName: "svc", SeverityText: "ERROR", SeverityNumber: 17, Body: "error"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	from := now.Add(-1 * time.Hour)
+	to := now.Add(1 * time.Hour)
+
+	tests := []struct {
+		severities SeverityRange
+		want       int
+	}{
+		{SeverityRange{}, 4},
+		{SeverityRange{Min: 13}, 2},
+		{SeverityRange{Max: 12}, 2},
+		{SeverityRange{Min: 9, Max: 16}, 2},
+	}
+	for _, tt := range tests {
+		resp, err := store.QueryLogs(ctx, "", "", tt.severities, "", "", from, to, 10, 0)
+		if err != nil {
+			t.Fatalf("QueryLogs failed: %v", err)
+		}
+		if resp.Total != tt.want {
+			t.Errorf("%+v: expected %d logs, got %d", tt.severities, tt.want, resp.Total)
+		}
+	}
+}
+
 func TestQueryLogs_WithSearch(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -485,7 +1278,7 @@ func TestQueryLogs_WithSearch(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now.Add(1 * time.Hour)
 
-	resp, err := store.QueryLogs(ctx, "", "", "", "database", from, to, 10, 0)
+	resp, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", "database", from, to, 10, 0)
 	if err != nil {
 		t.Fatalf("QueryLogs failed: %v", err)
 	}
@@ -526,6 +1319,48 @@ func TestGetLogLevels(t *testing.T) {
 	}
 }
 
+func TestGetLogLevelMatrix(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc-a", SeverityText: "INFO", Body: "1"},
+		{Timestamp: now, ServiceName: "svc-a", SeverityText: "INFO", Body: "2"},
+		{Timestamp: now, ServiceName: "svc-a", SeverityText: "ERROR", Body: "3"},
+		{Timestamp: now, ServiceName: "svc-b", SeverityText: "WARN", Body: "4"},
+		{Timestamp: now, ServiceName: "svc-b", SeverityText: "INFO", Body: "5"},
+		{Timestamp: now.Add(-48 * time.Hour), ServiceName: "svc-b", SeverityText: "ERROR", Body: "old"}, // Outside range
+	}
+	store.InsertLogs(ctx, logs)
+
+	matrix, err := store.GetLogLevelMatrix(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetLogLevelMatrix failed: %v", err)
+	}
+
+	expected := map[string]map[string]int64{
+		"svc-a": {"INFO": 2, "ERROR": 1},
+		"svc-b": {"WARN": 1, "INFO": 1},
+	}
+
+	if len(matrix) != len(expected) {
+		t.Fatalf("expected %d services, got %d: %v", len(expected), len(matrix), matrix)
+	}
+	for service, levels := range expected {
+		if len(matrix[service]) != len(levels) {
+			t.Errorf("%s: expected %d levels, got %v", service, len(levels), matrix[service])
+		}
+		for level, count := range levels {
+			if matrix[service][level] != count {
+				t.Errorf("%s/%s: expected count %d, got %d", service, level, count, matrix[service][level])
+			}
+		}
+	}
+}
+
 func TestGetLogLevels_Empty(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -540,6 +1375,129 @@ func TestGetLogLevels_Empty(t *testing.T) {
 	}
 }
 
+func TestGetSessionTranscript_ToolCallPairing(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	at := func(i int) time.Time { return now.Add(time.Duration(i) * time.Second) }
+	attrs := func(session string, kv ...string) map[string]string {
+		m := map[string]string{"session.id": session}
+		for i := 0; i+1 < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+	logs := []api.LogRecord{
+		// Claude Code OTLP: no ids, results follow their decisions
+		{Timestamp: at(0), ServiceName: "claude-code", Body: "tool_decision", LogAttributes: attrs("claude", "event.name", "tool_decision", "tool_name", "Read", "decision", "accept")},
+		{Timestamp: at(1), ServiceName: "claude-code", Body: "tool_decision", LogAttributes: attrs("claude", "event.name", "tool_decision", "tool_name", "Bash", "decision", "reject")},
+		{Timestamp: at(2), ServiceName: "claude-code", Body: "tool_decision", LogAttributes: attrs("claude", "event.name", "tool_decision", "tool_name", "Bash", "decision", "accept")},
+		{Timestamp: at(3), ServiceName: "claude-code", Body: "tool_result", LogAttributes: attrs("claude", "event.name", "tool_result", "tool_name", "Bash", "success", "true")},
+		{Timestamp: at(4), ServiceName: "claude-code", Body: "tool_result", LogAttributes: attrs("claude", "event.name", "tool_result", "tool_name", "Read", "success", "true")},
+		// Codex OTLP: call_id on both events, results out of order
+		{Timestamp: at(0), ServiceName: "codex_cli_rs", Body: "codex.tool_decision", LogAttributes: attrs("codex", "event.name", "codex.tool_decision", "tool_name", "shell", "call_id", "call_a")},
+		{Timestamp: at(1), ServiceName: "codex_cli_rs", Body: "codex.tool_decision", LogAttributes: attrs("codex", "event.name", "codex.tool_decision", "tool_name", "shell", "call_id", "call_b")},
+		{Timestamp: at(2), ServiceName: "codex_cli_rs", Body: "codex.tool_result", LogAttributes: attrs("codex", "event.name", "codex.tool_result", "tool_name", "shell", "call_id", "call_b", "output", "b")},
+		{Timestamp: at(3), ServiceName: "codex_cli_rs", Body: "codex.tool_result", LogAttributes: attrs("codex", "event.name", "codex.tool_result", "tool_name", "shell", "call_id", "call_a", "output", "a")},
+		// Imported Claude Code transcript: tool.use_id on both messages
+		{Timestamp: at(0), ServiceName: "claude-code", Body: "Tool call: Grep", LogAttributes: attrs("imported", "event.name", "transcript.message", "message.role", "tool_use", "tool.name", "Grep", "tool.use_id", "toolu_1")},
+		{Timestamp: at(1), ServiceName: "claude-code", Body: "match", LogAttributes: attrs("imported", "event.name", "transcript.message", "message.role", "tool_result", "tool.use_id", "toolu_1")},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	tests := []struct {
+		session string
+		want    []string // ToolCallID per message, in order
+	}{
+		{"claude", []string{"seq-2", "", "seq-1", "seq-1", "seq-2"}},
+		{"codex", []string{"call_a", "call_b", "call_b", "call_a"}},
+		{"imported", []string{"toolu_1", "toolu_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.session, func(t *testing.T) {
+			resp, err := store.GetSessionTranscript(ctx, tt.session)
+			if err != nil {
+				t.Fatalf("GetSessionTranscript failed: %v", err)
+			}
+			if len(resp.Messages) != len(tt.want) {
+				t.Fatalf("expected %d messages, got %+v", len(tt.want), resp.Messages)
+			}
+			for i, msg := range resp.Messages {
+				if msg.ToolCallID != tt.want[i] {
+					t.Errorf("message %d (%s %s): toolCallId = %q, want %q", i, msg.Role, msg.ToolName, msg.ToolCallID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQuerySessions_Preview(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	longPrompt := strings.Repeat("word ", 100)
+	logs := []api.LogRecord{
+		// OTLP sessions carry the prompt in an attribute; the earliest one is the preview
+		{Timestamp: now.Add(-3 * time.Minute), ServiceName: "claude-code", Body: "api_request",
+			LogAttributes: map[string]string{"session.id": "otlp", "event.name": "api_request"}},
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "claude-code", Body: "user_prompt",
+			LogAttributes: map[string]string{"session.id": "otlp", "event.name": "user_prompt", "prompt": "Fix the\nflaky test"}},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", Body: "user_prompt",
+			LogAttributes: map[string]string{"session.id": "otlp", "event.name": "user_prompt", "prompt": "Now add docs"}},
+		// Imported transcripts carry the user message in the body
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "claude-code", Body: "Hello from the transcript",
+			LogAttributes: map[string]string{"session.id": "imported", "event.name": "transcript.message", "message.role": "assistant"}},
+		{Timestamp: now.Add(-3 * time.Minute), ServiceName: "claude-code", Body: longPrompt,
+			LogAttributes: map[string]string{"session.id": "imported", "event.name": "transcript.message", "message.role": "user"}},
+		{Timestamp: now, ServiceName: "codex_cli_rs", Body: "codex.api_request",
+			LogAttributes: map[string]string{"conversation.id": "no-prompt", "event.name": "codex.api_request"}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	resp, err := store.QuerySessions(ctx, "", from, to, 10, 0, true)
+	if err != nil {
+		t.Fatalf("QuerySessions failed: %v", err)
+	}
+	previews := make(map[string]string)
+	for _, s := range resp.Sessions {
+		previews[s.SessionID] = s.Preview
+	}
+	if len(previews) != 3 {
+		t.Fatalf("expected 3 sessions, got %v", previews)
+	}
+	if previews["otlp"] != "Fix the flaky test" {
+		t.Errorf("otlp preview = %q, want the first prompt", previews["otlp"])
+	}
+	if want := strings.Repeat("word ", 40)[:199] + "…"; previews["imported"] != want {
+		t.Errorf("imported preview = %q, want %q", previews["imported"], want)
+	}
+	if previews["no-prompt"] != "" {
+		t.Errorf("expected no preview without a prompt, got %q", previews["no-prompt"])
+	}
+	if resp.Sessions[0].SessionID != "no-prompt" {
+		t.Errorf("expected sessions ordered by last activity, got %s first", resp.Sessions[0].SessionID)
+	}
+
+	resp, err = store.QuerySessions(ctx, "", from, to, 10, 0, false)
+	if err != nil {
+		t.Fatalf("QuerySessions failed: %v", err)
+	}
+	for _, s := range resp.Sessions {
+		if s.Preview != "" {
+			t.Errorf("expected no preview unless requested, got %q for %s", s.Preview, s.SessionID)
+		}
+	}
+}
+
 // ============ Metrics Store Tests ============
 
 func TestInsertMetrics(t *testing.T) {
@@ -581,6 +1539,50 @@ func TestInsertMetrics_Empty(t *testing.T) {
 	}
 }
 
+func TestReplaceMetrics(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ts := time.Unix(1609459200, 0)
+	point := func(metricType, model string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp:   ts,
+			ServiceName: "svc",
+			MetricName:  "tokens",
+			MetricType:  metricType,
+			Attributes:  map[string]string{"model": model},
+			Value:       ptrFloat64(v),
+		}
+	}
+
+	if err := store.ReplaceMetrics(ctx, []api.MetricDataPoint{point("gauge", "a", 1), point("gauge", "b", 2), point("sum", "a", 3)}); err != nil {
+		t.Fatalf("ReplaceMetrics failed: %v", err)
+	}
+	// Replaces the gauge for model a only; the sum of the same name and attributes is kept
+	if err := store.ReplaceMetrics(ctx, []api.MetricDataPoint{point("gauge", "a", 10)}); err != nil {
+		t.Fatalf("ReplaceMetrics failed: %v", err)
+	}
+
+	rows, err := store.db.QueryContext(ctx, "SELECT MetricType, Attributes['model'], Value FROM otel_metrics ORDER BY MetricType, Attributes['model']")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var metricType, model string
+		var value float64
+		if err := rows.Scan(&metricType, &model, &value); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%s/%s=%g", metricType, model, value))
+	}
+	if want := []string{"gauge/a=10", "gauge/b=2", "sum/a=3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stored points = %v, want %v", got, want)
+	}
+}
+
 func TestQueryMetrics(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -608,6 +1610,53 @@ func TestQueryMetrics(t *testing.T) {
 	}
 }
 
+func TestQueryMetrics_PaginationWithSameTimestamp(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	const count = 50
+	metrics := make([]api.MetricDataPoint, count)
+	for i := range metrics {
+		metrics[i] = api.MetricDataPoint{
+			Timestamp:   now,
+			ServiceName: "svc-a",
+			MetricName:  fmt.Sprintf("metric_%d", i%3),
+			MetricType:  "gauge",
+			Value:       ptrFloat64(float64(i)),
+		}
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from := now.Add(-1 * time.Hour)
+	to := now.Add(1 * time.Hour)
+
+	seen := make(map[float64]int)
+	const pageSize = 7
+	for offset := 0; offset < count; offset += pageSize {
+		resp, err := store.QueryMetrics(ctx, "", "", "", from, to, pageSize, offset)
+		if err != nil {
+			t.Fatalf("QueryMetrics failed: %v", err)
+		}
+		for _, m := range resp.Metrics {
+			seen[*m.Value]++
+		}
+	}
+
+	if len(seen) != count {
+		t.Errorf("expected %d distinct metrics across pages, got %d", count, len(seen))
+	}
+	for value, n := range seen {
+		if n != 1 {
+			t.Errorf("metric with value %v returned %d times", value, n)
+		}
+	}
+}
+
 func TestQueryMetrics_WithFilters(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -712,7 +1761,7 @@ func TestQueryTraces_Pagination(t *testing.T) {
 	to := now.Add(1 * time.Hour)
 
 	// Get first page
-	resp, err := store.QueryTraces(ctx, "", "", from, to, 2, 0)
+	resp, err := store.QueryTraces(ctx, "", "", "", from, to, 2, 0)
 	if err != nil {
 		t.Fatalf("QueryTraces failed: %v", err)
 	}
@@ -725,7 +1774,7 @@ func TestQueryTraces_Pagination(t *testing.T) {
 	}
 
 	// Get second page
-	resp, err = store.QueryTraces(ctx, "", "", from, to, 2, 2)
+	resp, err = store.QueryTraces(ctx, "", "", "", from, to, 2, 2)
 	if err != nil {
 		t.Fatalf("QueryTraces failed: %v", err)
 	}
@@ -753,7 +1802,7 @@ func TestQueryLogs_Pagination(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now.Add(1 * time.Hour)
 
-	resp, err := store.QueryLogs(ctx, "", "", "", "", from, to, 2, 0)
+	resp, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", "", from, to, 2, 0)
 	if err != nil {
 		t.Fatalf("QueryLogs failed: %v", err)
 	}
@@ -787,7 +1836,7 @@ func TestQueryMetricSeries(t *testing.T) {
 	to := now.Add(5 * time.Minute)
 
 	// Query time series
-	resp, err := store.QueryMetricSeries(ctx, "cpu_usage", "", from, to, 60, false)
+	resp, err := store.QueryMetricSeries(ctx, "cpu_usage", "", from, to, 60, false, 0)
 	if err != nil {
 		t.Fatalf("QueryMetricSeries failed: %v", err)
 	}
@@ -807,7 +1856,7 @@ func TestQueryMetricSeries_NoData(t *testing.T) {
 	from := now.Add(-1 * time.Hour)
 	to := now
 
-	resp, err := store.QueryMetricSeries(ctx, "nonexistent_metric", "", from, to, 60, false)
+	resp, err := store.QueryMetricSeries(ctx, "nonexistent_metric", "", from, to, 60, false, 0)
 	if err != nil {
 		t.Fatalf("QueryMetricSeries failed: %v", err)
 	}
@@ -836,7 +1885,7 @@ func TestQueryMetricSeries_WithAggregation(t *testing.T) {
 	to := now.Add(5 * time.Minute)
 
 	// Query with aggregation (scalar result)
-	resp, err := store.QueryMetricSeries(ctx, "memory_usage", "", from, to, 60, true)
+	resp, err := store.QueryMetricSeries(ctx, "memory_usage", "", from, to, 60, true, 0)
 	if err != nil {
 		t.Fatalf("QueryMetricSeries with aggregation failed: %v", err)
 	}
@@ -864,7 +1913,7 @@ func TestQueryMetricSeries_WithServiceFilter(t *testing.T) {
 	to := now.Add(5 * time.Minute)
 
 	// Query with service filter
-	resp, err := store.QueryMetricSeries(ctx, "requests", "svc-a", from, to, 60, true)
+	resp, err := store.QueryMetricSeries(ctx, "requests", "svc-a", from, to, 60, true, 0)
 	if err != nil {
 		t.Fatalf("QueryMetricSeries with service filter failed: %v", err)
 	}
@@ -893,7 +1942,7 @@ func TestQueryMetricSeries_SumMetric(t *testing.T) {
 	from := now.Add(-1 * time.Minute)
 	to := now.Add(5 * time.Minute)
 
-	resp, err := store.QueryMetricSeries(ctx, "request_count", "", from, to, 60, true)
+	resp, err := store.QueryMetricSeries(ctx, "request_count", "", from, to, 60, true, 0)
 	if err != nil {
 		t.Fatalf("QueryMetricSeries for sum metric failed: %v", err)
 	}
@@ -942,6 +1991,141 @@ func TestGetLatestMetricValue(t *testing.T) {
 	}
 }
 
+func TestGetMetricTable(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	point := func(offset time.Duration, service string, value float64, attrs map[string]string) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp:   now.Add(offset),
+			ServiceName: service,
+			MetricName:  "tokens",
+			MetricType:  "gauge",
+			Value:       ptrFloat64(value),
+			Attributes:  attrs,
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		point(-3*time.Minute, "svc", 10, map[string]string{"model": "a", "type": "input"}),
+		point(-1*time.Minute, "svc", 15, map[string]string{"model": "a", "type": "input"}), // Latest for series 1
+		point(-2*time.Minute, "svc", 20, map[string]string{"model": "a", "type": "output"}),
+		point(-2*time.Minute, "svc", 30, map[string]string{"model": "b"}),
+		point(-1*time.Minute, "other", 40, map[string]string{"model": "b"}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	rows, err := store.GetMetricTable(ctx, "tokens", "svc")
+	if err != nil {
+		t.Fatalf("GetMetricTable failed: %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows (one per attribute set), got %d: %+v", len(rows), rows)
+	}
+
+	latest := make(map[string]float64)
+	for _, row := range rows {
+		if row.ServiceName != "svc" {
+			t.Errorf("expected only svc rows, got %s", row.ServiceName)
+		}
+		if row.Value == nil {
+			t.Fatalf("expected value for row %+v", row)
+		}
+		latest[row.Attributes["model"]+"/"+row.Attributes["type"]] = *row.Value
+	}
+
+	expected := map[string]float64{"a/input": 15, "a/output": 20, "b/": 30}
+	for key, value := range expected {
+		if latest[key] != value {
+			t.Errorf("%s: expected latest value %f, got %f", key, value, latest[key])
+		}
+	}
+
+	// Without a service filter, series are distinct per service
+	rows, err = store.GetMetricTable(ctx, "tokens", "")
+	if err != nil {
+		t.Fatalf("GetMetricTable failed: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Errorf("expected 4 rows across services, got %d", len(rows))
+	}
+}
+
+func TestGetMetricBreakdown(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	delta := int32(1)
+
+	point := func(name, metricType, service string, value float64, attrs map[string]string) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp:              now,
+			ServiceName:            service,
+			MetricName:             name,
+			MetricType:             metricType,
+			Value:                  ptrFloat64(value),
+			AggregationTemporality: &delta,
+			Attributes:             attrs,
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		point("cost", "sum", "svc", 1.5, map[string]string{"model": "sonnet"}),
+		point("cost", "sum", "svc", 2.5, map[string]string{"model": "sonnet", "type": "x"}),
+		point("cost", "sum", "svc", 6, map[string]string{"model": "opus"}),
+		point("cost", "sum", "svc", 0.5, nil),
+		point("cost", "sum", "other", 100, map[string]string{"model": "opus"}),
+		point("load", "gauge", "svc", 10, map[string]string{"host.name": "a"}),
+		point("load", "gauge", "svc", 30, map[string]string{"host.name": "a"}),
+		point("load", "gauge", "svc", 5, map[string]string{"host.name": "b"}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	// Delta sums add up per model, largest first; points without the attribute group under ""
+	rows, err := store.GetMetricBreakdown(ctx, "cost", "model", "svc", from, to)
+	if err != nil {
+		t.Fatalf("GetMetricBreakdown failed: %v", err)
+	}
+	expected := []api.BreakdownRow{{Value: "opus", Total: 6}, {Value: "sonnet", Total: 4}, {Value: "", Total: 0.5}}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), rows)
+	}
+	for i, want := range expected {
+		if rows[i] != want {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, rows[i])
+		}
+	}
+
+	// Gauges average per value; dotted attribute names are matched as a single key
+	rows, err = store.GetMetricBreakdown(ctx, "load", "host.name", "", from, to)
+	if err != nil {
+		t.Fatalf("GetMetricBreakdown failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0] != (api.BreakdownRow{Value: "a", Total: 20}) || rows[1] != (api.BreakdownRow{Value: "b", Total: 5}) {
+		t.Errorf("expected averaged gauge per host, got %+v", rows)
+	}
+
+	rows, err = store.GetMetricBreakdown(ctx, "missing", "model", "", from, to)
+	if err != nil {
+		t.Fatalf("GetMetricBreakdown failed: %v", err)
+	}
+	if rows == nil || len(rows) != 0 {
+		t.Errorf("expected an empty, non-nil result for an unknown metric, got %+v", rows)
+	}
+}
+
 func TestGetLatestMetricValue_NotFound(t *testing.T) {
 	store, cleanup := setupTestStore(t)
 	defer cleanup()
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// efficiencySeriesName is the name of the derived series returned by GetEfficiencySeries
+const efficiencySeriesName = "tokens_per_dollar"
+
+// GetEfficiencySeries returns, per service, the tokens used per dollar spent in each interval
+// bucket within [from, to]: the sum of every *.token.usage point (all token types) divided by
+// the sum of every *.cost.usage point. Cumulative series contribute their increase since the
+// previous point in range, delta series their points. Buckets without cost have no data point,
+// as their ratio is undefined. An empty model matches all models.
+func (s *DuckDBStore) GetEfficiencySeries(ctx context.Context, service, model string, from, to time.Time, intervalSeconds int64) (*api.TimeSeriesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	intervalStr := fmt.Sprintf("%d seconds", intervalSeconds)
+
+	filter := ""
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	if service != "" {
+		filter += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	if model != "" {
+		filter += " AND json_extract_string(Attributes, '$.model') = ?"
+		args = append(args, model)
+	}
+
+	query := fmt.Sprintf(`
+		WITH points AS (
+			SELECT
+				Timestamp,
+				ServiceName,
+				MetricName LIKE '%%.cost.usage' as is_cost,
+				CASE WHEN AggregationTemporality = 2
+					THEN COALESCE(COALESCE(Value, Sum) - LAG(COALESCE(Value, Sum)) OVER (
+						PARTITION BY ServiceName, MetricName, CAST(Attributes AS VARCHAR) ORDER BY Timestamp
+					), 0)
+					ELSE COALESCE(Value, Sum)
+				END as value
+			FROM otel_metrics
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND (MetricName LIKE '%%.token.usage' OR MetricName LIKE '%%.cost.usage')%s
+		)
+		SELECT
+			time_bucket(INTERVAL '%s', Timestamp) as bucket,
+			ServiceName,
+			SUM(CASE WHEN NOT is_cost THEN value ELSE 0 END) as tokens,
+			SUM(CASE WHEN is_cost THEN value ELSE 0 END) as cost
+		FROM points
+		GROUP BY bucket, ServiceName
+		HAVING cost > 0
+		ORDER BY ServiceName, bucket
+	`, filter, intervalStr)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying efficiency: %w", err)
+	}
+	defer rows.Close()
+
+	series := []api.TimeSeries{}
+	for rows.Next() {
+		var bucket time.Time
+		var serviceName string
+		var tokens, cost float64
+		if err := rows.Scan(&bucket, &serviceName, &tokens, &cost); err != nil {
+			return nil, fmt.Errorf("scanning efficiency: %w", err)
+		}
+
+		if len(series) == 0 || series[len(series)-1].Labels["service"] != serviceName {
+			labels := map[string]string{"service": serviceName}
+			if model != "" {
+				labels["model"] = model
+			}
+			series = append(series, api.TimeSeries{
+				Name:       efficiencySeriesName,
+				Labels:     labels,
+				DataPoints: make([][2]float64, 0),
+			})
+		}
+		last := &series[len(series)-1]
+		last.DataPoints = append(last.DataPoints, [2]float64{
+			float64(bucket.UnixMilli()),
+			tokens / cost,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating efficiency: %w", err)
+	}
+
+	return &api.TimeSeriesResponse{Series: series}, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetEfficiencySeries(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	bucket := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+	cumulative := int32(2)
+
+	point := func(ts time.Time, service, metric, model string, temporality *int32, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: metric, MetricType: "sum", Value: &v,
+			AggregationTemporality: temporality, Attributes: map[string]string{"model": model},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		// Claude, first hour: 3000 tokens for $1.50 -> 2000 tokens per dollar
+		point(bucket.Add(time.Minute), "claude-code", "claude_code.token.usage", "sonnet", nil, 1000),
+		point(bucket.Add(2*time.Minute), "claude-code", "claude_code.token.usage", "sonnet", nil, 2000),
+		point(bucket.Add(2*time.Minute), "claude-code", "claude_code.cost.usage", "sonnet", nil, 1.5),
+		// Claude, second hour: tokens without cost have no data point
+		point(bucket.Add(61*time.Minute), "claude-code", "claude_code.token.usage", "sonnet", nil, 500),
+		// Claude, third hour: opus, 400 tokens for $2 -> 200
+		point(bucket.Add(121*time.Minute), "claude-code", "claude_code.token.usage", "opus", nil, 400),
+		point(bucket.Add(121*time.Minute), "claude-code", "claude_code.cost.usage", "opus", nil, 2),
+		// Gemini cumulative counters contribute their increase: 900 tokens for $0.30 -> 3000
+		point(bucket.Add(time.Minute), "gemini-cli", "gemini_cli.token.usage", "gemini-2.5-pro", &cumulative, 100),
+		point(bucket.Add(2*time.Minute), "gemini-cli", "gemini_cli.token.usage", "gemini-2.5-pro", &cumulative, 1000),
+		point(bucket.Add(time.Minute), "gemini-cli", "gemini_cli.cost.usage", "gemini-2.5-pro", &cumulative, 0.10),
+		point(bucket.Add(2*time.Minute), "gemini-cli", "gemini_cli.cost.usage", "gemini-2.5-pro", &cumulative, 0.40),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := bucket.Add(-time.Hour), bucket.Add(4*time.Hour)
+	resp, err := store.GetEfficiencySeries(ctx, "", "", from, to, 3600)
+	if err != nil {
+		t.Fatalf("GetEfficiencySeries failed: %v", err)
+	}
+
+	want := map[string][][2]float64{
+		"claude-code": {
+			{float64(bucket.UnixMilli()), 2000},
+			{float64(bucket.Add(2 * time.Hour).UnixMilli()), 200},
+		},
+		"gemini-cli": {
+			{float64(bucket.UnixMilli()), 3000},
+		},
+	}
+	if len(resp.Series) != len(want) {
+		t.Fatalf("expected %d series, got %+v", len(want), resp.Series)
+	}
+	for _, series := range resp.Series {
+		if series.Name != "tokens_per_dollar" {
+			t.Errorf("unexpected series name %q", series.Name)
+		}
+		points := want[series.Labels["service"]]
+		if len(series.DataPoints) != len(points) {
+			t.Fatalf("%s: expected %d points, got %v", series.Labels["service"], len(points), series.DataPoints)
+		}
+		for i, dp := range series.DataPoints {
+			if dp[0] != points[i][0] || math.Abs(dp[1]-points[i][1]) > 1e-6 {
+				t.Errorf("%s: point %d = %v, want %v", series.Labels["service"], i, dp, points[i])
+			}
+		}
+	}
+
+	sonnet, err := store.GetEfficiencySeries(ctx, "claude-code", "sonnet", from, to, 3600)
+	if err != nil {
+		t.Fatalf("GetEfficiencySeries failed: %v", err)
+	}
+	if len(sonnet.Series) != 1 || len(sonnet.Series[0].DataPoints) != 1 || sonnet.Series[0].Labels["model"] != "sonnet" {
+		t.Errorf("expected the sonnet bucket only, got %+v", sonnet.Series)
+	}
+}
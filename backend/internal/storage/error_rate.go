@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// errorRateSeriesName is the name of the series returned by QueryErrorRateSeries
+const errorRateSeriesName = "error_rate"
+
+// QueryErrorRateSeries returns one series per service with the fraction (0-1) of spans with
+// StatusCode ERROR in each interval bucket within [from, to]. Like QueryMetricSeries, every
+// bucket of the range is present; buckets without spans are 0. An empty service selects
+// all services that have spans in the range.
+func (s *DuckDBStore) QueryErrorRateSeries(ctx context.Context, service string, from, to time.Time, intervalSeconds int64) (*api.TimeSeriesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+	intervalStr := fmt.Sprintf("%d seconds", intervalSeconds)
+
+	serviceFilter := ""
+	args := []interface{}{fromStr, toStr, fromStr, toStr}
+	if service != "" {
+		serviceFilter = " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	query := fmt.Sprintf(`
+		WITH buckets AS (
+			SELECT UNNEST(generate_series(
+				time_bucket(INTERVAL '%[1]s', ?::TIMESTAMP),
+				time_bucket(INTERVAL '%[1]s', ?::TIMESTAMP),
+				INTERVAL '%[1]s'
+			)) as bucket
+		),
+		data AS (
+			SELECT
+				time_bucket(INTERVAL '%[1]s', Timestamp) as bucket,
+				ServiceName,
+				COUNT(*) FILTER (WHERE StatusCode = 'ERROR') / COUNT(*) as error_rate
+			FROM otel_traces
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				%[2]s
+			GROUP BY bucket, ServiceName
+		),
+		services AS (
+			SELECT DISTINCT ServiceName FROM data
+		)
+		SELECT
+			b.bucket,
+			s.ServiceName,
+			COALESCE(d.error_rate, 0) as error_rate
+		FROM buckets b
+		CROSS JOIN services s
+		LEFT JOIN data d ON b.bucket = d.bucket AND s.ServiceName = d.ServiceName
+		ORDER BY s.ServiceName, b.bucket
+	`, intervalStr, serviceFilter)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying error rate series: %w", err)
+	}
+	defer rows.Close()
+
+	series := make([]api.TimeSeries, 0)
+	for rows.Next() {
+		var bucket time.Time
+		var serviceName string
+		var rate float64
+		if err := rows.Scan(&bucket, &serviceName, &rate); err != nil {
+			return nil, fmt.Errorf("scanning error rate series: %w", err)
+		}
+
+		if len(series) == 0 || series[len(series)-1].Labels["service"] != serviceName {
+			series = append(series, api.TimeSeries{
+				Name:       errorRateSeriesName,
+				Labels:     map[string]string{"service": serviceName},
+				DataPoints: make([][2]float64, 0),
+			})
+		}
+		current := &series[len(series)-1]
+		current.DataPoints = append(current.DataPoints, [2]float64{float64(bucket.UnixMilli()), rate})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating error rate series: %w", err)
+	}
+
+	return &api.TimeSeriesResponse{Series: series}, nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestQueryErrorRateSeries(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Now().UTC().Truncate(time.Hour).Add(-3 * time.Hour)
+
+	// svc-a: 1 of 4 spans failed in the first hour, nothing in the second, 1 of 1 in the third
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "a1", ServiceName: "svc-a", SpanName: "op", Timestamp: base.Add(time.Minute), StatusCode: "ERROR"},
+		{TraceID: "t1", SpanID: "a2", ServiceName: "svc-a", SpanName: "op", Timestamp: base.Add(2 * time.Minute), StatusCode: "OK"},
+		{TraceID: "t1", SpanID: "a3", ServiceName: "svc-a", SpanName: "op", Timestamp: base.Add(3 * time.Minute)},
+		{TraceID: "t1", SpanID: "a4", ServiceName: "svc-a", SpanName: "op", Timestamp: base.Add(4 * time.Minute), StatusCode: "OK"},
+		{TraceID: "t2", SpanID: "a5", ServiceName: "svc-a", SpanName: "op", Timestamp: base.Add(2*time.Hour + time.Minute), StatusCode: "ERROR"},
+		{TraceID: "t3", SpanID: "b1", ServiceName: "svc-b", SpanName: "op", Timestamp: base.Add(time.Hour + time.Minute), StatusCode: "OK"},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	from, to := base, base.Add(2*time.Hour+30*time.Minute)
+	resp, err := store.QueryErrorRateSeries(ctx, "", from, to, 3600)
+	if err != nil {
+		t.Fatalf("QueryErrorRateSeries failed: %v", err)
+	}
+	if len(resp.Series) != 2 {
+		t.Fatalf("expected 2 series, got %+v", resp.Series)
+	}
+
+	a, b := resp.Series[0], resp.Series[1]
+	if a.Name != "error_rate" || a.Labels["service"] != "svc-a" || b.Labels["service"] != "svc-b" {
+		t.Fatalf("unexpected series labels: %+v", resp.Series)
+	}
+
+	want := [][2]float64{
+		{float64(base.UnixMilli()), 0.25},
+		{float64(base.Add(time.Hour).UnixMilli()), 0},
+		{float64(base.Add(2 * time.Hour).UnixMilli()), 1},
+	}
+	if len(a.DataPoints) != len(want) {
+		t.Fatalf("expected %d zero-filled buckets, got %v", len(want), a.DataPoints)
+	}
+	for i, point := range want {
+		if a.DataPoints[i] != point {
+			t.Errorf("svc-a bucket %d = %v, want %v", i, a.DataPoints[i], point)
+		}
+	}
+	if len(b.DataPoints) != 3 || b.DataPoints[0][1] != 0 || b.DataPoints[1][1] != 0 {
+		t.Errorf("unexpected svc-b series: %v", b.DataPoints)
+	}
+
+	// Service filter
+	resp, err = store.QueryErrorRateSeries(ctx, "svc-b", from, to, 3600)
+	if err != nil {
+		t.Fatalf("QueryErrorRateSeries failed: %v", err)
+	}
+	if len(resp.Series) != 1 || resp.Series[0].Labels["service"] != "svc-b" {
+		t.Errorf("expected only svc-b, got %+v", resp.Series)
+	}
+
+	// No spans in range
+	resp, err = store.QueryErrorRateSeries(ctx, "", base.Add(-48*time.Hour), base.Add(-24*time.Hour), 3600)
+	if err != nil {
+		t.Fatalf("QueryErrorRateSeries failed: %v", err)
+	}
+	if resp.Series == nil || len(resp.Series) != 0 {
+		t.Errorf("expected an empty, non-nil series list, got %+v", resp.Series)
+	}
+}
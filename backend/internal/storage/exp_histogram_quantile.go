@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// isExpHistogram reports whether a stored metric type is an OTLP exponential histogram.
+// Ingestion stores "exponential_histogram"; "exp_histogram" is accepted for older rows.
+func isExpHistogram(metricType string) bool {
+	return metricType == "exponential_histogram" || metricType == "exp_histogram"
+}
+
+// expHistogramPoint is a single stored exponential histogram data point
+type expHistogramPoint struct {
+	scale     int32
+	zeroCount float64
+	posOffset int32
+	pos       []uint64
+	negOffset int32
+	neg       []uint64
+}
+
+// expHistogram accumulates exponential histogram bucket counts at a single scale.
+// Counts are float64 so cumulative snapshots can be subtracted.
+type expHistogram struct {
+	scale int32
+	zero  float64
+	pos   map[int32]float64
+	neg   map[int32]float64
+}
+
+// mergeExpHistograms combines points into one histogram at the coarsest scale among them.
+// Points with a negative sign are subtracted, which turns cumulative snapshots into deltas.
+func mergeExpHistograms(points []expHistogramPoint, signs []float64) *expHistogram {
+	h := &expHistogram{scale: math.MaxInt32, pos: map[int32]float64{}, neg: map[int32]float64{}}
+	for _, p := range points {
+		if p.scale < h.scale {
+			h.scale = p.scale
+		}
+	}
+	for i, p := range points {
+		sign := signs[i]
+		// Downscaling by d merges 2^d adjacent buckets: index i maps to i >> d
+		shift := p.scale - h.scale
+		h.zero += sign * p.zeroCount
+		for j, c := range p.pos {
+			h.pos[(p.posOffset+int32(j))>>shift] += sign * float64(c)
+		}
+		for j, c := range p.neg {
+			h.neg[(p.negOffset+int32(j))>>shift] += sign * float64(c)
+		}
+	}
+	return h
+}
+
+// bucketBounds returns the (lower, upper] absolute value bounds of a bucket index
+func (h *expHistogram) bucketBounds(index int32) (float64, float64) {
+	base := math.Exp2(math.Exp2(-float64(h.scale)))
+	return math.Pow(base, float64(index)), math.Pow(base, float64(index+1))
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) by walking buckets from the most
+// negative value upwards and interpolating linearly within the bucket holding the rank.
+func (h *expHistogram) Quantile(q float64) float64 {
+	count := func(c float64) float64 { return math.Max(c, 0) }
+
+	negIdx := make([]int32, 0, len(h.neg))
+	for idx := range h.neg {
+		negIdx = append(negIdx, idx)
+	}
+	posIdx := make([]int32, 0, len(h.pos))
+	for idx := range h.pos {
+		posIdx = append(posIdx, idx)
+	}
+	// Larger negative indexes hold more negative values, so they come first
+	sort.Slice(negIdx, func(i, j int) bool { return negIdx[i] > negIdx[j] })
+	sort.Slice(posIdx, func(i, j int) bool { return posIdx[i] < posIdx[j] })
+
+	total := count(h.zero)
+	for _, c := range h.neg {
+		total += count(c)
+	}
+	for _, c := range h.pos {
+		total += count(c)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * total
+	seen := 0.0
+	for _, idx := range negIdx {
+		c := count(h.neg[idx])
+		if c > 0 && seen+c >= rank {
+			lower, upper := h.bucketBounds(idx)
+			return -upper + (upper-lower)*(rank-seen)/c
+		}
+		seen += c
+	}
+	if seen+count(h.zero) >= rank {
+		return 0
+	}
+	seen += count(h.zero)
+	for _, idx := range posIdx {
+		c := count(h.pos[idx])
+		if c > 0 && seen+c >= rank {
+			lower, upper := h.bucketBounds(idx)
+			return lower + (upper-lower)*(rank-seen)/c
+		}
+		seen += c
+	}
+	if len(posIdx) > 0 {
+		_, upper := h.bucketBounds(posIdx[len(posIdx)-1])
+		return upper
+	}
+	return 0
+}
+
+// expHistogramRow is a stored data point with the labels used to group it into a series
+type expHistogramRow struct {
+	bucket      time.Time
+	serviceName string
+	attrType    string
+	attributes  string
+	point       expHistogramPoint
+}
+
+// queryExpHistogramQuantiles returns the q-th quantile of an exponential histogram metric,
+// either per time bucket or over the whole range. DELTA points are merged as-is; CUMULATIVE
+// series use their latest snapshot per bucket, or latest minus earliest when aggregating.
+// Callers must hold s.mu.
+func (s *DuckDBStore) queryExpHistogramQuantiles(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate, isCumulative bool, q float64) (*api.TimeSeriesResponse, error) {
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+	intervalStr := fmt.Sprintf("%d seconds", intervalSeconds)
+
+	bucketExpr := "NULL::TIMESTAMP"
+	if !aggregate {
+		bucketExpr = fmt.Sprintf("time_bucket(INTERVAL '%s', Timestamp)", intervalStr)
+	}
+	query := fmt.Sprintf(`
+		SELECT
+			%s as bucket,
+			ServiceName,
+			COALESCE(Attributes->>'type', Attributes->>'gen_ai.token.type', 'default') as attr_type,
+			COALESCE(CAST(Attributes AS VARCHAR), ''),
+			COALESCE(Scale, 0),
+			COALESCE(ZeroCount, 0),
+			COALESCE(PositiveOffset, 0),
+			COALESCE(CAST(PositiveBucketCounts AS VARCHAR), '[]'),
+			COALESCE(NegativeOffset, 0),
+			COALESCE(CAST(NegativeBucketCounts AS VARCHAR), '[]')
+		FROM otel_metrics
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+			AND MetricName = ?
+			AND MetricType IN ('exponential_histogram', 'exp_histogram')
+	`, bucketExpr)
+	args := []interface{}{fromStr, toStr, metricName}
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	query += " ORDER BY Timestamp"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying exponential histograms: %w", err)
+	}
+	defer rows.Close()
+
+	var data []expHistogramRow
+	for rows.Next() {
+		var r expHistogramRow
+		var bucket sql.NullTime
+		var zero uint64
+		var posStr, negStr string
+		if err := rows.Scan(&bucket, &r.serviceName, &r.attrType, &r.attributes,
+			&r.point.scale, &zero, &r.point.posOffset, &posStr, &r.point.negOffset, &negStr); err != nil {
+			return nil, fmt.Errorf("scanning exponential histogram: %w", err)
+		}
+		r.bucket = bucket.Time
+		r.point.zeroCount = float64(zero)
+		if err := json.Unmarshal([]byte(posStr), &r.point.pos); err != nil {
+			return nil, fmt.Errorf("parsing positive buckets: %w", err)
+		}
+		if err := json.Unmarshal([]byte(negStr), &r.point.neg); err != nil {
+			return nil, fmt.Errorf("parsing negative buckets: %w", err)
+		}
+		data = append(data, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating exponential histograms: %w", err)
+	}
+
+	// Group rows into (bucket, series) cells, keeping each attribute set's points in time order
+	type cellKey struct {
+		bucket      time.Time
+		serviceName string
+		attrType    string
+	}
+	cells := make(map[cellKey]map[string][]expHistogramPoint)
+	for _, r := range data {
+		key := cellKey{r.bucket, r.serviceName, r.attrType}
+		if cells[key] == nil {
+			cells[key] = make(map[string][]expHistogramPoint)
+		}
+		cells[key][r.attributes] = append(cells[key][r.attributes], r.point)
+	}
+
+	quantiles := make(map[cellKey]float64, len(cells))
+	for key, byAttrs := range cells {
+		var points []expHistogramPoint
+		var signs []float64
+		for _, pts := range byAttrs {
+			if !isCumulative {
+				for _, p := range pts {
+					points = append(points, p)
+					signs = append(signs, 1)
+				}
+				continue
+			}
+			points = append(points, pts[len(pts)-1])
+			signs = append(signs, 1)
+			if aggregate && len(pts) > 1 {
+				points = append(points, pts[0])
+				signs = append(signs, -1)
+			}
+		}
+		quantiles[key] = mergeExpHistograms(points, signs).Quantile(q)
+	}
+
+	seriesMap := make(map[string]*api.TimeSeries)
+	seriesFor := func(serviceName, attrType string) *api.TimeSeries {
+		key := serviceName + ":" + attrType
+		if ts, ok := seriesMap[key]; ok {
+			return ts
+		}
+		labels := map[string]string{"service": serviceName}
+		if attrType != "default" {
+			labels["type"] = attrType
+		}
+		seriesMap[key] = &api.TimeSeries{Name: metricName, Labels: labels, DataPoints: make([][2]float64, 0)}
+		return seriesMap[key]
+	}
+
+	if aggregate {
+		for key, value := range quantiles {
+			seriesFor(key.serviceName, key.attrType).DataPoints = [][2]float64{{0, value}} // timestamp=0 indicates aggregate
+		}
+	} else {
+		buckets, err := s.seriesBuckets(ctx, intervalStr, fromStr, toStr)
+		if err != nil {
+			return nil, err
+		}
+		labels := make(map[cellKey]struct{})
+		for key := range cells {
+			labels[cellKey{serviceName: key.serviceName, attrType: key.attrType}] = struct{}{}
+		}
+		// Emit every bucket for every series, with zeros where there is no data
+		for label := range labels {
+			ts := seriesFor(label.serviceName, label.attrType)
+			for _, b := range buckets {
+				value := quantiles[cellKey{b, label.serviceName, label.attrType}]
+				ts.DataPoints = append(ts.DataPoints, [2]float64{float64(b.UnixMilli()), value})
+			}
+		}
+	}
+
+	series := make([]api.TimeSeries, 0, len(seriesMap))
+	for _, ts := range seriesMap {
+		series = append(series, *ts)
+	}
+	return &api.TimeSeriesResponse{Series: series}, nil
+}
+
+// seriesBuckets returns the time buckets between from and to, aligned like the series queries
+func (s *DuckDBStore) seriesBuckets(ctx context.Context, intervalStr, fromStr, toStr string) ([]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT UNNEST(generate_series(
+			time_bucket(INTERVAL '%[1]s', ?::TIMESTAMP),
+			time_bucket(INTERVAL '%[1]s', ?::TIMESTAMP),
+			INTERVAL '%[1]s'
+		))
+	`, intervalStr), fromStr, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("querying series buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []time.Time
+	for rows.Next() {
+		var b time.Time
+		if err := rows.Scan(&b); err != nil {
+			return nil, fmt.Errorf("scanning series bucket: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// expHistogramMetric builds a stored exponential histogram data point with positive buckets only
+func expHistogramMetric(ts time.Time, temporality, scale int32, counts []uint64) api.MetricDataPoint {
+	var offset int32
+	var zero uint64
+	return api.MetricDataPoint{
+		Timestamp:              ts,
+		ServiceName:            "svc",
+		MetricName:             "request.duration",
+		MetricType:             "exponential_histogram",
+		AggregationTemporality: &temporality,
+		Scale:                  &scale,
+		ZeroCount:              &zero,
+		PositiveOffset:         &offset,
+		PositiveBucketCounts:   counts,
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestExpHistogram_Quantile(t *testing.T) {
+	// Scale 0: bucket 0 covers (1, 2], bucket 1 covers (2, 4]
+	h := mergeExpHistograms([]expHistogramPoint{{scale: 0, pos: []uint64{10, 10}}}, []float64{1})
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 2},
+		{0.9, 3.6},
+		{1, 4},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); !approxEqual(got, tt.want) {
+			t.Errorf("Quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	// Negative buckets and the zero bucket come before positive values
+	h = mergeExpHistograms([]expHistogramPoint{{scale: 0, zeroCount: 10, neg: []uint64{10}, pos: []uint64{10}}}, []float64{1})
+	if got := h.Quantile(0.25); !approxEqual(got, -1.25) {
+		t.Errorf("Quantile(0.25) = %v, want -1.25", got)
+	}
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile(0.5) = %v, want 0", got)
+	}
+}
+
+func TestExpHistogram_MergeScales(t *testing.T) {
+	// Scale 1 buckets 0 and 1 both fall into scale 0 bucket 0 when downscaled
+	h := mergeExpHistograms([]expHistogramPoint{
+		{scale: 0, pos: []uint64{10, 10}},
+		{scale: 1, pos: []uint64{5, 5}},
+	}, []float64{1, 1})
+
+	if h.scale != 0 || h.pos[0] != 20 || h.pos[1] != 10 {
+		t.Fatalf("unexpected merged histogram: scale=%d pos=%v", h.scale, h.pos)
+	}
+	if got := h.Quantile(0.5); !approxEqual(got, 1.75) {
+		t.Errorf("Quantile(0.5) = %v, want 1.75", got)
+	}
+}
+
+func TestQueryMetricSeries_ExpHistogramQuantile(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	metrics := []api.MetricDataPoint{
+		expHistogramMetric(now, 1, 0, []uint64{10}),
+		expHistogramMetric(now.Add(time.Minute), 1, 0, []uint64{0, 10}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+	from, to := now.Add(-time.Minute), now.Add(2*time.Minute)
+
+	// Buckets are combined across the whole range: 10 values in (1, 2] and 10 in (2, 4]
+	resp, err := store.QueryMetricSeries(ctx, "request.duration", "", from, to, 60, true, 0.9)
+	if err != nil {
+		t.Fatalf("QueryMetricSeries failed: %v", err)
+	}
+	if len(resp.Series) != 1 || len(resp.Series[0].DataPoints) != 1 {
+		t.Fatalf("expected one aggregate series, got %+v", resp.Series)
+	}
+	if got := resp.Series[0].DataPoints[0][1]; !approxEqual(got, 3.6) {
+		t.Errorf("p90 = %v, want 3.6", got)
+	}
+
+	// Per time bucket, each minute only sees its own buckets
+	resp, err = store.QueryMetricSeries(ctx, "request.duration", "", from, to, 60, false, 0.5)
+	if err != nil {
+		t.Fatalf("QueryMetricSeries failed: %v", err)
+	}
+	if len(resp.Series) != 1 {
+		t.Fatalf("expected one series, got %d", len(resp.Series))
+	}
+	values := make(map[int64]float64)
+	for _, dp := range resp.Series[0].DataPoints {
+		values[int64(dp[0])] = dp[1]
+	}
+	if len(values) != 4 {
+		t.Errorf("expected 4 time buckets, got %d", len(values))
+	}
+	if got := values[now.UnixMilli()]; !approxEqual(got, 1.5) {
+		t.Errorf("p50 at first minute = %v, want 1.5", got)
+	}
+	if got := values[now.Add(time.Minute).UnixMilli()]; !approxEqual(got, 3) {
+		t.Errorf("p50 at second minute = %v, want 3", got)
+	}
+
+	// Batch queries accept the quantile as well
+	batch := store.QueryBatchMetricSeries(ctx, []api.MetricQuery{
+		{ID: "p90", Name: "request.duration", Aggregate: true, Quantile: 0.9},
+	}, from, to, 60)
+	if !batch.Results[0].Success || len(batch.Results[0].Series) != 1 {
+		t.Fatalf("unexpected batch result: %+v", batch.Results[0])
+	}
+	if got := batch.Results[0].Series[0].DataPoints[0][1]; !approxEqual(got, 3.6) {
+		t.Errorf("batch p90 = %v, want 3.6", got)
+	}
+}
+
+func TestQueryMetricSeries_ExpHistogramQuantileCumulative(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	// Cumulative snapshots: the second minute only added values in (2, 4]
+	metrics := []api.MetricDataPoint{
+		expHistogramMetric(now, 2, 0, []uint64{10}),
+		expHistogramMetric(now.Add(time.Minute), 2, 0, []uint64{10, 10}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	resp, err := store.QueryMetricSeries(ctx, "request.duration", "", now.Add(-time.Minute), now.Add(2*time.Minute), 60, true, 0.5)
+	if err != nil {
+		t.Fatalf("QueryMetricSeries failed: %v", err)
+	}
+	if len(resp.Series) != 1 {
+		t.Fatalf("expected one series, got %d", len(resp.Series))
+	}
+	if got := resp.Series[0].DataPoints[0][1]; !approxEqual(got, 3) {
+		t.Errorf("p50 of increase = %v, want 3", got)
+	}
+}
@@ -14,6 +14,39 @@ type ImportState struct {
 	FileHash    string
 	ImportedAt  time.Time
 	RecordCount int
+	Counts      *ImportCounts     // nil for files imported before counts were recorded
+	Checkpoint  *ImportCheckpoint // nil when the file cannot be parsed from where it stopped
+}
+
+// ImportCounts is how many records of each signal a file's parse produced, before any date
+// filtering. Session files only grow, so a later parse can skip this many records of each
+// signal to get the new ones.
+type ImportCounts struct {
+	Logs    int
+	Metrics int
+	Spans   int
+}
+
+// ImportCheckpoint is where the parse of an append-only session file stopped: the end of the
+// last complete line it read, and the parser state needed to continue with the next line
+type ImportCheckpoint struct {
+	Offset int64  // Bytes parsed
+	Lines  int    // Lines parsed
+	State  string // Parser-specific state, as JSON
+}
+
+// scanCounts fills state.Counts from nullable count columns
+func (state *ImportState) scanCounts(logs, metrics, spans sql.NullInt64) {
+	if logs.Valid && metrics.Valid && spans.Valid {
+		state.Counts = &ImportCounts{Logs: int(logs.Int64), Metrics: int(metrics.Int64), Spans: int(spans.Int64)}
+	}
+}
+
+// scanCheckpoint fills state.Checkpoint from nullable checkpoint columns
+func (state *ImportState) scanCheckpoint(offset, lines sql.NullInt64, parserState sql.NullString) {
+	if offset.Valid && lines.Valid {
+		state.Checkpoint = &ImportCheckpoint{Offset: offset.Int64, Lines: int(lines.Int64), State: parserState.String}
+	}
 }
 
 // GetImportState retrieves the import state for a specific file
@@ -22,18 +55,27 @@ func (s *DuckDBStore) GetImportState(ctx context.Context, source, filePath strin
 	defer s.mu.RUnlock()
 
 	query := `
-		SELECT source, file_path, file_hash, imported_at, record_count
+		SELECT source, file_path, file_hash, imported_at, record_count, log_count, metric_count, span_count,
+			file_offset, line_count, parser_state
 		FROM import_state
 		WHERE source = ? AND file_path = ?
 	`
 
 	var state ImportState
+	var logs, metrics, spans, offset, lines sql.NullInt64
+	var parserState sql.NullString
 	err := s.db.QueryRowContext(ctx, query, source, filePath).Scan(
 		&state.Source,
 		&state.FilePath,
 		&state.FileHash,
 		&state.ImportedAt,
 		&state.RecordCount,
+		&logs,
+		&metrics,
+		&spans,
+		&offset,
+		&lines,
+		&parserState,
 	)
 
 	if err == sql.ErrNoRows {
@@ -42,6 +84,8 @@ func (s *DuckDBStore) GetImportState(ctx context.Context, source, filePath strin
 	if err != nil {
 		return nil, fmt.Errorf("querying import state: %w", err)
 	}
+	state.scanCounts(logs, metrics, spans)
+	state.scanCheckpoint(offset, lines, parserState)
 
 	return &state, nil
 }
@@ -51,18 +95,48 @@ func (s *DuckDBStore) SetImportState(ctx context.Context, state *ImportState) er
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return setImportState(ctx, s.db, state)
+}
+
+// execer runs statements on the database or within a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// setImportState upserts a file's import state through db
+func setImportState(ctx context.Context, db execer, state *ImportState) error {
 	// Use INSERT OR REPLACE for upsert behavior
 	query := `
-		INSERT OR REPLACE INTO import_state (source, file_path, file_hash, imported_at, record_count)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO import_state (source, file_path, file_hash, imported_at, record_count, log_count, metric_count, span_count,
+			file_offset, line_count, parser_state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.ExecContext(ctx, query,
+	var logs, metrics, spans sql.NullInt64
+	if c := state.Counts; c != nil {
+		logs = sql.NullInt64{Int64: int64(c.Logs), Valid: true}
+		metrics = sql.NullInt64{Int64: int64(c.Metrics), Valid: true}
+		spans = sql.NullInt64{Int64: int64(c.Spans), Valid: true}
+	}
+	var offset, lines sql.NullInt64
+	var parserState sql.NullString
+	if c := state.Checkpoint; c != nil {
+		offset = sql.NullInt64{Int64: c.Offset, Valid: true}
+		lines = sql.NullInt64{Int64: int64(c.Lines), Valid: true}
+		parserState = sql.NullString{String: c.State, Valid: c.State != ""}
+	}
+	_, err := db.ExecContext(ctx, query,
 		state.Source,
 		state.FilePath,
 		state.FileHash,
 		state.ImportedAt,
 		state.RecordCount,
+		logs,
+		metrics,
+		spans,
+		offset,
+		lines,
+		parserState,
 	)
 	if err != nil {
 		return fmt.Errorf("setting import state: %w", err)
@@ -91,7 +165,8 @@ func (s *DuckDBStore) ListImportedFiles(ctx context.Context, source string) ([]I
 	defer s.mu.RUnlock()
 
 	query := `
-		SELECT source, file_path, file_hash, imported_at, record_count
+		SELECT source, file_path, file_hash, imported_at, record_count, log_count, metric_count, span_count,
+			file_offset, line_count, parser_state
 		FROM import_state
 		WHERE source = ?
 		ORDER BY imported_at DESC
@@ -106,15 +181,25 @@ func (s *DuckDBStore) ListImportedFiles(ctx context.Context, source string) ([]I
 	var states []ImportState
 	for rows.Next() {
 		var state ImportState
+		var logs, metrics, spans, offset, lines sql.NullInt64
+		var parserState sql.NullString
 		if err := rows.Scan(
 			&state.Source,
 			&state.FilePath,
 			&state.FileHash,
 			&state.ImportedAt,
 			&state.RecordCount,
+			&logs,
+			&metrics,
+			&spans,
+			&offset,
+			&lines,
+			&parserState,
 		); err != nil {
 			return nil, fmt.Errorf("scanning import state: %w", err)
 		}
+		state.scanCounts(logs, metrics, spans)
+		state.scanCheckpoint(offset, lines, parserState)
 		states = append(states, state)
 	}
 
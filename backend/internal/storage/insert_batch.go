@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// InsertBatch inserts spans, logs and metrics in a single transaction, so rows buffered from
+// many OTLP requests are committed at once. Nothing is stored if any insert fails.
+func (s *DuckDBStore) InsertBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint) error {
+	return s.InsertImportBatch(ctx, spans, logs, metrics, nil)
+}
+
+// InsertImportBatch is InsertBatch that also records the import state of the session files
+// the records were read from, so an import's progress is committed with its records and a
+// retried import never writes them twice
+func (s *DuckDBStore) InsertImportBatch(ctx context.Context, spans []api.Span, logs []api.LogRecord, metrics []api.MetricDataPoint, states []*ImportState) error {
+	if len(spans) == 0 && len(logs) == 0 && len(metrics) == 0 && len(states) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if len(spans) > 0 {
+		if err := insertSpansTx(ctx, tx, spans); err != nil {
+			return err
+		}
+	}
+	if len(logs) > 0 {
+		if err := insertLogsTx(ctx, tx, logs); err != nil {
+			return err
+		}
+	}
+	if len(metrics) > 0 {
+		if err := insertMetricsTx(ctx, tx, metrics); err != nil {
+			return err
+		}
+	}
+	for _, state := range states {
+		if err := setImportState(ctx, tx, state); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if len(logs) > 0 {
+		s.markLogsModified()
+	} else if len(spans) > 0 || len(metrics) > 0 {
+		s.markModified()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestInsertBatch(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	spans := []api.Span{
+		{TraceID: "trace-1", SpanID: "span-1", ServiceName: "svc", SpanName: "a", Timestamp: now},
+		{TraceID: "trace-1", SpanID: "span-2", ServiceName: "svc", SpanName: "b", Timestamp: now},
+	}
+	logs := []api.LogRecord{{Timestamp: now, ServiceName: "svc", Body: "hello"}}
+	metrics := []api.MetricDataPoint{{Timestamp: now, ServiceName: "svc", MetricName: "m", MetricType: "gauge"}}
+
+	version, _ := store.DataVersion()
+	if err := store.InsertBatch(ctx, spans, logs, metrics); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if v, _ := store.DataVersion(); v == version {
+		t.Error("expected InsertBatch to bump the data version")
+	}
+
+	for table, want := range map[string]int{"otel_traces": 2, "otel_logs": 1, "otel_metrics": 1} {
+		var count int
+		if err := store.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Fatalf("count query failed: %v", err)
+		}
+		if count != want {
+			t.Errorf("%s: expected %d rows, got %d", table, want, count)
+		}
+	}
+}
+
+func TestInsertBatch_Empty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	version, _ := store.DataVersion()
+	if err := store.InsertBatch(context.Background(), nil, nil, nil); err != nil {
+		t.Fatalf("InsertBatch with no rows should not error: %v", err)
+	}
+	if v, _ := store.DataVersion(); v != version {
+		t.Error("expected an empty batch to leave the data version unchanged")
+	}
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// GetLatencyCostScatter returns one point per trace started within [from, to] that can be
+// correlated with a session, pairing the trace duration with the cost spent on it, oldest
+// first. An empty service selects all services.
+//
+// Traces are grouped by TraceId and their duration computed as in QueryTraces. The session
+// comes from a session.id/conversation.id span or resource attribute, or else from a log
+// emitted with the trace's TraceId (the first two GetTraceSession heuristics). Each
+// *.cost.usage point of a session within [from, to] is attributed to the latest trace of that
+// session that started at or before it; cumulative series contribute their increase since
+// the previous point in range. Traces without such cost points have a cost of 0.
+func (s *DuckDBStore) GetLatencyCostScatter(ctx context.Context, service string, from, to time.Time) ([]api.ScatterPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	serviceFilter := ""
+	args := []interface{}{fromStr, toStr}
+	if service != "" {
+		serviceFilter = " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	args = append(args, fromStr, toStr)
+
+	query := `
+		WITH spans AS (
+			SELECT
+				TraceId, SpanName, ServiceName, Timestamp, Duration, ParentSpanId,
+				COALESCE(
+					json_extract_string(SpanAttributes, '$."session.id"'),
+					json_extract_string(SpanAttributes, '$."conversation.id"'),
+					json_extract_string(ResourceAttributes, '$."session.id"'),
+					json_extract_string(ResourceAttributes, '$."conversation.id"')
+				) as session_id
+			FROM otel_traces
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP` + serviceFilter + `
+		),
+		traces AS (
+			SELECT
+				TraceId,
+				FIRST(ServiceName ORDER BY Timestamp ASC) as service_name,
+				FIRST(SpanName ORDER BY Timestamp ASC) as root_span,
+				MIN(Timestamp) as start_time,
+				` + traceDurationExpr + ` as duration,
+				ANY_VALUE(session_id) as span_session
+			FROM spans
+			GROUP BY TraceId
+		),
+		log_sessions AS (
+			SELECT TraceId, FIRST(` + sessionIDExpr + ` ORDER BY Timestamp ASC) as log_session
+			FROM otel_logs
+			WHERE TraceId IN (SELECT TraceId FROM traces) AND ` + sessionIDExpr + ` IS NOT NULL
+			GROUP BY TraceId
+		),
+		correlated AS (
+			SELECT t.*, COALESCE(t.span_session, l.log_session) as session_id
+			FROM traces t
+			LEFT JOIN log_sessions l ON l.TraceId = t.TraceId
+			WHERE COALESCE(t.span_session, l.log_session) IS NOT NULL
+		),
+		cost_points AS (
+			SELECT
+				session_id,
+				Timestamp,
+				CASE WHEN AggregationTemporality = 2
+					THEN COALESCE(value - LAG(value) OVER (
+						PARTITION BY ServiceName, MetricName, CAST(Attributes AS VARCHAR) ORDER BY Timestamp
+					), 0)
+					ELSE value
+				END as cost
+			FROM (
+				SELECT
+					ServiceName, MetricName, Attributes, Timestamp, AggregationTemporality,
+					COALESCE(Value, Sum) as value,
+					COALESCE(
+						json_extract_string(Attributes, '$."session.id"'),
+						json_extract_string(Attributes, '$."conversation.id"')
+					) as session_id
+				FROM otel_metrics
+				WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+					AND MetricName LIKE '%.cost.usage'
+			)
+			WHERE session_id IS NOT NULL
+		),
+		trace_costs AS (
+			SELECT c.TraceId, SUM(p.cost) as cost
+			FROM cost_points p
+			ASOF JOIN correlated c ON p.session_id = c.session_id AND p.Timestamp >= c.start_time
+			GROUP BY c.TraceId
+		)
+		SELECT
+			c.TraceId, c.service_name, c.root_span, c.session_id, c.start_time, c.duration,
+			COALESCE(tc.cost, 0) as cost
+		FROM correlated c
+		LEFT JOIN trace_costs tc ON tc.TraceId = c.TraceId
+		ORDER BY c.start_time, c.TraceId
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying latency cost scatter: %w", err)
+	}
+	defer rows.Close()
+
+	points := []api.ScatterPoint{}
+	for rows.Next() {
+		var p api.ScatterPoint
+		if err := rows.Scan(&p.TraceID, &p.ServiceName, &p.RootSpan, &p.SessionID, &p.StartTime, &p.Duration, &p.CostUSD); err != nil {
+			return nil, fmt.Errorf("scanning scatter point: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating scatter points: %w", err)
+	}
+
+	return points, nil
+}
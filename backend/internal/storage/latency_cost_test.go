@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetLatencyCostScatter(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+
+	spans := []api.Span{
+		// Session s1 via span attributes: two traces
+		{TraceID: "t1", SpanID: "t1-root", ServiceName: "claude-code", SpanName: "claude.turn", Timestamp: now.Add(-10 * time.Minute), Duration: int64(2 * time.Second), SpanAttributes: map[string]string{"session.id": "s1"}},
+		{TraceID: "t1", SpanID: "t1-child", ParentSpanID: "t1-root", ServiceName: "claude-code", SpanName: "tool", Timestamp: now.Add(-10 * time.Minute), Duration: int64(time.Second)},
+		{TraceID: "t2", SpanID: "t2-root", ServiceName: "claude-code", SpanName: "claude.turn", Timestamp: now.Add(-5 * time.Minute), Duration: int64(10 * time.Second), ResourceAttributes: map[string]string{"session.id": "s1"}},
+		// Session s2 via a log emitted inside the trace
+		{TraceID: "t3", SpanID: "t3-root", ServiceName: "gemini_cli", SpanName: "request", Timestamp: now.Add(-3 * time.Minute), Duration: int64(time.Second)},
+		// Not correlated with any session
+		{TraceID: "t4", SpanID: "t4-root", ServiceName: "claude-code", SpanName: "orphan", Timestamp: now.Add(-2 * time.Minute), Duration: int64(time.Second)},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-3 * time.Minute), TraceID: "t3", ServiceName: "gemini_cli", Body: "request", LogAttributes: map[string]string{"session.id": "s2"}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	point := func(ts time.Time, service string, temporality *int32, session string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: service + ".cost.usage", MetricType: "sum",
+			AggregationTemporality: temporality, Attributes: map[string]string{"session.id": session}, Value: &v,
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		// Delta points go to the latest trace of the session started before them
+		point(now.Add(-9*time.Minute), "claude-code", nil, "s1", 0.10),
+		point(now.Add(-4*time.Minute), "claude-code", nil, "s1", 0.50),
+		point(now.Add(-4*time.Minute+time.Second), "claude-code", nil, "s1", 0.25),
+		// Cumulative points contribute their increase
+		point(now.Add(-2*time.Minute), "gemini_cli", &cumulative, "s2", 1.0),
+		point(now.Add(-time.Minute), "gemini_cli", &cumulative, "s2", 1.5),
+		// Cost of a session without traces is ignored
+		point(now.Add(-time.Minute), "claude-code", nil, "s9", 9),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	points, err := store.GetLatencyCostScatter(ctx, "", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetLatencyCostScatter failed: %v", err)
+	}
+
+	want := []struct {
+		traceID  string
+		session  string
+		duration time.Duration
+		cost     float64
+	}{
+		{"t1", "s1", 2 * time.Second, 0.10},
+		{"t2", "s1", 10 * time.Second, 0.75},
+		{"t3", "s2", time.Second, 0.5},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("expected %d points, got %+v", len(want), points)
+	}
+	for i, w := range want {
+		p := points[i]
+		if p.TraceID != w.traceID || p.SessionID != w.session || p.Duration != int64(w.duration) || math.Abs(p.CostUSD-w.cost) > 1e-9 {
+			t.Errorf("point %d = %+v, want trace %s, session %s, duration %v, cost %v", i, p, w.traceID, w.session, w.duration, w.cost)
+		}
+		if p.StartTime.IsZero() || p.ServiceName == "" || p.RootSpan == "" {
+			t.Errorf("point %d is missing trace details: %+v", i, p)
+		}
+	}
+
+	// Service filter
+	points, err = store.GetLatencyCostScatter(ctx, "gemini_cli", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetLatencyCostScatter failed: %v", err)
+	}
+	if len(points) != 1 || points[0].TraceID != "t3" {
+		t.Errorf("expected only t3, got %+v", points)
+	}
+}
+
+func TestGetLatencyCostScatter_Empty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	points, err := store.GetLatencyCostScatter(context.Background(), "", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetLatencyCostScatter failed: %v", err)
+	}
+	if points == nil || len(points) != 0 {
+		t.Errorf("expected an empty, non-nil point list, got %+v", points)
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// logSearchTables are the two tables the full-text log index alternates between. A rebuild
+// writes the table not in use, so searches keep using the current index until it is swapped.
+var logSearchTables = [2]string{"log_search_docs_a", "log_search_docs_b"}
+
+// logSearchIgnore is the pattern the full-text index splits words on. Words are runs of
+// letters and digits, lowercased and without accents, neither stemmed nor filtered for stop
+// words, so logSearchWords matches them exactly in SQL.
+const logSearchIgnore = `[^a-z0-9]+`
+
+// logSearchMinTail is the fewest logs written since the last index build that trigger a
+// rebuild; larger indexes wait for a tail of a tenth of their size
+const logSearchMinTail = 50000
+
+// logSearchIndex tracks the DuckDB full-text index over log bodies and attributes.
+// The FTS extension does not update its index on insert, so the index is built over a
+// snapshot table when search is enabled and rebuilt by RefreshLogSearchIndex once enough
+// logs were appended; logs after the snapshot (rowid above maxRowID) are matched by the same
+// word rule in SQL. Fields are guarded by DuckDBStore.mu.
+type logSearchIndex struct {
+	available  bool   // The fts extension loaded, see EnableLogSearch
+	ready      bool   // The index reflects otel_logs up to maxRowID
+	active     int    // Index into logSearchTables of the table searches use
+	maxRowID   int64  // Highest otel_logs rowid in the snapshot
+	rows       int64  // Logs in the snapshot
+	version    uint64 // LogsVersion the snapshot was taken at
+	generation uint64 // Incremented by invalidateLogSearchIndex
+}
+
+// EnableLogSearch loads DuckDB's fts extension, installing it first if needed, and builds
+// the full-text log index. It is called once at startup, before the store is shared; stores
+// that never search logs (CLI commands, clones) skip it. Log search falls back to ILIKE when
+// the extension is unavailable, e.g. offline without a cached copy.
+func (s *DuckDBStore) EnableLogSearch(ctx context.Context) {
+	if _, err := s.db.ExecContext(ctx, "LOAD fts"); err != nil {
+		if _, err := s.db.ExecContext(ctx, "INSTALL fts"); err != nil {
+			logger.Warn("Full-text log search unavailable, falling back to ILIKE", "error", err)
+			return
+		}
+		if _, err := s.db.ExecContext(ctx, "LOAD fts"); err != nil {
+			logger.Warn("Full-text log search unavailable, falling back to ILIKE", "error", err)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.logSearch.available = true
+	s.mu.Unlock()
+
+	// Searches match words in SQL until the index is built
+	if err := s.RefreshLogSearchIndex(ctx); err != nil {
+		logger.Warn("Failed to build log search index", "error", err)
+	}
+}
+
+// LogsVersion returns a counter incremented on every log insert or delete
+func (s *DuckDBStore) LogsVersion() uint64 {
+	return s.logsVersion.Load()
+}
+
+// RefreshLogSearchIndex builds the full-text log index, or rebuilds it once the logs written
+// since the last build reach logSearchMinTail or a tenth of the indexed logs, so the part
+// matched without the index stays small. The index is built from a snapshot of otel_logs
+// without holding the store lock, so ingest and queries continue meanwhile; only the swap to
+// the new index takes it. It is a no-op unless EnableLogSearch loaded the extension.
+func (s *DuckDBStore) RefreshLogSearchIndex(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	available, ready := s.logSearch.available, s.logSearch.ready
+	next, indexed, generation := 1-s.logSearch.active, s.logSearch.version, s.logSearch.generation
+	maxRowID, rows := s.logSearch.maxRowID, s.logSearch.rows
+	var tail int64
+	var err error
+	if available && ready && indexed != s.LogsVersion() {
+		err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM otel_logs WHERE rowid > ?", maxRowID).Scan(&tail)
+	}
+	s.mu.RUnlock()
+
+	if !available {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("counting unindexed logs: %w", err)
+	}
+	if ready && tail < max(logSearchMinTail, rows/10) {
+		return nil
+	}
+	version := s.LogsVersion()
+
+	// The fts extension keeps its own term tables, so the text columns are dropped once indexed
+	table := logSearchTables[next]
+	statements := []string{
+		fmt.Sprintf(`CREATE OR REPLACE TABLE %s AS
+			SELECT rowid AS LogRowId, Body, CAST(LogAttributes AS VARCHAR) AS Attributes FROM otel_logs`, table),
+		fmt.Sprintf(`PRAGMA create_fts_index('%s', 'LogRowId', 'Body', 'Attributes',
+			stemmer='none', stopwords='none', ignore='%s', strip_accents=1, lower=1, overwrite=1)`, table, logSearchIgnore),
+		fmt.Sprintf(`ALTER TABLE %s DROP COLUMN Body`, table),
+		fmt.Sprintf(`ALTER TABLE %s DROP COLUMN Attributes`, table),
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("building log search index: %w", err)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT COALESCE(MAX(LogRowId), -1), COUNT(*) FROM %s", table)
+	if err := s.db.QueryRowContext(ctx, query).Scan(&maxRowID, &rows); err != nil {
+		return fmt.Errorf("reading log search index range: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Logs deleted while building may still be in the snapshot with rowids DuckDB can reuse
+	if s.logSearch.generation != generation {
+		return nil
+	}
+	s.logSearch.ready = true
+	s.logSearch.active = next
+	s.logSearch.maxRowID = maxRowID
+	s.logSearch.rows = rows
+	s.logSearch.version = version
+	return nil
+}
+
+// RunLogSearchIndexer refreshes the full-text log index every interval until ctx is
+// cancelled
+func (s *DuckDBStore) RunLogSearchIndexer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.RefreshLogSearchIndex(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to refresh log search index", "error", err)
+		}
+	}
+}
+
+// markLogsModified bumps the data and logs versions after logs were inserted or deleted
+func (s *DuckDBStore) markLogsModified() {
+	s.logsVersion.Add(1)
+	s.markModified()
+}
+
+// invalidateLogSearchIndex stops using the index until the next rebuild, as deletes may let
+// DuckDB reuse indexed rowids. Callers must hold s.mu for writing.
+func (s *DuckDBStore) invalidateLogSearchIndex() {
+	s.logSearch.ready = false
+	s.logSearch.generation++
+}
+
+// searchWordPattern finds a character logSearchIgnore keeps, i.e. part of a word
+var searchWordPattern = regexp.MustCompile(`[a-z0-9]`)
+
+// logSearchWords returns the SQL list of the words in expr, split like the full-text index
+func logSearchWords(expr string) string {
+	return fmt.Sprintf(`list_filter(string_split_regex(lower(strip_accents(%s)), '%s'), w -> w <> '')`, expr, logSearchIgnore)
+}
+
+// logSearchFilter returns the condition matching search in a log's body, attributes, scope
+// name or severity. Scope names and severities are matched by substring. When the fts
+// extension is loaded, bodies and attributes match when they contain any word of search,
+// through the full-text index for the logs it covers and in SQL for the rest; otherwise,
+// or when search has no words, they are matched by substring too. Callers must hold s.mu.
+func (s *DuckDBStore) logSearchFilter(search string) (string, []interface{}) {
+	pattern := "%" + search + "%"
+	if !s.logSearch.available || !searchWordPattern.MatchString(strings.ToLower(search)) {
+		return "(Body ILIKE ? OR ScopeName ILIKE ? OR SeverityText ILIKE ? OR CAST(LogAttributes AS VARCHAR) ILIKE ?)",
+			[]interface{}{pattern, pattern, pattern, pattern}
+	}
+
+	words := logSearchWords("?")
+	match := fmt.Sprintf("(list_has_any(%s, %s) OR list_has_any(%s, %[2]s))",
+		logSearchWords("Body"), words, logSearchWords("CAST(LogAttributes AS VARCHAR)"))
+	if !s.logSearch.ready {
+		return "(" + match + " OR ScopeName ILIKE ? OR SeverityText ILIKE ?)",
+			[]interface{}{search, search, pattern, pattern}
+	}
+
+	table := logSearchTables[s.logSearch.active]
+	return fmt.Sprintf(`(rowid IN (
+			SELECT LogRowId FROM %[1]s
+			WHERE fts_main_%[1]s.match_bm25(LogRowId, ?) IS NOT NULL
+		)
+		OR (rowid > ? AND %[2]s)
+		OR ScopeName ILIKE ? OR SeverityText ILIKE ?)`, table, match),
+		[]interface{}{search, s.logSearch.maxRowID, search, search, pattern, pattern}
+}
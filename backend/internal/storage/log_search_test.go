@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestLogSearchFilter(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Without the fts extension every column is matched with ILIKE
+	cond, args := store.logSearchFilter("timeout")
+	if strings.Contains(cond, "list_has_any") || len(args) != 4 || args[0] != "%timeout%" {
+		t.Errorf("expected an ILIKE filter, got %s %v", cond, args)
+	}
+
+	// Before the index is built, words are matched in SQL for every log
+	store.logSearch = logSearchIndex{available: true}
+	cond, args = store.logSearchFilter("timeout")
+	if strings.Contains(cond, "match_bm25") || !strings.Contains(cond, "list_has_any") || len(args) != 4 || args[0] != "timeout" {
+		t.Errorf("expected a word filter, got %s %v", cond, args)
+	}
+
+	store.logSearch = logSearchIndex{available: true, ready: true, active: 1, maxRowID: 41}
+	cond, args = store.logSearchFilter("timeout")
+	if !strings.Contains(cond, "fts_main_log_search_docs_b.match_bm25") || !strings.Contains(cond, "rowid > ? AND (list_has_any") {
+		t.Errorf("expected a full-text filter with words matched in SQL above the index, got %s", cond)
+	}
+	if len(args) != 6 || args[0] != "timeout" || args[1] != int64(41) || args[2] != "timeout" {
+		t.Errorf("unexpected full-text filter args: %v", args)
+	}
+
+	// A search without words cannot use the index
+	if cond, _ := store.logSearchFilter("--"); strings.Contains(cond, "match_bm25") {
+		t.Error("expected a search without words to use ILIKE")
+	}
+
+	store.invalidateLogSearchIndex()
+	if cond, _ := store.logSearchFilter("timeout"); strings.Contains(cond, "match_bm25") {
+		t.Error("expected an invalidated index not to be used")
+	}
+}
+
+func TestQueryLogs_SearchWords(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", Body: "Upstream request TIMEOUT after 30s"},
+		{Timestamp: now, ServiceName: "svc", Body: "timeouts exceeded"},
+		{Timestamp: now, ServiceName: "svc", Body: "ok", LogAttributes: map[string]string{"error.type": "timeout"}},
+		{Timestamp: now, ServiceName: "svc", Body: "Café opened"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	// The rule the full-text index applies, matched in SQL for logs it does not cover
+	store.logSearch = logSearchIndex{available: true}
+	tests := []struct {
+		search string
+		want   int
+	}{
+		{"timeout", 2},       // Whole words only, in bodies and attributes, ignoring case
+		{"time", 0},          // No substrings
+		{"30s", 1},           // Letters and digits form words
+		{"cafe", 1},          // Accents are ignored
+		{"timeouts nope", 1}, // Any word matches
+	}
+	for _, tt := range tests {
+		resp, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", tt.search, now.Add(-time.Hour), now.Add(time.Hour), 50, 0)
+		if err != nil {
+			t.Fatalf("QueryLogs(%q) failed: %v", tt.search, err)
+		}
+		if len(resp.Logs) != tt.want {
+			t.Errorf("search %q: expected %d logs, got %d", tt.search, tt.want, len(resp.Logs))
+		}
+	}
+}
+
+func TestQueryLogs_SearchWithIndex(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	insert := func(body string) {
+		t.Helper()
+		if err := store.InsertLogs(ctx, []api.LogRecord{{Timestamp: now, ServiceName: "svc", Body: body}}); err != nil {
+			t.Fatalf("InsertLogs failed: %v", err)
+		}
+	}
+	search := func(term string) int {
+		t.Helper()
+		resp, err := store.QueryLogs(ctx, "", "", SeverityRange{}, "", term, now.Add(-time.Hour), now.Add(time.Hour), 50, 0)
+		if err != nil {
+			t.Fatalf("QueryLogs failed: %v", err)
+		}
+		return len(resp.Logs)
+	}
+
+	insert("upstream request timeout")
+	insert("request completed")
+
+	// Falls back to ILIKE when the fts extension cannot be loaded
+	store.EnableLogSearch(ctx)
+
+	// Logs written after the index was built are still found
+	insert("database timeout")
+	if got := search("timeout"); got != 2 {
+		t.Errorf("expected 2 logs matching timeout, got %d", got)
+	}
+	if got := search("request"); got != 2 {
+		t.Errorf("expected 2 logs matching request, got %d", got)
+	}
+
+	if _, err := store.DeleteLogsInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), ""); err != nil {
+		t.Fatalf("DeleteLogsInRange failed: %v", err)
+	}
+	if store.logSearch.ready {
+		t.Error("expected deleting logs to invalidate the index")
+	}
+	if got := search("timeout"); got != 0 {
+		t.Errorf("expected no logs after deleting, got %d", got)
+	}
+}
+
+func TestLogsVersion(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	version := store.LogsVersion()
+
+	// Trace and metric writes leave the logs version alone
+	if err := store.InsertSpans(ctx, []api.Span{{TraceID: "t1", SpanID: "s1", Timestamp: now, ServiceName: "svc"}}); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+	if err := store.InsertBatch(ctx, nil, nil, []api.MetricDataPoint{{Timestamp: now, ServiceName: "svc", MetricName: "m", MetricType: "gauge"}}); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if v := store.LogsVersion(); v != version {
+		t.Errorf("expected logs version %d after non-log writes, got %d", version, v)
+	}
+
+	if err := store.InsertBatch(ctx, nil, []api.LogRecord{{Timestamp: now, ServiceName: "svc", Body: "hello"}}, nil); err != nil {
+		t.Fatalf("InsertBatch failed: %v", err)
+	}
+	if v := store.LogsVersion(); v <= version {
+		t.Errorf("expected a log write to bump the logs version, got %d", v)
+	}
+}
@@ -3,7 +3,11 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tobilg/ai-observer/internal/api"
@@ -23,13 +27,26 @@ func (s *DuckDBStore) InsertLogs(ctx context.Context, logs []api.LogRecord) erro
 	}
 	defer tx.Rollback()
 
+	if err := insertLogsTx(ctx, tx, logs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.markLogsModified()
+	return nil
+}
+
+// insertLogsTx inserts logs within tx
+func insertLogsTx(ctx context.Context, tx *sql.Tx, logs []api.LogRecord) error {
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO otel_logs (
 			Timestamp, TraceId, SpanId, TraceFlags, SeverityText,
 			SeverityNumber, ServiceName, Body, ResourceSchemaUrl,
 			ResourceAttributes, ScopeSchemaUrl, ScopeName, ScopeVersion,
-			ScopeAttributes, LogAttributes
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ScopeAttributes, LogAttributes, DroppedAttributesCount
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("preparing statement: %w", err)
@@ -53,82 +70,123 @@ func (s *DuckDBStore) InsertLogs(ctx context.Context, logs []api.LogRecord) erro
 			nullString(log.ScopeVersion),
 			mapToString(log.ScopeAttributes),
 			mapToString(log.LogAttributes),
+			log.DroppedAttributesCount,
 		)
 		if err != nil {
 			return fmt.Errorf("inserting log: %w", err)
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
-func (s *DuckDBStore) QueryLogs(ctx context.Context, service, severity, traceID, search string, from, to time.Time, limit, offset int) (*api.LogsResponse, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// SeverityRange bounds the SeverityNumber of queried logs (1-24, see the OTLP log data model).
+// A zero bound leaves that side open, so the zero value matches every log.
+type SeverityRange struct {
+	Min int32
+	Max int32
+}
 
+// logsFilter builds the WHERE clause and arguments shared by QueryLogs and CountLogs.
+// Callers must hold s.mu, as search terms may use the full-text log index.
+func (s *DuckDBStore) logsFilter(service, severity string, severities SeverityRange, traceID, search string, from, to time.Time) (string, []interface{}) {
 	// Format times as strings to avoid timezone issues with DuckDB's TIMESTAMP type
-	fromStr := formatTimeForDB(from)
-	toStr := formatTimeForDB(to)
-
-	query := `
-		SELECT
-			Timestamp, TraceId, SpanId, TraceFlags, SeverityText,
-			SeverityNumber, ServiceName, Body, ResourceSchemaUrl,
-			ResourceAttributes, ScopeSchemaUrl, ScopeName, ScopeVersion,
-			ScopeAttributes, LogAttributes
-		FROM otel_logs
-		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
-	`
-	args := []interface{}{fromStr, toStr}
+	where := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
 
 	if service != "" {
-		query += " AND ServiceName = ?"
+		where += " AND ServiceName = ?"
 		args = append(args, service)
 	}
 
 	if severity != "" {
-		query += " AND SeverityText = ?"
+		where += " AND SeverityText = ?"
 		args = append(args, severity)
 	}
 
+	if severities.Min > 0 {
+		where += " AND SeverityNumber >= ?"
+		args = append(args, severities.Min)
+	}
+	if severities.Max > 0 {
+		where += " AND SeverityNumber <= ?"
+		args = append(args, severities.Max)
+	}
+
 	if traceID != "" {
-		query += " AND TraceId = ?"
+		where += " AND TraceId = ?"
 		args = append(args, traceID)
 	}
 
 	if search != "" {
-		query += " AND (Body ILIKE ? OR ScopeName ILIKE ? OR SeverityText ILIKE ? OR CAST(LogAttributes AS VARCHAR) ILIKE ?)"
-		pattern := "%" + search + "%"
-		args = append(args, pattern, pattern, pattern, pattern)
+		cond, searchArgs := s.logSearchFilter(search)
+		where += " AND " + cond
+		args = append(args, searchArgs...)
 	}
 
-	// Get total count
-	countQuery := "SELECT COUNT(*) FROM otel_logs WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
-	countArgs := []interface{}{fromStr, toStr}
-	if service != "" {
-		countQuery += " AND ServiceName = ?"
-		countArgs = append(countArgs, service)
-	}
-	if severity != "" {
-		countQuery += " AND SeverityText = ?"
-		countArgs = append(countArgs, severity)
-	}
-	if traceID != "" {
-		countQuery += " AND TraceId = ?"
-		countArgs = append(countArgs, traceID)
-	}
-	if search != "" {
-		countQuery += " AND (Body ILIKE ? OR ScopeName ILIKE ? OR SeverityText ILIKE ? OR CAST(LogAttributes AS VARCHAR) ILIKE ?)"
-		pattern := "%" + search + "%"
-		countArgs = append(countArgs, pattern, pattern, pattern, pattern)
+	return where, args
+}
+
+// CountLogs returns the number of logs matching the same filters as QueryLogs without fetching rows
+func (s *DuckDBStore) CountLogs(ctx context.Context, service, severity string, severities SeverityRange, traceID, search string, from, to time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	where, args := s.logsFilter(service, severity, severities, traceID, search, from, to)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM otel_logs WHERE "+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting logs: %w", err)
 	}
+	return total, nil
+}
+
+func (s *DuckDBStore) QueryLogs(ctx context.Context, service, severity string, severities SeverityRange, traceID, search string, from, to time.Time, limit, offset int) (*api.LogsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryLogs(ctx, service, severity, severities, traceID, search, from, to, limit, offset, nil)
+}
 
+// QueryLogsPage returns the logs matching the filters before or after a cursor, seeking on
+// (Timestamp, row key) instead of skipping rows with OFFSET. The row key (see logRowKey) stays
+// the same when other logs are deleted or the database is checkpointed, unlike rowid; logs
+// that share a timestamp and row key are seen as one at a page boundary.
+func (s *DuckDBStore) QueryLogsPage(ctx context.Context, service, severity string, severities SeverityRange, traceID, search string, from, to time.Time, limit int, page PageCursor) (*api.LogsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryLogs(ctx, service, severity, severities, traceID, search, from, to, limit, 0, &page)
+}
+
+// queryLogs pages through logs by offset, or by keyset when page is set. Callers must hold s.mu.
+func (s *DuckDBStore) queryLogs(ctx context.Context, service, severity string, severities SeverityRange, traceID, search string, from, to time.Time, limit, offset int, page *PageCursor) (*api.LogsResponse, error) {
+	where, args := s.logsFilter(service, severity, severities, traceID, search, from, to)
+
+	// Get total count
 	var total int
-	if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM otel_logs WHERE "+where, args...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("counting logs: %w", err)
 	}
 
-	query += fmt.Sprintf(" ORDER BY Timestamp DESC LIMIT %d OFFSET %d", limit, offset)
+	query := `
+		SELECT
+			Timestamp, TraceId, SpanId, TraceFlags, SeverityText,
+			SeverityNumber, ServiceName, Body, ResourceSchemaUrl,
+			ResourceAttributes, ScopeSchemaUrl, ScopeName, ScopeVersion,
+			ScopeAttributes, LogAttributes, DroppedAttributesCount, ` + logRowKey + `
+		FROM otel_logs
+		WHERE ` + where
+
+	if page != nil {
+		if _, err := strconv.ParseUint(page.ID, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: log cursor id %q is not a row key", ErrInvalidCursor, page.ID)
+		}
+		cond, pageArgs, dir := page.keysetCondition("Timestamp", logRowKey, page.ID)
+		query += " AND " + cond + fmt.Sprintf(" ORDER BY Timestamp %[1]s, %[2]s %[1]s LIMIT %[3]d", dir, logRowKey, limit+1)
+		args = append(args, pageArgs...)
+	} else {
+		query += fmt.Sprintf(" ORDER BY Timestamp DESC, %s DESC LIMIT %d OFFSET %d", logRowKey, limit, offset)
+	}
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -136,45 +194,102 @@ func (s *DuckDBStore) QueryLogs(ctx context.Context, service, severity, traceID,
 	}
 	defer rows.Close()
 
-	var logs []api.LogRecord
-	for rows.Next() {
-		var log api.LogRecord
-		var traceIDNull, spanIDNull, severityText, body, resourceSchemaURL sql.NullString
-		var scopeSchemaURL, scopeName, scopeVersion sql.NullString
-		var resourceAttrs, scopeAttrs, logAttrs interface{}
+	logs, keys, err := scanLogRows(rows, true)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := rows.Scan(
-			&log.Timestamp, &traceIDNull, &spanIDNull, &log.TraceFlags, &severityText,
-			&log.SeverityNumber, &log.ServiceName, &body, &resourceSchemaURL,
-			&resourceAttrs, &scopeSchemaURL, &scopeName, &scopeVersion,
-			&scopeAttrs, &logAttrs,
-		); err != nil {
-			return nil, fmt.Errorf("scanning log: %w", err)
+	hasMore := offset+len(logs) < total
+	if page != nil {
+		hasMore = len(logs) > limit
+		if hasMore {
+			logs, keys = logs[:limit], keys[:limit]
+		}
+		if page.After {
+			// Rows after the cursor were fetched oldest first; return them newest first
+			slices.Reverse(logs)
+			slices.Reverse(keys)
 		}
+	}
 
-		log.TraceID = traceIDNull.String
-		log.SpanID = spanIDNull.String
-		log.SeverityText = severityText.String
-		log.Body = body.String
-		log.ResourceSchemaURL = resourceSchemaURL.String
-		log.ScopeSchemaURL = scopeSchemaURL.String
-		log.ScopeName = scopeName.String
-		log.ScopeVersion = scopeVersion.String
-		log.ResourceAttributes = scanJSONToMap(resourceAttrs)
-		log.ScopeAttributes = scanJSONToMap(scopeAttrs)
-		log.LogAttributes = scanJSONToMap(logAttrs)
+	resp := &api.LogsResponse{
+		Logs:    logs,
+		Total:   total,
+		HasMore: hasMore,
+	}
+	if hasMore && len(logs) > 0 {
+		next := len(logs) - 1
+		if page != nil && page.After {
+			next = 0
+		}
+		resp.NextCursor = Cursor{Timestamp: logs[next].Timestamp, ID: strconv.FormatUint(keys[next], 10)}.Encode()
+	}
+	return resp, nil
+}
+
+// scanLogs reads log records selected with the column list used by QueryLogs
+func scanLogs(rows *sql.Rows) ([]api.LogRecord, error) {
+	logs, _, err := scanLogRows(rows, false)
+	return logs, err
+}
 
+// scanLogRows reads log records like scanLogs; withKey expects a trailing logRowKey column and
+// returns the row keys alongside the records
+func scanLogRows(rows *sql.Rows, withKey bool) ([]api.LogRecord, []uint64, error) {
+	var logs []api.LogRecord
+	var keys []uint64
+	for rows.Next() {
+		log, key, err := scanLogRow(rows, withKey)
+		if err != nil {
+			return nil, nil, err
+		}
 		logs = append(logs, log)
+		keys = append(keys, key)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating logs: %w", err)
+		return nil, nil, fmt.Errorf("iterating logs: %w", err)
 	}
 
-	return &api.LogsResponse{
-		Logs:    logs,
-		Total:   total,
-		HasMore: offset+len(logs) < total,
-	}, nil
+	return logs, keys, nil
+}
+
+// scanLogRow reads the current row of a log query selecting the QueryLogs columns
+func scanLogRow(rows *sql.Rows, withKey bool) (api.LogRecord, uint64, error) {
+	var log api.LogRecord
+	var traceIDNull, spanIDNull, severityText, body, resourceSchemaURL sql.NullString
+	var scopeSchemaURL, scopeName, scopeVersion sql.NullString
+	var resourceAttrs, scopeAttrs, logAttrs interface{}
+	var droppedAttrs sql.NullInt64
+
+	var key uint64
+
+	dest := []interface{}{
+		&log.Timestamp, &traceIDNull, &spanIDNull, &log.TraceFlags, &severityText,
+		&log.SeverityNumber, &log.ServiceName, &body, &resourceSchemaURL,
+		&resourceAttrs, &scopeSchemaURL, &scopeName, &scopeVersion,
+		&scopeAttrs, &logAttrs, &droppedAttrs,
+	}
+	if withKey {
+		dest = append(dest, &key)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return api.LogRecord{}, 0, fmt.Errorf("scanning log: %w", err)
+	}
+
+	log.TraceID = traceIDNull.String
+	log.SpanID = spanIDNull.String
+	log.SeverityText = severityText.String
+	log.Body = body.String
+	log.ResourceSchemaURL = resourceSchemaURL.String
+	log.ScopeSchemaURL = scopeSchemaURL.String
+	log.ScopeName = scopeName.String
+	log.ScopeVersion = scopeVersion.String
+	log.ResourceAttributes = scanJSONToMap(resourceAttrs)
+	log.ScopeAttributes = scanJSONToMap(scopeAttrs)
+	log.LogAttributes = scanJSONToMap(logAttrs)
+	log.DroppedAttributesCount = uint32(droppedAttrs.Int64)
+
+	return log, key, nil
 }
 
 func (s *DuckDBStore) GetLogLevels(ctx context.Context) (map[string]int64, error) {
@@ -211,9 +326,48 @@ func (s *DuckDBStore) GetLogLevels(ctx context.Context) (map[string]int64, error
 	return levels, nil
 }
 
+// GetLogLevelMatrix returns log counts per service and severity within the time range
+func (s *DuckDBStore) GetLogLevelMatrix(ctx context.Context, from, to time.Time) (map[string]map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ServiceName, SeverityText, COUNT(*) as count
+		FROM otel_logs
+		WHERE SeverityText IS NOT NULL
+			AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		GROUP BY ServiceName, SeverityText
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, formatTimeForDB(from), formatTimeForDB(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying log level matrix: %w", err)
+	}
+	defer rows.Close()
+
+	matrix := make(map[string]map[string]int64)
+	for rows.Next() {
+		var service, level string
+		var count int64
+		if err := rows.Scan(&service, &level, &count); err != nil {
+			return nil, fmt.Errorf("scanning log level matrix: %w", err)
+		}
+		if matrix[service] == nil {
+			matrix[service] = make(map[string]int64)
+		}
+		matrix[service][level] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating log level matrix: %w", err)
+	}
+
+	return matrix, nil
+}
+
 // QuerySessions returns sessions with transcript messages from all services
 // Supports: Claude Code (transcript.message), Gemini CLI (session.id), Codex CLI (conversation.id)
-func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, to time.Time, limit, offset int) (*api.SessionsResponse, error) {
+// With preview set, each session carries the start of its first user prompt
+func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, to time.Time, limit, offset int, preview bool) (*api.SessionsResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -284,6 +438,29 @@ func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, t
 
 	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
 
+	// The preview is looked up for the sessions of this page only, from the session's
+	// earliest user prompt even if it precedes the time range
+	previewExpr := "NULL"
+	if preview {
+		previewExpr = `(
+			SELECT COALESCE(NULLIF(json_extract_string(p.LogAttributes, '$.prompt'), ''), p.Body)
+			FROM otel_logs p
+			WHERE p.ServiceName = s.ServiceName
+			  AND COALESCE(
+				json_extract_string(p.LogAttributes, '$."session.id"'),
+				json_extract_string(p.LogAttributes, '$."conversation.id"')
+			  ) = s.session_id
+			  AND (
+				json_extract_string(p.LogAttributes, '$."event.name"') IN ('user_prompt', 'codex.user_prompt', 'gemini_cli.user_prompt')
+				OR (json_extract_string(p.LogAttributes, '$."event.name"') = 'transcript.message'
+					AND json_extract_string(p.LogAttributes, '$."message.role"') = 'user')
+			  )
+			ORDER BY p.Timestamp
+			LIMIT 1
+		)`
+	}
+	query = "SELECT s.*, " + previewExpr + " as preview FROM (" + query + ") s ORDER BY s.last_time DESC"
+
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying sessions: %w", err)
@@ -293,7 +470,7 @@ func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, t
 	var sessions []api.Session
 	for rows.Next() {
 		var session api.Session
-		var sessionID, model sql.NullString
+		var sessionID, model, previewText sql.NullString
 
 		if err := rows.Scan(
 			&sessionID,
@@ -302,12 +479,14 @@ func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, t
 			&session.LastTime,
 			&session.MessageCount,
 			&model,
+			&previewText,
 		); err != nil {
 			return nil, fmt.Errorf("scanning session: %w", err)
 		}
 
 		session.SessionID = sessionID.String
 		session.Model = model.String
+		session.Preview = truncatePreview(previewText.String, sessionPreviewLength)
 
 		sessions = append(sessions, session)
 	}
@@ -322,12 +501,32 @@ func (s *DuckDBStore) QuerySessions(ctx context.Context, service string, from, t
 	}, nil
 }
 
+// sessionPreviewLength is the number of characters of a session's first prompt kept as its preview
+const sessionPreviewLength = 200
+
+// truncatePreview shortens text to at most n characters on one line, marking cut text with an ellipsis
+func truncatePreview(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// errSessionNotFound is returned for sessions without transcript messages
+var errSessionNotFound = errors.New("session not found")
+
 // GetSessionTranscript returns all logs for a session, mapping events to transcript roles
 // Supports: Claude Code, Gemini CLI, Codex CLI
 func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string) (*api.TranscriptResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.sessionTranscript(ctx, sessionID)
+}
 
+// sessionTranscript implements GetSessionTranscript; callers hold s.mu
+func (s *DuckDBStore) sessionTranscript(ctx context.Context, sessionID string) (*api.TranscriptResponse, error) {
 	// Query for logs matching either session.id or conversation.id
 	// Note: Keys contain dots, use JSONPath with escaped quotes: $."key.name"
 	query := `
@@ -355,6 +554,7 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 	var startTime, lastTime time.Time
 	isFirst := true
 	index := 0
+	calls := toolCallPairer{}
 
 	for rows.Next() {
 		var timestamp time.Time
@@ -395,7 +595,8 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 		}
 
 		// Extract actual content based on event type
-		content := extractMessageContent(eventName, attrs, body.String)
+		raw := api.LogRecord{Body: body.String, LogAttributes: attrs}
+		content := extractMessageContent(eventName, attrs, raw.NormalizedBody())
 
 		msg := api.TranscriptMessage{
 			Timestamp:    timestamp,
@@ -406,10 +607,10 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 			ToolName:     getToolName(attrs, eventName),
 			ToolInput:    getToolInput(attrs),
 			ToolOutput:   getToolOutput(attrs),
-			InputTokens:  parseIntAttr(attrs, "input_tokens", "inputTokens"),
-			OutputTokens: parseIntAttr(attrs, "output_tokens", "outputTokens"),
-			CacheRead:    parseIntAttr(attrs, "cache_read_input_tokens", "cacheRead"),
-			CacheWrite:   parseIntAttr(attrs, "cache_creation_input_tokens", "cacheWrite"),
+			InputTokens:  parseIntAttr(attrs, "input_tokens", "inputTokens", "input_token_count"),
+			OutputTokens: parseIntAttr(attrs, "output_tokens", "outputTokens", "output_token_count"),
+			CacheRead:    parseIntAttr(attrs, "cache_read_input_tokens", "cacheRead", "cache_read_tokens", "cached_content_token_count"),
+			CacheWrite:   parseIntAttr(attrs, "cache_creation_input_tokens", "cacheWrite", "cache_creation_tokens"),
 			CostUSD:      parseFloatAttr(attrs, "cost_usd", "costUsd"),
 			DurationMs:   parseIntAttr(attrs, "duration_ms", "durationMs"),
 			Success:      parseBoolAttr(attrs, "success", "tool_success"),
@@ -417,6 +618,7 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 		}
 
 		messages = append(messages, msg)
+		calls.pair(messages, attrs)
 		index++
 	}
 	if err := rows.Err(); err != nil {
@@ -424,7 +626,7 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 	}
 
 	if len(messages) == 0 {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
+		return nil, fmt.Errorf("%w: %s", errSessionNotFound, sessionID)
 	}
 
 	return &api.TranscriptResponse{
@@ -436,6 +638,53 @@ func (s *DuckDBStore) GetSessionTranscript(ctx context.Context, sessionID string
 	}, nil
 }
 
+// toolCallIDKeys are the attributes carrying the id that links a tool call to its result:
+// imported Claude Code (tool.use_id), imported Codex (tool.call_id) and Codex OTLP (call_id)
+var toolCallIDKeys = []string{"tool.use_id", "tool.call_id", "tool.id", "tool_use_id", "tool_call_id", "call_id"}
+
+// toolCallPairer links tool_use and tool_result transcript messages through their ToolCallID.
+// Messages with a tool call id attribute use it; the others are paired in order, each result
+// going to the oldest unanswered call of the same tool, with a generated id.
+type toolCallPairer struct {
+	pending []int // Indexes of tool_use messages without an id still waiting for a result
+	paired  int
+}
+
+// pair sets the ToolCallID of the last message, which was read from attrs
+func (p *toolCallPairer) pair(messages []api.TranscriptMessage, attrs map[string]string) {
+	msg := &messages[len(messages)-1]
+	if msg.Role != "tool_use" && msg.Role != "tool_result" {
+		return
+	}
+	for _, key := range toolCallIDKeys {
+		if id := attrs[key]; id != "" {
+			msg.ToolCallID = id
+			return
+		}
+	}
+
+	if msg.Role == "tool_use" {
+		// Rejected calls are never run, so no result follows them
+		switch attrs["decision"] {
+		case "reject", "denied", "abort":
+		default:
+			p.pending = append(p.pending, len(messages)-1)
+		}
+		return
+	}
+	for i, idx := range p.pending {
+		call := &messages[idx]
+		if call.ToolName != "" && msg.ToolName != "" && call.ToolName != msg.ToolName {
+			continue
+		}
+		p.paired++
+		call.ToolCallID = fmt.Sprintf("seq-%d", p.paired)
+		msg.ToolCallID = call.ToolCallID
+		p.pending = append(p.pending[:i], p.pending[i+1:]...)
+		return
+	}
+}
+
 // mapEventToRole converts event names to transcript roles
 func mapEventToRole(eventName, serviceName string) string {
 	switch eventName {
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetMetricDelta returns how much a metric changed between from and to, summed over its
+// series (service and attribute set). Cumulative sums use the latest value at or before each
+// time, with 0 before a series' first point; counter resets are not detected. Delta sums and
+// histograms add up the points in (from, to]. Gauges use the points nearest to each time.
+// An unknown metric has a delta of 0.
+func (s *DuckDBStore) GetMetricDelta(ctx context.Context, metricName, service string, from, to time.Time) (float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	typeQuery := `
+		SELECT MetricType, AggregationTemporality
+		FROM otel_metrics
+		WHERE MetricName = ?
+		LIMIT 1
+	`
+	var metricType string
+	var aggregationTemporality sql.NullInt32
+	if err := s.db.QueryRowContext(ctx, typeQuery, metricName).Scan(&metricType, &aggregationTemporality); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("getting metric type: %w", err)
+	}
+
+	serviceFilter := ""
+	filterArgs := []interface{}{metricName}
+	if service != "" {
+		serviceFilter = " AND ServiceName = ?"
+		filterArgs = append(filterArgs, service)
+	}
+
+	var query string
+	var args []interface{}
+	switch {
+	case metricType == "gauge":
+		query = `
+			SELECT COALESCE(SUM(to_value - from_value), 0) FROM (
+				SELECT
+					arg_min(COALESCE(Value, Sum), abs(epoch_ms(Timestamp) - epoch_ms(?::TIMESTAMP))) as from_value,
+					arg_min(COALESCE(Value, Sum), abs(epoch_ms(Timestamp) - epoch_ms(?::TIMESTAMP))) as to_value
+				FROM otel_metrics
+				WHERE MetricName = ?` + serviceFilter + `
+					AND (Value IS NOT NULL OR Sum IS NOT NULL)
+				GROUP BY ServiceName, CAST(Attributes AS VARCHAR)
+			)
+		`
+		args = append([]interface{}{fromStr, toStr}, filterArgs...)
+	case metricType == "sum" && aggregationTemporality.Valid && aggregationTemporality.Int32 == 2:
+		query = `
+			SELECT COALESCE(SUM(COALESCE(to_value, 0) - COALESCE(from_value, 0)), 0) FROM (
+				SELECT
+					arg_max(COALESCE(Value, Sum), Timestamp) FILTER (WHERE Timestamp <= ?::TIMESTAMP) as from_value,
+					arg_max(COALESCE(Value, Sum), Timestamp) as to_value
+				FROM otel_metrics
+				WHERE MetricName = ?` + serviceFilter + `
+					AND Timestamp <= ?::TIMESTAMP
+					AND (Value IS NOT NULL OR Sum IS NOT NULL)
+				GROUP BY ServiceName, CAST(Attributes AS VARCHAR)
+			)
+		`
+		args = append(append([]interface{}{fromStr}, filterArgs...), toStr)
+	default:
+		query = `
+			SELECT COALESCE(SUM(COALESCE(Value, Sum)), 0)
+			FROM otel_metrics
+			WHERE MetricName = ?` + serviceFilter + `
+				AND Timestamp > ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		`
+		args = append(filterArgs, fromStr, toStr)
+	}
+
+	var delta float64
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&delta); err != nil {
+		return 0, fmt.Errorf("querying metric delta: %w", err)
+	}
+	return delta, nil
+}
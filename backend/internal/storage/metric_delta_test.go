@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetMetricDelta(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+	delta := int32(1)
+	point := func(ts time.Time, service, name, metricType string, temporality *int32, attrs map[string]string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: name, MetricType: metricType,
+			AggregationTemporality: temporality, Attributes: attrs, Value: &v,
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		// Cumulative cost counter with two series; the "haiku" series starts inside the range
+		point(now.Add(-2*time.Hour), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "sonnet"}, 1.0),
+		point(now.Add(-70*time.Minute), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "sonnet"}, 2.5),
+		point(now.Add(-30*time.Minute), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "sonnet"}, 4.0),
+		point(now.Add(-5*time.Minute), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "sonnet"}, 6.0),
+		point(now.Add(-20*time.Minute), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "haiku"}, 0.5),
+		// Points after "to" and from other services are ignored
+		point(now.Add(time.Hour), "claude-code", "cost", "sum", &cumulative, map[string]string{"model": "sonnet"}, 100),
+		point(now.Add(-5*time.Minute), "codex", "cost", "sum", &cumulative, nil, 50),
+
+		// Gauge: nearest point to "from" is 10, nearest to "to" is 16
+		point(now.Add(-3*time.Hour), "claude-code", "active_sessions", "gauge", nil, nil, 3),
+		point(now.Add(-62*time.Minute), "claude-code", "active_sessions", "gauge", nil, nil, 10),
+		point(now.Add(-30*time.Minute), "claude-code", "active_sessions", "gauge", nil, nil, 12),
+		point(now.Add(-2*time.Minute), "claude-code", "active_sessions", "gauge", nil, nil, 16),
+
+		// Delta counter: points in (from, to] are summed
+		point(now.Add(-90*time.Minute), "claude-code", "tokens", "sum", &delta, nil, 1000),
+		point(now.Add(-40*time.Minute), "claude-code", "tokens", "sum", &delta, nil, 200),
+		point(now.Add(-10*time.Minute), "claude-code", "tokens", "sum", &delta, nil, 300),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now
+	tests := []struct {
+		name    string
+		metric  string
+		service string
+		want    float64
+	}{
+		{"cumulative counter", "cost", "claude-code", (6.0 - 2.5) + 0.5},
+		{"cumulative counter across services", "cost", "", (6.0 - 2.5) + 0.5 + 50},
+		{"gauge", "active_sessions", "claude-code", 16 - 10},
+		{"delta counter", "tokens", "", 200 + 300},
+		{"unknown metric", "missing", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetMetricDelta(ctx, tt.metric, tt.service, from, to)
+			if err != nil {
+				t.Fatalf("GetMetricDelta failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("delta = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// GroupByService is the groupBy key that splits metric series by service rather than by an
+// attribute
+const GroupByService = "service"
+
+// QueryMetricSeriesGroupBy is QueryMetricSeries with series split by every groupBy key at
+// once: "service" for the service and any other key for that data point attribute, e.g.
+// service × model. Each series is labeled with its value per key, omitting empty values.
+// Only the maxSeries series with the most data points are returned; Truncated reports that
+// more exist. Cumulative sums are turned into deltas per series (service and attribute set)
+// before they are grouped, so counters sharing a group add up and empty buckets are zero.
+func (s *DuckDBStore) QueryMetricSeriesGroupBy(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate bool, groupBy []string, maxSeries int) (*api.TimeSeriesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	typeQuery := `
+		SELECT MetricType, AggregationTemporality
+		FROM otel_metrics
+		WHERE MetricName = ?
+		LIMIT 1
+	`
+	var metricType string
+	var aggregationTemporality sql.NullInt32
+	if err := s.db.QueryRowContext(ctx, typeQuery, metricName).Scan(&metricType, &aggregationTemporality); err != nil {
+		if err == sql.ErrNoRows {
+			return &api.TimeSeriesResponse{Series: []api.TimeSeries{}}, nil
+		}
+		return nil, fmt.Errorf("getting metric type: %w", err)
+	}
+
+	// OTLP AggregationTemporality: 0=UNSPECIFIED, 1=DELTA, 2=CUMULATIVE
+	isCumulative := aggregationTemporality.Valid && aggregationTemporality.Int32 == 2
+
+	// One column g0..gN per key; dotted attribute keys are quoted in the JSON path
+	var args []interface{}
+	dims := make([]string, len(groupBy))
+	dimExprs := make([]string, len(groupBy))
+	joins := make([]string, len(groupBy))
+	for i, key := range groupBy {
+		dims[i] = fmt.Sprintf("g%d", i)
+		if key == GroupByService {
+			dimExprs[i] = "ServiceName AS " + dims[i]
+		} else {
+			dimExprs[i] = fmt.Sprintf("COALESCE(json_extract_string(Attributes, ?), '') AS %s", dims[i])
+			args = append(args, `$."`+key+`"`)
+		}
+		joins[i] = fmt.Sprintf("s.%[1]s = d.%[1]s", dims[i])
+	}
+	dimList := strings.Join(dims, ", ")
+
+	serviceFilter := ""
+	args = append(args, formatTimeForDB(from), formatTimeForDB(to), metricName)
+	if service != "" {
+		serviceFilter = " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	args = append(args, maxSeries)
+
+	// A cumulative point becomes the increase since the previous point of its series, in both
+	// Value and Sum, so the points aggregate like delta data whatever the metric type
+	pointValues, window := "Value, Sum", ""
+	if isCumulative {
+		delta := "COALESCE(COALESCE(Value, Sum) - LAG(COALESCE(Value, Sum)) OVER series, 0)"
+		pointValues = delta + " AS Value, " + delta + " AS Sum"
+		window = "WINDOW series AS (PARTITION BY ServiceName, CAST(Attributes AS VARCHAR) ORDER BY Timestamp)"
+	}
+	aggFunction := metricAggFunction(metricType, false, aggregate)
+
+	// Rank the series by their number of points to keep the busiest ones
+	query := fmt.Sprintf(`
+		WITH points AS (
+			SELECT Timestamp, %[4]s, %[1]s
+			FROM otel_metrics
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND MetricName = ?
+				AND (Value IS NOT NULL OR Sum IS NOT NULL)
+				%[2]s
+			%[5]s
+		),
+		ranked AS (
+			SELECT %[3]s, COUNT(*) AS n
+			FROM points
+			GROUP BY %[3]s
+		),
+		series_labels AS (
+			SELECT %[3]s
+			FROM ranked
+			ORDER BY n DESC, %[3]s
+			LIMIT ?
+		)
+	`, strings.Join(dimExprs, ", "), serviceFilter, dimList, pointValues, window)
+
+	sPrefixed := "s." + strings.Join(dims, ", s.")
+	if aggregate {
+		query += fmt.Sprintf(`,
+			data AS (
+				SELECT %[1]s, %[2]s AS agg_value
+				FROM points
+				GROUP BY %[1]s
+			)
+			SELECT 0::BIGINT AS bucket_ms, %[3]s, d.agg_value, (SELECT COUNT(*) FROM ranked) AS total_series
+			FROM series_labels s
+			JOIN data d ON %[4]s
+			ORDER BY %[3]s
+		`, dimList, aggFunction, sPrefixed, strings.Join(joins, " AND "))
+	} else {
+		// Every series gets every bucket, with zeros where it has no data
+		query += fmt.Sprintf(`,
+			buckets AS (
+				SELECT UNNEST(generate_series(
+					time_bucket(INTERVAL '%[1]d seconds', ?::TIMESTAMP),
+					time_bucket(INTERVAL '%[1]d seconds', ?::TIMESTAMP),
+					INTERVAL '%[1]d seconds'
+				)) AS bucket
+			),
+			data AS (
+				SELECT time_bucket(INTERVAL '%[1]d seconds', Timestamp) AS bucket, %[2]s, %[3]s AS agg_value
+				FROM points
+				GROUP BY bucket, %[2]s
+			)
+			SELECT epoch_ms(b.bucket) AS bucket_ms, %[4]s, COALESCE(d.agg_value, 0) AS agg_value, (SELECT COUNT(*) FROM ranked) AS total_series
+			FROM buckets b
+			CROSS JOIN series_labels s
+			LEFT JOIN data d ON b.bucket = d.bucket AND %[5]s
+			ORDER BY b.bucket, %[4]s
+		`, intervalSeconds, dimList, aggFunction, sPrefixed, strings.Join(joins, " AND "))
+		args = append(args, formatTimeForDB(from), formatTimeForDB(to))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying grouped metric series: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &api.TimeSeriesResponse{Series: []api.TimeSeries{}}
+	index := make(map[string]int)
+	values := make([]string, len(groupBy))
+	for rows.Next() {
+		var bucketMs int64
+		var value sql.NullFloat64
+		var total int
+		dest := []interface{}{&bucketMs}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &value, &total)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scanning grouped metric series: %w", err)
+		}
+		resp.Truncated = total > maxSeries
+
+		key := strings.Join(values, "\x00")
+		i, ok := index[key]
+		if !ok {
+			labels := make(map[string]string, len(groupBy))
+			for j, k := range groupBy {
+				if values[j] != "" {
+					labels[k] = values[j]
+				}
+			}
+			i = len(resp.Series)
+			index[key] = i
+			resp.Series = append(resp.Series, api.TimeSeries{Name: metricName, Labels: labels, DataPoints: [][2]float64{}})
+		}
+		resp.Series[i].DataPoints = append(resp.Series[i].DataPoints, [2]float64{float64(bucketMs), value.Float64})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating grouped metric series: %w", err)
+	}
+
+	return resp, nil
+}
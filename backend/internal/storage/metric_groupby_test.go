@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestQueryMetricSeriesGroupBy(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	point := func(ts time.Time, service, model string, value float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: "tokens", MetricType: "sum", Value: ptrFloat64(value),
+			Attributes: map[string]string{"model": model, "gen_ai.request.model": model, "type": "input"},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		point(now, "svc-a", "sonnet", 10),
+		point(now.Add(time.Minute), "svc-a", "sonnet", 20),
+		point(now.Add(2*time.Minute), "svc-a", "sonnet", 5),
+		point(now, "svc-a", "haiku", 3),
+		point(now.Add(time.Minute), "svc-a", "haiku", 4),
+		point(now, "svc-b", "sonnet", 7),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+	from, to := now.Add(-time.Minute), now.Add(5*time.Minute)
+
+	totals := func(resp *api.TimeSeriesResponse, modelKey string) map[string]float64 {
+		t.Helper()
+		got := make(map[string]float64)
+		for _, s := range resp.Series {
+			sum := 0.0
+			for _, dp := range s.DataPoints {
+				sum += dp[1]
+			}
+			got[s.Labels["service"]+"/"+s.Labels[modelKey]] = sum
+		}
+		return got
+	}
+	want := map[string]float64{"svc-a/sonnet": 35, "svc-a/haiku": 7, "svc-b/sonnet": 7}
+
+	// One series per (service, model), including dotted attribute keys
+	for _, modelKey := range []string{"model", "gen_ai.request.model"} {
+		resp, err := store.QueryMetricSeriesGroupBy(ctx, "tokens", "", from, to, 60, true, []string{GroupByService, modelKey}, 10)
+		if err != nil {
+			t.Fatalf("QueryMetricSeriesGroupBy(%s) failed: %v", modelKey, err)
+		}
+		if len(resp.Series) != 3 || resp.Truncated {
+			t.Fatalf("%s: expected 3 untruncated series, got %d (truncated=%v)", modelKey, len(resp.Series), resp.Truncated)
+		}
+		got := totals(resp, modelKey)
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: series %s = %v, want %v (all: %v)", modelKey, k, got[k], v, got)
+			}
+		}
+	}
+
+	// Time-bucketed series share all buckets and keep the per-bucket values
+	resp, err := store.QueryMetricSeriesGroupBy(ctx, "tokens", "", from, to, 60, false, []string{GroupByService, "model"}, 10)
+	if err != nil {
+		t.Fatalf("QueryMetricSeriesGroupBy failed: %v", err)
+	}
+	if len(resp.Series) != 3 {
+		t.Fatalf("expected 3 bucketed series, got %d", len(resp.Series))
+	}
+	for _, s := range resp.Series {
+		if len(s.DataPoints) != 7 {
+			t.Errorf("series %v: expected 7 buckets, got %d", s.Labels, len(s.DataPoints))
+		}
+		if s.Labels["type"] != "" {
+			t.Errorf("series %v: expected only the requested labels", s.Labels)
+		}
+	}
+	got := totals(resp, "model")
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("bucketed series %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	// The series cap keeps the series with the most points
+	resp, err = store.QueryMetricSeriesGroupBy(ctx, "tokens", "svc-a", from, to, 60, true, []string{GroupByService, "model"}, 1)
+	if err != nil {
+		t.Fatalf("QueryMetricSeriesGroupBy failed: %v", err)
+	}
+	if len(resp.Series) != 1 || !resp.Truncated || resp.Series[0].Labels["model"] != "sonnet" {
+		t.Errorf("expected only the svc-a/sonnet series, truncated; got %+v (truncated=%v)", resp.Series, resp.Truncated)
+	}
+
+	// Unknown metrics return no series
+	resp, err = store.QueryMetricSeriesGroupBy(ctx, "missing", "", from, to, 60, true, []string{"model"}, 10)
+	if err != nil || len(resp.Series) != 0 {
+		t.Errorf("expected no series for an unknown metric, got %+v, %v", resp, err)
+	}
+}
+
+func TestQueryMetricSeriesGroupBy_Cumulative(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+	point := func(ts time.Time, session string, value float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: "svc", MetricName: "cost", MetricType: "sum", Value: ptrFloat64(value),
+			AggregationTemporality: &cumulative,
+			Attributes:             map[string]string{"model": "sonnet", "session.id": session},
+		}
+	}
+	// Two sessions count their cost separately; both grow by 3 over the range
+	metrics := []api.MetricDataPoint{
+		point(now, "s1", 1),
+		point(now.Add(time.Minute), "s1", 2),
+		point(now.Add(3*time.Minute), "s1", 4),
+		point(now, "s2", 10),
+		point(now.Add(time.Minute), "s2", 13),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+	from, to := now, now.Add(4*time.Minute)
+
+	resp, err := store.QueryMetricSeriesGroupBy(ctx, "cost", "", from, to, 60, true, []string{"model"}, 10)
+	if err != nil {
+		t.Fatalf("QueryMetricSeriesGroupBy failed: %v", err)
+	}
+	if len(resp.Series) != 1 || resp.Series[0].DataPoints[0][1] != 6 {
+		t.Fatalf("expected one series totalling 6, got %+v", resp.Series)
+	}
+
+	// Each bucket holds the increase of both sessions, zero where neither grew
+	resp, err = store.QueryMetricSeriesGroupBy(ctx, "cost", "", from, to, 60, false, []string{"model"}, 10)
+	if err != nil {
+		t.Fatalf("QueryMetricSeriesGroupBy failed: %v", err)
+	}
+	if len(resp.Series) != 1 {
+		t.Fatalf("expected one bucketed series, got %d", len(resp.Series))
+	}
+	var got []float64
+	for _, dp := range resp.Series[0].DataPoints {
+		got = append(got, dp[1])
+	}
+	if want := []float64{0, 4, 0, 2, 0}; !slices.Equal(got, want) {
+		t.Errorf("buckets = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// histogramPoint is a stored explicit-bucket histogram data point
+type histogramPoint struct {
+	bounds []float64
+	counts []uint64
+}
+
+// QueryMetricHistogram returns the bucket distribution of an explicit-bucket histogram metric
+// over the range, summed across its series. DELTA points are added up; CUMULATIVE series use
+// their latest point minus their earliest one, or the latest point alone. Points whose bounds
+// differ from the most common bounds are re-bucketed onto them. An unknown metric has no buckets.
+func (s *DuckDBStore) QueryMetricHistogram(ctx context.Context, metricName, service string, from, to time.Time) ([]api.HistogramBucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT
+			ServiceName,
+			COALESCE(CAST(Attributes AS VARCHAR), ''),
+			COALESCE(AggregationTemporality, 0),
+			COALESCE(CAST(BucketCounts AS VARCHAR), '[]'),
+			COALESCE(CAST(ExplicitBounds AS VARCHAR), '[]')
+		FROM otel_metrics
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+			AND MetricName = ?
+			AND MetricType = 'histogram'
+	`
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to), metricName}
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	query += " ORDER BY Timestamp"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying histogram: %w", err)
+	}
+	defer rows.Close()
+
+	// Points per series in time order, and how often each set of bounds occurs
+	series := make(map[string][]histogramPoint)
+	var order []string
+	cumulative := make(map[string]bool)
+	boundsCount := make(map[string]int)
+	boundsByKey := make(map[string][]float64)
+	for rows.Next() {
+		var serviceName, attrs, countsStr, boundsStr string
+		var temporality int32
+		if err := rows.Scan(&serviceName, &attrs, &temporality, &countsStr, &boundsStr); err != nil {
+			return nil, fmt.Errorf("scanning histogram: %w", err)
+		}
+		var p histogramPoint
+		if err := json.Unmarshal([]byte(countsStr), &p.counts); err != nil {
+			return nil, fmt.Errorf("parsing bucket counts: %w", err)
+		}
+		if err := json.Unmarshal([]byte(boundsStr), &p.bounds); err != nil {
+			return nil, fmt.Errorf("parsing explicit bounds: %w", err)
+		}
+		if len(p.counts) == 0 {
+			continue
+		}
+
+		key := serviceName + "\x00" + attrs
+		if _, ok := series[key]; !ok {
+			order = append(order, key)
+		}
+		series[key] = append(series[key], p)
+		// OTLP AggregationTemporality: 0=UNSPECIFIED, 1=DELTA, 2=CUMULATIVE
+		cumulative[key] = temporality == 2
+
+		boundsCount[boundsStr]++
+		boundsByKey[boundsStr] = p.bounds
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating histograms: %w", err)
+	}
+	if len(series) == 0 {
+		return []api.HistogramBucket{}, nil
+	}
+
+	// The most common bounds win; ties go to the finer bucketing, then the lower bounds
+	var target []float64
+	bestKey, bestCount := "", 0
+	for key, count := range boundsCount {
+		b := boundsByKey[key]
+		switch {
+		case count > bestCount,
+			count == bestCount && len(b) > len(target),
+			count == bestCount && len(b) == len(target) && key < bestKey:
+			target, bestKey, bestCount = b, key, count
+		}
+	}
+
+	totals := make([]float64, len(target)+1)
+	for _, key := range order {
+		points := series[key]
+		if !cumulative[key] {
+			for _, p := range points {
+				addRebucketed(totals, target, p, 1)
+			}
+			continue
+		}
+		addRebucketed(totals, target, points[len(points)-1], 1)
+		if len(points) > 1 {
+			addRebucketed(totals, target, points[0], -1)
+		}
+	}
+
+	buckets := make([]api.HistogramBucket, len(totals))
+	for i, total := range totals {
+		if i < len(target) {
+			bound := target[i]
+			buckets[i].UpperBound = &bound
+		}
+		// Counter resets can make a cumulative increase negative
+		buckets[i].Count = uint64(math.Max(total, 0))
+	}
+	return buckets, nil
+}
+
+// addRebucketed adds sign times a point's counts to totals, laid out by the target bounds.
+// Each source bucket's count lands in the target bucket holding the source's upper bound,
+// since how values are spread within a bucket is unknown.
+func addRebucketed(totals []float64, target []float64, p histogramPoint, sign float64) {
+	for i, count := range p.counts {
+		j := len(target) // Overflow bucket, for the source's own overflow bucket
+		if i < len(p.bounds) {
+			j = 0
+			for j < len(target) && p.bounds[i] > target[j] {
+				j++
+			}
+		}
+		totals[j] += sign * float64(count)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// histogramMetric builds a stored explicit-bucket histogram data point
+func histogramMetric(ts time.Time, service string, temporality int32, bounds []float64, counts []uint64) api.MetricDataPoint {
+	return api.MetricDataPoint{
+		Timestamp:              ts,
+		ServiceName:            service,
+		MetricName:             "http.duration",
+		MetricType:             "histogram",
+		AggregationTemporality: &temporality,
+		ExplicitBounds:         bounds,
+		BucketCounts:           counts,
+	}
+}
+
+// bucketCounts returns the counts of buckets and checks their upper bounds, with -1 standing
+// for the overflow bucket
+func bucketCounts(t *testing.T, buckets []api.HistogramBucket, bounds []float64) []uint64 {
+	t.Helper()
+	if len(buckets) != len(bounds) {
+		t.Fatalf("expected %d buckets, got %+v", len(bounds), buckets)
+	}
+	counts := make([]uint64, len(buckets))
+	for i, b := range buckets {
+		if bounds[i] == -1 {
+			if b.UpperBound != nil {
+				t.Errorf("bucket %d: expected the overflow bucket, got bound %v", i, *b.UpperBound)
+			}
+		} else if b.UpperBound == nil || *b.UpperBound != bounds[i] {
+			t.Errorf("bucket %d: expected bound %v, got %v", i, bounds[i], b.UpperBound)
+		}
+		counts[i] = b.Count
+	}
+	return counts
+}
+
+func TestQueryMetricHistogram(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	bounds := []float64{10, 100}
+	metrics := []api.MetricDataPoint{
+		// Delta points are summed across services
+		histogramMetric(now, "api", 1, bounds, []uint64{1, 2, 3}),
+		histogramMetric(now.Add(time.Minute), "api", 1, bounds, []uint64{4, 5, 6}),
+		histogramMetric(now, "worker", 1, bounds, []uint64{1, 0, 0}),
+		// Finer bounds are re-bucketed: (5, 10] into 10, (10, 50] into 100, (50, +Inf) into the overflow
+		histogramMetric(now, "worker", 1, []float64{5, 10, 50}, []uint64{1, 1, 1, 1}),
+		histogramMetric(now.Add(-48*time.Hour), "api", 1, bounds, []uint64{100, 100, 100}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	buckets, err := store.QueryMetricHistogram(ctx, "http.duration", "", from, to)
+	if err != nil {
+		t.Fatalf("QueryMetricHistogram failed: %v", err)
+	}
+	got := bucketCounts(t, buckets, []float64{10, 100, -1})
+	if want := []uint64{8, 8, 10}; got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("counts = %v, want %v", got, want)
+	}
+
+	buckets, err = store.QueryMetricHistogram(ctx, "http.duration", "api", from, to)
+	if err != nil {
+		t.Fatalf("QueryMetricHistogram failed: %v", err)
+	}
+	got = bucketCounts(t, buckets, []float64{10, 100, -1})
+	if want := []uint64{5, 7, 9}; got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("api counts = %v, want %v", got, want)
+	}
+
+	buckets, err = store.QueryMetricHistogram(ctx, "unknown", "", from, to)
+	if err != nil {
+		t.Fatalf("QueryMetricHistogram failed: %v", err)
+	}
+	if len(buckets) != 0 {
+		t.Errorf("expected no buckets for an unknown metric, got %+v", buckets)
+	}
+}
+
+func TestQueryMetricHistogram_Cumulative(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	bounds := []float64{1}
+	metrics := []api.MetricDataPoint{
+		histogramMetric(now, "api", 2, bounds, []uint64{10, 5}),
+		histogramMetric(now.Add(time.Minute), "api", 2, bounds, []uint64{12, 9}),
+		histogramMetric(now.Add(2*time.Minute), "api", 2, bounds, []uint64{15, 10}),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	buckets, err := store.QueryMetricHistogram(ctx, "http.duration", "", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("QueryMetricHistogram failed: %v", err)
+	}
+	got := bucketCounts(t, buckets, []float64{1, -1})
+	if got[0] != 5 || got[1] != 5 {
+		t.Errorf("expected the increase [5 5], got %v", got)
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MetricSnapshot is the current value of one gauge or sum series, identified by metric name,
+// service and attribute set
+type MetricSnapshot struct {
+	MetricName  string
+	Description string
+	Unit        string
+	MetricType  string
+	Monotonic   bool
+	ServiceName string
+	Attributes  map[string]string
+	Value       float64
+}
+
+// GetMetricSnapshots returns the current value of every gauge and sum series, ordered by
+// metric name, service and attributes. Like GetLatestMetricValue, gauges and cumulative sums
+// report the value of their latest point; delta sums report the sum of all their points, so
+// every sum reads as a running total. Histogram and summary metrics are not included.
+func (s *DuckDBStore) GetMetricSnapshots(ctx context.Context, service string) ([]MetricSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT
+			MetricName,
+			COALESCE(MetricDescription, ''),
+			COALESCE(MetricUnit, ''),
+			MetricType,
+			COALESCE(IsMonotonic, FALSE),
+			ServiceName,
+			Attributes,
+			CASE WHEN MetricType = 'sum' AND AggregationTemporality = 1
+				THEN SUM(Value) OVER (PARTITION BY ServiceName, MetricName, CAST(Attributes AS VARCHAR))
+				ELSE Value
+			END
+		FROM otel_metrics
+		WHERE MetricType IN ('gauge', 'sum') AND Value IS NOT NULL
+	`
+	var args []interface{}
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	query += `
+		QUALIFY ROW_NUMBER() OVER (
+			PARTITION BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)
+			ORDER BY Timestamp DESC
+		) = 1
+		ORDER BY MetricName, ServiceName, CAST(Attributes AS VARCHAR)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var result []MetricSnapshot
+	for rows.Next() {
+		var m MetricSnapshot
+		var attrs interface{}
+		var value sql.NullFloat64
+		if err := rows.Scan(&m.MetricName, &m.Description, &m.Unit, &m.MetricType, &m.Monotonic, &m.ServiceName, &attrs, &value); err != nil {
+			return nil, fmt.Errorf("scanning metric snapshot: %w", err)
+		}
+		m.Attributes = scanJSONToMap(attrs)
+		m.Value = value.Float64
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating metric snapshots: %w", err)
+	}
+	return result, nil
+}
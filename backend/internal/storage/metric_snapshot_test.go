@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetMetricSnapshots(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+	delta := int32(1)
+	monotonic := true
+	point := func(ts time.Time, service, name, metricType string, temporality *int32, attrs map[string]string, v float64) api.MetricDataPoint {
+		m := api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: name, MetricType: metricType,
+			AggregationTemporality: temporality, Attributes: attrs, Value: &v,
+			MetricDescription: "desc of " + name, MetricUnit: "1",
+		}
+		if metricType == "sum" {
+			m.IsMonotonic = &monotonic
+		}
+		return m
+	}
+
+	sum := 7.0
+	metrics := []api.MetricDataPoint{
+		// Cumulative counter: latest point per attribute set
+		point(now.Add(-time.Hour), "claude-code", "tokens", "sum", &cumulative, map[string]string{"type": "input"}, 100),
+		point(now.Add(-time.Minute), "claude-code", "tokens", "sum", &cumulative, map[string]string{"type": "input"}, 250),
+		point(now.Add(-time.Minute), "claude-code", "tokens", "sum", &cumulative, map[string]string{"type": "output"}, 40),
+		// Delta counter: all points summed
+		point(now.Add(-time.Hour), "codex", "cost", "sum", &delta, nil, 1.5),
+		point(now.Add(-time.Minute), "codex", "cost", "sum", &delta, nil, 2.0),
+		// Gauge: latest point
+		point(now.Add(-time.Hour), "claude-code", "sessions", "gauge", nil, nil, 3),
+		point(now.Add(-time.Minute), "claude-code", "sessions", "gauge", nil, nil, 1),
+		// Histograms are skipped
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "latency", MetricType: "histogram", Sum: &sum},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	snapshots, err := store.GetMetricSnapshots(ctx, "")
+	if err != nil {
+		t.Fatalf("GetMetricSnapshots failed: %v", err)
+	}
+
+	type key struct{ name, service, attrType string }
+	want := map[key]float64{
+		{"cost", "codex", ""}:               3.5,
+		{"sessions", "claude-code", ""}:     1,
+		{"tokens", "claude-code", "input"}:  250,
+		{"tokens", "claude-code", "output"}: 40,
+	}
+	if len(snapshots) != len(want) {
+		t.Fatalf("expected %d series, got %d: %+v", len(want), len(snapshots), snapshots)
+	}
+	for i, s := range snapshots {
+		k := key{s.MetricName, s.ServiceName, s.Attributes["type"]}
+		if v, ok := want[k]; !ok || v != s.Value {
+			t.Errorf("series %+v = %v, want %v (found %v)", k, s.Value, v, ok)
+		}
+		if s.Description != "desc of "+s.MetricName || s.Unit != "1" {
+			t.Errorf("series %+v: description %q, unit %q", k, s.Description, s.Unit)
+		}
+		if s.Monotonic != (s.MetricType == "sum") {
+			t.Errorf("series %+v: monotonic = %v", k, s.Monotonic)
+		}
+		if i > 0 && snapshots[i-1].MetricName > s.MetricName {
+			t.Errorf("snapshots not ordered by metric name: %s before %s", snapshots[i-1].MetricName, s.MetricName)
+		}
+	}
+
+	codex, err := store.GetMetricSnapshots(ctx, "codex")
+	if err != nil {
+		t.Fatalf("GetMetricSnapshots failed: %v", err)
+	}
+	if len(codex) != 1 || codex[0].MetricName != "cost" {
+		t.Errorf("expected only the codex series, got %+v", codex)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// ValidateMetricQuery reports whether the widget's metric exists, its type and unit, and how
+// many points match the widget's service and breakdown filters between from and to
+func (s *DuckDBStore) ValidateMetricQuery(ctx context.Context, cfg api.WidgetConfig, from, to time.Time) (*api.ValidateMetricResponse, error) {
+	types := s.batchGetMetricTypes(ctx, []api.MetricQuery{{Name: cfg.MetricName, Service: cfg.Service}})
+	info, ok := types[cfg.MetricName]
+	if !ok {
+		return &api.ValidateMetricResponse{}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// The unit describes the metric as a whole, so it ignores the widget's filters
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE((SELECT arg_max(MetricUnit, Timestamp) FROM otel_metrics WHERE MetricName = ?), '')
+		FROM otel_metrics
+		WHERE MetricName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+	`
+	args := []interface{}{cfg.MetricName, cfg.MetricName, formatTimeForDB(from), formatTimeForDB(to)}
+
+	if cfg.Service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, cfg.Service)
+	}
+	if cfg.BreakdownAttribute != "" && cfg.BreakdownValue != "" {
+		// Quote the key so dotted attribute names (gen_ai.request.model) are not treated as nested paths
+		query += " AND json_extract_string(Attributes, ?) = ?"
+		args = append(args, `$."`+cfg.BreakdownAttribute+`"`, cfg.BreakdownValue)
+	}
+
+	resp := &api.ValidateMetricResponse{Exists: true, MetricType: info.metricType}
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&resp.SampleCount, &resp.Unit); err != nil {
+		return nil, fmt.Errorf("probing metric: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestValidateMetricQuery(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	point := func(ts time.Time, service, model string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: "claude_code.cost.usage", MetricUnit: "USD",
+			MetricType: "sum", Attributes: map[string]string{"model": model}, Value: &v,
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		point(now.Add(-2*time.Hour), "claude-code", "sonnet", 1),
+		point(now.Add(-30*time.Minute), "claude-code", "sonnet", 2),
+		point(now.Add(-20*time.Minute), "claude-code", "haiku", 3),
+		point(now.Add(-10*time.Minute), "other", "sonnet", 4),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now
+	tests := []struct {
+		name      string
+		cfg       api.WidgetConfig
+		wantExist bool
+		wantCount int
+	}{
+		{"metric in range", api.WidgetConfig{MetricName: "claude_code.cost.usage"}, true, 3},
+		{"service filter", api.WidgetConfig{MetricName: "claude_code.cost.usage", Service: "claude-code"}, true, 2},
+		{"breakdown filter", api.WidgetConfig{MetricName: "claude_code.cost.usage", BreakdownAttribute: "model", BreakdownValue: "sonnet"}, true, 2},
+		{"service without data", api.WidgetConfig{MetricName: "claude_code.cost.usage", Service: "missing"}, true, 0},
+		{"unknown metric", api.WidgetConfig{MetricName: "missing.metric"}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := store.ValidateMetricQuery(ctx, tt.cfg, from, to)
+			if err != nil {
+				t.Fatalf("ValidateMetricQuery failed: %v", err)
+			}
+			if resp.Exists != tt.wantExist || resp.SampleCount != tt.wantCount {
+				t.Errorf("got exists=%v count=%d, want exists=%v count=%d", resp.Exists, resp.SampleCount, tt.wantExist, tt.wantCount)
+			}
+			if tt.wantExist && (resp.MetricType != "sum" || resp.Unit != "USD") {
+				t.Errorf("got type=%q unit=%q, want sum/USD", resp.MetricType, resp.Unit)
+			}
+		})
+	}
+}
@@ -27,6 +27,72 @@ func (s *DuckDBStore) InsertMetrics(ctx context.Context, metrics []api.MetricDat
 	}
 	defer tx.Rollback()
 
+	if err := insertMetricsTx(ctx, tx, metrics); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.markModified()
+	return nil
+}
+
+// replaceMetricsChunk is how many points ReplaceMetrics matches per DELETE statement
+const replaceMetricsChunk = 500
+
+// ReplaceMetrics inserts metrics after deleting stored points of the same series (service,
+// metric name, type and attributes) at the same timestamp, so the latest write wins. The
+// replaced points are deleted with one statement per chunk, joined against the batch.
+func (s *DuckDBStore) ReplaceMetrics(ctx context.Context, metrics []api.MetricDataPoint) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(metrics); start += replaceMetricsChunk {
+		chunk := metrics[start:min(start+replaceMetricsChunk, len(metrics))]
+
+		values := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*5)
+		for i, m := range chunk {
+			values[i] = "(?::TIMESTAMP, ?, ?, ?, ?)"
+			args = append(args, formatTimeForDB(m.Timestamp), m.ServiceName, m.MetricName, m.MetricType, mapToString(m.Attributes))
+		}
+
+		query := `
+			DELETE FROM otel_metrics
+			USING (VALUES ` + strings.Join(values, ", ") + `) AS r(Ts, Service, Name, Type, Attrs)
+			WHERE otel_metrics.Timestamp = r.Ts AND otel_metrics.ServiceName = r.Service
+				AND otel_metrics.MetricName = r.Name AND otel_metrics.MetricType = r.Type
+				AND CAST(otel_metrics.Attributes AS VARCHAR) = r.Attrs
+		`
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("deleting replaced metrics: %w", err)
+		}
+	}
+
+	if err := insertMetricsTx(ctx, tx, metrics); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.markModified()
+	return nil
+}
+
+// insertMetricsTx inserts metrics within tx
+func insertMetricsTx(ctx context.Context, tx *sql.Tx, metrics []api.MetricDataPoint) error {
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO otel_metrics (
 			Timestamp, ServiceName, MetricName, MetricDescription, MetricUnit,
@@ -34,8 +100,8 @@ func (s *DuckDBStore) InsertMetrics(ctx context.Context, metrics []api.MetricDat
 			Value, AggregationTemporality, IsMonotonic, Count, Sum,
 			BucketCounts, ExplicitBounds, Scale, ZeroCount, PositiveOffset,
 			PositiveBucketCounts, NegativeOffset, NegativeBucketCounts,
-			QuantileValues, QuantileQuantiles, Min, Max
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			QuantileValues, QuantileQuantiles, Min, Max, ScopeAttributes
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("preparing statement: %w", err)
@@ -71,71 +137,77 @@ func (s *DuckDBStore) InsertMetrics(ctx context.Context, metrics []api.MetricDat
 			float64ArrayToString(m.QuantileQuantiles),
 			nullFloat64(m.Min),
 			nullFloat64(m.Max),
+			mapToString(m.ScopeAttributes),
 		)
 		if err != nil {
 			return fmt.Errorf("inserting metric: %w", err)
 		}
 	}
-
-	return tx.Commit()
+	return nil
 }
 
-func (s *DuckDBStore) QueryMetrics(ctx context.Context, service, metricName, metricType string, from, to time.Time, limit, offset int) (*api.MetricsResponse, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
+// metricsFilter builds the WHERE clause and arguments shared by QueryMetrics and CountMetrics
+func metricsFilter(service, metricName, metricType string, from, to time.Time) (string, []interface{}) {
 	// Format times as strings to avoid timezone issues with DuckDB's TIMESTAMP type
-	fromStr := formatTimeForDB(from)
-	toStr := formatTimeForDB(to)
-
-	query := `
-		SELECT
-			Timestamp, ServiceName, MetricName, MetricDescription, MetricUnit,
-			ResourceAttributes, ScopeName, ScopeVersion, Attributes, MetricType,
-			Value, AggregationTemporality, IsMonotonic, Count, Sum,
-			Min, Max
-		FROM otel_metrics
-		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
-	`
-	args := []interface{}{fromStr, toStr}
+	where := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
 
 	if service != "" {
-		query += " AND ServiceName = ?"
+		where += " AND ServiceName = ?"
 		args = append(args, service)
 	}
 
 	if metricName != "" {
-		query += " AND MetricName = ?"
+		where += " AND MetricName = ?"
 		args = append(args, metricName)
 	}
 
 	if metricType != "" {
-		query += " AND MetricType = ?"
+		where += " AND MetricType = ?"
 		args = append(args, metricType)
 	}
 
-	// Get total count
-	countQuery := "SELECT COUNT(*) FROM otel_metrics WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
-	countArgs := []interface{}{fromStr, toStr}
-	if service != "" {
-		countQuery += " AND ServiceName = ?"
-		countArgs = append(countArgs, service)
-	}
-	if metricName != "" {
-		countQuery += " AND MetricName = ?"
-		countArgs = append(countArgs, metricName)
-	}
-	if metricType != "" {
-		countQuery += " AND MetricType = ?"
-		countArgs = append(countArgs, metricType)
+	return where, args
+}
+
+// CountMetrics returns the number of data points matching the same filters as QueryMetrics without fetching rows
+func (s *DuckDBStore) CountMetrics(ctx context.Context, service, metricName, metricType string, from, to time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	where, args := metricsFilter(service, metricName, metricType, from, to)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM otel_metrics WHERE "+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting metrics: %w", err)
 	}
+	return total, nil
+}
+
+func (s *DuckDBStore) QueryMetrics(ctx context.Context, service, metricName, metricType string, from, to time.Time, limit, offset int) (*api.MetricsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	where, args := metricsFilter(service, metricName, metricType, from, to)
+
+	query := `
+		SELECT
+			Timestamp, ServiceName, MetricName, MetricDescription, MetricUnit,
+			ResourceAttributes, ScopeName, ScopeVersion, Attributes, MetricType,
+			Value, AggregationTemporality, IsMonotonic, Count, Sum,
+			Min, Max, ScopeAttributes
+		FROM otel_metrics
+		WHERE ` + where
 
+	// Get total count
 	var total int
-	if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM otel_metrics WHERE "+where, args...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("counting metrics: %w", err)
 	}
 
-	query += fmt.Sprintf(" ORDER BY Timestamp DESC LIMIT %d OFFSET %d", limit, offset)
+	// Points from one OTLP batch share a timestamp, so MetricName and rowid break ties
+	// to keep pagination stable
+	query += fmt.Sprintf(" ORDER BY Timestamp DESC, MetricName, rowid LIMIT %d OFFSET %d", limit, offset)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -147,7 +219,7 @@ func (s *DuckDBStore) QueryMetrics(ctx context.Context, service, metricName, met
 	for rows.Next() {
 		var m api.MetricDataPoint
 		var desc, unit, scopeName, scopeVersion sql.NullString
-		var resourceAttrs, attrs interface{}
+		var resourceAttrs, attrs, scopeAttrs interface{}
 		var value, sum, min, max sql.NullFloat64
 		var aggregationTemporality sql.NullInt32
 		var isMonotonic sql.NullBool
@@ -157,7 +229,7 @@ func (s *DuckDBStore) QueryMetrics(ctx context.Context, service, metricName, met
 			&m.Timestamp, &m.ServiceName, &m.MetricName, &desc, &unit,
 			&resourceAttrs, &scopeName, &scopeVersion, &attrs, &m.MetricType,
 			&value, &aggregationTemporality, &isMonotonic, &count, &sum,
-			&min, &max,
+			&min, &max, &scopeAttrs,
 		); err != nil {
 			return nil, fmt.Errorf("scanning metric: %w", err)
 		}
@@ -168,6 +240,7 @@ func (s *DuckDBStore) QueryMetrics(ctx context.Context, service, metricName, met
 		m.ScopeVersion = scopeVersion.String
 		m.ResourceAttributes = scanJSONToMap(resourceAttrs)
 		m.Attributes = scanJSONToMap(attrs)
+		m.ScopeAttributes = scanJSONToMap(scopeAttrs)
 
 		if value.Valid {
 			m.Value = &value.Float64
@@ -244,6 +317,103 @@ func (s *DuckDBStore) GetMetricNames(ctx context.Context, service string) ([]str
 	return names, nil
 }
 
+// GetMetricTable returns the latest value of a metric for each distinct service and attribute set.
+// Histogram-style points without a Value fall back to their Sum.
+func (s *DuckDBStore) GetMetricTable(ctx context.Context, metricName, service string) ([]api.MetricRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ServiceName, Attributes, COALESCE(Value, Sum) as Value, Timestamp
+		FROM otel_metrics
+		WHERE MetricName = ?
+	`
+	args := []interface{}{metricName}
+
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	query += `
+		QUALIFY ROW_NUMBER() OVER (
+			PARTITION BY ServiceName, CAST(Attributes AS VARCHAR)
+			ORDER BY Timestamp DESC
+		) = 1
+		ORDER BY ServiceName, CAST(Attributes AS VARCHAR)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric table: %w", err)
+	}
+	defer rows.Close()
+
+	var result []api.MetricRow
+	for rows.Next() {
+		var row api.MetricRow
+		var attrs interface{}
+		var value sql.NullFloat64
+		if err := rows.Scan(&row.ServiceName, &attrs, &value, &row.Timestamp); err != nil {
+			return nil, fmt.Errorf("scanning metric row: %w", err)
+		}
+		row.Attributes = scanJSONToMap(attrs)
+		if value.Valid {
+			row.Value = &value.Float64
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating metric table: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetCostTotals returns the all-time cost in USD per service, summed over all *.cost.usage metrics.
+// Cumulative series contribute their increase (MAX - MIN), delta series the sum of their points.
+func (s *DuckDBStore) GetCostTotals(ctx context.Context) (map[string]float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT ServiceName, SUM(series_total) as total
+		FROM (
+			SELECT
+				ServiceName,
+				CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+					THEN MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum))
+					ELSE SUM(COALESCE(Value, Sum))
+				END as series_total
+			FROM otel_metrics
+			WHERE MetricName LIKE '%.cost.usage'
+			GROUP BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)
+		) series
+		GROUP BY ServiceName
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying cost totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var service string
+		var total sql.NullFloat64
+		if err := rows.Scan(&service, &total); err != nil {
+			return nil, fmt.Errorf("scanning cost total: %w", err)
+		}
+		totals[service] = total.Float64
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating cost totals: %w", err)
+	}
+
+	return totals, nil
+}
+
 // GetBreakdownValues returns distinct values for a given attribute on a metric.
 // This query is not time-filtered to return all historical values.
 func (s *DuckDBStore) GetBreakdownValues(ctx context.Context, metricName, attribute, service string) ([]string, error) {
@@ -289,7 +459,104 @@ func (s *DuckDBStore) GetBreakdownValues(ctx context.Context, metricName, attrib
 	return values, nil
 }
 
-func (s *DuckDBStore) QueryMetricSeries(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate bool) (*api.TimeSeriesResponse, error) {
+// GetMetricBreakdown aggregates a metric over the time range, grouped by the stored value of one attribute,
+// largest total first. Totals use the same type-aware aggregation as single-value widgets (see
+// metricAggFunction). Data points without the attribute are grouped under the empty string.
+func (s *DuckDBStore) GetMetricBreakdown(ctx context.Context, metricName, attribute, service string, from, to time.Time) ([]api.BreakdownRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	typeQuery := `
+		SELECT MetricType, AggregationTemporality
+		FROM otel_metrics
+		WHERE MetricName = ?
+		LIMIT 1
+	`
+	var metricType string
+	var aggregationTemporality sql.NullInt32
+	if err := s.db.QueryRowContext(ctx, typeQuery, metricName).Scan(&metricType, &aggregationTemporality); err != nil {
+		if err == sql.ErrNoRows {
+			return []api.BreakdownRow{}, nil
+		}
+		return nil, fmt.Errorf("getting metric type: %w", err)
+	}
+
+	// OTLP AggregationTemporality: 0=UNSPECIFIED, 1=DELTA, 2=CUMULATIVE
+	isCumulative := aggregationTemporality.Valid && aggregationTemporality.Int32 == 2
+
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(json_extract_string(Attributes, ?), '') as attr_value,
+			%s as agg_value
+		FROM otel_metrics
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+			AND MetricName = ?
+			AND (Value IS NOT NULL OR Sum IS NOT NULL)
+	`, metricAggFunction(metricType, isCumulative, true))
+	// Quote the key so dotted attribute names (gen_ai.request.model) are not treated as nested paths
+	args := []interface{}{`$."` + attribute + `"`, formatTimeForDB(from), formatTimeForDB(to), metricName}
+
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	query += " GROUP BY attr_value ORDER BY COALESCE(agg_value, 0) DESC, attr_value"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	result := []api.BreakdownRow{}
+	for rows.Next() {
+		var row api.BreakdownRow
+		var total sql.NullFloat64
+		if err := rows.Scan(&row.Value, &total); err != nil {
+			return nil, fmt.Errorf("scanning metric breakdown: %w", err)
+		}
+		row.Total = total.Float64
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating metric breakdown: %w", err)
+	}
+
+	return result, nil
+}
+
+// metricAggFunction returns the SQL aggregate that collapses a metric within a time bucket, or
+// over the whole time range when aggregate is set. COALESCE(Value, Sum) handles both gauge/sum
+// (Value) and histogram (Sum) metrics.
+func metricAggFunction(metricType string, isCumulative, aggregate bool) string {
+	switch metricType {
+	case "gauge":
+		return "AVG(COALESCE(Value, Sum))"
+	case "sum":
+		if isCumulative && aggregate {
+			// CUMULATIVE: value is running total, so total increase = MAX - MIN
+			return "(MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum)))"
+		}
+		if isCumulative {
+			// CUMULATIVE: show the running total at end of each bucket
+			return "arg_max(COALESCE(Value, Sum), Timestamp)"
+		}
+		// DELTA: each value is the change, so total = SUM
+		return "SUM(COALESCE(Value, Sum))"
+	case "histogram", "exp_histogram":
+		// Histograms store their sum in the Sum column
+		return "SUM(Sum)"
+	default:
+		// Default to SUM for unknown types
+		return "SUM(COALESCE(Value, Sum))"
+	}
+}
+
+// QueryMetricSeries returns a metric as time-bucketed series, or as one value per series when
+// aggregate is set. A quantile in (0, 1] switches exponential histogram metrics to quantiles
+// reconstructed from their buckets; it is ignored for other metric types.
+func (s *DuckDBStore) QueryMetricSeries(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate bool, quantile float64) (*api.TimeSeriesResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -317,54 +584,12 @@ func (s *DuckDBStore) QueryMetricSeries(ctx context.Context, metricName, service
 	// OTLP AggregationTemporality: 0=UNSPECIFIED, 1=DELTA, 2=CUMULATIVE
 	isCumulative := aggregationTemporality.Valid && aggregationTemporality.Int32 == 2
 
-	// Determine aggregation function based on metric type and mode
-	// Use COALESCE(Value, Sum) to handle both gauge/sum (Value) and histogram (Sum) metrics
-	var aggFunction string
-	if aggregate {
-		// Scalar aggregation over entire time range
-		switch metricType {
-		case "gauge":
-			aggFunction = "AVG(COALESCE(Value, Sum))"
-		case "sum":
-			if isCumulative {
-				// CUMULATIVE: value is running total, so total increase = MAX - MIN
-				aggFunction = "(MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum)))"
-			} else {
-				// DELTA: each value is the change, so total = SUM
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			}
-		case "histogram", "exp_histogram":
-			// Histograms store their sum in the Sum column
-			aggFunction = "SUM(Sum)"
-		default:
-			// Default to SUM for unknown types
-			aggFunction = "SUM(COALESCE(Value, Sum))"
-		}
-	} else {
-		// Time-bucketed aggregation
-		switch metricType {
-		case "gauge":
-			aggFunction = "AVG(COALESCE(Value, Sum))"
-		case "sum":
-			if isCumulative {
-				// CUMULATIVE: show the running total at end of each bucket
-				aggFunction = "arg_max(COALESCE(Value, Sum), Timestamp)"
-			} else if isMonotonic.Valid && isMonotonic.Bool {
-				// DELTA monotonic counter: sum within bucket
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			} else {
-				// DELTA non-monotonic: sum within bucket
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			}
-		case "histogram", "exp_histogram":
-			// Histograms store their sum in the Sum column
-			aggFunction = "SUM(Sum)"
-		default:
-			// Default to SUM for unknown types
-			aggFunction = "SUM(COALESCE(Value, Sum))"
-		}
+	if quantile > 0 && isExpHistogram(metricType) {
+		return s.queryExpHistogramQuantiles(ctx, metricName, service, from, to, intervalSeconds, aggregate, isCumulative, quantile)
 	}
 
+	aggFunction := metricAggFunction(metricType, isCumulative, aggregate)
+
 	var query string
 	args := []interface{}{fromStr, toStr, metricName}
 
@@ -567,7 +792,7 @@ func (s *DuckDBStore) QueryBatchMetricSeries(ctx context.Context, queries []api.
 			}
 
 			// Execute the query using internal method
-			resp, err := s.queryMetricSeriesInternal(ctx, q.Name, q.Service, from, to, intervalSeconds, q.Aggregate, typeInfo)
+			resp, err := s.queryMetricSeriesInternal(ctx, q.Name, q.Service, from, to, intervalSeconds, q.Aggregate, q.Quantile, typeInfo)
 			if err != nil {
 				result.Success = false
 				result.Error = err.Error()
@@ -650,7 +875,7 @@ func (s *DuckDBStore) batchGetMetricTypes(ctx context.Context, queries []api.Met
 }
 
 // queryMetricSeriesInternal is the core query logic, using pre-fetched type info
-func (s *DuckDBStore) queryMetricSeriesInternal(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate bool, typeInfo metricTypeInfo) (*api.TimeSeriesResponse, error) {
+func (s *DuckDBStore) queryMetricSeriesInternal(ctx context.Context, metricName, service string, from, to time.Time, intervalSeconds int64, aggregate bool, quantile float64, typeInfo metricTypeInfo) (*api.TimeSeriesResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -661,45 +886,12 @@ func (s *DuckDBStore) queryMetricSeriesInternal(ctx context.Context, metricName,
 	// OTLP AggregationTemporality: 0=UNSPECIFIED, 1=DELTA, 2=CUMULATIVE
 	isCumulative := typeInfo.aggregationTemporality.Valid && typeInfo.aggregationTemporality.Int32 == 2
 
-	// Determine aggregation function based on metric type and mode
-	// Use COALESCE(Value, Sum) to handle both gauge/sum (Value) and histogram (Sum) metrics
-	var aggFunction string
-	if aggregate {
-		// Scalar aggregation over entire time range
-		switch typeInfo.metricType {
-		case "gauge":
-			aggFunction = "AVG(COALESCE(Value, Sum))"
-		case "sum":
-			if isCumulative {
-				aggFunction = "(MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum)))"
-			} else {
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			}
-		case "histogram", "exp_histogram":
-			aggFunction = "SUM(Sum)"
-		default:
-			aggFunction = "SUM(COALESCE(Value, Sum))"
-		}
-	} else {
-		// Time-bucketed aggregation
-		switch typeInfo.metricType {
-		case "gauge":
-			aggFunction = "AVG(COALESCE(Value, Sum))"
-		case "sum":
-			if isCumulative {
-				aggFunction = "arg_max(COALESCE(Value, Sum), Timestamp)"
-			} else if typeInfo.isMonotonic.Valid && typeInfo.isMonotonic.Bool {
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			} else {
-				aggFunction = "SUM(COALESCE(Value, Sum))"
-			}
-		case "histogram", "exp_histogram":
-			aggFunction = "SUM(Sum)"
-		default:
-			aggFunction = "SUM(COALESCE(Value, Sum))"
-		}
+	if quantile > 0 && isExpHistogram(typeInfo.metricType) {
+		return s.queryExpHistogramQuantiles(ctx, metricName, service, from, to, intervalSeconds, aggregate, isCumulative, quantile)
 	}
 
+	aggFunction := metricAggFunction(typeInfo.metricType, isCumulative, aggregate)
+
 	var query string
 	args := []interface{}{fromStr, toStr, metricName}
 
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// unknownModel is reported for cost series without a model attribute
+const unknownModel = "unknown"
+
+// GetCostByModel returns the cost between from and to per model, highest first, optionally for
+// one service. Every tool's *.cost.usage metric (Claude Code, Codex CLI, Gemini CLI) counts; a
+// series is attributed to its "model" attribute or the GenAI semantic convention model keys.
+// Series split further, e.g. by gen_ai.token.type, add up within their model. Cumulative series
+// contribute their increase and delta series the sum of their points, as in GetCostTotals.
+func (s *DuckDBStore) GetCostByModel(ctx context.Context, service string, from, to time.Time) ([]api.ModelCost, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT
+			COALESCE(
+				json_extract_string(ANY_VALUE(Attributes), '$.model'),
+				json_extract_string(ANY_VALUE(Attributes), '$."gen_ai.response.model"'),
+				json_extract_string(ANY_VALUE(Attributes), '$."gen_ai.request.model"')
+			) as model,
+			CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+				THEN MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum))
+				ELSE SUM(COALESCE(Value, Sum))
+			END as series_total
+		FROM otel_metrics
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+			AND MetricName LIKE '%.cost.usage'
+	`
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	query += " GROUP BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)"
+
+	query = `
+		SELECT COALESCE(NULLIF(model, ''), ?) as model, SUM(series_total) as cost
+		FROM (` + query + `)
+		GROUP BY 1
+		HAVING SUM(series_total) > 0
+		ORDER BY cost DESC, model
+	`
+	args = append([]interface{}{unknownModel}, args...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying cost by model: %w", err)
+	}
+	defer rows.Close()
+
+	models := []api.ModelCost{}
+	for rows.Next() {
+		var m api.ModelCost
+		if err := rows.Scan(&m.Model, &m.CostUSD); err != nil {
+			return nil, fmt.Errorf("scanning model cost: %w", err)
+		}
+		models = append(models, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating model costs: %w", err)
+	}
+
+	return models, nil
+}
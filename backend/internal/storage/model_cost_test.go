@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetCostByModel(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+	point := func(ts time.Time, service, name string, temporality *int32, attrs map[string]string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: name, MetricType: "sum",
+			AggregationTemporality: temporality, Attributes: attrs, Value: &v,
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		point(now.Add(-50*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"model": "claude-sonnet-4"}, 1.5),
+		point(now.Add(-40*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"model": "claude-sonnet-4", "session.id": "s2"}, 0.5),
+		point(now.Add(-30*time.Minute), "codex_cli_rs", "codex_cli_rs.cost.usage", nil, map[string]string{"model": "gpt-4o"}, 0.75),
+		// Series split by token type add up within their model
+		point(now.Add(-30*time.Minute), "gemini_cli", "gemini_cli.cost.usage", nil, map[string]string{"gen_ai.request.model": "gemini-2.0-flash", "gen_ai.token.type": "input"}, 0.25),
+		point(now.Add(-30*time.Minute), "gemini_cli", "gemini_cli.cost.usage", nil, map[string]string{"gen_ai.request.model": "gemini-2.0-flash", "gen_ai.token.type": "output"}, 0.5),
+		// The user-facing variant duplicates cost and must not be counted
+		point(now.Add(-50*time.Minute), "claude-code", "claude_code.cost.usage_user_facing", nil, map[string]string{"model": "claude-sonnet-4"}, 1.5),
+		// Cumulative series contribute their increase
+		point(now.Add(-2*time.Hour), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"model": "claude-opus-4"}, 1.0),
+		point(now.Add(-20*time.Minute), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"model": "claude-opus-4"}, 2.0),
+		point(now.Add(-10*time.Minute), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"model": "claude-opus-4"}, 5.0),
+		point(now.Add(-10*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"session.id": "s3"}, 0.1),
+		// Outside the range
+		point(now.Add(-3*time.Hour), "claude-code", "claude_code.cost.usage", nil, map[string]string{"model": "claude-haiku"}, 9),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	models, err := store.GetCostByModel(ctx, "", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetCostByModel failed: %v", err)
+	}
+	want := []api.ModelCost{
+		{Model: "claude-opus-4", CostUSD: 3.0},
+		{Model: "claude-sonnet-4", CostUSD: 2.0},
+		{Model: "gemini-2.0-flash", CostUSD: 0.75},
+		{Model: "gpt-4o", CostUSD: 0.75},
+		{Model: unknownModel, CostUSD: 0.1},
+	}
+	if len(models) != len(want) {
+		t.Fatalf("expected %d models, got %+v", len(want), models)
+	}
+	for i, w := range want {
+		if models[i] != w {
+			t.Errorf("model %d = %+v, want %+v", i, models[i], w)
+		}
+	}
+
+	models, err = store.GetCostByModel(ctx, "codex_cli_rs", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetCostByModel failed: %v", err)
+	}
+	if len(models) != 1 || models[0].Model != "gpt-4o" {
+		t.Errorf("expected only the codex model, got %+v", models)
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// overviewTopModels is the number of models reported in the overview
+const overviewTopModels = 5
+
+// overviewCostSeries selects the cost of each *.cost.usage series from the first placeholder
+// on, attributed to the series' model. Cumulative series contribute their increase, delta
+// series the sum of their points, as in GetCostTotals.
+const overviewCostSeries = `
+	SELECT
+		COALESCE(json_extract_string(ANY_VALUE(Attributes), '$.model'), 'unknown') as model,
+		CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+			THEN MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum))
+			ELSE SUM(COALESCE(Value, Sum))
+		END as series_total
+	FROM otel_metrics
+	WHERE Timestamp >= ?::TIMESTAMP AND MetricName LIKE '%.cost.usage'
+	GROUP BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)
+`
+
+// GetOverview composes the home dashboard payload: all-time stats, error spans and log
+// level counts since since, the cost since today (local midnight) and the models with the
+// highest cost since since.
+func (s *DuckDBStore) GetOverview(ctx context.Context, since, today time.Time) (*api.OverviewResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats, err := s.getStatsLocked(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sinceStr := formatTimeForDB(since)
+	overview := &api.OverviewResponse{
+		Since:     since,
+		Stats:     stats,
+		TopModels: []api.ModelCost{},
+		LogLevels: make(map[string]int64),
+	}
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM otel_traces
+				WHERE StatusCode = 'ERROR' AND Timestamp >= ?::TIMESTAMP) as recent_errors,
+			(SELECT COALESCE(SUM(series_total), 0) FROM (` + overviewCostSeries + `)) as today_cost
+	`
+	if err := s.db.QueryRowContext(ctx, query, sinceStr, formatTimeForDB(today)).Scan(
+		&overview.RecentErrorCount,
+		&overview.TodayCostUSD,
+	); err != nil {
+		return nil, fmt.Errorf("querying overview totals: %w", err)
+	}
+
+	// Top models and log levels share one round-trip, told apart by the section column
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT * FROM (
+			SELECT 'model' as section, model as name, SUM(series_total) as value
+			FROM (`+overviewCostSeries+`)
+			GROUP BY model
+			ORDER BY value DESC, name
+			LIMIT ?
+		)
+		UNION ALL
+		SELECT 'level', SeverityText, COUNT(*)
+		FROM otel_logs
+		WHERE SeverityText IS NOT NULL AND Timestamp >= ?::TIMESTAMP
+		GROUP BY SeverityText
+	`, sinceStr, overviewTopModels, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("querying overview breakdowns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var section, name string
+		var value float64
+		if err := rows.Scan(&section, &name, &value); err != nil {
+			return nil, fmt.Errorf("scanning overview breakdown: %w", err)
+		}
+		switch section {
+		case "model":
+			overview.TopModels = append(overview.TopModels, api.ModelCost{Model: name, CostUSD: value})
+		case "level":
+			overview.LogLevels[name] = int64(value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating overview breakdowns: %w", err)
+	}
+	// UNION ALL does not keep the subquery's order
+	sort.Slice(overview.TopModels, func(i, j int) bool {
+		a, b := overview.TopModels[i], overview.TopModels[j]
+		if a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		return a.Model < b.Model
+	})
+
+	return overview, nil
+}
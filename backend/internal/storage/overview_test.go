@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetOverview(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	since, today := now.Add(-2*time.Hour), now.Add(-time.Hour)
+
+	spans := []api.Span{
+		{TraceID: "t1", SpanID: "s1", ServiceName: "claude-code", SpanName: "a", Timestamp: now.Add(-10 * time.Minute), StatusCode: "ERROR"},
+		{TraceID: "t1", SpanID: "s2", ServiceName: "claude-code", SpanName: "b", Timestamp: now.Add(-10 * time.Minute), StatusCode: "OK"},
+		{TraceID: "t0", SpanID: "s0", ServiceName: "claude-code", SpanName: "old", Timestamp: now.Add(-3 * time.Hour), StatusCode: "ERROR"},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-5 * time.Minute), ServiceName: "claude-code", SeverityText: "INFO", Body: "a"},
+		{Timestamp: now.Add(-5 * time.Minute), ServiceName: "claude-code", SeverityText: "INFO", Body: "b"},
+		{Timestamp: now.Add(-5 * time.Minute), ServiceName: "claude-code", SeverityText: "ERROR", Body: "c"},
+		{Timestamp: now.Add(-3 * time.Hour), ServiceName: "claude-code", SeverityText: "WARN", Body: "old"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	delta := int32(1)
+	cost := func(ts time.Time, model string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum",
+			AggregationTemporality: &delta, Value: &v, Attributes: map[string]string{"model": model},
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		cost(now.Add(-30*time.Minute), "opus", 3.0),
+		cost(now.Add(-30*time.Minute), "sonnet", 1.0),
+		cost(now.Add(-90*time.Minute), "sonnet", 0.5), // since "since", before "today"
+		cost(now.Add(-3*time.Hour), "haiku", 9.0),     // before "since"
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	overview, err := store.GetOverview(ctx, since, today)
+	if err != nil {
+		t.Fatalf("GetOverview failed: %v", err)
+	}
+
+	if overview.Stats == nil || overview.Stats.SpanCount != 3 || overview.Stats.LogCount != 4 {
+		t.Errorf("expected all-time stats, got %+v", overview.Stats)
+	}
+	if overview.RecentErrorCount != 1 {
+		t.Errorf("RecentErrorCount = %d, want 1", overview.RecentErrorCount)
+	}
+	if math.Abs(overview.TodayCostUSD-4.0) > 1e-9 {
+		t.Errorf("TodayCostUSD = %v, want 4", overview.TodayCostUSD)
+	}
+
+	wantModels := []api.ModelCost{{Model: "opus", CostUSD: 3.0}, {Model: "sonnet", CostUSD: 1.5}}
+	if len(overview.TopModels) != len(wantModels) {
+		t.Fatalf("TopModels = %+v, want %+v", overview.TopModels, wantModels)
+	}
+	for i, want := range wantModels {
+		got := overview.TopModels[i]
+		if got.Model != want.Model || math.Abs(got.CostUSD-want.CostUSD) > 1e-9 {
+			t.Errorf("TopModels[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if len(overview.LogLevels) != 2 || overview.LogLevels["INFO"] != 2 || overview.LogLevels["ERROR"] != 1 {
+		t.Errorf("LogLevels = %v, want INFO=2 ERROR=1", overview.LogLevels)
+	}
+}
+
+func TestGetOverview_Empty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	overview, err := store.GetOverview(context.Background(), time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("GetOverview failed: %v", err)
+	}
+	if overview.RecentErrorCount != 0 || overview.TodayCostUSD != 0 || len(overview.TopModels) != 0 || len(overview.LogLevels) != 0 {
+		t.Errorf("expected an empty overview, got %+v", overview)
+	}
+}
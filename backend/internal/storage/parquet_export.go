@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExportLogsParquet writes the logs matching the QueryLogs filters to w as a Parquet file,
+// newest first and without pagination
+func (s *DuckDBStore) ExportLogsParquet(ctx context.Context, w io.Writer, service, severity string, severities SeverityRange, traceID, search string, from, to time.Time) error {
+	// The search filter reads the log search index state, so it is built under the store lock
+	return s.exportParquet(ctx, w, func() (string, []interface{}) {
+		where, args := s.logsFilter(service, severity, severities, traceID, search, from, to)
+		return "SELECT * FROM otel_logs WHERE " + where + " ORDER BY Timestamp DESC", args
+	})
+}
+
+// ExportMetricsParquet writes the data points matching the QueryMetrics filters to w as a
+// Parquet file, newest first and without pagination
+func (s *DuckDBStore) ExportMetricsParquet(ctx context.Context, w io.Writer, service, metricName, metricType string, from, to time.Time) error {
+	where, args := metricsFilter(service, metricName, metricType, from, to)
+	return s.exportParquet(ctx, w, func() (string, []interface{}) {
+		return "SELECT * FROM otel_metrics WHERE " + where + " ORDER BY Timestamp DESC, MetricName", args
+	})
+}
+
+// ExportSpansParquet writes the spans matching the QueryTraces filters to w as a Parquet file,
+// newest first. Filters apply per span, so a trace is exported only partially when some of
+// its spans fall outside them.
+func (s *DuckDBStore) ExportSpansParquet(ctx context.Context, w io.Writer, service, search, event string, from, to time.Time) error {
+	where := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+
+	if service != "" {
+		where += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	if search != "" {
+		where += " AND (SpanName ILIKE ? OR ServiceName ILIKE ? OR StatusMessage ILIKE ? OR CAST(SpanAttributes AS VARCHAR) ILIKE ?)"
+		pattern := "%" + search + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+
+	if event != "" {
+		where += spanEventFilter
+		args = append(args, event)
+	}
+
+	return s.exportParquet(ctx, w, func() (string, []interface{}) {
+		return "SELECT * FROM otel_traces WHERE " + where + " ORDER BY Timestamp DESC", args
+	})
+}
+
+// exportParquet copies the rows selected by the query build returns to w as a Parquet file.
+// build runs under the same read lock as the COPY, so filters that read store state match the
+// data they are applied to. DuckDB's COPY can only write to a path through database/sql, so
+// the file is staged in a temporary file that is removed afterwards and read into w in
+// chunks, stopping when ctx is canceled. Nothing is written to w when the COPY fails.
+func (s *DuckDBStore) exportParquet(ctx context.Context, w io.Writer, build func() (string, []interface{})) error {
+	tmp, err := os.CreateTemp("", "ai-observer-export-*.parquet")
+	if err != nil {
+		return fmt.Errorf("creating export file: %w", err)
+	}
+	path := tmp.Name()
+	tmp.Close()
+	defer os.Remove(path)
+
+	s.mu.RLock()
+	query, args := build()
+	copyQuery := fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)", query, strings.ReplaceAll(path, "'", "''"))
+	_, err = s.db.ExecContext(ctx, copyQuery, args...)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("exporting parquet: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening export file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(w, contextReader{ctx: ctx, r: f}); err != nil {
+		return fmt.Errorf("writing export: %w", err)
+	}
+	return nil
+}
+
+// contextReader fails reads once ctx is done, so copies from it stop on cancellation
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// readParquet stages data in a file and returns the ServiceName column of its rows
+func readParquet(t *testing.T, store *DuckDBStore, data []byte) []string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("export is not a Parquet file (%d bytes)", len(data))
+	}
+
+	path := filepath.Join(t.TempDir(), "export.parquet")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("writing export: %v", err)
+	}
+
+	rows, err := store.db.Query("SELECT ServiceName FROM read_parquet('" + path + "')")
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	defer rows.Close()
+
+	var services []string
+	for rows.Next() {
+		var service string
+		if err := rows.Scan(&service); err != nil {
+			t.Fatalf("scanning export: %v", err)
+		}
+		services = append(services, service)
+	}
+	return services
+}
+
+func TestExportParquet(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	from, to := now.Add(-time.Hour), now
+
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-10 * time.Minute), ServiceName: "svc-a", SeverityText: "ERROR", Body: "boom"},
+		{Timestamp: now.Add(-20 * time.Minute), ServiceName: "svc-b", SeverityText: "ERROR", Body: "bang"},
+		{Timestamp: now.Add(-30 * time.Minute), ServiceName: "svc-a", SeverityText: "INFO", Body: "ok"},
+		{Timestamp: now.Add(-2 * time.Hour), ServiceName: "svc-a", SeverityText: "ERROR", Body: "old"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	value := 1.0
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc-a", MetricName: "cost", MetricType: "sum", Value: &value},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc-b", MetricName: "tokens", MetricType: "sum", Value: &value},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	spans := []api.Span{
+		{Timestamp: now.Add(-time.Minute), TraceID: "t1", SpanID: "s1", SpanName: "llm_request", ServiceName: "svc-a"},
+		{Timestamp: now.Add(-2 * time.Minute), TraceID: "t2", SpanID: "s2", SpanName: "tool", ServiceName: "svc-b"},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := store.ExportLogsParquet(ctx, &buf, "", "ERROR", SeverityRange{}, "", "", from, to); err != nil {
+		t.Fatalf("ExportLogsParquet failed: %v", err)
+	}
+	if got := readParquet(t, store, buf.Bytes()); len(got) != 2 || got[0] != "svc-a" || got[1] != "svc-b" {
+		t.Errorf("exported logs = %v, want [svc-a svc-b]", got)
+	}
+
+	buf.Reset()
+	if err := store.ExportMetricsParquet(ctx, &buf, "", "tokens", "", from, to); err != nil {
+		t.Fatalf("ExportMetricsParquet failed: %v", err)
+	}
+	if got := readParquet(t, store, buf.Bytes()); len(got) != 1 || got[0] != "svc-b" {
+		t.Errorf("exported metrics = %v, want [svc-b]", got)
+	}
+
+	buf.Reset()
+	if err := store.ExportSpansParquet(ctx, &buf, "", "llm", "", from, to); err != nil {
+		t.Fatalf("ExportSpansParquet failed: %v", err)
+	}
+	if got := readParquet(t, store, buf.Bytes()); len(got) != 1 || got[0] != "svc-a" {
+		t.Errorf("exported spans = %v, want [svc-a]", got)
+	}
+
+	// An empty selection is still a valid file
+	buf.Reset()
+	if err := store.ExportLogsParquet(ctx, &buf, "missing", "", SeverityRange{}, "", "", from, to); err != nil {
+		t.Fatalf("ExportLogsParquet failed: %v", err)
+	}
+	if got := readParquet(t, store, buf.Bytes()); len(got) != 0 {
+		t.Errorf("expected no exported logs, got %v", got)
+	}
+}
+
+func TestImportParquet(t *testing.T) {
+	source, cleanupSource := setupTestStore(t)
+	defer cleanupSource()
+	target, cleanupTarget := setupTestStore(t)
+	defer cleanupTarget()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc-a", SeverityText: "ERROR", Body: "boom", LogAttributes: map[string]string{"k": "v"}},
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "svc-b", SeverityText: "INFO", Body: "ok"},
+	}
+	if err := source.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := source.ExportLogsParquet(ctx, &buf, "", "", SeverityRange{}, "", "", now.Add(-time.Hour), now); err != nil {
+		t.Fatalf("ExportLogsParquet failed: %v", err)
+	}
+
+	version, _ := target.DataVersion()
+	rows, err := target.ImportParquet(ctx, "otel_logs", bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ImportParquet failed: %v", err)
+	}
+	if rows != 2 {
+		t.Errorf("expected 2 imported rows, got %d", rows)
+	}
+	if after, _ := target.DataVersion(); after == version {
+		t.Error("expected the data version to change after importing")
+	}
+
+	resp, err := target.QueryLogs(ctx, "svc-a", "", SeverityRange{}, "", "", now.Add(-time.Hour), now, 10, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if len(resp.Logs) != 1 || resp.Logs[0].Body != "boom" || resp.Logs[0].LogAttributes["k"] != "v" {
+		t.Errorf("unexpected imported logs: %+v", resp.Logs)
+	}
+
+	if _, err := target.ImportParquet(ctx, "dashboards", bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected an error importing into a non-telemetry table")
+	}
+	if _, err := target.ImportParquet(ctx, "otel_logs", bytes.NewReader([]byte("not parquet"))); err == nil {
+		t.Error("expected an error for an invalid Parquet file")
+	}
+}
+
+func TestReadParquet(t *testing.T) {
+	source, cleanupSource := setupTestStore(t)
+	defer cleanupSource()
+	target, cleanupTarget := setupTestStore(t)
+	defer cleanupTarget()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-time.Minute), ServiceName: "svc", SeverityText: "ERROR", Body: "boom", LogAttributes: map[string]string{"k": "v"}},
+	}
+	if err := source.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := source.ExportLogsParquet(ctx, &buf, "", "", SeverityRange{}, "", "", now.Add(-time.Hour), now); err != nil {
+		t.Fatalf("ExportLogsParquet failed: %v", err)
+	}
+
+	var read []api.LogRecord
+	err := target.ReadParquetLogs(ctx, bytes.NewReader(buf.Bytes()), func(log api.LogRecord) error {
+		read = append(read, log)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadParquetLogs failed: %v", err)
+	}
+	if len(read) != 1 || read[0].Body != "boom" || read[0].SeverityText != "ERROR" || read[0].LogAttributes["k"] != "v" || !read[0].Timestamp.Equal(logs[0].Timestamp) {
+		t.Errorf("unexpected logs read: %+v", read)
+	}
+
+	// Reading stores nothing
+	if count, _ := target.CountLogs(ctx, "", "", SeverityRange{}, "", "", now.Add(-time.Hour), now); count != 0 {
+		t.Errorf("expected no stored logs, got %d", count)
+	}
+
+	// Rows missing non-nullable span columns such as SpanName fail to scan
+	err = target.ReadParquetSpans(ctx, bytes.NewReader(buf.Bytes()), func(api.Span) error { return nil })
+	if err == nil {
+		t.Error("expected an error reading logs as spans")
+	}
+	if err := target.ReadParquetMetrics(ctx, bytes.NewReader([]byte("not parquet")), func(api.MetricDataPoint) error { return nil }); err == nil {
+		t.Error("expected an error for an invalid Parquet file")
+	}
+}
+
+func TestExportParquet_Canceled(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	logs := []api.LogRecord{{Timestamp: now.Add(-time.Minute), ServiceName: "svc", Body: "hello"}}
+	if err := store.InsertLogs(context.Background(), logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	err := store.ExportLogsParquet(ctx, &buf, "", "", SeverityRange{}, "", "", now.Add(-time.Hour), now)
+	if err == nil {
+		t.Fatal("expected an error for a canceled context")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing written after cancellation, got %d bytes", buf.Len())
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// importTables lists the tables ImportParquet can load into
+var importTables = map[string]bool{
+	"otel_traces":  true,
+	"otel_logs":    true,
+	"otel_metrics": true,
+}
+
+// ImportParquet inserts the rows of a Parquet file read from r into table (otel_traces,
+// otel_logs or otel_metrics) and returns the number of rows inserted. Columns are matched by
+// name, so files exported before a column was added load with NULLs in it. Like
+// exportParquet, the file is staged in a temporary file that is removed afterwards.
+func (s *DuckDBStore) ImportParquet(ctx context.Context, table string, r io.Reader) (int64, error) {
+	if !importTables[table] {
+		return 0, fmt.Errorf("cannot import into table %q", table)
+	}
+
+	path, err := stageParquet(r)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(path)
+
+	query := fmt.Sprintf("INSERT INTO %s BY NAME SELECT * FROM %s", table, readParquetSQL(path))
+	s.mu.Lock()
+	result, err := s.db.ExecContext(ctx, query)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, fmt.Errorf("importing parquet into %s: %w", table, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("importing parquet into %s: %w", table, err)
+	}
+	if rows > 0 && table == "otel_logs" {
+		s.markLogsModified()
+	} else if rows > 0 {
+		s.markModified()
+	}
+	return rows, nil
+}
+
+// stageParquet copies a Parquet file read from r into a temporary file and returns its path;
+// the caller removes it
+func stageParquet(r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp("", "ai-observer-import-*.parquet")
+	if err != nil {
+		return "", fmt.Errorf("creating import file: %w", err)
+	}
+	path := tmp.Name()
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("writing import file: %w", err)
+	}
+	return path, nil
+}
+
+// readParquetSQL returns the read_parquet call for a staged file
+func readParquetSQL(path string) string {
+	return fmt.Sprintf("read_parquet('%s')", strings.ReplaceAll(path, "'", "''"))
+}
+
+// readParquet stages a Parquet file and passes query a source selecting its rows with the
+// columns of table, matched by name like ImportParquet; columns missing from the file are
+// NULL. Nothing is written to the database.
+func (s *DuckDBStore) readParquet(table string, r io.Reader, query func(source string) error) error {
+	path, err := stageParquet(r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	source := fmt.Sprintf("((SELECT * FROM %s LIMIT 0) UNION ALL BY NAME (SELECT * FROM %s))", table, readParquetSQL(path))
+	if err := query(source); err != nil {
+		return fmt.Errorf("reading parquet: %w", err)
+	}
+	return nil
+}
+
+// ReadParquetSpans calls fn for each span of a Parquet file in the otel_traces layout, such
+// as a traces export, without storing anything
+func (s *DuckDBStore) ReadParquetSpans(ctx context.Context, r io.Reader, fn func(api.Span) error) error {
+	return s.readParquet("otel_traces", r, func(source string) error {
+		return s.iterateSpans(ctx, "SELECT "+traceSpanColumns+" FROM "+source, fn)
+	})
+}
+
+// ReadParquetLogs calls fn for each log record of a Parquet file in the otel_logs layout
+func (s *DuckDBStore) ReadParquetLogs(ctx context.Context, r io.Reader, fn func(api.LogRecord) error) error {
+	return s.readParquet("otel_logs", r, func(source string) error {
+		rows, err := s.db.QueryContext(ctx, "SELECT "+logRecordColumns+" FROM "+source)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			log, _, err := scanLogRow(rows, false)
+			if err != nil {
+				return err
+			}
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// ReadParquetMetrics calls fn for each data point of a Parquet file in the otel_metrics layout
+func (s *DuckDBStore) ReadParquetMetrics(ctx context.Context, r io.Reader, fn func(api.MetricDataPoint) error) error {
+	return s.readParquet("otel_metrics", r, func(source string) error {
+		rows, err := s.db.QueryContext(ctx, "SELECT "+metricPointColumns+" FROM "+source)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m, err := scanMetricRow(rows)
+			if err != nil {
+				return err
+			}
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// unknownProject is reported for cost that cannot be attributed to a working directory
+const unknownProject = "unknown"
+
+// GetCostByProject returns the cost between from and to per project, highest first. A cost
+// series is attributed to its "project" attribute (set by the importers from the session's
+// working directory) or else to the latest "cwd" logged for its session.id. Cumulative series
+// contribute their increase and delta series the sum of their points, as in GetCostTotals.
+func (s *DuckDBStore) GetCostByProject(ctx context.Context, from, to time.Time) ([]api.ProjectCost, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		WITH session_projects AS (
+			SELECT
+				json_extract_string(LogAttributes, '$."session.id"') as session_id,
+				arg_max(json_extract_string(LogAttributes, '$.cwd'), Timestamp) as project
+			FROM otel_logs
+			WHERE json_extract_string(LogAttributes, '$.cwd') IS NOT NULL
+				AND json_extract_string(LogAttributes, '$."session.id"') IS NOT NULL
+			GROUP BY session_id
+		),
+		series AS (
+			SELECT
+				json_extract_string(ANY_VALUE(Attributes), '$.project') as project,
+				json_extract_string(ANY_VALUE(Attributes), '$."session.id"') as session_id,
+				CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+					THEN MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum))
+					ELSE SUM(COALESCE(Value, Sum))
+				END as series_total
+			FROM otel_metrics
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND MetricName LIKE '%.cost.usage'
+			GROUP BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)
+		)
+		SELECT
+			COALESCE(series.project, session_projects.project, ?) as project,
+			SUM(series_total) as cost
+		FROM series
+		LEFT JOIN session_projects ON session_projects.session_id = series.session_id
+		GROUP BY 1
+		HAVING SUM(series_total) > 0
+		ORDER BY cost DESC, project
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, formatTimeForDB(from), formatTimeForDB(to), unknownProject)
+	if err != nil {
+		return nil, fmt.Errorf("querying cost by project: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []api.ProjectCost{}
+	for rows.Next() {
+		var p api.ProjectCost
+		if err := rows.Scan(&p.Project, &p.CostUSD); err != nil {
+			return nil, fmt.Errorf("scanning project cost: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating project costs: %w", err)
+	}
+
+	return projects, nil
+}
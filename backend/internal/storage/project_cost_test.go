@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetCostByProject(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+	point := func(ts time.Time, service, name string, temporality *int32, attrs map[string]string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: name, MetricType: "sum",
+			AggregationTemporality: temporality, Attributes: attrs, Value: &v,
+		}
+	}
+
+	metrics := []api.MetricDataPoint{
+		// Imported sessions carry the project attribute
+		point(now.Add(-50*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"model": "sonnet", "project": "/src/api"}, 1.5),
+		point(now.Add(-40*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"model": "opus", "project": "/src/api"}, 2.0),
+		point(now.Add(-30*time.Minute), "codex_cli_rs", "codex_cli_rs.cost.usage", nil, map[string]string{"model": "gpt-5", "project": "/src/web"}, 0.75),
+		// The user-facing variant duplicates cost and must not be counted
+		point(now.Add(-50*time.Minute), "claude-code", "claude_code.cost.usage_user_facing", nil, map[string]string{"model": "sonnet", "project": "/src/api"}, 1.5),
+		// OTLP cumulative series is attributed via the cwd logged for its session
+		point(now.Add(-2*time.Hour), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"session.id": "s1"}, 1.0),
+		point(now.Add(-20*time.Minute), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"session.id": "s1"}, 2.0),
+		point(now.Add(-10*time.Minute), "claude-code", "claude_code.cost.usage", &cumulative, map[string]string{"session.id": "s1"}, 4.0),
+		// A session without a logged cwd is unknown
+		point(now.Add(-10*time.Minute), "claude-code", "claude_code.cost.usage", nil, map[string]string{"session.id": "s2"}, 0.25),
+		// Outside the range
+		point(now.Add(-3*time.Hour), "claude-code", "claude_code.cost.usage", nil, map[string]string{"project": "/src/old"}, 9),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-90 * time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"session.id": "s1", "cwd": "/src/moved"}},
+		{Timestamp: now.Add(-15 * time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"session.id": "s1", "cwd": "/src/web"}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	projects, err := store.GetCostByProject(ctx, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetCostByProject failed: %v", err)
+	}
+
+	want := []api.ProjectCost{
+		{Project: "/src/api", CostUSD: 3.5},
+		{Project: "/src/web", CostUSD: 0.75 + 2.0},
+		{Project: unknownProject, CostUSD: 0.25},
+	}
+	if len(projects) != len(want) {
+		t.Fatalf("expected %d projects, got %+v", len(want), projects)
+	}
+	for i, w := range want {
+		if projects[i] != w {
+			t.Errorf("project %d = %+v, want %+v", i, projects[i], w)
+		}
+	}
+}
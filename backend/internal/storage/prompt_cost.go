@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// promptPatternLength is the number of characters of a normalized prompt kept as its pattern
+const promptPatternLength = 120
+
+// Rewrites applied in order by normalizePrompt; earlier rules win over later ones, so a UUID
+// inside a path becomes part of <path> and a number inside a UUID is not rewritten separately
+var promptPatternRules = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[a-z][a-z0-9+.-]*://\S+`), "<url>"},
+	{regexp.MustCompile(`(?:~|\.{1,2})?(?:/[\w.@-]+)+/?`), "<path>"},
+	{regexp.MustCompile(`\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<id>"},
+	{regexp.MustCompile(`\b[0-9a-f]*[0-9][0-9a-f]*[a-f][0-9a-f]*\b|\b[0-9a-f]*[a-f][0-9a-f]*[0-9][0-9a-f]*\b`), "<id>"},
+	{regexp.MustCompile("\"[^\"]*\"|'[^']*'|`[^`]*`"), "<str>"},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), "<n>"},
+}
+
+// normalizePrompt reduces a prompt to a pattern shared by prompts that differ only in
+// variable parts: it is lowercased, URLs, paths, IDs, quoted strings and numbers are replaced
+// by placeholders, whitespace is collapsed and the result is cut to promptPatternLength
+func normalizePrompt(prompt string) string {
+	pattern := strings.ToLower(prompt)
+	for _, rule := range promptPatternRules {
+		pattern = rule.re.ReplaceAllString(pattern, rule.replacement)
+	}
+	return truncatePreview(pattern, promptPatternLength)
+}
+
+// GetPromptCostBreakdown groups the user prompts sent within [from, to] by normalized pattern
+// (see normalizePrompt) and returns how often each pattern was sent and what it cost, most
+// expensive first.
+//
+// Prompts are the user prompt logs also used for session previews. Their session comes from
+// the log's session.id/conversation.id attribute or, failing that, from a span of the
+// prompt's trace. Each *.cost.usage point of a session within [from, to] is attributed to the
+// latest prompt of that session sent at or before it, with cumulative series contributing
+// their increase since the previous point in range, as in GetLatencyCostScatter.
+func (s *DuckDBStore) GetPromptCostBreakdown(ctx context.Context, from, to time.Time) ([]api.PromptCost, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	query := `
+		WITH prompts AS (
+			SELECT
+				ROW_NUMBER() OVER (ORDER BY l.Timestamp, l.ServiceName) as prompt_id,
+				l.Timestamp,
+				COALESCE(NULLIF(json_extract_string(l.LogAttributes, '$.prompt'), ''), l.Body) as prompt,
+				COALESCE(l.session_id, (
+					SELECT FIRST(COALESCE(
+						json_extract_string(t.SpanAttributes, '$."session.id"'),
+						json_extract_string(t.SpanAttributes, '$."conversation.id"')
+					))
+					FROM otel_traces t
+					WHERE t.TraceId = l.TraceId AND l.TraceId != '' AND COALESCE(
+						json_extract_string(t.SpanAttributes, '$."session.id"'),
+						json_extract_string(t.SpanAttributes, '$."conversation.id"')
+					) IS NOT NULL
+				)) as session_id
+			FROM (
+				SELECT *, ` + sessionIDExpr + ` as session_id
+				FROM otel_logs
+				WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				  AND (
+					json_extract_string(LogAttributes, '$."event.name"') IN ('user_prompt', 'codex.user_prompt', 'gemini_cli.user_prompt')
+					OR (json_extract_string(LogAttributes, '$."event.name"') = 'transcript.message'
+						AND json_extract_string(LogAttributes, '$."message.role"') = 'user')
+				  )
+			) l
+		),
+		cost_points AS (
+			SELECT
+				session_id,
+				Timestamp,
+				CASE WHEN AggregationTemporality = 2
+					THEN COALESCE(value - LAG(value) OVER (
+						PARTITION BY ServiceName, MetricName, CAST(Attributes AS VARCHAR) ORDER BY Timestamp
+					), 0)
+					ELSE value
+				END as cost
+			FROM (
+				SELECT
+					ServiceName, MetricName, Attributes, Timestamp, AggregationTemporality,
+					COALESCE(Value, Sum) as value,
+					COALESCE(
+						json_extract_string(Attributes, '$."session.id"'),
+						json_extract_string(Attributes, '$."conversation.id"')
+					) as session_id
+				FROM otel_metrics
+				WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+					AND MetricName LIKE '%.cost.usage'
+			)
+			WHERE session_id IS NOT NULL
+		),
+		prompt_costs AS (
+			SELECT p.prompt_id, SUM(c.cost) as cost
+			FROM cost_points c
+			ASOF JOIN prompts p ON c.session_id = p.session_id AND c.Timestamp >= p.Timestamp
+			GROUP BY p.prompt_id
+		)
+		SELECT p.prompt, p.Timestamp, COALESCE(pc.cost, 0)
+		FROM prompts p
+		LEFT JOIN prompt_costs pc ON pc.prompt_id = p.prompt_id
+		WHERE p.prompt IS NOT NULL AND p.prompt != ''
+		ORDER BY p.Timestamp
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, fromStr, toStr, fromStr, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("querying prompt costs: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[string]int)
+	result := []api.PromptCost{}
+	for rows.Next() {
+		var prompt string
+		var ts time.Time
+		var cost float64
+		if err := rows.Scan(&prompt, &ts, &cost); err != nil {
+			return nil, fmt.Errorf("scanning prompt cost: %w", err)
+		}
+
+		pattern := normalizePrompt(prompt)
+		i, ok := index[pattern]
+		if !ok {
+			i = len(result)
+			index[pattern] = i
+			result = append(result, api.PromptCost{Pattern: pattern})
+		}
+		// Rows are oldest first, so the last prompt seen is the most recent example
+		result[i].Count++
+		result[i].CostUSD += cost
+		result[i].Example = truncatePreview(prompt, sessionPreviewLength)
+		result[i].LastSeen = ts
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating prompt costs: %w", err)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].CostUSD != result[j].CostUSD {
+			return result[i].CostUSD > result[j].CostUSD
+		}
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Pattern < result[j].Pattern
+	})
+
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetPromptCostBreakdown(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+
+	prompt := func(ts time.Time, service, event, session, traceID, text string) api.LogRecord {
+		attrs := map[string]string{"event.name": event, "prompt": text}
+		if session != "" {
+			attrs["session.id"] = session
+		}
+		return api.LogRecord{Timestamp: ts, ServiceName: service, TraceID: traceID, Body: event, LogAttributes: attrs}
+	}
+	logs := []api.LogRecord{
+		// The same request with different numbers and paths, in two sessions
+		prompt(now.Add(-50*time.Minute), "claude-code", "user_prompt", "s1", "", "Fix the failing test in /src/app/main.go line 42"),
+		prompt(now.Add(-40*time.Minute), "claude-code", "user_prompt", "s1", "", "Explain this code"),
+		prompt(now.Add(-30*time.Minute), "codex_cli_rs", "codex.user_prompt", "s2", "", "fix the failing test in  /lib/util.go line 7"),
+		// Session resolved through a span of the prompt's trace
+		prompt(now.Add(-20*time.Minute), "gemini_cli", "gemini_cli.user_prompt", "", "t3", "Fix the failing test in ./pkg/x_test.go line 3"),
+		// Not a user prompt
+		{Timestamp: now.Add(-45 * time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{"event.name": "api_request", "session.id": "s1"}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+	spans := []api.Span{
+		{TraceID: "t3", SpanID: "t3-root", ServiceName: "gemini_cli", SpanName: "request", Timestamp: now.Add(-20 * time.Minute), SpanAttributes: map[string]string{"session.id": "s3"}},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	point := func(ts time.Time, service string, temporality *int32, session string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: ts, ServiceName: service, MetricName: service + ".cost.usage", MetricType: "sum",
+			AggregationTemporality: temporality, Attributes: map[string]string{"session.id": session}, Value: &v,
+		}
+	}
+	metrics := []api.MetricDataPoint{
+		// Delta points go to the latest prompt of the session sent before them
+		point(now.Add(-48*time.Minute), "claude-code", nil, "s1", 0.25),
+		point(now.Add(-46*time.Minute), "claude-code", nil, "s1", 0.25),
+		point(now.Add(-39*time.Minute), "claude-code", nil, "s1", 0.10),
+		// Cumulative points contribute their increase
+		point(now.Add(-29*time.Minute), "codex_cli_rs", &cumulative, "s2", 1.0),
+		point(now.Add(-28*time.Minute), "codex_cli_rs", &cumulative, "s2", 1.5),
+		point(now.Add(-19*time.Minute), "gemini_cli", nil, "s3", 2.0),
+		// Cost before any prompt of a session is ignored
+		point(now.Add(-55*time.Minute), "claude-code", nil, "s1", 9),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	prompts, err := store.GetPromptCostBreakdown(ctx, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetPromptCostBreakdown failed: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 prompt patterns, got %+v", prompts)
+	}
+
+	fix := prompts[0]
+	if fix.Pattern != "fix the failing test in <path> line <n>" {
+		t.Errorf("unexpected pattern %q", fix.Pattern)
+	}
+	if fix.Count != 3 || math.Abs(fix.CostUSD-3.0) > 1e-9 {
+		t.Errorf("expected 3 prompts costing 3.0, got %d costing %v", fix.Count, fix.CostUSD)
+	}
+	if fix.Example != "Fix the failing test in ./pkg/x_test.go line 3" || !fix.LastSeen.Equal(now.Add(-20*time.Minute)) {
+		t.Errorf("expected the latest prompt as example, got %q at %v", fix.Example, fix.LastSeen)
+	}
+
+	explain := prompts[1]
+	if explain.Pattern != "explain this code" || explain.Count != 1 || math.Abs(explain.CostUSD-0.10) > 1e-9 {
+		t.Errorf("unexpected pattern: %+v", explain)
+	}
+}
+
+func TestNormalizePrompt(t *testing.T) {
+	tests := map[string]string{
+		"Open https://example.com/a?b=1 now":                  "open <url> now",
+		"Delete session 3f2b8c1e-0a4d-4e5f-9b6c-7d8e9f0a1b2c": "delete session <id>",
+		"Revert commit a1b2c3d4e5":                            "revert commit <id>",
+		`Rename "foo" to 'bar'`:                               "rename <str> to <str>",
+		"Bump   version to 1.2.3\nplease":                     "bump version to <n>.<n> please",
+		"Read ~/notes/todo.md and /etc/hosts":                 "read <path> and <path>",
+		"Add 12 tests and 3.5 docs":                           "add <n> tests and <n> docs",
+	}
+	for input, want := range tests {
+		if got := normalizePrompt(input); got != want {
+			t.Errorf("normalizePrompt(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// rangeFilter returns the WHERE clause selecting rows with a timestamp in [from, to],
+// optionally limited to one service
+func rangeFilter(from, to time.Time, service string) (string, []interface{}) {
+	where := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	if service != "" {
+		where += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	return where, args
+}
+
+// streamPageSize is the number of rows the Stream*InRange functions read per page
+const streamPageSize = 5000
+
+// Row keys ordering the rows that share a timestamp. Unlike rowid they do not change when
+// other rows are deleted or the database is checkpointed, so they can mark where a page ended.
+const (
+	spanRowKey   = "hash(TraceId, SpanId)"
+	logRowKey    = "hash(TraceId, SpanId, Body)"
+	metricRowKey = "hash(ServiceName, MetricName, Attributes)"
+)
+
+// streamPages reads the columns of the table's rows matching where in (Timestamp, key) order,
+// one page of about streamPageSize rows at a time. Each page runs under its own read lock, so
+// writers are not blocked for a whole export; rows written or deleted between pages may or
+// may not be included. Rows with the same timestamp and key are kept in one page. run
+// executes a page query and is called with the read lock held.
+func (s *DuckDBStore) streamPages(ctx context.Context, table, columns, key, where string, args []interface{}, run func(query string, args []interface{}) error) error {
+	var after []interface{}
+	for {
+		last, err := func() (bool, error) {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+
+			cond := where
+			condArgs := append([]interface{}{}, args...)
+			if after != nil {
+				cond += fmt.Sprintf(" AND (Timestamp > ?::TIMESTAMP OR (Timestamp = ?::TIMESTAMP AND %s > ?::UBIGINT))", key)
+				condArgs = append(condArgs, after...)
+			}
+
+			// The last key of the page, or none when the rest fits in it
+			var ts, k string
+			err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+				SELECT CAST(Timestamp AS VARCHAR), CAST(%[1]s AS VARCHAR)
+				FROM %[2]s
+				WHERE %[3]s
+				ORDER BY Timestamp, %[1]s
+				LIMIT 1 OFFSET %[4]d
+			`, key, table, cond, streamPageSize-1), condArgs...).Scan(&ts, &k)
+			if err != nil && err != sql.ErrNoRows {
+				return false, fmt.Errorf("paging %s: %w", table, err)
+			}
+			last := err == sql.ErrNoRows
+			if !last {
+				cond += fmt.Sprintf(" AND (Timestamp < ?::TIMESTAMP OR (Timestamp = ?::TIMESTAMP AND %s <= ?::UBIGINT))", key)
+				condArgs = append(condArgs, ts, ts, k)
+				after = []interface{}{ts, ts, k}
+			}
+
+			query := fmt.Sprintf(`
+				SELECT %s
+				FROM %s
+				WHERE %s
+				ORDER BY Timestamp, %s
+			`, columns, table, cond, key)
+			return last, run(query, condArgs)
+		}()
+		if err != nil || last {
+			return err
+		}
+	}
+}
+
+// StreamSpansInRange calls fn for each span with a timestamp in [from, to] in timestamp order,
+// including its events and links. An empty service selects all services. Spans are read in
+// pages (see streamPages); iteration stops when ctx is cancelled or fn returns an error.
+func (s *DuckDBStore) StreamSpansInRange(ctx context.Context, from, to time.Time, service string, fn func(api.Span) error) error {
+	where, args := rangeFilter(from, to, service)
+	return s.streamPages(ctx, "otel_traces", traceSpanColumns, spanRowKey, where, args, func(query string, args []interface{}) error {
+		return s.iterateSpans(ctx, query, fn, args...)
+	})
+}
+
+// spanEvents rebuilds span events from the parallel Events.* JSON array columns
+func spanEvents(timestamps, names, attrs sql.NullString) ([]api.SpanEvent, error) {
+	var ts []time.Time
+	var ns []string
+	var as []map[string]string
+	if err := unmarshalJSONColumn(timestamps, &ts); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSONColumn(names, &ns); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSONColumn(attrs, &as); err != nil {
+		return nil, err
+	}
+
+	var events []api.SpanEvent
+	for i, name := range ns {
+		event := api.SpanEvent{Name: name}
+		if i < len(ts) {
+			event.Timestamp = ts[i]
+		}
+		if i < len(as) {
+			event.Attributes = as[i]
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// spanLinks rebuilds span links from the parallel Links.* JSON array columns
+func spanLinks(traceIDs, spanIDs, traceStates, attrs sql.NullString) ([]api.SpanLink, error) {
+	var tids, sids, states []string
+	var as []map[string]string
+	if err := unmarshalJSONColumn(traceIDs, &tids); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSONColumn(spanIDs, &sids); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSONColumn(traceStates, &states); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSONColumn(attrs, &as); err != nil {
+		return nil, err
+	}
+
+	var links []api.SpanLink
+	for i, traceID := range tids {
+		link := api.SpanLink{TraceID: traceID}
+		if i < len(sids) {
+			link.SpanID = sids[i]
+		}
+		if i < len(states) {
+			link.TraceState = states[i]
+		}
+		if i < len(as) {
+			link.Attributes = as[i]
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// StreamLogsInRange calls fn for each log record with a timestamp in [from, to] in timestamp
+// order. An empty service selects all services. Logs are read in pages (see streamPages);
+// iteration stops when ctx is cancelled or fn returns an error.
+func (s *DuckDBStore) StreamLogsInRange(ctx context.Context, from, to time.Time, service string, fn func(api.LogRecord) error) error {
+	where, args := rangeFilter(from, to, service)
+	return s.streamPages(ctx, "otel_logs", logRecordColumns, logRowKey, where, args, func(query string, args []interface{}) error {
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("querying logs: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			log, _, err := scanLogRow(rows, false)
+			if err != nil {
+				return err
+			}
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterating logs: %w", err)
+		}
+		return nil
+	})
+}
+
+// StreamMetricsInRange calls fn for each metric data point with a timestamp in [from, to] in
+// timestamp order, including histogram, exponential histogram and summary fields. An empty
+// service selects all services. Points are read in pages (see streamPages); iteration stops
+// when ctx is cancelled or fn returns an error.
+func (s *DuckDBStore) StreamMetricsInRange(ctx context.Context, from, to time.Time, service string, fn func(api.MetricDataPoint) error) error {
+	where, args := rangeFilter(from, to, service)
+	return s.streamPages(ctx, "otel_metrics", metricPointColumns, metricRowKey, where, args, func(query string, args []interface{}) error {
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("querying metrics: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			m, err := scanMetricRow(rows)
+			if err != nil {
+				return err
+			}
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterating metrics: %w", err)
+		}
+		return nil
+	})
+}
+
+// logRecordColumns are the otel_logs columns read by scanLogRow
+const logRecordColumns = `
+			Timestamp, TraceId, SpanId, TraceFlags, SeverityText,
+			SeverityNumber, ServiceName, Body, ResourceSchemaUrl,
+			ResourceAttributes, ScopeSchemaUrl, ScopeName, ScopeVersion,
+			ScopeAttributes, LogAttributes, DroppedAttributesCount`
+
+// metricPointColumns are the otel_metrics columns read by scanMetricRow
+const metricPointColumns = `
+			Timestamp, ServiceName, MetricName, MetricDescription, MetricUnit,
+			ResourceAttributes, ScopeName, ScopeVersion, Attributes, MetricType,
+			Value, AggregationTemporality, IsMonotonic, Count, Sum,
+			CAST(BucketCounts AS VARCHAR), CAST(ExplicitBounds AS VARCHAR),
+			Scale, ZeroCount, PositiveOffset, CAST(PositiveBucketCounts AS VARCHAR),
+			NegativeOffset, CAST(NegativeBucketCounts AS VARCHAR),
+			CAST(QuantileValues AS VARCHAR), CAST(QuantileQuantiles AS VARCHAR),
+			Min, Max, ScopeAttributes`
+
+// scanMetricRow reads the current row of a query selecting metricPointColumns
+func scanMetricRow(rows *sql.Rows) (api.MetricDataPoint, error) {
+	var m api.MetricDataPoint
+	var desc, unit, scopeName, scopeVersion sql.NullString
+	var resourceAttrs, attrs, scopeAttrs interface{}
+	var value, sum, min, max sql.NullFloat64
+	var aggregationTemporality, scale, positiveOffset, negativeOffset sql.NullInt32
+	var isMonotonic sql.NullBool
+	var count, zeroCount sql.NullInt64
+	var bucketCounts, explicitBounds, positiveBuckets, negativeBuckets sql.NullString
+	var quantileValues, quantileQuantiles sql.NullString
+
+	if err := rows.Scan(
+		&m.Timestamp, &m.ServiceName, &m.MetricName, &desc, &unit,
+		&resourceAttrs, &scopeName, &scopeVersion, &attrs, &m.MetricType,
+		&value, &aggregationTemporality, &isMonotonic, &count, &sum,
+		&bucketCounts, &explicitBounds,
+		&scale, &zeroCount, &positiveOffset, &positiveBuckets,
+		&negativeOffset, &negativeBuckets,
+		&quantileValues, &quantileQuantiles,
+		&min, &max, &scopeAttrs,
+	); err != nil {
+		return api.MetricDataPoint{}, fmt.Errorf("scanning metric: %w", err)
+	}
+
+	m.MetricDescription = desc.String
+	m.MetricUnit = unit.String
+	m.ScopeName = scopeName.String
+	m.ScopeVersion = scopeVersion.String
+	m.ResourceAttributes = scanJSONToMap(resourceAttrs)
+	m.Attributes = scanJSONToMap(attrs)
+	m.ScopeAttributes = scanJSONToMap(scopeAttrs)
+	m.Value = float64Ptr(value)
+	m.Sum = float64Ptr(sum)
+	m.Min = float64Ptr(min)
+	m.Max = float64Ptr(max)
+	m.AggregationTemporality = int32Ptr(aggregationTemporality)
+	m.Scale = int32Ptr(scale)
+	m.PositiveOffset = int32Ptr(positiveOffset)
+	m.NegativeOffset = int32Ptr(negativeOffset)
+	m.Count = uint64Ptr(count)
+	m.ZeroCount = uint64Ptr(zeroCount)
+	if isMonotonic.Valid {
+		m.IsMonotonic = &isMonotonic.Bool
+	}
+
+	for _, col := range []struct {
+		value sql.NullString
+		dest  interface{}
+	}{
+		{bucketCounts, &m.BucketCounts},
+		{explicitBounds, &m.ExplicitBounds},
+		{positiveBuckets, &m.PositiveBucketCounts},
+		{negativeBuckets, &m.NegativeBucketCounts},
+		{quantileValues, &m.QuantileValues},
+		{quantileQuantiles, &m.QuantileQuantiles},
+	} {
+		if err := unmarshalJSONColumn(col.value, col.dest); err != nil {
+			return api.MetricDataPoint{}, fmt.Errorf("reading metric %s: %w", m.MetricName, err)
+		}
+	}
+
+	return m, nil
+}
+
+// unmarshalJSONColumn decodes a JSON column read as VARCHAR into v; NULL leaves v unchanged
+func unmarshalJSONColumn(col sql.NullString, v interface{}) error {
+	if !col.Valid || col.String == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(col.String), v); err != nil {
+		return fmt.Errorf("parsing JSON array: %w", err)
+	}
+	return nil
+}
+
+// float64Ptr, int32Ptr and uint64Ptr convert scanned nullable columns to the optional
+// pointer fields of api.MetricDataPoint, the reverse of nullFloat64 and friends
+func float64Ptr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func int32Ptr(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func uint64Ptr(v sql.NullInt64) *uint64 {
+	if !v.Valid {
+		return nil
+	}
+	u := uint64(v.Int64)
+	return &u
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestStreamSpansInRange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	spans := []api.Span{
+		{
+			TraceID: "t1", SpanID: "s1", ServiceName: "claude-code", SpanName: "tool", Timestamp: now,
+			SpanAttributes: map[string]string{"tool": "Bash"},
+			Events: []api.SpanEvent{
+				{Timestamp: now.Add(time.Millisecond), Name: "start", Attributes: map[string]string{"k": "v"}},
+				{Timestamp: now.Add(2 * time.Millisecond), Name: "end"},
+			},
+			Links: []api.SpanLink{{TraceID: "t0", SpanID: "s0", TraceState: "a=1", Attributes: map[string]string{"l": "x"}}},
+		},
+		{TraceID: "t2", SpanID: "s2", ServiceName: "codex_cli_rs", SpanName: "other", Timestamp: now},
+		{TraceID: "t3", SpanID: "s3", ServiceName: "claude-code", SpanName: "old", Timestamp: now.Add(-48 * time.Hour)},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	var got []api.Span
+	err := store.StreamSpansInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), "claude-code", func(span api.Span) error {
+		got = append(got, span)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamSpansInRange failed: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 span, got %+v", got)
+	}
+	span := got[0]
+	if span.SpanID != "s1" || span.SpanAttributes["tool"] != "Bash" {
+		t.Errorf("unexpected span: %+v", span)
+	}
+	if len(span.Events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", span.Events)
+	}
+	if span.Events[0].Name != "start" || span.Events[0].Attributes["k"] != "v" || !span.Events[0].Timestamp.Equal(now.Add(time.Millisecond)) {
+		t.Errorf("unexpected first event: %+v", span.Events[0])
+	}
+	if span.Events[1].Name != "end" || len(span.Events[1].Attributes) != 0 {
+		t.Errorf("unexpected second event: %+v", span.Events[1])
+	}
+	wantLinks := []api.SpanLink{{TraceID: "t0", SpanID: "s0", TraceState: "a=1", Attributes: map[string]string{"l": "x"}}}
+	if !reflect.DeepEqual(span.Links, wantLinks) {
+		t.Errorf("expected links %+v, got %+v", wantLinks, span.Links)
+	}
+}
+
+func TestStreamLogsInRange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(time.Second), ServiceName: "claude-code", Body: "second", LogAttributes: map[string]string{"event.name": "api_request"}},
+		{Timestamp: now, ServiceName: "claude-code", Body: "first"},
+		{Timestamp: now.Add(-48 * time.Hour), ServiceName: "claude-code", Body: "old"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	var bodies []string
+	var attrs map[string]string
+	err := store.StreamLogsInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), "", func(log api.LogRecord) error {
+		bodies = append(bodies, log.Body)
+		if log.Body == "second" {
+			attrs = log.LogAttributes
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamLogsInRange failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(bodies, []string{"first", "second"}) {
+		t.Errorf("expected logs in timestamp order, got %v", bodies)
+	}
+	if attrs["event.name"] != "api_request" {
+		t.Errorf("expected log attributes, got %v", attrs)
+	}
+}
+
+func TestStreamLogsInRange_Pages(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Two and a half pages, one log per microsecond, with identical logs around the end of
+	// the first page
+	n := 2*streamPageSize + streamPageSize/2
+	_, err := store.db.ExecContext(ctx, `
+		INSERT INTO otel_logs (Timestamp, ServiceName, Body, TraceFlags, SeverityNumber)
+		SELECT
+			CASE WHEN duplicate THEN ?::TIMESTAMP ELSE ?::TIMESTAMP + to_microseconds(i) END,
+			'svc',
+			CASE WHEN duplicate THEN 'duplicate' ELSE 'log ' || i END,
+			0, 0
+		FROM (SELECT range AS i, range BETWEEN ? AND ? AS duplicate FROM range(?))
+	`, formatTimeForDB(now), formatTimeForDB(now), streamPageSize-10, streamPageSize+9, n)
+	if err != nil {
+		t.Fatalf("inserting logs: %v", err)
+	}
+
+	// A write started during the first page goes through before the next one is read
+	written := make(chan error, 1)
+	var count, duplicates int
+	var writtenBeforeLast bool
+	err = store.StreamLogsInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), "", func(log api.LogRecord) error {
+		switch count {
+		case 0:
+			go func() {
+				written <- store.InsertLogs(ctx, []api.LogRecord{{Timestamp: now.Add(-time.Minute), ServiceName: "other", Body: "late"}})
+			}()
+			time.Sleep(50 * time.Millisecond)
+		case n - 1:
+			select {
+			case err := <-written:
+				writtenBeforeLast = err == nil
+			default:
+			}
+		}
+		count++
+		if log.Body == "duplicate" {
+			duplicates++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamLogsInRange failed: %v", err)
+	}
+
+	if count != n || duplicates != 20 {
+		t.Errorf("expected %d logs with 20 duplicates, got %d with %d", n, count, duplicates)
+	}
+	if !writtenBeforeLast {
+		t.Error("expected the concurrent insert to complete while streaming")
+	}
+}
+
+func TestStreamMetricsInRange(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	count := uint64(3)
+	sum := 12.5
+	scale := int32(2)
+
+	metrics := []api.MetricDataPoint{
+		{
+			Timestamp: now, ServiceName: "claude-code", MetricName: "latency", MetricType: "histogram",
+			Attributes: map[string]string{"model": "m1"}, Count: &count, Sum: &sum,
+			BucketCounts: []uint64{1, 2}, ExplicitBounds: []float64{10},
+		},
+		{
+			Timestamp: now, ServiceName: "claude-code", MetricName: "size", MetricType: "exponential_histogram",
+			Scale: &scale, PositiveBucketCounts: []uint64{4, 5},
+		},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	got := make(map[string]api.MetricDataPoint)
+	err := store.StreamMetricsInRange(ctx, now.Add(-time.Hour), now.Add(time.Hour), "claude-code", func(m api.MetricDataPoint) error {
+		got[m.MetricName] = m
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamMetricsInRange failed: %v", err)
+	}
+
+	latency := got["latency"]
+	if latency.Count == nil || *latency.Count != 3 || latency.Sum == nil || *latency.Sum != 12.5 {
+		t.Errorf("unexpected histogram count/sum: %+v", latency)
+	}
+	if !reflect.DeepEqual(latency.BucketCounts, []uint64{1, 2}) || !reflect.DeepEqual(latency.ExplicitBounds, []float64{10}) {
+		t.Errorf("unexpected histogram buckets: %+v", latency)
+	}
+	if latency.Attributes["model"] != "m1" {
+		t.Errorf("expected attributes, got %v", latency.Attributes)
+	}
+
+	size := got["size"]
+	if size.Scale == nil || *size.Scale != 2 || !reflect.DeepEqual(size.PositiveBucketCounts, []uint64{4, 5}) {
+		t.Errorf("unexpected exponential histogram: %+v", size)
+	}
+	if size.Value != nil || size.Count != nil {
+		t.Errorf("expected unset fields to stay nil: %+v", size)
+	}
+}
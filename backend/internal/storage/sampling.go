@@ -0,0 +1,33 @@
+package storage
+
+// spanAdjustedCount is the number of spans a stored span stands for under OpenTelemetry
+// probability sampling, read from its W3C tracestate: "ot=th:<hex>" carries the rejection
+// threshold T out of 2^56 (sampling probability 1 - T/2^56) and the older "ot=p:<n>" a
+// probability of 2^-n. Spans without sampling information count once.
+const spanAdjustedCount = `COALESCE(
+	72057594037927936.0 / (72057594037927936 - ('0x' || rpad(NULLIF(
+		regexp_extract(TraceState, '(?:^|,)\s*ot=(?:[^,]*;)?th:([0-9a-f]{1,14})', 1), ''), 14, '0'))::BIGINT),
+	pow(2, TRY_CAST(NULLIF(regexp_extract(TraceState, '(?:^|,)\s*ot=(?:[^,]*;)?p:([0-9]{1,2})', 1), '') AS INTEGER)),
+	1.0
+)`
+
+// spanCountExpr counts the spans of a query, extrapolated by their sampling probability when
+// extrapolate is set
+func spanCountExpr(extrapolate bool) string {
+	if !extrapolate {
+		return "COUNT(*)"
+	}
+	return "CAST(COALESCE(ROUND(SUM(" + spanAdjustedCount + ")), 0) AS BIGINT)"
+}
+
+// traceCountExpr counts the distinct traces in relation (a table or parenthesized subquery of
+// spans), extrapolated when extrapolate is set. A head-sampled trace shares one sampling
+// decision, so each trace is weighted by the adjusted count of its earliest span.
+func traceCountExpr(relation string, extrapolate bool) string {
+	if !extrapolate {
+		return "SELECT COUNT(DISTINCT TraceId) FROM " + relation
+	}
+	return `SELECT CAST(COALESCE(ROUND(SUM(weight)), 0) AS BIGINT) FROM (
+		SELECT arg_min(` + spanAdjustedCount + `, Timestamp) as weight FROM ` + relation + ` GROUP BY TraceId
+	)`
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// insertSampledSpans stores spans of service "api" sampled at different probabilities: an
+// unsampled trace of 2 spans, a trace of 2 spans at 1/2, a span at 1/4 (th:c) and a span at
+// 1/8 (legacy p:3) with an error
+func insertSampledSpans(t *testing.T, store *DuckDBStore, now time.Time) {
+	t.Helper()
+	span := func(trace, id, traceState, status string) api.Span {
+		return api.Span{
+			Timestamp: now, TraceID: trace, SpanID: id, TraceState: traceState,
+			SpanName: "op", ServiceName: "api", StatusCode: status,
+		}
+	}
+	spans := []api.Span{
+		span("t1", "a", "", "OK"),
+		span("t1", "b", "vendor=x", "OK"),
+		span("t2", "c", "ot=th:8", "OK"),
+		span("t2", "d", "ot=rv:abcdef12345678;th:8", "OK"),
+		span("t3", "e", "vendor=x,ot=th:c", "OK"),
+		span("t4", "f", "ot=p:3", "ERROR"),
+	}
+	if err := store.InsertSpans(context.Background(), spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+}
+
+func TestGetExtrapolatedStats(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	insertSampledSpans(t, store, time.Now())
+
+	raw, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if raw.SpanCount != 6 || raw.TraceCount != 4 || raw.Extrapolated {
+		t.Errorf("expected 6 raw spans in 4 traces, got %+v", raw)
+	}
+
+	stats, err := store.GetExtrapolatedStats(ctx)
+	if err != nil {
+		t.Fatalf("GetExtrapolatedStats failed: %v", err)
+	}
+	// Spans: 1 + 1 + 2 + 2 + 4 + 8; traces: 1 + 2 + 4 + 8
+	if stats.SpanCount != 18 || stats.TraceCount != 15 || !stats.Extrapolated {
+		t.Errorf("expected 18 spans in 15 traces extrapolated, got %+v", stats)
+	}
+	if want := 8.0 / 18 * 100; stats.ErrorRate != want {
+		t.Errorf("ErrorRate = %v, want %v", stats.ErrorRate, want)
+	}
+}
+
+func TestGetServiceSummary_Extrapolated(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	insertSampledSpans(t, store, now)
+
+	summary, err := store.GetServiceSummary(ctx, "api", now.Add(-time.Hour), now.Add(time.Hour), true)
+	if err != nil {
+		t.Fatalf("GetServiceSummary failed: %v", err)
+	}
+	if summary.SpanCount != 18 || summary.TraceCount != 15 || summary.ErrorCount != 8 || !summary.Extrapolated {
+		t.Errorf("expected 18 spans, 15 traces and 8 errors extrapolated, got %+v", summary)
+	}
+	if len(summary.TopOperations) != 1 || summary.TopOperations[0].Count != 18 || summary.TopOperations[0].ErrorCount != 8 {
+		t.Errorf("expected the operation's counts extrapolated, got %+v", summary.TopOperations)
+	}
+
+	summary, err = store.GetServiceSummary(ctx, "api", now.Add(-time.Hour), now.Add(time.Hour), false)
+	if err != nil {
+		t.Fatalf("GetServiceSummary failed: %v", err)
+	}
+	if summary.SpanCount != 6 || summary.TraceCount != 4 || summary.ErrorCount != 1 || summary.TopOperations[0].Count != 6 {
+		t.Errorf("expected raw counts, got %+v", summary)
+	}
+}
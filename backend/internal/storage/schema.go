@@ -23,7 +23,11 @@ CREATE TABLE IF NOT EXISTS otel_traces (
     "Links.TraceId"         JSON,
     "Links.SpanId"          JSON,
     "Links.TraceState"      JSON,
-    "Links.Attributes"      JSON
+    "Links.Attributes"      JSON,
+    ScopeAttributes         JSON,
+    DroppedAttributesCount  UINTEGER,
+    DroppedEventsCount      UINTEGER,
+    DroppedLinksCount       UINTEGER
 );
 `
 
@@ -43,7 +47,8 @@ CREATE TABLE IF NOT EXISTS otel_logs (
     ScopeName               VARCHAR,
     ScopeVersion            VARCHAR,
     ScopeAttributes         JSON,
-    LogAttributes           JSON
+    LogAttributes           JSON,
+    DroppedAttributesCount  UINTEGER
 );
 `
 
@@ -75,10 +80,58 @@ CREATE TABLE IF NOT EXISTS otel_metrics (
     QuantileValues          JSON,
     QuantileQuantiles       JSON,
     Min                     DOUBLE,
-    Max                     DOUBLE
+    Max                     DOUBLE,
+    ScopeAttributes         JSON
 );
 `
 
+// migrateScopeAttributes adds scope attribute columns to databases created before they existed.
+// Columns are appended last so fresh and migrated tables share the same column order.
+const migrateScopeAttributes = `
+ALTER TABLE otel_traces ADD COLUMN IF NOT EXISTS ScopeAttributes JSON;
+ALTER TABLE otel_metrics ADD COLUMN IF NOT EXISTS ScopeAttributes JSON;
+`
+
+// migrateDroppedCounts adds the OTLP dropped attribute/event/link count columns to databases
+// created before they existed. NULL in older rows means the count was not recorded.
+const migrateDroppedCounts = `
+ALTER TABLE otel_traces ADD COLUMN IF NOT EXISTS DroppedAttributesCount UINTEGER;
+ALTER TABLE otel_traces ADD COLUMN IF NOT EXISTS DroppedEventsCount UINTEGER;
+ALTER TABLE otel_traces ADD COLUMN IF NOT EXISTS DroppedLinksCount UINTEGER;
+ALTER TABLE otel_logs ADD COLUMN IF NOT EXISTS DroppedAttributesCount UINTEGER;
+`
+
+// migrateImportStateCounts adds the per-signal record counts of an imported file to databases
+// created before they existed. NULL in older rows means the counts are unknown.
+const migrateImportStateCounts = `
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS log_count INTEGER;
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS metric_count INTEGER;
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS span_count INTEGER;
+`
+
+// migrateImportStateCheckpoint adds where the last parse of an imported file stopped, so an
+// appended session file can be parsed from there. NULL means the file is parsed in full.
+const migrateImportStateCheckpoint = `
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS file_offset BIGINT;
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS line_count INTEGER;
+ALTER TABLE import_state ADD COLUMN IF NOT EXISTS parser_state VARCHAR;
+`
+
+// migrateDashboardVariables adds the dashboard template variables, stored as a JSON array
+const migrateDashboardVariables = `
+ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS variables JSON;
+`
+
+// migrateLogEventBodies restores the empty body of log records that ingestion used to store
+// with their event.name as Body, now that bodies are stored as received and the event name is
+// only shown in their place at query time. Imported records set their Body deliberately and
+// carry import_source, so they are left alone.
+const migrateLogEventBodies = `
+UPDATE otel_logs SET Body = NULL
+WHERE Body = json_extract_string(LogAttributes, '$."event.name"')
+    AND json_extract_string(LogAttributes, '$.import_source') IS NULL;
+`
+
 const indexTraces = `
 CREATE INDEX IF NOT EXISTS idx_traces_timestamp ON otel_traces(Timestamp);
 CREATE INDEX IF NOT EXISTS idx_traces_trace_id ON otel_traces(TraceId);
@@ -142,6 +195,57 @@ CREATE TABLE IF NOT EXISTS import_state (
 );
 `
 
+// schemaAnnotations holds notes and tags users attach to traces and logs
+const schemaAnnotations = `
+CREATE TABLE IF NOT EXISTS annotations (
+    id              VARCHAR PRIMARY KEY,
+    target_type     VARCHAR NOT NULL,
+    target_id       VARCHAR NOT NULL,
+    note            VARCHAR,
+    tags            JSON,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const indexAnnotations = `
+CREATE INDEX IF NOT EXISTS idx_annotations_target ON annotations(target_type, target_id);
+`
+
+// schemaAlertRules holds metric threshold rules checked by the alert evaluator
+const schemaAlertRules = `
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id              VARCHAR PRIMARY KEY,
+    name            VARCHAR NOT NULL,
+    metric_name     VARCHAR NOT NULL,
+    service         VARCHAR,
+    comparison      VARCHAR NOT NULL,
+    threshold       DOUBLE NOT NULL,
+    window_seconds  BIGINT NOT NULL,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// schemaAlertEvents records fired alerts. Rows copy their rule, so they outlive it.
+const schemaAlertEvents = `
+CREATE TABLE IF NOT EXISTS alert_events (
+    id              VARCHAR PRIMARY KEY,
+    rule_id         VARCHAR NOT NULL,
+    rule_name       VARCHAR NOT NULL,
+    metric_name     VARCHAR NOT NULL,
+    service         VARCHAR,
+    comparison      VARCHAR NOT NULL,
+    threshold       DOUBLE NOT NULL,
+    window_seconds  BIGINT NOT NULL,
+    value           DOUBLE NOT NULL,
+    fired_at        TIMESTAMP NOT NULL
+);
+`
+
+const indexAlertEvents = `
+CREATE INDEX IF NOT EXISTS idx_alert_events_rule ON alert_events(rule_id, fired_at);
+`
+
 const indexImportState = `
 CREATE INDEX IF NOT EXISTS idx_import_state_source ON import_state(source);
 `
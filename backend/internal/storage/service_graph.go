@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// GetServiceGraph returns the services with spans in [from, to] and the calls between them:
+// an edge from service A to service B counts the spans of B whose parent span belongs to A.
+// Spans calling into their own service add no edge.
+//
+// With groupBy set, each node is annotated with the value of that resource attribute (e.g.
+// deployment.environment or k8s.cluster.name), so clients can collapse services into groups.
+// A service reporting several values gets the one on most of its spans; services without the
+// attribute are left ungrouped.
+func (s *DuckDBStore) GetServiceGraph(ctx context.Context, from, to time.Time, groupBy string) (*api.ServiceGraphResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	where, args := rangeFilter(from, to, "")
+	groupExpr := "NULL::VARCHAR"
+	if groupBy != "" {
+		// Quote the key so dotted attribute names (k8s.cluster.name) are not treated as nested paths
+		groupExpr = "NULLIF(json_extract_string(ResourceAttributes, ?), '')"
+		args = append([]interface{}{`$."` + groupBy + `"`}, args...)
+	}
+
+	nodesQuery := `
+		WITH spans AS (
+			SELECT ServiceName, StatusCode, ` + groupExpr + ` as grp
+			FROM otel_traces
+			WHERE ` + where + `
+		),
+		groups AS (
+			SELECT ServiceName, grp
+			FROM spans
+			WHERE grp IS NOT NULL
+			GROUP BY ServiceName, grp
+			QUALIFY ROW_NUMBER() OVER (PARTITION BY ServiceName ORDER BY COUNT(*) DESC, grp) = 1
+		)
+		SELECT
+			s.ServiceName,
+			COALESCE(ANY_VALUE(g.grp), ''),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE s.StatusCode = 'ERROR')
+		FROM spans s
+		LEFT JOIN groups g ON g.ServiceName = s.ServiceName
+		GROUP BY s.ServiceName
+		ORDER BY s.ServiceName
+	`
+
+	rows, err := s.db.QueryContext(ctx, nodesQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying service graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &api.ServiceGraphResponse{From: from, To: to, GroupBy: groupBy, Nodes: []api.ServiceGraphNode{}, Edges: []api.ServiceGraphEdge{}}
+	for rows.Next() {
+		var n api.ServiceGraphNode
+		if err := rows.Scan(&n.Service, &n.Group, &n.SpanCount, &n.ErrorCount); err != nil {
+			return nil, fmt.Errorf("scanning service graph node: %w", err)
+		}
+		resp.Nodes = append(resp.Nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating service graph nodes: %w", err)
+	}
+
+	edgesQuery := `
+		SELECT
+			p.ServiceName,
+			c.ServiceName,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE c.StatusCode = 'ERROR')
+		FROM otel_traces c
+		JOIN otel_traces p ON p.TraceId = c.TraceId AND p.SpanId = c.ParentSpanId
+		WHERE c.Timestamp >= ?::TIMESTAMP AND c.Timestamp <= ?::TIMESTAMP
+			AND p.ServiceName != c.ServiceName
+		GROUP BY p.ServiceName, c.ServiceName
+		ORDER BY p.ServiceName, c.ServiceName
+	`
+
+	edgeRows, err := s.db.QueryContext(ctx, edgesQuery, formatTimeForDB(from), formatTimeForDB(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying service graph edges: %w", err)
+	}
+	defer edgeRows.Close()
+
+	for edgeRows.Next() {
+		var e api.ServiceGraphEdge
+		if err := edgeRows.Scan(&e.Source, &e.Target, &e.CallCount, &e.ErrorCount); err != nil {
+			return nil, fmt.Errorf("scanning service graph edge: %w", err)
+		}
+		resp.Edges = append(resp.Edges, e)
+	}
+	if err := edgeRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating service graph edges: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetServiceGraph(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	prod := map[string]string{"k8s.cluster.name": "prod-eu"}
+	staging := map[string]string{"k8s.cluster.name": "staging"}
+
+	spans := []api.Span{
+		// gateway -> api -> db, with api calling itself once
+		{TraceID: "t1", SpanID: "g1", ServiceName: "gateway", SpanName: "GET /", Timestamp: now, ResourceAttributes: prod},
+		{TraceID: "t1", SpanID: "a1", ParentSpanID: "g1", ServiceName: "api", SpanName: "handle", Timestamp: now, ResourceAttributes: prod},
+		{TraceID: "t1", SpanID: "a2", ParentSpanID: "a1", ServiceName: "api", SpanName: "render", Timestamp: now, ResourceAttributes: prod},
+		{TraceID: "t1", SpanID: "d1", ParentSpanID: "a1", ServiceName: "db", SpanName: "query", Timestamp: now, StatusCode: "ERROR", ResourceAttributes: staging},
+		{TraceID: "t2", SpanID: "g2", ServiceName: "gateway", SpanName: "GET /", Timestamp: now, ResourceAttributes: prod},
+		{TraceID: "t2", SpanID: "a3", ParentSpanID: "g2", ServiceName: "api", SpanName: "handle", Timestamp: now, ResourceAttributes: staging},
+		// No cluster attribute
+		{TraceID: "t3", SpanID: "w1", ServiceName: "worker", SpanName: "job", Timestamp: now},
+		// Out of range
+		{TraceID: "old", SpanID: "o1", ServiceName: "legacy", SpanName: "old", Timestamp: now.Add(-48 * time.Hour), ResourceAttributes: prod},
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	graph, err := store.GetServiceGraph(ctx, now.Add(-time.Hour), now.Add(time.Hour), "k8s.cluster.name")
+	if err != nil {
+		t.Fatalf("GetServiceGraph failed: %v", err)
+	}
+	if graph.GroupBy != "k8s.cluster.name" {
+		t.Errorf("expected groupBy to be echoed, got %q", graph.GroupBy)
+	}
+
+	// api reports prod-eu on 2 of its 3 spans
+	wantNodes := []api.ServiceGraphNode{
+		{Service: "api", Group: "prod-eu", SpanCount: 3},
+		{Service: "db", Group: "staging", SpanCount: 1, ErrorCount: 1},
+		{Service: "gateway", Group: "prod-eu", SpanCount: 2},
+		{Service: "worker", SpanCount: 1},
+	}
+	if len(graph.Nodes) != len(wantNodes) {
+		t.Fatalf("expected %d nodes, got %+v", len(wantNodes), graph.Nodes)
+	}
+	for i, want := range wantNodes {
+		if graph.Nodes[i] != want {
+			t.Errorf("node %d: expected %+v, got %+v", i, want, graph.Nodes[i])
+		}
+	}
+
+	wantEdges := []api.ServiceGraphEdge{
+		{Source: "api", Target: "db", CallCount: 1, ErrorCount: 1},
+		{Source: "gateway", Target: "api", CallCount: 2},
+	}
+	if len(graph.Edges) != len(wantEdges) {
+		t.Fatalf("expected %d edges, got %+v", len(wantEdges), graph.Edges)
+	}
+	for i, want := range wantEdges {
+		if graph.Edges[i] != want {
+			t.Errorf("edge %d: expected %+v, got %+v", i, want, graph.Edges[i])
+		}
+	}
+
+	// Without groupBy no node carries a group
+	graph, err = store.GetServiceGraph(ctx, now.Add(-time.Hour), now.Add(time.Hour), "")
+	if err != nil {
+		t.Fatalf("GetServiceGraph failed: %v", err)
+	}
+	for _, n := range graph.Nodes {
+		if n.Group != "" {
+			t.Errorf("expected no group without groupBy, got %+v", n)
+		}
+	}
+}
+
+func TestGetServiceGraph_Empty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	graph, err := store.GetServiceGraph(context.Background(), now.Add(-time.Hour), now, "deployment.environment")
+	if err != nil {
+		t.Fatalf("GetServiceGraph failed: %v", err)
+	}
+	if graph.Nodes == nil || len(graph.Nodes) != 0 || graph.Edges == nil || len(graph.Edges) != 0 {
+		t.Errorf("expected an empty, non-nil graph, got %+v", graph)
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// GetServiceLatencyStats computes span Duration percentiles (interpolated with quantile_cont)
+// and the maximum per service within [from, to], slowest p95 first. An empty service selects
+// all services. Trace counts follow QueryTraces: Codex CLI spans without a stored parent
+// are virtual trace roots, other services count distinct TraceIds.
+func (s *DuckDBStore) GetServiceLatencyStats(ctx context.Context, service string, from, to time.Time) (*api.ServiceLatencyResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	const codexService = "codex_cli_rs"
+
+	where, args := rangeFilter(from, to, service)
+	query := `
+		WITH spans AS (
+			SELECT
+				t.ServiceName, t.TraceId, t.Duration,
+				t.ServiceName = '` + codexService + `' AND NOT EXISTS (
+					SELECT 1 FROM otel_traces p
+					WHERE p.SpanId = t.ParentSpanId AND p.ServiceName = '` + codexService + `'
+				) as codex_root
+			FROM otel_traces t
+			WHERE ` + where + `
+		)
+		SELECT
+			ServiceName,
+			COUNT(*) as span_count,
+			CASE WHEN ServiceName = '` + codexService + `'
+				THEN COUNT(*) FILTER (WHERE codex_root)
+				ELSE COUNT(DISTINCT TraceId)
+			END as trace_count,
+			CAST(ROUND(quantile_cont(Duration, 0.5)) AS BIGINT) as p50,
+			CAST(ROUND(quantile_cont(Duration, 0.95)) AS BIGINT) as p95,
+			CAST(ROUND(quantile_cont(Duration, 0.99)) AS BIGINT) as p99,
+			MAX(Duration) as max_duration
+		FROM spans
+		GROUP BY ServiceName
+		ORDER BY p95 DESC, ServiceName
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying service latency: %w", err)
+	}
+	defer rows.Close()
+
+	resp := &api.ServiceLatencyResponse{From: from, To: to, Services: []api.ServiceLatency{}}
+	for rows.Next() {
+		var l api.ServiceLatency
+		if err := rows.Scan(&l.Service, &l.SpanCount, &l.TraceCount, &l.P50, &l.P95, &l.P99, &l.Max); err != nil {
+			return nil, fmt.Errorf("scanning service latency: %w", err)
+		}
+		resp.Services = append(resp.Services, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating service latency: %w", err)
+	}
+
+	return resp, nil
+}
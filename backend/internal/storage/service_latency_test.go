@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetServiceLatencyStats(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// claude-code: 5 spans of 10ms..50ms across 2 traces
+	var spans []api.Span
+	for i := 0; i < 5; i++ {
+		spans = append(spans, api.Span{
+			TraceID:     fmt.Sprintf("trace-%d", i%2),
+			SpanID:      fmt.Sprintf("span-%d", i),
+			ServiceName: "claude-code",
+			SpanName:    "claude.request",
+			Timestamp:   now.Add(time.Duration(i) * time.Second),
+			Duration:    int64(i+1) * int64(10*time.Millisecond),
+		})
+	}
+	spans = append(spans,
+		// Codex: one TraceId with two first-level spans (two virtual traces), one with a child
+		api.Span{TraceID: "c1", SpanID: "turn-1", ParentSpanID: "session", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now, Duration: int64(time.Second)},
+		api.Span{TraceID: "c1", SpanID: "tool-1", ParentSpanID: "turn-1", ServiceName: "codex_cli_rs", SpanName: "tool", Timestamp: now, Duration: int64(500 * time.Millisecond)},
+		api.Span{TraceID: "c1", SpanID: "turn-2", ParentSpanID: "session", ServiceName: "codex_cli_rs", SpanName: "turn", Timestamp: now, Duration: int64(3 * time.Second)},
+		// Out of range
+		api.Span{TraceID: "old", SpanID: "old-1", ServiceName: "claude-code", SpanName: "old", Timestamp: now.Add(-48 * time.Hour), Duration: int64(time.Hour)},
+	)
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	resp, err := store.GetServiceLatencyStats(ctx, "", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetServiceLatencyStats failed: %v", err)
+	}
+	if len(resp.Services) != 2 {
+		t.Fatalf("expected 2 services, got %+v", resp.Services)
+	}
+
+	// Slowest p95 first
+	codex, claude := resp.Services[0], resp.Services[1]
+	if codex.Service != "codex_cli_rs" || claude.Service != "claude-code" {
+		t.Fatalf("expected codex_cli_rs before claude-code, got %+v", resp.Services)
+	}
+
+	ms := int64(time.Millisecond)
+	if claude.SpanCount != 5 || claude.TraceCount != 2 {
+		t.Errorf("claude-code counts = %d spans, %d traces; want 5, 2", claude.SpanCount, claude.TraceCount)
+	}
+	// quantile_cont interpolates: p95 of 10..50ms is 48ms, p99 is 49.6ms
+	if claude.P50 != 30*ms || claude.P95 != 48*ms || claude.P99 != 49600000 || claude.Max != 50*ms {
+		t.Errorf("unexpected claude-code latency: %+v", claude)
+	}
+
+	if codex.SpanCount != 3 || codex.TraceCount != 2 {
+		t.Errorf("codex counts = %d spans, %d traces; want 3, 2", codex.SpanCount, codex.TraceCount)
+	}
+	if codex.P50 != 1000*ms || codex.Max != 3000*ms {
+		t.Errorf("unexpected codex latency: %+v", codex)
+	}
+
+	// Service filter
+	resp, err = store.GetServiceLatencyStats(ctx, "claude-code", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetServiceLatencyStats failed: %v", err)
+	}
+	if len(resp.Services) != 1 || resp.Services[0].Service != "claude-code" {
+		t.Errorf("expected only claude-code, got %+v", resp.Services)
+	}
+}
+
+func TestGetServiceLatencyStats_Empty(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	resp, err := store.GetServiceLatencyStats(context.Background(), "", now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatalf("GetServiceLatencyStats failed: %v", err)
+	}
+	if resp.Services == nil || len(resp.Services) != 0 {
+		t.Errorf("expected an empty, non-nil service list, got %+v", resp.Services)
+	}
+}
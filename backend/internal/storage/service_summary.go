@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// serviceSummaryTopOperations is the number of span names reported in a service summary
+const serviceSummaryTopOperations = 10
+
+// GetServiceSummary aggregates a service's spans, logs and metrics within [from, to]: signal
+// counts, span error rate, span duration percentiles, the most frequent operations and cost.
+// Cost sums *.cost.usage metrics like GetCostTotals, limited to points inside the range.
+// With extrapolate, span, trace, error and operation counts are scaled by each span's
+// sampling probability; latency percentiles are unaffected by uniform sampling.
+func (s *DuckDBStore) GetServiceSummary(ctx context.Context, service string, from, to time.Time, extrapolate bool) (*api.ServiceSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromStr := formatTimeForDB(from)
+	toStr := formatTimeForDB(to)
+
+	query := `
+		WITH spans AS (
+			SELECT Timestamp, TraceId, TraceState, Duration, StatusCode
+			FROM otel_traces
+			WHERE ServiceName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		),
+		cost_series AS (
+			SELECT
+				CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+					THEN MAX(COALESCE(Value, Sum)) - MIN(COALESCE(Value, Sum))
+					ELSE SUM(COALESCE(Value, Sum))
+				END as series_total
+			FROM otel_metrics
+			WHERE ServiceName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+				AND MetricName LIKE '%.cost.usage'
+			GROUP BY MetricName, CAST(Attributes AS VARCHAR)
+		)
+		SELECT
+			(` + traceCountExpr("spans", extrapolate) + `) as trace_count,
+			(SELECT ` + spanCountExpr(extrapolate) + ` FROM spans) as span_count,
+			(SELECT COUNT(*) FROM otel_logs
+				WHERE ServiceName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP) as log_count,
+			(SELECT COUNT(*) FROM otel_metrics
+				WHERE ServiceName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP) as metric_count,
+			(SELECT ` + spanCountExpr(extrapolate) + ` FROM spans WHERE StatusCode = 'ERROR') as error_count,
+			(SELECT COALESCE(quantile_disc(Duration, 0.5), 0) FROM spans) as p50,
+			(SELECT COALESCE(quantile_disc(Duration, 0.9), 0) FROM spans) as p90,
+			(SELECT COALESCE(quantile_disc(Duration, 0.99), 0) FROM spans) as p99,
+			(SELECT COALESCE(SUM(series_total), 0) FROM cost_series) as cost
+	`
+
+	summary := &api.ServiceSummary{Service: service, From: from, To: to, TopOperations: []api.OperationSummary{}, Extrapolated: extrapolate}
+	if err := s.db.QueryRowContext(ctx, query,
+		service, fromStr, toStr,
+		service, fromStr, toStr,
+		service, fromStr, toStr,
+		service, fromStr, toStr,
+	).Scan(
+		&summary.TraceCount,
+		&summary.SpanCount,
+		&summary.LogCount,
+		&summary.MetricCount,
+		&summary.ErrorCount,
+		&summary.Latency.P50,
+		&summary.Latency.P90,
+		&summary.Latency.P99,
+		&summary.CostUSD,
+	); err != nil {
+		return nil, fmt.Errorf("querying service summary: %w", err)
+	}
+
+	if summary.SpanCount > 0 {
+		summary.ErrorRate = float64(summary.ErrorCount) / float64(summary.SpanCount) * 100
+	}
+
+	weight := "1"
+	if extrapolate {
+		weight = spanAdjustedCount
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			SpanName,
+			CAST(ROUND(SUM(`+weight+`)) AS BIGINT) as span_count,
+			CAST(ROUND(COALESCE(SUM(`+weight+`) FILTER (WHERE StatusCode = 'ERROR'), 0)) AS BIGINT) as error_count,
+			CAST(AVG(Duration) AS BIGINT) as avg_duration
+		FROM otel_traces
+		WHERE ServiceName = ? AND Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		GROUP BY SpanName
+		ORDER BY span_count DESC, SpanName
+		LIMIT ?
+	`, service, fromStr, toStr, serviceSummaryTopOperations)
+	if err != nil {
+		return nil, fmt.Errorf("querying top operations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var op api.OperationSummary
+		if err := rows.Scan(&op.Name, &op.Count, &op.ErrorCount, &op.AvgDuration); err != nil {
+			return nil, fmt.Errorf("scanning operation: %w", err)
+		}
+		summary.TopOperations = append(summary.TopOperations, op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating operations: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetServiceSummary(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// 10 spans of 10ms..100ms across 3 traces; "claude.request" is the most frequent operation
+	var spans []api.Span
+	for i := 0; i < 10; i++ {
+		span := api.Span{
+			TraceID:     fmt.Sprintf("trace-%d", i%3),
+			SpanID:      fmt.Sprintf("span-%d", i),
+			ServiceName: "claude-code",
+			SpanName:    "claude.request",
+			Timestamp:   now.Add(time.Duration(i) * time.Second),
+			Duration:    int64(i+1) * int64(10*time.Millisecond),
+			StatusCode:  "OK",
+		}
+		if i >= 6 {
+			span.SpanName = "tool.exec"
+		}
+		if i == 3 || i == 7 {
+			span.StatusCode = "ERROR"
+		}
+		spans = append(spans, span)
+	}
+	// Another service and an out-of-range span must not be counted
+	spans = append(spans,
+		api.Span{TraceID: "other", SpanID: "other-1", ServiceName: "codex", SpanName: "x", Timestamp: now, Duration: int64(time.Hour), StatusCode: "ERROR"},
+		api.Span{TraceID: "old", SpanID: "old-1", ServiceName: "claude-code", SpanName: "old", Timestamp: now.Add(-48 * time.Hour), Duration: int64(time.Hour)},
+	)
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	logs := []api.LogRecord{
+		{Timestamp: now, ServiceName: "claude-code", Body: "a"},
+		{Timestamp: now, ServiceName: "claude-code", Body: "b"},
+		{Timestamp: now, ServiceName: "claude-code", Body: "c"},
+		{Timestamp: now, ServiceName: "codex", Body: "d"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	delta := int32(1)
+	cost := func(v float64) *float64 { return &v }
+	metrics := []api.MetricDataPoint{
+		{Timestamp: now, ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Value: cost(0.5), AggregationTemporality: &delta},
+		{Timestamp: now.Add(time.Second), ServiceName: "claude-code", MetricName: "claude_code.cost.usage", MetricType: "sum", Value: cost(0.25), AggregationTemporality: &delta},
+		{Timestamp: now, ServiceName: "codex", MetricName: "codex.cost.usage", MetricType: "sum", Value: cost(3), AggregationTemporality: &delta},
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	summary, err := store.GetServiceSummary(ctx, "claude-code", from, to, false)
+	if err != nil {
+		t.Fatalf("GetServiceSummary failed: %v", err)
+	}
+
+	// Counts match the individual queries for the same service and range
+	traceCount, err := store.CountTraces(ctx, "claude-code", "", "", from, to)
+	if err != nil {
+		t.Fatalf("CountTraces failed: %v", err)
+	}
+	if summary.TraceCount != int64(traceCount) {
+		t.Errorf("TraceCount = %d, CountTraces = %d", summary.TraceCount, traceCount)
+	}
+	logsResp, err := store.QueryLogs(ctx, "claude-code", "", SeverityRange{}, "", "", from, to, 100, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if summary.LogCount != int64(logsResp.Total) {
+		t.Errorf("LogCount = %d, QueryLogs total = %d", summary.LogCount, logsResp.Total)
+	}
+	costTotals, err := store.GetCostTotals(ctx)
+	if err != nil {
+		t.Fatalf("GetCostTotals failed: %v", err)
+	}
+	if math.Abs(summary.CostUSD-costTotals["claude-code"]) > 1e-9 || math.Abs(summary.CostUSD-0.75) > 1e-9 {
+		t.Errorf("CostUSD = %v, GetCostTotals = %v, want 0.75", summary.CostUSD, costTotals["claude-code"])
+	}
+
+	if summary.SpanCount != 10 || summary.MetricCount != 2 {
+		t.Errorf("SpanCount/MetricCount = %d/%d, want 10/2", summary.SpanCount, summary.MetricCount)
+	}
+	if summary.ErrorCount != 2 || summary.ErrorRate != 20 {
+		t.Errorf("ErrorCount/ErrorRate = %d/%v, want 2/20", summary.ErrorCount, summary.ErrorRate)
+	}
+
+	ms := int64(time.Millisecond)
+	if summary.Latency.P50 != 50*ms || summary.Latency.P90 != 90*ms || summary.Latency.P99 != 100*ms {
+		t.Errorf("Latency = %+v, want p50=50ms p90=90ms p99=100ms", summary.Latency)
+	}
+
+	if len(summary.TopOperations) != 2 {
+		t.Fatalf("expected 2 operations, got %+v", summary.TopOperations)
+	}
+	want := []api.OperationSummary{
+		{Name: "claude.request", Count: 6, ErrorCount: 1, AvgDuration: 35 * ms},
+		{Name: "tool.exec", Count: 4, ErrorCount: 1, AvgDuration: 85 * ms},
+	}
+	for i, op := range want {
+		if summary.TopOperations[i] != op {
+			t.Errorf("TopOperations[%d] = %+v, want %+v", i, summary.TopOperations[i], op)
+		}
+	}
+}
+
+func TestGetServiceSummary_UnknownService(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	summary, err := store.GetServiceSummary(context.Background(), "missing", now.Add(-time.Hour), now, false)
+	if err != nil {
+		t.Fatalf("GetServiceSummary failed: %v", err)
+	}
+	if summary.SpanCount != 0 || summary.ErrorRate != 0 || summary.CostUSD != 0 || summary.TopOperations == nil {
+		t.Errorf("expected an empty summary, got %+v", summary)
+	}
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// Names of the series returned by GetSessionActivity
+const (
+	activeSessionsSeriesName  = "active_sessions"
+	sessionMessagesSeriesName = "messages"
+)
+
+// GetSessionActivity returns two series over the interval buckets within [from, to]: the
+// number of distinct sessions with logs in each bucket, and the number of those session logs,
+// counted like the message counts of QuerySessions. A session spanning several buckets is
+// active in each of them. Buckets without session logs have no data points.
+func (s *DuckDBStore) GetSessionActivity(ctx context.Context, from, to time.Time, intervalSeconds int64) (*api.TimeSeriesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	intervalStr := fmt.Sprintf("%d seconds", intervalSeconds)
+
+	query := fmt.Sprintf(`
+		SELECT
+			time_bucket(INTERVAL '%s', Timestamp) as bucket,
+			COUNT(DISTINCT session_id) as sessions,
+			COUNT(*) as messages
+		FROM (
+			SELECT
+				Timestamp,
+				COALESCE(
+					json_extract_string(LogAttributes, '$."session.id"'),
+					json_extract_string(LogAttributes, '$."conversation.id"')
+				) as session_id
+			FROM otel_logs
+			WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+		)
+		WHERE session_id IS NOT NULL
+		GROUP BY bucket
+		ORDER BY bucket
+	`, intervalStr)
+
+	rows, err := s.db.QueryContext(ctx, query, formatTimeForDB(from), formatTimeForDB(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying session activity: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := api.TimeSeries{Name: activeSessionsSeriesName, DataPoints: make([][2]float64, 0)}
+	messages := api.TimeSeries{Name: sessionMessagesSeriesName, DataPoints: make([][2]float64, 0)}
+	for rows.Next() {
+		var bucket time.Time
+		var sessionCount, messageCount int64
+		if err := rows.Scan(&bucket, &sessionCount, &messageCount); err != nil {
+			return nil, fmt.Errorf("scanning session activity: %w", err)
+		}
+		ts := float64(bucket.UnixMilli())
+		sessions.DataPoints = append(sessions.DataPoints, [2]float64{ts, float64(sessionCount)})
+		messages.DataPoints = append(messages.DataPoints, [2]float64{ts, float64(messageCount)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating session activity: %w", err)
+	}
+
+	return &api.TimeSeriesResponse{Series: []api.TimeSeries{sessions, messages}}, nil
+}
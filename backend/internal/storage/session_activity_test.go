@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetSessionActivity(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)
+
+	sessionLog := func(ts time.Time, service, key, session string) api.LogRecord {
+		return api.LogRecord{Timestamp: ts, ServiceName: service, Body: "message", LogAttributes: map[string]string{key: session}}
+	}
+	logs := []api.LogRecord{
+		// Day one: two Claude sessions, one spanning into day two, with 3 messages
+		sessionLog(day.Add(time.Hour), "claude-code", "session.id", "a"),
+		sessionLog(day.Add(2*time.Hour), "claude-code", "session.id", "a"),
+		sessionLog(day.Add(3*time.Hour), "claude-code", "session.id", "b"),
+		// Day two: session "a" again and a Codex conversation, 3 messages
+		sessionLog(day.Add(25*time.Hour), "claude-code", "session.id", "a"),
+		sessionLog(day.Add(26*time.Hour), "codex", "conversation.id", "c"),
+		sessionLog(day.Add(27*time.Hour), "codex", "conversation.id", "c"),
+		// Logs outside any session are not messages
+		{Timestamp: day.Add(26 * time.Hour), ServiceName: "claude-code", Body: "startup"},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	resp, err := store.GetSessionActivity(ctx, day, day.Add(48*time.Hour-time.Second), 86400)
+	if err != nil {
+		t.Fatalf("GetSessionActivity failed: %v", err)
+	}
+	if len(resp.Series) != 2 || resp.Series[0].Name != activeSessionsSeriesName || resp.Series[1].Name != sessionMessagesSeriesName {
+		t.Fatalf("expected session and message series, got %+v", resp.Series)
+	}
+
+	want := map[string][][2]float64{
+		activeSessionsSeriesName: {
+			{float64(day.UnixMilli()), 2},
+			{float64(day.Add(24 * time.Hour).UnixMilli()), 2},
+		},
+		sessionMessagesSeriesName: {
+			{float64(day.UnixMilli()), 3},
+			{float64(day.Add(24 * time.Hour).UnixMilli()), 3},
+		},
+	}
+	for _, series := range resp.Series {
+		got := series.DataPoints
+		if len(got) != 2 || got[0] != want[series.Name][0] || got[1] != want[series.Name][1] {
+			t.Errorf("%s = %v, want %v", series.Name, got, want[series.Name])
+		}
+	}
+
+	// An empty range has empty series
+	resp, err = store.GetSessionActivity(ctx, day.AddDate(0, 0, -10), day.AddDate(0, 0, -9), 86400)
+	if err != nil {
+		t.Fatalf("GetSessionActivity failed: %v", err)
+	}
+	if len(resp.Series) != 2 || len(resp.Series[0].DataPoints) != 0 || len(resp.Series[1].DataPoints) != 0 {
+		t.Errorf("expected empty series, got %+v", resp.Series)
+	}
+}
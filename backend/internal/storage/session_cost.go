@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+// Sources reported in api.SessionCostResponse
+const (
+	SessionCostSourceMetrics = "metrics"
+	SessionCostSourceLogs    = "logs"
+)
+
+// GetSessionCost returns the token usage and cost of a session, in total and per model, or
+// nil when nothing is recorded for it. Session ids are matched like in QuerySessions, on the
+// session.id or conversation.id attribute, so Claude Code, Codex CLI and Gemini CLI sessions
+// all work.
+//
+// Usage comes from the session's *.token.usage and *.cost.usage metrics: cumulative series are
+// scoped to the session, so each contributes its highest value, and delta series the sum of
+// their points. Sessions without such metrics fall back to the per-message usage of their
+// transcript (see GetSessionTranscript).
+func (s *DuckDBStore) GetSessionCost(ctx context.Context, sessionID string) (*api.SessionCostResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	models, err := s.sessionMetricUsage(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	source := SessionCostSourceMetrics
+
+	if len(models) == 0 {
+		transcript, err := s.sessionTranscript(ctx, sessionID)
+		if errors.Is(err, errSessionNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		models = transcriptUsage(transcript.Messages)
+		source = SessionCostSourceLogs
+	}
+
+	resp := &api.SessionCostResponse{SessionID: sessionID, Source: source, Models: []api.SessionUsage{}}
+	for _, m := range models {
+		resp.Models = append(resp.Models, *m)
+		addSessionUsage(&resp.Total, m)
+	}
+	sort.SliceStable(resp.Models, func(i, j int) bool {
+		if resp.Models[i].CostUSD != resp.Models[j].CostUSD {
+			return resp.Models[i].CostUSD > resp.Models[j].CostUSD
+		}
+		return resp.Models[i].Model < resp.Models[j].Model
+	})
+	return resp, nil
+}
+
+// sessionMetricUsage sums the token and cost metrics of a session per model; callers hold s.mu
+func (s *DuckDBStore) sessionMetricUsage(ctx context.Context, sessionID string) (map[string]*api.SessionUsage, error) {
+	query := `
+		SELECT
+			COALESCE(NULLIF(model, ''), ?) as model,
+			is_cost,
+			token_type,
+			SUM(series_total)
+		FROM (
+			SELECT
+				COALESCE(
+					json_extract_string(ANY_VALUE(Attributes), '$.model'),
+					json_extract_string(ANY_VALUE(Attributes), '$."gen_ai.response.model"'),
+					json_extract_string(ANY_VALUE(Attributes), '$."gen_ai.request.model"')
+				) as model,
+				MetricName LIKE '%.cost.usage' as is_cost,
+				COALESCE(
+					json_extract_string(ANY_VALUE(Attributes), '$.type'),
+					json_extract_string(ANY_VALUE(Attributes), '$."gen_ai.token.type"'),
+					''
+				) as token_type,
+				CASE WHEN ANY_VALUE(AggregationTemporality) = 2
+					THEN MAX(COALESCE(Value, Sum))
+					ELSE SUM(COALESCE(Value, Sum))
+				END as series_total
+			FROM otel_metrics
+			WHERE (MetricName LIKE '%.token.usage' OR MetricName LIKE '%.cost.usage')
+				AND COALESCE(
+					json_extract_string(Attributes, '$."session.id"'),
+					json_extract_string(Attributes, '$."conversation.id"')
+				) = ?
+			GROUP BY ServiceName, MetricName, CAST(Attributes AS VARCHAR)
+		)
+		GROUP BY 1, 2, 3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, unknownModel, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("querying session cost: %w", err)
+	}
+	defer rows.Close()
+
+	models := make(map[string]*api.SessionUsage)
+	for rows.Next() {
+		var model, tokenType string
+		var isCost bool
+		var total float64
+		if err := rows.Scan(&model, &isCost, &tokenType, &total); err != nil {
+			return nil, fmt.Errorf("scanning session cost: %w", err)
+		}
+
+		m, ok := models[model]
+		if !ok {
+			m = &api.SessionUsage{Model: model}
+			models[model] = m
+		}
+		if isCost {
+			m.CostUSD += total
+			continue
+		}
+		switch strings.ToLower(tokenType) {
+		case "input":
+			m.InputTokens += int64(total)
+		case "output":
+			m.OutputTokens += int64(total)
+		case "cacheread", "cache_read", "cache", "cached":
+			m.CacheRead += int64(total)
+		case "cachecreation", "cache_creation":
+			m.CacheWrite += int64(total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating session cost: %w", err)
+	}
+
+	return models, nil
+}
+
+// transcriptUsage sums the usage of transcript messages per model
+func transcriptUsage(messages []api.TranscriptMessage) map[string]*api.SessionUsage {
+	models := make(map[string]*api.SessionUsage)
+	for _, msg := range messages {
+		if msg.InputTokens == 0 && msg.OutputTokens == 0 && msg.CacheRead == 0 && msg.CacheWrite == 0 && msg.CostUSD == 0 {
+			continue
+		}
+		model := msg.Model
+		if model == "" {
+			model = unknownModel
+		}
+		m, ok := models[model]
+		if !ok {
+			m = &api.SessionUsage{Model: model}
+			models[model] = m
+		}
+		addSessionUsage(m, &api.SessionUsage{
+			InputTokens:  int64(msg.InputTokens),
+			OutputTokens: int64(msg.OutputTokens),
+			CacheRead:    int64(msg.CacheRead),
+			CacheWrite:   int64(msg.CacheWrite),
+			CostUSD:      msg.CostUSD,
+		})
+	}
+	return models
+}
+
+func addSessionUsage(total, u *api.SessionUsage) {
+	total.InputTokens += u.InputTokens
+	total.OutputTokens += u.OutputTokens
+	total.CacheRead += u.CacheRead
+	total.CacheWrite += u.CacheWrite
+	total.CostUSD += u.CostUSD
+}
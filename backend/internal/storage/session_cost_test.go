@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetSessionCost(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Minute)
+	cumulative := int32(2)
+
+	point := func(offset time.Duration, service, metric string, temporality *int32, attrs map[string]string, v float64) api.MetricDataPoint {
+		return api.MetricDataPoint{
+			Timestamp: now.Add(-offset), ServiceName: service, MetricName: metric, MetricType: "sum", Value: &v,
+			AggregationTemporality: temporality, Attributes: attrs,
+		}
+	}
+	claude := func(model, tokenType string) map[string]string {
+		attrs := map[string]string{"session.id": "claude-sess", "model": model}
+		if tokenType != "" {
+			attrs["type"] = tokenType
+		}
+		return attrs
+	}
+	metrics := []api.MetricDataPoint{
+		// Claude Code (imported): delta points summed per model
+		point(3*time.Minute, "claude-code", "claude_code.token.usage", nil, claude("sonnet", "input"), 100),
+		point(2*time.Minute, "claude-code", "claude_code.token.usage", nil, claude("sonnet", "input"), 50),
+		point(2*time.Minute, "claude-code", "claude_code.token.usage", nil, claude("sonnet", "output"), 20),
+		point(2*time.Minute, "claude-code", "claude_code.token.usage", nil, claude("sonnet", "cacheRead"), 300),
+		point(2*time.Minute, "claude-code", "claude_code.token.usage", nil, claude("sonnet", "cacheCreation"), 40),
+		point(2*time.Minute, "claude-code", "claude_code.cost.usage", nil, claude("sonnet", ""), 0.25),
+		point(time.Minute, "claude-code", "claude_code.token.usage", nil, claude("opus", "output"), 10),
+		point(time.Minute, "claude-code", "claude_code.cost.usage", nil, claude("opus", ""), 1),
+		// The user-facing duplicates are not counted
+		point(time.Minute, "claude-code", "claude_code.cost.usage_user_facing", nil, claude("opus", ""), 1),
+		// Gemini CLI (OTLP): cumulative series contribute their latest total
+		point(2*time.Minute, "gemini-cli", "gemini_cli.token.usage", &cumulative, map[string]string{"session.id": "gemini-sess", "model": "gemini-2.5-pro", "type": "input"}, 100),
+		point(time.Minute, "gemini-cli", "gemini_cli.token.usage", &cumulative, map[string]string{"session.id": "gemini-sess", "model": "gemini-2.5-pro", "type": "input"}, 400),
+		point(time.Minute, "gemini-cli", "gemini_cli.token.usage", &cumulative, map[string]string{"session.id": "gemini-sess", "model": "gemini-2.5-pro", "type": "cache"}, 60),
+		// Codex CLI: conversation.id
+		point(time.Minute, "codex", "codex_cli_rs.token.usage", nil, map[string]string{"conversation.id": "codex-conv", "model": "gpt-5", "type": "cached"}, 70),
+		point(time.Minute, "codex", "codex_cli_rs.cost.usage", nil, map[string]string{"conversation.id": "codex-conv"}, 0.5),
+	}
+	if err := store.InsertMetrics(ctx, metrics); err != nil {
+		t.Fatalf("InsertMetrics failed: %v", err)
+	}
+
+	resp, err := store.GetSessionCost(ctx, "claude-sess")
+	if err != nil {
+		t.Fatalf("GetSessionCost failed: %v", err)
+	}
+	if resp.Source != SessionCostSourceMetrics {
+		t.Errorf("expected metrics source, got %q", resp.Source)
+	}
+	wantTotal := api.SessionUsage{InputTokens: 150, OutputTokens: 30, CacheRead: 300, CacheWrite: 40, CostUSD: 1.25}
+	if resp.Total != wantTotal {
+		t.Errorf("total = %+v, want %+v", resp.Total, wantTotal)
+	}
+	if len(resp.Models) != 2 || resp.Models[0].Model != "opus" || resp.Models[1].Model != "sonnet" {
+		t.Fatalf("expected opus then sonnet, got %+v", resp.Models)
+	}
+	wantSonnet := api.SessionUsage{Model: "sonnet", InputTokens: 150, OutputTokens: 20, CacheRead: 300, CacheWrite: 40, CostUSD: 0.25}
+	if resp.Models[1] != wantSonnet {
+		t.Errorf("sonnet = %+v, want %+v", resp.Models[1], wantSonnet)
+	}
+
+	gemini, err := store.GetSessionCost(ctx, "gemini-sess")
+	if err != nil {
+		t.Fatalf("GetSessionCost failed: %v", err)
+	}
+	if gemini.Total.InputTokens != 400 || gemini.Total.CacheRead != 60 {
+		t.Errorf("expected the latest cumulative totals, got %+v", gemini.Total)
+	}
+
+	codex, err := store.GetSessionCost(ctx, "codex-conv")
+	if err != nil {
+		t.Fatalf("GetSessionCost failed: %v", err)
+	}
+	if codex.Total.CacheRead != 70 || codex.Total.CostUSD != 0.5 || len(codex.Models) != 2 {
+		t.Errorf("unexpected codex cost: %+v", codex)
+	}
+
+	missing, err := store.GetSessionCost(ctx, "no-such-session")
+	if err != nil {
+		t.Fatalf("GetSessionCost failed: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("expected nil for an unknown session, got %+v", missing)
+	}
+}
+
+func TestGetSessionCost_TranscriptFallback(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	logs := []api.LogRecord{
+		{Timestamp: now.Add(-3 * time.Minute), ServiceName: "claude-code", Body: "user_prompt", LogAttributes: map[string]string{"event.name": "user_prompt", "session.id": "otlp-sess", "prompt": "hi"}},
+		{Timestamp: now.Add(-2 * time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{
+			"event.name": "api_request", "session.id": "otlp-sess", "model": "sonnet",
+			"input_tokens": "100", "output_tokens": "20", "cache_read_tokens": "500", "cache_creation_tokens": "30", "cost_usd": "0.2",
+		}},
+		{Timestamp: now.Add(-time.Minute), ServiceName: "claude-code", Body: "api_request", LogAttributes: map[string]string{
+			"event.name": "api_request", "session.id": "otlp-sess", "model": "sonnet",
+			"input_tokens": "10", "output_tokens": "5", "cost_usd": "0.05",
+		}},
+	}
+	if err := store.InsertLogs(ctx, logs); err != nil {
+		t.Fatalf("InsertLogs failed: %v", err)
+	}
+
+	resp, err := store.GetSessionCost(ctx, "otlp-sess")
+	if err != nil {
+		t.Fatalf("GetSessionCost failed: %v", err)
+	}
+	if resp.Source != SessionCostSourceLogs {
+		t.Errorf("expected logs source, got %q", resp.Source)
+	}
+	want := api.SessionUsage{Model: "sonnet", InputTokens: 110, OutputTokens: 25, CacheRead: 500, CacheWrite: 30, CostUSD: 0.25}
+	if len(resp.Models) != 1 || resp.Models[0] != want {
+		t.Errorf("models = %+v, want [%+v]", resp.Models, want)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// settingPattern matches configuration-only statements:
+//
+//	SET [GLOBAL|SESSION] name = value  (or SET name TO value)
+//	PRAGMA name = value
+//	PRAGMA name
+//
+// Function-style pragmas (PRAGMA name(...)) and anything else are rejected, since
+// they can run queries or modify data.
+var settingPattern = regexp.MustCompile(`(?is)^(SET\s+((GLOBAL|SESSION)\s+)?[a-z_][a-z0-9_]*\s*(=|\s+TO\s+)\s*[^()]+|PRAGMA\s+[a-z_][a-z0-9_]*(\s*=\s*[^()]+)?)$`)
+
+// ValidateSetting checks that a statement only changes a DuckDB setting
+func ValidateSetting(stmt string) error {
+	stmt = strings.TrimSpace(stmt)
+	if strings.Contains(stmt, ";") || !settingPattern.MatchString(stmt) {
+		return fmt.Errorf("invalid database setting %q: only SET and PRAGMA statements are allowed", stmt)
+	}
+	return nil
+}
+
+// ApplySettings validates and executes SET/PRAGMA statements against the database.
+// All statements are validated before any is applied. Session-scoped settings only
+// affect a single pooled connection, so prefer SET GLOBAL where DuckDB supports it.
+func (s *DuckDBStore) ApplySettings(ctx context.Context, stmts []string) error {
+	for _, stmt := range stmts {
+		if err := ValidateSetting(stmt); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("applying database setting %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
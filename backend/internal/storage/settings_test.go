@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateSetting(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		wantErr bool
+	}{
+		{"SET threads = 2", false},
+		{"SET GLOBAL memory_limit = '1GB'", false},
+		{"set preserve_insertion_order to false", false},
+		{"PRAGMA enable_progress_bar", false},
+		{"PRAGMA threads = 4", false},
+		{"DROP TABLE otel_traces", true},
+		{"DELETE FROM otel_logs", true},
+		{"SET threads = 2; DROP TABLE otel_traces", true},
+		{"PRAGMA create_fts_index('otel_logs', 'Body')", true},
+		{"SET threads = (SELECT 1)", true},
+		{"SET", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSetting(tt.stmt)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSetting(%q) error = %v, wantErr %v", tt.stmt, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	store, err := NewDuckDBStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.ApplySettings(ctx, []string{"SET GLOBAL threads = 2", "PRAGMA enable_checkpoint_on_shutdown"}); err != nil {
+		t.Fatalf("ApplySettings failed: %v", err)
+	}
+
+	var threads int
+	if err := store.db.QueryRowContext(ctx, "SELECT current_setting('threads')").Scan(&threads); err != nil {
+		t.Fatalf("reading setting failed: %v", err)
+	}
+	if threads != 2 {
+		t.Errorf("threads = %d, want 2", threads)
+	}
+
+	// The store still works after applying settings
+	if _, err := store.GetStats(ctx); err != nil {
+		t.Errorf("GetStats failed after applying settings: %v", err)
+	}
+
+	// A disallowed statement is rejected before anything runs
+	err = store.ApplySettings(ctx, []string{"SET GLOBAL threads = 3", "DROP TABLE otel_traces"})
+	if err == nil {
+		t.Fatal("expected error for disallowed statement")
+	}
+	if err := store.db.QueryRowContext(ctx, "SELECT current_setting('threads')").Scan(&threads); err != nil {
+		t.Fatalf("reading setting failed: %v", err)
+	}
+	if threads != 2 {
+		t.Errorf("threads = %d, want 2 (no statement should run when validation fails)", threads)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetSpanKindDistribution returns span counts per span kind (SERVER, CLIENT, PRODUCER,
+// CONSUMER, INTERNAL) within the time range, optionally limited to one service.
+// Spans without a kind are counted as UNSPECIFIED.
+func (s *DuckDBStore) GetSpanKindDistribution(ctx context.Context, service string, from, to time.Time) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT COALESCE(NULLIF(SpanKind, ''), 'UNSPECIFIED') as kind, COUNT(*) as count
+		FROM otel_traces
+		WHERE Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP
+	`
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+	query += " GROUP BY kind"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying span kinds: %w", err)
+	}
+	defer rows.Close()
+
+	kinds := make(map[string]int64)
+	for rows.Next() {
+		var kind string
+		var count int64
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, fmt.Errorf("scanning span kind: %w", err)
+		}
+		kinds[kind] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating span kinds: %w", err)
+	}
+
+	return kinds, nil
+}
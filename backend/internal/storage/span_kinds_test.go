@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetSpanKindDistribution(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	span := func(id, service, kind string, ts time.Time) api.Span {
+		return api.Span{TraceID: "trace-" + id, SpanID: id, ServiceName: service, SpanName: "op", Timestamp: ts, SpanKind: kind}
+	}
+	spans := []api.Span{
+		span("1", "api", "SERVER", now),
+		span("2", "api", "SERVER", now),
+		span("3", "api", "CLIENT", now),
+		span("4", "api", "INTERNAL", now),
+		span("5", "api", "", now),
+		span("6", "worker", "CONSUMER", now),
+		span("7", "worker", "PRODUCER", now),
+		span("8", "api", "SERVER", now.Add(-48*time.Hour)),
+	}
+	if err := store.InsertSpans(ctx, spans); err != nil {
+		t.Fatalf("InsertSpans failed: %v", err)
+	}
+
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	kinds, err := store.GetSpanKindDistribution(ctx, "api", from, to)
+	if err != nil {
+		t.Fatalf("GetSpanKindDistribution failed: %v", err)
+	}
+	want := map[string]int64{"SERVER": 2, "CLIENT": 1, "INTERNAL": 1, "UNSPECIFIED": 1}
+	if len(kinds) != len(want) {
+		t.Errorf("expected %d kinds, got %v", len(want), kinds)
+	}
+	for kind, count := range want {
+		if kinds[kind] != count {
+			t.Errorf("kinds[%s] = %d, want %d", kind, kinds[kind], count)
+		}
+	}
+
+	all, err := store.GetSpanKindDistribution(ctx, "", from, to)
+	if err != nil {
+		t.Fatalf("GetSpanKindDistribution failed: %v", err)
+	}
+	if all["CONSUMER"] != 1 || all["PRODUCER"] != 1 || all["SERVER"] != 2 {
+		t.Errorf("unexpected distribution across services: %v", all)
+	}
+}
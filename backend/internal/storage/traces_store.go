@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/tobilg/ai-observer/internal/api"
@@ -24,6 +26,19 @@ func (s *DuckDBStore) InsertSpans(ctx context.Context, spans []api.Span) error {
 	}
 	defer tx.Rollback()
 
+	if err := insertSpansTx(ctx, tx, spans); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.markModified()
+	return nil
+}
+
+// insertSpansTx inserts spans within tx
+func insertSpansTx(ctx context.Context, tx *sql.Tx, spans []api.Span) error {
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO otel_traces (
 			Timestamp, TraceId, SpanId, ParentSpanId, TraceState,
@@ -31,8 +46,9 @@ func (s *DuckDBStore) InsertSpans(ctx context.Context, spans []api.Span) error {
 			ScopeName, ScopeVersion, SpanAttributes, Duration,
 			StatusCode, StatusMessage,
 			"Events.Timestamp", "Events.Name", "Events.Attributes",
-			"Links.TraceId", "Links.SpanId", "Links.TraceState", "Links.Attributes"
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			"Links.TraceId", "Links.SpanId", "Links.TraceState", "Links.Attributes",
+			ScopeAttributes, DroppedAttributesCount, DroppedEventsCount, DroppedLinksCount
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("preparing statement: %w", err)
@@ -83,19 +99,38 @@ func (s *DuckDBStore) InsertSpans(ctx context.Context, spans []api.Span) error {
 			stringArrayToString(linkSpanIDs),
 			stringArrayToString(linkTraceStates),
 			mapArrayToString(linkAttributes),
+			mapToString(span.ScopeAttributes),
+			span.DroppedAttributesCount,
+			span.DroppedEventsCount,
+			span.DroppedLinksCount,
 		)
 		if err != nil {
 			return fmt.Errorf("inserting span: %w", err)
 		}
 	}
+	return nil
+}
 
-	return tx.Commit()
+// QueryTraces returns trace overviews matching the filters. A non-empty event restricts the
+// results to spans that recorded an event with that name (e.g. "exception").
+func (s *DuckDBStore) QueryTraces(ctx context.Context, service, search, event string, from, to time.Time, limit, offset int) (*api.TracesResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.queryTraces(ctx, service, search, event, from, to, limit, offset, nil)
 }
 
-func (s *DuckDBStore) QueryTraces(ctx context.Context, service, search string, from, to time.Time, limit, offset int) (*api.TracesResponse, error) {
+// QueryTracesPage returns the traces matching the filters before or after a cursor, seeking
+// on (StartTime, TraceId) instead of skipping rows with OFFSET
+func (s *DuckDBStore) QueryTracesPage(ctx context.Context, service, search, event string, from, to time.Time, limit int, page PageCursor) (*api.TracesResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.queryTraces(ctx, service, search, event, from, to, limit, 0, &page)
+}
+
+// queryTraces pages through traces by offset, or by keyset when page is set. Callers must hold s.mu.
+func (s *DuckDBStore) queryTraces(ctx context.Context, service, search, event string, from, to time.Time, limit, offset int, page *PageCursor) (*api.TracesResponse, error) {
 	// For Codex CLI, we treat first-level spans (those whose parent doesn't exist) as virtual traces.
 	// For other services, we use traditional GROUP BY TraceId.
 	// When service filter is empty, we combine both approaches.
@@ -106,12 +141,19 @@ func (s *DuckDBStore) QueryTraces(ctx context.Context, service, search string, f
 	includeCodex := service == "" || service == codexService
 	includeOther := service == "" || service != codexService
 
+	// Each source must return enough rows to fill the combined page; keyset pages fetch one
+	// extra row to tell whether more remain
+	fetch := limit + offset
+	if page != nil {
+		fetch = limit + 1
+	}
+
 	var allTraces []api.TraceOverview
 	var total int
 
 	// Query non-Codex traces (traditional GROUP BY TraceId)
 	if includeOther {
-		traces, count, err := s.queryNonCodexTraces(ctx, service, search, from, to, limit, offset)
+		traces, count, err := s.queryNonCodexTraces(ctx, service, search, event, from, to, fetch, page)
 		if err != nil {
 			return nil, err
 		}
@@ -121,7 +163,7 @@ func (s *DuckDBStore) QueryTraces(ctx context.Context, service, search string, f
 
 	// Query Codex virtual traces (first-level spans as trace roots)
 	if includeCodex {
-		traces, count, err := s.queryCodexVirtualTraces(ctx, search, from, to, limit, offset)
+		traces, count, err := s.queryCodexVirtualTraces(ctx, search, event, from, to, fetch, page)
 		if err != nil {
 			return nil, err
 		}
@@ -133,47 +175,121 @@ func (s *DuckDBStore) QueryTraces(ctx context.Context, service, search string, f
 	// (We fetched more than needed to handle combined pagination properly)
 	sortTracesByStartTime(allTraces)
 
-	// Apply offset and limit to combined results
-	if offset >= len(allTraces) {
-		allTraces = nil
-	} else {
-		end := offset + limit
-		if end > len(allTraces) {
-			end = len(allTraces)
+	var hasMore bool
+	switch {
+	case page == nil:
+		// Apply offset and limit to combined results
+		if offset >= len(allTraces) {
+			allTraces = nil
+		} else {
+			end := offset + limit
+			if end > len(allTraces) {
+				end = len(allTraces)
+			}
+			allTraces = allTraces[offset:end]
+		}
+		hasMore = offset+len(allTraces) < total
+	case page.After:
+		// The traces closest to the cursor are the oldest of the newer ones
+		hasMore = len(allTraces) > limit
+		if hasMore {
+			allTraces = allTraces[len(allTraces)-limit:]
+		}
+	default:
+		hasMore = len(allTraces) > limit
+		if hasMore {
+			allTraces = allTraces[:limit]
 		}
-		allTraces = allTraces[offset:end]
 	}
 
-	return &api.TracesResponse{
+	resp := &api.TracesResponse{
 		Traces:  allTraces,
 		Total:   total,
-		HasMore: offset+len(allTraces) < total,
-	}, nil
+		HasMore: hasMore,
+	}
+	if hasMore && len(allTraces) > 0 {
+		next := allTraces[len(allTraces)-1]
+		if page != nil && page.After {
+			next = allTraces[0]
+		}
+		resp.NextCursor = Cursor{Timestamp: next.StartTime, ID: next.TraceID}.Encode()
+	}
+	return resp, nil
 }
 
-// queryNonCodexTraces queries traces for non-Codex services using GROUP BY TraceId
-func (s *DuckDBStore) queryNonCodexTraces(ctx context.Context, service, search string, from, to time.Time, limit, offset int) ([]api.TraceOverview, int, error) {
+// traceDurationExpr computes a trace's duration in nanoseconds when grouping spans by TraceId.
+// A single root span's own duration is used as-is, since clock-skewed children can otherwise
+// shift the extent of the trace; traces with no or several roots span from the earliest
+// start to the latest end.
+const traceDurationExpr = `CASE
+				WHEN COUNT(*) FILTER (WHERE ParentSpanId IS NULL OR ParentSpanId = '') = 1
+				THEN MAX(Duration) FILTER (WHERE ParentSpanId IS NULL OR ParentSpanId = '')
+				ELSE CAST((MAX(epoch_ms(Timestamp) + Duration/1000000) - MIN(epoch_ms(Timestamp))) * 1000000 AS BIGINT)
+			END`
+
+// spanEventFilter matches spans with at least one event of the given name in the serialized
+// "Events.Name" array
+const spanEventFilter = ` AND list_contains(CAST("Events.Name" AS VARCHAR[]), ?)`
+
+// traceEventFilter matches all spans of the traces that have a span with the event, so the
+// per-trace aggregates still see every span
+const traceEventFilter = ` AND TraceId IN (SELECT TraceId FROM otel_traces WHERE TRUE` + spanEventFilter + `)`
+
+// codexEventFilter matches Codex virtual trace roots (aliased t) whose subtree has a span with
+// the event, found by walking up from the spans with the event
+const codexEventFilter = ` AND t.SpanId IN (
+			WITH RECURSIVE ancestors AS (
+				SELECT SpanId, ParentSpanId FROM otel_traces
+				WHERE ServiceName = 'codex_cli_rs'` + spanEventFilter + `
+				UNION
+				SELECT p.SpanId, p.ParentSpanId FROM otel_traces p
+				JOIN ancestors a ON p.SpanId = a.ParentSpanId
+				WHERE p.ServiceName = 'codex_cli_rs'
+			)
+			SELECT SpanId FROM ancestors
+		  )`
+
+// nonCodexTracesFilter builds the WHERE clause and arguments for non-Codex trace queries
+func nonCodexTracesFilter(service, search, event string, from, to time.Time) (string, []interface{}) {
 	const codexService = "codex_cli_rs"
 
 	// Format times as strings to avoid timezone issues with DuckDB's TIMESTAMP type
-	fromStr := formatTimeForDB(from)
-	toStr := formatTimeForDB(to)
+	where := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
 
-	timeFilter := "Timestamp >= ?::TIMESTAMP AND Timestamp <= ?::TIMESTAMP"
-	serviceFilter := " AND ServiceName != '" + codexService + "'"
 	if service != "" && service != codexService {
-		serviceFilter = " AND ServiceName = ?"
+		where += " AND ServiceName = ?"
+		args = append(args, service)
+	} else {
+		where += " AND ServiceName != '" + codexService + "'"
 	}
 
-	searchFilter := ""
 	if search != "" {
-		searchFilter = " AND (SpanName ILIKE ? OR ServiceName ILIKE ? OR StatusMessage ILIKE ? OR CAST(SpanAttributes AS VARCHAR) ILIKE ?)"
+		where += " AND (SpanName ILIKE ? OR ServiceName ILIKE ? OR StatusMessage ILIKE ? OR CAST(SpanAttributes AS VARCHAR) ILIKE ?)"
+		pattern := "%" + search + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
 	}
 
-	var args []interface{}
-	args = append(args, fromStr, toStr)
-	if service != "" && service != codexService {
-		args = append(args, service)
+	if event != "" {
+		where += traceEventFilter
+		args = append(args, event)
+	}
+
+	return where, args
+}
+
+// queryNonCodexTraces queries traces for non-Codex services using GROUP BY TraceId
+func (s *DuckDBStore) queryNonCodexTraces(ctx context.Context, service, search, event string, from, to time.Time, limit int, page *PageCursor) ([]api.TraceOverview, int, error) {
+	where, filterArgs := nonCodexTracesFilter(service, search, event, from, to)
+	args := append([]interface{}{}, filterArgs...)
+
+	having, dir := "", "DESC"
+	if page != nil {
+		var cond string
+		var pageArgs []interface{}
+		cond, pageArgs, dir = page.keysetCondition("MIN(Timestamp)", "TraceId", page.ID)
+		having = "HAVING " + cond
+		args = append(args, pageArgs...)
 	}
 
 	query := `
@@ -182,23 +298,19 @@ func (s *DuckDBStore) queryNonCodexTraces(ctx context.Context, service, search s
 			FIRST(SpanName ORDER BY Timestamp ASC) as RootSpan,
 			FIRST(ServiceName ORDER BY Timestamp ASC) as ServiceName,
 			MIN(Timestamp) as StartTime,
-			CAST((MAX(epoch_ms(Timestamp) + Duration/1000000) - MIN(epoch_ms(Timestamp))) * 1000000 AS BIGINT) as Duration,
+			` + traceDurationExpr + ` as Duration,
 			COUNT(*) as SpanCount,
 			CASE WHEN SUM(CASE WHEN StatusCode = 'ERROR' THEN 1 ELSE 0 END) > 0 THEN 'ERROR'
 			     WHEN SUM(CASE WHEN StatusCode = 'OK' THEN 1 ELSE 0 END) > 0 THEN 'OK'
 			     ELSE 'UNSET' END as Status
 		FROM otel_traces
-		WHERE ` + timeFilter + serviceFilter + searchFilter + `
+		WHERE ` + where + `
 		GROUP BY TraceId
-		ORDER BY StartTime DESC
-		LIMIT ? OFFSET ?
+		` + having + `
+		ORDER BY StartTime ` + dir + `, TraceId ` + dir + `
+		LIMIT ?
 	`
-
-	if search != "" {
-		pattern := "%" + search + "%"
-		args = append(args, pattern, pattern, pattern, pattern)
-	}
-	args = append(args, limit+offset, 0) // Fetch enough for combined pagination
+	args = append(args, limit)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -215,28 +327,27 @@ func (s *DuckDBStore) queryNonCodexTraces(ctx context.Context, service, search s
 		traces = append(traces, t)
 	}
 
-	// Count query
-	var countArgs []interface{}
-	countArgs = append(countArgs, fromStr, toStr)
-	if service != "" && service != codexService {
-		countArgs = append(countArgs, service)
-	}
-	if search != "" {
-		pattern := "%" + search + "%"
-		countArgs = append(countArgs, pattern, pattern, pattern, pattern)
+	count, err := s.countNonCodexTraces(ctx, service, search, event, from, to)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	countQuery := `SELECT COUNT(DISTINCT TraceId) FROM otel_traces WHERE ` + timeFilter + serviceFilter + searchFilter
+	return traces, count, nil
+}
+
+// countNonCodexTraces counts distinct non-Codex traces matching the filters
+func (s *DuckDBStore) countNonCodexTraces(ctx context.Context, service, search, event string, from, to time.Time) (int, error) {
+	where, args := nonCodexTracesFilter(service, search, event, from, to)
+
 	var count int
-	if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&count); err != nil {
-		return nil, 0, fmt.Errorf("counting non-codex traces: %w", err)
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT TraceId) FROM otel_traces WHERE `+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting non-codex traces: %w", err)
 	}
-
-	return traces, count, nil
+	return count, nil
 }
 
 // queryCodexVirtualTraces queries Codex CLI "virtual traces" - first-level spans treated as trace roots
-func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search string, from, to time.Time, limit, offset int) ([]api.TraceOverview, int, error) {
+func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search, event string, from, to time.Time, limit int, page *PageCursor) ([]api.TraceOverview, int, error) {
 	const codexService = "codex_cli_rs"
 
 	// Format times as strings to avoid timezone issues with DuckDB's TIMESTAMP type
@@ -259,6 +370,19 @@ func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search string
 		pattern := "%" + search + "%"
 		searchArgs = append(searchArgs, pattern, pattern, pattern)
 	}
+	if event != "" {
+		searchFilter += codexEventFilter
+		searchArgs = append(searchArgs, event)
+	}
+	dir := "DESC"
+	if page != nil {
+		var cond string
+		var pageArgs []interface{}
+		// Compare prefixed IDs, as the combined page is ordered by the reported trace ID
+		cond, pageArgs, dir = page.keysetCondition("t.Timestamp", "'"+codexTracePrefix+"' || t.SpanId", page.ID)
+		searchFilter += " AND " + cond
+		searchArgs = append(searchArgs, pageArgs...)
+	}
 
 	// Query first-level spans (those whose parent doesn't exist)
 	// Use string interpolation for service name since it's a constant
@@ -279,14 +403,14 @@ func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search string
 			WHERE p.SpanId = t.ParentSpanId AND p.ServiceName = '` + codexService + `'
 		  )
 		` + searchFilter + `
-		ORDER BY t.Timestamp DESC
-		LIMIT ? OFFSET ?
+		ORDER BY t.Timestamp ` + dir + `, t.SpanId ` + dir + `
+		LIMIT ?
 	`
 
 	var args []interface{}
 	args = append(args, fromStr, toStr)
 	args = append(args, searchArgs...)
-	args = append(args, limit+offset, 0)
+	args = append(args, limit)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -321,10 +445,24 @@ func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search string
 		if err == nil {
 			traces[i].SpanCount = count
 		}
+		traces[i].TraceID = CodexTraceID(traces[i].TraceID)
 	}
 
 	// Count total first-level spans
-	countQuery := `
+	count, err := s.countCodexVirtualTraces(ctx, search, event, from, to)
+	if err != nil {
+		// Return what we have without count
+		return traces, len(traces), nil
+	}
+
+	return traces, count, nil
+}
+
+// countCodexVirtualTraces counts Codex first-level spans (virtual trace roots) matching the filters
+func (s *DuckDBStore) countCodexVirtualTraces(ctx context.Context, search, event string, from, to time.Time) (int, error) {
+	const codexService = "codex_cli_rs"
+
+	query := `
 		SELECT COUNT(*) FROM otel_traces t
 		WHERE t.ServiceName = '` + codexService + `'
 		  AND t.Timestamp >= ?::TIMESTAMP AND t.Timestamp <= ?::TIMESTAMP
@@ -332,26 +470,60 @@ func (s *DuckDBStore) queryCodexVirtualTraces(ctx context.Context, search string
 			SELECT 1 FROM otel_traces p
 			WHERE p.SpanId = t.ParentSpanId AND p.ServiceName = '` + codexService + `'
 		  )
-		` + searchFilter
+	`
+	args := []interface{}{formatTimeForDB(from), formatTimeForDB(to)}
 
-	var countArgs []interface{}
-	countArgs = append(countArgs, fromStr, toStr)
-	countArgs = append(countArgs, searchArgs...)
+	if search != "" {
+		query += " AND (SpanName ILIKE ? OR StatusMessage ILIKE ? OR CAST(SpanAttributes AS VARCHAR) ILIKE ?)"
+		pattern := "%" + search + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	if event != "" {
+		query += codexEventFilter
+		args = append(args, event)
+	}
 
 	var count int
-	if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&count); err != nil {
-		// Return what we have without count
-		return traces, len(traces), nil
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting codex traces: %w", err)
 	}
+	return count, nil
+}
 
-	return traces, count, nil
+// CountTraces returns the number of traces QueryTraces would report as Total for the same filters,
+// without fetching any trace rows
+func (s *DuckDBStore) CountTraces(ctx context.Context, service, search, event string, from, to time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	const codexService = "codex_cli_rs"
+
+	total := 0
+	if service != codexService {
+		count, err := s.countNonCodexTraces(ctx, service, search, event, from, to)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	if service == "" || service == codexService {
+		count, err := s.countCodexVirtualTraces(ctx, search, event, from, to)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+
+	return total, nil
 }
 
-// sortTracesByStartTime sorts traces by StartTime in descending order
+// sortTracesByStartTime sorts traces by StartTime in descending order, then by TraceID
+// descending to match the keyset order
 func sortTracesByStartTime(traces []api.TraceOverview) {
 	for i := 0; i < len(traces)-1; i++ {
 		for j := i + 1; j < len(traces); j++ {
-			if traces[j].StartTime.After(traces[i].StartTime) {
+			if traces[j].StartTime.After(traces[i].StartTime) ||
+				(traces[j].StartTime.Equal(traces[i].StartTime) && traces[j].TraceID > traces[i].TraceID) {
 				traces[i], traces[j] = traces[j], traces[i]
 			}
 		}
@@ -359,95 +531,294 @@ func sortTracesByStartTime(traces []api.TraceOverview) {
 }
 
 func (s *DuckDBStore) GetTraceSpans(ctx context.Context, traceID string) ([]api.Span, error) {
+	return s.GetTraceSpansByRole(ctx, traceID, SpanRoleAll)
+}
+
+// SpanRole selects spans by their position in the trace tree
+type SpanRole string
+
+const (
+	SpanRoleAll  SpanRole = ""
+	SpanRoleRoot SpanRole = "root" // Spans whose parent is not among the trace's spans
+	SpanRoleLeaf SpanRole = "leaf" // Spans without children among the trace's spans
+)
+
+// ParseSpanRole validates a spanRole query value; the empty string selects all spans
+func ParseSpanRole(value string) (SpanRole, error) {
+	switch role := SpanRole(value); role {
+	case SpanRoleAll, SpanRoleRoot, SpanRoleLeaf:
+		return role, nil
+	default:
+		return "", fmt.Errorf("invalid spanRole %q: must be root or leaf", value)
+	}
+}
+
+// GetTraceSpansByRole returns the spans of a trace, limited to root or leaf spans unless role
+// is SpanRoleAll
+func (s *DuckDBStore) GetTraceSpansByRole(ctx context.Context, traceID string, role SpanRole) ([]api.Span, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	query, arg, err := s.traceSpansQuery(ctx, traceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scanSpans(ctx, filterSpanRole(query, role), arg)
+}
+
+// filterSpanRole wraps a trace spans query so it only returns spans with the given role.
+// Parents and children are looked up among the spans the query selects.
+func filterSpanRole(query string, role SpanRole) string {
+	var condition string
+	switch role {
+	case SpanRoleRoot:
+		condition = "NOT EXISTS (SELECT 1 FROM trace_spans p WHERE p.SpanId = s.ParentSpanId)"
+	case SpanRoleLeaf:
+		condition = "NOT EXISTS (SELECT 1 FROM trace_spans c WHERE c.ParentSpanId = s.SpanId)"
+	default:
+		return query
+	}
+	return `
+		WITH trace_spans AS (SELECT * FROM (` + query + `))
+		SELECT * FROM trace_spans s
+		WHERE ` + condition + `
+		ORDER BY Timestamp
+	`
+}
+
+// traceSpanPageSize is how many spans StreamTraceSpans reads per page
+const traceSpanPageSize = 500
+
+// StreamTraceSpans calls fn for each span of a trace without materializing the full span
+// list. The trace's span keys are resolved once, which for Codex virtual traces runs the
+// recursive subtree query a single time; the spans are then read in pages of those keys,
+// ordered by (Timestamp, SpanId). The read lock is released before fn sees a page, so a slow
+// consumer never holds up ingest. Iteration stops when ctx is cancelled or fn returns an error.
+func (s *DuckDBStore) StreamTraceSpans(ctx context.Context, traceID string, role SpanRole, fn func(api.Span) error) error {
+	s.mu.RLock()
+	keys, err := s.traceSpanKeys(ctx, traceID, role)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(keys); start += traceSpanPageSize {
+		page := keys[start:min(start+traceSpanPageSize, len(keys))]
+		values := make([]string, len(page))
+		args := make([]interface{}, 0, len(page)*2)
+		for i, key := range page {
+			values[i] = "(?, ?)"
+			args = append(args, key.traceID, key.spanID)
+		}
+		query := `
+			SELECT ` + traceSpanColumns + `
+			FROM otel_traces
+			JOIN (VALUES ` + strings.Join(values, ", ") + `) AS k(TraceId, SpanId) USING (TraceId, SpanId)
+			ORDER BY Timestamp, SpanId
+		`
+
+		var spans []api.Span
+		collect := func(span api.Span) error {
+			spans = append(spans, span)
+			return nil
+		}
+		s.mu.RLock()
+		err := s.iterateSpans(ctx, query, collect, args...)
+		s.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+
+		for _, span := range spans {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(span); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// spanKey identifies a stored span
+type spanKey struct {
+	traceID string
+	spanID  string
+}
+
+// traceSpanKeys returns the keys of a trace's spans with the given role, ordered by
+// (Timestamp, SpanId). Callers must hold s.mu.
+func (s *DuckDBStore) traceSpanKeys(ctx context.Context, traceID string, role SpanRole) ([]spanKey, error) {
+	query, arg, err := s.traceSpansQuery(ctx, traceID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT TraceId, SpanId FROM (`+filterSpanRole(query, role)+`) ORDER BY Timestamp, SpanId`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("querying span keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []spanKey
+	seen := make(map[spanKey]bool)
+	for rows.Next() {
+		var key spanKey
+		if err := rows.Scan(&key.traceID, &key.spanID); err != nil {
+			return nil, fmt.Errorf("scanning span key: %w", err)
+		}
+		// Duplicate rows of a span are all read with its key
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// codexTracePrefix marks the IDs of Codex virtual traces, which are the SpanId of a
+// first-level Codex span rather than an OTel TraceId
+const codexTracePrefix = "codex:"
+
+// CodexTraceID returns the trace ID reported for the Codex virtual trace rooted at spanID
+func CodexTraceID(spanID string) string {
+	return codexTracePrefix + spanID
+}
+
+// IsCodexTraceID reports whether traceID names a Codex virtual trace
+func IsCodexTraceID(traceID string) bool {
+	return strings.HasPrefix(traceID, codexTracePrefix)
+}
+
+// traceSpansQuery returns the query selecting all spans for traceID and the value for its
+// single placeholder. Codex virtual trace IDs (codex:<spanId>) resolve to the subtree of
+// their first-level span; an unprefixed Codex SpanId is still accepted for older links.
+func (s *DuckDBStore) traceSpansQuery(ctx context.Context, traceID string) (string, string, error) {
 	const codexService = "codex_cli_rs"
 
+	if spanID, ok := strings.CutPrefix(traceID, codexTracePrefix); ok {
+		return codexSpanSubtreeQuery, spanID, nil
+	}
+
 	// Check if this is a Codex first-level span (virtual trace root)
-	// For Codex, the "traceID" is actually the SpanId of the first-level span
 	var isCodexSpan bool
 	err := s.db.QueryRowContext(ctx,
 		`SELECT EXISTS(SELECT 1 FROM otel_traces WHERE SpanId = ? AND ServiceName = ?)`,
 		traceID, codexService).Scan(&isCodexSpan)
 	if err != nil {
-		return nil, fmt.Errorf("checking codex span: %w", err)
+		return "", "", fmt.Errorf("checking codex span: %w", err)
 	}
 
 	if isCodexSpan {
 		// Use recursive CTE to get the span and all its descendants
-		return s.getCodexSpanSubtree(ctx, traceID)
+		return codexSpanSubtreeQuery, traceID, nil
 	}
 
 	// Standard query by TraceId for non-Codex services
-	query := `
-		SELECT
-			Timestamp, TraceId, SpanId, ParentSpanId, TraceState,
-			SpanName, SpanKind, ServiceName, ResourceAttributes,
-			ScopeName, ScopeVersion, SpanAttributes, Duration,
-			StatusCode, StatusMessage
+	return `
+		SELECT ` + traceSpanColumns + `
 		FROM otel_traces
 		WHERE TraceId = ?
 		ORDER BY Timestamp
-	`
-
-	return s.scanSpans(ctx, query, traceID)
+	`, traceID, nil
 }
 
-// getCodexSpanSubtree returns a Codex span and all its descendants using recursive CTE
-func (s *DuckDBStore) getCodexSpanSubtree(ctx context.Context, rootSpanID string) ([]api.Span, error) {
-	const codexService = "codex_cli_rs"
+// traceSpanColumns are the otel_traces columns scanned by iterateSpans
+const traceSpanColumns = `
+			Timestamp, TraceId, SpanId, ParentSpanId, TraceState,
+			SpanName, SpanKind, ServiceName, ResourceAttributes,
+			ScopeName, ScopeVersion, SpanAttributes, Duration,
+			StatusCode, StatusMessage, ScopeAttributes,
+			DroppedAttributesCount, DroppedEventsCount, DroppedLinksCount,
+			CAST("Events.Timestamp" AS VARCHAR) AS EventTimestamps, CAST("Events.Name" AS VARCHAR) AS EventNames,
+			CAST("Events.Attributes" AS VARCHAR) AS EventAttributes,
+			CAST("Links.TraceId" AS VARCHAR) AS LinkTraceIds, CAST("Links.SpanId" AS VARCHAR) AS LinkSpanIds,
+			CAST("Links.TraceState" AS VARCHAR) AS LinkTraceStates, CAST("Links.Attributes" AS VARCHAR) AS LinkAttributes`
+
+// codexSpanSubtreeQuery selects a Codex span and all its descendants using a recursive CTE
+const codexSpanSubtreeQuery = `
+	WITH RECURSIVE subtree AS (
+		-- Base case: the root span
+		SELECT
+			Timestamp, TraceId, SpanId, ParentSpanId, TraceState,
+			SpanName, SpanKind, ServiceName, ResourceAttributes,
+			ScopeName, ScopeVersion, SpanAttributes, Duration,
+			StatusCode, StatusMessage, ScopeAttributes,
+			DroppedAttributesCount, DroppedEventsCount, DroppedLinksCount,
+			CAST("Events.Timestamp" AS VARCHAR) AS EventTimestamps, CAST("Events.Name" AS VARCHAR) AS EventNames,
+			CAST("Events.Attributes" AS VARCHAR) AS EventAttributes,
+			CAST("Links.TraceId" AS VARCHAR) AS LinkTraceIds, CAST("Links.SpanId" AS VARCHAR) AS LinkSpanIds,
+			CAST("Links.TraceState" AS VARCHAR) AS LinkTraceStates, CAST("Links.Attributes" AS VARCHAR) AS LinkAttributes
+		FROM otel_traces
+		WHERE SpanId = ?
 
-	query := `
-		WITH RECURSIVE subtree AS (
-			-- Base case: the root span
-			SELECT
-				Timestamp, TraceId, SpanId, ParentSpanId, TraceState,
-				SpanName, SpanKind, ServiceName, ResourceAttributes,
-				ScopeName, ScopeVersion, SpanAttributes, Duration,
-				StatusCode, StatusMessage
-			FROM otel_traces
-			WHERE SpanId = ?
+		UNION ALL
 
-			UNION ALL
+		-- Recursive case: children of spans in the subtree
+		SELECT
+			t.Timestamp, t.TraceId, t.SpanId, t.ParentSpanId, t.TraceState,
+			t.SpanName, t.SpanKind, t.ServiceName, t.ResourceAttributes,
+			t.ScopeName, t.ScopeVersion, t.SpanAttributes, t.Duration,
+			t.StatusCode, t.StatusMessage, t.ScopeAttributes,
+			t.DroppedAttributesCount, t.DroppedEventsCount, t.DroppedLinksCount,
+			CAST(t."Events.Timestamp" AS VARCHAR), CAST(t."Events.Name" AS VARCHAR),
+			CAST(t."Events.Attributes" AS VARCHAR),
+			CAST(t."Links.TraceId" AS VARCHAR), CAST(t."Links.SpanId" AS VARCHAR),
+			CAST(t."Links.TraceState" AS VARCHAR), CAST(t."Links.Attributes" AS VARCHAR)
+		FROM otel_traces t
+		JOIN subtree s ON t.ParentSpanId = s.SpanId
+		WHERE t.ServiceName = 'codex_cli_rs'
+	)
+	SELECT * FROM subtree ORDER BY Timestamp
+`
 
-			-- Recursive case: children of spans in the subtree
-			SELECT
-				t.Timestamp, t.TraceId, t.SpanId, t.ParentSpanId, t.TraceState,
-				t.SpanName, t.SpanKind, t.ServiceName, t.ResourceAttributes,
-				t.ScopeName, t.ScopeVersion, t.SpanAttributes, t.Duration,
-				t.StatusCode, t.StatusMessage
-			FROM otel_traces t
-			JOIN subtree s ON t.ParentSpanId = s.SpanId
-			WHERE t.ServiceName = '` + codexService + `'
-		)
-		SELECT * FROM subtree ORDER BY Timestamp
-	`
+// scanSpans executes a query and scans the results into api.Span slice
+func (s *DuckDBStore) scanSpans(ctx context.Context, query string, args ...interface{}) ([]api.Span, error) {
+	var spans []api.Span
+	err := s.iterateSpans(ctx, query, func(span api.Span) error {
+		spans = append(spans, span)
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.scanSpans(ctx, query, rootSpanID)
+	return spans, nil
 }
 
-// scanSpans executes a query and scans the results into api.Span slice
-func (s *DuckDBStore) scanSpans(ctx context.Context, query string, args ...interface{}) ([]api.Span, error) {
+// iterateSpans executes a query and calls fn for each scanned span. The query must select the
+// span columns followed by the Events.* and Links.* arrays cast to VARCHAR.
+func (s *DuckDBStore) iterateSpans(ctx context.Context, query string, fn func(api.Span) error, args ...interface{}) error {
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("querying spans: %w", err)
+		return fmt.Errorf("querying spans: %w", err)
 	}
 	defer rows.Close()
 
-	var spans []api.Span
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		var span api.Span
 		var parentSpanID, traceState, spanKind, scopeName, scopeVersion, statusCode, statusMessage sql.NullString
-		var resourceAttrs, spanAttrs interface{}
+		var resourceAttrs, spanAttrs, scopeAttrs interface{}
+		var droppedAttrs, droppedEvents, droppedLinks sql.NullInt64
+		var eventTimestamps, eventNames, eventAttrs sql.NullString
+		var linkTraceIDs, linkSpanIDs, linkTraceStates, linkAttrs sql.NullString
 
 		if err := rows.Scan(
 			&span.Timestamp, &span.TraceID, &span.SpanID, &parentSpanID, &traceState,
 			&span.SpanName, &spanKind, &span.ServiceName, &resourceAttrs,
 			&scopeName, &scopeVersion, &spanAttrs, &span.Duration,
-			&statusCode, &statusMessage,
+			&statusCode, &statusMessage, &scopeAttrs,
+			&droppedAttrs, &droppedEvents, &droppedLinks,
+			&eventTimestamps, &eventNames, &eventAttrs,
+			&linkTraceIDs, &linkSpanIDs, &linkTraceStates, &linkAttrs,
 		); err != nil {
-			return nil, fmt.Errorf("scanning span: %w", err)
+			return fmt.Errorf("scanning span: %w", err)
 		}
 
 		span.ParentSpanID = parentSpanID.String
@@ -459,14 +830,27 @@ func (s *DuckDBStore) scanSpans(ctx context.Context, query string, args ...inter
 		span.StatusMessage = statusMessage.String
 		span.ResourceAttributes = scanJSONToMap(resourceAttrs)
 		span.SpanAttributes = scanJSONToMap(spanAttrs)
+		span.ScopeAttributes = scanJSONToMap(scopeAttrs)
+		span.DroppedAttributesCount = uint32(droppedAttrs.Int64)
+		span.DroppedEventsCount = uint32(droppedEvents.Int64)
+		span.DroppedLinksCount = uint32(droppedLinks.Int64)
 
-		spans = append(spans, span)
+		if span.Events, err = spanEvents(eventTimestamps, eventNames, eventAttrs); err != nil {
+			return fmt.Errorf("reading events of span %s: %w", span.SpanID, err)
+		}
+		if span.Links, err = spanLinks(linkTraceIDs, linkSpanIDs, linkTraceStates, linkAttrs); err != nil {
+			return fmt.Errorf("reading links of span %s: %w", span.SpanID, err)
+		}
+
+		if err := fn(span); err != nil {
+			return err
+		}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating spans: %w", err)
+		return fmt.Errorf("iterating spans: %w", err)
 	}
 
-	return spans, nil
+	return nil
 }
 
 
@@ -510,6 +894,63 @@ func (s *DuckDBStore) getServicesLocked(ctx context.Context) ([]string, error) {
 	return services, nil
 }
 
+// GetScopes returns the distinct instrumentation scope name/version pairs per service
+// across traces, logs and metrics, along with the signals each scope was seen in
+func (s *DuckDBStore) GetScopes(ctx context.Context, service string) ([]api.ScopeInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT
+			ServiceName,
+			ScopeName,
+			COALESCE(ScopeVersion, '') as ScopeVersion,
+			string_agg(DISTINCT signal, ',') as signals
+		FROM (
+			SELECT ServiceName, ScopeName, ScopeVersion, 'traces' as signal FROM otel_traces
+			UNION ALL
+			SELECT ServiceName, ScopeName, ScopeVersion, 'logs' as signal FROM otel_logs
+			UNION ALL
+			SELECT ServiceName, ScopeName, ScopeVersion, 'metrics' as signal FROM otel_metrics
+		)
+		WHERE ScopeName IS NOT NULL
+	`
+	args := []interface{}{}
+
+	if service != "" {
+		query += " AND ServiceName = ?"
+		args = append(args, service)
+	}
+
+	query += `
+		GROUP BY ServiceName, ScopeName, COALESCE(ScopeVersion, '')
+		ORDER BY ServiceName, ScopeName, ScopeVersion
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying scopes: %w", err)
+	}
+	defer rows.Close()
+
+	var scopes []api.ScopeInfo
+	for rows.Next() {
+		var scope api.ScopeInfo
+		var signals string
+		if err := rows.Scan(&scope.ServiceName, &scope.ScopeName, &scope.ScopeVersion, &signals); err != nil {
+			return nil, fmt.Errorf("scanning scope: %w", err)
+		}
+		scope.Signals = strings.Split(signals, ",")
+		sort.Strings(scope.Signals)
+		scopes = append(scopes, scope)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating scopes: %w", err)
+	}
+
+	return scopes, nil
+}
+
 func (s *DuckDBStore) GetRecentTraces(ctx context.Context, limit int) (*api.TracesResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -523,7 +964,7 @@ func (s *DuckDBStore) GetRecentTraces(ctx context.Context, limit int) (*api.Trac
 			FIRST(SpanName ORDER BY Timestamp ASC) as RootSpan,
 			FIRST(ServiceName ORDER BY Timestamp ASC) as ServiceName,
 			MIN(Timestamp) as StartTime,
-			CAST((MAX(epoch_ms(Timestamp) + Duration/1000000) - MIN(epoch_ms(Timestamp))) * 1000000 AS BIGINT) as Duration,
+			` + traceDurationExpr + ` as Duration,
 			COUNT(*) as SpanCount,
 			CASE WHEN SUM(CASE WHEN StatusCode = 'ERROR' THEN 1 ELSE 0 END) > 0 THEN 'ERROR'
 			     WHEN SUM(CASE WHEN StatusCode = 'OK' THEN 1 ELSE 0 END) > 0 THEN 'OK'
@@ -578,6 +1019,7 @@ func (s *DuckDBStore) GetRecentTraces(ctx context.Context, limit int) (*api.Trac
 			if err := rows.Scan(&t.TraceID, &t.RootSpan, &t.ServiceName, &t.StartTime, &t.Duration, &t.SpanCount, &t.Status); err != nil {
 				break
 			}
+			t.TraceID = CodexTraceID(t.TraceID)
 			traces = append(traces, t)
 		}
 		rows.Close()
@@ -600,17 +1042,32 @@ func (s *DuckDBStore) GetStats(ctx context.Context) (*api.StatsResponse, error)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	stats := &api.StatsResponse{}
+	return s.getStatsLocked(ctx, false)
+}
+
+// GetExtrapolatedStats is GetStats with span and trace counts and the error rate extrapolated
+// by each span's sampling probability, so they reflect the volume before head sampling
+func (s *DuckDBStore) GetExtrapolatedStats(ctx context.Context) (*api.StatsResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.getStatsLocked(ctx, true)
+}
+
+// getStatsLocked computes the all-time stats, optionally extrapolating sampled spans.
+// Caller must hold s.mu.
+func (s *DuckDBStore) getStatsLocked(ctx context.Context, extrapolate bool) (*api.StatsResponse, error) {
+	stats := &api.StatsResponse{Extrapolated: extrapolate}
 
 	// Combined query to get all counts in a single round-trip
 	// This reduces 5 queries to 1
 	statsQuery := `
 		SELECT
-			(SELECT COUNT(*) FROM otel_traces) as span_count,
-			(SELECT COUNT(DISTINCT TraceId) FROM otel_traces) as trace_count,
+			(SELECT ` + spanCountExpr(extrapolate) + ` FROM otel_traces) as span_count,
+			(` + traceCountExpr("otel_traces", extrapolate) + `) as trace_count,
 			(SELECT COUNT(*) FROM otel_logs) as log_count,
 			(SELECT COUNT(*) FROM otel_metrics) as metric_count,
-			(SELECT COUNT(*) FROM otel_traces WHERE StatusCode = 'ERROR') as error_count
+			(SELECT ` + spanCountExpr(extrapolate) + ` FROM otel_traces WHERE StatusCode = 'ERROR') as error_count
 	`
 
 	var errorCount int64
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// telemetryTables are the tables whose row counts are tracked by storage usage samples
+var telemetryTables = []string{"otel_traces", "otel_logs", "otel_metrics"}
+
+// GetStorageUsage returns the current database file size (including the WAL) and the row
+// count of each telemetry table. In-memory databases report a size of 0.
+func (s *DuckDBStore) GetStorageUsage(ctx context.Context) (*api.StorageSample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sample := &api.StorageSample{
+		Timestamp: time.Now(),
+		Rows:      make(map[string]int64, len(telemetryTables)),
+	}
+
+	if s.path != "" && s.path != ":memory:" {
+		for _, path := range []string{s.path, s.path + ".wal"} {
+			if info, err := os.Stat(path); err == nil {
+				sample.SizeBytes += info.Size()
+			}
+		}
+	}
+
+	for _, table := range telemetryTables {
+		var count int64
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			return nil, fmt.Errorf("counting %s rows: %w", table, err)
+		}
+		sample.Rows[table] = count
+	}
+
+	return sample, nil
+}
+
+// UsageSampler periodically records storage usage samples, keeping the most recent ones
+// in memory so growth can be reported without writing to the database being measured.
+type UsageSampler struct {
+	store *DuckDBStore
+	max   int
+
+	mu      sync.Mutex
+	samples []api.StorageSample
+}
+
+// NewUsageSampler creates a sampler that keeps at most maxSamples samples (minimum 2)
+func NewUsageSampler(store *DuckDBStore, maxSamples int) *UsageSampler {
+	return &UsageSampler{store: store, max: max(maxSamples, 2)}
+}
+
+// Run takes a sample immediately and then every interval until ctx is cancelled
+func (u *UsageSampler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.Sample(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("Failed to sample storage usage", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sample records the current storage usage and returns it
+func (u *UsageSampler) Sample(ctx context.Context) (*api.StorageSample, error) {
+	sample, err := u.store.GetStorageUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.samples = append(u.samples, *sample)
+	if len(u.samples) > u.max {
+		u.samples = append([]api.StorageSample(nil), u.samples[len(u.samples)-u.max:]...)
+	}
+	return sample, nil
+}
+
+// Samples returns up to the n most recent samples, oldest first; n <= 0 returns all
+func (u *UsageSampler) Samples(n int) []api.StorageSample {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	start := 0
+	if n > 0 && n < len(u.samples) {
+		start = len(u.samples) - n
+	}
+	return append([]api.StorageSample(nil), u.samples[start:]...)
+}
+
+// StorageGrowth computes the change between two samples
+func StorageGrowth(from, to api.StorageSample) *api.StorageGrowth {
+	growth := &api.StorageGrowth{
+		From:      from.Timestamp,
+		To:        to.Timestamp,
+		SizeBytes: to.SizeBytes - from.SizeBytes,
+		Rows:      make(map[string]int64, len(to.Rows)),
+	}
+	for table, count := range to.Rows {
+		growth.Rows[table] = count - from.Rows[table]
+	}
+	if hours := to.Timestamp.Sub(from.Timestamp).Hours(); hours > 0 {
+		growth.BytesPerHour = float64(growth.SizeBytes) / hours
+	}
+	return growth
+}
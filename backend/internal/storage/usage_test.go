@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tobilg/ai-observer/internal/api"
+)
+
+func TestGetStorageUsage(t *testing.T) {
+	store, err := NewDuckDBStore(filepath.Join(t.TempDir(), "usage.duckdb"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	store.InsertSpans(ctx, []api.Span{{Timestamp: now, TraceID: "t1", SpanID: "s1", ServiceName: "svc"}})
+	store.InsertLogs(ctx, []api.LogRecord{
+		{Timestamp: now, ServiceName: "svc", Body: "a"},
+		{Timestamp: now, ServiceName: "svc", Body: "b"},
+	})
+
+	sample, err := store.GetStorageUsage(ctx)
+	if err != nil {
+		t.Fatalf("GetStorageUsage failed: %v", err)
+	}
+
+	if sample.SizeBytes <= 0 {
+		t.Errorf("expected positive size for file database, got %d", sample.SizeBytes)
+	}
+	want := map[string]int64{"otel_traces": 1, "otel_logs": 2, "otel_metrics": 0}
+	for table, count := range want {
+		if sample.Rows[table] != count {
+			t.Errorf("Rows[%s] = %d, want %d", table, sample.Rows[table], count)
+		}
+	}
+}
+
+func TestUsageSampler_KeepsMostRecent(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sampler := NewUsageSampler(store, 3)
+
+	for i := 0; i < 5; i++ {
+		store.InsertLogs(ctx, []api.LogRecord{{Timestamp: time.Now(), ServiceName: "svc"}})
+		if _, err := sampler.Sample(ctx); err != nil {
+			t.Fatalf("Sample failed: %v", err)
+		}
+	}
+
+	samples := sampler.Samples(0)
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 retained samples, got %d", len(samples))
+	}
+	if samples[0].Rows["otel_logs"] != 3 || samples[2].Rows["otel_logs"] != 5 {
+		t.Errorf("expected samples for 3..5 logs, got %d..%d", samples[0].Rows["otel_logs"], samples[2].Rows["otel_logs"])
+	}
+
+	if last := sampler.Samples(1); len(last) != 1 || last[0].Rows["otel_logs"] != 5 {
+		t.Errorf("expected only the latest sample, got %+v", last)
+	}
+}
+
+func TestStorageGrowth(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	from := api.StorageSample{Timestamp: start, SizeBytes: 1000, Rows: map[string]int64{"otel_logs": 10}}
+	to := api.StorageSample{Timestamp: start.Add(2 * time.Hour), SizeBytes: 5000, Rows: map[string]int64{"otel_logs": 30, "otel_traces": 4}}
+
+	growth := StorageGrowth(from, to)
+
+	if growth.SizeBytes != 4000 {
+		t.Errorf("SizeBytes = %d, want 4000", growth.SizeBytes)
+	}
+	if growth.BytesPerHour != 2000 {
+		t.Errorf("BytesPerHour = %v, want 2000", growth.BytesPerHour)
+	}
+	if growth.Rows["otel_logs"] != 20 || growth.Rows["otel_traces"] != 4 {
+		t.Errorf("unexpected row growth: %v", growth.Rows)
+	}
+}
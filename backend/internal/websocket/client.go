@@ -1,9 +1,11 @@
 package websocket
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -66,7 +68,11 @@ type Client struct {
 	hub       *Hub
 	conn      *websocket.Conn
 	send      chan []byte
+	since     time.Time // Replay buffered messages newer than this on connect (zero = none)
 	closeOnce sync.Once // Ensures send channel is closed only once
+
+	// Messages the client subscribed to; nil receives everything. Set by readPump, read by the hub.
+	subscription atomic.Pointer[Subscription]
 }
 
 // Close safely closes the client's send channel.
@@ -77,8 +83,26 @@ func (c *Client) Close() {
 	})
 }
 
+// sendClose writes a close frame to the peer. Clients without a connection are skipped.
+func (c *Client) sendClose(msg []byte, deadline time.Time) {
+	if c.conn == nil {
+		return
+	}
+	// WriteControl may be called concurrently with writePump
+	if err := c.conn.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+		logger.Debug("Failed to send WebSocket close frame", "error", err)
+	}
+}
+
+// closeConn closes the underlying connection, which ends readPump and unregisters the client.
+func (c *Client) closeConn() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
 // readPump pumps messages from the websocket connection to the hub.
-// We mainly use this to detect disconnection.
+// Text messages are read as subscriptions; reading also detects disconnection.
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -93,18 +117,34 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		messageType, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logger.Debug("WebSocket unexpected close", "error", err)
 			}
 			break
 		}
-		// We don't process incoming messages for now
-		// This is mainly for receiving pong responses
+		if messageType == websocket.TextMessage {
+			c.subscribe(data)
+		}
 	}
 }
 
+// subscribe replaces the client's subscription with the one in data. Invalid messages are ignored.
+func (c *Client) subscribe(data []byte) {
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		logger.Debug("Ignoring invalid WebSocket subscription", "error", err)
+		return
+	}
+	if sub.all() {
+		c.subscription.Store(nil)
+	} else {
+		c.subscription.Store(&sub)
+	}
+	logger.Debug("WebSocket client subscribed", "signals", sub.Signals, "service", sub.Service)
+}
+
 // writePump pumps messages from the hub to the websocket connection.
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
@@ -150,7 +190,25 @@ func (c *Client) writePump() {
 }
 
 // ServeWs handles websocket requests from the peer.
+// An optional "since" query parameter (RFC3339 timestamp of the last message the client saw)
+// replays buffered messages newer than it before live updates. Optional "signals" (comma-separated)
+// and "service" parameters set the initial subscription, which also filters the replay.
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			logger.Warn("Ignoring invalid WebSocket since cursor", "since", s, "error", err)
+		} else {
+			since = parsed
+		}
+	}
+
+	if hub.Closing() {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Error("WebSocket upgrade error", "error", err)
@@ -158,10 +216,12 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, sendBufferSize),
+		hub:   hub,
+		conn:  conn,
+		send:  make(chan []byte, sendBufferSize),
+		since: since,
 	}
+	client.subscription.Store(parseSubscriptionQuery(r.URL.Query().Get("signals"), r.URL.Query().Get("service")))
 
 	hub.register <- client
 
@@ -1,12 +1,27 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/tobilg/ai-observer/internal/logger"
 )
 
+// replayBufferSize bounds the number of recent messages kept for reconnecting clients.
+const replayBufferSize = 256
+
+// shutdownDrainTimeout bounds how long Shutdown waits for clients to acknowledge the close frame.
+const shutdownDrainTimeout = 2 * time.Second
+
+// bufferedMessage is a broadcast kept for replay, with its marshaled form.
+type bufferedMessage struct {
+	message Message
+	data    []byte
+}
+
 // Hub maintains the set of active clients and broadcasts messages to them.
 type Hub struct {
 	// Registered clients
@@ -21,7 +36,14 @@ type Hub struct {
 	// Unregister requests from clients
 	unregister chan *Client
 
-	// Mutex for client map
+	// Recent broadcasts replayed to clients connecting with a since cursor.
+	// Only accessed from the Run goroutine.
+	history []bufferedMessage
+
+	// Set by Shutdown; no new clients are accepted afterwards
+	closing bool
+
+	// Mutex for client map and closing
 	mu sync.RWMutex
 }
 
@@ -41,11 +63,20 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			if h.closing {
+				h.mu.Unlock()
+				client.Close() // writePump sends a close frame and drops the connection
+				continue
+			}
 			h.clients[client] = true
 			count := len(h.clients)
 			h.mu.Unlock()
 			logger.Debug("WebSocket client connected", "total_clients", count)
 
+			if !client.since.IsZero() {
+				h.replay(client)
+			}
+
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
@@ -62,13 +93,19 @@ func (h *Hub) Run() {
 				logger.Error("Error marshaling WebSocket message", "error", err)
 				continue
 			}
+			h.record(message, data)
 
 			h.mu.RLock()
 			// Collect clients that need to be disconnected
 			var toDisconnect []*Client
+			filtered := make(map[string][]byte)
 			for client := range h.clients {
+				clientData := client.subscription.Load().encode(message, data, filtered)
+				if clientData == nil {
+					continue
+				}
 				select {
-				case client.send <- data:
+				case client.send <- clientData:
 				default:
 					// Client buffer full, mark for disconnect
 					toDisconnect = append(toDisconnect, client)
@@ -89,7 +126,41 @@ func (h *Hub) Run() {
 	}
 }
 
-// Broadcast sends a message to all connected clients.
+// record appends a marshaled message to the replay buffer, evicting the oldest when full.
+func (h *Hub) record(message Message, data []byte) {
+	if len(h.history) >= replayBufferSize {
+		// Shift instead of reslicing so the backing array doesn't grow unbounded
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, bufferedMessage{message: message, data: data})
+}
+
+// replay sends buffered messages newer than the client's since cursor that match its
+// subscription. Replay stops early if the client's send buffer fills up.
+func (h *Hub) replay(client *Client) {
+	replayed := 0
+	sub := client.subscription.Load()
+	for _, msg := range h.history {
+		if !msg.message.Timestamp.After(client.since) {
+			continue
+		}
+		data := sub.encode(msg.message, msg.data, make(map[string][]byte))
+		if data == nil {
+			continue
+		}
+		select {
+		case client.send <- data:
+			replayed++
+		default:
+			logger.Warn("Client send buffer full, truncating replay", "replayed", replayed)
+			return
+		}
+	}
+	logger.Debug("Replayed buffered WebSocket messages", "count", replayed, "since", client.since)
+}
+
+// Broadcast sends a message to all connected clients whose subscription matches it.
 func (h *Hub) Broadcast(msg Message) {
 	select {
 	case h.broadcast <- msg:
@@ -98,6 +169,53 @@ func (h *Hub) Broadcast(msg Message) {
 	}
 }
 
+// Closing reports whether Shutdown has been called.
+func (h *Hub) Closing() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closing
+}
+
+// Shutdown stops accepting new clients and sends every connected client a "going away" close
+// frame. It then waits for clients to answer with their own close frame and disconnect, for
+// up to shutdownDrainTimeout or until ctx ends, and closes the connections still open.
+func (h *Hub) Shutdown(ctx context.Context) {
+	h.mu.Lock()
+	h.closing = true
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, shutdownDrainTimeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, client := range clients {
+		client.sendClose(closeMsg, deadline)
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for h.ClientCount() > 0 {
+		select {
+		case <-ctx.Done():
+			h.mu.RLock()
+			remaining := len(h.clients)
+			for client := range h.clients {
+				client.closeConn()
+			}
+			h.mu.RUnlock()
+			logger.Warn("Closed WebSocket clients that did not acknowledge shutdown", "clients", remaining)
+			return
+		case <-ticker.C:
+		}
+	}
+	logger.Debug("WebSocket clients disconnected", "clients", len(clients))
+}
+
 // ClientCount returns the number of connected clients.
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
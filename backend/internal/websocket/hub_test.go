@@ -1,10 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tobilg/ai-observer/internal/api"
 )
 
 // MockClient creates a test client without a real WebSocket connection
@@ -321,3 +328,329 @@ func TestHubBroadcastChannelFull(t *testing.T) {
 		t.Error("Broadcast blocked when channel was full")
 	}
 }
+
+func TestHubReplaySince(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	time.Sleep(10 * time.Millisecond)
+
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(Message{Type: "test", Timestamp: base.Add(time.Duration(i) * time.Second), Payload: i})
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Reconnecting client last saw the first message
+	client := newMockClient(hub)
+	client.since = base
+	hub.register <- client
+
+	time.Sleep(20 * time.Millisecond)
+
+	for _, expected := range []int{1, 2} {
+		select {
+		case data := <-client.send:
+			var received Message
+			if err := json.Unmarshal(data, &received); err != nil {
+				t.Fatalf("failed to unmarshal replayed message: %v", err)
+			}
+			if payload, ok := received.Payload.(float64); !ok || int(payload) != expected {
+				t.Errorf("expected replayed payload %d, got %v", expected, received.Payload)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("did not receive replayed message %d", expected)
+		}
+	}
+
+	select {
+	case data := <-client.send:
+		t.Errorf("unexpected extra replayed message: %s", data)
+	default:
+	}
+}
+
+func TestHubNoReplayWithoutSince(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	time.Sleep(10 * time.Millisecond)
+
+	hub.Broadcast(Message{Type: "test", Timestamp: time.Now(), Payload: "before"})
+	time.Sleep(20 * time.Millisecond)
+
+	client := newMockClient(hub)
+	hub.register <- client
+	time.Sleep(20 * time.Millisecond)
+
+	select {
+	case data := <-client.send:
+		t.Errorf("client without since should not receive replay, got %s", data)
+	default:
+	}
+}
+
+func TestHubReplayBufferBounded(t *testing.T) {
+	hub := NewHub()
+	base := time.Now()
+
+	for i := 0; i < replayBufferSize+10; i++ {
+		hub.record(Message{Timestamp: base.Add(time.Duration(i) * time.Millisecond)}, []byte{byte(i)})
+	}
+
+	if len(hub.history) != replayBufferSize {
+		t.Fatalf("expected history bounded to %d, got %d", replayBufferSize, len(hub.history))
+	}
+
+	// Oldest entries are evicted first
+	if !hub.history[0].message.Timestamp.Equal(base.Add(10 * time.Millisecond)) {
+		t.Errorf("expected oldest retained entry at offset 10ms, got %v", hub.history[0].message.Timestamp.Sub(base))
+	}
+}
+
+func TestHubShutdownSendsCloseFrame(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("client was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Reading answers the server's close frame, as browsers do
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	hub.Shutdown(context.Background())
+	if elapsed := time.Since(start); elapsed >= shutdownDrainTimeout {
+		t.Errorf("Shutdown waited %v for an acknowledging client", elapsed)
+	}
+
+	err = <-readErr
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected a going-away close frame, got %v", err)
+	}
+	if count := hub.ClientCount(); count != 0 {
+		t.Errorf("expected no clients after shutdown, got %d", count)
+	}
+
+	// New clients are refused once shutdown has started
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err == nil {
+		t.Fatal("expected dial to fail after shutdown")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 after shutdown, got %v", resp)
+	}
+}
+
+func TestHubShutdownClosesUnresponsiveClients(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	}))
+	defer server.Close()
+
+	// This client never reads, so it never acknowledges the close frame
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("client was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	hub.Shutdown(ctx)
+
+	deadline = time.Now().Add(time.Second)
+	for hub.ClientCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the connection to be closed forcibly, %d clients remain", hub.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// receiveMessage returns the next message sent to client, or nil after a short wait
+func receiveMessage(t *testing.T, client *Client) *Message {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to unmarshal message: %v", err)
+		}
+		return &msg
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	}
+}
+
+func TestHubSubscriptionFilters(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	time.Sleep(10 * time.Millisecond)
+
+	all := newMockClient(hub)
+	logsOnly := newMockClient(hub)
+	logsOnly.subscription.Store(&Subscription{Signals: []MessageType{MessageTypeLogs}})
+	claudeLogs := newMockClient(hub)
+	claudeLogs.subscription.Store(&Subscription{Signals: []MessageType{MessageTypeLogs}, Service: "claude-code"})
+	for _, c := range []*Client{all, logsOnly, claudeLogs} {
+		hub.register <- c
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	hub.Broadcast(NewTracesMessage([]api.Span{{TraceID: "t1", ServiceName: "claude-code"}}))
+	hub.Broadcast(NewLogsMessage([]api.LogRecord{
+		{Body: "a", ServiceName: "codex_cli_rs"},
+		{Body: "b", ServiceName: "claude-code"},
+	}))
+	hub.Broadcast(NewLogsMessage([]api.LogRecord{{Body: "c", ServiceName: "gemini_cli"}}))
+
+	var types []MessageType
+	for msg := receiveMessage(t, all); msg != nil; msg = receiveMessage(t, all) {
+		types = append(types, msg.Type)
+	}
+	if len(types) != 3 {
+		t.Errorf("unfiltered client should receive every message, got %v", types)
+	}
+
+	for i := 0; i < 2; i++ {
+		if msg := receiveMessage(t, logsOnly); msg == nil || msg.Type != MessageTypeLogs {
+			t.Fatalf("expected logs message %d, got %+v", i, msg)
+		}
+	}
+	if msg := receiveMessage(t, logsOnly); msg != nil {
+		t.Errorf("logs subscriber received %+v", msg)
+	}
+
+	msg := receiveMessage(t, claudeLogs)
+	if msg == nil {
+		t.Fatal("expected the claude-code logs")
+	}
+	logs, _ := msg.Payload.([]interface{})
+	if len(logs) != 1 || logs[0].(map[string]interface{})["body"] != "b" {
+		t.Errorf("expected only the claude-code log, got %v", msg.Payload)
+	}
+	if msg := receiveMessage(t, claudeLogs); msg != nil {
+		t.Errorf("service subscriber received %+v", msg)
+	}
+}
+
+func TestHubReplayFiltersBySubscription(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	time.Sleep(10 * time.Millisecond)
+
+	base := time.Now()
+	hub.Broadcast(Message{Type: MessageTypeTraces, Timestamp: base.Add(time.Second), Payload: []api.Span{{ServiceName: "codex_cli_rs"}}})
+	hub.Broadcast(Message{Type: MessageTypeTraces, Timestamp: base.Add(2 * time.Second), Payload: []api.Span{{ServiceName: "claude-code"}}})
+	time.Sleep(20 * time.Millisecond)
+
+	client := newMockClient(hub)
+	client.since = base
+	client.subscription.Store(parseSubscriptionQuery("traces", "claude-code"))
+	hub.register <- client
+	time.Sleep(20 * time.Millisecond)
+
+	msg := receiveMessage(t, client)
+	if msg == nil || !msg.Timestamp.Equal(base.Add(2*time.Second)) {
+		t.Fatalf("expected only the claude-code traces to be replayed, got %+v", msg)
+	}
+	if msg := receiveMessage(t, client); msg != nil {
+		t.Errorf("unexpected extra replayed message: %+v", msg)
+	}
+}
+
+func TestClientSubscribeMessage(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"signals":["metrics"],"service":"claude-code"}`)); err != nil {
+		t.Fatalf("failed to send subscription: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		hub.mu.RLock()
+		var sub *Subscription
+		for client := range hub.clients {
+			sub = client.subscription.Load()
+		}
+		hub.mu.RUnlock()
+		if sub != nil {
+			if sub.Service != "claude-code" || len(sub.Signals) != 1 || sub.Signals[0] != MessageTypeMetrics {
+				t.Errorf("unexpected subscription: %+v", sub)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription was not applied")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	hub.Broadcast(NewLogsMessage([]api.LogRecord{{Body: "skipped", ServiceName: "claude-code"}}))
+	hub.Broadcast(NewMetricsMessage([]api.MetricDataPoint{{MetricName: "m", ServiceName: "claude-code"}}))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+	if msg.Type != MessageTypeMetrics {
+		t.Errorf("expected only the metrics message, got %s", data)
+	}
+}
@@ -8,6 +8,7 @@ const (
 	MessageTypeTraces  MessageType = "traces"
 	MessageTypeMetrics MessageType = "metrics"
 	MessageTypeLogs    MessageType = "logs"
+	MessageTypeAlerts  MessageType = "alerts"
 )
 
 type Message struct {
@@ -39,3 +40,12 @@ func NewLogsMessage(payload interface{}) Message {
 		Payload:   payload,
 	}
 }
+
+// NewAlertsMessage carries the api.AlertEvent values fired by one alert rule evaluation
+func NewAlertsMessage(payload interface{}) Message {
+	return Message{
+		Type:      MessageTypeAlerts,
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+}
@@ -46,6 +46,17 @@ func TestNewLogsMessage(t *testing.T) {
 	}
 }
 
+func TestNewAlertsMessage(t *testing.T) {
+	msg := NewAlertsMessage([]string{"alert"})
+
+	if msg.Type != MessageTypeAlerts {
+		t.Errorf("Type = %q, want %q", msg.Type, MessageTypeAlerts)
+	}
+	if msg.Payload == nil {
+		t.Error("Payload is nil")
+	}
+}
+
 func TestMessageTypes(t *testing.T) {
 	if MessageTypeTraces != "traces" {
 		t.Errorf("MessageTypeTraces = %q, want %q", MessageTypeTraces, "traces")
@@ -56,4 +67,7 @@ func TestMessageTypes(t *testing.T) {
 	if MessageTypeLogs != "logs" {
 		t.Errorf("MessageTypeLogs = %q, want %q", MessageTypeLogs, "logs")
 	}
+	if MessageTypeAlerts != "alerts" {
+		t.Errorf("MessageTypeAlerts = %q, want %q", MessageTypeAlerts, "alerts")
+	}
 }
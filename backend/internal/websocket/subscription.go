@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tobilg/ai-observer/internal/api"
+	"github.com/tobilg/ai-observer/internal/logger"
+)
+
+// Subscription narrows the messages a client receives. Clients send it as a JSON text
+// message, e.g. {"signals":["logs"],"service":"claude-code"}; each one replaces the previous
+// subscription. Empty fields match everything, so clients that never subscribe get every
+// message.
+type Subscription struct {
+	Signals []MessageType `json:"signals,omitempty"`
+	Service string        `json:"service,omitempty"`
+}
+
+// parseSubscriptionQuery reads a subscription from the signals (comma-separated) and service
+// query parameters, so it also applies to the replay sent on connect. It returns nil when
+// neither is set.
+func parseSubscriptionQuery(signals, service string) *Subscription {
+	sub := &Subscription{Service: strings.TrimSpace(service)}
+	for _, s := range strings.Split(signals, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sub.Signals = append(sub.Signals, MessageType(s))
+		}
+	}
+	if sub.all() {
+		return nil
+	}
+	return sub
+}
+
+// all reports whether the subscription matches every message
+func (s *Subscription) all() bool {
+	return s == nil || (len(s.Signals) == 0 && s.Service == "")
+}
+
+// matchesSignal reports whether messages of type t are subscribed to
+func (s *Subscription) matchesSignal(t MessageType) bool {
+	if len(s.Signals) == 0 {
+		return true
+	}
+	for _, signal := range s.Signals {
+		if signal == t {
+			return true
+		}
+	}
+	return false
+}
+
+// encode returns msg as it is sent to a client with this subscription, or nil when nothing
+// in it matches. full is msg already marshaled; encodings of payloads filtered to a service
+// are shared through cache (keyed by service) between the clients of one broadcast.
+func (s *Subscription) encode(msg Message, full []byte, cache map[string][]byte) []byte {
+	if s.all() {
+		return full
+	}
+	if !s.matchesSignal(msg.Type) {
+		return nil
+	}
+	if s.Service == "" {
+		return full
+	}
+	if data, ok := cache[s.Service]; ok {
+		return data
+	}
+
+	var data []byte
+	if payload, ok := filterPayload(msg.Payload, s.Service); ok {
+		msg.Payload = payload
+		encoded, err := json.Marshal(msg)
+		if err != nil {
+			logger.Error("Error marshaling filtered WebSocket message", "error", err)
+		} else {
+			data = encoded
+		}
+	}
+	cache[s.Service] = data
+	return data
+}
+
+// filterPayload keeps the spans, logs or metric points of service, and the alerts of rules on
+// service or on all services. ok is false when none remain. Payloads of other types carry no
+// service and are returned unchanged.
+func filterPayload(payload interface{}, service string) (interface{}, bool) {
+	switch p := payload.(type) {
+	case []api.AlertEvent:
+		return filterByService(p, service, func(e api.AlertEvent) string {
+			if e.Service == "" {
+				return service
+			}
+			return e.Service
+		})
+	case []api.Span:
+		return filterByService(p, service, func(s api.Span) string { return s.ServiceName })
+	case []api.LogRecord:
+		return filterByService(p, service, func(l api.LogRecord) string { return l.ServiceName })
+	case []api.MetricDataPoint:
+		return filterByService(p, service, func(m api.MetricDataPoint) string { return m.ServiceName })
+	}
+	return payload, true
+}
+
+func filterByService[T any](items []T, service string, serviceOf func(T) string) (interface{}, bool) {
+	var kept []T
+	for _, item := range items {
+		if serviceOf(item) == service {
+			kept = append(kept, item)
+		}
+	}
+	return kept, len(kept) > 0
+}
@@ -0,0 +1,123 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxZstdDecoderMemory bounds the window a zstd frame may make the decoder allocate
+const maxZstdDecoderMemory = 64 << 20
+
+var (
+	// ErrUnsupportedEncoding is returned for request Content-Encodings other than gzip and zstd
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+	// ErrDecompressedTooLarge is returned by a decompressing reader once its maximum size is
+	// exceeded
+	ErrDecompressedTooLarge = errors.New("decompressed body too large")
+)
+
+// NewDecompressReader wraps body in a reader that decompresses it according to a
+// Content-Encoding of gzip (or x-gzip) or zstd; "" and identity return body as is. When
+// maxSize > 0, reads fail with ErrDecompressedTooLarge past maxSize decompressed bytes, so a
+// small compressed body cannot expand without bound. Closing the reader releases the
+// decoder, not body.
+func NewDecompressReader(body io.Reader, encoding string, maxSize int64) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		r = io.NopCloser(body)
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case EncodingZstd:
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxZstdDecoderMemory))
+		if err != nil {
+			return nil, err
+		}
+		r = zr.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+
+	if maxSize <= 0 {
+		return r, nil
+	}
+	return &limitedReadCloser{ReadCloser: r, remaining: maxSize}, nil
+}
+
+// limitedReadCloser fails with ErrDecompressedTooLarge instead of silently truncating like
+// io.LimitReader
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrDecompressedTooLarge
+	}
+	// Read one byte past the limit to tell a body of exactly maxSize bytes from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrDecompressedTooLarge
+	}
+	return n, err
+}
+
+// DecompressMiddleware decompresses gzip- and zstd-encoded request bodies before next reads
+// them, removing the Content-Encoding header. Malformed compressed input is answered with
+// 400, bodies that decompress to more than maxSize bytes (when maxSize > 0) with 413, and
+// other encodings with 415.
+func DecompressMiddleware(maxSize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reader, err := NewDecompressReader(r.Body, encoding, maxSize)
+			if errors.Is(err, ErrUnsupportedEncoding) {
+				http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to decompress request body", http.StatusBadRequest)
+				return
+			}
+			// Decompress up front so corrupt input is rejected before next sees any of it
+			body, err := io.ReadAll(reader)
+			reader.Close()
+			if errors.Is(err, ErrDecompressedTooLarge) {
+				http.Error(w, fmt.Sprintf("Decompressed request body exceeds %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Failed to decompress request body", http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
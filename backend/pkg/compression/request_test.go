@@ -0,0 +1,129 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func zstdCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("creating zstd encoder: %v", err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(data, nil)
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(data)
+	gw.Close()
+	return buf.Bytes()
+}
+
+// serveDecompress posts body with the given Content-Encoding through DecompressMiddleware and
+// returns the response and the body the handler read
+func serveDecompress(t *testing.T, maxSize int64, encoding string, body []byte) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	var received string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ce := r.Header.Get("Content-Encoding"); ce != "" && ce != "identity" {
+			t.Errorf("Content-Encoding = %q after decompression, want empty", ce)
+		}
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rr := httptest.NewRecorder()
+	DecompressMiddleware(maxSize)(handler).ServeHTTP(rr, req)
+	return rr, received
+}
+
+func TestDecompressMiddleware_Encodings(t *testing.T) {
+	content := []byte(strings.Repeat("hello world ", 100))
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"zstd", zstdCompress(t, content)},
+		{"gzip", gzipBytes(t, content)},
+		{"x-gzip", gzipBytes(t, content)},
+		{"ZSTD", zstdCompress(t, content)},
+		{"identity", content},
+		{"", content},
+	}
+	for _, tt := range tests {
+		rr, received := serveDecompress(t, 0, tt.encoding, tt.body)
+		if rr.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, want 200", tt.encoding, rr.Code)
+		}
+		if received != string(content) {
+			t.Errorf("%q: handler read %d bytes, want the %d decompressed bytes", tt.encoding, len(received), len(content))
+		}
+	}
+}
+
+func TestDecompressMiddleware_MaxSize(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 1000)
+
+	// A body of exactly maxSize bytes is accepted
+	if rr, received := serveDecompress(t, 1000, "zstd", zstdCompress(t, content)); rr.Code != http.StatusOK || len(received) != 1000 {
+		t.Errorf("exact size: status = %d, read %d bytes; want 200 and 1000 bytes", rr.Code, len(received))
+	}
+
+	// Highly compressible bodies expanding past maxSize are rejected before the handler runs
+	for _, encoding := range []string{"zstd", "gzip"} {
+		body := zstdCompress(t, content)
+		if encoding == "gzip" {
+			body = gzipBytes(t, content)
+		}
+		rr, received := serveDecompress(t, 999, encoding, body)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status = %d, want 413", encoding, rr.Code)
+		}
+		if received != "" {
+			t.Errorf("%s: handler should not run for an oversized body", encoding)
+		}
+	}
+}
+
+func TestDecompressMiddleware_Malformed(t *testing.T) {
+	truncated := zstdCompress(t, bytes.Repeat([]byte("telemetry "), 500))
+	truncated = truncated[:len(truncated)/2]
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"invalid zstd", "zstd", []byte("not valid zstd")},
+		{"truncated zstd", "zstd", truncated},
+		{"invalid gzip", "gzip", []byte("not valid gzip")},
+	}
+	for _, tt := range tests {
+		if rr, _ := serveDecompress(t, 0, tt.encoding, tt.body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tt.name, rr.Code)
+		}
+	}
+}
+
+func TestDecompressMiddleware_UnsupportedEncoding(t *testing.T) {
+	if rr, _ := serveDecompress(t, 0, "br", []byte("data")); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", rr.Code)
+	}
+}
@@ -0,0 +1,230 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Response content encodings, in order of preference
+const (
+	EncodingZstd = "zstd"
+	EncodingGzip = "gzip"
+)
+
+// encoder is the part of gzip.Writer and zstd.Encoder used to compress responses
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	EncodingZstd: {New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+}
+
+// NegotiateEncoding picks the response encoding for an Accept-Encoding header: zstd when
+// accepted, else gzip (also for "*"), else "". Codings with q=0 are refused.
+func NegotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+
+	switch {
+	case accepted[EncodingZstd]:
+		return EncodingZstd
+	case accepted[EncodingGzip], accepted["*"] && !hasKey(accepted, EncodingGzip):
+		return EncodingGzip
+	}
+	return ""
+}
+
+func hasKey(m map[string]bool, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// compressible reports whether responses of contentType are worth compressing. Binary formats
+// such as Parquet are compressed already, and responses without a type are left alone.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// ResponseCompressionMiddleware compresses responses of at least minSize bytes with zstd or
+// gzip, as negotiated from Accept-Encoding. Smaller responses are sent as is with their
+// Content-Length; compressed ones are streamed without it. Responses that already carry a
+// Content-Encoding, that are not text or JSON, or that answer HEAD, Range or WebSocket
+// upgrade requests pass through unchanged. A handler that flushes before minSize bytes are written is
+// treated as a stream and compressed from then on.
+func ResponseCompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			defer cw.finish()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter holds back the status and up to minSize bytes of a response until it knows
+// whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status      int // Held back status, 0 until WriteHeader
+	buf         []byte
+	passthrough bool    // Writes go straight to ResponseWriter
+	enc         encoder // Set while compressing
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 || cw.passthrough || cw.enc != nil {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status) // Informational responses are sent right away
+		return
+	}
+
+	cw.status = status
+	h := cw.Header()
+	length, err := strconv.Atoi(h.Get("Content-Length"))
+	small := err == nil && length < cw.minSize
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) || small {
+		if small {
+			h.Add("Vary", "Accept-Encoding")
+		}
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 && !cw.passthrough && cw.enc == nil {
+		if cw.Header().Get("Content-Type") == "" {
+			// Sniff like net/http would, so the type can decide on compression
+			cw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	switch {
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(p)
+	case cw.enc != nil:
+		return cw.enc.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// startCompression sends the held back status with the compression headers and compresses
+// the buffered bytes
+func (cw *compressWriter) startCompression() error {
+	h := cw.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", cw.encoding)
+	h.Add("Vary", "Accept-Encoding")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	cw.enc = encoderPools[cw.encoding].Get().(encoder)
+	cw.enc.Reset(cw.ResponseWriter)
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.enc.Write(buf)
+	return err
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 && !cw.passthrough && cw.enc == nil {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.passthrough && cw.enc == nil {
+		if err := cw.startCompression(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// finish sends a response that stayed below minSize uncompressed, or ends the compressed
+// stream
+func (cw *compressWriter) finish() {
+	switch {
+	case cw.enc != nil:
+		cw.enc.Close()
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	case cw.status != 0 && !cw.passthrough:
+		h := cw.Header()
+		h.Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		h.Add("Vary", "Accept-Encoding")
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.ResponseWriter.Write(cw.buf)
+	}
+}
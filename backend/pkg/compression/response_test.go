@@ -0,0 +1,171 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"zstd;q=0, gzip;q=0.5", "gzip"},
+		{"gzip;q=0", ""},
+		{"GZIP", "gzip"},
+		{"*", "gzip"},
+		{"gzip;q=0, *", ""},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := NegotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func serveCompressed(t *testing.T, minSize int, acceptEncoding string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/traces", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	ResponseCompressionMiddleware(minSize)(handler).ServeHTTP(rec, req)
+	return rec
+}
+
+func jsonHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, body)
+	}
+}
+
+func TestResponseCompression_Large(t *testing.T) {
+	body := `{"traces":[` + strings.Repeat(`{"traceId":"abc","spanCount":1},`, 100) + `{}]}`
+
+	for _, encoding := range []string{"gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			rec := serveCompressed(t, 1024, encoding, jsonHandler(body))
+
+			if got := rec.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+			}
+			if got := rec.Header().Get("Content-Length"); got != "" {
+				t.Errorf("expected no Content-Length on a compressed response, got %q", got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if rec.Body.Len() >= len(body) {
+				t.Errorf("expected a compressed body, got %d bytes for %d", rec.Body.Len(), len(body))
+			}
+
+			var reader io.Reader
+			if encoding == "gzip" {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				reader = gz
+			} else {
+				zr, err := zstd.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("invalid zstd body: %v", err)
+				}
+				defer zr.Close()
+				reader = zr
+			}
+			decoded, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to decompress body: %v", err)
+			}
+			if string(decoded) != body {
+				t.Errorf("decompressed body does not match the original")
+			}
+		})
+	}
+}
+
+func TestResponseCompression_Small(t *testing.T) {
+	rec := serveCompressed(t, 1024, "gzip", jsonHandler(`{"traces":[]}`))
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected no Content-Encoding below the threshold, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != "13" {
+		t.Errorf("Content-Length = %q, want 13", got)
+	}
+	if rec.Body.String() != `{"traces":[]}` {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestResponseCompression_Passthrough(t *testing.T) {
+	large := strings.Repeat("x", 4096)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		wantEncoding   string
+	}{
+		{"client without compression", "", jsonHandler(large), ""},
+		{"already encoded", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		}, "br"},
+		{"binary content", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+			io.WriteString(w, large)
+		}, ""},
+		{"no content", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, 1024, tt.acceptEncoding, tt.handler)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if rec.Code == http.StatusOK && rec.Body.String() != large {
+				t.Errorf("expected the body unchanged")
+			}
+		})
+	}
+}
+
+func TestResponseCompression_Flush(t *testing.T) {
+	rec := serveCompressed(t, 1024, "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"n\":1}\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "{\"n\":2}\n")
+	})
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected a flushed stream to be compressed, got Content-Encoding %q", got)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(gz)
+	if string(decoded) != "{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("unexpected stream %q", decoded)
+	}
+}
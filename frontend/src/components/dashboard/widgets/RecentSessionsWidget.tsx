@@ -0,0 +1,95 @@
+import { useEffect, useState } from 'react'
+import { useNavigate } from 'react-router-dom'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { Badge } from '@/components/ui/badge'
+import { ScrollArea } from '@/components/ui/scroll-area'
+import { api } from '@/lib/api'
+import { formatRelativeTime } from '@/lib/utils'
+import { getServiceDisplayName } from '@/lib/metricMetadata'
+import type { WidgetConfig } from '@/types/dashboard'
+import type { Session } from '@/types/sessions'
+
+interface RecentSessionsWidgetProps {
+  title: string
+  config: WidgetConfig
+}
+
+export function RecentSessionsWidget({ title, config }: RecentSessionsWidgetProps) {
+  const navigate = useNavigate()
+  const [sessions, setSessions] = useState<Session[]>([])
+  const [loading, setLoading] = useState(true)
+
+  useEffect(() => {
+    const abortController = new AbortController()
+    api.getSessions({ service: config.service || undefined, limit: 10 }, { signal: abortController.signal })
+      .then((data) => setSessions(data.sessions ?? []))
+      .catch((err) => {
+        if (err instanceof DOMException && err.name === 'AbortError') return
+        console.error('Failed to fetch recent sessions:', err)
+      })
+      .finally(() => {
+        if (!abortController.signal.aborted) setLoading(false)
+      })
+    return () => abortController.abort()
+  }, [config.service])
+
+  const handleSessionClick = (sessionId: string) => {
+    navigate(`/sessions/${encodeURIComponent(sessionId)}`)
+  }
+
+  return (
+    <Card className="border-0 shadow-none">
+      <CardHeader className="p-4 pb-2">
+        <CardTitle className="flex items-center gap-2">
+          {title}
+        </CardTitle>
+        <CardDescription>Latest sessions of your AI coding tools</CardDescription>
+      </CardHeader>
+      <CardContent className="px-4 pb-4 pt-0">
+        <ScrollArea className="h-[200px]">
+          <div className="space-y-2">
+            {sessions.length ? (
+              sessions.map((session) => (
+                <div
+                  key={session.sessionId}
+                  className="flex items-center justify-between py-2 px-2 -mx-2 border-b last:border-0 cursor-pointer rounded-md hover:bg-accent transition-colors"
+                  onClick={() => handleSessionClick(session.sessionId)}
+                  role="button"
+                  tabIndex={0}
+                  onKeyDown={(e) => {
+                    if (e.key === 'Enter' || e.key === ' ') {
+                      e.preventDefault()
+                      handleSessionClick(session.sessionId)
+                    }
+                  }}
+                >
+                  <div className="flex items-center gap-3 min-w-0">
+                    {session.status === 'active' && (
+                      <Badge variant="success" className="shrink-0">
+                        Active
+                      </Badge>
+                    )}
+                    <div className="min-w-0">
+                      <p className="font-medium text-sm truncate">{session.sessionId}</p>
+                      <p className="text-xs text-muted-foreground">
+                        {getServiceDisplayName(session.serviceName)} &middot; {session.messageCount} message{session.messageCount !== 1 ? 's' : ''}
+                        {session.model && <> &middot; {session.model}</>}
+                      </p>
+                    </div>
+                  </div>
+                  <p className="text-xs text-muted-foreground shrink-0 pl-2">
+                    {formatRelativeTime(session.lastTime)}
+                  </p>
+                </div>
+              ))
+            ) : (
+              <p className="text-muted-foreground text-sm py-4 text-center">
+                {loading ? 'Loading...' : 'No sessions yet'}
+              </p>
+            )}
+          </div>
+        </ScrollArea>
+      </CardContent>
+    </Card>
+  )
+}
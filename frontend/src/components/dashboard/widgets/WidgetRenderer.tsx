@@ -5,6 +5,7 @@ import type { TraceOverview } from '@/types/traces'
 import { StatsWidget } from './StatsWidget'
 import { ActiveServicesWidget } from './ActiveServicesWidget'
 import { RecentTracesWidget } from './RecentTracesWidget'
+import { RecentSessionsWidget } from './RecentSessionsWidget'
 import { MetricValueWidget } from './MetricValueWidget'
 import { MetricChartWidget } from './MetricChartWidget'
 
@@ -54,6 +55,14 @@ export function WidgetRenderer({
         />
       )
 
+    case WIDGET_TYPES.RECENT_SESSIONS:
+      return (
+        <RecentSessionsWidget
+          title={widget.title}
+          config={widget.config}
+        />
+      )
+
     case WIDGET_TYPES.METRIC_VALUE:
       return (
         <MetricValueWidget
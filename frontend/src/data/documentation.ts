@@ -135,6 +135,7 @@ export const DOCUMENTATION_SECTIONS: DocSection[] = [
           'Error Rate - Percentage of spans with error status',
           'Active Services - List of services sending telemetry',
           'Recent Traces - Table of the most recent traces with details',
+          'Recent Sessions - The latest sessions of your AI coding tools, linking to their transcripts',
         ],
       },
       {
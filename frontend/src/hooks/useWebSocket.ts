@@ -19,6 +19,8 @@ class WebSocketManager {
   private _isConnected: boolean = false
   private _error: string | null = null
   private messageHandler: ((message: WebSocketMessage) => void) | null = null
+  // Timestamp of the last received message, sent as `since` on reconnect so the server replays missed records
+  private lastSeen: string | null = null
 
   get isConnected() {
     return this._isConnected
@@ -43,7 +45,7 @@ class WebSocketManager {
     this.messageHandler = handler
   }
 
-  connect(url: string) {
+  connect(url: string, since: string | null = null) {
     // Don't reconnect if already connected to the same URL
     if (this.ws && this.url === url && this.ws.readyState === WebSocket.OPEN) {
       return
@@ -56,9 +58,12 @@ class WebSocketManager {
 
     try {
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-      const wsUrl = url.startsWith('/')
+      const baseUrl = url.startsWith('/')
         ? `${protocol}//${window.location.host}${url}`
         : url
+      const wsUrl = since
+        ? `${baseUrl}${baseUrl.includes('?') ? '&' : '?'}since=${encodeURIComponent(since)}`
+        : baseUrl
 
       console.log('WebSocket connecting to:', wsUrl)
       this.ws = new WebSocket(wsUrl)
@@ -88,13 +93,17 @@ class WebSocketManager {
       }
 
       this.ws.onmessage = (event) => {
-        try {
-          const message: WebSocketMessage = JSON.parse(event.data)
-          if (this.messageHandler) {
-            this.messageHandler(message)
+        // The server may batch several messages into one frame, separated by newlines
+        for (const line of String(event.data).split('\n')) {
+          try {
+            const message: WebSocketMessage = JSON.parse(line)
+            this.lastSeen = message.timestamp
+            if (this.messageHandler) {
+              this.messageHandler(message)
+            }
+          } catch (err) {
+            console.error('Failed to parse WebSocket message:', err)
           }
-        } catch (err) {
-          console.error('Failed to parse WebSocket message:', err)
         }
       }
     } catch (err) {
@@ -110,7 +119,7 @@ class WebSocketManager {
     }
     this.reconnectTimeout = window.setTimeout(() => {
       console.log('Attempting to reconnect...')
-      this.connect(this.url)
+      this.connect(this.url, this.lastSeen)
     }, 3000)
   }
 
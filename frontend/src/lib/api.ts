@@ -126,6 +126,7 @@ interface StatsResponse {
 
 interface ServicesResponse {
   services: string[]
+  groups?: Record<string, string>
 }
 
 interface QueryParams {
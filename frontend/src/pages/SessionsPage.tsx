@@ -211,6 +211,11 @@ export function SessionsPage() {
                           <Badge variant="outline" className="shrink-0">
                             {getServiceDisplayName(session.serviceName)}
                           </Badge>
+                          {session.status === 'active' && (
+                            <Badge variant="success" className="shrink-0">
+                              Active
+                            </Badge>
+                          )}
                         </div>
                         <div className="flex items-center gap-4 text-sm text-muted-foreground">
                           <span>{formatTimestamp(session.startTime)}</span>
@@ -91,6 +91,7 @@ export const WIDGET_TYPES = {
   STATS_ERROR_RATE: 'stats_error_rate',
   ACTIVE_SERVICES: 'active_services',
   RECENT_ACTIVITY: 'recent_activity',
+  RECENT_SESSIONS: 'recent_sessions',
   METRIC_VALUE: 'metric_value',
   METRIC_CHART: 'metric_chart',
 } as const
@@ -163,6 +164,15 @@ export const WIDGET_DEFINITIONS: WidgetDefinition[] = [
     configurable: false,
     category: 'builtin',
   },
+  {
+    type: WIDGET_TYPES.RECENT_SESSIONS,
+    label: 'Recent Sessions',
+    description: 'Shows the latest sessions',
+    defaultColSpan: 4,
+    defaultRowSpan: 1,
+    configurable: false,
+    category: 'builtin',
+  },
   {
     type: WIDGET_TYPES.METRIC_VALUE,
     label: 'Metric Value',
@@ -13,6 +13,7 @@ export interface LogRecord {
   scopeName?: string
   scopeVersion?: string
   scopeAttributes?: Record<string, string>
+  droppedAttributesCount?: number
   logAttributes?: Record<string, string>
 }
 
@@ -20,6 +21,7 @@ export interface LogsResponse {
   logs: LogRecord[]
   total: number
   hasMore: boolean
+  nextCursor?: string
 }
 
 export interface LogLevelsResponse {
@@ -7,6 +7,7 @@ export interface MetricDataPoint {
   resourceAttributes?: Record<string, string>
   scopeName?: string
   scopeVersion?: string
+  scopeAttributes?: Record<string, string>
   attributes?: Record<string, string>
   metricType: 'gauge' | 'sum' | 'histogram' | 'exponential_histogram' | 'summary'
   value?: number
@@ -44,6 +45,18 @@ export interface TimeSeriesResponse {
   series: TimeSeries[]
 }
 
+export interface HistogramBucket {
+  upperBound: number | null // null for the +Inf overflow bucket
+  count: number
+}
+
+export interface MetricHistogramResponse {
+  name: string
+  from: string
+  to: string
+  buckets: HistogramBucket[]
+}
+
 export interface MetricNamesResponse {
   names: string[]
 }
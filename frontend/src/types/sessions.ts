@@ -5,6 +5,8 @@ export interface Session {
   lastTime: string
   messageCount: number
   model?: string
+  status: 'active' | 'completed'
+  preview?: string
 }
 
 export interface SessionsResponse {
@@ -22,6 +24,7 @@ export interface TranscriptMessage {
   toolName?: string
   toolInput?: string
   toolOutput?: string      // Tool execution output (from imports)
+  toolCallId?: string      // Shared by a tool_use and its tool_result
   inputTokens?: number     // Input token count
   outputTokens?: number    // Output token count
   cacheRead?: number       // Cache read tokens
@@ -20,6 +20,10 @@ export interface Span {
   resourceAttributes?: Record<string, string>
   scopeName?: string
   scopeVersion?: string
+  scopeAttributes?: Record<string, string>
+  droppedAttributesCount?: number
+  droppedEventsCount?: number
+  droppedLinksCount?: number
   spanAttributes?: Record<string, string>
   duration: number
   statusCode?: string
@@ -45,6 +49,7 @@ export interface TracesResponse {
   traces: TraceOverview[]
   total: number
   hasMore: boolean
+  nextCursor?: string
 }
 
 export interface SpansResponse {